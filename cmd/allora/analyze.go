@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/AlloraAi/AlloraCLI/pkg/analyze"
 	"github.com/AlloraAi/AlloraCLI/pkg/utils"
@@ -28,20 +29,31 @@ func newAnalyzeLogsCmd() *cobra.Command {
 	var logFile string
 	var pattern string
 	var timeRange string
+	var anomalyStdDev float64
 	var format string
 
 	cmd := &cobra.Command{
-		Use:   "logs",
+		Use:   "logs [file|-]",
 		Short: "Analyze log files with AI",
+		Long: `Analyze a log file, or logs piped on standard input when the file is "-".
+
+Examples:
+  allora analyze logs --file /var/log/app.log.gz
+  journalctl -u nginx | allora analyze logs -`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runAnalyzeLogs(logFile, pattern, timeRange, format)
+			if len(args) == 1 {
+				logFile = args[0]
+			}
+			return runAnalyzeLogs(cmd, logFile, pattern, timeRange, anomalyStdDev, format)
 		},
 	}
 
-	cmd.Flags().StringVarP(&logFile, "file", "f", "", "log file path")
+	cmd.Flags().StringVarP(&logFile, "file", "f", "", "log file path, or - for standard input")
 	cmd.Flags().StringVarP(&pattern, "pattern", "p", "", "search pattern or regex")
 	cmd.Flags().StringVarP(&timeRange, "time", "t", "24h", "time range (e.g., 1h, 24h, 7d)")
-	cmd.Flags().StringVarP(&format, "format", "o", "text", "output format (text, json, yaml)")
+	cmd.Flags().Float64Var(&anomalyStdDev, "anomaly-stddev", 3, "standard deviations above the rolling mean that count as a volume spike")
+	addOutputFlag(cmd, &format, "text", "table", "json", "yaml", "csv")
 
 	return cmd
 }
@@ -56,14 +68,14 @@ func newAnalyzePerformanceCmd() *cobra.Command {
 		Use:   "performance",
 		Short: "Analyze performance metrics",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runAnalyzePerformance(service, metric, timeRange, format)
+			return runAnalyzePerformance(cmd, service, metric, timeRange, format)
 		},
 	}
 
 	cmd.Flags().StringVarP(&service, "service", "s", "", "service name")
 	cmd.Flags().StringVarP(&metric, "metric", "m", "", "specific metric (cpu, memory, disk, network)")
 	cmd.Flags().StringVarP(&timeRange, "time", "t", "1h", "time range (e.g., 1h, 24h, 7d)")
-	cmd.Flags().StringVarP(&format, "format", "o", "text", "output format (text, json, yaml)")
+	addOutputFlag(cmd, &format, "text", "table", "json", "yaml", "csv")
 
 	return cmd
 }
@@ -78,14 +90,14 @@ func newAnalyzeCostsCmd() *cobra.Command {
 		Use:   "costs",
 		Short: "Analyze cloud costs and optimization opportunities",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runAnalyzeCosts(period, service, recommendations, format)
+			return runAnalyzeCosts(cmd, period, service, recommendations, format)
 		},
 	}
 
 	cmd.Flags().StringVarP(&period, "period", "p", "30d", "analysis period (e.g., 7d, 30d, 90d)")
 	cmd.Flags().StringVarP(&service, "service", "s", "", "specific service or resource")
 	cmd.Flags().BoolVarP(&recommendations, "recommendations", "r", true, "include cost optimization recommendations")
-	cmd.Flags().StringVarP(&format, "format", "o", "text", "output format (text, json, yaml)")
+	addOutputFlag(cmd, &format, "text", "table", "json", "yaml", "csv")
 
 	return cmd
 }
@@ -99,13 +111,13 @@ func newAnalyzeSecurityCmd() *cobra.Command {
 		Use:   "security",
 		Short: "Analyze security posture and vulnerabilities",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runAnalyzeSecurity(target, deep, format)
+			return runAnalyzeSecurity(cmd, target, deep, format)
 		},
 	}
 
 	cmd.Flags().StringVarP(&target, "target", "t", "", "target resource or service")
 	cmd.Flags().BoolVarP(&deep, "deep", "d", false, "perform deep security analysis")
-	cmd.Flags().StringVarP(&format, "format", "o", "text", "output format (text, json, yaml)")
+	addOutputFlag(cmd, &format, "text", "table", "json", "yaml", "csv")
 
 	return cmd
 }
@@ -113,50 +125,65 @@ func newAnalyzeSecurityCmd() *cobra.Command {
 func newAnalyzeCapacityCmd() *cobra.Command {
 	var service string
 	var forecast string
+	var history string
 	var format string
 
 	cmd := &cobra.Command{
 		Use:   "capacity",
 		Short: "Analyze capacity and forecast future needs",
+		Long: `Forecast CPU, memory and storage usage from their history in Prometheus.
+
+Each resource is projected with linear regression, or with Holt-Winters when
+the history shows a daily cycle that it fits better. The confidence is the
+R² of the fit. An alert is raised for every resource projected to run out
+within the forecast period.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runAnalyzeCapacity(service, forecast, format)
+			return runAnalyzeCapacity(cmd, service, forecast, history, format)
 		},
 	}
 
 	cmd.Flags().StringVarP(&service, "service", "s", "", "service name")
 	cmd.Flags().StringVarP(&forecast, "forecast", "f", "30d", "forecast period (e.g., 7d, 30d, 90d)")
-	cmd.Flags().StringVarP(&format, "format", "o", "text", "output format (text, json, yaml)")
+	cmd.Flags().StringVar(&history, "history", "7d", "metrics history the forecast is based on")
+	addOutputFlag(cmd, &format, "text", "table", "json", "yaml", "csv")
 
 	return cmd
 }
 
 // Implementation functions
-func runAnalyzeLogs(logFile, pattern, timeRange, format string) error {
+func runAnalyzeLogs(cmd *cobra.Command, logFile, pattern, timeRange string, anomalyStdDev float64, format string) error {
 	analyzer, err := analyze.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize analyzer: %w", err)
 	}
 
 	options := analyze.LogOptions{
-		File:      logFile,
-		Pattern:   pattern,
-		TimeRange: timeRange,
+		File:          logFile,
+		Pattern:       pattern,
+		TimeRange:     timeRange,
+		AnomalyStdDev: anomalyStdDev,
 	}
 
 	spinner := utils.NewSpinner("Analyzing logs...")
 	spinner.Start()
 
-	analysis, err := analyzer.AnalyzeLogs(options)
+	var analysis *analyze.LogAnalysis
+	if logFile == "-" {
+		options.File = "stdin"
+		analysis, err = analyzer.AnalyzeLogsStream(cmd.Context(), cmd.InOrStdin(), options)
+	} else {
+		analysis, err = analyzer.AnalyzeLogs(cmd.Context(), options)
+	}
 	spinner.Stop()
 
 	if err != nil {
 		return fmt.Errorf("failed to analyze logs: %w", err)
 	}
 
-	return utils.DisplayResponse(analysis, format)
+	return displayAnalysis(analysis, format)
 }
 
-func runAnalyzePerformance(service, metric, timeRange, format string) error {
+func runAnalyzePerformance(cmd *cobra.Command, service, metric, timeRange, format string) error {
 	analyzer, err := analyze.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize analyzer: %w", err)
@@ -171,17 +198,17 @@ func runAnalyzePerformance(service, metric, timeRange, format string) error {
 	spinner := utils.NewSpinner("Analyzing performance metrics...")
 	spinner.Start()
 
-	analysis, err := analyzer.AnalyzePerformance(options)
+	analysis, err := analyzer.AnalyzePerformance(cmd.Context(), options)
 	spinner.Stop()
 
 	if err != nil {
 		return fmt.Errorf("failed to analyze performance: %w", err)
 	}
 
-	return utils.DisplayResponse(analysis, format)
+	return displayAnalysis(analysis, format)
 }
 
-func runAnalyzeCosts(period, service string, recommendations bool, format string) error {
+func runAnalyzeCosts(cmd *cobra.Command, period, service string, recommendations bool, format string) error {
 	analyzer, err := analyze.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize analyzer: %w", err)
@@ -196,17 +223,17 @@ func runAnalyzeCosts(period, service string, recommendations bool, format string
 	spinner := utils.NewSpinner("Analyzing costs...")
 	spinner.Start()
 
-	analysis, err := analyzer.AnalyzeCosts(options)
+	analysis, err := analyzer.AnalyzeCosts(cmd.Context(), options)
 	spinner.Stop()
 
 	if err != nil {
 		return fmt.Errorf("failed to analyze costs: %w", err)
 	}
 
-	return utils.DisplayResponse(analysis, format)
+	return displayAnalysis(analysis, format)
 }
 
-func runAnalyzeSecurity(target string, deep bool, format string) error {
+func runAnalyzeSecurity(cmd *cobra.Command, target string, deep bool, format string) error {
 	analyzer, err := analyze.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize analyzer: %w", err)
@@ -220,17 +247,17 @@ func runAnalyzeSecurity(target string, deep bool, format string) error {
 	spinner := utils.NewSpinner("Analyzing security...")
 	spinner.Start()
 
-	analysis, err := analyzer.AnalyzeSecurity(options)
+	analysis, err := analyzer.AnalyzeSecurity(cmd.Context(), options)
 	spinner.Stop()
 
 	if err != nil {
 		return fmt.Errorf("failed to analyze security: %w", err)
 	}
 
-	return utils.DisplayResponse(analysis, format)
+	return displayAnalysis(analysis, format)
 }
 
-func runAnalyzeCapacity(service, forecast, format string) error {
+func runAnalyzeCapacity(cmd *cobra.Command, service, forecast, history, format string) error {
 	analyzer, err := analyze.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize analyzer: %w", err)
@@ -239,17 +266,34 @@ func runAnalyzeCapacity(service, forecast, format string) error {
 	options := analyze.CapacityOptions{
 		Service:  service,
 		Forecast: forecast,
+		History:  history,
 	}
 
 	spinner := utils.NewSpinner("Analyzing capacity...")
 	spinner.Start()
 
-	analysis, err := analyzer.AnalyzeCapacity(options)
+	analysis, err := analyzer.AnalyzeCapacity(cmd.Context(), options)
 	spinner.Stop()
 
 	if err != nil {
 		return fmt.Errorf("failed to analyze capacity: %w", err)
 	}
 
-	return utils.DisplayResponse(analysis, format)
+	return displayAnalysis(analysis, format)
+}
+
+// displayAnalysis prints machine-readable formats via analyze.FormatAnalysis
+// and anything else through the shared output renderers
+func displayAnalysis(analysis interface{}, format string) error {
+	switch strings.ToLower(format) {
+	case "json", "yaml", "csv", "table":
+		out, err := analyze.FormatAnalysis(analysis, format)
+		if err != nil {
+			return err
+		}
+		fmt.Print(out)
+		return nil
+	default:
+		return utils.DisplayResponse(analysis, format)
+	}
 }
@@ -2,11 +2,20 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
 
 	"github.com/AlloraAi/AlloraCLI/pkg/agents"
 	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/monitor"
+	"github.com/AlloraAi/AlloraCLI/pkg/troubleshoot"
+	"github.com/AlloraAi/AlloraCLI/pkg/ui"
 	"github.com/AlloraAi/AlloraCLI/pkg/utils"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -14,6 +23,15 @@ func newAskCmd() *cobra.Command {
 	var agentName string
 	var format string
 	var interactive bool
+	var stream bool
+	var files []string
+	var noCache bool
+	var showUsage bool
+	var tools bool
+	var docsDir string
+	var docsTopK int
+	var templateName string
+	var templateVars []string
 
 	cmd := &cobra.Command{
 		Use:   "ask [query]",
@@ -21,20 +39,52 @@ func newAskCmd() *cobra.Command {
 		Long: `Ask natural language questions to AI agents about your IT infrastructure.
 The agent will analyze your query and provide intelligent responses, suggestions,
 and actionable insights based on your infrastructure context.`,
-		Args: cobra.MinimumNArgs(1),
+		Annotations: map[string]string{longRunningAnnotation: "interactive"},
+		Args: func(cmd *cobra.Command, args []string) error {
+			// A template is the question, so it needs no arguments
+			if templateName != "" {
+				return nil
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runAsk(args, agentName, format, interactive)
+			if templateName != "" {
+				question, err := renderAskTemplate(templateName, templateVars, args)
+				if err != nil {
+					return err
+				}
+				args = []string{question}
+			} else if len(templateVars) > 0 {
+				return fmt.Errorf("--var needs --template")
+			}
+			return runAsk(cmd.Context(), args, agentName, format, interactive, stream, noCache, showUsage, tools, files, docsDir, docsTopK)
 		},
 	}
 
 	cmd.Flags().StringVarP(&agentName, "agent", "a", "", "specific agent to use (default: first available)")
 	cmd.Flags().StringVarP(&format, "format", "f", "text", "output format (text, json, yaml)")
 	cmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "interactive mode for follow-up questions")
+	cmd.Flags().BoolVar(&stream, "stream", false, "print the response as it is generated (text format only)")
+	cmd.Flags().StringArrayVar(&files, "file", nil, "attach a text file to the question (repeatable)")
+	cmd.Flags().BoolVar(&noCache, "no-cache", false, "ask the model again instead of reusing a cached response")
+	cmd.Flags().BoolVar(&showUsage, "show-usage", false, "print the tokens used and their estimated cost")
+	cmd.Flags().BoolVar(&tools, "tools", false, "let the agent run read-only AlloraCLI commands, such as monitor status, to answer")
+	cmd.Flags().StringVar(&docsDir, "docs", "", "ground answers in the markdown docs, such as runbooks, under this directory")
+	cmd.Flags().IntVar(&docsTopK, "docs-top-k", agents.DefaultDocsTopK, "number of doc excerpts added to each question")
+	cmd.Flags().StringVar(&templateName, "template", "", "ask with a prompt template, such as incident-triage; arguments are added after it")
+	cmd.Flags().StringArrayVar(&templateVars, "var", nil, "set a template variable as key=value (repeatable)")
 
 	return cmd
 }
 
-func runAsk(args []string, agentName, format string, interactive bool) error {
+func runAsk(ctx context.Context, args []string, agentName, format string, interactive, stream, noCache, showUsage, tools bool, files []string, docsDir string, docsTopK int) error {
+	if stream && format != "text" {
+		return fmt.Errorf("--stream only supports the text format, got %q", format)
+	}
+	if docsTopK <= 0 {
+		return fmt.Errorf("--docs-top-k must be greater than 0, got %d", docsTopK)
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -63,34 +113,252 @@ func runAsk(args []string, agentName, format string, interactive bool) error {
 		}
 	}
 
+	// Read attached files before contacting the agent
+	queryContext := make(map[string]interface{})
+	if len(files) > 0 {
+		attached, err := agents.LoadFileContext(files, agents.DefaultFileTokenBudget)
+		if err != nil {
+			return err
+		}
+		queryContext[agents.FilesContextKey] = attached
+	}
+	if noCache {
+		queryContext[agents.NoCacheContextKey] = true
+	}
+
 	// Initialize agent
 	aiAgent, err := agents.NewAgent(selectedAgent)
 	if err != nil {
 		return fmt.Errorf("failed to initialize agent: %w", err)
 	}
 
+	session := newAskSession(aiAgent, agentName, format, showUsage)
+	if tools {
+		session.tools = askTools
+	}
+	if docsDir != "" {
+		docs, err := loadAskDocs(ctx, selectedAgent, docsDir)
+		if err != nil {
+			return err
+		}
+		session.docs, session.docsTopK = docs, docsTopK
+	}
+
 	// Join all arguments into a single query
 	query := utils.JoinArgs(args)
 
 	if interactive {
-		return runInteractiveAsk(aiAgent, query, format)
+		return runInteractiveAsk(ctx, session, query, queryContext, format, stream)
+	}
+
+	return runSingleAsk(ctx, session, query, queryContext, format, stream)
+}
+
+// renderAskTemplate renders the question of ask --template, adding any
+// arguments after it
+func renderAskTemplate(name string, pairs, args []string) (string, error) {
+	vars, err := agents.ParseTemplateVars(pairs)
+	if err != nil {
+		return "", err
+	}
+
+	dir := ""
+	if configDir, err := config.GetConfigDir(); err == nil {
+		dir = filepath.Join(configDir, agents.TemplatesDirName)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return "", fmt.Errorf("failed to load configuration: %w", err)
+	}
+	templates, err := agents.LoadPromptTemplates(cfg.Templates, dir)
+	if err != nil {
+		return "", err
+	}
+
+	question, err := templates.RenderTemplate(name, vars)
+	if err != nil {
+		return "", err
+	}
+	if extra := utils.JoinArgs(args); extra != "" {
+		question += "\n\n" + extra
+	}
+	return question, nil
+}
+
+// askSession is the agent answering ask queries and the usage they add up to
+type askSession struct {
+	agent     agents.Agent
+	name      string
+	manager   *agents.AgentManager
+	format    string
+	showUsage bool
+	// tools are the commands the agent may run to answer; none by default
+	tools []askTool
+	// docs are searched for excerpts to add to each question when set
+	docs     *agents.KnowledgeBase
+	docsTopK int
+}
+
+// newAskSession creates a session whose usage is also appended to the usage log
+func newAskSession(agent agents.Agent, name, format string, showUsage bool) *askSession {
+	manager := agents.NewAgentManager()
+	if configDir, err := config.GetConfigDir(); err == nil {
+		manager.SetUsageLog(agents.NewUsageLog(agents.DefaultUsageLogPath(configDir)))
 	}
+	return &askSession{agent: agent, name: name, manager: manager, format: format, showUsage: showUsage}
+}
 
-	return runSingleAsk(aiAgent, query, format)
+// query builds the agent query for a question, offering the session's tools
+// and adding the excerpts of its docs that best match the question
+func (s *askSession) query(ctx context.Context, text string, queryContext map[string]interface{}) *agents.Query {
+	query := &agents.Query{
+		Text:    text,
+		Context: queryContext,
+	}
+	if s.docs != nil {
+		excerpts, err := s.docs.Search(ctx, text, s.docsTopK)
+		if err != nil {
+			utils.LogWarning(fmt.Sprintf("Answering without docs: %v", err))
+		} else if len(excerpts) > 0 {
+			query.Context = maps.Clone(queryContext)
+			query.Context[agents.DocsContextKey] = excerpts
+		}
+	}
+	if len(s.tools) > 0 {
+		for _, tool := range s.tools {
+			query.Tools = append(query.Tools, tool.Tool)
+		}
+		query.ExecuteTool = s.executeTool
+	}
+	return query
 }
 
-func runSingleAsk(agent agents.Agent, query, format string) error {
+// executeTool runs the command mapped to a tool call, returning its result as JSON
+func (s *askSession) executeTool(ctx context.Context, call agents.ToolCall) (string, error) {
+	for _, tool := range s.tools {
+		if tool.Name != call.Name {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "🔧 Running %s\n", tool.Command)
+		result, err := tool.run(ctx, call.Arguments)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(result)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode %s result: %w", tool.Name, err)
+		}
+		if len(data) > maxToolResultBytes {
+			data = append(data[:maxToolResultBytes], "...(truncated)"...)
+		}
+		return string(data), nil
+	}
+	return "", fmt.Errorf("unknown tool %s", call.Name)
+}
+
+// maxToolResultBytes bounds the tool output sent back to the model
+const maxToolResultBytes = 16 * 1024
+
+// askTool is an AlloraCLI command an agent may call as a tool
+type askTool struct {
+	agents.Tool
+	// Command is the equivalent allora command, shown when the tool runs
+	Command string
+	run     func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+}
+
+// askTools are the read-only commands ask --tools offers to agents
+var askTools = []askTool{
+	{
+		Tool: agents.Tool{
+			Name:        "monitor_status",
+			Description: "Get the current system status: CPU, memory and disk usage, services and active alerts.",
+			Parameters:  json.RawMessage(`{"type":"object","properties":{}}`),
+		},
+		Command: "allora monitor status",
+		run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			mon, err := monitor.New()
+			if err != nil {
+				return nil, fmt.Errorf("failed to initialize monitor: %w", err)
+			}
+			return mon.GetSystemStatus()
+		},
+	},
+	{
+		Tool: agents.Tool{
+			Name:        "troubleshoot_diagnose",
+			Description: "Run connectivity and disk diagnostics against a target: a local path, host, host:port or URL.",
+			Parameters: json.RawMessage(`{"type":"object","properties":{` +
+				`"target":{"type":"string","description":"path, host, host:port or http(s) URL"},` +
+				`"deep":{"type":"boolean","description":"also sample connection latency"}},"required":["target"]}`),
+		},
+		Command: "allora troubleshoot diagnose",
+		run: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			target, _ := args["target"].(string)
+			deep, _ := args["deep"].(bool)
+			ts, err := troubleshoot.New()
+			if err != nil {
+				return nil, fmt.Errorf("failed to initialize troubleshooter: %w", err)
+			}
+			return ts.RunDiagnostics(ctx, troubleshoot.DiagnosticOptions{Target: target, Deep: deep})
+		},
+	},
+}
+
+// record adds the usage of a response to the session, printing it with --show-usage
+func (s *askSession) record(response *agents.Response) {
+	s.manager.RecordUsage(s.name, response)
+	if !s.showUsage {
+		return
+	}
+	usage, ok := agents.ResponseUsage(s.name, response)
+	if !ok {
+		s.printUsage("Usage: this agent does not report token usage")
+		return
+	}
+	if usage.Cached {
+		s.printUsage("Usage: answered from the response cache, no tokens used")
+		return
+	}
+	line := fmt.Sprintf("Usage: %d prompt + %d completion tokens", usage.PromptTokens, usage.CompletionTokens)
+	if cost, ok := agents.EstimateCost(usage.Model, usage.PromptTokens, usage.CompletionTokens); ok {
+		line += fmt.Sprintf(", estimated $%.4f (%s)", cost, usage.Model)
+	}
+	s.printUsage(line)
+}
+
+// printTotal prints the usage of every query of the session, with --show-usage
+func (s *askSession) printTotal() {
+	if !s.showUsage {
+		return
+	}
+	stats := s.manager.UsageReport()
+	s.printUsage(fmt.Sprintf("Session usage: %d queries, %d tokens, estimated $%.4f", stats.Queries, stats.TotalTokens, stats.EstimatedCost))
+}
+
+// printUsage prints a usage line after text output, and to stderr otherwise
+// so structured output stays parseable
+func (s *askSession) printUsage(line string) {
+	if s.format == "text" {
+		fmt.Println()
+		fmt.Println(line)
+		return
+	}
+	fmt.Fprintln(os.Stderr, line)
+}
+
+func runSingleAsk(ctx context.Context, session *askSession, query string, queryContext map[string]interface{}, format string, stream bool) error {
+	if stream {
+		return runStreamAsk(ctx, session, query, queryContext)
+	}
+
 	// Show spinner while processing
 	spinner := utils.NewSpinner("Processing your question...")
 	spinner.Start()
 
 	// Process the query
-	ctx := context.Background()
-	agentQuery := &agents.Query{
-		Text:    query,
-		Context: make(map[string]interface{}),
-	}
-	response, err := agent.Query(ctx, agentQuery)
+	agentQuery := session.query(ctx, query, queryContext)
+	response, err := session.agent.Query(ctx, agentQuery)
 	spinner.Stop()
 
 	if err != nil {
@@ -98,17 +366,134 @@ func runSingleAsk(agent agents.Agent, query, format string) error {
 	}
 
 	// Format and display response
-	return utils.DisplayResponse(response, format)
+	if err := utils.DisplayResponse(response, format); err != nil {
+		return err
+	}
+
+	if format == "text" {
+		printReferences(response.References)
+		printDocSources(agentQuery)
+	}
+	session.record(response)
+
+	return nil
 }
 
-func runInteractiveAsk(agent agents.Agent, initialQuery, format string) error {
+// runStreamAsk prints the agent's response to stdout as it is generated,
+// stopping cleanly when ctx is cancelled
+func runStreamAsk(ctx context.Context, session *askSession, query string, queryContext map[string]interface{}) error {
+	ui.InfoColor.Print("🤖 AlloraAi: ")
+
+	agentQuery := session.query(ctx, query, queryContext)
+	response, err := agents.StreamQuery(ctx, session.agent, agentQuery, func(delta string) {
+		fmt.Print(delta)
+	})
+	fmt.Println()
+
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return fmt.Errorf("failed to process query: %w", err)
+	}
+
+	printReferences(response.References)
+	printDocSources(agentQuery)
+	session.record(response)
+	return nil
+}
+
+// printReferences renders a footer listing commands and links mentioned in a response
+func printReferences(refs *agents.References) {
+	if refs == nil || (len(refs.Commands) == 0 && len(refs.URLs) == 0) {
+		return
+	}
+
+	if len(refs.Commands) > 0 {
+		fmt.Println()
+		fmt.Println("Commands mentioned:")
+		for i, cmd := range refs.Commands {
+			fmt.Printf("  %d. %s\n", i+1, cmd.Command)
+		}
+	}
+
+	if len(refs.URLs) > 0 {
+		fmt.Println()
+		fmt.Println("References:")
+		for _, url := range refs.URLs {
+			fmt.Printf("  • %s\n", url)
+		}
+	}
+}
+
+// loadAskDocs indexes the markdown docs under dir for ask --docs. It
+// returns nil, answering without docs, when no doc could be indexed, such as
+// when the agent has no embeddings endpoint
+func loadAskDocs(ctx context.Context, agentConfig config.Agent, dir string) (*agents.KnowledgeBase, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read docs directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("--docs must be a directory, got %s", dir)
+	}
+
+	embedder, err := agents.NewEmbedder(agentConfig)
+	if err != nil {
+		utils.LogWarning(fmt.Sprintf("Answering without docs: %v", err))
+		return nil, nil
+	}
+	indexPath := ""
+	if configDir, err := config.GetConfigDir(); err == nil {
+		indexPath = agents.DefaultKnowledgeIndexPath(configDir, dir)
+	}
+	docs := agents.NewKnowledgeBase(embedder, indexPath)
+
+	spinner := utils.NewSpinner("Indexing docs...")
+	spinner.Start()
+	stats, err := docs.IngestDir(ctx, dir)
+	spinner.Stop()
+
+	if err != nil {
+		utils.LogWarning(fmt.Sprintf("Answering without docs: %v", err))
+		if docs.Len() == 0 {
+			return nil, nil
+		}
+	}
+	if docs.Len() == 0 {
+		utils.LogWarning(fmt.Sprintf("No markdown docs found in %s; answering without them", dir))
+		return nil, nil
+	}
+	logrus.Debugf("Indexed %d docs from %s into %d chunks, embedding %d", stats.Files, dir, stats.Chunks, stats.Embedded)
+	return docs, nil
+}
+
+// printDocSources lists the docs whose excerpts were added to a query
+func printDocSources(query *agents.Query) {
+	excerpts, _ := query.Context[agents.DocsContextKey].([]agents.DocChunk)
+	if len(excerpts) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("Docs consulted:")
+	for _, excerpt := range excerpts {
+		label := excerpt.Source
+		if excerpt.Heading != "" {
+			label += " > " + excerpt.Heading
+		}
+		fmt.Printf("  • %s (%.2f)\n", label, excerpt.Score)
+	}
+}
+
+func runInteractiveAsk(ctx context.Context, session *askSession, initialQuery string, queryContext map[string]interface{}, format string, stream bool) error {
 	fmt.Println("🤖 Interactive mode - Type 'exit' to quit, 'help' for commands")
 	fmt.Println()
 
 	// Process initial query if provided
 	if initialQuery != "" {
 		fmt.Printf("You: %s\n", initialQuery)
-		if err := runSingleAsk(agent, initialQuery, format); err != nil {
+		if err := runSingleAsk(ctx, session, initialQuery, queryContext, format, stream); err != nil {
 			return err
 		}
 		fmt.Println()
@@ -124,6 +509,7 @@ func runInteractiveAsk(agent agents.Agent, initialQuery, format string) error {
 		// Handle special commands
 		switch query {
 		case "exit", "quit":
+			session.printTotal()
 			fmt.Println("👋 Goodbye!")
 			return nil
 		case "help":
@@ -135,9 +521,12 @@ func runInteractiveAsk(agent agents.Agent, initialQuery, format string) error {
 		}
 
 		// Process the query
-		if err := runSingleAsk(agent, query, format); err != nil {
+		if err := runSingleAsk(ctx, session, query, queryContext, format, stream); err != nil {
 			fmt.Printf("Error: %v\n", err)
 		}
+		if ctx.Err() != nil {
+			return nil
+		}
 		fmt.Println()
 	}
 }
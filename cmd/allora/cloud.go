@@ -2,12 +2,18 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/AlloraAi/AlloraCLI/pkg/cloud"
 	"github.com/AlloraAi/AlloraCLI/pkg/config"
 	"github.com/AlloraAi/AlloraCLI/pkg/utils"
+	"github.com/prometheus/common/model"
 	"github.com/spf13/cobra"
 )
 
@@ -19,10 +25,14 @@ func newCloudCmd() *cobra.Command {
 	}
 
 	cmd.AddCommand(newCloudResourcesCmd())
+	cmd.AddCommand(newCloudDeleteCmd())
 	cmd.AddCommand(newCloudCostsCmd())
 	cmd.AddCommand(newCloudOptimizeCmd())
 	cmd.AddCommand(newCloudMigrateCmd())
 	cmd.AddCommand(newCloudBackupCmd())
+	cmd.AddCommand(newCloudExportCmd())
+	cmd.AddCommand(newCloudWhoamiCmd())
+	cmd.AddCommand(newCloudDriftCmd())
 
 	return cmd
 }
@@ -31,18 +41,56 @@ func newCloudResourcesCmd() *cobra.Command {
 	var provider string
 	var resourceType string
 	var format string
+	var max int
+	var tags map[string]string
+	var all bool
+	var noCache bool
 
 	cmd := &cobra.Command{
-		Use:   "resources",
-		Short: "Manage cloud resources",
+		Use:     "resources",
+		Aliases: []string{"list"},
+		Short:   "Manage cloud resources",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runCloudResources(provider, resourceType, format)
+			if all && provider != "" {
+				return fmt.Errorf("--all lists every configured provider and cannot be combined with --provider")
+			}
+			return runCloudResources(cmd.Context(), provider, resourceType, format, max, tags, all, noCache)
 		},
 	}
 
 	cmd.Flags().StringVarP(&provider, "provider", "p", "", "cloud provider (aws, azure, gcp)")
+	cmd.Flags().BoolVar(&all, "all", false, "list resources from every configured provider")
+	cmd.Flags().BoolVar(&noCache, "no-cache", false, "ignore cached listings and query the provider")
 	cmd.Flags().StringVarP(&resourceType, "type", "t", "", "resource type (ec2, s3, rds, etc.)")
-	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table, json, yaml)")
+	cmd.Flags().IntVar(&max, "max", 0, "maximum number of resources to return (0 for no limit)")
+	cmd.Flags().StringToStringVar(&tags, "tag", nil, "only list resources with this tag (Key=Value, repeatable)")
+	addOutputFlag(cmd, &format, "table", "json", "yaml", "csv", "ndjson")
+
+	return cmd
+}
+
+func newCloudDeleteCmd() *cobra.Command {
+	var provider string
+	var confirm bool
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "delete [resource-id]",
+		Short: "Delete a cloud resource",
+		Long: `Delete a cloud resource. Deletion is refused when other active resources depend on it.
+With --dry-run the dependencies are checked but nothing is deleted.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeResourceIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCloudDelete(cmd.Context(), provider, args[0], confirm, dryRun)
+		},
+	}
+
+	cmd.Flags().StringVarP(&provider, "provider", "p", "", "cloud provider (aws, azure, gcp)")
+	cmd.Flags().BoolVarP(&confirm, "confirm", "y", false, "skip confirmation prompts")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "show what would be deleted without making changes")
+	cmd.MarkFlagRequired("provider")
+	cmd.RegisterFlagCompletionFunc("provider", completeProviders)
 
 	return cmd
 }
@@ -51,20 +99,29 @@ func newCloudCostsCmd() *cobra.Command {
 	var provider string
 	var period string
 	var breakdown bool
+	var budget float64
 	var format string
+	var emails []string
 
 	cmd := &cobra.Command{
-		Use:   "costs",
-		Short: "Analyze cloud costs",
+		Use:     "costs",
+		Aliases: []string{"cost"},
+		Short:   "Analyze cloud costs",
+		Long: `Analyze cloud costs. With --budget, also report how much of the
+budget is used and the projected end-of-period spend, and recommend action
+first when spending is over budget. With --email, the analysis is also mailed
+through the SMTP server in notifications.email.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runCloudCosts(provider, period, breakdown, format)
+			return runCloudCosts(cmd.Context(), provider, period, breakdown, budget, format, emails)
 		},
 	}
 
 	cmd.Flags().StringVarP(&provider, "provider", "p", "", "cloud provider (aws, azure, gcp)")
 	cmd.Flags().StringVarP(&period, "period", "d", "30d", "analysis period (e.g., 7d, 30d, 90d)")
 	cmd.Flags().BoolVarP(&breakdown, "breakdown", "b", false, "show cost breakdown by service")
+	cmd.Flags().Float64Var(&budget, "budget", 0, "spend budget for the period to compare costs with")
 	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table, json, yaml)")
+	addEmailFlag(cmd, &emails)
 
 	return cmd
 }
@@ -73,19 +130,27 @@ func newCloudOptimizeCmd() *cobra.Command {
 	var provider string
 	var resourceType string
 	var autoApply bool
+	var lookback string
+	var threshold float64
 	var format string
+	var dryRun bool
 
 	cmd := &cobra.Command{
 		Use:   "optimize",
 		Short: "Optimize cloud resources with AI recommendations",
+		Long: `Recommend smaller instance types for instances whose CPU and memory
+utilization stayed below the threshold for the whole lookback window.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runCloudOptimize(provider, resourceType, autoApply, format)
+			return runCloudOptimize(cmd.Context(), provider, resourceType, autoApply, dryRun, lookback, threshold, format)
 		},
 	}
 
 	cmd.Flags().StringVarP(&provider, "provider", "p", "", "cloud provider (aws, azure, gcp)")
 	cmd.Flags().StringVarP(&resourceType, "type", "t", "", "resource type to optimize")
 	cmd.Flags().BoolVarP(&autoApply, "auto-apply", "a", false, "automatically apply optimization recommendations")
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "show the actions --auto-apply would take without making changes")
+	cmd.Flags().StringVar(&lookback, "lookback", "14d", "utilization window to examine (e.g., 7d, 14d, 30d)")
+	cmd.Flags().Float64Var(&threshold, "threshold", cloud.DefaultOptimizeThreshold, "utilization percentage below which an instance is oversized")
 	cmd.Flags().StringVarP(&format, "format", "f", "text", "output format (text, json, yaml)")
 
 	return cmd
@@ -135,43 +200,466 @@ func newCloudBackupCmd() *cobra.Command {
 	return cmd
 }
 
+func newCloudExportCmd() *cobra.Command {
+	var provider string
+	var resourceType string
+	var format string
+	var out string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the resource inventory to CSV or JSON",
+		Long: `Export resources with their ID, name, type, provider, region, state, tags
+and monthly cost. Without --provider, every configured provider is exported.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCloudExport(cmd.Context(), provider, resourceType, format, out)
+		},
+	}
+
+	cmd.Flags().StringVarP(&provider, "provider", "p", "", "cloud provider (aws, azure, gcp); all configured providers if empty")
+	cmd.Flags().StringVarP(&resourceType, "type", "t", "", "resource type (ec2, s3, rds, etc.)")
+	cmd.Flags().StringVarP(&format, "format", "f", "csv", "export format ("+strings.Join(cloud.ExportFormats, ", ")+")")
+	cmd.Flags().StringVar(&out, "out", "-", "file to write the export to, - for stdout")
+
+	return cmd
+}
+
 // Implementation functions
-func runCloudResources(provider, resourceType, format string) error {
+func newCloudWhoamiCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "whoami",
+		Short: "Show the account and identity used for each provider",
+		Long: `Show the account each configured provider acts on and the identity it
+acts as: the AWS account and caller ARN, the Azure subscription, tenant and
+signed-in user or application, and the GCP project and account email. Check
+it before changing anything to make sure you are in the right account.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCloudWhoami(cmd.Context(), format)
+		},
+	}
+
+	addOutputFlag(cmd, &format, "table", "json", "yaml")
+
+	return cmd
+}
+
+func runCloudResources(ctx context.Context, provider, resourceType, format string, max int, tags map[string]string, all, noCache bool) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
 	cloudService := cloud.NewCloudService(cfg)
-	ctx := context.Background()
 
+	opts := []cloud.ListOption{cloud.WithMax(max), cloud.WithTags(tags)}
+	if noCache {
+		opts = append(opts, cloud.WithNoCache())
+	}
+
+	resources, err := fetchCloudResources(ctx, cloudService, provider, resourceType, all, opts...)
+	if err != nil {
+		return err
+	}
+
+	if all {
+		return utils.DisplayResponse(providerResourceTable(resources), format)
+	}
+	return utils.DisplayResponse(resourceTable(resources), format)
+}
+
+// fetchCloudResources lists resources from provider, or from every
+// configured provider when all is set. Providers that fail while others
+// answer are reported as a warning
+func fetchCloudResources(ctx context.Context, cloudService cloud.CloudService, provider, resourceType string, all bool, opts ...cloud.ListOption) ([]cloud.Resource, error) {
 	spinner := utils.NewSpinner("Fetching cloud resources...")
 	spinner.Start()
 
-	resources, err := cloudService.ListResources(ctx, provider, resourceType)
+	if all {
+		resources, err := cloudService.ListAllResources(ctx, resourceType, opts...)
+		spinner.Stop()
+
+		if err != nil {
+			if len(resources) == 0 {
+				return nil, fmt.Errorf("failed to list cloud resources: %w", err)
+			}
+			// Show what the reachable providers returned and report the rest
+			utils.LogWarning(err.Error())
+		}
+		return resources, nil
+	}
+
+	resources, err := cloudService.ListResources(ctx, provider, resourceType, opts...)
+	spinner.Stop()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cloud resources: %w", err)
+	}
+
+	for i := range resources {
+		if resources[i].Provider == "" {
+			resources[i].Provider = provider
+		}
+	}
+	return resources, nil
+}
+
+func runCloudExport(ctx context.Context, provider, resourceType, format, out string) error {
+	if !slices.Contains(cloud.ExportFormats, format) {
+		return fmt.Errorf("unsupported export format %q; use one of %s", format, strings.Join(cloud.ExportFormats, ", "))
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	cloudService := cloud.NewCloudService(cfg)
+
+	resources, err := fetchCloudResources(ctx, cloudService, provider, resourceType, provider == "")
+	if err != nil {
+		return err
+	}
+
+	if out == "-" {
+		return cloud.ExportResources(resources, format, os.Stdout)
+	}
+
+	file, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	if err := cloud.ExportResources(resources, format, file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	fmt.Printf("Exported %d resources to %s\n", len(resources), out)
+	return nil
+}
+
+func newCloudDriftCmd() *cobra.Command {
+	var provider string
+	var desired string
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "drift",
+		Short: "Detect drift between declared and live resources",
+		Long: `Compare the declared state of resources with their live state to catch
+changes made outside of automation, such as in the console.
+
+--desired is a YAML or JSON file holding a list of resources, or a resources
+key with the list: a template declaring the attributes that matter, or a
+snapshot saved with allora cloud resources -o json. Resources are matched by
+id, or by type and name when no id is declared, and only the attributes a
+resource declares are compared, except that declared tags must match
+exactly. Live resources of the declared types that are not declared are
+reported as added.
+
+The command fails if any resource has drifted, so it can gate CI jobs.`,
+		Example: `  allora cloud resources -p aws -t ec2 -o json > snapshot.json
+  allora cloud drift -p aws --desired snapshot.json
+  allora cloud drift -p aws --desired desired.yaml -o json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCloudDrift(cmd.Context(), provider, desired, format)
+		},
+	}
+
+	cmd.Flags().StringVarP(&provider, "provider", "p", "", "cloud provider (aws, azure, gcp)")
+	cmd.Flags().StringVarP(&desired, "desired", "d", "", "YAML or JSON file of the declared resources")
+	cmd.MarkFlagRequired("provider")
+	cmd.MarkFlagRequired("desired")
+	addOutputFlag(cmd, &format, "table", "json", "yaml")
+
+	return cmd
+}
+
+func runCloudDrift(ctx context.Context, provider, desiredPath, format string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	desired, err := cloud.LoadDesiredState(desiredPath)
+	if err != nil {
+		return err
+	}
+
+	spinner := utils.NewSpinner("Comparing live resources...")
+	spinner.Start()
+	report, err := cloud.NewCloudService(cfg).DetectDrift(ctx, provider, desired)
+	spinner.Stop()
+
+	if err != nil {
+		return fmt.Errorf("failed to detect drift: %w", err)
+	}
+
+	if format == "table" {
+		if report.Drifted {
+			if err := utils.DisplayResponse(driftTable{report}, "table"); err != nil {
+				return err
+			}
+		}
+		summary := report.Summary
+		fmt.Printf("%d declared resources: %d in sync, %d changed, %d removed; %d live resources not declared\n",
+			summary.Desired, summary.InSync, summary.Changed, summary.Removed, summary.Added)
+	} else if err := utils.DisplayResponse(report, format); err != nil {
+		return err
+	}
+
+	if report.Drifted {
+		return fmt.Errorf("drift detected in %d resources", report.Summary.Added+report.Summary.Removed+report.Summary.Changed)
+	}
+	return nil
+}
+
+// driftTable renders a drift report, one row per added or removed
+// resource and per changed attribute
+type driftTable struct {
+	*cloud.DriftReport
+}
+
+// TableHeaders returns the drift table columns
+func (t driftTable) TableHeaders() []string {
+	return []string{"ID", "Type", "Drift", "Attribute", "Desired", "Live"}
+}
+
+// TableRows returns the added and removed resources, then the changed attributes
+func (t driftTable) TableRows() [][]string {
+	report := t.DriftReport
+	var rows [][]string
+	for _, resource := range report.Added {
+		rows = append(rows, []string{resource.ID, resource.Type, "added", "", "", ""})
+	}
+	for _, resource := range report.Removed {
+		rows = append(rows, []string{resource.ID, resource.Type, "removed", "", "", ""})
+	}
+	for _, drift := range report.Changed {
+		for _, change := range drift.Changes {
+			rows = append(rows, []string{drift.ID, drift.Type, "changed", change.Attribute, driftValue(change.Desired), driftValue(change.Live)})
+		}
+	}
+	return rows
+}
+
+// driftValue formats an attribute value for the drift table, with - for a
+// missing value
+func driftValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "-"
+	case string:
+		return value
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(data)
+	}
+}
+
+func runCloudWhoami(ctx context.Context, format string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	spinner := utils.NewSpinner("Checking cloud identities...")
+	spinner.Start()
+	identities, err := cloud.NewCloudService(cfg).GetIdentities(ctx)
+	spinner.Stop()
+
+	if err != nil {
+		if len(identities) == 0 {
+			return fmt.Errorf("failed to get cloud identities: %w", err)
+		}
+		// Show the providers that answered and report the rest
+		utils.LogWarning(err.Error())
+	}
+	return utils.DisplayResponse(identityTable(identities), format)
+}
+
+// identityTable renders provider identities as table rows
+type identityTable []cloud.Identity
+
+// TableHeaders returns the identity table columns
+func (t identityTable) TableHeaders() []string {
+	return []string{"Provider", "Account", "Principal", "Tenant", "Region"}
+}
+
+// TableRows returns one row per provider
+func (t identityTable) TableRows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, identity := range t {
+		rows = append(rows, []string{identity.Provider, identity.Account, identity.Principal, identity.Tenant, identity.Region})
+	}
+	return rows
+}
+
+// providerResourceTable renders resources from several providers, naming the provider of each
+type providerResourceTable []cloud.Resource
+
+// TableHeaders returns the resource table columns with the provider first
+func (t providerResourceTable) TableHeaders() []string {
+	return append([]string{"Provider"}, resourceTable(t).TableHeaders()...)
+}
+
+// TableRows returns one row per resource
+func (t providerResourceTable) TableRows() [][]string {
+	rows := resourceTable(t).TableRows()
+	for i, row := range rows {
+		rows[i] = append([]string{t[i].Provider}, row...)
+	}
+	return rows
+}
+
+// resourceTable renders a resource list as table or csv rows
+type resourceTable []cloud.Resource
+
+// TableHeaders returns the resource table columns
+func (t resourceTable) TableHeaders() []string {
+	return []string{"ID", "Name", "Type", "Region", "State"}
+}
+
+// TableRows returns one row per resource
+func (t resourceTable) TableRows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, resource := range t {
+		rows = append(rows, []string{resource.ID, resource.Name, resource.Type, resource.Region, resource.State})
+	}
+	return rows
+}
+
+func runCloudDelete(ctx context.Context, provider, resourceID string, confirm, dryRun bool) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	cloudService := cloud.NewCloudService(cfg)
+	var plan *cloud.DryRun
+	if dryRun {
+		ctx, plan = cloud.WithDryRun(ctx)
+	}
+
+	spinner := utils.NewSpinner("Checking resource dependencies...")
+	spinner.Start()
+
+	dependents, err := cloudService.GetDependents(ctx, provider, resourceID)
+	spinner.Stop()
+
+	if err != nil {
+		return fmt.Errorf("failed to check dependencies: %w", err)
+	}
+
+	if len(dependents) > 0 {
+		printDependents(resourceID, dependents)
+		return fmt.Errorf("refusing to delete %s while it has active dependents", resourceID)
+	}
+
+	// Get confirmation if not auto-confirmed
+	if !confirm && !dryRun {
+		if !utils.ConfirmAction(fmt.Sprintf("Are you sure you want to delete %s?", resourceID)) {
+			fmt.Println("Deletion cancelled.")
+			return nil
+		}
+	}
+
+	spinner = utils.NewSpinner(fmt.Sprintf("Deleting %s...", resourceID))
+	spinner.Start()
+
+	err = cloudService.DeleteResource(ctx, provider, resourceID)
 	spinner.Stop()
 
+	var depErr *cloud.DependencyError
+	if errors.As(err, &depErr) {
+		printDependents(resourceID, depErr.Dependents)
+		return fmt.Errorf("refusing to delete %s while it has active dependents", resourceID)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to list cloud resources: %w", err)
+		return fmt.Errorf("failed to delete resource: %w", err)
 	}
 
-	return utils.DisplayResponse(resources, format)
+	if plan != nil {
+		return displayPlan(plan, "text")
+	}
+	fmt.Printf("✅ Resource %s deleted successfully!\n", resourceID)
+	return nil
 }
 
-func runCloudCosts(provider, period string, breakdown bool, format string) error {
+// displayPlan prints the actions planned by a dry run
+func displayPlan(plan *cloud.DryRun, format string) error {
+	actions := plan.Actions()
+	if format != "text" && format != "table" {
+		return utils.DisplayResponse(actions, format)
+	}
+
+	if len(actions) == 0 {
+		fmt.Println("Dry run: nothing would be changed")
+		return nil
+	}
+	fmt.Printf("Dry run: %d action(s) planned, nothing was changed\n\n", len(actions))
+	return utils.DisplayResponse(plannedActionTable(actions), "table")
+}
+
+// plannedActionTable renders planned actions as table rows
+type plannedActionTable []cloud.PlannedAction
+
+func (t plannedActionTable) TableHeaders() []string {
+	return []string{"Action", "Provider", "Resource", "Description"}
+}
+
+func (t plannedActionTable) TableRows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, action := range t {
+		resourceID := action.ResourceID
+		if resourceID == "" {
+			resourceID = "-"
+		}
+		rows = append(rows, []string{action.Action, action.Provider, resourceID, action.Description})
+	}
+	return rows
+}
+
+// printDependents lists the resources blocking a deletion
+func printDependents(resourceID string, dependents []*cloud.Resource) {
+	fmt.Printf("⚠️  %s is in use by %d resource(s):\n", resourceID, len(dependents))
+	for _, dep := range dependents {
+		fmt.Printf("  • %s (%s) %s - %s\n", dep.ID, dep.Type, dep.Name, dep.State)
+	}
+}
+
+func runCloudCosts(ctx context.Context, provider, period string, breakdown bool, budget float64, format string, emails []string) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	mailer, err := reportMailer(cfg, emails)
+	if err != nil {
+		return err
+	}
+
 	cloudService := cloud.NewCloudService(cfg)
-	ctx := context.Background()
 
+	lookback, err := model.ParseDuration(period)
+	if err != nil || lookback <= 0 {
+		return fmt.Errorf("invalid period %q: use a duration such as 7d, 30d or 90d", period)
+	}
+
+	now := time.Now()
 	options := cloud.CostOptions{
-		StartDate:   time.Now().Add(-30 * 24 * time.Hour), // Default 30 days
-		EndDate:     time.Now(),
+		StartDate:   now.Add(-time.Duration(lookback)),
+		EndDate:     now,
 		Granularity: "daily",
 		GroupBy:     []string{"service"},
+		Budget:      budget,
 	}
 
 	spinner := utils.NewSpinner("Analyzing cloud costs...")
@@ -184,22 +672,99 @@ func runCloudCosts(provider, period string, breakdown bool, format string) error
 		return fmt.Errorf("failed to analyze cloud costs: %w", err)
 	}
 
-	return utils.DisplayResponse(costs, format)
+	if err := displayCosts(costs, format); err != nil || mailer == nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("AlloraCLI cost analysis: %.2f %s (%s)", costs.TotalCost, costs.Currency, costs.Period)
+	return emailReport(ctx, mailer, subject, costs.Report(), emails)
 }
 
-func runCloudOptimize(provider, resourceType string, autoApply bool, format string) error {
+// displayCosts prints a cost analysis, summarizing the totals, budget and
+// recommendations around a breakdown table for the table format
+func displayCosts(costs *cloud.CostAnalysis, format string) error {
+	if format != "table" {
+		return utils.DisplayResponse(costs, format)
+	}
+
+	fmt.Printf("Total: %.2f %s (%s)\n", costs.TotalCost, costs.Currency, costs.Period)
+	if status := costs.BudgetStatus; status != nil {
+		state := "under budget"
+		if status.OverBudget {
+			state = "OVER BUDGET"
+		}
+		fmt.Printf("Budget: %.2f %s, %.1f%% used, %s\n", status.Budget, costs.Currency, status.PercentUsed, state)
+
+		projection := "within budget"
+		if status.ProjectedOverBudget {
+			projection = "over budget"
+		}
+		fmt.Printf("Projected end-of-period spend: %.2f %s (%s)\n", status.ProjectedSpend, costs.Currency, projection)
+	}
+	fmt.Println()
+
+	if err := utils.DisplayResponse(costTable(costs.Breakdown), format); err != nil {
+		return err
+	}
+
+	if len(costs.Recommendations) > 0 {
+		fmt.Println("\nRecommendations:")
+		for _, rec := range costs.Recommendations {
+			priority := ""
+			if rec.Priority != "" {
+				priority = fmt.Sprintf("[%s] ", strings.ToUpper(rec.Priority))
+			}
+			fmt.Printf("  • %s%s: %s (potential savings %.2f %s)\n", priority, rec.Title, rec.Description, rec.Savings, costs.Currency)
+		}
+	}
+	return nil
+}
+
+// costTable renders a cost breakdown as table rows
+type costTable []cloud.CostBreakdown
+
+// TableHeaders returns the cost breakdown columns
+func (t costTable) TableHeaders() []string {
+	return []string{"Category", "Cost", "Share", "Resources"}
+}
+
+// TableRows returns one row per cost category
+func (t costTable) TableRows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, category := range t {
+		rows = append(rows, []string{
+			category.Category,
+			fmt.Sprintf("%.2f", category.Cost),
+			fmt.Sprintf("%.1f%%", category.Percentage),
+			fmt.Sprint(category.ResourceCount),
+		})
+	}
+	return rows
+}
+
+func runCloudOptimize(ctx context.Context, provider, resourceType string, autoApply, dryRun bool, lookback string, threshold float64, format string) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
 	cloudService := cloud.NewCloudService(cfg)
-	ctx := context.Background()
+	var plan *cloud.DryRun
+	if dryRun {
+		ctx, plan = cloud.WithDryRun(ctx)
+	}
+
+	window, err := model.ParseDuration(lookback)
+	if err != nil || window <= 0 {
+		return fmt.Errorf("invalid lookback %q: use a duration such as 7d, 14d or 30d", lookback)
+	}
 
 	options := cloud.OptimizeOptions{
 		ResourceTypes: []string{resourceType},
 		Criteria:      []string{"cost", "performance"},
 		DryRun:        !autoApply,
+		Lookback:      time.Duration(window),
+		Threshold:     threshold,
 	}
 
 	spinner := utils.NewSpinner("Generating optimization recommendations...")
@@ -212,7 +777,74 @@ func runCloudOptimize(provider, resourceType string, autoApply bool, format stri
 		return fmt.Errorf("failed to optimize cloud resources: %w", err)
 	}
 
-	return utils.DisplayResponse(optimization, format)
+	if plan != nil {
+		return displayPlan(plan, format)
+	}
+	return displayOptimization(optimization, format)
+}
+
+// displayOptimization prints optimization results, summarizing the savings
+// and risk above a recommendation table for the text and table formats
+func displayOptimization(result *cloud.OptimizationResult, format string) error {
+	if format != "text" && format != "table" {
+		return utils.DisplayResponse(result, format)
+	}
+
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	if len(result.Recommendations) == 0 {
+		fmt.Println("No optimization opportunities found")
+		return nil
+	}
+
+	fmt.Printf("Potential savings: %.2f/month across %d recommendations (risk: %s, status: %s)\n",
+		result.PotentialSavings, len(result.Recommendations), result.RiskAssessment, result.Status)
+	if result.Status == "dry-run" {
+		fmt.Println("Dry run: nothing will be changed; use --auto-apply to queue the actions")
+	}
+	fmt.Println()
+
+	return utils.DisplayResponse(optimizationTable(result.Recommendations), "table")
+}
+
+// optimizationTable renders optimization recommendations as table rows
+type optimizationTable []cloud.OptimizationRecommendation
+
+func (t optimizationTable) TableHeaders() []string {
+	return []string{"Resource", "Type", "Current", "Recommended", "Savings/Month", "Confidence"}
+}
+
+func (t optimizationTable) TableRows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, rec := range t {
+		rows = append(rows, []string{
+			rec.ResourceID,
+			rec.Type,
+			describeSizing(rec.Current),
+			describeSizing(rec.Recommended),
+			fmt.Sprintf("%.2f", rec.Savings),
+			fmt.Sprintf("%.0f%%", rec.Confidence*100),
+		})
+	}
+	return rows
+}
+
+// describeSizing summarizes a recommendation's current or recommended
+// state: the instance type for rightsizing, the volume or its state for cleanup
+func describeSizing(sizing map[string]interface{}) string {
+	for _, key := range []string{"instance_type", "machine_type", "vm_size"} {
+		if size, ok := sizing[key].(string); ok {
+			return size
+		}
+	}
+	if volumeType, ok := sizing["volume_type"].(string); ok {
+		return fmt.Sprintf("%s %v GiB (%v)", volumeType, sizing["size"], sizing["state"])
+	}
+	if state, ok := sizing["state"].(string); ok {
+		return state
+	}
+	return "-"
 }
 
 func runCloudMigrate(source, target string, plan bool, format string) error {
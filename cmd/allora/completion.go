@@ -1,11 +1,23 @@
 package main
 
 import (
+	"context"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/AlloraAi/AlloraCLI/pkg/cloud"
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/monitor"
+	"github.com/AlloraAi/AlloraCLI/pkg/plugins"
 	"github.com/spf13/cobra"
 )
 
+// completionTimeout bounds each lookup made while completing a command line,
+// so a slow or unreachable service never stalls the shell
+const completionTimeout = 3 * time.Second
+
 func newCompletionCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "completion [bash|zsh|fish|powershell]",
@@ -43,6 +55,11 @@ PowerShell:
   # To load completions for every new session, run:
   PS> allora completion powershell > allora.ps1
   # and source this file from your PowerShell profile.
+
+Besides commands and flags, the scripts complete agent names, alert names,
+service names, plugin names and cloud resource IDs by asking the configured
+services. A lookup that fails or takes longer than a few seconds offers no
+suggestions.
 `,
 		DisableFlagsInUseLine: true,
 		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
@@ -64,3 +81,158 @@ PowerShell:
 
 	return cmd
 }
+
+// completeFirstArg completes the first positional argument with the names
+// returned by lookup. Any error or timeout yields no suggestions rather than an error message
+func completeFirstArg(lookup func(ctx context.Context, cmd *cobra.Command) ([]string, error)) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		parent := cmd.Context()
+		if parent == nil {
+			parent = context.Background()
+		}
+		ctx, cancel := context.WithTimeout(parent, completionTimeout)
+		defer cancel()
+
+		type result struct {
+			names []string
+			err   error
+		}
+		// Not every service honours ctx, so the lookup runs on its own goroutine
+		done := make(chan result, 1)
+		go func() {
+			names, err := lookup(ctx, cmd)
+			done <- result{names, err}
+		}()
+
+		var names []string
+		select {
+		case res := <-done:
+			if res.err != nil {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			names = res.names
+		case <-ctx.Done():
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		var matches []string
+		for _, name := range names {
+			if strings.HasPrefix(name, toComplete) {
+				matches = append(matches, name)
+			}
+		}
+		sort.Strings(matches)
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// withDescription formats a completion candidate that shells able to show
+// descriptions display next to the value
+func withDescription(value, description string) string {
+	if description == "" {
+		return value
+	}
+	return value + "\t" + description
+}
+
+// completeAgentNames completes the names of configured agents
+var completeAgentNames = completeFirstArg(func(ctx context.Context, cmd *cobra.Command) ([]string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(cfg.Agents))
+	for name, agent := range cfg.Agents {
+		names = append(names, withDescription(name, agent.Type))
+	}
+	return names, nil
+})
+
+// completeAlertNames completes the names of configured monitoring alerts
+var completeAlertNames = completeFirstArg(func(ctx context.Context, cmd *cobra.Command) ([]string, error) {
+	mon, err := monitor.New()
+	if err != nil {
+		return nil, err
+	}
+	alerts, err := mon.ListAlerts()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		names = append(names, withDescription(alert.RuleName, alert.Severity))
+	}
+	return names, nil
+})
+
+// completeServiceNames completes the names of monitored services
+var completeServiceNames = completeFirstArg(func(ctx context.Context, cmd *cobra.Command) ([]string, error) {
+	mon, err := monitor.New()
+	if err != nil {
+		return nil, err
+	}
+	services, err := mon.ListServices()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(services))
+	for _, service := range services {
+		names = append(names, withDescription(service.Name, service.Status))
+	}
+	return names, nil
+})
+
+// completePluginNames completes the names of installed plugins
+var completePluginNames = completeFirstArg(func(ctx context.Context, cmd *cobra.Command) ([]string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	pluginService, err := plugins.NewPluginService(cfg)
+	if err != nil {
+		return nil, err
+	}
+	installed, err := pluginService.ListPlugins(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(installed))
+	for _, plugin := range installed {
+		names = append(names, withDescription(plugin.Name, plugin.Description))
+	}
+	return names, nil
+})
+
+// completeResourceIDs completes cloud resource IDs from the provider named
+// by the command's --provider flag, or from every configured provider
+var completeResourceIDs = completeFirstArg(func(ctx context.Context, cmd *cobra.Command) ([]string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	provider, _ := cmd.Flags().GetString("provider")
+
+	cloudService := cloud.NewCloudService(cfg)
+	var resources []cloud.Resource
+	if provider == "" {
+		// A provider that fails still leaves the resources of the others
+		resources, _ = cloudService.ListAllResources(ctx, "")
+	} else if resources, err = cloudService.ListResources(ctx, provider, ""); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		ids = append(ids, withDescription(resource.ID, resource.Name))
+	}
+	return ids, nil
+})
+
+// completeProviders completes the --provider flag
+func completeProviders(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return []string{"aws", "azure", "gcp"}, cobra.ShellCompDirectiveNoFileComp
+}
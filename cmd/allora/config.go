@@ -2,8 +2,13 @@ package main
 
 import (
 	"fmt"
+	"strconv"
+	"time"
 
+	"github.com/AlloraAi/AlloraCLI/pkg/agents"
 	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/utils"
+	"github.com/prometheus/common/model"
 	"github.com/spf13/cobra"
 )
 
@@ -49,12 +54,13 @@ func newConfigAgentCmd() *cobra.Command {
 	cmd.AddCommand(newConfigAgentAddCmd())
 	cmd.AddCommand(newConfigAgentRemoveCmd())
 	cmd.AddCommand(newConfigAgentListCmd())
+	cmd.AddCommand(newConfigAgentUsageCmd())
 
 	return cmd
 }
 
 func newConfigAgentAddCmd() *cobra.Command {
-	var name, agentType, apiKey, model string
+	var name, agentType, provider, apiKey, model, endpoint string
 	var maxTokens int
 	var temperature float64
 
@@ -62,14 +68,16 @@ func newConfigAgentAddCmd() *cobra.Command {
 		Use:   "add",
 		Short: "Add a new AI agent",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runConfigAgentAdd(name, agentType, apiKey, model, maxTokens, temperature)
+			return runConfigAgentAdd(name, agentType, provider, apiKey, model, endpoint, maxTokens, temperature)
 		},
 	}
 
 	cmd.Flags().StringVarP(&name, "name", "n", "", "agent name (required)")
 	cmd.Flags().StringVarP(&agentType, "type", "t", "general", "agent type (general, aws, azure, gcp, kubernetes, monitoring)")
+	cmd.Flags().StringVar(&provider, "provider", "", "model backend (openai, ollama)")
 	cmd.Flags().StringVarP(&apiKey, "api-key", "k", "", "API key for the agent")
 	cmd.Flags().StringVarP(&model, "model", "m", "gpt-4", "AI model to use")
+	cmd.Flags().StringVar(&endpoint, "endpoint", "", "API endpoint of the model backend")
 	cmd.Flags().IntVar(&maxTokens, "max-tokens", 2048, "maximum tokens for responses")
 	cmd.Flags().Float64Var(&temperature, "temperature", 0.7, "response creativity (0.0-1.0)")
 
@@ -80,9 +88,10 @@ func newConfigAgentAddCmd() *cobra.Command {
 
 func newConfigAgentRemoveCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "remove [agent-name]",
-		Short: "Remove an AI agent",
-		Args:  cobra.ExactArgs(1),
+		Use:               "remove [agent-name]",
+		Short:             "Remove an AI agent",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeAgentNames,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runConfigAgentRemove(args[0])
 		},
@@ -103,6 +112,28 @@ func newConfigAgentListCmd() *cobra.Command {
 	return cmd
 }
 
+func newConfigAgentUsageCmd() *cobra.Command {
+	var since string
+	var clearUsage bool
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Show the tokens agents used and their estimated cost",
+		Long: `Show the tokens used by agent queries from allora ask and allora gemini,
+per model, with a cost estimated from OpenAI list prices.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigAgentUsage(since, clearUsage, format)
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "", "only count usage in this recent period, such as 24h or 30d")
+	cmd.Flags().BoolVar(&clearUsage, "clear", false, "delete all recorded usage")
+	addOutputFlag(cmd, &format, "table", "json", "yaml")
+
+	return cmd
+}
+
 func newConfigCloudCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "cloud",
@@ -270,7 +301,7 @@ func runConfigShow(format string) error {
 	return config.Display(cfg, format)
 }
 
-func runConfigAgentAdd(name, agentType, apiKey, model string, maxTokens int, temperature float64) error {
+func runConfigAgentAdd(name, agentType, provider, apiKey, model, endpoint string, maxTokens int, temperature float64) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
@@ -281,14 +312,19 @@ func runConfigAgentAdd(name, agentType, apiKey, model string, maxTokens int, tem
 	if !contains(validTypes, agentType) {
 		return fmt.Errorf("invalid agent type: %s. Valid types: %v", agentType, validTypes)
 	}
+	if provider != "" && !contains(config.AgentProviders, provider) {
+		return fmt.Errorf("invalid provider: %s. Valid providers: %v", provider, config.AgentProviders)
+	}
 
 	// Add agent to configuration
 	cfg.Agents[name] = config.Agent{
 		Type:        agentType,
+		Provider:    provider,
 		APIKey:      apiKey,
 		Model:       model,
 		MaxTokens:   maxTokens,
 		Temperature: temperature,
+		Endpoint:    endpoint,
 	}
 
 	// Save configuration
@@ -339,6 +375,64 @@ func runConfigAgentList() error {
 	return nil
 }
 
+func runConfigAgentUsage(since string, clearUsage bool, format string) error {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to get config directory: %w", err)
+	}
+	usageLog := agents.NewUsageLog(agents.DefaultUsageLogPath(configDir))
+
+	if clearUsage {
+		if err := usageLog.Clear(); err != nil {
+			return err
+		}
+		fmt.Println("Agent usage cleared.")
+		return nil
+	}
+
+	var from time.Time
+	if since != "" {
+		period, err := model.ParseDuration(since)
+		if err != nil || period <= 0 {
+			return fmt.Errorf("invalid --since %q: use a duration such as 24h or 30d", since)
+		}
+		from = time.Now().Add(-time.Duration(period))
+	}
+
+	stats, err := usageLog.Report(from)
+	if err != nil {
+		return err
+	}
+	if stats.Queries == 0 && format == "table" {
+		fmt.Println("No agent usage recorded.")
+		return nil
+	}
+	return utils.DisplayResponse(usageTable(stats), format)
+}
+
+// usageTable renders agent usage as one row per model and a total row
+type usageTable agents.UsageStats
+
+// TableHeaders returns the usage table columns
+func (t usageTable) TableHeaders() []string {
+	return []string{"Model", "Queries", "Cached", "Prompt Tokens", "Completion Tokens", "Estimated Cost"}
+}
+
+// TableRows returns one row per model, then the totals
+func (t usageTable) TableRows() [][]string {
+	rows := make([][]string, 0, len(t.Models)+1)
+	for _, usage := range t.Models {
+		cost := "unknown"
+		if usage.Priced {
+			cost = fmt.Sprintf("$%.4f", usage.EstimatedCost)
+		}
+		rows = append(rows, []string{usage.Model, strconv.Itoa(usage.Queries), strconv.Itoa(usage.CachedQueries),
+			strconv.Itoa(usage.PromptTokens), strconv.Itoa(usage.CompletionTokens), cost})
+	}
+	return append(rows, []string{"Total", strconv.Itoa(t.Queries), strconv.Itoa(t.CachedQueries),
+		strconv.Itoa(t.PromptTokens), strconv.Itoa(t.CompletionTokens), fmt.Sprintf("$%.4f", t.EstimatedCost)})
+}
+
 func runConfigCloudAWS(region, profile string) error {
 	cfg, err := config.Load()
 	if err != nil {
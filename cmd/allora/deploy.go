@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/AlloraAi/AlloraCLI/pkg/deploy"
@@ -33,12 +34,14 @@ func newDeployInfraCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "infra",
 		Short: "Deploy infrastructure",
+		Long: `Plan a Terraform configuration and apply its resources one at a time, with each
+resource applied after the resources it depends on.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runDeployInfra(template, optimize, dryRun, vars)
+			return runDeployInfra(cmd.Context(), template, optimize, dryRun, vars)
 		},
 	}
 
-	cmd.Flags().StringVarP(&template, "template", "t", "", "infrastructure template (terraform, cloudformation, pulumi)")
+	cmd.Flags().StringVarP(&template, "template", "t", "", "terraform configuration directory or file (default: current directory)")
 	cmd.Flags().BoolVarP(&optimize, "optimize", "o", false, "enable AI optimization")
 	cmd.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "show what would be deployed without making changes")
 	cmd.Flags().StringSliceVarP(&vars, "var", "v", []string{}, "template variables (key=value)")
@@ -56,7 +59,7 @@ func newDeployAppCmd() *cobra.Command {
 		Use:   "app",
 		Short: "Deploy application",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runDeployApp(image, environment, replicas, strategy)
+			return runDeployApp(cmd.Context(), image, environment, replicas, strategy)
 		},
 	}
 
@@ -80,7 +83,7 @@ func newDeployStatusCmd() *cobra.Command {
 			if len(args) > 0 {
 				deploymentID = args[0]
 			}
-			return runDeployStatus(deploymentID, format)
+			return runDeployStatus(cmd.Context(), deploymentID, format)
 		},
 	}
 
@@ -95,18 +98,20 @@ func newDeployRollbackCmd() *cobra.Command {
 	var confirm bool
 
 	cmd := &cobra.Command{
-		Use:   "rollback [deployment-id]",
+		Use:   "rollback [namespace/]deployment",
 		Short: "Rollback deployment",
-		Args:  cobra.MaximumNArgs(1),
+		Long: `Roll a Kubernetes Deployment back to a previous ReplicaSet revision using kubectl.
+Without --version the revision before the current one is restored.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 0 {
 				deploymentID = args[0]
 			}
-			return runDeployRollback(deploymentID, version, confirm)
+			return runDeployRollback(cmd.Context(), deploymentID, version, confirm)
 		},
 	}
 
-	cmd.Flags().StringVarP(&version, "version", "v", "", "specific version to rollback to")
+	cmd.Flags().StringVarP(&version, "version", "v", "", "revision to roll back to (default: previous revision)")
 	cmd.Flags().BoolVarP(&confirm, "confirm", "y", false, "skip confirmation prompts")
 
 	return cmd
@@ -121,11 +126,11 @@ func newDeployPlanCmd() *cobra.Command {
 		Use:   "plan",
 		Short: "Generate deployment plan",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runDeployPlan(template, optimize, format)
+			return runDeployPlan(cmd.Context(), template, optimize, format)
 		},
 	}
 
-	cmd.Flags().StringVarP(&template, "template", "t", "", "infrastructure template")
+	cmd.Flags().StringVarP(&template, "template", "t", "", "terraform configuration directory or file (default: current directory)")
 	cmd.Flags().BoolVarP(&optimize, "optimize", "o", false, "enable AI optimization")
 	cmd.Flags().StringVarP(&format, "format", "f", "text", "output format (text, json, yaml)")
 
@@ -133,7 +138,7 @@ func newDeployPlanCmd() *cobra.Command {
 }
 
 // Implementation functions
-func runDeployInfra(template string, optimize, dryRun bool, vars []string) error {
+func runDeployInfra(ctx context.Context, template string, optimize, dryRun bool, vars []string) error {
 	deployer, err := deploy.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize deployer: %w", err)
@@ -149,10 +154,15 @@ func runDeployInfra(template string, optimize, dryRun bool, vars []string) error
 	spinner := utils.NewSpinner("Preparing infrastructure deployment...")
 	spinner.Start()
 
-	result, err := deployer.DeployInfrastructure(options)
+	result, err := deployer.DeployInfrastructure(ctx, options)
 	spinner.Stop()
 
 	if err != nil {
+		// A cancelled deployment reports the resources it applied
+		if result != nil {
+			fmt.Println("⚠️  Partial deployment result:")
+			utils.DisplayResponse(result, "text")
+		}
 		return fmt.Errorf("failed to deploy infrastructure: %w", err)
 	}
 
@@ -165,7 +175,7 @@ func runDeployInfra(template string, optimize, dryRun bool, vars []string) error
 	return utils.DisplayResponse(result, "text")
 }
 
-func runDeployApp(image, environment string, replicas int, strategy string) error {
+func runDeployApp(ctx context.Context, image, environment string, replicas int, strategy string) error {
 	deployer, err := deploy.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize deployer: %w", err)
@@ -181,7 +191,7 @@ func runDeployApp(image, environment string, replicas int, strategy string) erro
 	spinner := utils.NewSpinner("Deploying application...")
 	spinner.Start()
 
-	result, err := deployer.DeployApplication(options)
+	result, err := deployer.DeployApplication(ctx, options)
 	spinner.Stop()
 
 	if err != nil {
@@ -192,7 +202,7 @@ func runDeployApp(image, environment string, replicas int, strategy string) erro
 	return utils.DisplayResponse(result, "text")
 }
 
-func runDeployStatus(deploymentID, format string) error {
+func runDeployStatus(ctx context.Context, deploymentID, format string) error {
 	deployer, err := deploy.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize deployer: %w", err)
@@ -200,7 +210,7 @@ func runDeployStatus(deploymentID, format string) error {
 
 	if deploymentID == "" {
 		// List all deployments
-		deployments, err := deployer.ListDeployments()
+		deployments, err := deployer.ListDeployments(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to list deployments: %w", err)
 		}
@@ -208,7 +218,7 @@ func runDeployStatus(deploymentID, format string) error {
 	}
 
 	// Get specific deployment status
-	status, err := deployer.GetDeploymentStatus(deploymentID)
+	status, err := deployer.GetDeploymentStatus(ctx, deploymentID)
 	if err != nil {
 		return fmt.Errorf("failed to get deployment status: %w", err)
 	}
@@ -216,7 +226,7 @@ func runDeployStatus(deploymentID, format string) error {
 	return utils.DisplayResponse(status, format)
 }
 
-func runDeployRollback(deploymentID, version string, confirm bool) error {
+func runDeployRollback(ctx context.Context, deploymentID, version string, confirm bool) error {
 	deployer, err := deploy.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize deployer: %w", err)
@@ -237,7 +247,7 @@ func runDeployRollback(deploymentID, version string, confirm bool) error {
 	spinner := utils.NewSpinner("Rolling back deployment...")
 	spinner.Start()
 
-	result, err := deployer.RollbackDeployment(deploymentID, version)
+	result, err := deployer.RollbackDeployment(ctx, deploymentID, version)
 	spinner.Stop()
 
 	if err != nil {
@@ -248,7 +258,7 @@ func runDeployRollback(deploymentID, version string, confirm bool) error {
 	return utils.DisplayResponse(result, "text")
 }
 
-func runDeployPlan(template string, optimize bool, format string) error {
+func runDeployPlan(ctx context.Context, template string, optimize bool, format string) error {
 	deployer, err := deploy.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize deployer: %w", err)
@@ -262,7 +272,7 @@ func runDeployPlan(template string, optimize bool, format string) error {
 	spinner := utils.NewSpinner("Generating deployment plan...")
 	spinner.Start()
 
-	plan, err := deployer.GeneratePlan(options)
+	plan, err := deployer.GeneratePlan(ctx, options)
 	spinner.Stop()
 
 	if err != nil {
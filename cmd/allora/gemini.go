@@ -1,12 +1,18 @@
 package main
 
 import (
+	"fmt"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/agents"
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
 	"github.com/AlloraAi/AlloraCLI/pkg/ui"
 	"github.com/spf13/cobra"
 )
 
 func newGeminiCmd() *cobra.Command {
 	var colorEnabled bool
+	var noAnimation bool
+	var resume bool
 	var exportFile string
 
 	cmd := &cobra.Command{
@@ -15,9 +21,21 @@ func newGeminiCmd() *cobra.Command {
 		Long: `Launch the Gemini-style AI interface for natural language interactions.
 This provides a chat-like experience similar to Google Gemini, allowing you to 
 interact with AlloraAi using natural language for infrastructure management tasks.`,
+		Annotations: map[string]string{longRunningAnnotation: ""},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Create and start the Gemini interface
-			geminiInterface := ui.NewGeminiInterface(colorEnabled)
+			geminiInterface := ui.NewGeminiInterface(colorEnabled, !noAnimation)
+			geminiInterface.SetResume(resume)
+
+			// Register the configured agents so queries can be routed to them
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load configuration: %w", err)
+			}
+			geminiInterface.Agents().AddAgentsFromConfig(cfg.Agents)
+			if configDir, err := config.GetConfigDir(); err == nil {
+				geminiInterface.Agents().SetUsageLog(agents.NewUsageLog(agents.DefaultUsageLogPath(configDir)))
+			}
 
 			// Set export file if provided
 			if exportFile != "" {
@@ -37,6 +55,8 @@ interact with AlloraAi using natural language for infrastructure management task
 
 	// Add flags
 	cmd.Flags().BoolVar(&colorEnabled, "color", true, "Enable colorized output")
+	cmd.Flags().BoolVar(&noAnimation, "no-animation", false, "Disable the logo, thinking and typing animations")
+	cmd.Flags().BoolVar(&resume, "resume", false, "Resume the most recent saved session")
 	cmd.Flags().StringVar(&exportFile, "export", "", "Export conversation to file when exiting")
 
 	return cmd
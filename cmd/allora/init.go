@@ -2,86 +2,140 @@ package main
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/ui"
 	"github.com/AlloraAi/AlloraCLI/pkg/utils"
 	"github.com/fatih/color"
-	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 )
 
+// initProviders are the cloud providers allora init can configure
+var initProviders = []string{"aws", "azure", "gcp"}
+
+// initModels are the AI models offered by the init wizard
+var initModels = []string{"gpt-4", "gpt-4-turbo", "gpt-3.5-turbo", "claude-3", "gemini-pro"}
+
+// initOptions are the settings allora init writes, given as flags and
+// environment variables or answered in the wizard
+type initOptions struct {
+	force bool
+
+	providers         []string
+	awsRegion         string
+	awsProfile        string
+	azureSubscription string
+	azureTenant       string
+	gcpProject        string
+	gcpRegion         string
+
+	agentName string
+	agentType string
+	model     string
+	apiKey    string
+
+	prometheus    string
+	grafana       string
+	grafanaAPIKey string
+}
+
 func newInitCmd() *cobra.Command {
+	opts := &initOptions{}
+
 	cmd := &cobra.Command{
 		Use:   "init",
 		Short: "Initialize AlloraCLI configuration and authentication",
-		Long:  `Initialize AlloraCLI by setting up configuration files, authentication, and basic agent setup.`,
-		RunE:  runInit,
+		Long: `Initialize AlloraCLI by setting up configuration files, authentication, and basic agent setup.
+
+In a terminal, init walks through a wizard to pick cloud providers, enter
+their regions and credentials, choose the AI model and API key, and set
+monitoring endpoints; flags fill in the wizard's defaults. Without a
+terminal, init configures from the flags alone, reading the API keys from
+ALLORA_API_KEY and ALLORA_GRAFANA_API_KEY when they are not given, and
+fails if a required value is missing.`,
+		Annotations: map[string]string{longRunningAnnotation: ""},
+		Args:        cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			configFile, _ := cmd.Flags().GetString("config")
+			return runInit(opts, configFile)
+		},
 	}
 
+	cmd.Flags().BoolVar(&opts.force, "force", false, "overwrite an existing configuration without asking")
+	cmd.Flags().StringSliceVar(&opts.providers, "provider", nil, "cloud providers to configure ("+strings.Join(initProviders, ", ")+")")
+	cmd.Flags().StringVar(&opts.awsRegion, "aws-region", "us-west-2", "AWS region")
+	cmd.Flags().StringVar(&opts.awsProfile, "aws-profile", "default", "AWS credentials profile")
+	cmd.Flags().StringVar(&opts.azureSubscription, "azure-subscription-id", "", "Azure subscription ID")
+	cmd.Flags().StringVar(&opts.azureTenant, "azure-tenant-id", "", "Azure tenant ID")
+	cmd.Flags().StringVar(&opts.gcpProject, "gcp-project-id", "", "GCP project ID")
+	cmd.Flags().StringVar(&opts.gcpRegion, "gcp-region", "us-central1", "GCP region")
+	cmd.Flags().StringVar(&opts.agentName, "agent-name", "infra-assistant", "name of the AI agent")
+	cmd.Flags().StringVar(&opts.agentType, "agent-type", "general", "agent type ("+strings.Join(config.AgentTypes, ", ")+")")
+	cmd.Flags().StringVar(&opts.model, "model", "gpt-4", "AI model")
+	cmd.Flags().StringVar(&opts.apiKey, "api-key", "", "AI provider API key (env ALLORA_API_KEY)")
+	cmd.Flags().StringVar(&opts.prometheus, "prometheus-endpoint", "", "Prometheus endpoint, such as http://localhost:9090")
+	cmd.Flags().StringVar(&opts.grafana, "grafana-endpoint", "", "Grafana endpoint, such as http://localhost:3000")
+	cmd.Flags().StringVar(&opts.grafanaAPIKey, "grafana-api-key", "", "Grafana API key (env ALLORA_GRAFANA_API_KEY)")
+
 	return cmd
 }
 
-func runInit(cmd *cobra.Command, args []string) error {
-	utils.PrintBanner()
+func runInit(opts *initOptions, configFile string) error {
+	if opts.apiKey == "" {
+		opts.apiKey = os.Getenv("ALLORA_API_KEY")
+	}
+	if opts.grafanaAPIKey == "" {
+		opts.grafanaAPIKey = os.Getenv("ALLORA_GRAFANA_API_KEY")
+	}
 
-	fmt.Println("🚀 Welcome to AlloraCLI!")
-	fmt.Println("Let's set up your AI-powered IT infrastructure management CLI.")
+	if configFile == "" {
+		configDir, err := config.GetConfigDir()
+		if err != nil {
+			return fmt.Errorf("failed to get config directory: %w", err)
+		}
+		configFile = filepath.Join(configDir, "config.yaml")
+	}
 
-	// Check if already initialized
-	configDir, err := config.GetConfigDir()
-	if err != nil {
-		return fmt.Errorf("failed to get config directory: %w", err)
+	uiManager := ui.NewUIManager(true, false)
+	interactive := uiManager.IsTerminalInteractive()
+
+	if interactive {
+		utils.PrintBanner()
+		fmt.Println("🚀 Welcome to AlloraCLI!")
+		fmt.Println("Let's set up your AI-powered IT infrastructure management CLI.")
 	}
 
-	configFile := filepath.Join(configDir, "config.yaml")
-	if _, err := os.Stat(configFile); err == nil {
-		prompt := promptui.Prompt{
-			Label:     "AlloraCLI is already initialized. Do you want to reinitialize",
-			IsConfirm: true,
+	// Check if already initialized
+	if _, err := os.Stat(configFile); err == nil && !opts.force {
+		if !interactive {
+			return fmt.Errorf("AlloraCLI is already initialized at %s; pass --force to overwrite it", configFile)
 		}
-
-		if _, err := prompt.Run(); err != nil {
+		reinit, err := uiManager.InteractiveConfirm("AlloraCLI is already initialized. Do you want to reinitialize", false)
+		if err != nil || !reinit {
 			fmt.Println("Initialization cancelled.")
 			return nil
 		}
 	}
 
-	// Create config directory if it doesn't exist
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
-	}
-
-	// Interactive setup
-	cfg := &config.Config{
-		Version: "1.0.0",
-		Agents:  make(map[string]config.Agent),
-		CloudProviders: config.CloudProviders{
-			AWS:   config.AWSConfig{},
-			Azure: config.AzureConfig{},
-			GCP:   config.GCPConfig{},
-		},
-		Monitoring: config.MonitoringConfig{},
-		Security: config.SecurityConfig{
-			Encryption:   true,
-			AuditLogging: true,
-		},
+	if interactive {
+		results, err := uiManager.InteractiveWizard("AlloraCLI Setup", initWizardSteps(opts))
+		if err != nil {
+			return fmt.Errorf("setup wizard failed: %w", err)
+		}
+		opts.apply(results)
 	}
 
-	// Setup default agent
-	if err := setupDefaultAgent(cfg); err != nil {
-		return fmt.Errorf("failed to setup default agent: %w", err)
+	cfg, err := opts.config()
+	if err != nil {
+		return err
 	}
-
-	// Setup cloud providers (optional)
-	if err := setupCloudProviders(cfg); err != nil {
-		return fmt.Errorf("failed to setup cloud providers: %w", err)
-	}
-
-	// Setup monitoring (optional)
-	if err := setupMonitoring(cfg); err != nil {
-		return fmt.Errorf("failed to setup monitoring: %w", err)
+	if err := config.Validate(cfg); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
 	}
 
 	// Save configuration
@@ -92,6 +146,9 @@ func runInit(cmd *cobra.Command, args []string) error {
 	// Success message
 	color.Green("✅ AlloraCLI has been successfully initialized!")
 	fmt.Printf("\nConfiguration saved to: %s\n", configFile)
+	if opts.apiKey == "" {
+		fmt.Println("\nNo API key was set; run 'allora init' again to add one.")
+	}
 	fmt.Println("\nYou can now start using AlloraCLI:")
 	fmt.Println("  allora ask \"What's the status of my infrastructure?\"")
 	fmt.Println("  allora monitor status")
@@ -101,184 +158,211 @@ func runInit(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func setupDefaultAgent(cfg *config.Config) error {
-	fmt.Println("\n🤖 Setting up your first AI agent...")
-
-	// Agent name
-	prompt := promptui.Prompt{
-		Label:   "Agent name",
-		Default: "infra-assistant",
-	}
-	name, err := prompt.Run()
-	if err != nil {
-		return err
+// initWizardSteps builds the setup wizard, with opts as the defaults
+func initWizardSteps(opts *initOptions) []ui.WizardStep {
+	selected := func(provider string) func(map[string]interface{}) bool {
+		return func(results map[string]interface{}) bool {
+			providers, _ := results["providers"].([]string)
+			return slices.Contains(providers, provider)
+		}
 	}
-
-	// Agent type
-	selectPrompt := promptui.Select{
-		Label: "Agent type",
-		Items: []string{"general", "aws", "azure", "gcp", "kubernetes", "monitoring"},
+	monitoring := func(results map[string]interface{}) bool {
+		enabled, _ := results["monitoring"].(bool)
+		return enabled
 	}
-	_, agentType, err := selectPrompt.Run()
-	if err != nil {
-		return err
+	confirmDefault := func(value bool) string {
+		if value {
+			return "true"
+		}
+		return "false"
 	}
 
-	// API Key (optional for now)
-	apiKeyPrompt := promptui.Prompt{
-		Label: "API Key (optional, can be set later via environment variable ALLORA_API_KEY)",
-		Mask:  '*',
-	}
-	apiKey, err := apiKeyPrompt.Run()
-	if err != nil && err != promptui.ErrInterrupt {
-		return err
+	return []ui.WizardStep{
+		{
+			Title:       "Cloud providers",
+			Description: "Pick the providers to manage, then Done. Pick none to skip.",
+			Type:        ui.WizardStepTypeMultiSelect,
+			Key:         "providers",
+			Prompt:      "Cloud providers",
+			Options:     initProviders,
+		},
+		{Title: "AWS region", Type: ui.WizardStepTypeInput, Key: "aws_region", Prompt: "AWS Region", Default: opts.awsRegion, Required: true, When: selected("aws")},
+		{Title: "AWS profile", Description: "The credentials profile in ~/.aws/credentials.", Type: ui.WizardStepTypeInput, Key: "aws_profile", Prompt: "AWS Profile", Default: opts.awsProfile, Required: true, When: selected("aws")},
+		{Title: "Azure subscription", Type: ui.WizardStepTypeInput, Key: "azure_subscription_id", Prompt: "Azure Subscription ID", Default: opts.azureSubscription, Required: true, When: selected("azure")},
+		{Title: "Azure tenant", Type: ui.WizardStepTypeInput, Key: "azure_tenant_id", Prompt: "Azure Tenant ID", Default: opts.azureTenant, Required: true, When: selected("azure")},
+		{Title: "GCP project", Type: ui.WizardStepTypeInput, Key: "gcp_project_id", Prompt: "GCP Project ID", Default: opts.gcpProject, Required: true, When: selected("gcp")},
+		{Title: "GCP region", Type: ui.WizardStepTypeInput, Key: "gcp_region", Prompt: "GCP Region", Default: opts.gcpRegion, Required: true, When: selected("gcp")},
+		{Title: "Agent name", Type: ui.WizardStepTypeInput, Key: "agent_name", Prompt: "Agent name", Default: opts.agentName, Required: true, Validate: validateAgentName},
+		{Title: "Agent type", Type: ui.WizardStepTypeSelect, Key: "agent_type", Prompt: "Agent type", Default: opts.agentType, Options: config.AgentTypes},
+		{Title: "AI model", Type: ui.WizardStepTypeSelect, Key: "model", Prompt: "AI Model", Default: opts.model, Options: initModels},
+		{
+			Title:       "API key",
+			Description: "Leave empty to keep the key from --api-key or ALLORA_API_KEY, or to set one later.",
+			Type:        ui.WizardStepTypePassword,
+			Key:         "api_key",
+			Prompt:      "API Key",
+			Default:     opts.apiKey,
+		},
+		{
+			Title:   "Monitoring",
+			Type:    ui.WizardStepTypeConfirm,
+			Key:     "monitoring",
+			Prompt:  "Configure monitoring endpoints",
+			Default: confirmDefault(opts.prometheus != "" || opts.grafana != ""),
+		},
+		{
+			Title:    "Prometheus",
+			Type:     ui.WizardStepTypeInput,
+			Key:      "prometheus_endpoint",
+			Prompt:   "Prometheus Endpoint (empty to skip)",
+			Default:  valueOrDefault(opts.prometheus, "http://localhost:9090"),
+			Validate: validateEndpoint,
+			When:     monitoring,
+		},
+		{
+			Title:    "Grafana",
+			Type:     ui.WizardStepTypeInput,
+			Key:      "grafana_endpoint",
+			Prompt:   "Grafana Endpoint (empty to skip)",
+			Default:  valueOrDefault(opts.grafana, "http://localhost:3000"),
+			Validate: validateEndpoint,
+			When:     monitoring,
+		},
+		{
+			Title:       "Grafana API key",
+			Description: "Used by 'allora monitor grafana' to provision dashboards. Leave empty to skip.",
+			Type:        ui.WizardStepTypePassword,
+			Key:         "grafana_api_key",
+			Prompt:      "Grafana API Key",
+			Default:     opts.grafanaAPIKey,
+			When: func(results map[string]interface{}) bool {
+				endpoint, _ := results["grafana_endpoint"].(string)
+				return monitoring(results) && endpoint != ""
+			},
+		},
 	}
+}
 
-	// Model selection
-	modelSelect := promptui.Select{
-		Label: "AI Model",
-		Items: []string{"gpt-4", "gpt-3.5-turbo", "claude-3", "gemini-pro"},
-	}
-	_, model, err := modelSelect.Run()
-	if err != nil {
-		return err
+// apply copies the wizard's answers into opts
+func (opts *initOptions) apply(results map[string]interface{}) {
+	text := func(key string, target *string) {
+		if value, ok := results[key].(string); ok {
+			*target = value
+		}
 	}
 
-	cfg.Agents[name] = config.Agent{
-		Type:        agentType,
-		APIKey:      apiKey,
-		Model:       model,
-		MaxTokens:   2048,
-		Temperature: 0.7,
+	opts.providers, _ = results["providers"].([]string)
+	text("aws_region", &opts.awsRegion)
+	text("aws_profile", &opts.awsProfile)
+	text("azure_subscription_id", &opts.azureSubscription)
+	text("azure_tenant_id", &opts.azureTenant)
+	text("gcp_project_id", &opts.gcpProject)
+	text("gcp_region", &opts.gcpRegion)
+	text("agent_name", &opts.agentName)
+	text("agent_type", &opts.agentType)
+	text("model", &opts.model)
+	text("api_key", &opts.apiKey)
+
+	if enabled, _ := results["monitoring"].(bool); !enabled {
+		opts.prometheus, opts.grafana, opts.grafanaAPIKey = "", "", ""
+		return
 	}
-
-	return nil
+	text("prometheus_endpoint", &opts.prometheus)
+	text("grafana_endpoint", &opts.grafana)
+	text("grafana_api_key", &opts.grafanaAPIKey)
 }
 
-func setupCloudProviders(cfg *config.Config) error {
-	fmt.Println("\n☁️  Cloud Provider Setup (optional)")
-
-	prompt := promptui.Prompt{
-		Label:     "Do you want to configure cloud providers now",
-		IsConfirm: true,
-	}
-
-	if _, err := prompt.Run(); err != nil {
-		return nil // Skip cloud provider setup
+// config builds the configuration from opts, reporting every missing or
+// invalid value at once
+func (opts *initOptions) config() (*config.Config, error) {
+	var problems []string
+	require := func(flag, value, provider string) {
+		if value == "" {
+			problems = append(problems, fmt.Sprintf("--%s is required to configure %s", flag, provider))
+		}
 	}
 
-	// AWS Setup
-	awsPrompt := promptui.Prompt{
-		Label:     "Configure AWS",
-		IsConfirm: true,
+	// Start from the defaults so the file spells out every setting, but
+	// configure only the providers and endpoints that were asked for
+	cfg, err := config.Default()
+	if err != nil {
+		return nil, err
 	}
-	if _, err := awsPrompt.Run(); err == nil {
-		regionPrompt := promptui.Prompt{
-			Label:   "AWS Region",
-			Default: "us-west-2",
-		}
-		region, err := regionPrompt.Run()
-		if err != nil {
-			return err
-		}
-
-		profilePrompt := promptui.Prompt{
-			Label:   "AWS Profile",
-			Default: "default",
-		}
-		profile, err := profilePrompt.Run()
-		if err != nil {
-			return err
-		}
-
-		cfg.CloudProviders.AWS = config.AWSConfig{
-			Region:  region,
-			Profile: profile,
+	cfg.Agents = make(map[string]config.Agent)
+	cfg.CloudProviders = config.CloudProviders{}
+	cfg.Monitoring = config.MonitoringConfig{}
+
+	for _, provider := range opts.providers {
+		switch provider {
+		case "aws":
+			require("aws-region", opts.awsRegion, "AWS")
+			cfg.CloudProviders.AWS = config.AWSConfig{Region: opts.awsRegion, Profile: opts.awsProfile}
+		case "azure":
+			require("azure-subscription-id", opts.azureSubscription, "Azure")
+			require("azure-tenant-id", opts.azureTenant, "Azure")
+			cfg.CloudProviders.Azure = config.AzureConfig{SubscriptionID: opts.azureSubscription, TenantID: opts.azureTenant}
+		case "gcp":
+			require("gcp-project-id", opts.gcpProject, "GCP")
+			require("gcp-region", opts.gcpRegion, "GCP")
+			cfg.CloudProviders.GCP = config.GCPConfig{ProjectID: opts.gcpProject, Region: opts.gcpRegion, ApplicationDefault: true}
+		default:
+			problems = append(problems, fmt.Sprintf("unknown provider %q; use one of %s", provider, strings.Join(initProviders, ", ")))
 		}
 	}
 
-	// Azure Setup
-	azurePrompt := promptui.Prompt{
-		Label:     "Configure Azure",
-		IsConfirm: true,
+	if err := validateAgentName(opts.agentName); err != nil {
+		problems = append(problems, fmt.Sprintf("--agent-name: %v", err))
+	}
+	cfg.Agents[opts.agentName] = config.Agent{
+		Type:        opts.agentType,
+		APIKey:      opts.apiKey,
+		Model:       opts.model,
+		MaxTokens:   2048,
+		Temperature: 0.7,
 	}
-	if _, err := azurePrompt.Run(); err == nil {
-		subscriptionPrompt := promptui.Prompt{
-			Label: "Azure Subscription ID",
-		}
-		subscriptionID, err := subscriptionPrompt.Run()
-		if err != nil {
-			return err
-		}
-
-		tenantPrompt := promptui.Prompt{
-			Label: "Azure Tenant ID",
-		}
-		tenantID, err := tenantPrompt.Run()
-		if err != nil {
-			return err
-		}
 
-		cfg.CloudProviders.Azure = config.AzureConfig{
-			SubscriptionID: subscriptionID,
-			TenantID:       tenantID,
+	for flag, endpoint := range map[string]string{"prometheus-endpoint": opts.prometheus, "grafana-endpoint": opts.grafana} {
+		if err := validateEndpoint(endpoint); err != nil {
+			problems = append(problems, fmt.Sprintf("--%s: %v", flag, err))
 		}
 	}
+	cfg.Monitoring.Prometheus.Endpoint = opts.prometheus
+	cfg.Monitoring.Grafana = config.GrafanaConfig{Endpoint: opts.grafana, APIKey: opts.grafanaAPIKey}
 
-	return nil
-}
-
-func setupMonitoring(cfg *config.Config) error {
-	fmt.Println("\n📊 Monitoring Setup (optional)")
-
-	prompt := promptui.Prompt{
-		Label:     "Do you want to configure monitoring tools now",
-		IsConfirm: true,
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return nil, fmt.Errorf("cannot initialize:\n  %s", strings.Join(problems, "\n  "))
 	}
+	return cfg, nil
+}
 
-	if _, err := prompt.Run(); err != nil {
-		return nil // Skip monitoring setup
+// validateAgentName rejects names that cannot be config keys
+func validateAgentName(name string) error {
+	if name == "" {
+		return fmt.Errorf("a name is required")
 	}
-
-	// Prometheus
-	prometheusPrompt := promptui.Prompt{
-		Label:     "Configure Prometheus",
-		IsConfirm: true,
+	if strings.ContainsAny(name, ". \t") {
+		return fmt.Errorf("must not contain dots or spaces")
 	}
-	if _, err := prometheusPrompt.Run(); err == nil {
-		endpointPrompt := promptui.Prompt{
-			Label:   "Prometheus Endpoint",
-			Default: "http://localhost:9090",
-		}
-		endpoint, err := endpointPrompt.Run()
-		if err != nil {
-			return err
-		}
+	return nil
+}
 
-		cfg.Monitoring.Prometheus = config.PrometheusConfig{
-			Endpoint: endpoint,
-		}
+// validateEndpoint accepts an empty value or an http(s) URL with a host
+func validateEndpoint(endpoint string) error {
+	if endpoint == "" {
+		return nil
 	}
-
-	// Grafana
-	grafanaPrompt := promptui.Prompt{
-		Label:     "Configure Grafana",
-		IsConfirm: true,
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q must be an http:// or https:// URL", endpoint)
 	}
-	if _, err := grafanaPrompt.Run(); err == nil {
-		endpointPrompt := promptui.Prompt{
-			Label:   "Grafana Endpoint",
-			Default: "http://localhost:3000",
-		}
-		endpoint, err := endpointPrompt.Run()
-		if err != nil {
-			return err
-		}
+	return nil
+}
 
-		cfg.Monitoring.Grafana = config.GrafanaConfig{
-			Endpoint: endpoint,
-		}
+// valueOrDefault returns value, or fallback when it is empty
+func valueOrDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
 	}
-
-	return nil
+	return value
 }
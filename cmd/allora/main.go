@@ -2,12 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/AlloraAi/AlloraCLI/pkg/agents"
 	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/output"
+	"github.com/AlloraAi/AlloraCLI/pkg/ui"
 	"github.com/AlloraAi/AlloraCLI/pkg/utils"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -19,8 +26,27 @@ var (
 	date    = "unknown"
 )
 
+// defaultTimeout bounds how long a command may run unless --timeout says otherwise
+const defaultTimeout = 5 * time.Minute
+
+// longRunningAnnotation marks commands that run until interrupted, which
+// the default timeout does not apply to. Its value lists the flags, comma
+// separated, that make the command run until interrupted, or is empty when
+// it always does
+const longRunningAnnotation = "allora/long-running"
+
+// timeoutError is the cause of a context cancelled by --timeout
+type timeoutError struct {
+	timeout time.Duration
+}
+
+// Error implements error
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s", e.timeout)
+}
+
 func main() {
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(utils.WithTraceID(context.Background()))
 	defer cancel()
 
 	// Handle graceful shutdown
@@ -31,15 +57,61 @@ func main() {
 		cancel()
 	}()
 
-	if err := newRootCmd().ExecuteContext(ctx); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if cmd, err := newRootCmd().ExecuteContextC(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", explainTimeout(cmd, err))
 		os.Exit(1)
 	}
 }
 
+// explainTimeout adds to the error of a command stopped by --timeout that
+// it was, and how to allow more time
+func explainTimeout(cmd *cobra.Command, err error) error {
+	if cmd == nil || cmd.Context() == nil {
+		return err
+	}
+	var timeout *timeoutError
+	// Some clients report the cause of the cancellation, others only the deadline
+	if !errors.As(err, &timeout) && (!errors.Is(err, context.DeadlineExceeded) || !errors.As(context.Cause(cmd.Context()), &timeout)) {
+		return err
+	}
+	return fmt.Errorf("%w\n%s did not finish within --timeout %s; pass a longer --timeout, or --timeout 0 to disable it", err, cmd.CommandPath(), timeout.timeout)
+}
+
+// applyTimeout bounds the context of cmd by timeout. The default timeout is
+// skipped for long-running commands and commands with a --timeout of their
+// own; a timeout given on the command line applies to everything but the latter
+func applyTimeout(cmd *cobra.Command, timeout time.Duration) context.CancelFunc {
+	if timeout <= 0 || cmd.LocalNonPersistentFlags().Lookup("timeout") != nil {
+		return func() {}
+	}
+	if flags, ok := cmd.Annotations[longRunningAnnotation]; ok && !cmd.Flags().Changed("timeout") {
+		if flags == "" || slices.ContainsFunc(strings.Split(flags, ","), cmd.Flags().Changed) {
+			return func() {}
+		}
+	}
+
+	ctx, cancel := context.WithTimeoutCause(cmd.Context(), timeout, &timeoutError{timeout: timeout})
+	cmd.SetContext(ctx)
+	return cancel
+}
+
+// boxTableOptions lays out tables with box-drawing borders for --table,
+// narrowing wide columns to fit the terminal
+func boxTableOptions() *output.TableOptions {
+	options := &output.TableOptions{Style: output.TableStyleBox}
+	if uiManager := ui.NewUIManager(false, false); uiManager.IsOutputTerminal() {
+		options.MaxWidth, _ = uiManager.GetTerminalSize()
+	}
+	return options
+}
+
 func newRootCmd() *cobra.Command {
 	var configFile string
 	var verbose bool
+	var profile string
+	var timeout time.Duration
+	var boxTables bool
+	cancelTimeout := func() {}
 
 	cmd := &cobra.Command{
 		Use:   "allora",
@@ -50,26 +122,52 @@ intelligent automation for DevOps and IT operations through natural language
 processing and multi-agent AI systems.`,
 		Version: fmt.Sprintf("%s (commit: %s, date: %s)", version, commit, date),
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			cancelTimeout = applyTimeout(cmd, timeout)
+
 			// Initialize configuration
 			if err := config.Initialize(configFile, verbose); err != nil {
-				return fmt.Errorf("failed to initialize configuration: %w", err)
+				// Let init and config run with an invalid configuration so it can be fixed
+				var invalid *config.ValidationError
+				if !errors.As(err, &invalid) || !repairsConfig(cmd) {
+					return fmt.Errorf("failed to initialize configuration: %w", err)
+				}
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 			}
 
 			// Initialize logging
-			if err := utils.InitializeLogging(verbose); err != nil {
+			if err := utils.InitializeLogging(cmd.Context(), verbose); err != nil {
 				return fmt.Errorf("failed to initialize logging: %w", err)
 			}
 
+			// Mask the configured secret patterns in queries sent to agents.
+			// Invalid patterns fail validation above, unless the config is
+			// being repaired, and leave only the built-in ones
+			if err := agents.SetRedactionPatterns(viper.GetStringSlice("security.redaction_patterns")); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+
+			if boxTables {
+				output.SetTableOptions(boxTableOptions())
+			}
+
 			return nil
 		},
+		// Commands that fail exit right away, so only success needs to stop the timer
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			cancelTimeout()
+		},
 	}
 
 	// Global flags
 	cmd.PersistentFlags().StringVar(&configFile, "config", "", "config file (default is $HOME/.config/alloracli/config.yaml)")
 	cmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	cmd.PersistentFlags().StringVar(&profile, "profile", "", "config profile to merge over the base configuration (env ALLORA_PROFILE)")
+	cmd.PersistentFlags().DurationVar(&timeout, "timeout", defaultTimeout, "maximum time a command may run (0 disables it; long-running commands have none unless set)")
+	cmd.PersistentFlags().BoolVar(&boxTables, "table", false, "draw table output with Unicode borders, cut to the terminal's width")
 
 	// Bind flags to viper
 	viper.BindPFlag("verbose", cmd.PersistentFlags().Lookup("verbose"))
+	viper.BindPFlag("profile", cmd.PersistentFlags().Lookup("profile"))
 
 	// Add subcommands
 	cmd.AddCommand(newInitCmd())
@@ -84,9 +182,20 @@ processing and multi-agent AI systems.`,
 	cmd.AddCommand(newPluginCmd())
 	cmd.AddCommand(newCompletionCmd())
 	cmd.AddCommand(newGeminiCmd())
+	cmd.AddCommand(newServeCmd())
 
 	// Enable auto-completion
 	cmd.CompletionOptions.DisableDefaultCmd = false
 
 	return cmd
 }
+
+// repairsConfig reports whether cmd belongs to the init or config commands
+func repairsConfig(cmd *cobra.Command) bool {
+	for c := cmd; c != nil && c.HasParent(); c = c.Parent() {
+		if !c.Parent().HasParent() {
+			return c.Name() == "init" || c.Name() == "config"
+		}
+	}
+	return false
+}
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
 	"time"
 
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
 	"github.com/AlloraAi/AlloraCLI/pkg/monitor"
+	"github.com/AlloraAi/AlloraCLI/pkg/notify"
+	"github.com/AlloraAi/AlloraCLI/pkg/output"
+	"github.com/AlloraAi/AlloraCLI/pkg/ui"
 	"github.com/AlloraAi/AlloraCLI/pkg/utils"
 	"github.com/spf13/cobra"
 )
@@ -21,24 +28,40 @@ func newMonitorCmd() *cobra.Command {
 	cmd.AddCommand(newMonitorAlertCmd())
 	cmd.AddCommand(newMonitorMetricsCmd())
 	cmd.AddCommand(newMonitorDashboardCmd())
+	cmd.AddCommand(newMonitorGrafanaCmd())
 
 	return cmd
 }
 
 func newMonitorStatusCmd() *cobra.Command {
+	var watch bool
+	var interval time.Duration
 	var refresh int
 	var format string
 
 	cmd := &cobra.Command{
 		Use:   "status",
 		Short: "Get overall system status",
+		Long: `Get the overall system status.
+
+With --watch the status is redrawn every --interval until Ctrl+C, like
+watch(1). Watching needs a terminal; to record the status over time, run the
+command in a loop with its output redirected instead. While watching, alerts
+are sent to the notification sinks in notifications as they start firing.`,
+		Annotations: map[string]string{longRunningAnnotation: "watch,refresh"},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runMonitorStatus(refresh, format)
+			if refresh > 0 {
+				watch, interval = true, time.Duration(refresh)*time.Second
+			}
+			return runMonitorStatus(cmd.Context(), watch, interval, format)
 		},
 	}
 
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "keep redrawing the status until interrupted")
+	cmd.Flags().DurationVar(&interval, "interval", 5*time.Second, "time between redraws with --watch")
 	cmd.Flags().IntVarP(&refresh, "refresh", "r", 0, "auto-refresh interval in seconds (0 = no refresh)")
-	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table, json, yaml)")
+	cmd.Flags().MarkDeprecated("refresh", "use --watch --interval instead")
+	addOutputFlag(cmd, &format, "table", "json", "yaml")
 
 	return cmd
 }
@@ -49,9 +72,10 @@ func newMonitorServiceCmd() *cobra.Command {
 	var format string
 
 	cmd := &cobra.Command{
-		Use:   "service [service-name]",
-		Short: "Monitor specific service health",
-		Args:  cobra.MaximumNArgs(1),
+		Use:               "service [service-name]",
+		Short:             "Monitor specific service health",
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completeServiceNames,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 0 {
 				serviceName = args[0]
@@ -93,7 +117,7 @@ func newMonitorAlertCreateCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&name, "name", "n", "", "alert name (required)")
-	cmd.Flags().StringVarP(&condition, "condition", "c", "", "alert condition (e.g., 'cpu > 80%')")
+	cmd.Flags().StringVarP(&condition, "condition", "c", "", "alert condition (e.g., 'cpu_usage > 80')")
 	cmd.Flags().StringVarP(&action, "action", "a", "", "action to take (e.g., 'notify', 'scale-up')")
 	cmd.Flags().StringVarP(&severity, "severity", "s", "medium", "alert severity (low, medium, high, critical)")
 	cmd.Flags().BoolVarP(&enabled, "enabled", "e", true, "enable alert")
@@ -119,9 +143,10 @@ func newMonitorAlertListCmd() *cobra.Command {
 
 func newMonitorAlertDeleteCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "delete [alert-name]",
-		Short: "Delete a monitoring alert",
-		Args:  cobra.ExactArgs(1),
+		Use:               "delete [alert-name]",
+		Short:             "Delete a monitoring alert",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeAlertNames,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return runMonitorAlertDelete(args[0])
 		},
@@ -139,7 +164,7 @@ func newMonitorMetricsCmd() *cobra.Command {
 		Use:   "metrics",
 		Short: "View system metrics",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runMonitorMetrics(metric, duration, format)
+			return runMonitorMetrics(cmd.Context(), metric, duration, format)
 		},
 	}
 
@@ -153,66 +178,135 @@ func newMonitorMetricsCmd() *cobra.Command {
 func newMonitorDashboardCmd() *cobra.Command {
 	var port int
 	var host string
+	var interval time.Duration
 
 	cmd := &cobra.Command{
-		Use:   "dashboard",
-		Short: "Launch monitoring dashboard",
+		Use:         "dashboard",
+		Short:       "Launch monitoring dashboard",
+		Annotations: map[string]string{longRunningAnnotation: ""},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runMonitorDashboard(host, port)
+			return runMonitorDashboard(cmd.Context(), host, port, interval)
 		},
 	}
 
 	cmd.Flags().IntVarP(&port, "port", "p", 8080, "dashboard port")
 	cmd.Flags().StringVarP(&host, "host", "h", "localhost", "dashboard host")
+	cmd.Flags().DurationVarP(&interval, "interval", "i", 5*time.Second, "live update interval")
+
+	return cmd
+}
+
+func newMonitorGrafanaCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "grafana",
+		Short: "Provision the AlloraCLI dashboard in Grafana",
+		Long: `Create or update the AlloraCLI Overview dashboard in the Grafana at
+monitoring.grafana.endpoint, authenticating with monitoring.grafana.api_key.
+The dashboard charts the CPU, memory and disk usage, alerts and service
+state AlloraCLI exports to Prometheus. Running it again updates the same
+dashboard, and leaves it alone when it is already current.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMonitorGrafana(cmd.Context(), format)
+		},
+	}
+
+	addOutputFlag(cmd, &format, "table", "json", "yaml")
 
 	return cmd
 }
 
 // Implementation functions
-func runMonitorStatus(refresh int, format string) error {
+func runMonitorStatus(ctx context.Context, watch bool, interval time.Duration, format string) error {
+	if watch {
+		// Alerts are sent to the notification sinks as they start firing
+		mon, err := monitor.New(monitor.WithAlertNotifications())
+		if err != nil {
+			return fmt.Errorf("failed to initialize monitor: %w", err)
+		}
+		defer mon.Stop()
+		return watchMonitorStatus(ctx, mon, interval, format)
+	}
+
 	mon, err := monitor.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize monitor: %w", err)
 	}
 
-	if refresh > 0 {
-		return runMonitorStatusWithRefresh(mon, refresh, format)
-	}
-
 	status, err := mon.GetSystemStatus()
 	if err != nil {
 		return fmt.Errorf("failed to get system status: %w", err)
 	}
 
-	return utils.DisplayResponse(status, format)
+	return displayStatus(os.Stdout, status, format)
 }
 
-func runMonitorStatusWithRefresh(mon monitor.Monitor, refresh int, format string) error {
-	ticker := time.NewTicker(time.Duration(refresh) * time.Second)
-	defer ticker.Stop()
+// watchMonitorStatus redraws the system status every interval until ctx is
+// cancelled
+func watchMonitorStatus(ctx context.Context, mon monitor.Monitor, interval time.Duration, format string) error {
+	if interval < time.Second {
+		return fmt.Errorf("--interval must be at least 1s, got %s", interval)
+	}
+	uiManager := ui.NewUIManager(true, false)
+	if !uiManager.IsOutputTerminal() {
+		return fmt.Errorf("--watch needs a terminal; to record the status over time, run 'allora monitor status -o json' in a loop instead")
+	}
+
+	// Open and resolve PagerDuty incidents as alerts change between refreshes
+	var incidents *monitor.IncidentSync
+	if cfg, err := config.Load(); err == nil {
+		if notifier := notify.IncidentsFromConfig(cfg.Notifications); notifier != nil {
+			incidents = monitor.NewIncidentSync(notifier)
+		}
+	}
 
-	for {
-		utils.ClearScreen()
+	return uiManager.Watch(ctx, "allora monitor status", interval, func(w io.Writer) error {
 		status, err := mon.GetSystemStatus()
 		if err != nil {
 			return fmt.Errorf("failed to get system status: %w", err)
 		}
-
-		fmt.Printf("Last updated: %s (refreshing every %ds)\n\n", time.Now().Format("15:04:05"), refresh)
-		if err := utils.DisplayResponse(status, format); err != nil {
-			return err
-		}
-
-		select {
-		case <-ticker.C:
-			continue
-		default:
-			// Check for user input to exit
-			if utils.CheckForExit() {
-				return nil
+		if incidents != nil {
+			if err := incidents.Sync(ctx, status.Alerts); err != nil {
+				fmt.Fprintf(w, "Failed to update incidents: %v\n\n", err)
 			}
 		}
+		return displayStatus(w, status, format)
+	})
+}
+
+// displayStatus writes system status to w, as one row per service for table output
+func displayStatus(w io.Writer, status *monitor.SystemStatus, format string) error {
+	if format != "table" {
+		return output.Render(w, format, status)
 	}
+
+	fmt.Fprintf(w, "Overall: %s (%d active alerts)\n\n", status.Overall, len(status.Alerts))
+	return output.Render(w, format, statusTable(status.Services))
+}
+
+// statusTable renders service statuses as table rows
+type statusTable []*monitor.ServiceStatus
+
+// TableHeaders returns the service status columns
+func (t statusTable) TableHeaders() []string {
+	return []string{"Service", "Status", "Health", "CPU", "Memory", "Uptime"}
+}
+
+// TableRows returns one row per service
+func (t statusTable) TableRows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, service := range t {
+		rows = append(rows, []string{
+			service.Name,
+			service.Status,
+			service.Health,
+			fmt.Sprintf("%.1f%%", service.CPU),
+			fmt.Sprintf("%.1f%%", service.Memory),
+			service.Uptime.Round(time.Second).String(),
+		})
+	}
+	return rows
 }
 
 func runMonitorService(serviceName string, detailed bool, format string) error {
@@ -239,6 +333,20 @@ func runMonitorService(serviceName string, detailed bool, format string) error {
 	return utils.DisplayResponse(service, format)
 }
 
+func runMonitorGrafana(ctx context.Context, format string) error {
+	mon, err := monitor.New()
+	if err != nil {
+		return fmt.Errorf("failed to initialize monitor: %w", err)
+	}
+
+	dashboard, err := mon.ProvisionGrafanaDashboard(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to provision Grafana dashboard: %w", err)
+	}
+
+	return utils.DisplayResponse(dashboard, format)
+}
+
 func runMonitorAlertCreate(name, condition, action, severity string, enabled bool) error {
 	mon, err := monitor.New()
 	if err != nil {
@@ -300,13 +408,13 @@ func runMonitorAlertDelete(name string) error {
 	return nil
 }
 
-func runMonitorMetrics(metric, duration, format string) error {
+func runMonitorMetrics(ctx context.Context, metric, duration, format string) error {
 	mon, err := monitor.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize monitor: %w", err)
 	}
 
-	metrics, err := mon.GetMetrics(metric, duration)
+	metrics, err := mon.GetMetricsContext(ctx, metric, duration)
 	if err != nil {
 		return fmt.Errorf("failed to get metrics: %w", err)
 	}
@@ -314,14 +422,19 @@ func runMonitorMetrics(metric, duration, format string) error {
 	return utils.DisplayResponse(metrics, format)
 }
 
-func runMonitorDashboard(host string, port int) error {
-	mon, err := monitor.New()
+func runMonitorDashboard(ctx context.Context, host string, port int, interval time.Duration) error {
+	mon, err := monitor.New(monitor.WithAlertNotifications())
 	if err != nil {
 		return fmt.Errorf("failed to initialize monitor: %w", err)
 	}
+	defer mon.Stop()
+
+	if err := mon.UpdateConfiguration(&monitor.MonitorConfig{DashboardInterval: interval}); err != nil {
+		return fmt.Errorf("invalid dashboard configuration: %w", err)
+	}
 
 	fmt.Printf("🚀 Starting monitoring dashboard at http://%s:%d\n", host, port)
 	fmt.Println("Press Ctrl+C to stop...")
 
-	return mon.StartDashboard(host, port)
+	return mon.StartDashboard(ctx, host, port)
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/notify"
+	"github.com/AlloraAi/AlloraCLI/pkg/output"
+	"github.com/AlloraAi/AlloraCLI/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// addOutputFlag adds --output/-o to cmd, accepting only formats and
+// defaulting to the first. --format stays available as a hidden alias for
+// scripts written before --output existed
+func addOutputFlag(cmd *cobra.Command, format *string, formats ...string) {
+	usage := fmt.Sprintf("output format (%s)", strings.Join(formats, ", "))
+	cmd.Flags().VarP(output.NewFormatFlag(format, formats[0], formats...), "output", "o", usage)
+
+	shorthand := ""
+	if cmd.Flags().ShorthandLookup("f") == nil {
+		shorthand = "f"
+	}
+	cmd.Flags().VarP(output.NewFormatFlag(format, formats[0], formats...), "format", shorthand, usage)
+	cmd.Flags().MarkHidden("format")
+
+	completions := cobra.FixedCompletions(formats, cobra.ShellCompDirectiveNoFileComp)
+	cmd.RegisterFlagCompletionFunc("output", completions)
+	cmd.RegisterFlagCompletionFunc("format", completions)
+}
+
+// addEmailFlag adds --email to a command that produces a report
+func addEmailFlag(cmd *cobra.Command, recipients *[]string) {
+	cmd.Flags().StringSliceVar(recipients, "email", nil, "email the report to these addresses through notifications.email (repeatable)")
+}
+
+// reportMailer returns a mailer for the configured SMTP server when
+// recipients were given with --email, or nil otherwise
+func reportMailer(cfg *config.Config, recipients []string) (*notify.Mailer, error) {
+	if len(recipients) == 0 {
+		return nil, nil
+	}
+	mailer, err := notify.NewMailer(cfg.Notifications.Email)
+	if err != nil {
+		return nil, fmt.Errorf("cannot email the report: %w", err)
+	}
+	return mailer, nil
+}
+
+// emailReport renders report and mails it to recipients
+func emailReport(ctx context.Context, mailer *notify.Mailer, subject string, report notify.Report, recipients []string) error {
+	body, err := notify.RenderReport(report)
+	if err != nil {
+		return err
+	}
+	if err := mailer.SendReport(ctx, subject, body, recipients); err != nil {
+		return err
+	}
+	utils.LogInfo(fmt.Sprintf("Emailed %q to %s", subject, strings.Join(recipients, ", ")))
+	return nil
+}
@@ -2,11 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/AlloraAi/AlloraCLI/pkg/config"
 	"github.com/AlloraAi/AlloraCLI/pkg/plugins"
+	"github.com/AlloraAi/AlloraCLI/pkg/ui"
 	"github.com/AlloraAi/AlloraCLI/pkg/utils"
+	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 )
 
@@ -22,7 +28,9 @@ func newPluginCmd() *cobra.Command {
 	cmd.AddCommand(newPluginUninstallCmd())
 	cmd.AddCommand(newPluginUpdateCmd())
 	cmd.AddCommand(newPluginSearchCmd())
+	cmd.AddCommand(newPluginBrowseCmd())
 	cmd.AddCommand(newPluginRunCmd())
+	cmd.AddCommand(newPluginInitCmd())
 
 	return cmd
 }
@@ -34,7 +42,7 @@ func newPluginListCmd() *cobra.Command {
 		Use:   "list",
 		Short: "List installed plugins",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runPluginList(format)
+			return runPluginList(cmd.Context(), format)
 		},
 	}
 
@@ -46,29 +54,32 @@ func newPluginListCmd() *cobra.Command {
 func newPluginInstallCmd() *cobra.Command {
 	var source string
 	var version string
+	var withDeps bool
 
 	cmd := &cobra.Command{
 		Use:   "install [plugin-name]",
 		Short: "Install a plugin",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runPluginInstall(args[0], source, version)
+			return runPluginInstall(cmd.Context(), args[0], source, version, withDeps)
 		},
 	}
 
-	cmd.Flags().StringVarP(&source, "source", "s", "", "plugin source (URL or registry)")
-	cmd.Flags().StringVarP(&version, "version", "v", "latest", "plugin version")
+	cmd.Flags().StringVarP(&source, "source", "s", "", "plugin archive (.tar.gz or .zip) as a URL or local path; defaults to the plugin registry")
+	cmd.Flags().StringVar(&version, "version", "latest", "plugin version")
+	cmd.Flags().BoolVar(&withDeps, "with-deps", false, "also install missing dependencies from the plugin registry")
 
 	return cmd
 }
 
 func newPluginUninstallCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "uninstall [plugin-name]",
-		Short: "Uninstall a plugin",
-		Args:  cobra.ExactArgs(1),
+		Use:               "uninstall [plugin-name]",
+		Short:             "Uninstall a plugin",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completePluginNames,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runPluginUninstall(args[0])
+			return runPluginUninstall(cmd.Context(), args[0])
 		},
 	}
 
@@ -79,16 +90,17 @@ func newPluginUpdateCmd() *cobra.Command {
 	var all bool
 
 	cmd := &cobra.Command{
-		Use:   "update [plugin-name]",
-		Short: "Update a plugin",
+		Use:               "update [plugin-name]",
+		Short:             "Update a plugin",
+		ValidArgsFunction: completePluginNames,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if all {
-				return runPluginUpdateAll()
+				return runPluginUpdateAll(cmd.Context())
 			}
 			if len(args) == 0 {
 				return fmt.Errorf("plugin name required when --all is not specified")
 			}
-			return runPluginUpdate(args[0])
+			return runPluginUpdate(cmd.Context(), args[0])
 		},
 	}
 
@@ -109,7 +121,7 @@ func newPluginSearchCmd() *cobra.Command {
 			if len(args) > 0 {
 				query = args[0]
 			}
-			return runPluginSearch(query, format)
+			return runPluginSearch(cmd.Context(), query, format)
 		},
 	}
 
@@ -118,15 +130,63 @@ func newPluginSearchCmd() *cobra.Command {
 	return cmd
 }
 
+func newPluginBrowseCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "browse [query]",
+		Short: "Browse the plugin registry interactively",
+		Long: `Browse plugin search results, view the details of a plugin and install it.
+
+Without an interactive terminal the search results are listed instead.`,
+		Args:        cobra.MaximumNArgs(1),
+		Annotations: map[string]string{longRunningAnnotation: ""},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := ""
+			if len(args) > 0 {
+				query = args[0]
+			}
+			return runPluginBrowse(cmd.Context(), query)
+		},
+	}
+
+	return cmd
+}
+
+func newPluginInitCmd() *cobra.Command {
+	var dir string
+	var description string
+	var author string
+	var module string
+
+	cmd := &cobra.Command{
+		Use:   "init [plugin-name]",
+		Short: "Scaffold a new plugin project",
+		Long: `Create a directory with a plugin manifest, a main.go that serves the
+plugin to AlloraCLI, a go.mod and a Makefile that builds and packages it.
+The directory must not exist yet.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPluginInit(args[0], dir, description, author, module)
+		},
+	}
+
+	cmd.Flags().StringVarP(&dir, "dir", "d", "", "directory to create (default: ./<plugin-name>)")
+	cmd.Flags().StringVar(&description, "description", "", "plugin description")
+	cmd.Flags().StringVar(&author, "author", "", "plugin author")
+	cmd.Flags().StringVar(&module, "module", "", "Go module path (default: the plugin name)")
+
+	return cmd
+}
+
 func newPluginRunCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "run [plugin-name] [plugin-args...]",
-		Short: "Run a plugin",
-		Args:  cobra.MinimumNArgs(1),
+		Use:               "run [plugin-name] [plugin-args...]",
+		Short:             "Run a plugin",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: completePluginNames,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			pluginName := args[0]
 			pluginArgs := args[1:]
-			return runPluginRun(pluginName, pluginArgs)
+			return runPluginRun(cmd.Context(), pluginName, pluginArgs)
 		},
 	}
 
@@ -134,7 +194,7 @@ func newPluginRunCmd() *cobra.Command {
 }
 
 // Implementation functions
-func runPluginList(format string) error {
+func runPluginList(ctx context.Context, format string) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
@@ -145,7 +205,6 @@ func runPluginList(format string) error {
 		return fmt.Errorf("failed to initialize plugin service: %w", err)
 	}
 
-	ctx := context.Background()
 	pluginList, err := pluginService.ListPlugins(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to list plugins: %w", err)
@@ -154,7 +213,7 @@ func runPluginList(format string) error {
 	return utils.DisplayResponse(pluginList, format)
 }
 
-func runPluginInstall(name, source, version string) error {
+func runPluginInstall(ctx context.Context, name, source, version string, withDeps bool) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
@@ -165,16 +224,15 @@ func runPluginInstall(name, source, version string) error {
 		return fmt.Errorf("failed to initialize plugin service: %w", err)
 	}
 
-	ctx := context.Background()
-
-	if source == "" {
-		source = fmt.Sprintf("https://registry.alloraai.com/plugins/%s", name)
-	}
-
 	spinner := utils.NewSpinner(fmt.Sprintf("Installing plugin %s...", name))
 	spinner.Start()
 
-	err = pluginService.InstallPlugin(ctx, name, source)
+	err = pluginService.InstallPlugin(ctx, plugins.InstallOptions{
+		Name:             name,
+		Source:           source,
+		Version:          version,
+		WithDependencies: withDeps,
+	})
 	spinner.Stop()
 
 	if err != nil {
@@ -185,7 +243,7 @@ func runPluginInstall(name, source, version string) error {
 	return nil
 }
 
-func runPluginUninstall(name string) error {
+func runPluginUninstall(ctx context.Context, name string) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
@@ -196,8 +254,6 @@ func runPluginUninstall(name string) error {
 		return fmt.Errorf("failed to initialize plugin service: %w", err)
 	}
 
-	ctx := context.Background()
-
 	spinner := utils.NewSpinner(fmt.Sprintf("Uninstalling plugin %s...", name))
 	spinner.Start()
 
@@ -212,7 +268,7 @@ func runPluginUninstall(name string) error {
 	return nil
 }
 
-func runPluginUpdate(name string) error {
+func runPluginUpdate(ctx context.Context, name string) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
@@ -223,8 +279,6 @@ func runPluginUpdate(name string) error {
 		return fmt.Errorf("failed to initialize plugin service: %w", err)
 	}
 
-	ctx := context.Background()
-
 	spinner := utils.NewSpinner(fmt.Sprintf("Updating plugin %s...", name))
 	spinner.Start()
 
@@ -239,7 +293,7 @@ func runPluginUpdate(name string) error {
 	return nil
 }
 
-func runPluginUpdateAll() error {
+func runPluginUpdateAll(ctx context.Context) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
@@ -250,8 +304,6 @@ func runPluginUpdateAll() error {
 		return fmt.Errorf("failed to initialize plugin service: %w", err)
 	}
 
-	ctx := context.Background()
-
 	// List all plugins first
 	pluginList, err := pluginService.ListPlugins(ctx)
 	if err != nil {
@@ -280,7 +332,7 @@ func runPluginUpdateAll() error {
 	return nil
 }
 
-func runPluginSearch(query, format string) error {
+func runPluginSearch(ctx context.Context, query, format string) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
@@ -291,8 +343,6 @@ func runPluginSearch(query, format string) error {
 		return fmt.Errorf("failed to initialize plugin service: %w", err)
 	}
 
-	ctx := context.Background()
-
 	spinner := utils.NewSpinner("Searching for plugins...")
 	spinner.Start()
 
@@ -303,10 +353,41 @@ func runPluginSearch(query, format string) error {
 		return fmt.Errorf("failed to search plugins: %w", err)
 	}
 
-	return utils.DisplayResponse(results, format)
+	return utils.DisplayResponse(pluginSearchTable(results), format)
+}
+
+// pluginSearchTable renders plugin search results as table rows
+type pluginSearchTable []plugins.PluginSearchResult
+
+// TableHeaders returns the search result columns
+func (t pluginSearchTable) TableHeaders() []string {
+	return []string{"Name", "Version", "Rating", "Downloads", "Description"}
+}
+
+// TableRows returns one row per plugin
+func (t pluginSearchTable) TableRows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, result := range t {
+		rows = append(rows, []string{result.Name, result.Version, fmt.Sprintf("%.1f", result.Rating), strconv.Itoa(result.Downloads), result.Description})
+	}
+	return rows
 }
 
-func runPluginRun(name string, args []string) error {
+// Plugin browser actions
+const (
+	browseInstall         = "Install"
+	browseInstallWithDeps = "Install with dependencies"
+	browseUpdate          = "Update"
+	browseBack            = "Back to results"
+	browseQuit            = "Quit"
+)
+
+func runPluginBrowse(ctx context.Context, query string) error {
+	uiManager := ui.NewUIManager(true, false)
+	if !uiManager.IsTerminalInteractive() || !uiManager.IsOutputTerminal() {
+		return runPluginSearch(ctx, query, "table")
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
@@ -317,7 +398,166 @@ func runPluginRun(name string, args []string) error {
 		return fmt.Errorf("failed to initialize plugin service: %w", err)
 	}
 
-	ctx := context.Background()
+	spinner := utils.NewSpinner("Searching for plugins...")
+	spinner.Start()
+
+	results, err := pluginService.SearchPlugins(ctx, query)
+	spinner.Stop()
+
+	if err != nil {
+		return fmt.Errorf("failed to search plugins: %w", err)
+	}
+	if len(results) == 0 {
+		fmt.Println("No plugins found.")
+		return nil
+	}
+
+	labels := make([]string, 0, len(results)+1)
+	for _, result := range results {
+		labels = append(labels, pluginResultLabel(result))
+	}
+	labels = append(labels, browseQuit)
+
+	for {
+		choice, err := uiManager.InteractiveSelect(fmt.Sprintf("%d plugins found", len(results)), labels)
+		if err != nil {
+			return browseError(err)
+		}
+		if choice == browseQuit {
+			return nil
+		}
+
+		result := results[slices.Index(labels, choice)]
+		quit, err := browsePlugin(ctx, uiManager, pluginService, result)
+		if err != nil || quit {
+			return err
+		}
+	}
+}
+
+// browsePlugin shows the details of a search result and offers to install
+// it, returning whether the user chose to quit the browser
+func browsePlugin(ctx context.Context, uiManager *ui.UIManager, pluginService plugins.PluginService, result plugins.PluginSearchResult) (bool, error) {
+	// Plugins that are not installed have no local info
+	installed, _ := pluginService.GetPluginInfo(ctx, result.Name)
+	manifest, err := pluginService.GetRegistryManifest(ctx, result.Name)
+	if err != nil {
+		utils.LogWarning(err.Error())
+		manifest = nil
+	}
+	printPluginDetails(result, manifest, installed)
+
+	actions := []string{browseInstall}
+	if manifest != nil && len(manifest.Dependencies) > 0 {
+		actions = append(actions, browseInstallWithDeps)
+	}
+	if installed != nil {
+		actions = []string{browseUpdate}
+	}
+	actions = append(actions, browseBack, browseQuit)
+
+	action, err := uiManager.InteractiveSelect(result.Name, actions)
+	if err != nil {
+		return true, browseError(err)
+	}
+
+	switch action {
+	case browseInstall, browseInstallWithDeps:
+		spinner := utils.NewSpinner(fmt.Sprintf("Installing plugin %s...", result.Name))
+		spinner.Start()
+		err = pluginService.InstallPlugin(ctx, plugins.InstallOptions{
+			Name:             result.Name,
+			WithDependencies: action == browseInstallWithDeps,
+		})
+		spinner.Stop()
+		if err != nil {
+			utils.LogError(fmt.Sprintf("Failed to install plugin %s: %v", result.Name, err))
+			return false, nil
+		}
+		fmt.Printf("✅ Plugin %s installed successfully!\n", result.Name)
+	case browseUpdate:
+		spinner := utils.NewSpinner(fmt.Sprintf("Updating plugin %s...", result.Name))
+		spinner.Start()
+		err = pluginService.UpdatePlugin(ctx, result.Name)
+		spinner.Stop()
+		if err != nil {
+			utils.LogError(fmt.Sprintf("Failed to update plugin %s: %v", result.Name, err))
+			return false, nil
+		}
+		fmt.Printf("✅ Plugin %s updated successfully!\n", result.Name)
+	case browseQuit:
+		return true, nil
+	}
+	return false, nil
+}
+
+// printPluginDetails prints a search result, with the registry manifest and
+// installed plugin when known
+func printPluginDetails(result plugins.PluginSearchResult, manifest *plugins.PluginManifest, installed *plugins.PluginInfo) {
+	fmt.Printf("\n%s %s\n", result.Name, result.Version)
+	if result.Description != "" {
+		fmt.Printf("  %s\n", result.Description)
+	}
+	fmt.Println()
+
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Printf("  %-13s %s\n", name+":", value)
+		}
+	}
+	field("Author", result.Author)
+	field("Rating", fmt.Sprintf("%.1f", result.Rating))
+	field("Downloads", fmt.Sprintf("%d", result.Downloads))
+	if !result.Updated.IsZero() {
+		field("Updated", result.Updated.Format("2006-01-02"))
+	}
+	field("Tags", strings.Join(result.Tags, ", "))
+	if manifest != nil {
+		field("License", manifest.License)
+		field("Homepage", manifest.Homepage)
+		field("Repository", manifest.Repository)
+		field("Dependencies", strings.Join(manifest.Dependencies, ", "))
+		if manifest.Signature != "" {
+			field("Signed", "yes")
+		}
+		commands := make([]string, 0, len(manifest.Commands))
+		for _, command := range manifest.Commands {
+			commands = append(commands, command.Name)
+		}
+		field("Commands", strings.Join(commands, ", "))
+	}
+	if installed != nil {
+		field("Installed", fmt.Sprintf("%s (%s)", installed.Version, installed.Status))
+	} else {
+		field("Installed", "no")
+	}
+	fmt.Println()
+}
+
+// pluginResultLabel formats a search result as a line of the browser menu
+func pluginResultLabel(result plugins.PluginSearchResult) string {
+	return fmt.Sprintf("%-24s %-10s ★ %.1f  %8d downloads", result.Name, result.Version, result.Rating, result.Downloads)
+}
+
+// browseError treats interrupting a prompt as leaving the browser
+func browseError(err error) error {
+	if errors.Is(err, promptui.ErrInterrupt) || errors.Is(err, promptui.ErrEOF) {
+		return nil
+	}
+	return fmt.Errorf("failed to read selection: %w", err)
+}
+
+func runPluginRun(ctx context.Context, name string, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	pluginService, err := plugins.NewPluginService(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize plugin service: %w", err)
+	}
+	defer pluginService.Close()
 
 	result, err := pluginService.ExecutePlugin(ctx, name, args)
 	if err != nil {
@@ -337,3 +577,28 @@ func runPluginRun(name string, args []string) error {
 
 	return nil
 }
+
+func runPluginInit(name, dir, description, author, module string) error {
+	if dir == "" {
+		dir = name
+	}
+
+	err := plugins.Scaffold(dir, plugins.ScaffoldOptions{
+		Name:        name,
+		Description: description,
+		Author:      author,
+		Module:      module,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create plugin: %w", err)
+	}
+
+	fmt.Printf("✅ Plugin %s created in %s\n\n", name, dir)
+	fmt.Println("Next steps:")
+	fmt.Printf("  cd %s\n", dir)
+	fmt.Println("  # implement Execute in main.go and list its commands in manifest.yaml")
+	fmt.Println("  make package    # build the binary and dist/*.tar.gz")
+	fmt.Println("  make install    # install it with allora plugin install")
+	fmt.Printf("  allora plugin run %s hello\n", name)
+	return nil
+}
@@ -3,9 +3,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/AlloraAi/AlloraCLI/pkg/config"
 	"github.com/AlloraAi/AlloraCLI/pkg/security"
+	"github.com/AlloraAi/AlloraCLI/pkg/security/syslog"
 	"github.com/AlloraAi/AlloraCLI/pkg/utils"
 	"github.com/spf13/cobra"
 )
@@ -22,6 +27,7 @@ func newSecurityCmd() *cobra.Command {
 	cmd.AddCommand(newSecurityAuditCmd())
 	cmd.AddCommand(newSecurityReportCmd())
 	cmd.AddCommand(newSecurityMonitorCmd())
+	cmd.AddCommand(newSecurityPoliciesCmd())
 
 	return cmd
 }
@@ -29,18 +35,21 @@ func newSecurityCmd() *cobra.Command {
 func newSecurityScanCmd() *cobra.Command {
 	var target string
 	var scanType string
+	var timeout time.Duration
 	var format string
 
 	cmd := &cobra.Command{
 		Use:   "scan",
 		Short: "Scan for security vulnerabilities",
+		Long:  `Scan a container image or filesystem path for known vulnerabilities using Trivy, which must be installed and on PATH.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runSecurityScan(target, scanType, format)
+			return runSecurityScan(cmd.Context(), target, scanType, timeout, format)
 		},
 	}
 
-	cmd.Flags().StringVarP(&target, "target", "t", "", "target to scan (IP, domain, or resource)")
+	cmd.Flags().StringVarP(&target, "target", "t", "", "image reference or filesystem path to scan (default: current directory)")
 	cmd.Flags().StringVarP(&scanType, "type", "T", "comprehensive", "scan type (quick, comprehensive, custom)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Minute, "maximum time to wait for the scan")
 	cmd.Flags().StringVarP(&format, "format", "f", "text", "output format (text, json, yaml)")
 
 	return cmd
@@ -54,11 +63,11 @@ func newSecurityComplianceCmd() *cobra.Command {
 		Use:   "compliance",
 		Short: "Check compliance against security standards",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runSecurityCompliance(standard, format)
+			return runSecurityCompliance(cmd.Context(), standard, format)
 		},
 	}
 
-	cmd.Flags().StringVarP(&standard, "standard", "s", "cis", "compliance standard (cis, pci, sox, hipaa)")
+	cmd.Flags().StringVarP(&standard, "standard", "s", "cis", "compliance standard or pack file (e.g. cis-aws-1.4, ./pack.yaml)")
 	cmd.Flags().StringVarP(&format, "format", "f", "text", "output format (text, json, yaml)")
 
 	return cmd
@@ -71,12 +80,26 @@ func newSecurityAuditCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "audit",
 		Short: "Audit permissions and access controls",
+		Long: `Audit the AWS IAM permissions of a principal or resource.
+
+--resource takes an IAM user, role, group or managed policy ARN, the
+user/NAME, role/NAME or group/NAME shorthand, or the ARN of any other
+resource to list who is granted access to it. Each statement of the
+applicable policies is listed as a permission, and wildcard, administrator
+and privilege escalation grants are reported as issues.
+
+Policies are read with the credentials in cloud_providers.aws, which need
+IAM read access such as the IAMReadOnlyAccess managed policy.`,
+		Example: `  allora security audit -r role/deploy
+  allora security audit -r arn:aws:iam::123456789012:user/alice
+  allora security audit -r arn:aws:s3:::audit-logs -f json`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runSecurityAudit(resource, format)
+			return runSecurityAudit(cmd.Context(), resource, format)
 		},
 	}
 
-	cmd.Flags().StringVarP(&resource, "resource", "r", "", "resource to audit")
+	cmd.Flags().StringVarP(&resource, "resource", "r", "", "AWS ARN, or user/NAME, role/NAME or group/NAME, to audit")
+	cmd.MarkFlagRequired("resource")
 	cmd.Flags().StringVarP(&format, "format", "f", "text", "output format (text, json, yaml)")
 
 	return cmd
@@ -84,18 +107,108 @@ func newSecurityAuditCmd() *cobra.Command {
 
 func newSecurityReportCmd() *cobra.Command {
 	var reportType string
+	var targets []string
 	var format string
+	var emails []string
 
 	cmd := &cobra.Command{
 		Use:   "report",
 		Short: "Generate comprehensive security reports",
+		Long: `Generate comprehensive security reports.
+
+Vulnerabilities found in the scanned targets can be exported as SARIF 2.1.0
+for CI systems and code scanning dashboards:
+
+  allora security report --target . --format sarif > allora.sarif
+
+With --email, the report is also mailed as HTML with a plaintext fallback
+through the SMTP server in notifications.email:
+
+  allora security report --target . --email ops@example.com`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runSecurityReport(reportType, format)
+			return runSecurityReport(cmd.Context(), cmd, reportType, targets, format, emails)
 		},
 	}
 
 	cmd.Flags().StringVarP(&reportType, "type", "t", "summary", "report type (summary, detailed, executive)")
-	cmd.Flags().StringVarP(&format, "format", "f", "text", "output format (text, json, yaml, pdf)")
+	cmd.Flags().StringSliceVar(&targets, "target", nil, "paths or images to scan for vulnerabilities (repeatable)")
+	cmd.Flags().StringVarP(&format, "format", "f", "text", "output format (text, json, yaml, sarif)")
+	addEmailFlag(cmd, &emails)
+
+	return cmd
+}
+
+func newSecurityPoliciesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "policies",
+		Short: "Manage security policies",
+	}
+
+	cmd.AddCommand(newSecurityPoliciesValidateCmd())
+	cmd.AddCommand(newSecurityPoliciesEvaluateCmd())
+
+	return cmd
+}
+
+func newSecurityPoliciesValidateCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "validate FILE",
+		Short: "Validate the rules of security policies",
+		Long: `Validate the security policies in a YAML or JSON file.
+
+Each rule's condition must be "<field> <op> <value>" clauses joined by and,
+its action one of allow, deny, quarantine, require_mfa, alert, notify or log,
+and it must set the parameters its action needs. Enabled rules of a policy
+that match the same requests with opposing actions are reported as
+conflicts. The command fails if any policy is invalid.`,
+		Example: `  allora security policies validate policies.yaml
+  allora security policies validate policies.json -f json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSecurityPoliciesValidate(args[0], format)
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "text", "output format (text, json, yaml)")
+
+	return cmd
+}
+
+func newSecurityPoliciesEvaluateCmd() *cobra.Command {
+	var policies []string
+	var input string
+	var v0Compatible bool
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "evaluate",
+		Short: "Evaluate resources against Open Policy Agent Rego policies",
+		Long: `Evaluate cloud resources against Open Policy Agent Rego policies.
+
+--input is a JSON file holding a resource or a list of them, such as the
+output of allora cloud resources -f json, and - reads standard input. Each
+resource is evaluated as input against the deny and warn rules of every
+package in the --policy files and directories. Every deny message is
+reported as an issue and every warn message as a warning; rules may return
+strings or objects with msg and severity fields.
+
+Policies that do not parse or compile are reported with their file and line.
+The command fails if any resource is denied or any policy does not compile.`,
+		Example: `  allora security policies evaluate --policy ./policy --input resources.json
+  allora cloud resources -p aws -t s3 -f json | allora security policies evaluate -P s3.rego -i -`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSecurityPoliciesEvaluate(cmd.Context(), policies, input, v0Compatible, format)
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&policies, "policy", "P", nil, "Rego policy file or directory (repeatable)")
+	cmd.Flags().StringVarP(&input, "input", "i", "", "JSON file of resources to evaluate, or - for standard input")
+	cmd.Flags().BoolVar(&v0Compatible, "v0-compatible", false, "parse policies written for Rego before OPA 1.0")
+	cmd.Flags().StringVarP(&format, "format", "f", "text", "output format (text, json, yaml)")
+	cmd.MarkFlagRequired("policy")
+	cmd.MarkFlagRequired("input")
 
 	return cmd
 }
@@ -103,30 +216,51 @@ func newSecurityReportCmd() *cobra.Command {
 func newSecurityMonitorCmd() *cobra.Command {
 	var duration string
 	var format string
+	var minSeverity string
+	var notifySinks bool
+	var syslogAddr string
 
 	cmd := &cobra.Command{
 		Use:   "monitor",
 		Short: "Monitor security events in real-time",
+		Long: `Monitor security events until interrupted or --duration passes.
+
+Each event at or above --min-severity is recorded in the audit log at
+security.audit_log_path (audit.log in the config directory by default).
+With --notify, high and critical events are also sent to the notification
+sinks in notifications. A running summary is printed every 30 seconds and
+when monitoring stops.
+
+Events are read from RFC 5424 syslog messages when security.syslog.address
+or --syslog is set, and from a synthetic demo source otherwise.`,
+		Annotations: map[string]string{longRunningAnnotation: ""},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runSecurityMonitor(duration, format)
+			return runSecurityMonitor(cmd.Context(), duration, format, minSeverity, syslogAddr, notifySinks)
 		},
 	}
 
 	cmd.Flags().StringVarP(&duration, "duration", "d", "continuous", "monitoring duration (5m, 1h, continuous)")
 	cmd.Flags().StringVarP(&format, "format", "f", "text", "output format (text, json, yaml)")
+	cmd.Flags().StringVar(&minSeverity, "min-severity", "", "ignore events less severe than this (low, medium, high, critical)")
+	cmd.Flags().BoolVar(&notifySinks, "notify", false, "send high and critical events to the configured notification sinks")
+	cmd.Flags().StringVar(&syslogAddr, "syslog", "", "listen for syslog on this host:port instead of security.syslog.address")
 
 	return cmd
 }
 
 // Implementation functions
-func runSecurityScan(target, scanType, format string) error {
+func runSecurityScan(ctx context.Context, target, scanType string, timeout time.Duration, format string) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
 	secService := security.NewSecurityService(cfg)
-	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 
 	spinner := utils.NewSpinner("Scanning for security vulnerabilities...")
 	spinner.Start()
@@ -141,14 +275,13 @@ func runSecurityScan(target, scanType, format string) error {
 	return utils.DisplayResponse(result, format)
 }
 
-func runSecurityCompliance(standard, format string) error {
+func runSecurityCompliance(ctx context.Context, standard, format string) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
 	secService := security.NewSecurityService(cfg)
-	ctx := context.Background()
 
 	spinner := utils.NewSpinner("Checking compliance...")
 	spinner.Start()
@@ -163,14 +296,13 @@ func runSecurityCompliance(standard, format string) error {
 	return utils.DisplayResponse(result, format)
 }
 
-func runSecurityAudit(resource, format string) error {
+func runSecurityAudit(ctx context.Context, resource, format string) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
 	secService := security.NewSecurityService(cfg)
-	ctx := context.Background()
 
 	spinner := utils.NewSpinner("Auditing permissions...")
 	spinner.Start()
@@ -185,17 +317,88 @@ func runSecurityAudit(resource, format string) error {
 	return utils.DisplayResponse(result, format)
 }
 
-func runSecurityReport(reportType, format string) error {
+func runSecurityPoliciesValidate(path, format string) error {
+	result, err := security.ValidatePolicyFile(path)
+	if err != nil {
+		return err
+	}
+
+	return displayPolicyValidation(result, format)
+}
+
+func runSecurityPoliciesEvaluate(ctx context.Context, policies []string, input string, v0Compatible bool, format string) error {
+	inputs, err := security.LoadRegoInputs(input)
+	if err != nil {
+		return err
+	}
+
+	result, err := security.EvaluateRegoPolicies(ctx, security.RegoOptions{Paths: policies, V0Compatible: v0Compatible}, inputs)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate policies: %w", err)
+	}
+
+	return displayPolicyValidation(result, format)
+}
+
+// displayPolicyValidation shows the issues and warnings of validated
+// policies, failing if any policy is invalid
+func displayPolicyValidation(result *security.ValidationResult, format string) error {
+	if format == "text" {
+		table := policyValidationTable{}
+		for _, policy := range result.Policies {
+			for _, issue := range policy.Issues {
+				table = append(table, []string{policy.PolicyID, issue.RuleID, issue.Severity, issue.Type, issue.Description})
+			}
+			for _, warning := range policy.Warnings {
+				table = append(table, []string{policy.PolicyID, warning.RuleID, "warning", warning.Type, warning.Description})
+			}
+		}
+		if len(table) > 0 {
+			if err := utils.DisplayResponse(table, "table"); err != nil {
+				return err
+			}
+		}
+		summary := result.Summary
+		fmt.Printf("%d policies: %d valid, %d invalid, %d issues, %d warnings\n",
+			summary.TotalPolicies, summary.ValidPolicies, summary.InvalidPolicies, summary.TotalIssues, summary.TotalWarnings)
+	} else if err := utils.DisplayResponse(result, format); err != nil {
+		return err
+	}
+	if result.Summary.InvalidPolicies > 0 {
+		return fmt.Errorf("%d of %d policies are invalid", result.Summary.InvalidPolicies, result.Summary.TotalPolicies)
+	}
+	return nil
+}
+
+// policyValidationTable lists the issues and warnings of validated policies
+type policyValidationTable [][]string
+
+// TableHeaders returns the policy validation columns
+func (t policyValidationTable) TableHeaders() []string {
+	return []string{"Policy", "Rule", "Severity", "Type", "Description"}
+}
+
+// TableRows returns one row per issue or warning
+func (t policyValidationTable) TableRows() [][]string {
+	return t
+}
+
+func runSecurityReport(ctx context.Context, cmd *cobra.Command, reportType string, targets []string, format string, emails []string) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	mailer, err := reportMailer(cfg, emails)
+	if err != nil {
+		return err
+	}
+
 	secService := security.NewSecurityService(cfg)
-	ctx := context.Background()
 
 	options := security.ReportOptions{
 		Type:           reportType,
+		Targets:        targets,
 		IncludeDetails: true,
 		Format:         format,
 	}
@@ -210,30 +413,113 @@ func runSecurityReport(reportType, format string) error {
 		return fmt.Errorf("failed to generate security report: %w", err)
 	}
 
-	return utils.DisplayResponse(result, format)
+	switch strings.ToLower(format) {
+	case "json", "sarif":
+		err = security.ExportReport(result, format, cmd.OutOrStdout())
+	default:
+		err = utils.DisplayResponse(result, format)
+	}
+	if err != nil || mailer == nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("AlloraCLI security report: %d critical, %d high priority findings",
+		result.ExecutiveSummary.CriticalFindings, result.ExecutiveSummary.HighPriorityFindings)
+	return emailReport(ctx, mailer, subject, result.Report(), emails)
 }
 
-func runSecurityMonitor(duration, format string) error {
+// monitorSummaryInterval is how often security monitor prints its running summary
+const monitorSummaryInterval = 30 * time.Second
+
+func runSecurityMonitor(ctx context.Context, duration, format, minSeverity, syslogAddr string, notifySinks bool) error {
+	if minSeverity != "" && !contains(config.Severities, strings.ToLower(minSeverity)) {
+		return fmt.Errorf("unknown severity %q; use one of %s", minSeverity, strings.Join(config.Severities, ", "))
+	}
+	if duration != "" && duration != "continuous" {
+		d, err := time.ParseDuration(duration)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid --duration %q; use a duration such as 5m or continuous", duration)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
+	if err := security.StartConfigKeyRotation(ctx, cfg.Security); err != nil {
+		return fmt.Errorf("failed to start key rotation: %w", err)
+	}
+
+	auditPath := os.ExpandEnv(cfg.Security.AuditLogPath)
+	if auditPath == "" {
+		configDir, err := config.GetConfigDir()
+		if err != nil {
+			return fmt.Errorf("failed to get config directory: %w", err)
+		}
+		auditPath = filepath.Join(configDir, security.AuditLogFileName)
+	}
+	auditor, err := security.NewEventAuditLogger(auditPath)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer auditor.Close()
+
 	secService := security.NewSecurityService(cfg)
-	ctx := context.Background()
 
-	fmt.Println("Starting security monitoring... (Press Ctrl+C to stop)")
+	syslogCfg := cfg.Security.Syslog
+	if syslogAddr != "" {
+		syslogCfg.Address = syslogAddr
+	}
+	var syslogSource *syslog.Source
+	if syslogCfg.Address != "" {
+		syslogSource = syslog.NewSource(syslogCfg)
+		secService.SetEventSources(syslogSource)
+	}
 
-	events, err := secService.MonitorSecurityEvents(ctx)
+	text := format == "text"
+	if text {
+		if syslogSource != nil {
+			fmt.Printf("Listening for syslog on %s (%s)\n", syslogCfg.Address, syslogSource.Protocol())
+		}
+		fmt.Printf("Monitoring security events, recording them in %s (Press Ctrl+C to stop)\n", auditPath)
+	}
+
+	lastSummary := time.Now()
+	summary, err := secService.WatchSecurityEvents(ctx, security.MonitorOptions{
+		MinSeverity: strings.ToLower(minSeverity),
+		Auditor:     auditor,
+		Notify:      notifySinks,
+		OnEvent: func(event security.SecurityEvent, summary *security.MonitorSummary) {
+			if !text {
+				if err := utils.DisplayResponse(event, format); err != nil {
+					utils.LogError(fmt.Sprintf("Failed to display event: %v", err))
+				}
+				return
+			}
+			fmt.Printf("%s  %-8s %-20s %-15s %s\n", event.Timestamp.Format("15:04:05"), strings.ToUpper(event.Severity), event.Type, event.Source, event.Description)
+			if time.Since(lastSummary) >= monitorSummaryInterval {
+				fmt.Printf("--- %s\n", summary.Summary())
+				lastSummary = time.Now()
+			}
+		},
+	})
 	if err != nil {
 		return fmt.Errorf("failed to start security monitoring: %w", err)
 	}
 
-	for event := range events {
-		if err := utils.DisplayResponse(event, format); err != nil {
-			utils.LogError(fmt.Sprintf("Failed to display event: %v", err))
-		}
+	// Keep structured output parseable by summarizing on stderr
+	out := os.Stdout
+	if !text {
+		out = os.Stderr
+	}
+	fmt.Fprintf(out, "Security monitoring stopped: %s\n", summary.Summary())
+	if syslogSource != nil {
+		stats := syslogSource.Stats()
+		fmt.Fprintf(out, "Syslog: %d messages received, %d malformed dropped\n", stats.Received, stats.Dropped)
 	}
-
 	return nil
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/analyze"
+	"github.com/AlloraAi/AlloraCLI/pkg/cloud"
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/monitor"
+	"github.com/AlloraAi/AlloraCLI/pkg/notify"
+	"github.com/AlloraAi/AlloraCLI/pkg/security"
+	"github.com/AlloraAi/AlloraCLI/pkg/server"
+	"github.com/AlloraAi/AlloraCLI/pkg/troubleshoot"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newServeCmd() *cobra.Command {
+	var addr string
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the AlloraCLI services over an HTTP API",
+		Long: `Start an HTTP server exposing the core services as JSON endpoints:
+
+  POST /ask              answer {"query": "...", "agent": "name"}
+  POST /ask/stream       the same, as Server-Sent Events while it is generated
+  GET  /monitor/status   system status
+  GET  /monitor/alerts   alert rules
+  GET  /cloud/resources  resources (?provider=&type=&max=&no_cache=)
+  GET  /cloud/costs      cost analysis (?provider=&period=&budget=)
+  POST /analyze/logs     analyze the log lines in the body (?pattern=&anomaly_stddev=)
+  POST /alertmanager     receive Alertmanager webhook notifications
+  GET  /health           liveness probe, no token needed
+
+Every other endpoint requires "Authorization: Bearer <token>" with the token
+from server.token or ALLORA_SERVER_TOKEN. Without a token the server only
+listens on loopback addresses. Each request is logged with its method,
+path, status and duration.
+
+Alerts received from Alertmanager are sent to the configured notification
+sinks, open or resolve PagerDuty incidents, and run diagnostics against
+their instance or service label.`,
+		Annotations: map[string]string{longRunningAnnotation: ""},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServe(cmd.Context(), addr)
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", "", fmt.Sprintf("address to listen on (default server.address or %s)", server.DefaultAddress))
+
+	return cmd
+}
+
+func runServe(ctx context.Context, addr string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if addr == "" {
+		addr = cfg.Server.Address
+	}
+	if addr == "" {
+		addr = server.DefaultAddress
+	}
+	token := cfg.Server.Token
+	if env := os.Getenv("ALLORA_SERVER_TOKEN"); env != "" {
+		token = env
+	}
+
+	if err := security.StartConfigKeyRotation(ctx, cfg.Security); err != nil {
+		return fmt.Errorf("failed to start key rotation: %w", err)
+	}
+
+	mon, err := monitor.New()
+	if err != nil {
+		return fmt.Errorf("failed to initialize monitor: %w", err)
+	}
+	analyzer, err := analyze.New()
+	if err != nil {
+		return fmt.Errorf("failed to initialize analyzer: %w", err)
+	}
+	troubleshooter, err := troubleshoot.New()
+	if err != nil {
+		return fmt.Errorf("failed to initialize troubleshooter: %w", err)
+	}
+
+	srv, err := server.New(server.Options{
+		Config:   cfg,
+		Token:    token,
+		Monitor:  mon,
+		Cloud:    cloud.NewCloudService(cfg),
+		Analyzer: analyzer,
+		Logger:   logrus.StandardLogger(),
+
+		Notifier:       notify.FromConfig(cfg.Notifications),
+		Incidents:      notify.IncidentsFromConfig(cfg.Notifications),
+		Troubleshooter: troubleshooter,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create API server: %w", err)
+	}
+
+	fmt.Printf("🚀 Serving the AlloraCLI API at http://%s\n", addr)
+	fmt.Println("Press Ctrl+C to stop...")
+
+	return srv.ListenAndServe(ctx, addr)
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/AlloraAi/AlloraCLI/pkg/troubleshoot"
 	"github.com/AlloraAi/AlloraCLI/pkg/utils"
@@ -26,6 +29,7 @@ func newTroubleshootCmd() *cobra.Command {
 
 func newTroubleshootIncidentCmd() *cobra.Command {
 	var logs string
+	var sources map[string]string
 	var service string
 	var severity string
 	var format string
@@ -33,12 +37,19 @@ func newTroubleshootIncidentCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "incident",
 		Short: "Analyze incidents and provide solutions",
+		Long: `Analyze an incident and suggest remediations.
+
+Give the logs of each affected component with --source name=path, such as
+the service and system logs. Error spikes are correlated across the sources
+to rank the candidate root causes, the source that failed first ranking
+highest.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runTroubleshootIncident(logs, service, severity, format)
+			return runTroubleshootIncident(cmd.Context(), logs, sources, service, severity, format)
 		},
 	}
 
 	cmd.Flags().StringVarP(&logs, "logs", "l", "", "path to log file or log content")
+	cmd.Flags().StringToStringVar(&sources, "source", nil, "named log source as name=path, repeatable")
 	cmd.Flags().StringVarP(&service, "service", "s", "", "service name related to the incident")
 	cmd.Flags().StringVarP(&severity, "severity", "v", "medium", "incident severity (low, medium, high, critical)")
 	cmd.Flags().StringVarP(&format, "format", "f", "text", "output format (text, json, yaml)")
@@ -49,59 +60,77 @@ func newTroubleshootIncidentCmd() *cobra.Command {
 func newTroubleshootSuggestCmd() *cobra.Command {
 	var service string
 	var issue string
-	var context string
+	var issueContext string
 	var format string
 
 	cmd := &cobra.Command{
 		Use:   "suggest",
 		Short: "Get AI-powered remediation suggestions",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runTroubleshootSuggest(service, issue, context, format)
+			return runTroubleshootSuggest(cmd.Context(), service, issue, issueContext, format)
 		},
 	}
 
 	cmd.Flags().StringVarP(&service, "service", "s", "", "service name")
 	cmd.Flags().StringVarP(&issue, "issue", "i", "", "issue description")
-	cmd.Flags().StringVarP(&context, "context", "c", "", "additional context")
+	cmd.Flags().StringVarP(&issueContext, "context", "c", "", "additional context")
 	cmd.Flags().StringVarP(&format, "format", "f", "text", "output format (text, json, yaml)")
 
 	return cmd
 }
 
 func newTroubleshootAutofixCmd() *cobra.Command {
+	var target string
 	var severity string
 	var dryRun bool
 	var confirm bool
 
 	cmd := &cobra.Command{
-		Use:   "autofix",
+		Use:   "autofix [target]",
 		Short: "Automatically fix common issues",
+		Long: `Run diagnostics against the target and execute the automated remediation
+for each issue found. Destructive remediations are skipped unless --confirm is set.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runTroubleshootAutofix(severity, dryRun, confirm)
+			if len(args) > 0 {
+				target = args[0]
+			}
+			return runTroubleshootAutofix(cmd.Context(), target, severity, dryRun, confirm)
 		},
 	}
 
 	cmd.Flags().StringVarP(&severity, "severity", "s", "medium", "maximum severity to auto-fix (low, medium, high)")
 	cmd.Flags().BoolVarP(&dryRun, "dry-run", "d", false, "show what would be fixed without making changes")
-	cmd.Flags().BoolVarP(&confirm, "confirm", "y", false, "skip confirmation prompts")
+	cmd.Flags().BoolVarP(&confirm, "confirm", "y", false, "allow destructive remediation actions")
 
 	return cmd
 }
 
 func newTroubleshootDiagnoseCmd() *cobra.Command {
-	var target string
 	var deep bool
 	var format string
 
 	cmd := &cobra.Command{
-		Use:   "diagnose [target]",
+		Use:   "diagnose [target...]",
 		Short: "Run comprehensive system diagnostics",
-		Args:  cobra.MaximumNArgs(1),
+		Long: `Run diagnostics against a target:
+
+  a local path (default: current directory)  disk space
+  host                                        DNS resolution
+  host:port                                   DNS resolution and TCP connectivity
+  http(s)://host/path                         DNS, TCP and an HTTP health check
+
+--deep additionally samples connection latency. Several targets are
+diagnosed concurrently and summarized one per line.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return runTroubleshootDiagnoseBatch(cmd.Context(), args, deep, format)
+			}
+			target := ""
 			if len(args) > 0 {
 				target = args[0]
 			}
-			return runTroubleshootDiagnose(target, deep, format)
+			return runTroubleshootDiagnose(cmd.Context(), target, deep, format)
 		},
 	}
 
@@ -113,24 +142,26 @@ func newTroubleshootDiagnoseCmd() *cobra.Command {
 
 func newTroubleshootHistoryCmd() *cobra.Command {
 	var limit int
+	var clearHistory bool
 	var format string
 
 	cmd := &cobra.Command{
 		Use:   "history",
 		Short: "View troubleshooting history",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runTroubleshootHistory(limit, format)
+			return runTroubleshootHistory(cmd.Context(), limit, clearHistory, format)
 		},
 	}
 
 	cmd.Flags().IntVarP(&limit, "limit", "l", 10, "number of recent entries to show")
+	cmd.Flags().BoolVar(&clearHistory, "clear", false, "delete all recorded troubleshooting sessions")
 	cmd.Flags().StringVarP(&format, "format", "f", "table", "output format (table, json, yaml)")
 
 	return cmd
 }
 
 // Implementation functions
-func runTroubleshootIncident(logs, service, severity, format string) error {
+func runTroubleshootIncident(ctx context.Context, logs string, sources map[string]string, service, severity, format string) error {
 	ts, err := troubleshoot.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize troubleshooter: %w", err)
@@ -138,6 +169,7 @@ func runTroubleshootIncident(logs, service, severity, format string) error {
 
 	incident := troubleshoot.Incident{
 		Logs:     logs,
+		Sources:  sources,
 		Service:  service,
 		Severity: severity,
 	}
@@ -145,7 +177,7 @@ func runTroubleshootIncident(logs, service, severity, format string) error {
 	spinner := utils.NewSpinner("Analyzing incident...")
 	spinner.Start()
 
-	analysis, err := ts.AnalyzeIncident(incident)
+	analysis, err := ts.AnalyzeIncident(ctx, incident)
 	spinner.Stop()
 
 	if err != nil {
@@ -155,7 +187,7 @@ func runTroubleshootIncident(logs, service, severity, format string) error {
 	return utils.DisplayResponse(analysis, format)
 }
 
-func runTroubleshootSuggest(service, issue, context, format string) error {
+func runTroubleshootSuggest(ctx context.Context, service, issue, issueContext, format string) error {
 	ts, err := troubleshoot.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize troubleshooter: %w", err)
@@ -164,13 +196,13 @@ func runTroubleshootSuggest(service, issue, context, format string) error {
 	request := troubleshoot.SuggestionRequest{
 		Service: service,
 		Issue:   issue,
-		Context: context,
+		Context: issueContext,
 	}
 
 	spinner := utils.NewSpinner("Generating remediation suggestions...")
 	spinner.Start()
 
-	suggestions, err := ts.GetSuggestions(request)
+	suggestions, err := ts.GetSuggestions(ctx, request)
 	spinner.Stop()
 
 	if err != nil {
@@ -180,13 +212,14 @@ func runTroubleshootSuggest(service, issue, context, format string) error {
 	return utils.DisplayResponse(suggestions, format)
 }
 
-func runTroubleshootAutofix(severity string, dryRun, confirm bool) error {
+func runTroubleshootAutofix(ctx context.Context, target, severity string, dryRun, confirm bool) error {
 	ts, err := troubleshoot.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize troubleshooter: %w", err)
 	}
 
 	options := troubleshoot.AutofixOptions{
+		Target:   target,
 		Severity: severity,
 		DryRun:   dryRun,
 		Confirm:  confirm,
@@ -195,10 +228,11 @@ func runTroubleshootAutofix(severity string, dryRun, confirm bool) error {
 	spinner := utils.NewSpinner("Scanning for issues to auto-fix...")
 	spinner.Start()
 
-	results, err := ts.AutoFix(options)
+	results, err := ts.AutoFix(ctx, options)
 	spinner.Stop()
 
-	if err != nil {
+	// A cancelled run still returns the issues it got through
+	if err != nil && results == nil {
 		return fmt.Errorf("failed to run autofix: %w", err)
 	}
 
@@ -208,20 +242,32 @@ func runTroubleshootAutofix(severity string, dryRun, confirm bool) error {
 		fmt.Println("🔧 Auto-fix results:")
 	}
 
+	if len(results) == 0 {
+		fmt.Println("  No issues found at or below the requested severity.")
+	}
+
 	for _, result := range results {
-		status := "✅ Fixed"
-		if result.Error != "" {
+		var status string
+		switch result.Status {
+		case "success":
+			status = "✅ Fixed"
+		case "would_fix":
+			status = fmt.Sprintf("🔄 Would run: %s", result.Command)
+		case "skipped":
+			status = fmt.Sprintf("⏭️  Skipped: %s", result.Error)
+		default:
 			status = fmt.Sprintf("❌ Error: %s", result.Error)
-		} else if dryRun {
-			status = "🔄 Would fix"
 		}
 		fmt.Printf("  • %s - %s\n", result.Issue, status)
 	}
 
+	if err != nil {
+		return fmt.Errorf("failed to run autofix: %w", err)
+	}
 	return nil
 }
 
-func runTroubleshootDiagnose(target string, deep bool, format string) error {
+func runTroubleshootDiagnose(ctx context.Context, target string, deep bool, format string) error {
 	ts, err := troubleshoot.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize troubleshooter: %w", err)
@@ -235,7 +281,7 @@ func runTroubleshootDiagnose(target string, deep bool, format string) error {
 	spinner := utils.NewSpinner("Running diagnostics...")
 	spinner.Start()
 
-	diagnostics, err := ts.RunDiagnostics(options)
+	diagnostics, err := ts.RunDiagnostics(ctx, options)
 	spinner.Stop()
 
 	if err != nil {
@@ -245,13 +291,70 @@ func runTroubleshootDiagnose(target string, deep bool, format string) error {
 	return utils.DisplayResponse(diagnostics, format)
 }
 
-func runTroubleshootHistory(limit int, format string) error {
+func runTroubleshootDiagnoseBatch(ctx context.Context, targets []string, deep bool, format string) error {
+	ts, err := troubleshoot.New()
+	if err != nil {
+		return fmt.Errorf("failed to initialize troubleshooter: %w", err)
+	}
+
+	spinner := utils.NewSpinner(fmt.Sprintf("Running diagnostics on %d targets...", len(targets)))
+	spinner.Start()
+
+	reports, err := ts.RunDiagnosticsBatch(ctx, targets, troubleshoot.DiagnosticOptions{Deep: deep})
+	spinner.Stop()
+
+	if len(reports) == 0 {
+		return fmt.Errorf("failed to run diagnostics: %w", err)
+	}
+	if err != nil {
+		// Show the targets that were diagnosed and report the rest
+		utils.LogWarning(err.Error())
+	}
+
+	if format == "text" {
+		table := diagnosticReportTable{}
+		for _, target := range targets {
+			if report, ok := reports[target]; ok && !slices.Contains(table, report) {
+				table = append(table, report)
+			}
+		}
+		return utils.DisplayResponse(table, "table")
+	}
+	return utils.DisplayResponse(reports, format)
+}
+
+// diagnosticReportTable summarizes diagnostic reports, one row per target
+type diagnosticReportTable []*troubleshoot.DiagnosticReport
+
+// TableHeaders returns the diagnostic summary columns
+func (t diagnosticReportTable) TableHeaders() []string {
+	return []string{"Target", "Status", "Summary", "Duration"}
+}
+
+// TableRows returns one row per target
+func (t diagnosticReportTable) TableRows() [][]string {
+	rows := make([][]string, 0, len(t))
+	for _, report := range t {
+		rows = append(rows, []string{report.Target, report.Status, report.Summary, report.Duration.Round(time.Millisecond).String()})
+	}
+	return rows
+}
+
+func runTroubleshootHistory(ctx context.Context, limit int, clearHistory bool, format string) error {
 	ts, err := troubleshoot.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize troubleshooter: %w", err)
 	}
 
-	history, err := ts.GetHistory(limit)
+	if clearHistory {
+		if err := ts.ClearHistory(ctx); err != nil {
+			return fmt.Errorf("failed to clear history: %w", err)
+		}
+		fmt.Println("Troubleshooting history cleared.")
+		return nil
+	}
+
+	history, err := ts.GetHistory(ctx, limit)
 	if err != nil {
 		return fmt.Errorf("failed to get history: %w", err)
 	}
@@ -28,6 +28,12 @@ type Agent interface {
 type Query struct {
 	Text    string                 `json:"text"`
 	Context map[string]interface{} `json:"context"`
+	// Tools are functions the model may call while answering
+	Tools []Tool `json:"tools,omitempty"`
+	// ExecuteTool runs the tool calls the model requests, feeding their
+	// results back until it answers. Without it the calls are returned as
+	// actions of type ToolActionType
+	ExecuteTool ToolExecutor `json:"-"`
 }
 
 // Response represents an AI agent response
@@ -39,6 +45,7 @@ type Response struct {
 	Metadata    map[string]interface{} `json:"metadata"`
 	Suggestions []string               `json:"suggestions"`
 	Actions     []Action               `json:"actions"`
+	References  *References            `json:"references,omitempty"`
 	Timestamp   time.Time              `json:"timestamp"`
 }
 
@@ -66,12 +73,22 @@ type AgentConfig struct {
 	Temperature float64 `json:"temperature"`
 	APIKey      string  `json:"api_key"`
 	Endpoint    string  `json:"endpoint"`
+	// MaxContextTokens bounds the conversation history sent with each query
+	MaxContextTokens int `json:"max_context_tokens"`
+	// SummarizeHistory summarizes history that no longer fits instead of dropping it
+	SummarizeHistory bool `json:"summarize_history"`
+	// CacheTTLSeconds is how long responses to identical queries are reused
+	CacheTTLSeconds int `json:"cache_ttl_seconds"`
+	// CacheDeterministicOnly skips the cache when the temperature is above 0
+	CacheDeterministicOnly bool `json:"cache_deterministic_only"`
 }
 
 // AgentManager manages multiple AI agents
 type AgentManager struct {
 	agents map[string]Agent
 	mutex  sync.RWMutex
+	// usage totals the tokens of the responses recorded this session
+	usage usageTracker
 }
 
 // NewAgentManager creates a new agent manager
@@ -135,6 +152,8 @@ type BaseAgent struct {
 	context     context.Context
 	status      *AgentStatus
 	agentConfig *AgentConfig
+	// cache holds recent responses; nil when caching is off
+	cache *responseCache
 }
 
 // GetName returns the agent name
@@ -187,6 +206,11 @@ func (b *BaseAgent) GetConfiguration() *AgentConfig {
 			Temperature: b.config.Temperature,
 			APIKey:      b.config.APIKey,
 			Endpoint:    b.config.Endpoint,
+
+			MaxContextTokens:       b.config.MaxContextTokens,
+			SummarizeHistory:       b.config.SummarizeHistory,
+			CacheTTLSeconds:        b.config.CacheTTLSeconds,
+			CacheDeterministicOnly: b.config.CacheDeterministicOnly,
 		}
 	}
 	return b.agentConfig
@@ -200,6 +224,13 @@ func (b *BaseAgent) UpdateConfiguration(config *AgentConfig) error {
 	b.config.Temperature = config.Temperature
 	b.config.APIKey = config.APIKey
 	b.config.Endpoint = config.Endpoint
+	b.config.MaxContextTokens = config.MaxContextTokens
+	b.config.SummarizeHistory = config.SummarizeHistory
+	if config.CacheTTLSeconds != b.config.CacheTTLSeconds {
+		b.cache = newResponseCache(config.CacheTTLSeconds)
+	}
+	b.config.CacheTTLSeconds = config.CacheTTLSeconds
+	b.config.CacheDeterministicOnly = config.CacheDeterministicOnly
 	return nil
 }
 
@@ -259,6 +290,13 @@ type MonitoringAgent struct {
 
 // NewAgent creates a new agent based on the configuration
 func NewAgent(cfg config.Agent) (Agent, error) {
+	switch cfg.Provider {
+	case config.ProviderOllama:
+		return NewOllamaAgent(cfg, cfg.Type)
+	case config.ProviderOpenAI:
+		return NewOpenAIAgent(cfg, cfg.Type)
+	}
+
 	// Check if this should be an OpenAI agent
 	if cfg.APIKey != "" && (cfg.Model == "gpt-4" || cfg.Model == "gpt-3.5-turbo" || cfg.Model == "gpt-4-turbo") {
 		return NewOpenAIAgent(cfg, cfg.Type)
@@ -270,6 +308,7 @@ func NewAgent(cfg config.Agent) (Agent, error) {
 		config:  cfg,
 		client:  resty.New(),
 		context: context.Background(),
+		cache:   newResponseCache(cfg.CacheTTLSeconds),
 	}
 
 	// Configure HTTP client
@@ -300,6 +339,11 @@ func NewAgent(cfg config.Agent) (Agent, error) {
 	}
 }
 
+// Query answers a query with the general agent's specialized response
+func (g *GeneralAgent) Query(ctx context.Context, query *Query) (*Response, error) {
+	return g.ProcessQuery(query.Text)
+}
+
 // ProcessQuery processes a query for the general agent
 func (g *GeneralAgent) ProcessQuery(query string) (*Response, error) {
 	// This is a mock implementation
@@ -341,6 +385,11 @@ func (g *GeneralAgent) GetModel() string {
 	return g.config.Model
 }
 
+// Query answers a query with the AWS agent's specialized response
+func (a *AWSAgent) Query(ctx context.Context, query *Query) (*Response, error) {
+	return a.ProcessQuery(query.Text)
+}
+
 // ProcessQuery processes a query for the AWS agent
 func (a *AWSAgent) ProcessQuery(query string) (*Response, error) {
 	response := &Response{
@@ -381,6 +430,11 @@ func (a *AWSAgent) GetModel() string {
 	return a.config.Model
 }
 
+// Query answers a query with the Azure agent's specialized response
+func (az *AzureAgent) Query(ctx context.Context, query *Query) (*Response, error) {
+	return az.ProcessQuery(query.Text)
+}
+
 // ProcessQuery processes a query for the Azure agent
 func (az *AzureAgent) ProcessQuery(query string) (*Response, error) {
 	response := &Response{
@@ -421,6 +475,11 @@ func (az *AzureAgent) GetModel() string {
 	return az.config.Model
 }
 
+// Query answers a query with the GCP agent's specialized response
+func (g *GCPAgent) Query(ctx context.Context, query *Query) (*Response, error) {
+	return g.ProcessQuery(query.Text)
+}
+
 // ProcessQuery processes a query for the GCP agent
 func (g *GCPAgent) ProcessQuery(query string) (*Response, error) {
 	response := &Response{
@@ -461,6 +520,11 @@ func (g *GCPAgent) GetModel() string {
 	return g.config.Model
 }
 
+// Query answers a query with the Kubernetes agent's specialized response
+func (k *KubernetesAgent) Query(ctx context.Context, query *Query) (*Response, error) {
+	return k.ProcessQuery(query.Text)
+}
+
 // ProcessQuery processes a query for the Kubernetes agent
 func (k *KubernetesAgent) ProcessQuery(query string) (*Response, error) {
 	response := &Response{
@@ -501,6 +565,11 @@ func (k *KubernetesAgent) GetModel() string {
 	return k.config.Model
 }
 
+// Query answers a query with the monitoring agent's specialized response
+func (m *MonitoringAgent) Query(ctx context.Context, query *Query) (*Response, error) {
+	return m.ProcessQuery(query.Text)
+}
+
 // ProcessQuery processes a query for the monitoring agent
 func (m *MonitoringAgent) ProcessQuery(query string) (*Response, error) {
 	response := &Response{
@@ -735,14 +804,8 @@ func (m *MonitoringAgent) generateMonitoringActions(query string) []Action {
 	}
 }
 
-// ProcessQuery processes a query using available agents
-func (m *AgentManager) ProcessQuery(ctx context.Context, queryText string) (string, error) {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
-
-	// If no agents are available, return a helpful message
-	if len(m.agents) == 0 {
-		return `I'm AlloraAi, your AI-powered infrastructure assistant! 
+// demoResponse is the answer given when no agents are configured
+const demoResponse = `I'm AlloraAi, your AI-powered infrastructure assistant! 
 
 I can help you with:
 🔧 Cloud infrastructure management (AWS, Azure, GCP)
@@ -756,29 +819,10 @@ To get started, you'll need to configure your cloud providers using:
 - allora config set
 - allora init
 
-For now, I'm running in demo mode. How can I help you today?`, nil
-	}
+For now, I'm running in demo mode. How can I help you today?`
 
-	// Create a query object
-	query := &Query{
-		Text:    queryText,
-		Context: make(map[string]interface{}),
-	}
-
-	// Try to find the best agent for the query
-	// For now, just use the first available agent
-	for _, agent := range m.agents {
-		if agent.IsHealthy() {
-			response, err := agent.Query(ctx, query)
-			if err != nil {
-				continue // Try next agent
-			}
-			return response.Content, nil
-		}
-	}
-
-	// If no healthy agents, return a fallback response
-	return fmt.Sprintf(`I understand you're asking about: "%s"
+// fallbackResponse is the answer given when no agent could answer a query
+const fallbackResponse = `I understand you're asking about: "%s"
 
 While I'm currently in demo mode, I can help you with infrastructure management tasks like:
 - Setting up monitoring for your applications
@@ -790,5 +834,10 @@ While I'm currently in demo mode, I can help you with infrastructure management
 To enable full AI capabilities, please configure your API keys using:
 allora config set openai.api_key YOUR_API_KEY
 
-Would you like me to help you get started with the setup?`, queryText), nil
+Would you like me to help you get started with the setup?`
+
+// ProcessQuery answers a query with the agent best suited to it
+func (m *AgentManager) ProcessQuery(ctx context.Context, queryText string) (string, error) {
+	answer, _, err := m.RouteQuery(ctx, queryText, "")
+	return answer, err
 }
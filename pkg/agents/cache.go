@@ -0,0 +1,139 @@
+package agents
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NoCacheContextKey is the Query.Context key that, set to true, makes the
+// agent ask the model again instead of answering from its response cache
+const NoCacheContextKey = "no_cache"
+
+// maxCachedResponses bounds how many responses an agent's cache keeps
+const maxCachedResponses = 100
+
+// responseCache keeps the most recently used responses of an agent for ttl
+type responseCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+}
+
+// cacheEntry is a response in the cache with when it expires
+type cacheEntry struct {
+	key      string
+	response *Response
+	expires  time.Time
+}
+
+// newResponseCache creates a cache keeping responses for ttlSeconds. It
+// returns nil, turning caching off, when ttlSeconds is not positive
+func newResponseCache(ttlSeconds int) *responseCache {
+	if ttlSeconds <= 0 {
+		return nil
+	}
+	return &responseCache{
+		ttl:      time.Duration(ttlSeconds) * time.Second,
+		capacity: maxCachedResponses,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// get returns a copy of the response cached under key, marked as cached in
+// its metadata, unless it has expired
+func (c *responseCache) get(key string) (*Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if c.now().After(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+
+	response := copyResponse(entry.response)
+	response.Metadata["cached"] = true
+	return response, true
+}
+
+// put caches a copy of response under key, evicting the least recently
+// used response when the cache is full
+func (c *responseCache) put(key string, response *Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{key: key, response: copyResponse(response), expires: c.now().Add(c.ttl)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// copyResponse copies a response so callers cannot change a cached one
+func copyResponse(response *Response) *Response {
+	copied := *response
+	copied.Metadata = make(map[string]interface{}, len(response.Metadata)+1)
+	for key, value := range response.Metadata {
+		copied.Metadata[key] = value
+	}
+	return &copied
+}
+
+// useCache reports whether query may be answered from, and its response
+// stored in, the agent's cache
+func (b *BaseAgent) useCache(query *Query) bool {
+	if b.cache == nil {
+		return false
+	}
+	if bypass, _ := query.Context[NoCacheContextKey].(bool); bypass {
+		return false
+	}
+	// Tool results can change between calls
+	if len(query.Tools) > 0 {
+		return false
+	}
+	// Replies sampled above temperature 0 differ between calls
+	return !b.config.CacheDeterministicOnly || b.config.Temperature == 0
+}
+
+// cacheKey hashes what determines a reply: the model, the temperature and
+// the prompt with its whitespace normalized
+func (b *BaseAgent) cacheKey(prompt string) string {
+	hash := sha256.New()
+	hash.Write([]byte(b.config.Model))
+	hash.Write([]byte{0})
+	hash.Write([]byte(strconv.FormatFloat(b.config.Temperature, 'g', -1, 64)))
+	hash.Write([]byte{0})
+	hash.Write([]byte(normalizePrompt(prompt)))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// normalizePrompt collapses runs of whitespace, so prompts differing only
+// in spacing share a cache entry
+func normalizePrompt(prompt string) string {
+	return strings.Join(strings.Fields(prompt), " ")
+}
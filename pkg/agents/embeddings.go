@@ -0,0 +1,143 @@
+package agents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Default embedding models of the providers, used when an agent sets no
+// embedding_model
+const (
+	DefaultOpenAIEmbeddingModel = "text-embedding-3-small"
+	DefaultOllamaEmbeddingModel = "nomic-embed-text"
+)
+
+// Embedder turns text into vectors that are close for text of similar meaning
+type Embedder interface {
+	// Embed returns the vector of each text, in order
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Model names the embedding model; vectors of different models cannot
+	// be compared
+	Model() string
+}
+
+// NewEmbedder creates an embedder calling the embeddings endpoint of an
+// agent's provider
+func NewEmbedder(cfg config.Agent) (Embedder, error) {
+	model := cfg.EmbeddingModel
+	switch {
+	case cfg.Provider == config.ProviderOllama:
+		if model == "" {
+			model = DefaultOllamaEmbeddingModel
+		}
+		endpoint := strings.TrimSuffix(cfg.Endpoint, "/")
+		if endpoint == "" {
+			endpoint = DefaultOllamaEndpoint
+		}
+		return &ollamaEmbedder{
+			httpClient: &http.Client{Transport: &utils.TracingTransport{}},
+			endpoint:   endpoint,
+			model:      model,
+		}, nil
+	case cfg.APIKey != "":
+		if model == "" {
+			model = DefaultOpenAIEmbeddingModel
+		}
+		client, err := newOpenAIClient(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return &openAIEmbedder{client: client, model: model}, nil
+	default:
+		return nil, fmt.Errorf("embeddings need an OpenAI API key or the ollama provider")
+	}
+}
+
+// openAIEmbedder embeds text with the OpenAI embeddings API
+type openAIEmbedder struct {
+	client *openai.Client
+	model  string
+}
+
+// Embed implements Embedder
+func (e *openAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	resp, err := e.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: texts,
+		Model: openai.EmbeddingModel(e.model),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI API error: %w", err)
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings from OpenAI, got %d", len(texts), len(resp.Data))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, embedding := range resp.Data {
+		if embedding.Index < 0 || embedding.Index >= len(vectors) {
+			return nil, fmt.Errorf("OpenAI returned an embedding for unknown input %d", embedding.Index)
+		}
+		vectors[embedding.Index] = embedding.Embedding
+	}
+	return vectors, nil
+}
+
+// Model implements Embedder
+func (e *openAIEmbedder) Model() string {
+	return e.model
+}
+
+// ollamaEmbedder embeds text with a model served by Ollama
+type ollamaEmbedder struct {
+	httpClient *http.Client
+	endpoint   string
+	model      string
+}
+
+// Embed implements Embedder
+func (e *ollamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": e.model, "input": texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Ollama request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint+"/api/embed", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Ollama request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, ollamaUnreachable(e.endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, ollamaStatusError(resp, e.model)
+	}
+
+	var reply struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("failed to decode Ollama response: %w", err)
+	}
+	if len(reply.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings from Ollama, got %d", len(texts), len(reply.Embeddings))
+	}
+	return reply.Embeddings, nil
+}
+
+// Model implements Embedder
+func (e *ollamaEmbedder) Model() string {
+	return e.model
+}
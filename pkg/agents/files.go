@@ -0,0 +1,159 @@
+package agents
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// FilesContextKey is the Query.Context key holding attached files as []FileContext
+const FilesContextKey = "files"
+
+// DefaultFileTokenBudget bounds the tokens all attached files may use together
+const DefaultFileTokenBudget = 8000
+
+// bytesPerToken approximates how many bytes of text make up one token
+const bytesPerToken = 4
+
+// binarySniffSize is how much of a file is inspected to detect binary content
+const binarySniffSize = 8000
+
+// FileContext is a file attached to a query
+type FileContext struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	// Size is the size of the file on disk in bytes
+	Size int64 `json:"size"`
+	// Truncated is set when the middle of the file was left out to fit the budget
+	Truncated bool `json:"truncated"`
+}
+
+// LoadFileContext reads text files to attach to a query, sharing tokenBudget
+// between them. Files that do not fit keep their beginning and end with the
+// middle elided, so large logs still show their most recent lines
+func LoadFileContext(paths []string, tokenBudget int) ([]FileContext, error) {
+	sizes := make([]int64, len(paths))
+	for i, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("%s is a directory; attach individual files", path)
+		}
+		sizes[i] = info.Size()
+	}
+
+	budgets := splitBudget(sizes, int64(tokenBudget)*bytesPerToken)
+
+	files := make([]FileContext, len(paths))
+	for i, path := range paths {
+		file, err := readFileContext(path, sizes[i], budgets[i])
+		if err != nil {
+			return nil, err
+		}
+		files[i] = *file
+	}
+	return files, nil
+}
+
+// splitBudget shares total bytes between files, giving smaller files what
+// they need and splitting the rest evenly between the larger ones
+func splitBudget(sizes []int64, total int64) []int64 {
+	order := make([]int, len(sizes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return sizes[order[a]] < sizes[order[b]] })
+
+	budgets := make([]int64, len(sizes))
+	remaining := total
+	for n, i := range order {
+		share := remaining / int64(len(sizes)-n)
+		if sizes[i] < share {
+			share = sizes[i]
+		}
+		budgets[i] = share
+		remaining -= share
+	}
+	return budgets
+}
+
+// readFileContext reads a text file, keeping its head and tail when it is
+// larger than budget bytes
+func readFileContext(path string, size, budget int64) (*FileContext, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer f.Close()
+
+	sniff := make([]byte, binarySniffSize)
+	n, err := io.ReadFull(f, sniff)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if isBinary(sniff[:n], int64(n) < size) {
+		return nil, fmt.Errorf("%s appears to be a binary file; only text files can be attached", path)
+	}
+
+	file := &FileContext{Path: path, Size: size}
+	if size <= budget {
+		data := make([]byte, size)
+		if _, err := f.ReadAt(data, 0); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		file.Content = string(data)
+		return file, nil
+	}
+
+	head := make([]byte, budget/2)
+	if _, err := f.ReadAt(head, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	tail := make([]byte, budget-budget/2)
+	if _, err := f.ReadAt(tail, size-int64(len(tail))); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	// Cut at line boundaries so no partial lines are shown
+	if i := bytes.LastIndexByte(head, '\n'); i >= 0 {
+		head = head[:i+1]
+	}
+	if i := bytes.IndexByte(tail, '\n'); i >= 0 {
+		tail = tail[i+1:]
+	}
+
+	omitted := size - int64(len(head)) - int64(len(tail))
+	file.Content = fmt.Sprintf("%s[... %d bytes omitted ...]\n%s", head, omitted, tail)
+	file.Truncated = true
+	return file, nil
+}
+
+// isBinary reports whether sample looks like binary data; partial means the
+// sample was cut from a longer file and may end in the middle of a character
+func isBinary(sample []byte, partial bool) bool {
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+	if partial {
+		// Drop a trailing multi-byte character cut off by the sample
+		for i := 0; i < utf8.UTFMax && len(sample) > 0 && !utf8.Valid(sample); i++ {
+			sample = sample[:len(sample)-1]
+		}
+	}
+	return !utf8.Valid(sample)
+}
+
+// formatFileContext labels an attached file for the prompt
+func formatFileContext(file FileContext) string {
+	label := file.Path
+	if file.Truncated {
+		label += fmt.Sprintf(" (%d bytes, truncated to fit)", file.Size)
+	}
+	return fmt.Sprintf("Attached file: %s\n----- BEGIN %s -----\n%s\n----- END %s -----", label, file.Path, strings.TrimRight(file.Content, "\n"), file.Path)
+}
@@ -0,0 +1,88 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// HistoryContextKey is the Query.Context key holding earlier turns as []Turn
+const HistoryContextKey = "history"
+
+// DefaultMaxContextTokens bounds the conversation history sent with a query
+// when the agent does not configure max_context_tokens
+const DefaultMaxContextTokens = 4000
+
+// messageOverheadTokens approximates the tokens each message costs beyond its content
+const messageOverheadTokens = 4
+
+// Turn is an earlier message of the conversation a query belongs to
+type Turn struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// EstimateTokens approximates how many tokens text uses
+func EstimateTokens(text string) int {
+	return (len(text) + bytesPerToken - 1) / bytesPerToken
+}
+
+// ContextBudget returns how many tokens of history may be sent to agent
+func ContextBudget(agent Agent) int {
+	if cfg := agent.GetConfiguration(); cfg != nil && cfg.MaxContextTokens > 0 {
+		return cfg.MaxContextTokens
+	}
+	return DefaultMaxContextTokens
+}
+
+// FitHistory keeps the most recent turns that fit in budget tokens and
+// returns them with the older turns that were dropped, both oldest first
+func FitHistory(turns []Turn, budget int) (kept, dropped []Turn) {
+	used := 0
+	start := len(turns)
+	for start > 0 {
+		cost := EstimateTokens(turns[start-1].Content) + messageOverheadTokens
+		if used+cost > budget {
+			break
+		}
+		used += cost
+		start--
+	}
+	return turns[start:], turns[:start]
+}
+
+// SummarizeHistory asks agent to fold turns into the summary of the
+// conversation so far, keeping the result under maxTokens
+func SummarizeHistory(ctx context.Context, agent Agent, summary string, turns []Turn, maxTokens int) (string, error) {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Summarize this conversation between a user and an infrastructure assistant in at most %d words. "+
+		"Keep the facts, resource names, commands and decisions needed to continue it.\n\n", maxTokens*3/4)
+	if summary != "" {
+		fmt.Fprintf(&prompt, "Summary of the conversation before these messages:\n%s\n\n", summary)
+	}
+	for _, turn := range turns {
+		fmt.Fprintf(&prompt, "%s: %s\n", turn.Role, turn.Content)
+	}
+
+	response, err := agent.Query(ctx, &Query{Text: prompt.String(), Context: make(map[string]interface{})})
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+	return truncateTokens(strings.TrimSpace(responseText(response)), maxTokens), nil
+}
+
+// truncateTokens cuts text to about maxTokens tokens without splitting a character
+func truncateTokens(text string, maxTokens int) string {
+	limit := maxTokens * bytesPerToken
+	if limit <= 0 {
+		return ""
+	}
+	if len(text) <= limit {
+		return text
+	}
+	for limit > 0 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+	return text[:limit]
+}
@@ -0,0 +1,349 @@
+package agents
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DocsContextKey is the Query.Context key holding excerpts of the user's
+// docs retrieved for the query as []DocChunk
+const DocsContextKey = "docs"
+
+// DefaultDocsTopK is how many excerpts are retrieved for a query
+const DefaultDocsTopK = 4
+
+// maxChunkBytes bounds the size of a chunk of a doc, about 500 tokens
+const maxChunkBytes = 2000
+
+// embedBatchSize bounds how many chunks are sent in one embeddings request
+const embedBatchSize = 64
+
+// DocChunk is a section of a markdown doc in a knowledge base
+type DocChunk struct {
+	// Source is the path of the doc relative to the ingested directory
+	Source  string `json:"source"`
+	Heading string `json:"heading,omitempty"`
+	Text    string `json:"text"`
+	// Score is the similarity of a retrieved chunk to the query
+	Score  float64   `json:"score,omitempty"`
+	Vector []float32 `json:"vector,omitempty"`
+}
+
+// IngestStats describes what ingesting a directory of docs did
+type IngestStats struct {
+	Files int `json:"files"`
+	// Embedded counts the files that were new or changed since the last ingest
+	Embedded int `json:"embedded"`
+	Chunks   int `json:"chunks"`
+}
+
+// KnowledgeBase is a local vector store of markdown docs, searched by
+// cosine similarity to answer queries from the user's own runbooks
+type KnowledgeBase struct {
+	embedder  Embedder
+	indexPath string
+	chunks    []DocChunk
+}
+
+// knowledgeIndex is the on-disk form of a knowledge base, so unchanged
+// docs are not embedded again on every run
+type knowledgeIndex struct {
+	Model string                 `json:"model"`
+	Files map[string]indexedFile `json:"files"`
+}
+
+// indexedFile is the chunks of a doc and the hash of the content they came from
+type indexedFile struct {
+	Hash   string     `json:"hash"`
+	Chunks []DocChunk `json:"chunks"`
+}
+
+// NewKnowledgeBase creates an empty knowledge base embedding with embedder.
+// Ingested docs are indexed at indexPath; an empty indexPath keeps them in
+// memory only
+func NewKnowledgeBase(embedder Embedder, indexPath string) *KnowledgeBase {
+	return &KnowledgeBase{embedder: embedder, indexPath: indexPath}
+}
+
+// DefaultKnowledgeIndexPath returns where the index of the docs in docsDir
+// is kept under configDir
+func DefaultKnowledgeIndexPath(configDir, docsDir string) string {
+	if abs, err := filepath.Abs(docsDir); err == nil {
+		docsDir = abs
+	}
+	sum := sha256.Sum256([]byte(docsDir))
+	return filepath.Join(configDir, "knowledge", hex.EncodeToString(sum[:8])+".json")
+}
+
+// IngestDir indexes the markdown docs under dir, replacing what the
+// knowledge base held. Only docs that are new or changed since the index
+// was last saved are embedded
+func (k *KnowledgeBase) IngestDir(ctx context.Context, dir string) (IngestStats, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return IngestStats{}, fmt.Errorf("failed to read docs directory: %w", err)
+	}
+	if !info.IsDir() {
+		return IngestStats{}, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	previous := k.loadIndex()
+	index := knowledgeIndex{Model: k.embedder.Model(), Files: make(map[string]indexedFile)}
+
+	var stats IngestStats
+	var pending []*DocChunk
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isMarkdown(entry.Name()) {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		source, err := filepath.Rel(dir, path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		source = filepath.ToSlash(source)
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+
+		stats.Files++
+		if old, ok := previous.Files[source]; ok && old.Hash == hash {
+			index.Files[source] = old
+			return nil
+		}
+
+		stats.Embedded++
+		file := indexedFile{Hash: hash, Chunks: chunkMarkdown(source, string(data))}
+		index.Files[source] = file
+		for i := range file.Chunks {
+			pending = append(pending, &file.Chunks[i])
+		}
+		return nil
+	})
+	if err != nil {
+		return IngestStats{}, fmt.Errorf("failed to ingest docs: %w", err)
+	}
+
+	for start := 0; start < len(pending); start += embedBatchSize {
+		batch := pending[start:min(start+embedBatchSize, len(pending))]
+		texts := make([]string, len(batch))
+		for i, chunk := range batch {
+			texts[i], _ = redact(chunkEmbeddingText(*chunk))
+		}
+		vectors, err := k.embedder.Embed(ctx, texts)
+		if err != nil {
+			return IngestStats{}, fmt.Errorf("failed to embed docs: %w", err)
+		}
+		for i, chunk := range batch {
+			chunk.Vector = vectors[i]
+		}
+	}
+
+	sources := make([]string, 0, len(index.Files))
+	for source := range index.Files {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	k.chunks = nil
+	for _, source := range sources {
+		k.chunks = append(k.chunks, index.Files[source].Chunks...)
+	}
+	stats.Chunks = len(k.chunks)
+
+	if err := k.saveIndex(index); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// Len returns how many chunks the knowledge base holds
+func (k *KnowledgeBase) Len() int {
+	return len(k.chunks)
+}
+
+// Search returns the topK chunks most similar to query, most similar first.
+// The returned chunks carry their score but not their vector
+func (k *KnowledgeBase) Search(ctx context.Context, query string, topK int) ([]DocChunk, error) {
+	if len(k.chunks) == 0 || topK <= 0 {
+		return nil, nil
+	}
+
+	// The embeddings endpoint is a model provider too, so it never sees
+	// secrets that chatMessages would mask
+	text, _ := redact(query)
+	vectors, err := k.embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	matches := make([]DocChunk, 0, len(k.chunks))
+	for _, chunk := range k.chunks {
+		score := cosineSimilarity(vectors[0], chunk.Vector)
+		if score <= 0 {
+			continue
+		}
+		chunk.Score = score
+		chunk.Vector = nil
+		matches = append(matches, chunk)
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > topK {
+		matches = matches[:topK]
+	}
+	return matches, nil
+}
+
+// loadIndex reads the saved index, returning an empty one when there is
+// none or it was embedded with another model
+func (k *KnowledgeBase) loadIndex() knowledgeIndex {
+	empty := knowledgeIndex{Files: map[string]indexedFile{}}
+	if k.indexPath == "" {
+		return empty
+	}
+	data, err := os.ReadFile(k.indexPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.Debugf("Ignoring unreadable knowledge index %s: %v", k.indexPath, err)
+		}
+		return empty
+	}
+	var index knowledgeIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		logrus.Debugf("Ignoring corrupt knowledge index %s: %v", k.indexPath, err)
+		return empty
+	}
+	if index.Model != k.embedder.Model() || index.Files == nil {
+		return empty
+	}
+	return index
+}
+
+// saveIndex writes the index so the next ingest can reuse its embeddings
+func (k *KnowledgeBase) saveIndex(index knowledgeIndex) error {
+	if k.indexPath == "" {
+		return nil
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("failed to marshal knowledge index: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(k.indexPath), 0755); err != nil {
+		return fmt.Errorf("failed to create knowledge index directory: %w", err)
+	}
+	if err := os.WriteFile(k.indexPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write knowledge index: %w", err)
+	}
+	return nil
+}
+
+// isMarkdown reports whether name is a markdown file
+func isMarkdown(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
+
+// chunkMarkdown splits a markdown doc into its sections, splitting sections
+// larger than maxChunkBytes at line boundaries. Headings inside fenced code
+// blocks, such as shell comments, do not start sections
+func chunkMarkdown(source, content string) []DocChunk {
+	var chunks []DocChunk
+	heading := ""
+	var body strings.Builder
+
+	flush := func() {
+		text := strings.TrimSpace(body.String())
+		body.Reset()
+		if text != "" {
+			chunks = append(chunks, DocChunk{Source: source, Heading: heading, Text: text})
+		}
+	}
+
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		} else if !inFence && strings.HasPrefix(trimmed, "#") {
+			if title := strings.TrimSpace(strings.TrimLeft(trimmed, "#")); title != "" {
+				flush()
+				heading = title
+				continue
+			}
+		}
+
+		if body.Len() > 0 && body.Len()+len(line)+1 > maxChunkBytes {
+			flush()
+		}
+		body.WriteString(line)
+		body.WriteByte('\n')
+	}
+	flush()
+	return chunks
+}
+
+// chunkEmbeddingText is what is embedded for a chunk; the heading helps
+// match sections whose body does not repeat their subject
+func chunkEmbeddingText(chunk DocChunk) string {
+	if chunk.Heading == "" {
+		return chunk.Text
+	}
+	return chunk.Heading + "\n\n" + chunk.Text
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors, or
+// 0 when they differ in length or either is zero
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// formatDocChunks presents retrieved excerpts to the model ahead of the question
+func formatDocChunks(chunks []DocChunk) string {
+	var b strings.Builder
+	b.WriteString("Excerpts from the user's own documentation that may help answer the next question. Prefer them over general advice where they apply, and name the source when you use one.")
+	for i, chunk := range chunks {
+		label := chunk.Source
+		if chunk.Heading != "" {
+			label += " > " + chunk.Heading
+		}
+		fmt.Fprintf(&b, "\n\n[%d] %s\n%s", i+1, label, chunk.Text)
+	}
+	return b.String()
+}
@@ -0,0 +1,274 @@
+package agents
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+)
+
+// DefaultOllamaEndpoint is where a local Ollama server listens by default
+const DefaultOllamaEndpoint = "http://localhost:11434"
+
+// maxOllamaLine bounds the size of a single line of a streamed reply
+const maxOllamaLine = 1024 * 1024
+
+// OllamaAgent implements the Agent interface using a model served by
+// Ollama, so queries never leave the machine or network it runs on
+type OllamaAgent struct {
+	*BaseAgent
+	httpClient   *http.Client
+	endpoint     string
+	systemPrompt string
+}
+
+// ollamaMessage is a message of an Ollama chat
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ollamaOptions are the model parameters of an Ollama chat request
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+}
+
+// ollamaChatRequest is the body of a request to /api/chat
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Options  ollamaOptions   `json:"options"`
+}
+
+// ollamaChatResponse is a reply from /api/chat, or one line of a streamed
+// reply; only the last line of a stream is done and carries the token counts
+type ollamaChatResponse struct {
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// NewOllamaAgent creates a new agent backed by an Ollama server. No API key
+// is needed; the endpoint defaults to a server on localhost
+func NewOllamaAgent(cfg config.Agent, agentType string) (*OllamaAgent, error) {
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("an Ollama model is required, such as llama3")
+	}
+
+	endpoint := strings.TrimSuffix(cfg.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = DefaultOllamaEndpoint
+	}
+
+	baseAgent := &BaseAgent{
+		name:    fmt.Sprintf("ollama-%s", agentType),
+		config:  cfg,
+		context: context.Background(),
+		cache:   newResponseCache(cfg.CacheTTLSeconds),
+	}
+
+	return &OllamaAgent{
+		BaseAgent:    baseAgent,
+		httpClient:   &http.Client{Transport: &utils.TracingTransport{}},
+		endpoint:     endpoint,
+		systemPrompt: getSystemPrompt(agentType),
+	}, nil
+}
+
+// Query processes a query using the Ollama model
+func (o *OllamaAgent) Query(ctx context.Context, query *Query) (*Response, error) {
+	return o.StreamQuery(ctx, query, nil)
+}
+
+// StreamQuery processes a query using the Ollama model, passing each piece
+// of the reply to onDelta as it arrives. A nil onDelta asks for the reply
+// in one piece
+func (o *OllamaAgent) StreamQuery(ctx context.Context, query *Query, onDelta func(delta string)) (*Response, error) {
+	o.startQuery()
+
+	messages := chatMessages(o.systemPrompt, query)
+	key := ""
+	if o.useCache(query) {
+		key = o.cacheKey(chatPrompt(messages))
+		if cached, ok := o.cache.get(key); ok {
+			o.status.State = "idle"
+			if onDelta != nil {
+				onDelta(cached.Text)
+			}
+			return cached, nil
+		}
+	}
+
+	request := ollamaChatRequest{
+		Model:    o.config.Model,
+		Messages: make([]ollamaMessage, 0, len(messages)),
+		Stream:   onDelta != nil,
+		Options:  ollamaOptions{Temperature: o.config.Temperature, NumPredict: o.config.MaxTokens},
+	}
+	for _, message := range messages {
+		request.Messages = append(request.Messages, ollamaMessage{Role: message.Role, Content: message.Content})
+	}
+
+	reply, err := o.chat(ctx, request, onDelta)
+	if err != nil {
+		o.status.State = "error"
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	o.status.State = "idle"
+	response := o.newResponse(reply)
+	if key != "" {
+		o.cache.put(key, response)
+	}
+	return response, nil
+}
+
+// chat posts a request to /api/chat and reads the reply, joining the lines
+// of a streamed reply into one
+func (o *OllamaAgent) chat(ctx context.Context, request ollamaChatRequest, onDelta func(delta string)) (*ollamaChatResponse, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Ollama request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.endpoint+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Ollama request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, ollamaUnreachable(o.endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, ollamaStatusError(resp, request.Model)
+	}
+
+	var reply ollamaChatResponse
+	var content strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxOllamaLine)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode Ollama response: %w", err)
+		}
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("Ollama API error: %s", chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			if onDelta != nil {
+				onDelta(chunk.Message.Content)
+			}
+		}
+		if chunk.Done {
+			reply = chunk
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read Ollama response: %w", err)
+	}
+	if !reply.Done {
+		return nil, fmt.Errorf("Ollama response ended before the reply was done")
+	}
+
+	reply.Message.Content = content.String()
+	return &reply, nil
+}
+
+// ollamaUnreachable explains a failed request to the Ollama server, which
+// refuses connections when it is not running
+func ollamaUnreachable(endpoint string, err error) error {
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("failed to reach Ollama at %s: is Ollama running? Start it with `ollama serve`", endpoint)
+	}
+	return fmt.Errorf("failed to reach Ollama at %s: %w", endpoint, err)
+}
+
+// ollamaStatusError reads the error of a failed Ollama response. Ollama
+// answers 404 for models that have not been pulled
+func ollamaStatusError(resp *http.Response, model string) error {
+	var failure struct {
+		Error string `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxOllamaLine))
+	if json.Unmarshal(data, &failure) != nil || failure.Error == "" {
+		failure.Error = strings.TrimSpace(string(data))
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("Ollama API error: %s; pull the model with `ollama pull %s`", failure.Error, model)
+	}
+	return fmt.Errorf("Ollama API error: %s (status %d)", failure.Error, resp.StatusCode)
+}
+
+// newResponse builds a Response from a completed reply
+func (o *OllamaAgent) newResponse(reply *ollamaChatResponse) *Response {
+	content := reply.Message.Content
+	usage := openai.Usage{
+		PromptTokens:     reply.PromptEvalCount,
+		CompletionTokens: reply.EvalCount,
+		TotalTokens:      reply.PromptEvalCount + reply.EvalCount,
+	}
+
+	response := &Response{
+		Text:       content,
+		Content:    content,
+		Type:       "text",
+		Confidence: calculateConfidence(usage),
+		Metadata: map[string]interface{}{
+			"agent_type":        o.GetType(),
+			"provider":          config.ProviderOllama,
+			"model":             o.config.Model,
+			"tokens_used":       usage.TotalTokens,
+			"prompt_tokens":     usage.PromptTokens,
+			"completion_tokens": usage.CompletionTokens,
+			"finish_reason":     reply.DoneReason,
+		},
+		Suggestions: parseSuggestions(content),
+		Actions:     parseActions(content),
+		Timestamp:   time.Now().UTC(),
+	}
+
+	return AttachReferences(response)
+}
+
+// startQuery marks the agent as processing a query
+func (o *OllamaAgent) startQuery() {
+	if o.status == nil {
+		o.status = &AgentStatus{}
+	}
+	o.status.LastActivity = time.Now().UTC()
+	o.status.State = "processing"
+}
+
+// GetCapabilities returns the capabilities of the Ollama agent
+func (o *OllamaAgent) GetCapabilities() []string {
+	return []string{"chat", "analysis", "recommendations", "offline"}
+}
@@ -2,12 +2,19 @@ package agents
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/utils"
 	"github.com/sashabaranov/go-openai"
+	"github.com/sirupsen/logrus"
 )
 
 // OpenAIAgent implements the Agent interface using OpenAI's GPT models
@@ -19,16 +26,16 @@ type OpenAIAgent struct {
 
 // NewOpenAIAgent creates a new OpenAI-powered agent
 func NewOpenAIAgent(cfg config.Agent, agentType string) (*OpenAIAgent, error) {
-	if cfg.APIKey == "" {
-		return nil, fmt.Errorf("OpenAI API key is required")
+	client, err := newOpenAIClient(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	client := openai.NewClient(cfg.APIKey)
-
 	baseAgent := &BaseAgent{
 		name:    fmt.Sprintf("openai-%s", agentType),
 		config:  cfg,
 		context: context.Background(),
+		cache:   newResponseCache(cfg.CacheTTLSeconds),
 	}
 
 	agent := &OpenAIAgent{
@@ -40,78 +47,278 @@ func NewOpenAIAgent(cfg config.Agent, agentType string) (*OpenAIAgent, error) {
 	return agent, nil
 }
 
+// newOpenAIClient creates an OpenAI API client for the agent's key and endpoint
+func newOpenAIClient(cfg config.Agent) (*openai.Client, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("OpenAI API key is required")
+	}
+
+	clientConfig := openai.DefaultConfig(cfg.APIKey)
+	if cfg.Endpoint != "" {
+		clientConfig.BaseURL = strings.TrimSuffix(cfg.Endpoint, "/")
+	}
+	clientConfig.HTTPClient = &http.Client{Transport: &utils.TracingTransport{}}
+	return openai.NewClientWithConfig(clientConfig), nil
+}
+
 // Query processes a query using OpenAI's GPT model
 func (o *OpenAIAgent) Query(ctx context.Context, query *Query) (*Response, error) {
-	// Update last activity
+	o.startQuery()
+
+	request := o.chatRequest(query)
+	key := ""
+	if o.useCache(query) {
+		key = o.cacheKey(chatPrompt(request.Messages))
+		if cached, ok := o.cache.get(key); ok {
+			o.status.State = "idle"
+			return cached, nil
+		}
+	}
+
+	// Make the API call, running the tools the model calls until it answers
+	var usage openai.Usage
+	var choice openai.ChatCompletionChoice
+	toolCalls := 0
+	for iteration := 0; ; iteration++ {
+		resp, err := o.client.CreateChatCompletion(ctx, request)
+		if err != nil {
+			o.status.State = "error"
+			return nil, fmt.Errorf("OpenAI API error: %w", err)
+		}
+
+		if len(resp.Choices) == 0 {
+			o.status.State = "error"
+			return nil, fmt.Errorf("no response from OpenAI")
+		}
+		usage.PromptTokens += resp.Usage.PromptTokens
+		usage.CompletionTokens += resp.Usage.CompletionTokens
+		usage.TotalTokens += resp.Usage.TotalTokens
+
+		choice = resp.Choices[0]
+		if len(choice.Message.ToolCalls) == 0 || query.ExecuteTool == nil {
+			break
+		}
+		if iteration == MaxToolIterations {
+			o.status.State = "error"
+			return nil, fmt.Errorf("model still calling tools after %d rounds", MaxToolIterations)
+		}
+
+		toolCalls += len(choice.Message.ToolCalls)
+		request.Messages = append(request.Messages, choice.Message)
+		request.Messages = append(request.Messages, runToolCalls(ctx, query.ExecuteTool, choice.Message.ToolCalls)...)
+	}
+
+	o.status.State = "idle"
+	response := o.newResponse(choice.Message.Content, usage, choice.FinishReason)
+	for _, call := range choice.Message.ToolCalls {
+		parsed, err := parseToolCall(call)
+		if err != nil {
+			return nil, err
+		}
+		response.Actions = append(response.Actions, toolAction(parsed))
+	}
+	if toolCalls > 0 {
+		response.Metadata["tool_calls"] = toolCalls
+	}
+	if key != "" {
+		o.cache.put(key, response)
+	}
+	return response, nil
+}
+
+// StreamQuery processes a query using OpenAI's GPT model, passing each piece
+// of the reply to onDelta as it arrives
+func (o *OpenAIAgent) StreamQuery(ctx context.Context, query *Query, onDelta func(delta string)) (*Response, error) {
+	// Tool calls are only read from complete replies
+	if len(query.Tools) > 0 {
+		response, err := o.Query(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		onDelta(response.Text)
+		return response, nil
+	}
+
+	o.startQuery()
+
+	request := o.chatRequest(query)
+	key := ""
+	if o.useCache(query) {
+		key = o.cacheKey(chatPrompt(request.Messages))
+		if cached, ok := o.cache.get(key); ok {
+			o.status.State = "idle"
+			onDelta(cached.Text)
+			return cached, nil
+		}
+	}
+
+	request.Stream = true
+	request.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+
+	stream, err := o.client.CreateChatCompletionStream(ctx, request)
+	if err != nil {
+		o.status.State = "error"
+		return nil, fmt.Errorf("OpenAI API error: %w", err)
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	var usage openai.Usage
+	var finishReason openai.FinishReason
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			o.status.State = "error"
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("OpenAI API error: %w", err)
+		}
+
+		if chunk.Usage != nil {
+			usage = *chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				onDelta(choice.Delta.Content)
+			}
+		}
+	}
+
+	o.status.State = "idle"
+	response := o.newResponse(content.String(), usage, finishReason)
+	if key != "" {
+		o.cache.put(key, response)
+	}
+	return response, nil
+}
+
+// startQuery marks the agent as processing a query
+func (o *OpenAIAgent) startQuery() {
 	if o.status == nil {
 		o.status = &AgentStatus{}
 	}
 	o.status.LastActivity = time.Now().UTC()
 	o.status.State = "processing"
+}
 
-	// Prepare the conversation
+// chatRequest builds the chat completion request for a query
+func (o *OpenAIAgent) chatRequest(query *Query) openai.ChatCompletionRequest {
+	return openai.ChatCompletionRequest{
+		Model:       o.config.Model,
+		Messages:    chatMessages(o.systemPrompt, query),
+		MaxTokens:   o.config.MaxTokens,
+		Temperature: float32(o.config.Temperature),
+		Tools:       openAITools(query.Tools),
+	}
+}
+
+// chatMessages builds the conversation sent to a chat model for a query
+func chatMessages(systemPrompt string, query *Query) []openai.ChatCompletionMessage {
+	// Prepare the conversation, replaying earlier turns before the query
 	messages := []openai.ChatCompletionMessage{
 		{
 			Role:    openai.ChatMessageRoleSystem,
-			Content: o.systemPrompt,
-		},
-		{
-			Role:    openai.ChatMessageRoleUser,
-			Content: query.Text,
+			Content: systemPrompt,
 		},
 	}
+	if history, ok := query.Context[HistoryContextKey].([]Turn); ok {
+		for _, turn := range history {
+			messages = append(messages, openai.ChatCompletionMessage{
+				Role:    turn.Role,
+				Content: turn.Content,
+			})
+		}
+	}
+	// Ground the answer in excerpts retrieved from the user's docs
+	if docs, ok := query.Context[DocsContextKey].([]DocChunk); ok && len(docs) > 0 {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: formatDocChunks(docs),
+		})
+	}
+
+	messages = append(messages, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: query.Text,
+	})
 
 	// Add context if available
-	if len(query.Context) > 0 {
-		contextStr := formatContext(query.Context)
+	if contextStr := formatContext(query.Context); contextStr != "" {
 		messages = append(messages, openai.ChatCompletionMessage{
 			Role:    openai.ChatMessageRoleSystem,
 			Content: fmt.Sprintf("Additional context: %s", contextStr),
 		})
 	}
 
-	// Make the API call
-	resp, err := o.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model:       o.config.Model,
-		Messages:    messages,
-		MaxTokens:   o.config.MaxTokens,
-		Temperature: float32(o.config.Temperature),
-	})
-
-	if err != nil {
-		o.status.State = "error"
-		return nil, fmt.Errorf("OpenAI API error: %w", err)
+	// Add each attached file as its own labelled message
+	if files, ok := query.Context[FilesContextKey].([]FileContext); ok {
+		for _, file := range files {
+			messages = append(messages, openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleUser,
+				Content: formatFileContext(file),
+			})
+		}
 	}
 
-	if len(resp.Choices) == 0 {
-		o.status.State = "error"
-		return nil, fmt.Errorf("no response from OpenAI")
+	// Mask secrets before anything leaves the process
+	redacted := 0
+	for i := range messages {
+		var n int
+		messages[i].Content, n = redact(messages[i].Content)
+		redacted += n
+	}
+	if redacted > 0 {
+		logrus.Debugf("Redacted %d secrets from the query", redacted)
 	}
 
-	o.status.State = "idle"
+	return messages
+}
 
+// chatPrompt flattens chat messages into the prompt a response is cached by
+func chatPrompt(messages []openai.ChatCompletionMessage) string {
+	var prompt strings.Builder
+	for _, message := range messages {
+		prompt.WriteString(message.Role)
+		prompt.WriteByte(0)
+		prompt.WriteString(normalizePrompt(message.Content))
+		prompt.WriteByte(0)
+	}
+	return prompt.String()
+}
+
+// newResponse builds a Response from a completed reply
+func (o *OpenAIAgent) newResponse(content string, usage openai.Usage, finishReason openai.FinishReason) *Response {
 	// Parse the response for actions and suggestions
-	content := resp.Choices[0].Message.Content
 	actions := parseActions(content)
 	suggestions := parseSuggestions(content)
 
-	return &Response{
+	response := &Response{
 		Text:       content,
 		Content:    content,
 		Type:       "text",
-		Confidence: calculateConfidence(resp.Usage),
+		Confidence: calculateConfidence(usage),
 		Metadata: map[string]interface{}{
 			"agent_type":        o.GetType(),
 			"model":             o.config.Model,
-			"tokens_used":       resp.Usage.TotalTokens,
-			"prompt_tokens":     resp.Usage.PromptTokens,
-			"completion_tokens": resp.Usage.CompletionTokens,
-			"finish_reason":     resp.Choices[0].FinishReason,
+			"tokens_used":       usage.TotalTokens,
+			"prompt_tokens":     usage.PromptTokens,
+			"completion_tokens": usage.CompletionTokens,
+			"finish_reason":     finishReason,
 		},
 		Suggestions: suggestions,
 		Actions:     actions,
 		Timestamp:   time.Now().UTC(),
-	}, nil
+	}
+
+	return AttachReferences(response)
 }
 
 // GetCapabilities returns the capabilities of the OpenAI agent
@@ -168,11 +375,16 @@ func getSystemPrompt(agentType string) string {
 	}
 }
 
-// formatContext converts the context map to a readable string
+// formatContext converts the context map to a readable string, leaving out
+// attached files and docs. Keys are sorted so the same context always reads,
+// and is cached, the same
 func formatContext(context map[string]interface{}) string {
 	var parts []string
-	for key, value := range context {
-		parts = append(parts, fmt.Sprintf("%s: %v", key, value))
+	for _, key := range slices.Sorted(maps.Keys(context)) {
+		if key == FilesContextKey || key == DocsContextKey || key == HistoryContextKey || key == NoCacheContextKey {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s: %v", key, context[key]))
 	}
 	return strings.Join(parts, ", ")
 }
@@ -0,0 +1,150 @@
+package agents
+
+import (
+	"regexp"
+	"strings"
+)
+
+// References holds the commands and links an agent response refers to
+type References struct {
+	Commands []CommandReference `json:"commands"`
+	URLs     []string           `json:"urls"`
+}
+
+// CommandReference represents a shell command mentioned in a response
+type CommandReference struct {
+	Command  string `json:"command"`
+	Tool     string `json:"tool"`
+	Language string `json:"language"`
+}
+
+// shellLanguages lists the fenced code block languages treated as shell
+var shellLanguages = map[string]bool{
+	"":           true,
+	"sh":         true,
+	"bash":       true,
+	"shell":      true,
+	"zsh":        true,
+	"console":    true,
+	"terminal":   true,
+	"powershell": true,
+	"ps1":        true,
+}
+
+// urlPattern matches http(s) URLs in free text and markdown links
+var urlPattern = regexp.MustCompile("https?://[^\\s<>()\\[\\]\"'`]+")
+
+// ExtractReferences parses commands from fenced code blocks and URLs from a
+// markdown response
+func ExtractReferences(content string) *References {
+	return &References{
+		Commands: extractCommands(content),
+		URLs:     extractURLs(content),
+	}
+}
+
+// AttachReferences populates the structured references of a response
+func AttachReferences(response *Response) *Response {
+	if response == nil {
+		return nil
+	}
+
+	content := response.Content
+	if content == "" {
+		content = response.Text
+	}
+	response.References = ExtractReferences(content)
+
+	return response
+}
+
+// extractCommands returns shell commands found in fenced code blocks
+func extractCommands(content string) []CommandReference {
+	var commands []CommandReference
+	seen := make(map[string]bool)
+
+	inBlock := false
+	language := ""
+	var pending strings.Builder
+
+	flush := func() {
+		command := strings.TrimSpace(pending.String())
+		pending.Reset()
+		if command == "" || seen[command] {
+			return
+		}
+		seen[command] = true
+		commands = append(commands, CommandReference{
+			Command:  command,
+			Tool:     strings.Fields(command)[0],
+			Language: language,
+		})
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			if inBlock {
+				flush()
+				inBlock = false
+				continue
+			}
+			inBlock = true
+			language = strings.ToLower(strings.TrimSpace(trimmed[3:]))
+			continue
+		}
+
+		if !inBlock || !shellLanguages[language] {
+			continue
+		}
+
+		// Skip blank lines and comments
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			flush()
+			continue
+		}
+
+		// Console blocks mark commands with a prompt; everything else is output
+		if language == "console" || language == "terminal" {
+			if !strings.HasPrefix(trimmed, "$ ") && pending.Len() == 0 {
+				continue
+			}
+		}
+		trimmed = strings.TrimPrefix(trimmed, "$ ")
+
+		// Join line continuations
+		if strings.HasSuffix(trimmed, "\\") {
+			pending.WriteString(strings.TrimSpace(strings.TrimSuffix(trimmed, "\\")))
+			pending.WriteString(" ")
+			continue
+		}
+
+		pending.WriteString(trimmed)
+		flush()
+	}
+
+	// An unterminated block still yields its last command
+	if inBlock {
+		flush()
+	}
+
+	return commands
+}
+
+// extractURLs returns the unique URLs found in the content, in order
+func extractURLs(content string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+
+	for _, match := range urlPattern.FindAllString(content, -1) {
+		url := strings.TrimRight(match, ".,;:!?*_")
+		if url == "" || seen[url] {
+			continue
+		}
+		seen[url] = true
+		urls = append(urls, url)
+	}
+
+	return urls
+}
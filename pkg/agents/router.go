@@ -0,0 +1,210 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/sirupsen/logrus"
+)
+
+// GeneralIntent is the intent of queries no specialized agent claims
+const GeneralIntent = "general"
+
+// intentKeywords maps specialized agent types to the words that route a
+// query to them
+var intentKeywords = map[string][]string{
+	"aws": {
+		"aws", "ec2", "s3", "lambda", "rds", "iam", "eks", "ecs", "dynamodb",
+		"cloudformation", "cloudfront", "route53", "vpc", "sqs", "sns", "fargate", "ami",
+	},
+	"azure": {
+		"azure", "aks", "bicep", "entra", "vnet", "cosmosdb", "blob",
+		"appservice", "subscription", "keyvault",
+	},
+	"gcp": {
+		"gcp", "gcloud", "gke", "gce", "bigquery", "pubsub", "firestore",
+		"cloudrun", "gcs", "spanner", "google",
+	},
+	"kubernetes": {
+		"kubernetes", "k8s", "kubectl", "pod", "pods", "deployment", "deployments",
+		"namespace", "namespaces", "helm", "ingress", "statefulset", "daemonset",
+		"node", "nodes", "cluster", "container", "containers",
+	},
+	"monitoring": {
+		"monitoring", "monitor", "metrics", "metric", "alert", "alerts", "alerting",
+		"prometheus", "grafana", "dashboard", "dashboards", "latency", "uptime",
+		"logs", "tracing", "cpu", "memory",
+	},
+}
+
+// ClassifyIntent returns the agent type best suited to answer query by
+// counting keyword matches. Queries matching no type, or several types
+// equally, are ambiguous and classified as GeneralIntent
+func ClassifyIntent(query string) string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	scores := make(map[string]int, len(intentKeywords))
+	for _, word := range words {
+		for intent, keywords := range intentKeywords {
+			for _, keyword := range keywords {
+				if word == keyword {
+					scores[intent]++
+				}
+			}
+		}
+	}
+
+	best, bestScore, tied := GeneralIntent, 0, false
+	for intent, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = intent, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+	if tied {
+		return GeneralIntent
+	}
+	return best
+}
+
+// AddAgentsFromConfig creates and registers an agent for each configured
+// agent. Agents that fail to initialize are skipped with a warning, so that
+// one misconfigured agent does not keep the others from answering
+func (m *AgentManager) AddAgentsFromConfig(agents map[string]config.Agent) {
+	names := make([]string, 0, len(agents))
+	for name := range agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		agent, err := NewAgent(agents[name])
+		if err != nil {
+			logrus.Warnf("Skipping agent %s: %v", name, err)
+			continue
+		}
+		if err := m.AddAgent(agent); err != nil {
+			logrus.Warnf("Skipping agent %s: %v", name, err)
+		}
+	}
+}
+
+// FindAgent returns the agent registered under name, or else the first
+// agent of that type
+func (m *AgentManager) FindAgent(name string) (Agent, error) {
+	if agent, err := m.GetAgent(name); err == nil {
+		return agent, nil
+	}
+
+	for _, agent := range m.sortedAgents() {
+		if agent.GetType() == name {
+			return agent, nil
+		}
+	}
+	return nil, fmt.Errorf("agent not found: %s", name)
+}
+
+// Route orders the healthy agents by how well they suit query: agents of
+// the classified type first, then general agents, then the rest by name
+func (m *AgentManager) Route(query string) []Agent {
+	intent := ClassifyIntent(query)
+
+	rank := func(agent Agent) int {
+		switch agent.GetType() {
+		case intent:
+			return 0
+		case GeneralIntent:
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	var candidates []Agent
+	for _, agent := range m.sortedAgents() {
+		if agent.IsHealthy() {
+			candidates = append(candidates, agent)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return rank(candidates[i]) < rank(candidates[j]) })
+	return candidates
+}
+
+// QueryBuilder builds the query sent to agent, so its context can be
+// fitted to that agent's limits
+type QueryBuilder func(ctx context.Context, agent Agent) (*Query, error)
+
+// RouteQuery answers queryText with the agent named agentName, or with the
+// best suited agent when agentName is empty. It returns the answer and the
+// agent that gave it, which is nil when no agent could answer
+func (m *AgentManager) RouteQuery(ctx context.Context, queryText, agentName string) (string, Agent, error) {
+	return m.RouteQueryWith(ctx, queryText, agentName, func(ctx context.Context, agent Agent) (*Query, error) {
+		return &Query{
+			Text:    queryText,
+			Context: make(map[string]interface{}),
+		}, nil
+	})
+}
+
+// RouteQueryWith is RouteQuery with the query for each agent tried built by build
+func (m *AgentManager) RouteQueryWith(ctx context.Context, queryText, agentName string, build QueryBuilder) (string, Agent, error) {
+	if agentName != "" {
+		agent, err := m.FindAgent(agentName)
+		if err != nil {
+			return "", nil, err
+		}
+		query, err := build(ctx, agent)
+		if err != nil {
+			return "", nil, err
+		}
+		response, err := agent.Query(ctx, query)
+		if err != nil {
+			return "", nil, fmt.Errorf("agent %s failed: %w", agent.GetName(), err)
+		}
+		m.RecordUsage(agent.GetName(), response)
+		return responseText(response), agent, nil
+	}
+
+	if len(m.ListAgents()) == 0 {
+		return demoResponse, nil, nil
+	}
+
+	for _, agent := range m.Route(queryText) {
+		query, err := build(ctx, agent)
+		if err != nil {
+			return "", nil, err
+		}
+		response, err := agent.Query(ctx, query)
+		if err != nil {
+			continue // Try next agent
+		}
+		m.RecordUsage(agent.GetName(), response)
+		return responseText(response), agent, nil
+	}
+
+	// If no healthy agents, return a fallback response
+	return fmt.Sprintf(fallbackResponse, queryText), nil, nil
+}
+
+// sortedAgents returns the registered agents ordered by name
+func (m *AgentManager) sortedAgents() []Agent {
+	agents := m.ListAgents()
+	sort.Slice(agents, func(i, j int) bool { return agents[i].GetName() < agents[j].GetName() })
+	return agents
+}
+
+// responseText returns the text of a response, whichever field holds it
+func responseText(response *Response) string {
+	if response.Content != "" {
+		return response.Content
+	}
+	return response.Text
+}
@@ -0,0 +1,31 @@
+package agents
+
+import "context"
+
+// Streamer is implemented by agents that can stream a response as it is generated
+type Streamer interface {
+	// StreamQuery passes each piece of the response text to onDelta as it
+	// arrives and returns the complete response once the stream ends
+	StreamQuery(ctx context.Context, query *Query, onDelta func(delta string)) (*Response, error)
+}
+
+// StreamQuery streams an agent's response to query through onDelta. Agents
+// that cannot stream deliver their whole response as a single delta
+func StreamQuery(ctx context.Context, agent Agent, query *Query, onDelta func(delta string)) (*Response, error) {
+	if streamer, ok := agent.(Streamer); ok {
+		return streamer.StreamQuery(ctx, query, onDelta)
+	}
+
+	response, err := agent.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	text := response.Text
+	if text == "" {
+		text = response.Content
+	}
+	if text != "" {
+		onDelta(text)
+	}
+	return response, nil
+}
@@ -0,0 +1,114 @@
+package agents
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// TemplatesDirName is the directory under the config dir whose .tmpl files
+// are prompt templates named after the file
+const TemplatesDirName = "templates"
+
+// BuiltinTemplates are the prompt templates available without any
+// configuration. Configured templates of the same name replace them
+var BuiltinTemplates = map[string]string{
+	"incident-triage": `We have an incident affecting {{.service}}. Symptoms: {{.symptoms}}.
+Help me triage it: list the most likely causes in order, the commands or dashboards to check each one, how to mitigate the impact right now, and what to capture for the postmortem.`,
+	"cost-review": `Review the {{.provider}} costs of our infrastructure over the last {{.period}}.
+Point out the biggest cost drivers, idle or oversized resources, and commitments or pricing options that would save money, with the estimated savings and risk of each change.`,
+	"security-audit": `Audit the security of {{.target}}.
+Check access control and least privilege, exposed services and ports, secrets handling, encryption in transit and at rest, patch levels, and logging. Rank the findings by severity and give a fix for each.`,
+	"restart-runbook": `Write a runbook for safely restarting {{.service}}.
+Cover the pre-checks, how to drain traffic, the restart itself, how to verify it is healthy again, and how to roll back if it is not.`,
+}
+
+// PromptTemplates are the named prompt templates questions can be asked with
+type PromptTemplates struct {
+	templates map[string]string
+}
+
+// LoadPromptTemplates collects the built-in templates, the .tmpl files in
+// dir and the configured templates, each replacing templates of the same
+// name from the ones before. A missing dir is skipped
+func LoadPromptTemplates(configured map[string]string, dir string) (*PromptTemplates, error) {
+	t := &PromptTemplates{templates: make(map[string]string)}
+	for name, text := range BuiltinTemplates {
+		t.templates[name] = text
+	}
+
+	if dir != "" {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list templates: %w", err)
+		}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read template: %w", err)
+			}
+			t.templates[templateName(strings.TrimSuffix(filepath.Base(path), ".tmpl"))] = string(data)
+		}
+	}
+
+	for name, text := range configured {
+		t.templates[templateName(name)] = text
+	}
+	return t, nil
+}
+
+// Names returns the names of the templates in order
+func (t *PromptTemplates) Names() []string {
+	names := make([]string, 0, len(t.templates))
+	for name := range t.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RenderTemplate fills in the named template with vars. Every variable the
+// template uses must be set
+func (t *PromptTemplates) RenderTemplate(name string, vars map[string]string) (string, error) {
+	text, ok := t.templates[templateName(name)]
+	if !ok {
+		return "", fmt.Errorf("unknown template %q; available templates: %s", name, strings.Join(t.Names(), ", "))
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	if vars == nil {
+		vars = map[string]string{}
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// ParseTemplateVars parses key=value pairs, such as those of --var flags
+func ParseTemplateVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid template variable %q; use key=value", pair)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// templateName normalizes a template name; configured names are lowercased
+// when the config is read, so names match regardless of case
+func templateName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
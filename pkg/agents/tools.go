@@ -0,0 +1,102 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/sirupsen/logrus"
+)
+
+// ToolActionType is the Action type of a tool call the model requested
+const ToolActionType = "tool-call"
+
+// MaxToolIterations bounds how many rounds of tool calls a query may make
+// before the agent gives up on an answer
+const MaxToolIterations = 5
+
+// Tool is a function the model may ask to call while answering a query
+type Tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Parameters is the JSON schema of the arguments the tool takes
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolCall is a call of a tool the model requested
+type ToolCall struct {
+	ID        string                 `json:"id"`
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+// ToolExecutor runs a tool call and returns its result for the model
+type ToolExecutor func(ctx context.Context, call ToolCall) (string, error)
+
+// openAITools converts tools to the chat completion tools parameter
+func openAITools(tools []Tool) []openai.Tool {
+	if len(tools) == 0 {
+		return nil
+	}
+	converted := make([]openai.Tool, 0, len(tools))
+	for _, tool := range tools {
+		definition := &openai.FunctionDefinition{Name: tool.Name, Description: tool.Description}
+		if len(tool.Parameters) > 0 {
+			definition.Parameters = tool.Parameters
+		} else {
+			definition.Parameters = json.RawMessage(`{"type":"object","properties":{}}`)
+		}
+		converted = append(converted, openai.Tool{Type: openai.ToolTypeFunction, Function: definition})
+	}
+	return converted
+}
+
+// parseToolCall decodes the JSON arguments of a tool call the model requested
+func parseToolCall(call openai.ToolCall) (ToolCall, error) {
+	parsed := ToolCall{ID: call.ID, Name: call.Function.Name, Arguments: map[string]interface{}{}}
+	if call.Function.Arguments == "" {
+		return parsed, nil
+	}
+	if err := json.Unmarshal([]byte(call.Function.Arguments), &parsed.Arguments); err != nil {
+		return parsed, fmt.Errorf("invalid arguments for tool %s: %w", call.Function.Name, err)
+	}
+	return parsed, nil
+}
+
+// toolAction surfaces a tool call as an action for the caller to run
+func toolAction(call ToolCall) Action {
+	return Action{
+		Type:        ToolActionType,
+		Description: fmt.Sprintf("Call tool %s", call.Name),
+		Command:     call.Name,
+		Parameters:  call.Arguments,
+	}
+}
+
+// runToolCalls executes the calls of a model reply, returning the tool
+// messages that feed their results back. A failing call is reported to the
+// model as its result so it can recover
+func runToolCalls(ctx context.Context, execute ToolExecutor, calls []openai.ToolCall) []openai.ChatCompletionMessage {
+	messages := make([]openai.ChatCompletionMessage, 0, len(calls))
+	for _, call := range calls {
+		result := ""
+		parsed, err := parseToolCall(call)
+		if err == nil {
+			result, err = execute(ctx, parsed)
+		}
+		if err != nil {
+			result = fmt.Sprintf("error: %v", err)
+		}
+		result, redacted := redact(result)
+		if redacted > 0 {
+			logrus.Debugf("Redacted %d secrets from the result of tool %s", redacted, call.Function.Name)
+		}
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:       openai.ChatMessageRoleTool,
+			Content:    result,
+			ToolCallID: call.ID,
+		})
+	}
+	return messages
+}
@@ -0,0 +1,297 @@
+package agents
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// UsageLogFileName is the JSONL file agent usage is appended to under the config dir
+const UsageLogFileName = "agent_usage.jsonl"
+
+// maxUsageLine bounds the size of a single usage record
+const maxUsageLine = 64 * 1024
+
+// ModelPrice is what a model costs in US dollars per 1,000 tokens
+type ModelPrice struct {
+	Prompt     float64 `json:"prompt" yaml:"prompt"`
+	Completion float64 `json:"completion" yaml:"completion"`
+}
+
+// ModelPrices lists the list prices of the OpenAI models agents use. A
+// model not listed is priced as the longest listed prefix of its name, so
+// dated snapshots such as gpt-4-0613 are priced as gpt-4
+var ModelPrices = map[string]ModelPrice{
+	"gpt-4":         {Prompt: 0.03, Completion: 0.06},
+	"gpt-4-32k":     {Prompt: 0.06, Completion: 0.12},
+	"gpt-4-turbo":   {Prompt: 0.01, Completion: 0.03},
+	"gpt-4o":        {Prompt: 0.0025, Completion: 0.01},
+	"gpt-4o-mini":   {Prompt: 0.00015, Completion: 0.0006},
+	"gpt-3.5-turbo": {Prompt: 0.0005, Completion: 0.0015},
+}
+
+// modelPrice returns the price of model, reporting whether it is known
+func modelPrice(model string) (ModelPrice, bool) {
+	if price, ok := ModelPrices[model]; ok {
+		return price, true
+	}
+	best := ""
+	for name := range ModelPrices {
+		if strings.HasPrefix(model, name+"-") && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return ModelPrices[best], true
+}
+
+// EstimateCost estimates what the tokens of a response cost with model,
+// reporting whether the model's price is known
+func EstimateCost(model string, promptTokens, completionTokens int) (float64, bool) {
+	price, ok := modelPrice(model)
+	if !ok {
+		return 0, false
+	}
+	return float64(promptTokens)/1000*price.Prompt + float64(completionTokens)/1000*price.Completion, true
+}
+
+// UsageRecord is the token usage of a single agent response
+type UsageRecord struct {
+	Timestamp        time.Time `json:"timestamp"`
+	Agent            string    `json:"agent"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	// Cached responses were answered without calling the model
+	Cached bool `json:"cached,omitempty"`
+}
+
+// ResponseUsage reads the token usage an agent reported in a response's
+// metadata. It reports false for responses without usage, such as those
+// of agents that do not call a model
+func ResponseUsage(agentName string, response *Response) (UsageRecord, bool) {
+	if response == nil {
+		return UsageRecord{}, false
+	}
+	prompt, okPrompt := response.Metadata["prompt_tokens"].(int)
+	completion, okCompletion := response.Metadata["completion_tokens"].(int)
+	if !okPrompt || !okCompletion {
+		return UsageRecord{}, false
+	}
+	model, _ := response.Metadata["model"].(string)
+	cached, _ := response.Metadata["cached"].(bool)
+	timestamp := response.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now().UTC()
+	}
+	return UsageRecord{
+		Timestamp:        timestamp,
+		Agent:            agentName,
+		Model:            model,
+		PromptTokens:     prompt,
+		CompletionTokens: completion,
+		Cached:           cached,
+	}, true
+}
+
+// ModelUsage totals the usage of one model
+type ModelUsage struct {
+	Model            string `json:"model" yaml:"model"`
+	Queries          int    `json:"queries" yaml:"queries"`
+	CachedQueries    int    `json:"cached_queries" yaml:"cached_queries"`
+	PromptTokens     int    `json:"prompt_tokens" yaml:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens" yaml:"completion_tokens"`
+	TotalTokens      int    `json:"total_tokens" yaml:"total_tokens"`
+	// EstimatedCost is in US dollars; zero when the model is not priced
+	EstimatedCost float64 `json:"estimated_cost" yaml:"estimated_cost"`
+	Priced        bool    `json:"priced" yaml:"priced"`
+}
+
+// UsageStats totals agent usage across models
+type UsageStats struct {
+	Queries          int          `json:"queries" yaml:"queries"`
+	CachedQueries    int          `json:"cached_queries" yaml:"cached_queries"`
+	PromptTokens     int          `json:"prompt_tokens" yaml:"prompt_tokens"`
+	CompletionTokens int          `json:"completion_tokens" yaml:"completion_tokens"`
+	TotalTokens      int          `json:"total_tokens" yaml:"total_tokens"`
+	EstimatedCost    float64      `json:"estimated_cost" yaml:"estimated_cost"`
+	Models           []ModelUsage `json:"models" yaml:"models"`
+}
+
+// usageStats totals usage records. Cached responses count as queries but
+// not as tokens, since the model was not called for them
+func usageStats(records []UsageRecord) UsageStats {
+	models := make(map[string]*ModelUsage)
+	for _, record := range records {
+		usage, ok := models[record.Model]
+		if !ok {
+			usage = &ModelUsage{Model: record.Model}
+			_, usage.Priced = modelPrice(record.Model)
+			models[record.Model] = usage
+		}
+		usage.Queries++
+		if record.Cached {
+			usage.CachedQueries++
+			continue
+		}
+		usage.PromptTokens += record.PromptTokens
+		usage.CompletionTokens += record.CompletionTokens
+		usage.TotalTokens += record.PromptTokens + record.CompletionTokens
+	}
+
+	stats := UsageStats{Models: make([]ModelUsage, 0, len(models))}
+	for _, usage := range models {
+		usage.EstimatedCost, _ = EstimateCost(usage.Model, usage.PromptTokens, usage.CompletionTokens)
+		stats.Queries += usage.Queries
+		stats.CachedQueries += usage.CachedQueries
+		stats.PromptTokens += usage.PromptTokens
+		stats.CompletionTokens += usage.CompletionTokens
+		stats.TotalTokens += usage.TotalTokens
+		stats.EstimatedCost += usage.EstimatedCost
+		stats.Models = append(stats.Models, *usage)
+	}
+	sort.Slice(stats.Models, func(i, j int) bool { return stats.Models[i].Model < stats.Models[j].Model })
+	return stats
+}
+
+// usageTracker collects the usage records of a session
+type usageTracker struct {
+	mu      sync.Mutex
+	records []UsageRecord
+	log     *UsageLog
+}
+
+// RecordUsage adds the token usage of a response from the named agent to
+// the session totals, and to the usage log when one is set. Responses
+// without usage are ignored
+func (m *AgentManager) RecordUsage(agentName string, response *Response) {
+	record, ok := ResponseUsage(agentName, response)
+	if !ok {
+		return
+	}
+
+	m.usage.mu.Lock()
+	m.usage.records = append(m.usage.records, record)
+	log := m.usage.log
+	m.usage.mu.Unlock()
+
+	if log != nil {
+		if err := log.Append(record); err != nil {
+			logrus.Warnf("Failed to record agent usage: %v", err)
+		}
+	}
+}
+
+// UsageReport totals the token usage and estimated cost of the responses
+// recorded this session
+func (m *AgentManager) UsageReport() UsageStats {
+	m.usage.mu.Lock()
+	defer m.usage.mu.Unlock()
+	return usageStats(m.usage.records)
+}
+
+// SetUsageLog makes the manager append the usage it records to log
+func (m *AgentManager) SetUsageLog(log *UsageLog) {
+	m.usage.mu.Lock()
+	defer m.usage.mu.Unlock()
+	m.usage.log = log
+}
+
+// UsageLog appends usage records to a JSON lines file, so usage can be
+// reported across sessions
+type UsageLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewUsageLog creates a usage log backed by path
+func NewUsageLog(path string) *UsageLog {
+	return &UsageLog{path: path}
+}
+
+// DefaultUsageLogPath returns where usage is logged under configDir
+func DefaultUsageLogPath(configDir string) string {
+	return filepath.Join(configDir, UsageLogFileName)
+}
+
+// Append writes a record as a new line at the end of the log
+func (l *UsageLog) Append(record UsageRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage record: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create usage log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open usage log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write usage log: %w", err)
+	}
+	return nil
+}
+
+// Report totals the records logged since since; a zero since totals them
+// all. Lines that cannot be parsed, such as one truncated by a crash, are
+// skipped
+func (l *UsageLog) Report(since time.Time) (UsageStats, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return usageStats(nil), nil
+		}
+		return UsageStats{}, fmt.Errorf("failed to open usage log: %w", err)
+	}
+	defer file.Close()
+
+	var records []UsageRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 4*1024), maxUsageLine)
+	for scanner.Scan() {
+		var record UsageRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if record.Timestamp.Before(since) {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return UsageStats{}, fmt.Errorf("failed to read usage log: %w", err)
+	}
+	return usageStats(records), nil
+}
+
+// Clear removes all logged usage
+func (l *UsageLog) Clear() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear usage log: %w", err)
+	}
+	return nil
+}
@@ -1,19 +1,23 @@
 package analyze
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/monitor"
 )
 
 // Analyzer interface defines analysis operations
 type Analyzer interface {
-	AnalyzeLogs(options LogOptions) (*LogAnalysis, error)
-	AnalyzePerformance(options PerformanceOptions) (*PerformanceAnalysis, error)
-	AnalyzeCosts(options CostOptions) (*CostAnalysis, error)
-	AnalyzeSecurity(options SecurityOptions) (*SecurityAnalysis, error)
-	AnalyzeCapacity(options CapacityOptions) (*CapacityAnalysis, error)
+	AnalyzeLogs(ctx context.Context, options LogOptions) (*LogAnalysis, error)
+	AnalyzeLogsStream(ctx context.Context, r io.Reader, options LogOptions) (*LogAnalysis, error)
+	AnalyzePerformance(ctx context.Context, options PerformanceOptions) (*PerformanceAnalysis, error)
+	AnalyzeCosts(ctx context.Context, options CostOptions) (*CostAnalysis, error)
+	AnalyzeSecurity(ctx context.Context, options SecurityOptions) (*SecurityAnalysis, error)
+	AnalyzeCapacity(ctx context.Context, options CapacityOptions) (*CapacityAnalysis, error)
 }
 
 // LogOptions represents log analysis options
@@ -21,6 +25,9 @@ type LogOptions struct {
 	File      string `json:"file" yaml:"file"`
 	Pattern   string `json:"pattern" yaml:"pattern"`
 	TimeRange string `json:"time_range" yaml:"time_range"`
+	// AnomalyStdDev is how many standard deviations above the rolling mean
+	// a minute's log volume must be to be reported as a spike (default 3)
+	AnomalyStdDev float64 `json:"anomaly_stddev,omitempty" yaml:"anomaly_stddev,omitempty"`
 }
 
 // PerformanceOptions represents performance analysis options
@@ -47,6 +54,8 @@ type SecurityOptions struct {
 type CapacityOptions struct {
 	Service  string `json:"service" yaml:"service"`
 	Forecast string `json:"forecast" yaml:"forecast"`
+	// History is how far back the metrics forecasts are based on reach (default 7d)
+	History string `json:"history,omitempty" yaml:"history,omitempty"`
 }
 
 // LogAnalysis represents log analysis results
@@ -236,6 +245,8 @@ type CapacityForecast struct {
 	Predicted      float64    `json:"predicted" yaml:"predicted"`
 	Confidence     float64    `json:"confidence" yaml:"confidence"`
 	ExhaustionDate *time.Time `json:"exhaustion_date,omitempty" yaml:"exhaustion_date,omitempty"`
+	// Method is the model the forecast came from: linear or holt-winters
+	Method string `json:"method" yaml:"method"`
 }
 
 // CapacityAlert represents a capacity alert
@@ -252,6 +263,8 @@ type CapacityAlert struct {
 // AnalyzerImpl implements the Analyzer interface
 type AnalyzerImpl struct {
 	config *config.Config
+	// metrics provides capacity history, a Prometheus monitor if nil
+	metrics MetricsSource
 }
 
 // New creates a new analyzer instance
@@ -266,58 +279,32 @@ func New() (Analyzer, error) {
 	}, nil
 }
 
-// AnalyzeLogs analyzes log files
-func (a *AnalyzerImpl) AnalyzeLogs(options LogOptions) (*LogAnalysis, error) {
-	// Mock implementation
-	analysis := &LogAnalysis{
-		Summary:      "Log analysis completed successfully",
-		ErrorCount:   25,
-		WarningCount: 42,
-		Patterns: []LogPattern{
-			{
-				Pattern:   "connection timeout",
-				Count:     15,
-				Severity:  "error",
-				FirstSeen: time.Now().Add(-24 * time.Hour),
-				LastSeen:  time.Now().Add(-1 * time.Hour),
-				Examples:  []string{"2023-07-11 10:30:25 ERROR: connection timeout to database"},
-			},
-			{
-				Pattern:   "slow query",
-				Count:     8,
-				Severity:  "warning",
-				FirstSeen: time.Now().Add(-12 * time.Hour),
-				LastSeen:  time.Now().Add(-30 * time.Minute),
-				Examples:  []string{"2023-07-11 14:15:30 WARN: slow query detected (2.5s)"},
-			},
-		},
-		Anomalies: []LogAnomaly{
-			{
-				Type:        "spike",
-				Description: "Unusual spike in error messages",
-				Severity:    "high",
-				Timestamp:   time.Now().Add(-2 * time.Hour),
-				Context:     "Error rate increased by 300% during 14:00-15:00",
-			},
-		},
-		Insights: []string{
-			"Database connection issues are the primary cause of errors",
-			"Query performance degraded during peak hours",
-			"Consider implementing connection pooling",
-		},
-		Metadata: map[string]string{
-			"file":           options.File,
-			"lines_analyzed": "10000",
-			"time_range":     options.TimeRange,
-		},
-		Timestamp: time.Now(),
+// AnalyzeLogs analyzes a log file, clustering similar messages into patterns
+func (a *AnalyzerImpl) AnalyzeLogs(ctx context.Context, options LogOptions) (*LogAnalysis, error) {
+	if options.File == "" {
+		return nil, fmt.Errorf("log file is required")
 	}
 
-	return analysis, nil
+	reader, err := openLogFile(options.File)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return a.AnalyzeLogsStream(ctx, reader, options)
+}
+
+// AnalyzeLogsStream analyzes logs read incrementally from r with bounded memory
+func (a *AnalyzerImpl) AnalyzeLogsStream(ctx context.Context, r io.Reader, options LogOptions) (*LogAnalysis, error) {
+	if r == nil {
+		return nil, fmt.Errorf("log reader is required")
+	}
+
+	return analyzeLogReader(ctx, r, options)
 }
 
 // AnalyzePerformance analyzes performance metrics
-func (a *AnalyzerImpl) AnalyzePerformance(options PerformanceOptions) (*PerformanceAnalysis, error) {
+func (a *AnalyzerImpl) AnalyzePerformance(ctx context.Context, options PerformanceOptions) (*PerformanceAnalysis, error) {
 	// Mock implementation
 	analysis := &PerformanceAnalysis{
 		Summary:       "Performance analysis shows moderate resource utilization",
@@ -382,7 +369,7 @@ func (a *AnalyzerImpl) AnalyzePerformance(options PerformanceOptions) (*Performa
 }
 
 // AnalyzeCosts analyzes cloud costs
-func (a *AnalyzerImpl) AnalyzeCosts(options CostOptions) (*CostAnalysis, error) {
+func (a *AnalyzerImpl) AnalyzeCosts(ctx context.Context, options CostOptions) (*CostAnalysis, error) {
 	// Mock implementation
 	analysis := &CostAnalysis{
 		Summary:   "Cost analysis for the past 30 days",
@@ -448,7 +435,7 @@ func (a *AnalyzerImpl) AnalyzeCosts(options CostOptions) (*CostAnalysis, error)
 }
 
 // AnalyzeSecurity analyzes security posture
-func (a *AnalyzerImpl) AnalyzeSecurity(options SecurityOptions) (*SecurityAnalysis, error) {
+func (a *AnalyzerImpl) AnalyzeSecurity(ctx context.Context, options SecurityOptions) (*SecurityAnalysis, error) {
 	// Mock implementation
 	analysis := &SecurityAnalysis{
 		Summary:      "Security analysis completed with moderate risk level",
@@ -498,73 +485,74 @@ func (a *AnalyzerImpl) AnalyzeSecurity(options SecurityOptions) (*SecurityAnalys
 	return analysis, nil
 }
 
-// AnalyzeCapacity analyzes capacity and forecasts
-func (a *AnalyzerImpl) AnalyzeCapacity(options CapacityOptions) (*CapacityAnalysis, error) {
-	// Mock implementation
-	exhaustionDate := time.Now().Add(45 * 24 * time.Hour)
+// AnalyzeCapacity forecasts CPU, memory and storage usage from their
+// history in Prometheus, projecting when each will be exhausted
+func (a *AnalyzerImpl) AnalyzeCapacity(ctx context.Context, options CapacityOptions) (*CapacityAnalysis, error) {
+	period, horizon, err := parseCapacityPeriod(options.Forecast, defaultCapacityForecast, "forecast period")
+	if err != nil {
+		return nil, err
+	}
+	history, _, err := parseCapacityPeriod(options.History, defaultCapacityHistory, "history")
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := a.metrics
+	if metrics == nil {
+		if a.config == nil || a.config.Monitoring.Prometheus.Endpoint == "" {
+			return nil, fmt.Errorf("capacity forecasting needs metrics history: configure monitoring.prometheus.endpoint")
+		}
+		mon, err := monitor.New()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize monitor: %w", err)
+		}
+		metrics = mon
+	}
 
 	analysis := &CapacityAnalysis{
-		Summary: "Capacity analysis shows good resource availability",
-		CurrentUsage: []CapacityMetric{
-			{
-				Resource: "CPU",
-				Current:  25.4,
-				Maximum:  100.0,
-				Usage:    25.4,
-				Unit:     "percent",
-				Status:   "normal",
-				Trend:    "stable",
-			},
-			{
-				Resource: "Memory",
-				Current:  4.2,
-				Maximum:  8.0,
-				Usage:    52.5,
-				Unit:     "GB",
-				Status:   "normal",
-				Trend:    "increasing",
-			},
-			{
-				Resource: "Storage",
-				Current:  750.0,
-				Maximum:  1000.0,
-				Usage:    75.0,
-				Unit:     "GB",
-				Status:   "warning",
-				Trend:    "increasing",
-			},
-		},
-		Forecast: []CapacityForecast{
-			{
-				Resource:       "Storage",
-				Period:         "30 days",
-				Predicted:      950.0,
-				Confidence:     0.85,
-				ExhaustionDate: &exhaustionDate,
-			},
-		},
-		Alerts: []CapacityAlert{
-			{
-				Resource:  "Storage",
-				Type:      "threshold",
-				Severity:  "warning",
-				Message:   "Storage usage approaching 80% threshold",
-				Threshold: 80.0,
-				Current:   75.0,
-				Action:    "Consider adding storage capacity",
-			},
-		},
-		Recommendations: []string{
-			"Plan storage expansion within 30 days",
-			"Monitor memory usage trend",
-			"Consider implementing auto-scaling",
-		},
+		CurrentUsage:    []CapacityMetric{},
+		Forecast:        []CapacityForecast{},
+		Alerts:          []CapacityAlert{},
+		Recommendations: []string{},
 		Metadata: map[string]string{
 			"service":  options.Service,
-			"forecast": options.Forecast,
+			"forecast": period,
+			"history":  history,
 		},
 		Timestamp: time.Now(),
 	}
 
+	for _, q := range capacityQueries {
+		data, err := metrics.GetMetricsContext(ctx, q.promQL(options.Service), history)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s history: %w", q.resource, err)
+		}
+		forecastCapacity(analysis, q.resource, metricSeries(data), 100, horizon, period)
+	}
+	if len(analysis.Forecast) == 0 {
+		return nil, fmt.Errorf("no metrics history found for %s", capacityScope(options.Service))
+	}
+
+	analysis.Summary = fmt.Sprintf("Forecast %d resources over %s: %d projected to run out of capacity",
+		len(analysis.Forecast), period, countAlerts(analysis.Alerts, "exhaustion"))
 	return analysis, nil
 }
+
+// capacityScope names the service a capacity analysis covers
+func capacityScope(service string) string {
+	if service == "" {
+		return "any service"
+	}
+	return service
+}
+
+// countAlerts counts the alerts of a type
+func countAlerts(alerts []CapacityAlert, alertType string) int {
+	count := 0
+	for _, alert := range alerts {
+		if alert.Type == alertType {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,463 @@
+package analyze
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/monitor"
+)
+
+func sampleLogs(now time.Time) string {
+	ts := func(ago time.Duration) string {
+		return now.Add(-ago).UTC().Format(time.RFC3339)
+	}
+	return strings.Join([]string{
+		ts(3*time.Hour) + " ERROR connection timeout to 10.0.0.5:5432 after 30s",
+		ts(2*time.Hour) + " ERROR connection timeout to 10.0.0.7:5432 after 31s",
+		ts(1*time.Hour) + " WARN slow query took 2.5s",
+		ts(30*time.Minute) + " INFO request 42 completed",
+		ts(48*time.Hour) + " ERROR disk full on /dev/sda1",
+		"",
+	}, "\n")
+}
+
+func writeLogFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create log file: %v", err)
+	}
+	defer file.Close()
+
+	if strings.HasSuffix(name, ".gz") {
+		gz := gzip.NewWriter(file)
+		if _, err := gz.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write gzip log file: %v", err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatalf("failed to close gzip writer: %v", err)
+		}
+		return path
+	}
+
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatalf("failed to write log file: %v", err)
+	}
+	return path
+}
+
+func TestAnalyzeLogs(t *testing.T) {
+	now := time.Now()
+	analyzer := &AnalyzerImpl{}
+
+	for _, name := range []string{"app.log", "app.log.gz"} {
+		t.Run(name, func(t *testing.T) {
+			path := writeLogFile(t, name, sampleLogs(now))
+
+			analysis, err := analyzer.AnalyzeLogs(context.Background(), LogOptions{File: path, TimeRange: "24h"})
+			if err != nil {
+				t.Fatalf("AnalyzeLogs() failed: %v", err)
+			}
+
+			if analysis.ErrorCount != 2 {
+				t.Errorf("expected 2 errors within 24h, got %d", analysis.ErrorCount)
+			}
+			if analysis.WarningCount != 1 {
+				t.Errorf("expected 1 warning, got %d", analysis.WarningCount)
+			}
+			if analysis.Metadata["lines_analyzed"] != "4" {
+				t.Errorf("expected 4 lines analyzed, got %s", analysis.Metadata["lines_analyzed"])
+			}
+
+			if len(analysis.Patterns) == 0 {
+				t.Fatal("expected patterns to be found")
+			}
+			top := analysis.Patterns[0]
+			if top.Pattern != "connection timeout to <ip> after <num>s" {
+				t.Errorf("unexpected top pattern: %q", top.Pattern)
+			}
+			if top.Count != 2 || top.Severity != "error" {
+				t.Errorf("expected 2 error occurrences, got %d %s", top.Count, top.Severity)
+			}
+			if !top.FirstSeen.Before(top.LastSeen) {
+				t.Errorf("expected FirstSeen before LastSeen, got %v and %v", top.FirstSeen, top.LastSeen)
+			}
+			if got := now.Sub(top.FirstSeen).Round(time.Hour); got != 3*time.Hour {
+				t.Errorf("expected FirstSeen 3h ago, got %v", got)
+			}
+		})
+	}
+}
+
+func TestAnalyzeLogsPattern(t *testing.T) {
+	path := writeLogFile(t, "app.log", sampleLogs(time.Now()))
+	analyzer := &AnalyzerImpl{}
+
+	analysis, err := analyzer.AnalyzeLogs(context.Background(), LogOptions{File: path, Pattern: `disk|slow`})
+	if err != nil {
+		t.Fatalf("AnalyzeLogs() failed: %v", err)
+	}
+	if analysis.ErrorCount != 1 || analysis.WarningCount != 1 {
+		t.Errorf("expected 1 error and 1 warning, got %d and %d", analysis.ErrorCount, analysis.WarningCount)
+	}
+
+	if _, err := analyzer.AnalyzeLogs(context.Background(), LogOptions{File: path, Pattern: "("}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestAnalyzeLogsMissingFile(t *testing.T) {
+	analyzer := &AnalyzerImpl{}
+
+	_, err := analyzer.AnalyzeLogs(context.Background(), LogOptions{File: filepath.Join(t.TempDir(), "missing.log")})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestAnalyzeLogsStream(t *testing.T) {
+	analyzer := &AnalyzerImpl{}
+
+	analysis, err := analyzer.AnalyzeLogsStream(context.Background(), strings.NewReader(sampleLogs(time.Now())), LogOptions{File: "stdin"})
+	if err != nil {
+		t.Fatalf("AnalyzeLogsStream() failed: %v", err)
+	}
+	if analysis.ErrorCount != 3 || analysis.WarningCount != 1 {
+		t.Errorf("expected 3 errors and 1 warning, got %d and %d", analysis.ErrorCount, analysis.WarningCount)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = analyzer.AnalyzeLogsStream(ctx, strings.NewReader(sampleLogs(time.Now())), LogOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestPatternAggregatorBounded(t *testing.T) {
+	aggregator := newPatternAggregator(3)
+
+	for i := 0; i < 5; i++ {
+		aggregator.Add(&logEntry{Raw: "frequent", Level: "error", Message: "frequent failure"})
+	}
+	for i := 0; i < 10; i++ {
+		aggregator.Add(&logEntry{Raw: "rare", Level: "info", Message: fmt.Sprintf("rare event %c", 'a'+i)})
+	}
+
+	if aggregator.Len() != 3 {
+		t.Errorf("expected 3 tracked patterns, got %d", aggregator.Len())
+	}
+	if aggregator.Evicted() != 8 {
+		t.Errorf("expected 8 evicted patterns, got %d", aggregator.Evicted())
+	}
+
+	top := aggregator.Top(1)
+	if len(top) != 1 || top[0].Pattern != "frequent failure" || top[0].Count != 5 {
+		t.Errorf("expected the frequent pattern to survive eviction, got %+v", top)
+	}
+}
+
+func TestParseLogLine(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		line    string
+		level   string
+		message string
+		year    int
+	}{
+		{"2024-03-10 11:00:00,123 ERROR db: query failed", "error", "db: query failed", 2024},
+		{"[2024-03-10T11:00:00Z] WARN: retrying", "warning", "retrying", 2024},
+		{"Mar  9 23:59:01 host sshd[42]: INFO accepted key", "info", "accepted key", 2024},
+		{`time="2024-03-10T11:00:00Z" level=error msg="boom"`, "error", `time="2024-03-10T11:00:00Z" level=error msg="boom"`, 0},
+		{"Dec 31 23:00:00 host kernel: ERR oops", "error", "oops", 2023},
+	}
+
+	for _, tt := range tests {
+		entry := parseLogLine(tt.line, now)
+		if entry.Level != tt.level {
+			t.Errorf("%q: expected level %q, got %q", tt.line, tt.level, entry.Level)
+		}
+		if entry.Message != tt.message {
+			t.Errorf("%q: expected message %q, got %q", tt.line, tt.message, entry.Message)
+		}
+		if tt.year != 0 && entry.Timestamp.Year() != tt.year {
+			t.Errorf("%q: expected year %d, got %v", tt.line, tt.year, entry.Timestamp)
+		}
+	}
+}
+
+func TestAnomalyDetectorSpike(t *testing.T) {
+	start := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	var lines []string
+	for minute := 0; minute < 30; minute++ {
+		count := 10 + minute%3
+		if minute == 20 {
+			count = 200
+		}
+		for i := 0; i < count; i++ {
+			ts := start.Add(time.Duration(minute)*time.Minute + time.Duration(i)*time.Second/4)
+			lines = append(lines, ts.Format(time.RFC3339)+" INFO request served")
+		}
+	}
+
+	analyzer := &AnalyzerImpl{}
+	analysis, err := analyzer.AnalyzeLogsStream(context.Background(), strings.NewReader(strings.Join(lines, "\n")), LogOptions{})
+	if err != nil {
+		t.Fatalf("AnalyzeLogsStream() failed: %v", err)
+	}
+
+	if len(analysis.Anomalies) != 1 {
+		t.Fatalf("expected 1 anomaly, got %d: %+v", len(analysis.Anomalies), analysis.Anomalies)
+	}
+	anomaly := analysis.Anomalies[0]
+	if anomaly.Type != "spike" || anomaly.Severity != "high" {
+		t.Errorf("expected a high severity spike, got %s %s", anomaly.Severity, anomaly.Type)
+	}
+	if want := start.Add(20 * time.Minute); !anomaly.Timestamp.Equal(want) {
+		t.Errorf("expected spike at %v, got %v", want, anomaly.Timestamp)
+	}
+	if !strings.Contains(anomaly.Context, "observed 200 lines") {
+		t.Errorf("expected observed count in context, got %q", anomaly.Context)
+	}
+
+	// A looser threshold tolerates the burst
+	analysis, err = analyzer.AnalyzeLogsStream(context.Background(), strings.NewReader(strings.Join(lines, "\n")), LogOptions{AnomalyStdDev: 500})
+	if err != nil {
+		t.Fatalf("AnalyzeLogsStream() failed: %v", err)
+	}
+	if len(analysis.Anomalies) != 0 {
+		t.Errorf("expected no anomalies with a loose threshold, got %d", len(analysis.Anomalies))
+	}
+
+	if _, err := analyzer.AnalyzeLogsStream(context.Background(), strings.NewReader(""), LogOptions{AnomalyStdDev: -1}); err == nil {
+		t.Error("expected an error for a negative stddev")
+	}
+}
+
+func TestAnomalyDetectorSteady(t *testing.T) {
+	detector := newAnomalyDetector(0)
+	start := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+	for minute := 0; minute < 120; minute++ {
+		for i := 0; i < 10; i++ {
+			detector.Add(start.Add(time.Duration(minute) * time.Minute))
+		}
+	}
+
+	if anomalies := detector.Anomalies(); len(anomalies) != 0 {
+		t.Errorf("expected no anomalies for steady volume, got %+v", anomalies)
+	}
+}
+
+func TestAnomalyDetectorDistantTimestamps(t *testing.T) {
+	detector := newAnomalyDetector(0)
+	detector.Add(time.Date(1, time.January, 1, 0, 1, 0, 0, time.UTC))
+	detector.Add(time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC))
+
+	start := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	for minute := 0; minute < 10; minute++ {
+		for i := 0; i < 5; i++ {
+			detector.Add(start.Add(time.Duration(minute) * time.Minute))
+		}
+	}
+	for i := 0; i < 100; i++ {
+		detector.Add(start.Add(10 * time.Minute))
+	}
+
+	done := make(chan []LogAnomaly)
+	go func() { done <- detector.Anomalies() }()
+	select {
+	case anomalies := <-done:
+		if len(anomalies) != 1 || !anomalies[0].Timestamp.Equal(start.Add(10*time.Minute)) {
+			t.Errorf("expected only the spike after the gap, got %+v", anomalies)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected distant timestamps not to be walked minute by minute")
+	}
+}
+
+func TestFormatAnalysis(t *testing.T) {
+	analysis := &CostAnalysis{
+		Summary:  "costs",
+		Currency: "USD",
+		Breakdown: []CostBreakdown{
+			{Category: "Compute", Cost: 120.5, Percentage: 60, Change: -2.5, Trend: "down"},
+			{Category: "Storage, archive", Cost: 80, Percentage: 40, Change: 1, Trend: "up"},
+		},
+	}
+
+	out, err := FormatAnalysis(analysis, "csv")
+	if err != nil {
+		t.Fatalf("FormatAnalysis(csv) failed: %v", err)
+	}
+	want := "category,cost,currency,percentage,change,trend\n" +
+		"Compute,120.5,USD,60,-2.5,down\n" +
+		"\"Storage, archive\",80,USD,40,1,up\n"
+	if out != want {
+		t.Errorf("unexpected csv output:\n%s\nwant:\n%s", out, want)
+	}
+
+	out, err = FormatAnalysis(analysis, "JSON")
+	if err != nil {
+		t.Fatalf("FormatAnalysis(json) failed: %v", err)
+	}
+	if !strings.Contains(out, `"category": "Compute"`) {
+		t.Errorf("expected breakdown in json output, got %s", out)
+	}
+
+	out, err = FormatAnalysis(analysis, "yaml")
+	if err != nil {
+		t.Fatalf("FormatAnalysis(yaml) failed: %v", err)
+	}
+	if !strings.Contains(out, "currency: USD") {
+		t.Errorf("expected currency in yaml output, got %s", out)
+	}
+
+	out, err = FormatAnalysis(analysis, "table")
+	if err != nil {
+		t.Fatalf("FormatAnalysis(table) failed: %v", err)
+	}
+	if !strings.Contains(out, "CATEGORY") || !strings.Contains(out, "Storage, archive") {
+		t.Errorf("expected breakdown rows in table output, got %s", out)
+	}
+
+	if _, err := FormatAnalysis(analysis, "xml"); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+	if _, err := FormatAnalysis(struct{}{}, "csv"); err == nil {
+		t.Error("expected an error for csv of an unknown type")
+	}
+}
+
+// fakeMetrics serves a generated series for every capacity query
+type fakeMetrics struct {
+	series  func(i int) float64
+	points  int
+	step    time.Duration
+	end     time.Time
+	queries []string
+}
+
+func (f *fakeMetrics) GetMetricsContext(ctx context.Context, metric, duration string) (*monitor.MetricsData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.queries = append(f.queries, metric)
+	data := &monitor.MetricsData{Metric: metric}
+	for i := 0; i < f.points; i++ {
+		data.Data = append(data.Data, monitor.MetricPoint{
+			Timestamp: f.end.Add(-time.Duration(f.points-1-i) * f.step),
+			Value:     f.series(i),
+		})
+	}
+	return data, nil
+}
+
+func TestAnalyzeCapacityLinearTrend(t *testing.T) {
+	// Usage grows from 50% by 2 points a day, sampled hourly for a week
+	end := time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC)
+	metrics := &fakeMetrics{
+		series: func(i int) float64 { return 50 + float64(i)*2/24 },
+		points: 7*24 + 1,
+		step:   time.Hour,
+		end:    end,
+	}
+	analyzer := &AnalyzerImpl{metrics: metrics}
+
+	analysis, err := analyzer.AnalyzeCapacity(context.Background(), CapacityOptions{Service: "node", Forecast: "30d"})
+	if err != nil {
+		t.Fatalf("AnalyzeCapacity failed: %v", err)
+	}
+	if len(analysis.Forecast) != len(capacityQueries) || len(metrics.queries) != len(capacityQueries) {
+		t.Fatalf("expected a forecast per resource, got %+v", analysis.Forecast)
+	}
+	if !strings.Contains(metrics.queries[0], `mode="idle",job="node"`) {
+		t.Errorf("expected the query to select the service, got %s", metrics.queries[0])
+	}
+
+	forecast := analysis.Forecast[0]
+	if forecast.Method != forecastLinear || forecast.Confidence < 0.99 {
+		t.Errorf("expected a confident linear forecast, got %+v", forecast)
+	}
+	// 64% now, plus 60 points over 30 days
+	if math.Abs(forecast.Predicted-124) > 0.01 {
+		t.Errorf("expected 124%% predicted, got %v", forecast.Predicted)
+	}
+	// 36 points to go at 2 a day
+	want := end.Add(18 * 24 * time.Hour)
+	if forecast.ExhaustionDate == nil || forecast.ExhaustionDate.Sub(want).Abs() > time.Minute {
+		t.Errorf("expected exhaustion at %v, got %v", want, forecast.ExhaustionDate)
+	}
+	if usage := analysis.CurrentUsage[0]; usage.Current != 64 || usage.Trend != "increasing" {
+		t.Errorf("unexpected current usage: %+v", usage)
+	}
+	if len(analysis.Alerts) != len(capacityQueries) || analysis.Alerts[0].Type != "exhaustion" || analysis.Alerts[0].Severity != "warning" {
+		t.Errorf("expected a warning exhaustion alert per resource, got %+v", analysis.Alerts)
+	}
+
+	// Beyond a 7 day window the exhaustion is reported without an alert
+	analysis, err = analyzer.AnalyzeCapacity(context.Background(), CapacityOptions{Forecast: "7d"})
+	if err != nil {
+		t.Fatalf("AnalyzeCapacity failed: %v", err)
+	}
+	if analysis.Forecast[0].ExhaustionDate == nil || len(analysis.Alerts) != 0 {
+		t.Errorf("expected an exhaustion date without alerts, got %+v", analysis)
+	}
+}
+
+func TestAnalyzeCapacitySeasonal(t *testing.T) {
+	// A daily cycle of +-20 points around a slowly rising 40%
+	end := time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC)
+	metrics := &fakeMetrics{
+		series: func(i int) float64 { return 40 + float64(i)*0.01 + 20*math.Sin(2*math.Pi*float64(i)/24) },
+		points: 14 * 24,
+		step:   time.Hour,
+		end:    end,
+	}
+	analyzer := &AnalyzerImpl{metrics: metrics}
+
+	analysis, err := analyzer.AnalyzeCapacity(context.Background(), CapacityOptions{Forecast: "3d"})
+	if err != nil {
+		t.Fatalf("AnalyzeCapacity failed: %v", err)
+	}
+	forecast := analysis.Forecast[0]
+	if forecast.Method != forecastHoltWinters || forecast.Confidence < 0.9 {
+		t.Errorf("expected a confident Holt-Winters forecast, got %+v", forecast)
+	}
+	if forecast.ExhaustionDate != nil || len(analysis.Alerts) != 0 {
+		t.Errorf("expected the peaks to stay below capacity, got %+v", analysis)
+	}
+}
+
+func TestAnalyzeCapacityErrors(t *testing.T) {
+	if _, err := (&AnalyzerImpl{config: &config.Config{}}).AnalyzeCapacity(context.Background(), CapacityOptions{}); err == nil {
+		t.Error("expected an error without a Prometheus endpoint")
+	}
+
+	flat := &AnalyzerImpl{metrics: &fakeMetrics{series: func(int) float64 { return 30 }, points: 1, step: time.Hour}}
+	if _, err := flat.AnalyzeCapacity(context.Background(), CapacityOptions{}); err == nil {
+		t.Error("expected an error with a single sample")
+	}
+	if _, err := flat.AnalyzeCapacity(context.Background(), CapacityOptions{Forecast: "soon"}); err == nil {
+		t.Error("expected an error for an invalid forecast period")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := flat.AnalyzeCapacity(ctx, CapacityOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
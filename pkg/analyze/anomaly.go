@@ -0,0 +1,129 @@
+package analyze
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Anomaly detection settings
+const (
+	// defaultAnomalyStdDev is how many standard deviations above the rolling mean a bucket must be to count as a spike
+	defaultAnomalyStdDev = 3.0
+	// anomalyBucket is the width of a log volume bucket
+	anomalyBucket = time.Minute
+	// anomalyWindow is how many preceding buckets the rolling mean and stddev cover
+	anomalyWindow = 60
+	// anomalyMinHistory is how many preceding buckets are needed before a bucket is judged
+	anomalyMinHistory = 5
+	// anomalyMinStdDev keeps a perfectly flat history from flagging every small change
+	anomalyMinStdDev = 1.0
+)
+
+// anomalyDetector counts log lines per time bucket and flags volume spikes
+type anomalyDetector struct {
+	stdDev  float64
+	buckets map[int64]int
+	// now is when detection started; lines dated after it are ignored
+	now time.Time
+}
+
+// newAnomalyDetector creates a detector flagging buckets above mean + stdDev*stddev
+func newAnomalyDetector(stdDev float64) *anomalyDetector {
+	if stdDev <= 0 {
+		stdDev = defaultAnomalyStdDev
+	}
+	return &anomalyDetector{
+		stdDev:  stdDev,
+		buckets: make(map[int64]int),
+		now:     time.Now(),
+	}
+}
+
+// Add counts a line in the bucket covering ts; lines without a timestamp or
+// dated in the future are ignored
+func (d *anomalyDetector) Add(ts time.Time) {
+	if ts.IsZero() || ts.After(d.now) {
+		return
+	}
+	d.buckets[ts.Unix()/int64(anomalyBucket/time.Second)]++
+}
+
+// Anomalies returns a spike anomaly for every bucket exceeding its rolling threshold
+func (d *anomalyDetector) Anomalies() []LogAnomaly {
+	anomalies := []LogAnomaly{}
+	if len(d.buckets) == 0 {
+		return anomalies
+	}
+
+	keys := make([]int64, 0, len(d.buckets))
+	for key := range d.buckets {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	// Slide over the buckets in order, counting empty ones between them as
+	// zero. Empty buckets never exceed a threshold, so they are only added to
+	// the window, and a gap as long as the window starts it afresh
+	window := make([]float64, 0, anomalyWindow)
+	var sum, sumSq float64
+	push := func(observed float64) {
+		if len(window) == anomalyWindow {
+			oldest := window[0]
+			window = window[1:]
+			sum -= oldest
+			sumSq -= oldest * oldest
+		}
+		window = append(window, observed)
+		sum += observed
+		sumSq += observed * observed
+	}
+
+	for i, key := range keys {
+		if i > 0 {
+			if empty := key - keys[i-1] - 1; empty >= anomalyWindow {
+				window, sum, sumSq = window[:0], 0, 0
+			} else {
+				for ; empty > 0; empty-- {
+					push(0)
+				}
+			}
+		}
+		observed := float64(d.buckets[key])
+
+		if len(window) >= anomalyMinHistory {
+			n := float64(len(window))
+			mean := sum / n
+			stdDev := math.Sqrt(math.Max(sumSq/n-mean*mean, 0))
+			threshold := mean + d.stdDev*math.Max(stdDev, anomalyMinStdDev)
+
+			if observed > threshold {
+				anomalies = append(anomalies, d.spike(key, observed, mean, stdDev, threshold))
+			}
+		}
+		push(observed)
+	}
+
+	return anomalies
+}
+
+// spike builds the anomaly reported for an outlying bucket
+func (d *anomalyDetector) spike(key int64, observed, mean, stdDev, threshold float64) LogAnomaly {
+	start := time.Unix(key*int64(anomalyBucket/time.Second), 0)
+	end := start.Add(anomalyBucket)
+
+	severity := "medium"
+	if observed > mean+2*(threshold-mean) {
+		severity = "high"
+	}
+
+	return LogAnomaly{
+		Type:        "spike",
+		Description: fmt.Sprintf("Log volume spiked to %.0f lines per minute", observed),
+		Severity:    severity,
+		Timestamp:   start,
+		Context: fmt.Sprintf("%s - %s: observed %.0f lines, expected %.1f (stddev %.1f, threshold %.1f)",
+			start.Format(time.RFC3339), end.Format(time.RFC3339), observed, mean, stdDev, threshold),
+	}
+}
@@ -0,0 +1,194 @@
+package analyze
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/monitor"
+	"github.com/prometheus/common/model"
+)
+
+// Capacity analysis defaults
+const (
+	defaultCapacityForecast = "30d"
+	defaultCapacityHistory  = "7d"
+	// capacityWarningUsage is the usage percentage that raises a threshold alert
+	capacityWarningUsage = 80.0
+	// capacityCriticalLead is how close projected exhaustion must be for a
+	// critical rather than a warning alert
+	capacityCriticalLead = 7 * 24 * time.Hour
+)
+
+// MetricsSource provides the historical metric series capacity forecasts
+// are based on, such as a monitor.Monitor querying Prometheus
+type MetricsSource interface {
+	GetMetricsContext(ctx context.Context, metric, duration string) (*monitor.MetricsData, error)
+}
+
+// capacityQuery is a resource whose usage is forecast, as a percentage
+type capacityQuery struct {
+	resource string
+	// query is a PromQL template whose selectors each end in %[1]s, where
+	// the service's matcher is added
+	query string
+}
+
+// capacityQueries are the node exporter based resources AnalyzeCapacity forecasts
+var capacityQueries = []capacityQuery{
+	{"CPU", `100 * (1 - avg(rate(node_cpu_seconds_total{mode="idle"%[1]s}[5m])))`},
+	{"Memory", `100 * (1 - sum(node_memory_MemAvailable_bytes{job=~".+"%[1]s}) / sum(node_memory_MemTotal_bytes{job=~".+"%[1]s}))`},
+	{"Storage", `100 * max(1 - node_filesystem_avail_bytes{mountpoint="/"%[1]s} / node_filesystem_size_bytes{mountpoint="/"%[1]s})`},
+}
+
+// promQL renders the query for a service's targets, or every target
+func (q capacityQuery) promQL(service string) string {
+	matcher := ""
+	if service != "" {
+		matcher = fmt.Sprintf(",job=%q", service)
+	}
+	return fmt.Sprintf(q.query, matcher)
+}
+
+// metricSeries merges the samples of every series in data into one series,
+// in time order, taking the highest value at each timestamp
+func metricSeries(data *monitor.MetricsData) []seriesPoint {
+	byTime := map[time.Time]float64{}
+	add := func(t time.Time, v float64) {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return
+		}
+		if current, ok := byTime[t]; !ok || v > current {
+			byTime[t] = v
+		}
+	}
+	for _, p := range data.Data {
+		add(p.Timestamp, p.Value)
+	}
+	for _, p := range data.Points {
+		add(p.Timestamp, p.Value)
+	}
+
+	points := make([]seriesPoint, 0, len(byTime))
+	for t, v := range byTime {
+		points = append(points, seriesPoint{Time: t, Value: v})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	return points
+}
+
+// fitSeries fits a line through the series and, when the series covers two
+// daily seasons, Holt-Winters, returning whichever explains it better
+func fitSeries(points []seriesPoint) (forecaster, bool) {
+	linear, ok := fitLinear(points)
+	if !ok {
+		return nil, false
+	}
+	if seasonal, ok := fitHoltWinters(points); ok && seasonal.RSquared() > linear.RSquared() {
+		return seasonal, true
+	}
+	return linear, true
+}
+
+// forecastCapacity forecasts one resource's usage over the horizon, adding
+// its current usage, forecast and any alerts to the analysis
+func forecastCapacity(analysis *CapacityAnalysis, resource string, points []seriesPoint, maximum float64, horizon time.Duration, period string) {
+	fit, ok := fitSeries(points)
+	if !ok {
+		analysis.Metadata[strings.ToLower(resource)+"_error"] = fmt.Sprintf("not enough samples to forecast (%d)", len(points))
+		return
+	}
+
+	last := points[len(points)-1]
+	predicted := fit.At(last.Time.Add(horizon))
+	change := predicted - last.Value
+
+	metric := CapacityMetric{
+		Resource: resource,
+		Current:  round2(last.Value),
+		Maximum:  maximum,
+		Usage:    round2(last.Value / maximum * 100),
+		Unit:     "percent",
+		Status:   "normal",
+		Trend:    "stable",
+	}
+	switch {
+	case metric.Usage >= 90:
+		metric.Status = "critical"
+	case metric.Usage >= capacityWarningUsage:
+		metric.Status = "warning"
+	}
+	// Changes under 5% of capacity over the horizon are noise
+	if math.Abs(change) >= maximum*0.05 {
+		if change > 0 {
+			metric.Trend = "increasing"
+		} else {
+			metric.Trend = "decreasing"
+		}
+	}
+	analysis.CurrentUsage = append(analysis.CurrentUsage, metric)
+
+	forecast := CapacityForecast{
+		Resource:   resource,
+		Period:     period,
+		Predicted:  round2(math.Max(predicted, 0)),
+		Confidence: round2(fit.RSquared()),
+		Method:     fit.Method(),
+	}
+	exhaustion, exhausts := fit.Exhaustion(maximum, horizon)
+	if exhausts {
+		forecast.ExhaustionDate = &exhaustion
+	}
+	analysis.Forecast = append(analysis.Forecast, forecast)
+
+	if exhausts && !exhaustion.After(last.Time.Add(horizon)) {
+		severity := "warning"
+		if exhaustion.Sub(last.Time) <= capacityCriticalLead {
+			severity = "critical"
+		}
+		analysis.Alerts = append(analysis.Alerts, CapacityAlert{
+			Resource:  resource,
+			Type:      "exhaustion",
+			Severity:  severity,
+			Message:   fmt.Sprintf("%s projected to reach %s%% by %s", resource, formatFloat(maximum), exhaustion.Format("2006-01-02")),
+			Threshold: maximum,
+			Current:   metric.Current,
+			Action:    fmt.Sprintf("Add %s capacity before %s", strings.ToLower(resource), exhaustion.Format("2006-01-02")),
+		})
+		analysis.Recommendations = append(analysis.Recommendations,
+			fmt.Sprintf("Plan %s expansion before %s", strings.ToLower(resource), exhaustion.Format("2006-01-02")))
+	} else if metric.Usage >= capacityWarningUsage {
+		analysis.Alerts = append(analysis.Alerts, CapacityAlert{
+			Resource:  resource,
+			Type:      "threshold",
+			Severity:  "warning",
+			Message:   fmt.Sprintf("%s usage above %s%% threshold", resource, formatFloat(capacityWarningUsage)),
+			Threshold: capacityWarningUsage,
+			Current:   metric.Usage,
+			Action:    fmt.Sprintf("Review %s usage", strings.ToLower(resource)),
+		})
+	}
+}
+
+// parseCapacityPeriod parses a Prometheus-style duration such as 30d
+func parseCapacityPeriod(value, fallback, name string) (string, time.Duration, error) {
+	if value == "" {
+		value = fallback
+	}
+	d, err := model.ParseDuration(value)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	if d <= 0 {
+		return "", 0, fmt.Errorf("%s must be positive: %s", name, value)
+	}
+	return value, time.Duration(d), nil
+}
+
+// round2 rounds to two decimal places
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
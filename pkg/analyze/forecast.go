@@ -0,0 +1,265 @@
+package analyze
+
+import (
+	"math"
+	"time"
+)
+
+// Forecast methods reported in CapacityForecast.Method
+const (
+	forecastLinear      = "linear"
+	forecastHoltWinters = "holt-winters"
+)
+
+// seasonLength is the period of the seasonality Holt-Winters models: daily cycles
+const seasonLength = 24 * time.Hour
+
+// maxExhaustionHorizon bounds how far ahead a linear exhaustion date is
+// reported; beyond it the trend says nothing useful
+const maxExhaustionHorizon = 5 * 365 * 24 * time.Hour
+
+// Smoothing parameters tried when fitting Holt-Winters
+var (
+	holtWintersAlphas = []float64{0.2, 0.5, 0.8}
+	holtWintersBetas  = []float64{0.05, 0.2}
+	holtWintersGammas = []float64{0.1, 0.3, 0.6}
+)
+
+// seriesPoint is one sample of a metric series
+type seriesPoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// forecaster projects a fitted series into the future
+type forecaster interface {
+	// At returns the projected value at t, after the end of the series
+	At(t time.Time) float64
+	// RSquared is the share of the series' variance the fit explains
+	RSquared() float64
+	Method() string
+	// Exhaustion returns when the projection first reaches limit. Models
+	// that cannot extrapolate far look no further than horizon
+	Exhaustion(limit float64, horizon time.Duration) (time.Time, bool)
+}
+
+// linearFit is a least-squares line through a series
+type linearFit struct {
+	origin    time.Time
+	end       time.Time
+	slope     float64 // per second
+	intercept float64
+	r2        float64
+}
+
+// fitLinear fits a line through the series by least squares. It needs at
+// least two points at different times
+func fitLinear(points []seriesPoint) (*linearFit, bool) {
+	if len(points) < 2 {
+		return nil, false
+	}
+
+	origin := points[0].Time
+	n := float64(len(points))
+	var sumX, sumY, sumXY, sumXX float64
+	for _, p := range points {
+		x := p.Time.Sub(origin).Seconds()
+		sumX += x
+		sumY += p.Value
+		sumXY += x * p.Value
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return nil, false
+	}
+
+	fit := &linearFit{origin: origin, end: points[len(points)-1].Time}
+	fit.slope = (n*sumXY - sumX*sumY) / denominator
+	fit.intercept = (sumY - fit.slope*sumX) / n
+
+	fitted := make([]float64, len(points))
+	actual := make([]float64, len(points))
+	for i, p := range points {
+		fitted[i] = fit.value(p.Time)
+		actual[i] = p.Value
+	}
+	fit.r2 = rSquared(actual, fitted)
+	return fit, true
+}
+
+func (f *linearFit) value(t time.Time) float64 {
+	return f.intercept + f.slope*t.Sub(f.origin).Seconds()
+}
+
+// At implements forecaster
+func (f *linearFit) At(t time.Time) float64 { return f.value(t) }
+
+// RSquared implements forecaster
+func (f *linearFit) RSquared() float64 { return f.r2 }
+
+// Method implements forecaster
+func (f *linearFit) Method() string { return forecastLinear }
+
+// Exhaustion implements forecaster. A rising line is followed up to
+// maxExhaustionHorizon whatever the horizon; a line already past limit at
+// the end of the series is exhausted then
+func (f *linearFit) Exhaustion(limit float64, horizon time.Duration) (time.Time, bool) {
+	if f.slope <= 0 {
+		return time.Time{}, false
+	}
+	seconds := (limit - f.intercept) / f.slope
+	if seconds > f.end.Sub(f.origin).Seconds()+maxExhaustionHorizon.Seconds() {
+		return time.Time{}, false
+	}
+	at := f.origin.Add(time.Duration(seconds * float64(time.Second)))
+	if at.Before(f.end) {
+		return f.end, true
+	}
+	return at, true
+}
+
+// holtWintersFit is an additive Holt-Winters model of an evenly spaced series
+type holtWintersFit struct {
+	end      time.Time
+	step     time.Duration
+	level    float64
+	trend    float64
+	seasonal []float64
+	// next is the index into seasonal of the step after the series' end
+	next int
+	r2   float64
+}
+
+// fitHoltWinters fits the best additive Holt-Winters model over a grid of
+// smoothing parameters. It needs evenly spaced points covering two seasons
+func fitHoltWinters(points []seriesPoint) (*holtWintersFit, bool) {
+	if len(points) < 3 {
+		return nil, false
+	}
+	step := points[1].Time.Sub(points[0].Time)
+	if step <= 0 {
+		return nil, false
+	}
+	for i := 2; i < len(points); i++ {
+		if points[i].Time.Sub(points[i-1].Time) != step {
+			return nil, false
+		}
+	}
+	period := int(seasonLength / step)
+	if period < 2 || len(points) < 2*period {
+		return nil, false
+	}
+
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Value
+	}
+
+	var best *holtWintersFit
+	for _, alpha := range holtWintersAlphas {
+		for _, beta := range holtWintersBetas {
+			for _, gamma := range holtWintersGammas {
+				fit := holtWinters(values, period, alpha, beta, gamma)
+				if best == nil || fit.r2 > best.r2 {
+					best = fit
+				}
+			}
+		}
+	}
+	best.end = points[len(points)-1].Time
+	best.step = step
+	return best, true
+}
+
+// holtWinters runs additive Holt-Winters smoothing over values, scoring it
+// with the R² of its one-step-ahead predictions after the first season
+func holtWinters(values []float64, period int, alpha, beta, gamma float64) *holtWintersFit {
+	var first, second float64
+	for i := 0; i < period; i++ {
+		first += values[i]
+		second += values[period+i]
+	}
+	first /= float64(period)
+	second /= float64(period)
+
+	level := first
+	trend := (second - first) / float64(period)
+	seasonal := make([]float64, period)
+	for i := range seasonal {
+		seasonal[i] = values[i] - first
+	}
+
+	actual := make([]float64, 0, len(values)-period)
+	predicted := make([]float64, 0, len(values)-period)
+	for i := period; i < len(values); i++ {
+		s := i % period
+		actual = append(actual, values[i])
+		predicted = append(predicted, level+trend+seasonal[s])
+
+		previous := level
+		level = alpha*(values[i]-seasonal[s]) + (1-alpha)*(level+trend)
+		trend = beta*(level-previous) + (1-beta)*trend
+		seasonal[s] = gamma*(values[i]-level) + (1-gamma)*seasonal[s]
+	}
+
+	return &holtWintersFit{
+		level:    level,
+		trend:    trend,
+		seasonal: seasonal,
+		next:     len(values) % period,
+		r2:       rSquared(actual, predicted),
+	}
+}
+
+// At implements forecaster
+func (f *holtWintersFit) At(t time.Time) float64 {
+	h := int(math.Ceil(float64(t.Sub(f.end)) / float64(f.step)))
+	if h < 1 {
+		h = 1
+	}
+	return f.level + float64(h)*f.trend + f.seasonal[(f.next+h-1)%len(f.seasonal)]
+}
+
+// RSquared implements forecaster
+func (f *holtWintersFit) RSquared() float64 { return f.r2 }
+
+// Method implements forecaster
+func (f *holtWintersFit) Method() string { return forecastHoltWinters }
+
+// Exhaustion implements forecaster, stepping through the horizon
+func (f *holtWintersFit) Exhaustion(limit float64, horizon time.Duration) (time.Time, bool) {
+	for t := f.end.Add(f.step); !t.After(f.end.Add(horizon)); t = t.Add(f.step) {
+		if f.At(t) >= limit {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// rSquared is the coefficient of determination of predicted against actual,
+// clamped to [0, 1]
+func rSquared(actual, predicted []float64) float64 {
+	if len(actual) == 0 {
+		return 0
+	}
+	var mean float64
+	for _, v := range actual {
+		mean += v
+	}
+	mean /= float64(len(actual))
+
+	var residual, total float64
+	for i, v := range actual {
+		residual += (v - predicted[i]) * (v - predicted[i])
+		total += (v - mean) * (v - mean)
+	}
+	if total == 0 {
+		// A flat series is explained perfectly by a flat fit
+		if residual == 0 {
+			return 1
+		}
+		return 0
+	}
+	return math.Max(0, math.Min(1, 1-residual/total))
+}
@@ -0,0 +1,129 @@
+package analyze
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/output"
+)
+
+// AnalysisFormats lists the formats supported by FormatAnalysis
+var AnalysisFormats = []string{"csv", "json", "table", "yaml"}
+
+// analysisTable adapts flattened analysis rows to output.Tabular
+type analysisTable struct {
+	headers []string
+	rows    [][]string
+}
+
+// TableHeaders returns the CSV column names
+func (t *analysisTable) TableHeaders() []string {
+	return t.headers
+}
+
+// TableRows returns the flattened analysis rows
+func (t *analysisTable) TableRows() [][]string {
+	return t.rows
+}
+
+// FormatAnalysis renders an analysis result as json, yaml, csv or table. CSV
+// and table output flatten the most relevant list of each result with a fixed
+// column order
+func FormatAnalysis(v interface{}, format string) (string, error) {
+	format = strings.ToLower(format)
+
+	var data interface{}
+	switch format {
+	case "json", "yaml":
+		data = v
+	case "csv", "table":
+		table, err := flattenAnalysis(v)
+		if err != nil {
+			return "", err
+		}
+		data = table
+	default:
+		return "", fmt.Errorf("unsupported format: %s (available: %s)", format, strings.Join(AnalysisFormats, ", "))
+	}
+
+	var buf bytes.Buffer
+	if err := output.Render(&buf, format, data); err != nil {
+		return "", fmt.Errorf("failed to format analysis: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// flattenAnalysis converts an analysis result into CSV rows
+func flattenAnalysis(v interface{}) (*analysisTable, error) {
+	switch analysis := v.(type) {
+	case *LogAnalysis:
+		table := &analysisTable{headers: []string{"severity", "pattern", "count", "first_seen", "last_seen"}}
+		for _, p := range analysis.Patterns {
+			table.rows = append(table.rows, []string{
+				p.Severity, p.Pattern, strconv.Itoa(p.Count), formatTime(p.FirstSeen), formatTime(p.LastSeen),
+			})
+		}
+		return table, nil
+	case *PerformanceAnalysis:
+		table := &analysisTable{headers: []string{"metric", "value", "unit", "status", "threshold", "trend"}}
+		for _, m := range analysis.Metrics {
+			table.rows = append(table.rows, []string{
+				m.Name, formatFloat(m.Value), m.Unit, m.Status, formatFloat(m.Threshold), m.Trend,
+			})
+		}
+		return table, nil
+	case *CostAnalysis:
+		table := &analysisTable{headers: []string{"category", "cost", "currency", "percentage", "change", "trend"}}
+		for _, b := range analysis.Breakdown {
+			table.rows = append(table.rows, []string{
+				b.Category, formatFloat(b.Cost), analysis.Currency, formatFloat(b.Percentage), formatFloat(b.Change), b.Trend,
+			})
+		}
+		return table, nil
+	case *SecurityAnalysis:
+		table := &analysisTable{headers: []string{"id", "title", "severity", "cvss", "component", "status"}}
+		for _, vuln := range analysis.Vulnerabilities {
+			table.rows = append(table.rows, []string{
+				vuln.ID, vuln.Title, vuln.Severity, formatFloat(vuln.CVSS), vuln.Component, vuln.Status,
+			})
+		}
+		return table, nil
+	case *CapacityAnalysis:
+		forecasts := map[string]CapacityForecast{}
+		for _, f := range analysis.Forecast {
+			forecasts[f.Resource] = f
+		}
+		table := &analysisTable{headers: []string{"resource", "current", "maximum", "usage", "unit", "status", "trend", "predicted", "exhaustion"}}
+		for _, c := range analysis.CurrentUsage {
+			predicted, exhaustion := "", ""
+			if f, ok := forecasts[c.Resource]; ok {
+				predicted = formatFloat(f.Predicted)
+				if f.ExhaustionDate != nil {
+					exhaustion = formatTime(*f.ExhaustionDate)
+				}
+			}
+			table.rows = append(table.rows, []string{
+				c.Resource, formatFloat(c.Current), formatFloat(c.Maximum), formatFloat(c.Usage), c.Unit, c.Status, c.Trend, predicted, exhaustion,
+			})
+		}
+		return table, nil
+	default:
+		return nil, fmt.Errorf("csv and table output are not supported for %T", v)
+	}
+}
+
+// formatFloat formats a number without trailing zeros
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// formatTime formats a timestamp as RFC 3339, leaving unknown times empty
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,488 @@
+package analyze
+
+import (
+	"bufio"
+	"compress/gzip"
+	"container/heap"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// Log scanning limits
+const (
+	// maxLogLineSize is the longest line the scanner accepts
+	maxLogLineSize = 1024 * 1024
+	// maxPatternExamples is how many raw lines are kept per pattern
+	maxPatternExamples = 3
+	// maxPatternsReported is how many patterns are returned, most frequent first
+	maxPatternsReported = 20
+	// maxTrackedPatterns bounds the patterns held in memory during a scan
+	maxTrackedPatterns = 10000
+	// cancelCheckLines is how many lines are scanned between context checks
+	cancelCheckLines = 1000
+)
+
+// logEntry is a single parsed log line
+type logEntry struct {
+	Raw       string
+	Timestamp time.Time
+	Level     string
+	Message   string
+}
+
+// timestampLayout pairs a pattern matching a leading timestamp with the layouts used to parse it
+type timestampLayout struct {
+	pattern *regexp.Regexp
+	layouts []string
+	// noYear marks layouts such as syslog that omit the year
+	noYear bool
+}
+
+var timestampLayouts = []timestampLayout{
+	{
+		pattern: regexp.MustCompile(`^\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`),
+		layouts: []string{
+			time.RFC3339Nano,
+			"2006-01-02T15:04:05.999999999Z0700",
+			"2006-01-02 15:04:05.999999999Z07:00",
+			"2006-01-02 15:04:05.999999999Z0700",
+			"2006-01-02T15:04:05.999999999",
+			"2006-01-02 15:04:05.999999999",
+		},
+	},
+	{
+		pattern: regexp.MustCompile(`^\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`),
+		layouts: []string{"02/Jan/2006:15:04:05 -0700"},
+	},
+	{
+		pattern: regexp.MustCompile(`^[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}`),
+		layouts: []string{time.Stamp},
+		noYear:  true,
+	},
+}
+
+// Level detection: upper-case level words, or key=value style levels
+var (
+	levelWordPattern  = regexp.MustCompile(`\b(FATAL|PANIC|CRITICAL|CRIT|ERROR|ERR|WARNING|WARN|INFO|DEBUG|TRACE)\b:?`)
+	levelFieldPattern = regexp.MustCompile(`(?i)\b(?:level|lvl|severity)="?(\w+)"?`)
+)
+
+// Message normalisation rules, applied in order, so similar lines share a pattern
+var messageNormalizers = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<uuid>"},
+	{regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`), "<hex>"},
+	{regexp.MustCompile(`\b[0-9a-fA-F]{8,}\b`), "<id>"},
+	{regexp.MustCompile(`"[^"]*"`), `"<str>"`},
+	{regexp.MustCompile(`'[^']*'`), `'<str>'`},
+	{regexp.MustCompile(`\d+(?:\.\d+)?`), "<num>"},
+	{regexp.MustCompile(`\s+`), " "},
+}
+
+// openLogFile opens a log file, transparently decompressing .gz files
+func openLogFile(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("log file not found: %s", path)
+		}
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	if !strings.HasSuffix(path, ".gz") {
+		return file, nil
+	}
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read gzipped log file %s: %w", path, err)
+	}
+	return &gzipReadCloser{Reader: gz, file: file}, nil
+}
+
+// gzipReadCloser closes both the gzip stream and the underlying file
+type gzipReadCloser struct {
+	*gzip.Reader
+	file *os.File
+}
+
+// Close closes the gzip stream and the file
+func (g *gzipReadCloser) Close() error {
+	gzErr := g.Reader.Close()
+	if err := g.file.Close(); err != nil {
+		return err
+	}
+	return gzErr
+}
+
+// logFilter selects the log lines included in an analysis
+type logFilter struct {
+	pattern *regexp.Regexp
+	since   time.Time
+}
+
+// newLogFilter compiles the pattern and time range from the options
+func newLogFilter(options LogOptions, now time.Time) (*logFilter, error) {
+	filter := &logFilter{}
+
+	if options.Pattern != "" {
+		pattern, err := regexp.Compile(options.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", options.Pattern, err)
+		}
+		filter.pattern = pattern
+	}
+
+	if options.TimeRange != "" {
+		duration, err := model.ParseDuration(options.TimeRange)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time range %q: %w", options.TimeRange, err)
+		}
+		filter.since = now.Add(-time.Duration(duration))
+	}
+
+	return filter, nil
+}
+
+// Match reports whether the entry passes the filter; entries without a
+// parsable timestamp are never excluded by the time range
+func (f *logFilter) Match(entry *logEntry) bool {
+	if f.pattern != nil && !f.pattern.MatchString(entry.Raw) {
+		return false
+	}
+	if !f.since.IsZero() && !entry.Timestamp.IsZero() && entry.Timestamp.Before(f.since) {
+		return false
+	}
+	return true
+}
+
+// LogEntry is a log line parsed by ParseLogLine
+type LogEntry struct {
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+	Level     string    `json:"level" yaml:"level"`
+	Message   string    `json:"message" yaml:"message"`
+	// Pattern is the message with its variable parts replaced, as clustered
+	// by AnalyzeLogs
+	Pattern string `json:"pattern" yaml:"pattern"`
+}
+
+// ParseLogLine extracts the timestamp, level, message and pattern from a log
+// line. Timestamps without a year, such as syslog's, are taken to be in the
+// year up to now
+func ParseLogLine(line string, now time.Time) LogEntry {
+	entry := parseLogLine(line, now)
+	return LogEntry{
+		Timestamp: entry.Timestamp,
+		Level:     entry.Level,
+		Message:   entry.Message,
+		Pattern:   normalizeMessage(entry.Message),
+	}
+}
+
+// parseLogLine extracts the timestamp, level and message from a log line
+func parseLogLine(line string, now time.Time) *logEntry {
+	entry := &logEntry{Raw: line}
+
+	rest := strings.TrimSpace(line)
+	rest = strings.TrimPrefix(rest, "[")
+	entry.Timestamp, rest = parseTimestamp(rest, now)
+	rest = strings.TrimLeft(rest, "] \t")
+
+	if loc := levelWordPattern.FindStringSubmatchIndex(rest); loc != nil {
+		entry.Level = normalizeLevel(rest[loc[2]:loc[3]])
+		rest = rest[loc[1]:]
+	} else if match := levelFieldPattern.FindStringSubmatch(rest); match != nil {
+		entry.Level = normalizeLevel(match[1])
+	}
+
+	entry.Message = strings.TrimSpace(strings.TrimLeft(rest, "]:-| \t"))
+	return entry
+}
+
+// parseTimestamp parses a leading timestamp, returning it and the rest of the line
+func parseTimestamp(s string, now time.Time) (time.Time, string) {
+	for _, tl := range timestampLayouts {
+		match := tl.pattern.FindString(s)
+		if match == "" {
+			continue
+		}
+		value := strings.Replace(match, ",", ".", 1)
+		for _, layout := range tl.layouts {
+			ts, err := time.ParseInLocation(layout, value, time.Local)
+			if err != nil {
+				continue
+			}
+			if tl.noYear {
+				ts = ts.AddDate(now.Year(), 0, 0)
+				// A syslog timestamp in the future belongs to last year
+				if ts.After(now.Add(24 * time.Hour)) {
+					ts = ts.AddDate(-1, 0, 0)
+				}
+			}
+			return ts, s[len(match):]
+		}
+	}
+	return time.Time{}, s
+}
+
+// normalizeLevel maps level spellings onto error, warning, info and debug
+func normalizeLevel(level string) string {
+	switch strings.ToLower(level) {
+	case "fatal", "panic", "critical", "crit", "error", "err":
+		return "error"
+	case "warning", "warn":
+		return "warning"
+	case "info", "notice":
+		return "info"
+	case "debug", "trace":
+		return "debug"
+	default:
+		return ""
+	}
+}
+
+// normalizeMessage replaces variable parts of a message with placeholders
+func normalizeMessage(message string) string {
+	for _, n := range messageNormalizers {
+		message = n.pattern.ReplaceAllString(message, n.replacement)
+	}
+	return strings.TrimSpace(message)
+}
+
+// patternAggregator clusters log entries by normalised message. It tracks at
+// most limit patterns; when full, the least frequent pattern is evicted so
+// memory stays bounded however large the input is
+type patternAggregator struct {
+	limit    int
+	patterns map[string]*trackedPattern
+	heap     patternHeap
+	evicted  int
+}
+
+// trackedPattern is a pattern together with its position in the eviction heap
+type trackedPattern struct {
+	LogPattern
+	key   string
+	index int
+}
+
+// patternHeap is a min-heap of tracked patterns ordered by count
+type patternHeap []*trackedPattern
+
+func (h patternHeap) Len() int           { return len(h) }
+func (h patternHeap) Less(i, j int) bool { return h[i].Count < h[j].Count }
+func (h patternHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *patternHeap) Push(x interface{}) {
+	pattern := x.(*trackedPattern)
+	pattern.index = len(*h)
+	*h = append(*h, pattern)
+}
+
+func (h *patternHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	pattern := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return pattern
+}
+
+// newPatternAggregator creates an empty aggregator tracking at most limit patterns
+func newPatternAggregator(limit int) *patternAggregator {
+	return &patternAggregator{
+		limit:    limit,
+		patterns: make(map[string]*trackedPattern),
+	}
+}
+
+// Add records an entry against its pattern
+func (a *patternAggregator) Add(entry *logEntry) {
+	template := normalizeMessage(entry.Message)
+	if template == "" {
+		return
+	}
+
+	severity := entry.Level
+	if severity == "" {
+		severity = "info"
+	}
+	key := severity + "\x00" + template
+
+	pattern, exists := a.patterns[key]
+	if !exists {
+		if a.limit > 0 && len(a.patterns) >= a.limit {
+			least := heap.Pop(&a.heap).(*trackedPattern)
+			delete(a.patterns, least.key)
+			a.evicted++
+		}
+		pattern = &trackedPattern{
+			LogPattern: LogPattern{
+				Pattern:  template,
+				Severity: severity,
+			},
+			key: key,
+		}
+		a.patterns[key] = pattern
+		heap.Push(&a.heap, pattern)
+	}
+
+	pattern.Count++
+	heap.Fix(&a.heap, pattern.index)
+
+	if len(pattern.Examples) < maxPatternExamples {
+		pattern.Examples = append(pattern.Examples, entry.Raw)
+	}
+	if ts := entry.Timestamp; !ts.IsZero() {
+		if pattern.FirstSeen.IsZero() || ts.Before(pattern.FirstSeen) {
+			pattern.FirstSeen = ts
+		}
+		if ts.After(pattern.LastSeen) {
+			pattern.LastSeen = ts
+		}
+	}
+}
+
+// Evicted returns how many patterns were dropped to stay within the limit
+func (a *patternAggregator) Evicted() int {
+	return a.evicted
+}
+
+// Len returns the number of distinct patterns
+func (a *patternAggregator) Len() int {
+	return len(a.patterns)
+}
+
+// Top returns up to n patterns ordered by count, most frequent first
+func (a *patternAggregator) Top(n int) []LogPattern {
+	patterns := make([]LogPattern, 0, len(a.patterns))
+	for _, pattern := range a.patterns {
+		patterns = append(patterns, pattern.LogPattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if patterns[i].Count != patterns[j].Count {
+			return patterns[i].Count > patterns[j].Count
+		}
+		return patterns[i].Pattern < patterns[j].Pattern
+	})
+	if n > 0 && len(patterns) > n {
+		patterns = patterns[:n]
+	}
+	return patterns
+}
+
+// analyzeLogReader scans r line by line and builds a log analysis, checking
+// for cancellation every cancelCheckLines lines
+func analyzeLogReader(ctx context.Context, r io.Reader, options LogOptions) (*LogAnalysis, error) {
+	now := time.Now()
+	filter, err := newLogFilter(options, now)
+	if err != nil {
+		return nil, err
+	}
+	if options.AnomalyStdDev < 0 {
+		return nil, fmt.Errorf("anomaly stddev must not be negative: %v", options.AnomalyStdDev)
+	}
+
+	analysis := &LogAnalysis{
+		Insights: []string{},
+		Metadata: map[string]string{
+			"file":       options.File,
+			"pattern":    options.Pattern,
+			"time_range": options.TimeRange,
+		},
+		Timestamp: now,
+	}
+
+	aggregator := newPatternAggregator(maxTrackedPatterns)
+	detector := newAnomalyDetector(options.AnomalyStdDev)
+	linesRead, linesAnalyzed := 0, 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogLineSize)
+	for scanner.Scan() {
+		if linesRead%cancelCheckLines == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("log analysis cancelled after %d lines: %w", linesRead, err)
+			}
+		}
+
+		line := scanner.Text()
+		linesRead++
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		entry := parseLogLine(line, now)
+		if !filter.Match(entry) {
+			continue
+		}
+		linesAnalyzed++
+
+		switch entry.Level {
+		case "error":
+			analysis.ErrorCount++
+		case "warning":
+			analysis.WarningCount++
+		}
+		aggregator.Add(entry)
+		detector.Add(entry.Timestamp)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read logs: %w", err)
+	}
+
+	analysis.Patterns = aggregator.Top(maxPatternsReported)
+	analysis.Anomalies = detector.Anomalies()
+	analysis.Insights = logInsights(analysis)
+	analysis.Summary = fmt.Sprintf("Analyzed %d of %d lines: %d errors, %d warnings, %d distinct patterns",
+		linesAnalyzed, linesRead, analysis.ErrorCount, analysis.WarningCount, aggregator.Len())
+	analysis.Metadata["lines_read"] = strconv.Itoa(linesRead)
+	analysis.Metadata["lines_analyzed"] = strconv.Itoa(linesAnalyzed)
+	if evicted := aggregator.Evicted(); evicted > 0 {
+		analysis.Metadata["patterns_evicted"] = strconv.Itoa(evicted)
+	}
+
+	return analysis, nil
+}
+
+// logInsights summarises the most frequent error and warning patterns
+func logInsights(analysis *LogAnalysis) []string {
+	insights := []string{}
+	seen := map[string]bool{}
+	for _, pattern := range analysis.Patterns {
+		if pattern.Severity != "error" && pattern.Severity != "warning" {
+			continue
+		}
+		if seen[pattern.Severity] {
+			continue
+		}
+		seen[pattern.Severity] = true
+		insights = append(insights, fmt.Sprintf("Most frequent %s: %q (%d occurrences)",
+			pattern.Severity, pattern.Pattern, pattern.Count))
+	}
+	if len(analysis.Anomalies) > 0 {
+		insights = append(insights, fmt.Sprintf("Detected %d spikes in log volume, first at %s",
+			len(analysis.Anomalies), analysis.Anomalies[0].Timestamp.Format(time.RFC3339)))
+	}
+	if analysis.ErrorCount == 0 && analysis.WarningCount == 0 {
+		insights = append(insights, "No errors or warnings found")
+	}
+	return insights
+}
@@ -2,22 +2,57 @@ package cloud
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/AlloraAi/AlloraCLI/pkg/utils"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/costexplorer"
+	cetypes "github.com/aws/aws-sdk-go-v2/service/costexplorer/types"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
 	"github.com/sirupsen/logrus"
 )
 
+// costExplorerRegion is the region hosting the global Cost Explorer endpoint
+const costExplorerRegion = "us-east-1"
+
+// costMetric is the Cost Explorer metric used for cost reporting
+const costMetric = "UnblendedCost"
+
+// ec2API is the subset of the EC2 client used by the AWS provider
+type ec2API interface {
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	DescribeVolumes(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error)
+	DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
+	DescribeVpcs(ctx context.Context, params *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error)
+	DescribeRegions(ctx context.Context, params *ec2.DescribeRegionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error)
+	TerminateInstances(ctx context.Context, params *ec2.TerminateInstancesInput, optFns ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error)
+	DeleteVolume(ctx context.Context, params *ec2.DeleteVolumeInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVolumeOutput, error)
+	DeleteSecurityGroup(ctx context.Context, params *ec2.DeleteSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error)
+	DeleteVpc(ctx context.Context, params *ec2.DeleteVpcInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVpcOutput, error)
+}
+
+// stsAPI is the subset of the STS client used by the AWS provider
+type stsAPI interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+// activeInstanceStates lists instance states that still hold on to their resources
+var activeInstanceStates = []string{"pending", "running", "stopping", "stopped", "shutting-down"}
+
 // AWSProvider implements the CloudProvider interface for AWS
 type AWSProvider struct {
-	ec2Client *ec2.Client
-	stsClient *sts.Client
+	ec2Client ec2API
+	stsClient stsAPI
+	ceClient  *costexplorer.Client
 	config    *ProviderConfig
 	connected bool
 	logger    *logrus.Logger
@@ -25,8 +60,7 @@ type AWSProvider struct {
 
 // NewAWSProvider creates a new AWS provider
 func NewAWSProvider(cfg *ProviderConfig) (CloudProvider, error) {
-	logger := logrus.New()
-	logger.SetLevel(logrus.InfoLevel)
+	logger := utils.NewLogger()
 
 	provider := &AWSProvider{
 		config: cfg,
@@ -54,21 +88,20 @@ func (p *AWSProvider) Connect(ctx context.Context) error {
 
 	p.logger.Info("Connecting to AWS...")
 
-	// Load AWS config
-	cfg, err := config.LoadDefaultConfig(ctx)
+	cfg, err := LoadAWSConfig(ctx, p.config)
 	if err != nil {
-		return fmt.Errorf("failed to load AWS config: %w", err)
-	}
-
-	// Override region if specified
-	if p.config.Region != "" {
-		cfg.Region = p.config.Region
+		return err
 	}
 
 	// Create EC2 client
 	p.ec2Client = ec2.NewFromConfig(cfg)
 	p.stsClient = sts.NewFromConfig(cfg)
 
+	// Cost Explorer is a global service served from us-east-1
+	ceCfg := cfg.Copy()
+	ceCfg.Region = costExplorerRegion
+	p.ceClient = costexplorer.NewFromConfig(ceCfg)
+
 	// Test connection
 	if err := p.ValidateCredentials(ctx); err != nil {
 		return fmt.Errorf("failed to validate AWS credentials: %w", err)
@@ -79,6 +112,35 @@ func (p *AWSProvider) Connect(ctx context.Context) error {
 	return nil
 }
 
+// LoadAWSConfig loads the AWS SDK config, honouring the profile, static
+// keys and region of cfg; a nil cfg uses the default credential chain
+func LoadAWSConfig(ctx context.Context, cfg *ProviderConfig) (aws.Config, error) {
+	if cfg == nil {
+		cfg = &ProviderConfig{}
+	}
+
+	var opts []func(*config.LoadOptions) error
+	if cfg.Profile != "" && cfg.Profile != "default" {
+		opts = append(opts, config.WithSharedConfigProfile(cfg.Profile))
+	}
+	if accessKey := cfg.Credentials["access_key_id"]; accessKey != "" {
+		opts = append(opts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(accessKey, cfg.Credentials["secret_access_key"], ""),
+		))
+	}
+
+	awsCfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	// Override region if specified
+	if cfg.Region != "" {
+		awsCfg.Region = cfg.Region
+	}
+	return awsCfg, nil
+}
+
 // Disconnect closes the connection
 func (p *AWSProvider) Disconnect(ctx context.Context) error {
 	p.connected = false
@@ -106,168 +168,225 @@ func (p *AWSProvider) ValidateCredentials(ctx context.Context) error {
 }
 
 // ListResources lists AWS resources
-func (p *AWSProvider) ListResources(ctx context.Context, resourceType string) ([]*Resource, error) {
+func (p *AWSProvider) ListResources(ctx context.Context, resourceType string, opts ...ListOption) ([]*Resource, error) {
 	if !p.connected {
 		if err := p.Connect(ctx); err != nil {
 			return nil, err
 		}
 	}
 
+	options := NewListOptions(opts...)
 	switch strings.ToLower(resourceType) {
-	case "ec2", "instances":
-		return p.listEC2Instances(ctx)
-	case "volumes", "ebs":
-		return p.listEBSVolumes(ctx)
-	case "security-groups", "sg":
-		return p.listSecurityGroups(ctx)
+	case "ec2", "instances", "ec2-instance":
+		return p.listEC2Instances(ctx, options)
+	case "volumes", "ebs", "ebs-volume":
+		return p.listEBSVolumes(ctx, options)
+	case "security-groups", "sg", "security-group":
+		return p.listSecurityGroups(ctx, options)
 	case "vpcs", "vpc":
-		return p.listVPCs(ctx)
+		return p.listVPCs(ctx, options)
 	default:
 		return nil, fmt.Errorf("unsupported resource type: %s", resourceType)
 	}
 }
 
-// listEC2Instances lists EC2 instances
-func (p *AWSProvider) listEC2Instances(ctx context.Context) ([]*Resource, error) {
-	input := &ec2.DescribeInstancesInput{}
-	result, err := p.ec2Client.DescribeInstances(ctx, input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to describe instances: %w", err)
-	}
-
+// listEC2Instances lists EC2 instances across all result pages
+func (p *AWSProvider) listEC2Instances(ctx context.Context, options *ListOptions) ([]*Resource, error) {
 	var resources []*Resource
-	for _, reservation := range result.Reservations {
-		for _, instance := range reservation.Instances {
-			resource := &Resource{
-				ID:       aws.ToString(instance.InstanceId),
-				Name:     p.getInstanceName(instance),
-				Type:     "ec2-instance",
-				Provider: "aws",
-				Region:   aws.ToString(instance.Placement.AvailabilityZone),
-				State:    string(instance.State.Name),
-				Status:   string(instance.State.Name),
-				Created:  aws.ToTime(instance.LaunchTime),
-				Modified: time.Now(),
-				Tags:     p.convertEC2Tags(instance.Tags),
-				Config: map[string]interface{}{
-					"instance_type":   string(instance.InstanceType),
-					"architecture":    string(instance.Architecture),
-					"platform":        aws.ToString(instance.PlatformDetails),
-					"vpc_id":          aws.ToString(instance.VpcId),
-					"subnet_id":       aws.ToString(instance.SubnetId),
-					"public_ip":       aws.ToString(instance.PublicIpAddress),
-					"private_ip":      aws.ToString(instance.PrivateIpAddress),
-					"security_groups": p.getSecurityGroupNames(instance.SecurityGroups),
-				},
+
+	paginator := ec2.NewDescribeInstancesPaginator(p.ec2Client, &ec2.DescribeInstancesInput{
+		Filters: ec2TagFilters(options),
+	})
+	for paginator.HasMorePages() && !options.Reached(len(resources)) {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe instances: %w", err)
+		}
+
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				if options.Reached(len(resources)) {
+					return resources, nil
+				}
+				resources = append(resources, p.convertEC2Instance(instance))
 			}
-			resources = append(resources, resource)
 		}
 	}
 
 	return resources, nil
 }
 
-// listEBSVolumes lists EBS volumes
-func (p *AWSProvider) listEBSVolumes(ctx context.Context) ([]*Resource, error) {
-	input := &ec2.DescribeVolumesInput{}
-	result, err := p.ec2Client.DescribeVolumes(ctx, input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to describe volumes: %w", err)
+// ec2TagFilters translates the tag filter into EC2 tag:Key filters
+func ec2TagFilters(options *ListOptions) []types.Filter {
+	var filters []types.Filter
+	for _, key := range options.SortedTagKeys() {
+		filters = append(filters, types.Filter{
+			Name:   aws.String("tag:" + key),
+			Values: []string{options.Tags[key]},
+		})
+	}
+	return filters
+}
+
+// convertEC2Instance converts an EC2 instance to a Resource
+func (p *AWSProvider) convertEC2Instance(instance types.Instance) *Resource {
+	var availabilityZone, state string
+	if instance.Placement != nil {
+		availabilityZone = aws.ToString(instance.Placement.AvailabilityZone)
+	}
+	if instance.State != nil {
+		state = string(instance.State.Name)
 	}
 
+	return &Resource{
+		ID:       aws.ToString(instance.InstanceId),
+		Name:     p.getInstanceName(instance),
+		Type:     "ec2-instance",
+		Provider: "aws",
+		Region:   availabilityZone,
+		State:    state,
+		Status:   state,
+		Created:  aws.ToTime(instance.LaunchTime),
+		Modified: time.Now(),
+		Tags:     p.convertEC2Tags(instance.Tags),
+		Config: map[string]interface{}{
+			"instance_type":   string(instance.InstanceType),
+			"architecture":    string(instance.Architecture),
+			"platform":        aws.ToString(instance.PlatformDetails),
+			"vpc_id":          aws.ToString(instance.VpcId),
+			"subnet_id":       aws.ToString(instance.SubnetId),
+			"public_ip":       aws.ToString(instance.PublicIpAddress),
+			"private_ip":      aws.ToString(instance.PrivateIpAddress),
+			"security_groups": p.getSecurityGroupNames(instance.SecurityGroups),
+		},
+	}
+}
+
+// listEBSVolumes lists EBS volumes across all result pages
+func (p *AWSProvider) listEBSVolumes(ctx context.Context, options *ListOptions) ([]*Resource, error) {
 	var resources []*Resource
-	for _, volume := range result.Volumes {
-		resource := &Resource{
-			ID:       aws.ToString(volume.VolumeId),
-			Name:     p.getVolumeName(volume),
-			Type:     "ebs-volume",
-			Provider: "aws",
-			Region:   aws.ToString(volume.AvailabilityZone),
-			State:    string(volume.State),
-			Status:   string(volume.State),
-			Created:  aws.ToTime(volume.CreateTime),
-			Modified: time.Now(),
-			Tags:     p.convertEBSVolumeTags(volume.Tags),
-			Config: map[string]interface{}{
-				"volume_type": string(volume.VolumeType),
-				"size":        aws.ToInt32(volume.Size),
-				"iops":        aws.ToInt32(volume.Iops),
-				"throughput":  aws.ToInt32(volume.Throughput),
-				"encrypted":   aws.ToBool(volume.Encrypted),
-				"snapshot_id": aws.ToString(volume.SnapshotId),
-			},
+
+	paginator := ec2.NewDescribeVolumesPaginator(p.ec2Client, &ec2.DescribeVolumesInput{
+		Filters: ec2TagFilters(options),
+	})
+	for paginator.HasMorePages() && !options.Reached(len(resources)) {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe volumes: %w", err)
+		}
+
+		for _, volume := range page.Volumes {
+			if options.Reached(len(resources)) {
+				return resources, nil
+			}
+			resources = append(resources, &Resource{
+				ID:       aws.ToString(volume.VolumeId),
+				Name:     p.getVolumeName(volume),
+				Type:     "ebs-volume",
+				Provider: "aws",
+				Region:   aws.ToString(volume.AvailabilityZone),
+				State:    string(volume.State),
+				Status:   string(volume.State),
+				Created:  aws.ToTime(volume.CreateTime),
+				Modified: time.Now(),
+				Tags:     p.convertEBSVolumeTags(volume.Tags),
+				Config: map[string]interface{}{
+					"volume_type": string(volume.VolumeType),
+					"size":        aws.ToInt32(volume.Size),
+					"iops":        aws.ToInt32(volume.Iops),
+					"throughput":  aws.ToInt32(volume.Throughput),
+					"encrypted":   aws.ToBool(volume.Encrypted),
+					"snapshot_id": aws.ToString(volume.SnapshotId),
+				},
+			})
 		}
-		resources = append(resources, resource)
 	}
 
 	return resources, nil
 }
 
-// listSecurityGroups lists security groups
-func (p *AWSProvider) listSecurityGroups(ctx context.Context) ([]*Resource, error) {
-	input := &ec2.DescribeSecurityGroupsInput{}
-	result, err := p.ec2Client.DescribeSecurityGroups(ctx, input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to describe security groups: %w", err)
-	}
-
+// listSecurityGroups lists security groups across all result pages
+func (p *AWSProvider) listSecurityGroups(ctx context.Context, options *ListOptions) ([]*Resource, error) {
 	var resources []*Resource
-	for _, sg := range result.SecurityGroups {
-		resource := &Resource{
-			ID:       aws.ToString(sg.GroupId),
-			Name:     aws.ToString(sg.GroupName),
-			Type:     "security-group",
-			Provider: "aws",
-			Region:   "", // Security groups don't have a specific region in the response
-			State:    "available",
-			Status:   "available",
-			Created:  time.Now(), // AWS doesn't provide creation time for security groups
-			Modified: time.Now(),
-			Tags:     p.convertSecurityGroupTags(sg.Tags),
-			Config: map[string]interface{}{
-				"description": aws.ToString(sg.Description),
-				"vpc_id":      aws.ToString(sg.VpcId),
-				"owner_id":    aws.ToString(sg.OwnerId),
-				"rules_count": len(sg.IpPermissions) + len(sg.IpPermissionsEgress),
-			},
+
+	paginator := ec2.NewDescribeSecurityGroupsPaginator(p.ec2Client, &ec2.DescribeSecurityGroupsInput{
+		Filters: ec2TagFilters(options),
+	})
+	for paginator.HasMorePages() && !options.Reached(len(resources)) {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe security groups: %w", err)
+		}
+
+		for _, sg := range page.SecurityGroups {
+			if options.Reached(len(resources)) {
+				return resources, nil
+			}
+			resources = append(resources, &Resource{
+				ID:       aws.ToString(sg.GroupId),
+				Name:     aws.ToString(sg.GroupName),
+				Type:     "security-group",
+				Provider: "aws",
+				Region:   "", // Security groups don't have a specific region in the response
+				State:    "available",
+				Status:   "available",
+				Created:  time.Now(), // AWS doesn't provide creation time for security groups
+				Modified: time.Now(),
+				Tags:     p.convertSecurityGroupTags(sg.Tags),
+				Config: map[string]interface{}{
+					"description":          aws.ToString(sg.Description),
+					"vpc_id":               aws.ToString(sg.VpcId),
+					"owner_id":             aws.ToString(sg.OwnerId),
+					"rules_count":          len(sg.IpPermissions) + len(sg.IpPermissionsEgress),
+					"ingress_rules_count":  len(sg.IpPermissions),
+					"egress_rules_count":   len(sg.IpPermissionsEgress),
+					"public_admin_ingress": allowsPublicAdminIngress(sg.IpPermissions),
+				},
+			})
 		}
-		resources = append(resources, resource)
 	}
 
 	return resources, nil
 }
 
-// listVPCs lists VPCs
-func (p *AWSProvider) listVPCs(ctx context.Context) ([]*Resource, error) {
-	input := &ec2.DescribeVpcsInput{}
-	result, err := p.ec2Client.DescribeVpcs(ctx, input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to describe VPCs: %w", err)
-	}
-
+// listVPCs lists VPCs across all result pages
+func (p *AWSProvider) listVPCs(ctx context.Context, options *ListOptions) ([]*Resource, error) {
 	var resources []*Resource
-	for _, vpc := range result.Vpcs {
-		resource := &Resource{
-			ID:       aws.ToString(vpc.VpcId),
-			Name:     p.getVPCName(vpc),
-			Type:     "vpc",
-			Provider: "aws",
-			Region:   "", // VPCs don't have a specific region in the response
-			State:    string(vpc.State),
-			Status:   string(vpc.State),
-			Created:  time.Now(), // AWS doesn't provide creation time for VPCs
-			Modified: time.Now(),
-			Tags:     p.convertVPCTags(vpc.Tags),
-			Config: map[string]interface{}{
-				"cidr_block":           aws.ToString(vpc.CidrBlock),
-				"dhcp_options_id":      aws.ToString(vpc.DhcpOptionsId),
-				"instance_tenancy":     string(vpc.InstanceTenancy),
-				"is_default":           aws.ToBool(vpc.IsDefault),
-				"ipv6_cidr_block_sets": len(vpc.Ipv6CidrBlockAssociationSet),
-				"owner_id":             aws.ToString(vpc.OwnerId),
-			},
+
+	paginator := ec2.NewDescribeVpcsPaginator(p.ec2Client, &ec2.DescribeVpcsInput{
+		Filters: ec2TagFilters(options),
+	})
+	for paginator.HasMorePages() && !options.Reached(len(resources)) {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe VPCs: %w", err)
+		}
+
+		for _, vpc := range page.Vpcs {
+			if options.Reached(len(resources)) {
+				return resources, nil
+			}
+			resources = append(resources, &Resource{
+				ID:       aws.ToString(vpc.VpcId),
+				Name:     p.getVPCName(vpc),
+				Type:     "vpc",
+				Provider: "aws",
+				Region:   "", // VPCs don't have a specific region in the response
+				State:    string(vpc.State),
+				Status:   string(vpc.State),
+				Created:  time.Now(), // AWS doesn't provide creation time for VPCs
+				Modified: time.Now(),
+				Tags:     p.convertVPCTags(vpc.Tags),
+				Config: map[string]interface{}{
+					"cidr_block":           aws.ToString(vpc.CidrBlock),
+					"dhcp_options_id":      aws.ToString(vpc.DhcpOptionsId),
+					"instance_tenancy":     string(vpc.InstanceTenancy),
+					"is_default":           aws.ToBool(vpc.IsDefault),
+					"ipv6_cidr_block_sets": len(vpc.Ipv6CidrBlockAssociationSet),
+					"owner_id":             aws.ToString(vpc.OwnerId),
+				},
+			})
 		}
-		resources = append(resources, resource)
 	}
 
 	return resources, nil
@@ -310,36 +429,23 @@ func (p *AWSProvider) getEC2InstanceDetails(ctx context.Context, instanceID stri
 	}
 
 	instance := result.Reservations[0].Instances[0]
-	resource := &Resource{
-		ID:       aws.ToString(instance.InstanceId),
-		Name:     p.getInstanceName(instance),
-		Type:     "ec2-instance",
-		Provider: "aws",
-		Region:   aws.ToString(instance.Placement.AvailabilityZone),
-		State:    string(instance.State.Name),
-		Status:   string(instance.State.Name),
-		Created:  aws.ToTime(instance.LaunchTime),
-		Modified: time.Now(),
-		Tags:     p.convertEC2Tags(instance.Tags),
-		Config: map[string]interface{}{
-			"instance_type":     string(instance.InstanceType),
-			"architecture":      string(instance.Architecture),
-			"platform":          aws.ToString(instance.PlatformDetails),
-			"vpc_id":            aws.ToString(instance.VpcId),
-			"subnet_id":         aws.ToString(instance.SubnetId),
-			"public_ip":         aws.ToString(instance.PublicIpAddress),
-			"private_ip":        aws.ToString(instance.PrivateIpAddress),
-			"public_dns":        aws.ToString(instance.PublicDnsName),
-			"private_dns":       aws.ToString(instance.PrivateDnsName),
-			"security_groups":   p.getSecurityGroupNames(instance.SecurityGroups),
-			"key_name":          aws.ToString(instance.KeyName),
-			"image_id":          aws.ToString(instance.ImageId),
-			"monitoring":        string(instance.Monitoring.State),
-			"source_dest_check": aws.ToBool(instance.SourceDestCheck),
-			"virtualization":    string(instance.VirtualizationType),
-			"root_device_name":  aws.ToString(instance.RootDeviceName),
-			"root_device_type":  string(instance.RootDeviceType),
-		},
+	resource := p.convertEC2Instance(instance)
+	var monitoring string
+	if instance.Monitoring != nil {
+		monitoring = string(instance.Monitoring.State)
+	}
+	for key, value := range map[string]interface{}{
+		"public_dns":        aws.ToString(instance.PublicDnsName),
+		"private_dns":       aws.ToString(instance.PrivateDnsName),
+		"key_name":          aws.ToString(instance.KeyName),
+		"image_id":          aws.ToString(instance.ImageId),
+		"monitoring":        monitoring,
+		"source_dest_check": aws.ToBool(instance.SourceDestCheck),
+		"virtualization":    string(instance.VirtualizationType),
+		"root_device_name":  aws.ToString(instance.RootDeviceName),
+		"root_device_type":  string(instance.RootDeviceType),
+	} {
+		resource.Config[key] = value
 	}
 
 	return resource, nil
@@ -413,6 +519,40 @@ func (p *AWSProvider) getSecurityGroupNames(groups []types.GroupIdentifier) []st
 	return names
 }
 
+// adminPorts are the remote administration ports (SSH and RDP)
+var adminPorts = []int32{22, 3389}
+
+// allowsPublicAdminIngress reports whether any ingress rule opens SSH or RDP to the internet
+func allowsPublicAdminIngress(permissions []types.IpPermission) bool {
+	for _, perm := range permissions {
+		public := false
+		for _, r := range perm.IpRanges {
+			if aws.ToString(r.CidrIp) == "0.0.0.0/0" {
+				public = true
+			}
+		}
+		for _, r := range perm.Ipv6Ranges {
+			if aws.ToString(r.CidrIpv6) == "::/0" {
+				public = true
+			}
+		}
+		if !public {
+			continue
+		}
+
+		// Protocol -1 means all traffic on all ports
+		if aws.ToString(perm.IpProtocol) == "-1" {
+			return true
+		}
+		for _, port := range adminPorts {
+			if aws.ToInt32(perm.FromPort) <= port && port <= aws.ToInt32(perm.ToPort) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Additional methods to implement CloudProvider interface
 func (p *AWSProvider) CreateResource(ctx context.Context, req *CreateResourceRequest) (*Resource, error) {
 	return nil, fmt.Errorf("CreateResource not implemented for AWS provider")
@@ -422,16 +562,286 @@ func (p *AWSProvider) UpdateResource(ctx context.Context, req *UpdateResourceReq
 	return nil, fmt.Errorf("UpdateResource not implemented for AWS provider")
 }
 
+// DeleteResource deletes an AWS resource, refusing when other resources depend
+// on it. In a dry run it plans the deletion instead
 func (p *AWSProvider) DeleteResource(ctx context.Context, resourceID string) error {
-	return fmt.Errorf("DeleteResource not implemented for AWS provider")
+	if !p.connected {
+		if err := p.Connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	dependents, err := p.GetDependents(ctx, resourceID)
+	if err != nil {
+		return fmt.Errorf("failed to check dependents of %s: %w", resourceID, err)
+	}
+	if len(dependents) > 0 {
+		return &DependencyError{ResourceID: resourceID, Dependents: dependents}
+	}
+
+	if plan := DryRunFrom(ctx); plan != nil {
+		plan.Plan(PlannedAction{
+			Action:      "delete",
+			Provider:    p.GetType(),
+			ResourceID:  resourceID,
+			Description: fmt.Sprintf("delete %s", resourceID),
+		})
+		return nil
+	}
+
+	switch {
+	case strings.HasPrefix(resourceID, "i-"):
+		_, err = p.ec2Client.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
+			InstanceIds: []string{resourceID},
+		})
+	case strings.HasPrefix(resourceID, "vol-"):
+		_, err = p.ec2Client.DeleteVolume(ctx, &ec2.DeleteVolumeInput{
+			VolumeId: aws.String(resourceID),
+		})
+	case strings.HasPrefix(resourceID, "sg-"):
+		_, err = p.ec2Client.DeleteSecurityGroup(ctx, &ec2.DeleteSecurityGroupInput{
+			GroupId: aws.String(resourceID),
+		})
+	case strings.HasPrefix(resourceID, "vpc-"):
+		_, err = p.ec2Client.DeleteVpc(ctx, &ec2.DeleteVpcInput{
+			VpcId: aws.String(resourceID),
+		})
+	default:
+		return fmt.Errorf("unsupported resource ID format: %s", resourceID)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", resourceID, err)
+	}
+
+	p.logger.Infof("Deleted AWS resource: %s", resourceID)
+	return nil
+}
+
+// GetDependents returns the active resources that depend on the given resource
+func (p *AWSProvider) GetDependents(ctx context.Context, resourceID string) ([]*Resource, error) {
+	if !p.connected {
+		if err := p.Connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(resourceID, "sg-"):
+		return p.findInstances(ctx, types.Filter{
+			Name:   aws.String("instance.group-id"),
+			Values: []string{resourceID},
+		})
+	case strings.HasPrefix(resourceID, "vpc-"):
+		return p.findInstances(ctx, types.Filter{
+			Name:   aws.String("vpc-id"),
+			Values: []string{resourceID},
+		})
+	case strings.HasPrefix(resourceID, "vol-"):
+		return p.findInstances(ctx, types.Filter{
+			Name:   aws.String("block-device-mapping.volume-id"),
+			Values: []string{resourceID},
+		})
+	}
+
+	// Instances have no tracked dependents
+	return nil, nil
+}
+
+// findInstances lists active EC2 instances matching the given filter
+func (p *AWSProvider) findInstances(ctx context.Context, filter types.Filter) ([]*Resource, error) {
+	input := &ec2.DescribeInstancesInput{
+		Filters: []types.Filter{
+			filter,
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: activeInstanceStates,
+			},
+		},
+	}
+
+	var resources []*Resource
+	paginator := ec2.NewDescribeInstancesPaginator(p.ec2Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe instances: %w", err)
+		}
+
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				resources = append(resources, p.convertEC2Instance(instance))
+			}
+		}
+	}
+
+	return resources, nil
 }
 
 func (p *AWSProvider) GetMetrics(ctx context.Context, req *MetricsRequest) (*MetricsResponse, error) {
 	return nil, fmt.Errorf("GetMetrics not implemented for AWS provider")
 }
 
+// GetCost retrieves cost and usage data from AWS Cost Explorer
 func (p *AWSProvider) GetCost(ctx context.Context, req *CostRequest) (*CostResponse, error) {
-	return nil, fmt.Errorf("GetCost not implemented for AWS provider")
+	if !p.connected {
+		if err := p.Connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if req == nil {
+		req = &CostRequest{}
+	}
+
+	// Default to the last 30 days when no period is given
+	endTime := req.EndTime
+	if endTime.IsZero() {
+		endTime = time.Now()
+	}
+	startTime := req.StartTime
+	if startTime.IsZero() {
+		startTime = endTime.AddDate(0, 0, -30)
+	}
+	if !startTime.Before(endTime) {
+		return nil, fmt.Errorf("invalid cost period: start time %s must be before end time %s",
+			startTime.Format(time.DateOnly), endTime.Format(time.DateOnly))
+	}
+
+	input := &costexplorer.GetCostAndUsageInput{
+		TimePeriod: &cetypes.DateInterval{
+			Start: aws.String(startTime.Format(time.DateOnly)),
+			End:   aws.String(endTime.Format(time.DateOnly)),
+		},
+		Granularity: cetypes.GranularityMonthly,
+		Metrics:     []string{costMetric},
+	}
+
+	if req.GroupBy != "" {
+		groupBy, err := costGroupDefinition(req.GroupBy)
+		if err != nil {
+			return nil, err
+		}
+		input.GroupBy = []cetypes.GroupDefinition{groupBy}
+	}
+
+	response := &CostResponse{
+		Currency: "USD",
+		Period: &CostPeriod{
+			StartTime: startTime,
+			EndTime:   endTime,
+		},
+		BreakdownBy: make(map[string]float64),
+	}
+
+	// Cost Explorer paginates results using NextPageToken
+	for {
+		result, err := p.ceClient.GetCostAndUsage(ctx, input)
+		if err != nil {
+			return nil, p.wrapCostExplorerError(err)
+		}
+
+		for _, period := range result.ResultsByTime {
+			if len(period.Groups) == 0 {
+				amount, unit, err := parseCostMetric(period.Total[costMetric])
+				if err != nil {
+					return nil, err
+				}
+				response.Total += amount
+				if unit != "" {
+					response.Currency = unit
+				}
+				continue
+			}
+
+			for _, group := range period.Groups {
+				amount, unit, err := parseCostMetric(group.Metrics[costMetric])
+				if err != nil {
+					return nil, err
+				}
+				key := strings.Join(group.Keys, ",")
+				response.BreakdownBy[key] += amount
+				response.Total += amount
+				if unit != "" {
+					response.Currency = unit
+				}
+			}
+		}
+
+		if aws.ToString(result.NextPageToken) == "" {
+			break
+		}
+		input.NextPageToken = result.NextPageToken
+	}
+
+	return response, nil
+}
+
+// costGroupDefinition maps a CostRequest group-by value to a Cost Explorer group definition
+func costGroupDefinition(groupBy string) (cetypes.GroupDefinition, error) {
+	if strings.HasPrefix(strings.ToLower(groupBy), "tag:") {
+		return cetypes.GroupDefinition{
+			Type: cetypes.GroupDefinitionTypeTag,
+			Key:  aws.String(groupBy[len("tag:"):]),
+		}, nil
+	}
+
+	dimensions := map[string]cetypes.Dimension{
+		"service":           cetypes.DimensionService,
+		"region":            cetypes.DimensionRegion,
+		"account":           cetypes.DimensionLinkedAccount,
+		"linked_account":    cetypes.DimensionLinkedAccount,
+		"az":                cetypes.DimensionAz,
+		"availability_zone": cetypes.DimensionAz,
+		"instance_type":     cetypes.DimensionInstanceType,
+		"usage_type":        cetypes.DimensionUsageType,
+		"operation":         cetypes.DimensionOperation,
+		"purchase_type":     cetypes.DimensionPurchaseType,
+	}
+
+	key := strings.ReplaceAll(strings.ToLower(groupBy), "-", "_")
+	dimension, ok := dimensions[key]
+	if !ok {
+		return cetypes.GroupDefinition{}, fmt.Errorf("unsupported cost group-by dimension: %s", groupBy)
+	}
+
+	return cetypes.GroupDefinition{
+		Type: cetypes.GroupDefinitionTypeDimension,
+		Key:  aws.String(string(dimension)),
+	}, nil
+}
+
+// parseCostMetric converts a Cost Explorer metric value to an amount and unit
+func parseCostMetric(metric cetypes.MetricValue) (float64, string, error) {
+	amount := aws.ToString(metric.Amount)
+	if amount == "" {
+		return 0, aws.ToString(metric.Unit), nil
+	}
+
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to parse cost amount %q: %w", amount, err)
+	}
+
+	return value, aws.ToString(metric.Unit), nil
+}
+
+// wrapCostExplorerError turns common Cost Explorer failures into actionable errors
+func (p *AWSProvider) wrapCostExplorerError(err error) error {
+	var unavailable *cetypes.DataUnavailableException
+	if errors.As(err, &unavailable) {
+		return fmt.Errorf("cost data is unavailable; make sure Cost Explorer is enabled for this account (data can take up to 24 hours to appear after enabling): %w", err)
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessDeniedException" {
+		if strings.Contains(strings.ToLower(apiErr.ErrorMessage()), "not enabled") {
+			return fmt.Errorf("Cost Explorer is not enabled for this account; enable it in the AWS Billing console: %w", err)
+		}
+		return fmt.Errorf("access to Cost Explorer denied; ensure the credentials allow ce:GetCostAndUsage: %w", err)
+	}
+
+	return fmt.Errorf("failed to get cost and usage: %w", err)
 }
 
 func (p *AWSProvider) GetConfiguration() *ProviderConfig {
@@ -458,6 +868,26 @@ func (p *AWSProvider) GetStatus() *ProviderStatus {
 	return status
 }
 
+// GetIdentity returns the account and ARN of the caller
+func (p *AWSProvider) GetIdentity(ctx context.Context) (*Identity, error) {
+	if !p.connected {
+		if err := p.Connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	output, err := p.stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller identity: %w", err)
+	}
+	return &Identity{
+		Provider:  p.GetType(),
+		Account:   aws.ToString(output.Account),
+		Principal: aws.ToString(output.Arn),
+		Region:    p.config.Region,
+	}, nil
+}
+
 func (p *AWSProvider) GetRegions(ctx context.Context) ([]string, error) {
 	input := &ec2.DescribeRegionsInput{}
 	result, err := p.ec2Client.DescribeRegions(ctx, input)
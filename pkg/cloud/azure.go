@@ -2,39 +2,142 @@ package cloud
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/AlloraAi/AlloraCLI/pkg/utils"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/costmanagement/armcostmanagement/v2"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/monitor/armmonitor"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v4"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
 	"github.com/sirupsen/logrus"
 )
 
+// Azure resource types of the resources ListResources lists by tag
+const (
+	azureVirtualMachineType = "Microsoft.Compute/virtualMachines"
+	azureVirtualNetworkType = "Microsoft.Network/virtualNetworks"
+)
+
+// azureMetricsAPI is the subset of the Azure Monitor metrics client used by
+// the Azure provider
+type azureMetricsAPI interface {
+	List(ctx context.Context, resourceURI string, options *armmonitor.MetricsClientListOptions) (armmonitor.MetricsClientListResponse, error)
+}
+
+// azureCostAPI is the subset of the Cost Management query client used by the
+// Azure provider
+type azureCostAPI interface {
+	Usage(ctx context.Context, scope string, parameters armcostmanagement.QueryDefinition, options *armcostmanagement.QueryClientUsageOptions) (armcostmanagement.QueryClientUsageResponse, error)
+}
+
+// Cost Management queries are throttled per tenant and scope; throttled
+// queries are retried this many times in all
+const maxCostAttempts = 4
+
+// azureCostColumn names the summed cost in Cost Management query results
+const azureCostColumn = "totalCost"
+
+// azureCostDimensions maps CostRequest group-by values to Cost Management
+// grouping dimensions
+var azureCostDimensions = map[string]string{
+	"service":        "ServiceName",
+	"service_name":   "ServiceName",
+	"resource_group": "ResourceGroup",
+	"resourcegroup":  "ResourceGroup",
+	"rg":             "ResourceGroup",
+	"region":         "ResourceLocation",
+	"location":       "ResourceLocation",
+	"meter_category": "MeterCategory",
+	"resource_type":  "ResourceType",
+}
+
+// azureRetryAfterHeaders carry how long to wait before retrying a throttled
+// Cost Management query, in seconds, most specific first
+var azureRetryAfterHeaders = []string{
+	"x-ms-ratelimit-microsoft.costmanagement-qpu-retry-after",
+	"x-ms-ratelimit-microsoft.costmanagement-entity-retry-after",
+	"x-ms-ratelimit-microsoft.costmanagement-tenant-retry-after",
+	"Retry-After",
+}
+
+// azureMetric is an Azure Monitor platform metric and the aggregation that
+// gives one value per interval
+type azureMetric struct {
+	name        string
+	aggregation armmonitor.AggregationTypeEnum
+}
+
+// azureMetrics maps the provider-neutral metric names to Azure Monitor
+// metrics. Percentages are averaged; byte counters are summed per interval
+var azureMetrics = map[string]azureMetric{
+	MetricCPUUtilization: {"Percentage CPU", armmonitor.AggregationTypeEnumAverage},
+	"network_in":         {"Network In Total", armmonitor.AggregationTypeEnumTotal},
+	"network_out":        {"Network Out Total", armmonitor.AggregationTypeEnumTotal},
+	"percentage cpu":     {"Percentage CPU", armmonitor.AggregationTypeEnumAverage},
+	"network in":         {"Network In", armmonitor.AggregationTypeEnumTotal},
+	"network out":        {"Network Out", armmonitor.AggregationTypeEnumTotal},
+	"network in total":   {"Network In Total", armmonitor.AggregationTypeEnumTotal},
+	"network out total":  {"Network Out Total", armmonitor.AggregationTypeEnumTotal},
+}
+
+// azureTimeGrains are the metric intervals Azure Monitor accepts, shortest first
+var azureTimeGrains = []struct {
+	period   time.Duration
+	interval string
+}{
+	{time.Minute, "PT1M"},
+	{5 * time.Minute, "PT5M"},
+	{15 * time.Minute, "PT15M"},
+	{30 * time.Minute, "PT30M"},
+	{time.Hour, "PT1H"},
+	{6 * time.Hour, "PT6H"},
+	{12 * time.Hour, "PT12H"},
+	{24 * time.Hour, "P1D"},
+}
+
 // AzureProvider implements the CloudProvider interface for Azure
 type AzureProvider struct {
-	credential     azcore.TokenCredential
-	computeClient  *armcompute.VirtualMachinesClient
-	networkClient  *armnetwork.VirtualNetworksClient
-	resourceClient *armresources.Client
-	subscriptionID string
-	config         *ProviderConfig
-	connected      bool
-	logger         *logrus.Logger
+	credential           azcore.TokenCredential
+	computeClient        *armcompute.VirtualMachinesClient
+	networkClient        *armnetwork.VirtualNetworksClient
+	resourceGroupsClient *armresources.ResourceGroupsClient
+	resourcesClient      *armresources.Client
+	metricsClient        azureMetricsAPI
+	costClient           azureCostAPI
+	subscriptionID       string
+	config               *ProviderConfig
+	connected            bool
+	logger               *logrus.Logger
+
+	// costBackoff grows the wait between throttled cost queries when Azure
+	// does not say how long to wait
+	costBackoff time.Duration
 }
 
 // NewAzureProvider creates a new Azure provider
 func NewAzureProvider(cfg *ProviderConfig) (CloudProvider, error) {
-	logger := logrus.New()
-	logger.SetLevel(logrus.InfoLevel)
+	logger := utils.NewLogger()
 
 	provider := &AzureProvider{
 		config:         cfg,
 		logger:         logger,
 		subscriptionID: cfg.SubscriptionID,
+		costBackoff:    5 * time.Second,
 	}
 
 	return provider, nil
@@ -58,8 +161,16 @@ func (p *AzureProvider) Connect(ctx context.Context) error {
 
 	p.logger.Info("Connecting to Azure...")
 
-	// Create credential
-	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	// Create credential, preferring a configured service principal
+	var cred azcore.TokenCredential
+	var err error
+	clientID := p.config.Credentials["client_id"]
+	clientSecret := p.config.Credentials["client_secret"]
+	if p.config.TenantID != "" && clientID != "" && clientSecret != "" {
+		cred, err = azidentity.NewClientSecretCredential(p.config.TenantID, clientID, clientSecret, nil)
+	} else {
+		cred, err = azidentity.NewDefaultAzureCredential(nil)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create Azure credential: %w", err)
 	}
@@ -87,7 +198,29 @@ func (p *AzureProvider) Connect(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to create Azure resource client factory: %w", err)
 	}
-	p.resourceClient = resourceClientFactory.NewClient()
+	p.resourceGroupsClient = resourceClientFactory.NewResourceGroupsClient()
+	p.resourcesClient = resourceClientFactory.NewClient()
+
+	metricsClient, err := armmonitor.NewMetricsClient(p.subscriptionID, cred, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create Azure Monitor metrics client: %w", err)
+	}
+	p.metricsClient = metricsClient
+
+	// Throttled cost queries are retried by GetCost, which knows the Cost
+	// Management retry headers, so the pipeline leaves 429 alone
+	costClient, err := armcostmanagement.NewQueryClient(cred, &arm.ClientOptions{
+		ClientOptions: policy.ClientOptions{
+			Retry: policy.RetryOptions{StatusCodes: []int{
+				http.StatusRequestTimeout, http.StatusInternalServerError, http.StatusBadGateway,
+				http.StatusServiceUnavailable, http.StatusGatewayTimeout,
+			}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create Azure Cost Management client: %w", err)
+	}
+	p.costClient = costClient
 
 	// Test connection
 	if err := p.ValidateCredentials(ctx); err != nil {
@@ -113,12 +246,12 @@ func (p *AzureProvider) IsConnected() bool {
 
 // ValidateCredentials validates Azure credentials
 func (p *AzureProvider) ValidateCredentials(ctx context.Context) error {
-	if p.resourceClient == nil {
-		return fmt.Errorf("resource client not initialized")
+	if p.resourceGroupsClient == nil {
+		return fmt.Errorf("resource groups client not initialized")
 	}
 
 	// Try to list resource groups to test credentials
-	pager := p.resourceClient.NewListPager(nil)
+	pager := p.resourceGroupsClient.NewListPager(nil)
 	if !pager.More() {
 		return fmt.Errorf("failed to access subscription resources")
 	}
@@ -132,76 +265,74 @@ func (p *AzureProvider) ValidateCredentials(ctx context.Context) error {
 }
 
 // ListResources lists Azure resources
-func (p *AzureProvider) ListResources(ctx context.Context, resourceType string) ([]*Resource, error) {
+func (p *AzureProvider) ListResources(ctx context.Context, resourceType string, opts ...ListOption) ([]*Resource, error) {
 	if !p.connected {
 		if err := p.Connect(ctx); err != nil {
 			return nil, err
 		}
 	}
 
+	options := NewListOptions(opts...)
+	var resources []*Resource
+	var err error
 	switch strings.ToLower(resourceType) {
-	case "vm", "virtualmachines", "vms":
-		return p.listVirtualMachines(ctx)
-	case "vnets", "virtualnetworks", "networks":
-		return p.listVirtualNetworks(ctx)
-	case "resourcegroups", "rg":
-		return p.listResourceGroups(ctx)
+	case "vm", "virtualmachines", "vms", "virtual-machine":
+		resources, err = p.listVirtualMachines(ctx, options)
+	case "vnets", "virtualnetworks", "networks", "virtual-network":
+		resources, err = p.listVirtualNetworks(ctx, options)
+	case "resourcegroups", "rg", "resource-group":
+		resources, err = p.listResourceGroups(ctx, options)
 	default:
 		return nil, fmt.Errorf("unsupported resource type: %s", resourceType)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	return options.Apply(resources), nil
 }
 
 // listVirtualMachines lists Azure virtual machines
-func (p *AzureProvider) listVirtualMachines(ctx context.Context) ([]*Resource, error) {
-	var resources []*Resource
-
-	// List all resource groups first
-	rgPager := p.resourceClient.NewListPager(nil)
-	for rgPager.More() {
-		page, err := rgPager.NextPage(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to list resource groups: %w", err)
-		}
+func (p *AzureProvider) listVirtualMachines(ctx context.Context, options *ListOptions) ([]*Resource, error) {
+	groups, tagged, err := p.listedGroups(ctx, azureVirtualMachineType, options)
+	if err != nil {
+		return nil, err
+	}
 
-		for _, rg := range page.Value {
-			if rg.Name == nil {
-				continue
+	var resources []*Resource
+	for _, group := range groups {
+		vmPager := p.computeClient.NewListPager(group, nil)
+		for vmPager.More() {
+			vmPage, err := vmPager.NextPage(ctx)
+			if err != nil {
+				p.logger.Warnf("Failed to list VMs in resource group %s: %v", group, err)
+				break
 			}
 
-			// List VMs in this resource group
-			vmPager := p.computeClient.NewListPager(*rg.Name, nil)
-			for vmPager.More() {
-				vmPage, err := vmPager.NextPage(ctx)
-				if err != nil {
-					p.logger.Warnf("Failed to list VMs in resource group %s: %v", *rg.Name, err)
+			for _, vm := range vmPage.Value {
+				if vm.Name == nil || vm.ID == nil || (tagged != nil && !tagged[strings.ToLower(*vm.ID)]) {
 					continue
 				}
 
-				for _, vm := range vmPage.Value {
-					if vm.Name == nil || vm.ID == nil {
-						continue
-					}
-
-					resource := &Resource{
-						ID:       *vm.ID,
-						Name:     *vm.Name,
-						Type:     "virtual-machine",
-						Provider: "azure",
-						Region:   p.getStringValue(vm.Location),
-						State:    p.getVMState(vm),
-						Status:   p.getVMState(vm),
-						Created:  time.Now(), // Azure doesn't provide creation time in list operation
-						Modified: time.Now(),
-						Tags:     p.convertAzureTags(vm.Tags),
-						Config: map[string]interface{}{
-							"resource_group": *rg.Name,
-							"vm_size":        p.getVMSize(vm),
-							"os_type":        p.getOSType(vm),
-							"location":       p.getStringValue(vm.Location),
-						},
-					}
-					resources = append(resources, resource)
+				resource := &Resource{
+					ID:       *vm.ID,
+					Name:     *vm.Name,
+					Type:     "virtual-machine",
+					Provider: "azure",
+					Region:   p.getStringValue(vm.Location),
+					State:    p.getVMState(vm),
+					Status:   p.getVMState(vm),
+					Created:  time.Now(), // Azure doesn't provide creation time in list operation
+					Modified: time.Now(),
+					Tags:     p.convertAzureTags(vm.Tags),
+					Config: map[string]interface{}{
+						"resource_group": group,
+						"vm_size":        p.getVMSize(vm),
+						"os_type":        p.getOSType(vm),
+						"location":       p.getStringValue(vm.Location),
+					},
 				}
+				resources = append(resources, resource)
 			}
 		}
 	}
@@ -210,56 +341,46 @@ func (p *AzureProvider) listVirtualMachines(ctx context.Context) ([]*Resource, e
 }
 
 // listVirtualNetworks lists Azure virtual networks
-func (p *AzureProvider) listVirtualNetworks(ctx context.Context) ([]*Resource, error) {
-	var resources []*Resource
-
-	// List all resource groups first
-	rgPager := p.resourceClient.NewListPager(nil)
-	for rgPager.More() {
-		page, err := rgPager.NextPage(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("failed to list resource groups: %w", err)
-		}
+func (p *AzureProvider) listVirtualNetworks(ctx context.Context, options *ListOptions) ([]*Resource, error) {
+	groups, tagged, err := p.listedGroups(ctx, azureVirtualNetworkType, options)
+	if err != nil {
+		return nil, err
+	}
 
-		for _, rg := range page.Value {
-			if rg.Name == nil {
-				continue
+	var resources []*Resource
+	for _, group := range groups {
+		vnetPager := p.networkClient.NewListPager(group, nil)
+		for vnetPager.More() {
+			vnetPage, err := vnetPager.NextPage(ctx)
+			if err != nil {
+				p.logger.Warnf("Failed to list VNets in resource group %s: %v", group, err)
+				break
 			}
 
-			// List VNets in this resource group
-			vnetPager := p.networkClient.NewListPager(*rg.Name, nil)
-			for vnetPager.More() {
-				vnetPage, err := vnetPager.NextPage(ctx)
-				if err != nil {
-					p.logger.Warnf("Failed to list VNets in resource group %s: %v", *rg.Name, err)
+			for _, vnet := range vnetPage.Value {
+				if vnet.Name == nil || vnet.ID == nil || (tagged != nil && !tagged[strings.ToLower(*vnet.ID)]) {
 					continue
 				}
 
-				for _, vnet := range vnetPage.Value {
-					if vnet.Name == nil || vnet.ID == nil {
-						continue
-					}
-
-					resource := &Resource{
-						ID:       *vnet.ID,
-						Name:     *vnet.Name,
-						Type:     "virtual-network",
-						Provider: "azure",
-						Region:   p.getStringValue(vnet.Location),
-						State:    p.getVNetState(vnet),
-						Status:   p.getVNetState(vnet),
-						Created:  time.Now(),
-						Modified: time.Now(),
-						Tags:     p.convertAzureTags(vnet.Tags),
-						Config: map[string]interface{}{
-							"resource_group": *rg.Name,
-							"location":       p.getStringValue(vnet.Location),
-							"address_spaces": p.getAddressSpaces(vnet),
-							"subnets_count":  p.getSubnetsCount(vnet),
-						},
-					}
-					resources = append(resources, resource)
+				resource := &Resource{
+					ID:       *vnet.ID,
+					Name:     *vnet.Name,
+					Type:     "virtual-network",
+					Provider: "azure",
+					Region:   p.getStringValue(vnet.Location),
+					State:    p.getVNetState(vnet),
+					Status:   p.getVNetState(vnet),
+					Created:  time.Now(),
+					Modified: time.Now(),
+					Tags:     p.convertAzureTags(vnet.Tags),
+					Config: map[string]interface{}{
+						"resource_group": group,
+						"location":       p.getStringValue(vnet.Location),
+						"address_spaces": p.getAddressSpaces(vnet),
+						"subnets_count":  p.getSubnetsCount(vnet),
+					},
 				}
+				resources = append(resources, resource)
 			}
 		}
 	}
@@ -267,11 +388,85 @@ func (p *AzureProvider) listVirtualNetworks(ctx context.Context) ([]*Resource, e
 	return resources, nil
 }
 
+// listedGroups returns the resource groups to list resources of azureType
+// in. With tags, the resources carrying the first one are looked up
+// server-side, and only the groups holding those of azureType are listed;
+// tagged holds their lowercased IDs, and is nil without tags
+func (p *AzureProvider) listedGroups(ctx context.Context, azureType string, options *ListOptions) (groups []string, tagged map[string]bool, err error) {
+	keys := options.SortedTagKeys()
+	if len(keys) == 0 {
+		groups, err = p.resourceGroupNames(ctx)
+		return groups, nil, err
+	}
+
+	// The resources API does not combine a tag query with a resourceType
+	// one, so the type is matched on the results
+	var matches []*armresources.GenericResourceExpanded
+	pager := p.resourcesClient.NewListPager(&armresources.ClientListOptions{
+		Filter: to.Ptr(azureTagFilter(keys[0], options.Tags[keys[0]])),
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list tagged resources: %w", err)
+		}
+		matches = append(matches, page.Value...)
+	}
+	groups, tagged = azureTaggedGroups(matches, azureType)
+	return groups, tagged, nil
+}
+
+// azureTaggedGroups returns the sorted resource groups holding resources of
+// azureType, and the lowercased IDs of those resources
+func azureTaggedGroups(resources []*armresources.GenericResourceExpanded, azureType string) ([]string, map[string]bool) {
+	tagged := map[string]bool{}
+	groups := map[string]bool{}
+	for _, resource := range resources {
+		if resource == nil || resource.ID == nil || resource.Type == nil || !strings.EqualFold(*resource.Type, azureType) {
+			continue
+		}
+		id, err := arm.ParseResourceID(*resource.ID)
+		if err != nil || id.ResourceGroupName == "" {
+			continue
+		}
+		tagged[strings.ToLower(*resource.ID)] = true
+		groups[id.ResourceGroupName] = true
+	}
+	return slices.Sorted(maps.Keys(groups)), tagged
+}
+
+// resourceGroupNames lists the names of the subscription's resource groups
+func (p *AzureProvider) resourceGroupNames(ctx context.Context) ([]string, error) {
+	var names []string
+	pager := p.resourceGroupsClient.NewListPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list resource groups: %w", err)
+		}
+		for _, rg := range page.Value {
+			if rg.Name != nil {
+				names = append(names, *rg.Name)
+			}
+		}
+	}
+	return names, nil
+}
+
 // listResourceGroups lists Azure resource groups
-func (p *AzureProvider) listResourceGroups(ctx context.Context) ([]*Resource, error) {
+func (p *AzureProvider) listResourceGroups(ctx context.Context, options *ListOptions) ([]*Resource, error) {
 	var resources []*Resource
 
-	pager := p.resourceClient.NewListPager(nil)
+	// The resource groups API accepts a single tag query; remaining tags are
+	// matched in memory by ListResources
+	var listOptions *armresources.ResourceGroupsClientListOptions
+	if keys := options.SortedTagKeys(); len(keys) > 0 {
+		listOptions = &armresources.ResourceGroupsClientListOptions{
+			Filter: to.Ptr(azureTagFilter(keys[0], options.Tags[keys[0]])),
+		}
+	}
+
+	pager := p.resourceGroupsClient.NewListPager(listOptions)
 	for pager.More() {
 		page, err := pager.NextPage(ctx)
 		if err != nil {
@@ -289,14 +484,14 @@ func (p *AzureProvider) listResourceGroups(ctx context.Context) ([]*Resource, er
 				Type:     "resource-group",
 				Provider: "azure",
 				Region:   p.getStringValue(rg.Location),
-				State:    p.getGenericResourceState(rg),
-				Status:   p.getGenericResourceState(rg),
+				State:    p.getResourceGroupState(rg),
+				Status:   p.getResourceGroupState(rg),
 				Created:  time.Now(),
 				Modified: time.Now(),
 				Tags:     p.convertAzureTags(rg.Tags),
 				Config: map[string]interface{}{
 					"location":           p.getStringValue(rg.Location),
-					"provisioning_state": p.getGenericResourceProvisioningState(rg),
+					"provisioning_state": p.getResourceGroupProvisioningState(rg),
 				},
 			}
 			resources = append(resources, resource)
@@ -306,6 +501,14 @@ func (p *AzureProvider) listResourceGroups(ctx context.Context) ([]*Resource, er
 	return resources, nil
 }
 
+// azureTagFilter builds an OData tag query for the resources API
+func azureTagFilter(key, value string) string {
+	escape := func(s string) string {
+		return strings.ReplaceAll(s, "'", "''")
+	}
+	return fmt.Sprintf("tagName eq '%s' and tagValue eq '%s'", escape(key), escape(value))
+}
+
 // GetResourceDetails gets detailed information about a resource
 func (p *AzureProvider) GetResourceDetails(ctx context.Context, resourceID string) (*Resource, error) {
 	if !p.connected {
@@ -443,28 +646,6 @@ func (p *AzureProvider) getResourceGroupProvisioningState(rg *armresources.Resou
 	return "unknown"
 }
 
-func (p *AzureProvider) getGenericResourceState(rg *armresources.GenericResourceExpanded) string {
-	if rg.Properties != nil {
-		if state, ok := rg.Properties.(map[string]interface{}); ok {
-			if provisioningState, ok := state["provisioningState"].(string); ok {
-				return provisioningState
-			}
-		}
-	}
-	return "unknown"
-}
-
-func (p *AzureProvider) getGenericResourceProvisioningState(rg *armresources.GenericResourceExpanded) string {
-	if rg.Properties != nil {
-		if state, ok := rg.Properties.(map[string]interface{}); ok {
-			if provisioningState, ok := state["provisioningState"].(string); ok {
-				return provisioningState
-			}
-		}
-	}
-	return "unknown"
-}
-
 func (p *AzureProvider) convertAzureTags(tags map[string]*string) map[string]string {
 	result := make(map[string]string)
 	for k, v := range tags {
@@ -488,12 +669,276 @@ func (p *AzureProvider) DeleteResource(ctx context.Context, resourceID string) e
 	return fmt.Errorf("DeleteResource not implemented for Azure provider")
 }
 
+// GetMetrics queries Azure Monitor for a platform metric of a resource over
+// the requested time range
 func (p *AzureProvider) GetMetrics(ctx context.Context, req *MetricsRequest) (*MetricsResponse, error) {
-	return nil, fmt.Errorf("GetMetrics not implemented for Azure provider")
+	if err := validateAzureMetricsTarget(req.ResourceID); err != nil {
+		return nil, err
+	}
+	metric, ok := azureMetrics[strings.ToLower(req.MetricName)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported Azure metric: %s", req.MetricName)
+	}
+	if !req.EndTime.After(req.StartTime) {
+		return nil, fmt.Errorf("metrics end time must be after the start time")
+	}
+
+	if !p.connected {
+		if err := p.Connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	aggregation := string(metric.aggregation)
+	resultType := armmonitor.ResultTypeData
+	resp, err := p.metricsClient.List(ctx, strings.TrimPrefix(req.ResourceID, "/"), &armmonitor.MetricsClientListOptions{
+		Metricnames: to.Ptr(metric.name),
+		Aggregation: to.Ptr(aggregation),
+		Timespan:    to.Ptr(req.StartTime.UTC().Format(time.RFC3339) + "/" + req.EndTime.UTC().Format(time.RFC3339)),
+		Interval:    to.Ptr(azureTimeGrain(req.Period)),
+		ResultType:  &resultType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s for %s: %w", metric.name, req.ResourceID, err)
+	}
+
+	response := &MetricsResponse{MetricName: req.MetricName, DataPoints: []*MetricDataPoint{}}
+	for _, m := range resp.Value {
+		if m == nil {
+			continue
+		}
+		if m.ErrorCode != nil && *m.ErrorCode != "Success" {
+			message := *m.ErrorCode
+			if m.ErrorMessage != nil {
+				message = *m.ErrorMessage
+			}
+			return nil, fmt.Errorf("failed to get %s for %s: %s", metric.name, req.ResourceID, message)
+		}
+		unit := ""
+		if m.Unit != nil {
+			unit = string(*m.Unit)
+		}
+		for _, series := range m.Timeseries {
+			if series == nil {
+				continue
+			}
+			for _, value := range series.Data {
+				point := azureDataPoint(value, metric.aggregation, unit)
+				if point != nil {
+					response.DataPoints = append(response.DataPoints, point)
+				}
+			}
+		}
+	}
+	return response, nil
+}
+
+// validateAzureMetricsTarget checks that id names a resource, not a
+// subscription or resource group, which Azure Monitor has no metrics for
+func validateAzureMetricsTarget(id string) error {
+	parsed, err := arm.ParseResourceID(id)
+	if err != nil || parsed.SubscriptionID == "" || parsed.ResourceGroupName == "" ||
+		parsed.ResourceType.Namespace == "Microsoft.Resources" {
+		return fmt.Errorf("%q is not an Azure metrics target: expected a resource ID such as /subscriptions/<id>/resourceGroups/<group>/providers/Microsoft.Compute/virtualMachines/<name>", id)
+	}
+	return nil
+}
+
+// azureTimeGrain returns the shortest Azure Monitor interval covering period
+// seconds, or one hour when period is unset
+func azureTimeGrain(period int) string {
+	if period <= 0 {
+		return "PT1H"
+	}
+	want := time.Duration(period) * time.Second
+	for _, grain := range azureTimeGrains {
+		if grain.period >= want {
+			return grain.interval
+		}
+	}
+	return azureTimeGrains[len(azureTimeGrains)-1].interval
+}
+
+// azureDataPoint converts a metric value, skipping intervals Azure reports
+// without data for the aggregation
+func azureDataPoint(value *armmonitor.MetricValue, aggregation armmonitor.AggregationTypeEnum, unit string) *MetricDataPoint {
+	if value == nil || value.TimeStamp == nil {
+		return nil
+	}
+	var v *float64
+	switch aggregation {
+	case armmonitor.AggregationTypeEnumTotal:
+		v = value.Total
+	default:
+		v = value.Average
+	}
+	if v == nil {
+		return nil
+	}
+	return &MetricDataPoint{Timestamp: value.TimeStamp.UTC(), Value: *v, Unit: unit}
 }
 
+// GetCost queries Cost Management for the actual cost of the subscription
+// over the requested period, grouped by one dimension when GroupBy is set
 func (p *AzureProvider) GetCost(ctx context.Context, req *CostRequest) (*CostResponse, error) {
-	return nil, fmt.Errorf("GetCost not implemented for Azure provider")
+	if req == nil {
+		req = &CostRequest{}
+	}
+
+	// Default to the last 30 days when no period is given
+	endTime := req.EndTime
+	if endTime.IsZero() {
+		endTime = time.Now()
+	}
+	startTime := req.StartTime
+	if startTime.IsZero() {
+		startTime = endTime.AddDate(0, 0, -30)
+	}
+	if !startTime.Before(endTime) {
+		return nil, fmt.Errorf("invalid cost period: start time %s must be before end time %s",
+			startTime.Format(time.DateOnly), endTime.Format(time.DateOnly))
+	}
+
+	dataset := &armcostmanagement.QueryDataset{
+		Aggregation: map[string]*armcostmanagement.QueryAggregation{
+			azureCostColumn: {Name: to.Ptr("Cost"), Function: to.Ptr(armcostmanagement.FunctionTypeSum)},
+		},
+	}
+	dimension := ""
+	if req.GroupBy != "" {
+		var ok bool
+		dimension, ok = azureCostDimensions[strings.ReplaceAll(strings.ToLower(req.GroupBy), "-", "_")]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cost group-by dimension: %s", req.GroupBy)
+		}
+		dataset.Grouping = []*armcostmanagement.QueryGrouping{
+			{Type: to.Ptr(armcostmanagement.QueryColumnTypeDimension), Name: to.Ptr(dimension)},
+		}
+	}
+
+	if !p.connected {
+		if err := p.Connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := p.queryCost(ctx, armcostmanagement.QueryDefinition{
+		Type:       to.Ptr(armcostmanagement.ExportTypeActualCost),
+		Timeframe:  to.Ptr(armcostmanagement.TimeframeTypeCustom),
+		TimePeriod: &armcostmanagement.QueryTimePeriod{From: to.Ptr(startTime.UTC()), To: to.Ptr(endTime.UTC())},
+		Dataset:    dataset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &CostResponse{
+		Currency: "USD",
+		Period: &CostPeriod{
+			StartTime: startTime,
+			EndTime:   endTime,
+		},
+		BreakdownBy: make(map[string]float64),
+	}
+	if result.Properties == nil {
+		return response, nil
+	}
+	if result.Properties.NextLink != nil && *result.Properties.NextLink != "" {
+		p.logger.Warnf("Azure cost results by %s were truncated to the first %d groups", dimension, len(result.Properties.Rows))
+	}
+
+	columns := make(map[string]int)
+	for i, column := range result.Properties.Columns {
+		if column != nil && column.Name != nil {
+			columns[strings.ToLower(*column.Name)] = i
+		}
+	}
+	// The summed column is named after either the alias or the source column
+	var costIndex int
+	found := false
+	for _, name := range []string{strings.ToLower(azureCostColumn), "cost", "pretaxcost"} {
+		if costIndex, found = columns[name]; found {
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("Azure cost query returned no cost column")
+	}
+	currencyIndex, hasCurrency := columns["currency"]
+	groupIndex, hasGroup := columns[strings.ToLower(dimension)]
+
+	for _, row := range result.Properties.Rows {
+		if costIndex >= len(row) {
+			continue
+		}
+		amount, ok := row[costIndex].(float64)
+		if !ok {
+			return nil, fmt.Errorf("failed to parse cost amount %v", row[costIndex])
+		}
+		response.Total += amount
+		if hasCurrency && currencyIndex < len(row) {
+			if currency, ok := row[currencyIndex].(string); ok && currency != "" {
+				response.Currency = currency
+			}
+		}
+		if hasGroup && groupIndex < len(row) {
+			group := fmt.Sprint(row[groupIndex])
+			if group == "" {
+				group = "(none)"
+			}
+			response.BreakdownBy[group] += amount
+		}
+	}
+	return response, nil
+}
+
+// queryCost runs a Cost Management query against the subscription, waiting
+// and retrying while it is throttled
+func (p *AzureProvider) queryCost(ctx context.Context, query armcostmanagement.QueryDefinition) (armcostmanagement.QueryResult, error) {
+	scope := "subscriptions/" + p.subscriptionID
+	for attempt := 1; ; attempt++ {
+		resp, err := p.costClient.Usage(ctx, scope, query, nil)
+		if err == nil {
+			return resp.QueryResult, nil
+		}
+
+		var respErr *azcore.ResponseError
+		if !errors.As(err, &respErr) {
+			return armcostmanagement.QueryResult{}, fmt.Errorf("failed to query Azure costs: %w", err)
+		}
+		switch {
+		case respErr.StatusCode == http.StatusForbidden || respErr.StatusCode == http.StatusUnauthorized:
+			return armcostmanagement.QueryResult{}, fmt.Errorf("failed to query Azure costs: the credentials need the Cost Management Reader role on subscription %s: %w", p.subscriptionID, err)
+		case respErr.StatusCode != http.StatusTooManyRequests:
+			return armcostmanagement.QueryResult{}, fmt.Errorf("failed to query Azure costs: %w", err)
+		case attempt == maxCostAttempts:
+			return armcostmanagement.QueryResult{}, fmt.Errorf("failed to query Azure costs: still throttled after %d attempts: %w", attempt, err)
+		}
+
+		delay := time.Duration(attempt) * p.costBackoff
+		if respErr.RawResponse != nil {
+			if wait, ok := azureRetryAfter(respErr.RawResponse.Header); ok {
+				delay = wait
+			}
+		}
+		p.logger.Debugf("Azure cost query throttled; retrying in %s", delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return armcostmanagement.QueryResult{}, fmt.Errorf("failed to query Azure costs: %w", ctx.Err())
+		}
+	}
+}
+
+// azureRetryAfter reads how long Cost Management asked to wait before the
+// next query
+func azureRetryAfter(header http.Header) (time.Duration, bool) {
+	for _, name := range azureRetryAfterHeaders {
+		if seconds, err := strconv.Atoi(header.Get(name)); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	return 0, false
 }
 
 func (p *AzureProvider) GetConfiguration() *ProviderConfig {
@@ -523,6 +968,72 @@ func (p *AzureProvider) GetStatus() *ProviderStatus {
 	return status
 }
 
+// GetIdentity returns the subscription and the user or application the
+// credential signs in as, read from the claims of its management token
+func (p *AzureProvider) GetIdentity(ctx context.Context) (*Identity, error) {
+	if !p.connected {
+		if err := p.Connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	token, err := p.credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Azure token: %w", err)
+	}
+	identity, err := azureTokenIdentity(token.Token)
+	if err != nil {
+		return nil, err
+	}
+	identity.Provider = p.GetType()
+	identity.Account = p.subscriptionID
+	if identity.Tenant == "" {
+		identity.Tenant = p.config.TenantID
+	}
+	identity.Region = p.config.Region
+	return identity, nil
+}
+
+// azureTokenIdentity reads the tenant and principal from the claims of an
+// Entra ID access token. Users are named by their sign-in name and
+// applications by their client ID
+func azureTokenIdentity(token string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("failed to read Azure token: not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Azure token: %w", err)
+	}
+	var claims struct {
+		TenantID          string `json:"tid"`
+		ObjectID          string `json:"oid"`
+		UPN               string `json:"upn"`
+		PreferredUsername string `json:"preferred_username"`
+		UniqueName        string `json:"unique_name"`
+		AppID             string `json:"appid"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("failed to read Azure token claims: %w", err)
+	}
+
+	identity := &Identity{Tenant: claims.TenantID}
+	for _, principal := range []string{claims.UPN, claims.PreferredUsername, claims.UniqueName} {
+		if principal != "" {
+			identity.Principal = principal
+			return identity, nil
+		}
+	}
+	switch {
+	case claims.AppID != "":
+		identity.Principal = "application " + claims.AppID
+	case claims.ObjectID != "":
+		identity.Principal = "object " + claims.ObjectID
+	}
+	return identity, nil
+}
+
 func (p *AzureProvider) GetRegions(ctx context.Context) ([]string, error) {
 	// Azure regions are well-known, return common ones
 	return []string{
@@ -629,7 +1140,7 @@ func (p *AzureProvider) getVirtualNetworkDetails(ctx context.Context, resourceID
 
 func (p *AzureProvider) getResourceGroupDetails(ctx context.Context, resourceID string) (*Resource, error) {
 	// For simplicity, we'll use the list API to find the resource
-	resources, err := p.listResourceGroups(ctx)
+	resources, err := p.listResourceGroups(ctx, NewListOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to list resource groups: %w", err)
 	}
@@ -0,0 +1,102 @@
+package cloud
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+)
+
+// DefaultCacheTTL is how long listed resources are reused when
+// cloud_providers.cache_ttl is not set
+const DefaultCacheTTL = 5 * time.Minute
+
+// cacheEntry holds one cached listing
+type cacheEntry struct {
+	provider  string
+	resources []Resource
+	expires   time.Time
+}
+
+// cacheKey identifies a listing by provider, resource type and filters
+func cacheKey(provider, resourceType string, options *ListOptions) string {
+	keys := make([]string, 0, len(options.Tags))
+	for key := range options.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s|%s|%d", provider, resourceType, options.Max)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "|%s=%s", key, options.Tags[key])
+	}
+	return b.String()
+}
+
+// cachedResources returns a copy of the cached listing for key if it has not expired
+func (c *DefaultCloudService) cachedResources(key string) ([]Resource, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.cache[key]
+	if !ok || !c.now().Before(entry.expires) {
+		return nil, false
+	}
+	return append([]Resource(nil), entry.resources...), true
+}
+
+// cacheResources stores a listing for the cache TTL
+func (c *DefaultCloudService) cacheResources(key, provider string, resources []Resource) {
+	if c.cacheTTL <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cache == nil {
+		c.cache = make(map[string]cacheEntry)
+	}
+	c.cache[key] = cacheEntry{
+		provider:  provider,
+		resources: append([]Resource(nil), resources...),
+		expires:   c.now().Add(c.cacheTTL),
+	}
+}
+
+// InvalidateCache drops the cached listings of provider, or of every
+// provider when provider is empty, so the next listing queries the cloud API
+func (c *DefaultCloudService) InvalidateCache(provider string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.cache {
+		if provider == "" || entry.provider == provider {
+			delete(c.cache, key)
+		}
+	}
+}
+
+// now returns the current time, overridable in tests
+func (c *DefaultCloudService) now() time.Time {
+	if c.clock != nil {
+		return c.clock()
+	}
+	return time.Now()
+}
+
+// cacheTTLFromConfig returns the configured cache TTL, DefaultCacheTTL if
+// unset; zero turns caching off
+func cacheTTLFromConfig(cfg *config.Config) time.Duration {
+	if cfg == nil || cfg.CloudProviders.CacheTTL == "" {
+		return DefaultCacheTTL
+	}
+	ttl, err := time.ParseDuration(cfg.CloudProviders.CacheTTL)
+	if err != nil || ttl < 0 {
+		return DefaultCacheTTL
+	}
+	return ttl
+}
@@ -2,7 +2,12 @@ package cloud
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,14 +16,20 @@ import (
 
 // CloudService interface defines cloud provider operations
 type CloudService interface {
-	ListResources(ctx context.Context, provider string, resourceType string) ([]Resource, error)
+	ListResources(ctx context.Context, provider string, resourceType string, opts ...ListOption) ([]Resource, error)
+	ListAllResources(ctx context.Context, resourceType string, opts ...ListOption) ([]Resource, error)
+	InvalidateCache(provider string)
+	ListResourcesFiltered(ctx context.Context, provider string, resourceType string, filters map[string]string) ([]Resource, error)
 	CreateResource(ctx context.Context, provider string, spec ResourceSpec) (*Resource, error)
 	UpdateResource(ctx context.Context, provider string, resourceID string, spec ResourceSpec) (*Resource, error)
 	DeleteResource(ctx context.Context, provider string, resourceID string) error
+	GetDependents(ctx context.Context, provider string, resourceID string) ([]*Resource, error)
 	GetResourceDetails(ctx context.Context, provider string, resourceID string) (*ResourceDetails, error)
 	GetCostAnalysis(ctx context.Context, provider string, options CostOptions) (*CostAnalysis, error)
 	OptimizeResources(ctx context.Context, provider string, options OptimizeOptions) (*OptimizationResult, error)
-	MonitorHealth(ctx context.Context, provider string) (<-chan HealthEvent, error)
+	MonitorHealth(ctx context.Context, provider string, options HealthOptions) (<-chan HealthEvent, error)
+	GetIdentities(ctx context.Context) ([]Identity, error)
+	DetectDrift(ctx context.Context, provider string, desired []Resource) (*DriftReport, error)
 }
 
 // CloudProvider interface defines cloud provider operations
@@ -28,7 +39,7 @@ type CloudProvider interface {
 	Connect(ctx context.Context) error
 	Disconnect(ctx context.Context) error
 	IsConnected() bool
-	ListResources(ctx context.Context, resourceType string) ([]*Resource, error)
+	ListResources(ctx context.Context, resourceType string, opts ...ListOption) ([]*Resource, error)
 	GetResourceDetails(ctx context.Context, resourceID string) (*Resource, error)
 	CreateResource(ctx context.Context, req *CreateResourceRequest) (*Resource, error)
 	UpdateResource(ctx context.Context, req *UpdateResourceRequest) (*Resource, error)
@@ -41,6 +52,7 @@ type CloudProvider interface {
 	ValidateCredentials(ctx context.Context) error
 	GetRegions(ctx context.Context) ([]string, error)
 	GetResourceTypes(ctx context.Context) ([]string, error)
+	GetIdentity(ctx context.Context) (*Identity, error)
 }
 
 // Resource represents a cloud resource
@@ -158,6 +170,8 @@ type CostOptions struct {
 	Granularity  string    `json:"granularity"`
 	GroupBy      []string  `json:"group_by"`
 	ResourceType string    `json:"resource_type"`
+	// Budget is the spend allowed for the period; 0 means no budget
+	Budget float64 `json:"budget,omitempty"`
 }
 
 // CostAnalysis provides cost analysis results
@@ -168,6 +182,19 @@ type CostAnalysis struct {
 	Breakdown       []CostBreakdown      `json:"breakdown"`
 	Trends          []CostTrend          `json:"trends"`
 	Recommendations []CostRecommendation `json:"recommendations"`
+	BudgetStatus    *BudgetStatus        `json:"budget_status,omitempty"`
+}
+
+// BudgetStatus compares spend with the budget set in CostOptions
+type BudgetStatus struct {
+	Budget      float64 `json:"budget"`
+	Spent       float64 `json:"spent"`
+	Remaining   float64 `json:"remaining"`
+	PercentUsed float64 `json:"percent_used"`
+	OverBudget  bool    `json:"over_budget"`
+	// ProjectedSpend is the expected end-of-period spend if the latest trend continues
+	ProjectedSpend      float64 `json:"projected_spend"`
+	ProjectedOverBudget bool    `json:"projected_over_budget"`
 }
 
 // CostBreakdown provides cost breakdown by category
@@ -195,6 +222,7 @@ type CostRecommendation struct {
 	Effort      string   `json:"effort"`
 	Risk        string   `json:"risk"`
 	Actions     []string `json:"actions"`
+	Priority    string   `json:"priority,omitempty"`
 }
 
 // OptimizeOptions defines options for resource optimization
@@ -202,6 +230,11 @@ type OptimizeOptions struct {
 	ResourceTypes []string `json:"resource_types"`
 	Criteria      []string `json:"criteria"`
 	DryRun        bool     `json:"dry_run"`
+	// Lookback is how far back utilization is examined, DefaultOptimizeLookback if unset
+	Lookback time.Duration `json:"lookback,omitempty"`
+	// Threshold is the utilization percentage below which an instance is oversized,
+	// DefaultOptimizeThreshold if unset
+	Threshold float64 `json:"threshold,omitempty"`
 }
 
 // OptimizationResult provides optimization results
@@ -212,6 +245,9 @@ type OptimizationResult struct {
 	Recommendations  []OptimizationRecommendation `json:"recommendations"`
 	PotentialSavings float64                      `json:"potential_savings"`
 	RiskAssessment   string                       `json:"risk_assessment"`
+	// Warnings describe checks that could not run, such as instances whose
+	// utilization metrics were unavailable
+	Warnings []string `json:"warnings,omitempty"`
 }
 
 // OptimizationRecommendation represents an optimization recommendation
@@ -238,6 +274,88 @@ type HealthEvent struct {
 	Details    map[string]string `json:"details"`
 }
 
+// ListOptions controls how resources are listed
+type ListOptions struct {
+	// Max caps the number of resources returned; zero means no limit
+	Max int
+	// Tags restricts the listing to resources carrying all given tags
+	Tags map[string]string
+	// NoCache bypasses cached listings and refreshes them from the provider
+	NoCache bool
+}
+
+// ListOption configures ListOptions
+type ListOption func(*ListOptions)
+
+// WithMax limits a listing to at most max resources
+func WithMax(max int) ListOption {
+	return func(o *ListOptions) {
+		o.Max = max
+	}
+}
+
+// WithTags limits a listing to resources carrying all given tag values
+func WithTags(tags map[string]string) ListOption {
+	return func(o *ListOptions) {
+		o.Tags = tags
+	}
+}
+
+// WithNoCache makes a listing query the provider even if a cached listing is fresh
+func WithNoCache() ListOption {
+	return func(o *ListOptions) {
+		o.NoCache = true
+	}
+}
+
+// NewListOptions applies the given options to a ListOptions
+func NewListOptions(opts ...ListOption) *ListOptions {
+	options := &ListOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// Reached reports whether a listing holding count resources hit the cap
+func (o *ListOptions) Reached(count int) bool {
+	return o.Max > 0 && count >= o.Max
+}
+
+// MatchesTags reports whether tags contain every configured tag filter
+func (o *ListOptions) MatchesTags(tags map[string]string) bool {
+	for key, value := range o.Tags {
+		if actual, ok := tags[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply filters resources by tag and truncates them to the configured cap
+func (o *ListOptions) Apply(resources []*Resource) []*Resource {
+	var result []*Resource
+	for _, resource := range resources {
+		if o.Reached(len(result)) {
+			break
+		}
+		if o.MatchesTags(resource.Tags) {
+			result = append(result, resource)
+		}
+	}
+	return result
+}
+
+// SortedTagKeys returns the tag filter keys in a stable order
+func (o *ListOptions) SortedTagKeys() []string {
+	keys := make([]string, 0, len(o.Tags))
+	for key := range o.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // CreateResourceRequest represents a request to create a resource
 type CreateResourceRequest struct {
 	Type   string                 `json:"type"`
@@ -309,6 +427,45 @@ type ProviderConfig struct {
 	ServiceAccountPath string `json:"service_account_path,omitempty"`
 }
 
+// DependencyResolver is implemented by providers that can report which
+// resources depend on a given resource
+type DependencyResolver interface {
+	GetDependents(ctx context.Context, resourceID string) ([]*Resource, error)
+}
+
+// DependencyError is returned when a resource cannot be deleted because
+// other active resources depend on it
+type DependencyError struct {
+	ResourceID string      `json:"resource_id"`
+	Dependents []*Resource `json:"dependents"`
+}
+
+// Error implements the error interface
+func (e *DependencyError) Error() string {
+	var names []string
+	for _, dep := range e.Dependents {
+		if dep.Name != "" && dep.Name != dep.ID {
+			names = append(names, fmt.Sprintf("%s (%s, %s)", dep.ID, dep.Name, dep.Type))
+		} else {
+			names = append(names, fmt.Sprintf("%s (%s)", dep.ID, dep.Type))
+		}
+	}
+	return fmt.Sprintf("cannot delete %s: %d active dependent(s): %s",
+		e.ResourceID, len(e.Dependents), strings.Join(names, ", "))
+}
+
+// ConfigError describes a missing or invalid provider configuration value
+type ConfigError struct {
+	Provider string `json:"provider"`
+	Field    string `json:"field"`
+	Message  string `json:"message"`
+}
+
+// Error implements the error interface
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s provider configuration error: %s: %s", e.Provider, e.Field, e.Message)
+}
+
 // ProviderStatus represents cloud provider status
 type ProviderStatus struct {
 	Name      string    `json:"name"`
@@ -319,11 +476,27 @@ type ProviderStatus struct {
 	Health    string    `json:"health"`
 }
 
+// Identity is the account and principal a provider acts as
+type Identity struct {
+	Provider string `json:"provider"`
+	// Account is the AWS account, Azure subscription or GCP project
+	Account string `json:"account"`
+	// Principal is the AWS ARN, Azure user or application, or GCP account email
+	Principal string `json:"principal"`
+	Tenant    string `json:"tenant,omitempty"`
+	Region    string `json:"region,omitempty"`
+}
+
 // DefaultCloudService provides a default implementation
 type DefaultCloudService struct {
 	config    *config.Config
 	providers map[string]CloudProvider
 	mu        sync.RWMutex
+
+	// cache holds recent provider listings for cacheTTL
+	cache    map[string]cacheEntry
+	cacheTTL time.Duration
+	clock    func() time.Time
 }
 
 // NewCloudService creates a new cloud service
@@ -331,6 +504,8 @@ func NewCloudService(cfg *config.Config) CloudService {
 	service := &DefaultCloudService{
 		config:    cfg,
 		providers: make(map[string]CloudProvider),
+		cache:     make(map[string]cacheEntry),
+		cacheTTL:  cacheTTLFromConfig(cfg),
 	}
 
 	// Initialize providers based on configuration
@@ -344,49 +519,97 @@ func (c *DefaultCloudService) initializeProviders() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Initialize AWS provider if configured
-	if awsConfig := c.getProviderConfig("aws"); awsConfig != nil {
-		if provider, err := NewAWSProvider(awsConfig); err == nil {
-			c.providers["aws"] = provider
+	for _, name := range []string{"aws", "azure", "gcp"} {
+		if provider, err := c.newProvider(name); err == nil {
+			c.providers[name] = provider
 		}
 	}
+}
 
-	// Initialize Azure provider if configured
-	if azureConfig := c.getProviderConfig("azure"); azureConfig != nil {
-		if provider, err := NewAzureProvider(azureConfig); err == nil {
-			c.providers["azure"] = provider
-		}
+// newProvider creates the named provider from the service configuration
+func (c *DefaultCloudService) newProvider(name string) (CloudProvider, error) {
+	providerConfig := c.getProviderConfig(name)
+	if providerConfig == nil {
+		return nil, fmt.Errorf("provider %s not found or not configured", name)
 	}
 
-	// Initialize GCP provider if configured
-	if gcpConfig := c.getProviderConfig("gcp"); gcpConfig != nil {
-		if provider, err := NewGCPProvider(gcpConfig); err == nil {
-			c.providers["gcp"] = provider
-		}
+	switch name {
+	case "aws":
+		return NewAWSProvider(providerConfig)
+	case "azure":
+		return NewAzureProvider(providerConfig)
+	case "gcp":
+		return NewGCPProvider(providerConfig)
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", name)
 	}
 }
 
+// NewProvider creates the named provider from the loaded configuration.
+// Unlike CloudService it never falls back to mock data, so callers that
+// must work on real resources get an error when the provider is not configured
+func NewProvider(cfg *config.Config, name string) (CloudProvider, error) {
+	service := &DefaultCloudService{config: cfg}
+	return service.newProvider(name)
+}
+
+// ProviderConfigFor returns the configuration of the named provider in cfg,
+// or nil when it is not configured
+func ProviderConfigFor(cfg *config.Config, name string) *ProviderConfig {
+	service := &DefaultCloudService{config: cfg}
+	return service.getProviderConfig(name)
+}
+
 // getProviderConfig extracts provider configuration from main config
 func (c *DefaultCloudService) getProviderConfig(provider string) *ProviderConfig {
-	// This is a simplified implementation
-	// In a real implementation, you would extract from c.config
-	cfg := &ProviderConfig{
-		Region:      "us-west-2", // Default region
-		Credentials: make(map[string]string),
+	if c.config == nil {
+		return nil
 	}
 
+	providers := c.config.CloudProviders
+
 	switch provider {
 	case "aws":
-		cfg.Profile = "default"
+		aws := providers.AWS
+		if aws.Region == "" && aws.Profile == "" && aws.AccessKeyID == "" {
+			return nil
+		}
+		cfg := &ProviderConfig{
+			Region:      aws.Region,
+			Profile:     aws.Profile,
+			Credentials: make(map[string]string),
+		}
+		if aws.AccessKeyID != "" {
+			cfg.Credentials["access_key_id"] = aws.AccessKeyID
+			cfg.Credentials["secret_access_key"] = aws.SecretKey
+		}
 		return cfg
 	case "azure":
-		cfg.SubscriptionID = "" // Should be loaded from config
-		cfg.TenantID = ""       // Should be loaded from config
+		azure := providers.Azure
+		if azure.SubscriptionID == "" {
+			return nil
+		}
+		cfg := &ProviderConfig{
+			SubscriptionID: azure.SubscriptionID,
+			TenantID:       azure.TenantID,
+			Credentials:    make(map[string]string),
+		}
+		if azure.ClientID != "" {
+			cfg.Credentials["client_id"] = azure.ClientID
+			cfg.Credentials["client_secret"] = azure.ClientSecret
+		}
 		return cfg
 	case "gcp":
-		cfg.ProjectID = ""          // Should be loaded from config
-		cfg.ServiceAccountPath = "" // Should be loaded from config
-		return cfg
+		gcp := providers.GCP
+		if gcp.ProjectID == "" && gcp.ServiceAccountPath == "" {
+			return nil
+		}
+		return &ProviderConfig{
+			Region:             gcp.Region,
+			ProjectID:          gcp.ProjectID,
+			ServiceAccountPath: gcp.ServiceAccountPath,
+			Credentials:        make(map[string]string),
+		}
 	}
 
 	return nil
@@ -406,39 +629,213 @@ func (c *DefaultCloudService) getProvider(name string) (CloudProvider, error) {
 }
 
 // ListResources lists resources from the specified provider
-func (c *DefaultCloudService) ListResources(ctx context.Context, provider string, resourceType string) ([]Resource, error) {
+func (c *DefaultCloudService) ListResources(ctx context.Context, provider string, resourceType string, opts ...ListOption) ([]Resource, error) {
 	// Try to use real provider first
 	if cloudProvider, err := c.getProvider(provider); err == nil {
-		resources, err := cloudProvider.ListResources(ctx, resourceType)
+		result, err := c.listFromProvider(ctx, provider, cloudProvider, resourceType, opts...)
 		if err == nil {
-			// Convert []*Resource to []Resource
-			var result []Resource
-			for _, res := range resources {
-				if res != nil {
-					result = append(result, *res)
-				}
-			}
 			return result, nil
 		}
 		// If real provider fails, log warning and fall back to mock
-		fmt.Printf("Warning: Real provider %s failed: %v. Using mock data.\n", provider, err)
+		fmt.Fprintf(os.Stderr, "Warning: Real provider %s failed: %v. Using mock data.\n", provider, err)
 	}
 
 	// Fallback to mock implementation
+	var resources []Resource
+	var err error
 	switch provider {
 	case "aws":
-		return c.listAWSResources(ctx, resourceType)
+		resources, err = c.listAWSResources(ctx, resourceType)
 	case "azure":
-		return c.listAzureResources(ctx, resourceType)
+		resources, err = c.listAzureResources(ctx, resourceType)
 	case "gcp":
-		return c.listGCPResources(ctx, resourceType)
+		resources, err = c.listGCPResources(ctx, resourceType)
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", provider)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	options := NewListOptions(opts...)
+	var result []Resource
+	for _, res := range resources {
+		if options.Reached(len(result)) {
+			break
+		}
+		if options.MatchesTags(res.Tags) {
+			result = append(result, res)
+		}
+	}
+	return result, nil
+}
+
+// GetIdentities returns the identity of every configured provider, in
+// provider order. A provider that fails does not fail the call: the other
+// identities are returned together with an error naming each that failed
+func (c *DefaultCloudService) GetIdentities(ctx context.Context) ([]Identity, error) {
+	c.mu.RLock()
+	names := make([]string, 0, len(c.providers))
+	providers := make(map[string]CloudProvider, len(c.providers))
+	for name, provider := range c.providers {
+		names = append(names, name)
+		providers[name] = provider
+	}
+	c.mu.RUnlock()
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no cloud providers are configured")
+	}
+	sort.Strings(names)
+
+	identities := []Identity{}
+	var errs []error
+	for _, name := range names {
+		identity, err := providers[name].GetIdentity(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		identity.Provider = name
+		identities = append(identities, *identity)
+	}
+
+	err := errors.Join(errs...)
+	if err != nil && len(identities) == 0 {
+		return nil, fmt.Errorf("failed to get the identity of every provider: %w", err)
+	}
+	return identities, err
+}
+
+// maxConcurrentProviders bounds how many providers ListAllResources queries at once
+const maxConcurrentProviders = 4
+
+// ListAllResources lists resources from every configured provider
+// concurrently, tagging each with its provider. A provider that fails does not
+// fail the call: the resources from the others are returned together with an
+// error naming each provider that failed
+func (c *DefaultCloudService) ListAllResources(ctx context.Context, resourceType string, opts ...ListOption) ([]Resource, error) {
+	c.mu.RLock()
+	names := make([]string, 0, len(c.providers))
+	providers := make(map[string]CloudProvider, len(c.providers))
+	for name, provider := range c.providers {
+		names = append(names, name)
+		providers[name] = provider
+	}
+	c.mu.RUnlock()
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no cloud providers are configured")
+	}
+	sort.Strings(names)
+
+	results := make([][]Resource, len(names))
+	errs := make([]error, len(names))
+	sem := make(chan struct{}, maxConcurrentProviders)
+	var wg sync.WaitGroup
+
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = fmt.Errorf("%s: %w", name, ctx.Err())
+				return
+			}
+
+			resources, err := c.listFromProvider(ctx, name, providers[name], resourceType, opts...)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", name, err)
+				return
+			}
+			results[i] = resources
+		}(i, name)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var merged []Resource
+	for i, resources := range results {
+		for _, resource := range resources {
+			resource.Provider = names[i]
+			merged = append(merged, resource)
+		}
+	}
+
+	err := errors.Join(errs...)
+	switch {
+	case err == nil:
+		return merged, nil
+	case len(merged) == 0 && !slices.ContainsFunc(errs, func(err error) bool { return err == nil }):
+		return nil, fmt.Errorf("failed to list resources from every provider: %w", err)
+	default:
+		return merged, fmt.Errorf("failed to list resources from some providers: %w", err)
+	}
+}
+
+// listFromProvider lists resources from a real provider, reusing a cached
+// listing unless it expired or the caller asked for fresh data
+func (c *DefaultCloudService) listFromProvider(ctx context.Context, name string, provider CloudProvider, resourceType string, opts ...ListOption) ([]Resource, error) {
+	options := NewListOptions(opts...)
+	key := cacheKey(name, resourceType, options)
+	if !options.NoCache {
+		if resources, ok := c.cachedResources(key); ok {
+			return resources, nil
+		}
+	}
+
+	resources, err := provider.ListResources(ctx, resourceType, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert []*Resource to []Resource
+	var result []Resource
+	for _, res := range resources {
+		if res != nil {
+			result = append(result, *res)
+		}
+	}
+	c.cacheResources(key, name, result)
+	return result, nil
+}
+
+// ListResourcesFiltered lists resources from the specified provider carrying
+// all given tags. Providers query the tags server-side as far as their API
+// allows, and match the rest in memory
+func (c *DefaultCloudService) ListResourcesFiltered(ctx context.Context, provider string, resourceType string, filters map[string]string) ([]Resource, error) {
+	return c.ListResources(ctx, provider, resourceType, WithTags(filters))
 }
 
-// CreateResource creates a new resource
+// CreateResource creates a new resource. In a dry run it returns the planned
+// resource without creating it
 func (c *DefaultCloudService) CreateResource(ctx context.Context, provider string, spec ResourceSpec) (*Resource, error) {
+	if plan := DryRunFrom(ctx); plan != nil {
+		resource := &Resource{
+			Name:     spec.Name,
+			Type:     spec.Type,
+			Provider: provider,
+			Region:   spec.Region,
+			Status:   "planned",
+			Config:   spec.Configuration,
+			Tags:     spec.Tags,
+		}
+		plan.Plan(PlannedAction{
+			Action:      "create",
+			Provider:    provider,
+			Description: fmt.Sprintf("create %s %s in %s", spec.Type, spec.Name, spec.Region),
+			Resource:    resource,
+		})
+		return resource, nil
+	}
+
 	// Mock implementation
 	resource := &Resource{
 		ID:       fmt.Sprintf("%s-%d", spec.Name, time.Now().Unix()),
@@ -455,11 +852,34 @@ func (c *DefaultCloudService) CreateResource(ctx context.Context, provider strin
 		},
 	}
 
+	c.InvalidateCache(provider)
 	return resource, nil
 }
 
-// UpdateResource updates an existing resource
+// UpdateResource updates an existing resource. In a dry run it returns the
+// planned resource without updating it
 func (c *DefaultCloudService) UpdateResource(ctx context.Context, provider string, resourceID string, spec ResourceSpec) (*Resource, error) {
+	if plan := DryRunFrom(ctx); plan != nil {
+		resource := &Resource{
+			ID:       resourceID,
+			Name:     spec.Name,
+			Type:     spec.Type,
+			Provider: provider,
+			Region:   spec.Region,
+			Status:   "planned",
+			Config:   spec.Configuration,
+			Tags:     spec.Tags,
+		}
+		plan.Plan(PlannedAction{
+			Action:      "update",
+			Provider:    provider,
+			ResourceID:  resourceID,
+			Description: fmt.Sprintf("update %s", resourceID),
+			Resource:    resource,
+		})
+		return resource, nil
+	}
+
 	// Mock implementation
 	resource := &Resource{
 		ID:       resourceID,
@@ -476,15 +896,63 @@ func (c *DefaultCloudService) UpdateResource(ctx context.Context, provider strin
 		},
 	}
 
+	c.InvalidateCache(provider)
 	return resource, nil
 }
 
-// DeleteResource deletes a resource
+// DeleteResource deletes a resource. Providers implementing DependencyResolver
+// refuse with a *DependencyError when the resource still has active dependents.
+// A dry run checks the dependents but plans the deletion without making it
 func (c *DefaultCloudService) DeleteResource(ctx context.Context, provider string, resourceID string) error {
-	// Mock implementation - would call cloud provider API
+	cloudProvider, err := c.getProvider(provider)
+	if err != nil {
+		return err
+	}
+
+	if plan := DryRunFrom(ctx); plan != nil {
+		dependents, err := c.GetDependents(ctx, provider, resourceID)
+		if err != nil {
+			return err
+		}
+		if len(dependents) > 0 {
+			return &DependencyError{ResourceID: resourceID, Dependents: dependents}
+		}
+		plan.Plan(PlannedAction{
+			Action:      "delete",
+			Provider:    provider,
+			ResourceID:  resourceID,
+			Description: fmt.Sprintf("delete %s", resourceID),
+		})
+		return nil
+	}
+
+	if err := cloudProvider.DeleteResource(ctx, resourceID); err != nil {
+		return err
+	}
+	c.InvalidateCache(provider)
 	return nil
 }
 
+// GetDependents lists the resources that depend on the given resource
+func (c *DefaultCloudService) GetDependents(ctx context.Context, provider string, resourceID string) ([]*Resource, error) {
+	cloudProvider, err := c.getProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver, ok := cloudProvider.(DependencyResolver)
+	if !ok {
+		return nil, nil
+	}
+
+	dependents, err := resolver.GetDependents(ctx, resourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check dependents of %s: %w", resourceID, err)
+	}
+
+	return dependents, nil
+}
+
 // GetResourceDetails gets detailed information about a resource
 func (c *DefaultCloudService) GetResourceDetails(ctx context.Context, provider string, resourceID string) (*ResourceDetails, error) {
 	// Mock implementation
@@ -558,10 +1026,120 @@ func (c *DefaultCloudService) GetResourceDetails(ctx context.Context, provider s
 	return details, nil
 }
 
-// GetCostAnalysis provides cost analysis
+// GetCostAnalysis analyzes the provider's billing data, broken down by each
+// GroupBy dimension. Without a configured provider it returns sample data
 func (c *DefaultCloudService) GetCostAnalysis(ctx context.Context, provider string, options CostOptions) (*CostAnalysis, error) {
-	// Mock implementation
+	if options.Budget < 0 {
+		return nil, fmt.Errorf("budget must not be negative, got %.2f", options.Budget)
+	}
+
+	var analysis *CostAnalysis
+	if cloudProvider, err := c.getProvider(provider); err == nil {
+		analysis, err = analyzeProviderCosts(ctx, cloudProvider, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get costs from %s: %w", provider, err)
+		}
+	} else {
+		analysis = mockCostAnalysis()
+	}
+
+	if options.Budget > 0 {
+		applyBudget(analysis, options.Budget)
+	}
+
+	return analysis, nil
+}
+
+// analyzeProviderCosts builds a cost analysis from provider billing data.
+// Trends compare the requested period with the period of the same length
+// before it
+func analyzeProviderCosts(ctx context.Context, provider CloudProvider, options CostOptions) (*CostAnalysis, error) {
+	end := options.EndDate
+	if end.IsZero() {
+		end = time.Now()
+	}
+	start := options.StartDate
+	if start.IsZero() {
+		start = end.AddDate(0, 0, -30)
+	}
+	if !start.Before(end) {
+		return nil, fmt.Errorf("invalid cost period: start %s must be before end %s", start.Format(time.DateOnly), end.Format(time.DateOnly))
+	}
+
+	current, err := provider.GetCost(ctx, &CostRequest{StartTime: start, EndTime: end})
+	if err != nil {
+		return nil, err
+	}
+
 	analysis := &CostAnalysis{
+		TotalCost:       current.Total,
+		Currency:        current.Currency,
+		Period:          fmt.Sprintf("%s to %s", start.Format(time.DateOnly), end.Format(time.DateOnly)),
+		Breakdown:       []CostBreakdown{},
+		Recommendations: []CostRecommendation{},
+	}
+
+	for _, dimension := range options.GroupBy {
+		grouped, err := provider.GetCost(ctx, &CostRequest{StartTime: start, EndTime: end, GroupBy: dimension})
+		if err != nil {
+			return nil, fmt.Errorf("failed to group costs by %s: %w", dimension, err)
+		}
+
+		prefix := ""
+		if len(options.GroupBy) > 1 {
+			prefix = dimension + ": "
+		}
+		analysis.Breakdown = append(analysis.Breakdown, costBreakdown(grouped, prefix)...)
+	}
+
+	previous, err := provider.GetCost(ctx, &CostRequest{StartTime: start.Add(-end.Sub(start)), EndTime: start})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get costs for the previous period: %w", err)
+	}
+	analysis.Trends = []CostTrend{
+		{Date: start, Cost: previous.Total},
+		{Date: end, Cost: current.Total, Change: percentChange(previous.Total, current.Total)},
+	}
+
+	return analysis, nil
+}
+
+// costBreakdown converts grouped provider costs to breakdown entries,
+// largest first, with each group's share of the grouped total
+func costBreakdown(costs *CostResponse, prefix string) []CostBreakdown {
+	total := 0.0
+	for _, cost := range costs.BreakdownBy {
+		total += cost
+	}
+
+	breakdown := make([]CostBreakdown, 0, len(costs.BreakdownBy))
+	for group, cost := range costs.BreakdownBy {
+		entry := CostBreakdown{Category: prefix + group, Cost: cost}
+		if total > 0 {
+			entry.Percentage = cost / total * 100
+		}
+		breakdown = append(breakdown, entry)
+	}
+	sort.Slice(breakdown, func(i, j int) bool {
+		if breakdown[i].Cost != breakdown[j].Cost {
+			return breakdown[i].Cost > breakdown[j].Cost
+		}
+		return breakdown[i].Category < breakdown[j].Category
+	})
+	return breakdown
+}
+
+// percentChange returns the change from previous to current in percent
+func percentChange(previous, current float64) float64 {
+	if previous == 0 {
+		return 0
+	}
+	return (current - previous) / previous * 100
+}
+
+// mockCostAnalysis returns sample cost data for when no provider is configured
+func mockCostAnalysis() *CostAnalysis {
+	return &CostAnalysis{
 		TotalCost: 1250.75,
 		Currency:  "USD",
 		Period:    "monthly",
@@ -610,14 +1188,109 @@ func (c *DefaultCloudService) GetCostAnalysis(ctx context.Context, provider stri
 			},
 		},
 	}
+}
 
-	return analysis, nil
+// applyBudget sets the analysis budget status and, when spend is over the
+// budget, puts a high-priority recommendation first
+func applyBudget(analysis *CostAnalysis, budget float64) {
+	status := &BudgetStatus{
+		Budget:         budget,
+		Spent:          analysis.TotalCost,
+		Remaining:      budget - analysis.TotalCost,
+		PercentUsed:    analysis.TotalCost / budget * 100,
+		OverBudget:     analysis.TotalCost > budget,
+		ProjectedSpend: projectSpend(analysis),
+	}
+	status.ProjectedOverBudget = status.ProjectedSpend > budget
+	analysis.BudgetStatus = status
+
+	if !status.OverBudget {
+		return
+	}
+
+	overrun := analysis.TotalCost - budget
+	recommendation := CostRecommendation{
+		ID:    "cost-budget-overrun",
+		Type:  "budget",
+		Title: "Spending is over budget",
+		Description: fmt.Sprintf("Spend of %.2f %s is %.2f over the %.2f budget (%.0f%% used)",
+			analysis.TotalCost, analysis.Currency, overrun, budget, status.PercentUsed),
+		Savings:  overrun,
+		Effort:   "medium",
+		Risk:     "medium",
+		Priority: "high",
+		Actions: []string{
+			"Review the largest cost categories in the breakdown",
+			"Apply the cost recommendations that follow",
+			"Stop or schedule idle non-production resources",
+		},
+	}
+	if len(analysis.Breakdown) > 0 {
+		largest := analysis.Breakdown[0]
+		for _, category := range analysis.Breakdown[1:] {
+			if category.Cost > largest.Cost {
+				largest = category
+			}
+		}
+		recommendation.Actions[0] = fmt.Sprintf("Review %s, the largest cost category at %.2f %s", largest.Category, largest.Cost, analysis.Currency)
+	}
+
+	analysis.Recommendations = append([]CostRecommendation{recommendation}, analysis.Recommendations...)
+}
+
+// projectSpend estimates end-of-period spend by applying the most recent
+// period-over-period change to the current spend
+func projectSpend(analysis *CostAnalysis) float64 {
+	if len(analysis.Trends) == 0 {
+		return analysis.TotalCost
+	}
+
+	latest := analysis.Trends[0]
+	for _, trend := range analysis.Trends[1:] {
+		if trend.Date.After(latest.Date) {
+			latest = trend
+		}
+	}
+	return analysis.TotalCost * (1 + latest.Change/100)
 }
 
-// OptimizeResources optimizes cloud resources
+// OptimizeResources optimizes cloud resources. A dry run never applies the
+// recommendations and plans one action for each
 func (c *DefaultCloudService) OptimizeResources(ctx context.Context, provider string, options OptimizeOptions) (*OptimizationResult, error) {
-	// Mock implementation
-	result := &OptimizationResult{
+	if options.Threshold < 0 || options.Threshold > 100 {
+		return nil, fmt.Errorf("threshold must be a percentage between 0 and 100, got %.1f", options.Threshold)
+	}
+
+	plan := DryRunFrom(ctx)
+	if plan != nil {
+		options.DryRun = true
+	}
+
+	result := mockOptimization()
+	if cloudProvider, err := c.getProvider(provider); err == nil {
+		result, err = optimizeWithMetrics(ctx, cloudProvider, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to optimize %s resources: %w", provider, err)
+		}
+	}
+
+	if plan != nil {
+		result.Status = "dry-run"
+		for _, rec := range result.Recommendations {
+			plan.Plan(PlannedAction{
+				Action:      rec.Type,
+				Provider:    provider,
+				ResourceID:  rec.ResourceID,
+				Description: fmt.Sprintf("saves %.2f/month", rec.Savings),
+			})
+		}
+	}
+	return result, nil
+}
+
+// mockOptimization returns sample recommendations for providers that are not configured
+func mockOptimization() *OptimizationResult {
+	return &OptimizationResult{
 		ID:        "opt-001",
 		Timestamp: time.Now(),
 		Status:    "completed",
@@ -643,18 +1316,25 @@ func (c *DefaultCloudService) OptimizeResources(ctx context.Context, provider st
 		PotentialSavings: 300.00,
 		RiskAssessment:   "low",
 	}
-
-	return result, nil
 }
 
-// MonitorHealth monitors cloud resource health
-func (c *DefaultCloudService) MonitorHealth(ctx context.Context, provider string) (<-chan HealthEvent, error) {
+// MonitorHealth monitors cloud resource health, delivering only status
+// transitions at or above options.MinSeverity
+func (c *DefaultCloudService) MonitorHealth(ctx context.Context, provider string, options HealthOptions) (<-chan HealthEvent, error) {
+	if err := validateHealthOptions(options); err != nil {
+		return nil, err
+	}
+	interval := options.Interval
+	if interval <= 0 {
+		interval = defaultHealthInterval
+	}
+
 	events := make(chan HealthEvent, 100)
 
 	// Mock implementation - would integrate with cloud provider health APIs
 	go func() {
 		defer close(events)
-		ticker := time.NewTicker(10 * time.Second)
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 
 		for {
@@ -686,7 +1366,7 @@ func (c *DefaultCloudService) MonitorHealth(ctx context.Context, provider string
 		}
 	}()
 
-	return events, nil
+	return FilterHealthEvents(ctx, events, options.MinSeverity), nil
 }
 
 // Helper methods for different cloud providers
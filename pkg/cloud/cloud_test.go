@@ -2,9 +2,35 @@ package cloud
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v5"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/costmanagement/armcostmanagement/v2"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/monitor/armmonitor"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/sirupsen/logrus"
 )
 
 func TestCloudManager(t *testing.T) {
@@ -207,6 +233,292 @@ func TestCloudProviderStatus(t *testing.T) {
 	}
 }
 
+func TestDeleteResourceBlockedByDependents(t *testing.T) {
+	ec2Client := &fakeEC2Client{
+		instances: []types.Instance{
+			{
+				InstanceId: aws.String("i-0abc123"),
+				State:      &types.InstanceState{Name: types.InstanceStateNameRunning},
+				SecurityGroups: []types.GroupIdentifier{
+					{GroupId: aws.String("sg-0web"), GroupName: aws.String("web")},
+				},
+				Tags: []types.Tag{{Key: aws.String("Name"), Value: aws.String("web-server")}},
+			},
+		},
+	}
+
+	service := &DefaultCloudService{
+		providers: map[string]CloudProvider{
+			"aws": newTestAWSProvider(ec2Client),
+		},
+	}
+
+	ctx := context.Background()
+
+	// Deleting a security group in use must be refused
+	err := service.DeleteResource(ctx, "aws", "sg-0web")
+	if err == nil {
+		t.Fatal("Expected deletion of an in-use security group to fail")
+	}
+
+	var depErr *DependencyError
+	if !errors.As(err, &depErr) {
+		t.Fatalf("Expected *DependencyError, got %T: %v", err, err)
+	}
+	if len(depErr.Dependents) != 1 || depErr.Dependents[0].ID != "i-0abc123" {
+		t.Errorf("Expected dependent instance i-0abc123, got %+v", depErr.Dependents)
+	}
+	if !strings.Contains(err.Error(), "i-0abc123 (web-server, ec2-instance)") {
+		t.Errorf("Expected error to list the dependent instance, got: %v", err)
+	}
+	if len(ec2Client.deleted) != 0 {
+		t.Errorf("Expected nothing to be deleted, got %v", ec2Client.deleted)
+	}
+
+	// The dependents are also available up front
+	dependents, err := service.GetDependents(ctx, "aws", "sg-0web")
+	if err != nil {
+		t.Fatalf("GetDependents() failed: %v", err)
+	}
+	if len(dependents) != 1 {
+		t.Errorf("Expected 1 dependent, got %d", len(dependents))
+	}
+
+	// An unused security group is deleted
+	if err := service.DeleteResource(ctx, "aws", "sg-0unused"); err != nil {
+		t.Fatalf("DeleteResource() failed: %v", err)
+	}
+	if len(ec2Client.deleted) != 1 || ec2Client.deleted[0] != "sg-0unused" {
+		t.Errorf("Expected sg-0unused to be deleted, got %v", ec2Client.deleted)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	ec2Client := &fakeEC2Client{
+		instances: []types.Instance{
+			{
+				InstanceId: aws.String("i-0abc123"),
+				State:      &types.InstanceState{Name: types.InstanceStateNameRunning},
+				SecurityGroups: []types.GroupIdentifier{
+					{GroupId: aws.String("sg-0web"), GroupName: aws.String("web")},
+				},
+			},
+		},
+	}
+	awsProvider := newTestAWSProvider(ec2Client)
+	service := &DefaultCloudService{
+		providers: map[string]CloudProvider{"aws": awsProvider},
+	}
+
+	ctx, plan := WithDryRun(context.Background())
+	if DryRunFrom(context.Background()) != nil {
+		t.Error("Expected no dry run outside WithDryRun")
+	}
+
+	if err := service.DeleteResource(ctx, "aws", "sg-0unused"); err != nil {
+		t.Fatalf("DeleteResource() failed: %v", err)
+	}
+	// Dependents are still checked, so the preview shows a refusal
+	var depErr *DependencyError
+	if err := service.DeleteResource(ctx, "aws", "sg-0web"); !errors.As(err, &depErr) {
+		t.Errorf("Expected *DependencyError, got %v", err)
+	}
+	// The provider respects the dry run when called directly
+	if err := awsProvider.DeleteResource(ctx, "i-0abc123"); err != nil {
+		t.Fatalf("AWSProvider.DeleteResource() failed: %v", err)
+	}
+
+	resource, err := service.CreateResource(ctx, "aws", ResourceSpec{Name: "web", Type: "ec2-instance", Region: "us-west-2"})
+	if err != nil {
+		t.Fatalf("CreateResource() failed: %v", err)
+	}
+	if resource.Status != "planned" || resource.ID != "" {
+		t.Errorf("Expected an unnamed planned resource, got %+v", resource)
+	}
+
+	result, err := service.OptimizeResources(ctx, "azure", OptimizeOptions{})
+	if err != nil {
+		t.Fatalf("OptimizeResources() failed: %v", err)
+	}
+	if result.Status != "dry-run" {
+		t.Errorf("Expected status dry-run, got %q", result.Status)
+	}
+
+	if len(ec2Client.deleted) != 0 {
+		t.Errorf("Expected nothing to be deleted, got %v", ec2Client.deleted)
+	}
+
+	var got []string
+	for _, action := range plan.Actions() {
+		got = append(got, action.Action+" "+action.ResourceID)
+	}
+	want := []string{"delete sg-0unused", "delete i-0abc123", "create "}
+	for _, rec := range result.Recommendations {
+		want = append(want, rec.Type+" "+rec.ResourceID)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Planned actions = %q, want %q", got, want)
+	}
+}
+
+func TestGetProviderConfig(t *testing.T) {
+	service := &DefaultCloudService{
+		config: &config.Config{
+			CloudProviders: config.CloudProviders{
+				AWS: config.AWSConfig{
+					Region:      "eu-west-1",
+					Profile:     "prod",
+					AccessKeyID: "AKIAEXAMPLE",
+					SecretKey:   "secret",
+				},
+				Azure: config.AzureConfig{
+					TenantID: "tenant-without-subscription",
+				},
+				GCP: config.GCPConfig{
+					ProjectID:          "my-project",
+					Region:             "europe-west1",
+					ServiceAccountPath: "/etc/gcp/sa.json",
+				},
+			},
+		},
+	}
+
+	awsConfig := service.getProviderConfig("aws")
+	if awsConfig == nil {
+		t.Fatal("Expected AWS provider config")
+	}
+	if awsConfig.Region != "eu-west-1" || awsConfig.Profile != "prod" {
+		t.Errorf("Unexpected AWS region/profile: %s/%s", awsConfig.Region, awsConfig.Profile)
+	}
+	if awsConfig.Credentials["access_key_id"] != "AKIAEXAMPLE" || awsConfig.Credentials["secret_access_key"] != "secret" {
+		t.Errorf("Unexpected AWS credentials: %v", awsConfig.Credentials)
+	}
+
+	// Azure is not usable without a subscription
+	if azureConfig := service.getProviderConfig("azure"); azureConfig != nil {
+		t.Errorf("Expected nil Azure config without subscription, got %+v", azureConfig)
+	}
+
+	gcpConfig := service.getProviderConfig("gcp")
+	if gcpConfig == nil {
+		t.Fatal("Expected GCP provider config")
+	}
+	if gcpConfig.ProjectID != "my-project" || gcpConfig.Region != "europe-west1" || gcpConfig.ServiceAccountPath != "/etc/gcp/sa.json" {
+		t.Errorf("Unexpected GCP config: %+v", gcpConfig)
+	}
+
+	service.config.CloudProviders.Azure = config.AzureConfig{
+		SubscriptionID: "sub-123",
+		TenantID:       "tenant-456",
+	}
+	azureConfig := service.getProviderConfig("azure")
+	if azureConfig == nil {
+		t.Fatal("Expected Azure provider config")
+	}
+	if azureConfig.SubscriptionID != "sub-123" || azureConfig.TenantID != "tenant-456" {
+		t.Errorf("Unexpected Azure subscription/tenant: %s/%s", azureConfig.SubscriptionID, azureConfig.TenantID)
+	}
+
+	if unknown := service.getProviderConfig("digitalocean"); unknown != nil {
+		t.Errorf("Expected nil config for unknown provider, got %+v", unknown)
+	}
+}
+
+func TestListEC2InstancesPagination(t *testing.T) {
+	ec2Client := &fakeEC2Client{pageSize: 3}
+	for i := 0; i < 5; i++ {
+		ec2Client.instances = append(ec2Client.instances, types.Instance{
+			InstanceId: aws.String(fmt.Sprintf("i-%03d", i)),
+			State:      &types.InstanceState{Name: types.InstanceStateNameRunning},
+			Placement:  &types.Placement{AvailabilityZone: aws.String("us-west-2a")},
+		})
+	}
+
+	provider := newTestAWSProvider(ec2Client)
+	ctx := context.Background()
+
+	resources, err := provider.ListResources(ctx, "ec2")
+	if err != nil {
+		t.Fatalf("ListResources() failed: %v", err)
+	}
+	if len(resources) != 5 {
+		t.Fatalf("Expected 5 instances across two pages, got %d", len(resources))
+	}
+	if ec2Client.calls != 2 {
+		t.Errorf("Expected 2 DescribeInstances calls, got %d", ec2Client.calls)
+	}
+	for i, resource := range resources {
+		if expected := fmt.Sprintf("i-%03d", i); resource.ID != expected {
+			t.Errorf("Expected instance %s at position %d, got %s", expected, i, resource.ID)
+		}
+	}
+
+	// A cap stops paging once enough instances are collected
+	ec2Client.calls = 0
+	resources, err = provider.ListResources(ctx, "ec2", WithMax(2))
+	if err != nil {
+		t.Fatalf("ListResources() failed: %v", err)
+	}
+	if len(resources) != 2 {
+		t.Errorf("Expected 2 instances with max cap, got %d", len(resources))
+	}
+	if ec2Client.calls != 1 {
+		t.Errorf("Expected 1 DescribeInstances call with max cap, got %d", ec2Client.calls)
+	}
+}
+
+func TestListResourcesFilteredByTag(t *testing.T) {
+	tagged := func(id, env string) types.Instance {
+		return types.Instance{
+			InstanceId: aws.String(id),
+			State:      &types.InstanceState{Name: types.InstanceStateNameRunning},
+			Tags:       []types.Tag{{Key: aws.String("Environment"), Value: aws.String(env)}},
+		}
+	}
+	ec2Client := &fakeEC2Client{
+		instances: []types.Instance{
+			tagged("i-prod1", "production"),
+			tagged("i-dev1", "development"),
+			tagged("i-prod2", "production"),
+		},
+	}
+
+	ctx := context.Background()
+	service := &DefaultCloudService{
+		providers: map[string]CloudProvider{
+			"aws": newTestAWSProvider(ec2Client),
+		},
+	}
+
+	// The AWS provider filters server-side with tag:Key filters
+	resources, err := service.ListResourcesFiltered(ctx, "aws", "ec2", map[string]string{"Environment": "production"})
+	if err != nil {
+		t.Fatalf("ListResourcesFiltered() failed: %v", err)
+	}
+	if len(resources) != 2 || resources[0].ID != "i-prod1" || resources[1].ID != "i-prod2" {
+		t.Errorf("Expected production instances only, got %+v", resources)
+	}
+
+	// The mock fallback applies the same filter in memory
+	fallback := &DefaultCloudService{providers: make(map[string]CloudProvider)}
+
+	resources, err = fallback.ListResourcesFiltered(ctx, "aws", "", map[string]string{"Team": "web"})
+	if err != nil {
+		t.Fatalf("ListResourcesFiltered() failed: %v", err)
+	}
+	if len(resources) != 2 {
+		t.Errorf("Expected 2 mock resources tagged Team=web, got %d", len(resources))
+	}
+
+	resources, err = fallback.ListResourcesFiltered(ctx, "aws", "", map[string]string{"Environment": "staging"})
+	if err != nil {
+		t.Fatalf("ListResourcesFiltered() failed: %v", err)
+	}
+	if len(resources) != 0 {
+		t.Errorf("Expected no mock resources tagged Environment=staging, got %d", len(resources))
+	}
+}
+
 func BenchmarkListResources(b *testing.B) {
 	provider := &MockCloudProvider{
 		name:   "aws",
@@ -257,7 +569,7 @@ func (m *MockCloudProvider) IsConnected() bool {
 	return m.status == "connected"
 }
 
-func (m *MockCloudProvider) ListResources(ctx context.Context, resourceType string) ([]*Resource, error) {
+func (m *MockCloudProvider) ListResources(ctx context.Context, resourceType string, opts ...ListOption) ([]*Resource, error) {
 	if m.resources == nil {
 		m.resources = make(map[string]*Resource)
 		// Add default resources
@@ -440,3 +752,1436 @@ func (m *MockCloudProvider) GetRegions(ctx context.Context) ([]string, error) {
 func (m *MockCloudProvider) GetResourceTypes(ctx context.Context) ([]string, error) {
 	return []string{"ec2", "ebs", "s3", "rds"}, nil
 }
+
+func (m *MockCloudProvider) GetIdentity(ctx context.Context) (*Identity, error) {
+	if m.status == "error" {
+		return nil, fmt.Errorf("credentials expired")
+	}
+	return &Identity{Account: "123456789012", Principal: "arn:aws:iam::123456789012:user/" + m.name, Region: m.region}, nil
+}
+
+// newTestAWSProvider returns a connected AWS provider backed by a fake EC2 API
+func newTestAWSProvider(client ec2API) *AWSProvider {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	return &AWSProvider{
+		ec2Client: client,
+		config:    &ProviderConfig{Region: "us-west-2"},
+		connected: true,
+		logger:    logger,
+	}
+}
+
+// fakeEC2Client is an in-memory implementation of the ec2API interface
+type fakeEC2Client struct {
+	instances []types.Instance
+	deleted   []string
+
+	// pageSize splits DescribeInstances results into pages when non-zero
+	pageSize int
+	calls    int
+}
+
+func (f *fakeEC2Client) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	f.calls++
+
+	var matched []types.Instance
+	for _, instance := range f.instances {
+		if instanceMatchesFilters(instance, params.Filters) {
+			matched = append(matched, instance)
+		}
+	}
+
+	if f.pageSize == 0 {
+		return &ec2.DescribeInstancesOutput{
+			Reservations: []types.Reservation{{Instances: matched}},
+		}, nil
+	}
+
+	start := 0
+	if params.NextToken != nil {
+		fmt.Sscanf(aws.ToString(params.NextToken), "%d", &start)
+	}
+	end := start + f.pageSize
+	if end > len(matched) {
+		end = len(matched)
+	}
+
+	output := &ec2.DescribeInstancesOutput{
+		Reservations: []types.Reservation{{Instances: matched[start:end]}},
+	}
+	if end < len(matched) {
+		output.NextToken = aws.String(fmt.Sprintf("%d", end))
+	}
+	return output, nil
+}
+
+func (f *fakeEC2Client) DescribeVolumes(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+	return &ec2.DescribeVolumesOutput{}, nil
+}
+
+func (f *fakeEC2Client) DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	return &ec2.DescribeSecurityGroupsOutput{}, nil
+}
+
+func (f *fakeEC2Client) DescribeVpcs(ctx context.Context, params *ec2.DescribeVpcsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVpcsOutput, error) {
+	return &ec2.DescribeVpcsOutput{}, nil
+}
+
+func (f *fakeEC2Client) DescribeRegions(ctx context.Context, params *ec2.DescribeRegionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error) {
+	return &ec2.DescribeRegionsOutput{}, nil
+}
+
+func (f *fakeEC2Client) TerminateInstances(ctx context.Context, params *ec2.TerminateInstancesInput, optFns ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error) {
+	f.deleted = append(f.deleted, params.InstanceIds...)
+	return &ec2.TerminateInstancesOutput{}, nil
+}
+
+func (f *fakeEC2Client) DeleteVolume(ctx context.Context, params *ec2.DeleteVolumeInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVolumeOutput, error) {
+	f.deleted = append(f.deleted, aws.ToString(params.VolumeId))
+	return &ec2.DeleteVolumeOutput{}, nil
+}
+
+func (f *fakeEC2Client) DeleteSecurityGroup(ctx context.Context, params *ec2.DeleteSecurityGroupInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSecurityGroupOutput, error) {
+	f.deleted = append(f.deleted, aws.ToString(params.GroupId))
+	return &ec2.DeleteSecurityGroupOutput{}, nil
+}
+
+func (f *fakeEC2Client) DeleteVpc(ctx context.Context, params *ec2.DeleteVpcInput, optFns ...func(*ec2.Options)) (*ec2.DeleteVpcOutput, error) {
+	f.deleted = append(f.deleted, aws.ToString(params.VpcId))
+	return &ec2.DeleteVpcOutput{}, nil
+}
+
+// instanceMatchesFilters applies the subset of EC2 filters used by the provider
+func instanceMatchesFilters(instance types.Instance, filters []types.Filter) bool {
+	for _, filter := range filters {
+		var values []string
+		switch aws.ToString(filter.Name) {
+		case "instance.group-id":
+			for _, group := range instance.SecurityGroups {
+				values = append(values, aws.ToString(group.GroupId))
+			}
+		case "vpc-id":
+			values = []string{aws.ToString(instance.VpcId)}
+		case "instance-state-name":
+			if instance.State != nil {
+				values = []string{string(instance.State.Name)}
+			}
+		default:
+			key, ok := strings.CutPrefix(aws.ToString(filter.Name), "tag:")
+			if !ok {
+				continue
+			}
+			for _, tag := range instance.Tags {
+				if aws.ToString(tag.Key) == key {
+					values = append(values, aws.ToString(tag.Value))
+				}
+			}
+		}
+
+		matched := false
+		for _, want := range filter.Values {
+			for _, have := range values {
+				if want == have {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAllowsPublicAdminIngress(t *testing.T) {
+	rule := func(protocol string, from, to int32, cidr string) types.IpPermission {
+		return types.IpPermission{
+			IpProtocol: aws.String(protocol),
+			FromPort:   aws.Int32(from),
+			ToPort:     aws.Int32(to),
+			IpRanges:   []types.IpRange{{CidrIp: aws.String(cidr)}},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		rules []types.IpPermission
+		want  bool
+	}{
+		{"public ssh", []types.IpPermission{rule("tcp", 22, 22, "0.0.0.0/0")}, true},
+		{"public port range with rdp", []types.IpPermission{rule("tcp", 3000, 4000, "0.0.0.0/0")}, true},
+		{"all traffic", []types.IpPermission{rule("-1", 0, 0, "0.0.0.0/0")}, true},
+		{"private ssh", []types.IpPermission{rule("tcp", 22, 22, "10.0.0.0/8")}, false},
+		{"public https", []types.IpPermission{rule("tcp", 443, 443, "0.0.0.0/0")}, false},
+	}
+
+	for _, tt := range tests {
+		if got := allowsPublicAdminIngress(tt.rules); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCostAnalysisBudget(t *testing.T) {
+	service := &DefaultCloudService{}
+	ctx := context.Background()
+
+	analysis, err := service.GetCostAnalysis(ctx, "aws", CostOptions{})
+	if err != nil {
+		t.Fatalf("GetCostAnalysis() failed: %v", err)
+	}
+	if analysis.BudgetStatus != nil {
+		t.Errorf("expected no budget status without a budget, got %+v", analysis.BudgetStatus)
+	}
+
+	analysis, err = service.GetCostAnalysis(ctx, "aws", CostOptions{Budget: 1000})
+	if err != nil {
+		t.Fatalf("GetCostAnalysis() failed: %v", err)
+	}
+	status := analysis.BudgetStatus
+	if status == nil || !status.OverBudget || status.Remaining >= 0 {
+		t.Fatalf("expected spend of %.2f to be over a 1000 budget, got %+v", analysis.TotalCost, status)
+	}
+	if status.PercentUsed < 125 || status.PercentUsed > 126 {
+		t.Errorf("expected about 125%% of the budget used, got %.1f", status.PercentUsed)
+	}
+	if status.ProjectedSpend <= analysis.TotalCost || !status.ProjectedOverBudget {
+		t.Errorf("expected a rising trend to project higher spend, got %+v", status)
+	}
+	if rec := analysis.Recommendations[0]; rec.Type != "budget" || rec.Priority != "high" {
+		t.Errorf("expected a high-priority budget recommendation first, got %+v", rec)
+	}
+
+	analysis, err = service.GetCostAnalysis(ctx, "aws", CostOptions{Budget: 2000})
+	if err != nil {
+		t.Fatalf("GetCostAnalysis() failed: %v", err)
+	}
+	if analysis.BudgetStatus.OverBudget || analysis.BudgetStatus.ProjectedOverBudget {
+		t.Errorf("expected spend to be within a 2000 budget, got %+v", analysis.BudgetStatus)
+	}
+	for _, rec := range analysis.Recommendations {
+		if rec.Type == "budget" {
+			t.Errorf("expected no budget recommendation when under budget, got %+v", rec)
+		}
+	}
+
+	if _, err := service.GetCostAnalysis(ctx, "aws", CostOptions{Budget: -1}); err == nil {
+		t.Error("expected a negative budget to be rejected")
+	}
+}
+
+// billingProvider returns costs from fixed data and records requests; periods
+// starting before periodStart are charged previous
+type billingProvider struct {
+	*MockCloudProvider
+	groups      map[string]map[string]float64
+	periodStart time.Time
+	previous    float64
+	requests    []*CostRequest
+}
+
+func (b *billingProvider) GetCost(ctx context.Context, req *CostRequest) (*CostResponse, error) {
+	b.requests = append(b.requests, req)
+	if req.GroupBy == "" {
+		if req.StartTime.Before(b.periodStart) {
+			return &CostResponse{Total: b.previous, Currency: "EUR"}, nil
+		}
+		total := 0.0
+		for _, cost := range b.groups["service"] {
+			total += cost
+		}
+		return &CostResponse{Total: total, Currency: "EUR"}, nil
+	}
+
+	groups, ok := b.groups[req.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported cost group-by %q", req.GroupBy)
+	}
+	return &CostResponse{Currency: "EUR", BreakdownBy: groups}, nil
+}
+
+func TestCostAnalysisFromProvider(t *testing.T) {
+	end := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	start := end.AddDate(0, 0, -30)
+	provider := &billingProvider{
+		MockCloudProvider: &MockCloudProvider{name: "aws"},
+		periodStart:       start,
+		groups: map[string]map[string]float64{
+			"service": {"Amazon EC2": 600, "Amazon S3": 300, "AWS Lambda": 100},
+			"region":  {"us-east-1": 750, "eu-west-1": 250},
+		},
+		previous: 800,
+	}
+	service := &DefaultCloudService{providers: map[string]CloudProvider{"aws": provider}}
+
+	analysis, err := service.GetCostAnalysis(context.Background(), "aws", CostOptions{
+		StartDate: start,
+		EndDate:   end,
+		GroupBy:   []string{"service", "region"},
+	})
+	if err != nil {
+		t.Fatalf("GetCostAnalysis() failed: %v", err)
+	}
+
+	if analysis.TotalCost != 1000 || analysis.Currency != "EUR" {
+		t.Errorf("expected a total of 1000 EUR, got %.2f %s", analysis.TotalCost, analysis.Currency)
+	}
+	if len(analysis.Breakdown) != 5 {
+		t.Fatalf("expected a breakdown entry per service and region, got %+v", analysis.Breakdown)
+	}
+	if first := analysis.Breakdown[0]; first.Category != "service: Amazon EC2" || first.Percentage != 60 {
+		t.Errorf("expected EC2 first with 60%% of service costs, got %+v", first)
+	}
+	if region := analysis.Breakdown[3]; region.Category != "region: us-east-1" || region.Percentage != 75 {
+		t.Errorf("expected us-east-1 with 75%% of region costs, got %+v", region)
+	}
+
+	if len(analysis.Trends) != 2 || analysis.Trends[0].Cost != 800 || analysis.Trends[1].Change != 25 {
+		t.Errorf("expected a 25%% rise from the previous period, got %+v", analysis.Trends)
+	}
+	last := provider.requests[len(provider.requests)-1]
+	if !last.StartTime.Equal(start.AddDate(0, 0, -30)) || !last.EndTime.Equal(start) {
+		t.Errorf("expected the previous period to end where the requested one starts, got %s to %s", last.StartTime, last.EndTime)
+	}
+
+	if _, err := service.GetCostAnalysis(context.Background(), "aws", CostOptions{GroupBy: []string{"usage_type"}}); err == nil || !strings.Contains(err.Error(), "usage_type") {
+		t.Errorf("expected provider errors to be returned, got %v", err)
+	}
+}
+
+// metricsProvider serves fixed instances, volumes and hourly utilization
+// samples; metrics missing from samples are reported as unavailable
+type metricsProvider struct {
+	*MockCloudProvider
+	instances []*Resource
+	volumes   []*Resource
+	samples   map[string][]float64
+	requests  []*MetricsRequest
+}
+
+func (p *metricsProvider) ListResources(ctx context.Context, resourceType string, opts ...ListOption) ([]*Resource, error) {
+	switch resourceType {
+	case "instances":
+		return p.instances, nil
+	case "volumes":
+		return p.volumes, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (p *metricsProvider) GetMetrics(ctx context.Context, req *MetricsRequest) (*MetricsResponse, error) {
+	p.requests = append(p.requests, req)
+	values, ok := p.samples[req.ResourceID+"/"+req.MetricName]
+	if !ok {
+		return nil, fmt.Errorf("metric %s not available", req.MetricName)
+	}
+
+	points := make([]*MetricDataPoint, len(values))
+	for i, value := range values {
+		points[i] = &MetricDataPoint{Timestamp: req.StartTime.Add(time.Duration(i) * time.Hour), Value: value, Unit: "Percent"}
+	}
+	return &MetricsResponse{MetricName: req.MetricName, DataPoints: points}, nil
+}
+
+// hourlySamples returns n samples of value
+func hourlySamples(n int, value float64) []float64 {
+	samples := make([]float64, n)
+	for i := range samples {
+		samples[i] = value
+	}
+	return samples
+}
+
+func TestOptimizeResourcesFromMetrics(t *testing.T) {
+	instance := func(id, size, state string) *Resource {
+		return &Resource{ID: id, Type: "instances", State: state, Config: map[string]interface{}{"instance_type": size}}
+	}
+	provider := &metricsProvider{
+		MockCloudProvider: &MockCloudProvider{name: "aws"},
+		instances: []*Resource{
+			instance("i-idle", "m5.xlarge", "running"),
+			instance("i-busy", "m5.xlarge", "running"),
+			instance("i-sparse", "t3.medium", "running"),
+			instance("i-smallest", "t3.nano", "running"),
+			instance("i-stopped", "m5.xlarge", "stopped"),
+		},
+		samples: map[string][]float64{
+			"i-idle/" + MetricCPUUtilization:        hourlySamples(24, 5),
+			"i-idle/" + MetricMemoryUtilization:     hourlySamples(24, 12),
+			"i-busy/" + MetricCPUUtilization:        append(hourlySamples(20, 5), hourlySamples(4, 90)...),
+			"i-sparse/" + MetricCPUUtilization:      hourlySamples(12, 3),
+			"i-smallest/" + MetricCPUUtilization:    hourlySamples(24, 1),
+			"i-stopped/" + MetricCPUUtilization:     hourlySamples(24, 0),
+			"i-smallest/" + MetricMemoryUtilization: hourlySamples(24, 1),
+		},
+	}
+	service := &DefaultCloudService{providers: map[string]CloudProvider{"aws": provider}}
+
+	result, err := service.OptimizeResources(context.Background(), "aws", OptimizeOptions{
+		DryRun:   true,
+		Lookback: 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("OptimizeResources() failed: %v", err)
+	}
+
+	if result.Status != "dry-run" {
+		t.Errorf("expected a dry run to only report, got status %q", result.Status)
+	}
+	if len(result.Recommendations) != 2 {
+		t.Fatalf("expected recommendations for the idle and sparse instances, got %+v", result.Recommendations)
+	}
+
+	idle := result.Recommendations[0]
+	if idle.ResourceID != "i-idle" || idle.Recommended["instance_type"] != "m5.large" || idle.Savings != 70.08 {
+		t.Errorf("expected i-idle to move to m5.large saving 70.08, got %+v", idle)
+	}
+	if idle.Confidence != 1 {
+		t.Errorf("expected full confidence with complete CPU and memory data, got %.2f", idle.Confidence)
+	}
+
+	sparse := result.Recommendations[1]
+	if sparse.ResourceID != "i-sparse" || sparse.Recommended["instance_type"] != "t3.small" {
+		t.Errorf("expected i-sparse to move to t3.small, got %+v", sparse)
+	}
+	if sparse.Confidence != 0.35 {
+		t.Errorf("expected half coverage without memory data to give 0.35 confidence, got %.2f", sparse.Confidence)
+	}
+	if result.PotentialSavings != 85.26 || result.RiskAssessment != "high" {
+		t.Errorf("unexpected totals: savings %.2f, risk %s", result.PotentialSavings, result.RiskAssessment)
+	}
+
+	for _, req := range provider.requests {
+		if req.EndTime.Sub(req.StartTime) != 24*time.Hour || req.Period != 3600 {
+			t.Errorf("expected hourly metrics over the lookback window, got %+v", req)
+		}
+		if req.ResourceID == "i-stopped" || req.ResourceID == "i-smallest" {
+			t.Errorf("expected stopped and smallest instances to be skipped, got a request for %s", req.ResourceID)
+		}
+	}
+
+	applied, err := service.OptimizeResources(context.Background(), "aws", OptimizeOptions{Lookback: 24 * time.Hour, Threshold: 2})
+	if err != nil {
+		t.Fatalf("OptimizeResources() failed: %v", err)
+	}
+	if applied.Status != "pending" || len(applied.Recommendations) != 0 {
+		t.Errorf("expected no recommendations below a 2%% threshold, got %+v", applied)
+	}
+
+	if _, err := service.OptimizeResources(context.Background(), "aws", OptimizeOptions{ResourceTypes: []string{"volumes"}}); err != nil {
+		t.Errorf("expected resource types without instances to give no recommendations, got %v", err)
+	}
+}
+
+func TestOptimizeResourcesFindsUnattachedVolumes(t *testing.T) {
+	volume := func(id, state, volumeType string, size int32) *Resource {
+		return &Resource{ID: id, Type: "ebs-volume", State: state, Config: map[string]interface{}{"volume_type": volumeType, "size": size}}
+	}
+	provider := &metricsProvider{
+		MockCloudProvider: &MockCloudProvider{name: "aws"},
+		volumes: []*Resource{
+			volume("vol-orphan", "available", "gp3", 500),
+			volume("vol-root", "in-use", "gp3", 100),
+			volume("vol-cold", "available", "sc1", 1000),
+		},
+	}
+	service := &DefaultCloudService{providers: map[string]CloudProvider{"aws": provider}}
+
+	result, err := service.OptimizeResources(context.Background(), "aws", OptimizeOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("OptimizeResources() failed: %v", err)
+	}
+	if len(result.Recommendations) != 2 {
+		t.Fatalf("expected cleanup recommendations for the two available volumes, got %+v", result.Recommendations)
+	}
+
+	orphan := result.Recommendations[0]
+	if orphan.ResourceID != "vol-orphan" || orphan.Type != "cleanup" || orphan.Savings != 40 {
+		t.Errorf("expected vol-orphan to be cleaned up saving 40, got %+v", orphan)
+	}
+	if len(orphan.Actions) == 0 || !strings.Contains(orphan.Actions[0], "no longer needed") {
+		t.Errorf("expected the first action to warn before deleting, got %v", orphan.Actions)
+	}
+	if cold := result.Recommendations[1]; cold.ResourceID != "vol-cold" || cold.Savings != 15 {
+		t.Errorf("expected vol-cold to be cleaned up saving 15, got %+v", cold)
+	}
+	if result.PotentialSavings != 55 {
+		t.Errorf("expected potential savings of 55, got %.2f", result.PotentialSavings)
+	}
+
+	instancesOnly, err := service.OptimizeResources(context.Background(), "aws", OptimizeOptions{ResourceTypes: []string{"instances"}})
+	if err != nil {
+		t.Fatalf("OptimizeResources() failed: %v", err)
+	}
+	if len(instancesOnly.Recommendations) != 0 {
+		t.Errorf("expected volumes to be skipped when only instances are requested, got %+v", instancesOnly.Recommendations)
+	}
+
+	// Instances without metrics must not hide the volume recommendations
+	provider.instances = []*Resource{{ID: "i-unmonitored", Type: "instances", State: "running", Config: map[string]interface{}{"instance_type": "m5.xlarge"}}}
+	result, err = service.OptimizeResources(context.Background(), "aws", OptimizeOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("expected missing metrics to be a warning alongside other recommendations, got %v", err)
+	}
+	if len(result.Recommendations) != 2 {
+		t.Errorf("expected the volume recommendations to be kept, got %+v", result.Recommendations)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "1 of 1 instances were not checked") {
+		t.Errorf("expected a warning about the unchecked instance, got %v", result.Warnings)
+	}
+
+	if _, err := service.OptimizeResources(context.Background(), "aws", OptimizeOptions{ResourceTypes: []string{"instances"}}); err == nil || !strings.Contains(err.Error(), "failed to read utilization metrics") {
+		t.Errorf("expected missing metrics to fail a run with nothing to report, got %v", err)
+	}
+}
+
+// listingProvider returns fixed resources or an error, tracking how many
+// listings run at once
+type listingProvider struct {
+	*MockCloudProvider
+	resources []*Resource
+	err       error
+	inFlight  *int32
+	peak      *int32
+}
+
+func (p *listingProvider) ListResources(ctx context.Context, resourceType string, opts ...ListOption) ([]*Resource, error) {
+	if p.inFlight != nil {
+		current := atomic.AddInt32(p.inFlight, 1)
+		defer atomic.AddInt32(p.inFlight, -1)
+		for {
+			peak := atomic.LoadInt32(p.peak)
+			if current <= peak || atomic.CompareAndSwapInt32(p.peak, peak, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.resources, nil
+}
+
+func TestListAllResources(t *testing.T) {
+	service := &DefaultCloudService{providers: map[string]CloudProvider{
+		"aws": &listingProvider{
+			MockCloudProvider: &MockCloudProvider{name: "aws"},
+			resources:         []*Resource{{ID: "i-1"}, {ID: "i-2"}},
+		},
+		"gcp": &listingProvider{
+			MockCloudProvider: &MockCloudProvider{name: "gcp"},
+			resources:         []*Resource{{ID: "vm-1", Provider: "google"}},
+		},
+		"azure": &listingProvider{
+			MockCloudProvider: &MockCloudProvider{name: "azure"},
+			err:               fmt.Errorf("connection refused"),
+		},
+	}}
+
+	resources, err := service.ListAllResources(context.Background(), "instances")
+	if err == nil || !strings.Contains(err.Error(), "azure: connection refused") {
+		t.Errorf("expected the azure failure to be reported, got %v", err)
+	}
+	if len(resources) != 3 {
+		t.Fatalf("expected the resources of the reachable providers, got %+v", resources)
+	}
+	for _, resource := range resources {
+		want := "aws"
+		if resource.ID == "vm-1" {
+			want = "gcp"
+		}
+		if resource.Provider != want {
+			t.Errorf("expected %s to be tagged with provider %s, got %q", resource.ID, want, resource.Provider)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := service.ListAllResources(ctx, "instances"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancelled context to stop the listing, got %v", err)
+	}
+
+	if _, err := (&DefaultCloudService{}).ListAllResources(context.Background(), "instances"); err == nil {
+		t.Error("expected an error without configured providers")
+	}
+}
+
+func TestListAllResourcesBoundsConcurrency(t *testing.T) {
+	var inFlight, peak int32
+	providers := make(map[string]CloudProvider)
+	for i := 0; i < maxConcurrentProviders*2; i++ {
+		name := fmt.Sprintf("provider-%d", i)
+		providers[name] = &listingProvider{
+			MockCloudProvider: &MockCloudProvider{name: name},
+			resources:         []*Resource{{ID: name + "-resource"}},
+			inFlight:          &inFlight,
+			peak:              &peak,
+		}
+	}
+	service := &DefaultCloudService{providers: providers}
+
+	resources, err := service.ListAllResources(context.Background(), "instances")
+	if err != nil {
+		t.Fatalf("ListAllResources() failed: %v", err)
+	}
+	if len(resources) != len(providers) {
+		t.Errorf("expected a resource per provider, got %d", len(resources))
+	}
+	if peak > maxConcurrentProviders {
+		t.Errorf("expected at most %d concurrent listings, got %d", maxConcurrentProviders, peak)
+	}
+}
+
+// countingProvider counts the listings that reached it
+type countingProvider struct {
+	*MockCloudProvider
+	calls int
+}
+
+func (p *countingProvider) ListResources(ctx context.Context, resourceType string, opts ...ListOption) ([]*Resource, error) {
+	p.calls++
+	return []*Resource{{ID: fmt.Sprintf("%s-%d", resourceType, p.calls)}}, nil
+}
+
+func (p *countingProvider) DeleteResource(ctx context.Context, resourceID string) error {
+	return nil
+}
+
+func TestListResourcesCache(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	provider := &countingProvider{MockCloudProvider: &MockCloudProvider{name: "aws"}}
+	service := &DefaultCloudService{
+		providers: map[string]CloudProvider{"aws": provider},
+		cacheTTL:  time.Minute,
+		clock:     func() time.Time { return now },
+	}
+	ctx := context.Background()
+
+	list := func(resourceType string, opts ...ListOption) []Resource {
+		t.Helper()
+		resources, err := service.ListResources(ctx, "aws", resourceType, opts...)
+		if err != nil {
+			t.Fatalf("ListResources() failed: %v", err)
+		}
+		return resources
+	}
+
+	first := list("instances")
+	if second := list("instances"); provider.calls != 1 || second[0].ID != first[0].ID {
+		t.Errorf("expected a second listing within the TTL to be served from the cache, got %d provider calls", provider.calls)
+	}
+
+	list("instances", WithTags(map[string]string{"env": "prod"}))
+	list("volumes")
+	if provider.calls != 3 {
+		t.Errorf("expected different filters and types to be cached separately, got %d provider calls", provider.calls)
+	}
+
+	list("instances", WithNoCache())
+	if provider.calls != 4 {
+		t.Errorf("expected WithNoCache to query the provider, got %d provider calls", provider.calls)
+	}
+	if refreshed := list("instances"); provider.calls != 4 || refreshed[0].ID != "instances-4" {
+		t.Errorf("expected the refreshed listing to be cached, got %+v after %d calls", refreshed, provider.calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	list("instances")
+	if provider.calls != 5 {
+		t.Errorf("expected an expired listing to query the provider, got %d provider calls", provider.calls)
+	}
+
+	if err := service.DeleteResource(ctx, "aws", "instances-5"); err != nil {
+		t.Fatalf("DeleteResource() failed: %v", err)
+	}
+	list("instances")
+	if provider.calls != 6 {
+		t.Errorf("expected deleting a resource to invalidate the cache, got %d provider calls", provider.calls)
+	}
+
+	service.cacheTTL = 0
+	service.InvalidateCache("")
+	list("instances")
+	list("instances")
+	if provider.calls != 8 {
+		t.Errorf("expected a zero TTL to turn caching off, got %d provider calls", provider.calls)
+	}
+}
+
+func TestExportResources(t *testing.T) {
+	resources := []Resource{
+		{
+			ID: "i-1", Name: "web, primary", Type: "ec2-instance", Provider: "aws", Region: "us-east-1", State: "running",
+			Tags: map[string]string{"team": "web", "env": "prod"},
+			Cost: &CostInfo{Monthly: 70.08},
+		},
+		{ID: "vm-1", Name: "batch", Type: "compute-instance", Provider: "gcp", Region: "us-central1", State: "stopped"},
+	}
+
+	var csvOut strings.Builder
+	if err := ExportResources(resources, "csv", &csvOut); err != nil {
+		t.Fatalf("ExportResources(csv) failed: %v", err)
+	}
+	want := "ID,Name,Type,Provider,Region,State,Tags,MonthlyCost\n" +
+		"i-1,\"web, primary\",ec2-instance,aws,us-east-1,running,env=prod;team=web,70.08\n" +
+		"vm-1,batch,compute-instance,gcp,us-central1,stopped,,0.00\n"
+	if csvOut.String() != want {
+		t.Errorf("unexpected csv export:\n%s\nwant:\n%s", csvOut.String(), want)
+	}
+
+	var jsonOut strings.Builder
+	if err := ExportResources(resources, "json", &jsonOut); err != nil {
+		t.Fatalf("ExportResources(json) failed: %v", err)
+	}
+	var entries []map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonOut.String()), &entries); err != nil {
+		t.Fatalf("export is not valid JSON: %v", err)
+	}
+	if len(entries) != 2 || entries[0]["monthly_cost"] != 70.08 || entries[1]["provider"] != "gcp" {
+		t.Errorf("unexpected json export: %v", entries)
+	}
+	if tags, ok := entries[1]["tags"].(map[string]interface{}); !ok || len(tags) != 0 {
+		t.Errorf("expected resources without tags to export an empty object, got %v", entries[1]["tags"])
+	}
+
+	if err := ExportResources(resources, "xml", io.Discard); err == nil {
+		t.Error("expected an unsupported format to be rejected")
+	}
+}
+
+func TestFilterHealthEvents(t *testing.T) {
+	event := func(resourceID, status, severity string) HealthEvent {
+		return HealthEvent{Provider: "aws", ResourceID: resourceID, Status: status, Severity: severity}
+	}
+	sequence := []HealthEvent{
+		event("i-1", "healthy", "info"),
+		event("i-1", "healthy", "info"),
+		event("i-2", "healthy", "info"),
+		event("i-1", "unhealthy", "error"),
+		event("i-1", "unhealthy", "error"),
+		event("i-2", "degraded", "warning"),
+		event("i-1", "healthy", "info"),
+		event("i-1", "healthy", "info"),
+		event("i-2", "healthy", "info"),
+	}
+
+	collect := func(minSeverity string) []string {
+		in := make(chan HealthEvent, len(sequence))
+		for _, e := range sequence {
+			in <- e
+		}
+		close(in)
+
+		var got []string
+		for e := range FilterHealthEvents(context.Background(), in, minSeverity) {
+			got = append(got, e.ResourceID+":"+e.Status)
+		}
+		return got
+	}
+
+	want := []string{"i-1:healthy", "i-2:healthy", "i-1:unhealthy", "i-2:degraded", "i-1:healthy", "i-2:healthy"}
+	if got := collect(""); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected only transitions, got %v, want %v", got, want)
+	}
+
+	// Recoveries are delivered for failures that were, even when below the floor
+	want = []string{"i-1:unhealthy", "i-1:healthy"}
+	if got := collect("error"); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected error transitions and their recovery, got %v, want %v", got, want)
+	}
+}
+
+func TestMonitorHealth(t *testing.T) {
+	service := &DefaultCloudService{}
+	if _, err := service.MonitorHealth(context.Background(), "aws", HealthOptions{MinSeverity: "loud"}); err == nil {
+		t.Error("expected an unknown severity to be rejected")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := service.MonitorHealth(ctx, "aws", HealthOptions{Interval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("MonitorHealth() failed: %v", err)
+	}
+	first := <-events
+	if first.Status != "healthy" {
+		t.Errorf("expected the initial status to be delivered, got %+v", first)
+	}
+	cancel()
+	for range events {
+	}
+}
+
+// fakeAzureMetrics is an in-memory implementation of the azureMetricsAPI
+// interface that records the query it was sent
+type fakeAzureMetrics struct {
+	resourceURI string
+	options     *armmonitor.MetricsClientListOptions
+	response    armmonitor.Response
+}
+
+func (f *fakeAzureMetrics) List(ctx context.Context, resourceURI string, options *armmonitor.MetricsClientListOptions) (armmonitor.MetricsClientListResponse, error) {
+	f.resourceURI = resourceURI
+	f.options = options
+	return armmonitor.MetricsClientListResponse{Response: f.response}, nil
+}
+
+func TestAzureGetMetrics(t *testing.T) {
+	const vmID = "/subscriptions/sub-123/resourceGroups/web/providers/Microsoft.Compute/virtualMachines/web-1"
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	at := func(minutes int) *time.Time {
+		return to.Ptr(start.Add(time.Duration(minutes) * time.Minute).In(time.FixedZone("CEST", 2*3600)))
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	newProvider := func(response armmonitor.Response) (*AzureProvider, *fakeAzureMetrics) {
+		client := &fakeAzureMetrics{response: response}
+		return &AzureProvider{metricsClient: client, config: &ProviderConfig{}, connected: true, logger: logger}, client
+	}
+
+	t.Run("cpu average", func(t *testing.T) {
+		provider, client := newProvider(armmonitor.Response{Value: []*armmonitor.Metric{{
+			Unit: to.Ptr(armmonitor.UnitPercent),
+			Timeseries: []*armmonitor.TimeSeriesElement{{Data: []*armmonitor.MetricValue{
+				{TimeStamp: at(0), Average: to.Ptr(12.5)},
+				{TimeStamp: at(5)},
+				{TimeStamp: at(10), Average: to.Ptr(40.0)},
+			}}},
+		}}})
+
+		metrics, err := provider.GetMetrics(context.Background(), &MetricsRequest{
+			ResourceID: vmID, MetricName: MetricCPUUtilization, StartTime: start, EndTime: start.Add(time.Hour), Period: 300,
+		})
+		if err != nil {
+			t.Fatalf("GetMetrics() failed: %v", err)
+		}
+		if client.resourceURI != strings.TrimPrefix(vmID, "/") {
+			t.Errorf("expected the VM to be queried, got %s", client.resourceURI)
+		}
+		opts := client.options
+		if *opts.Metricnames != "Percentage CPU" || *opts.Aggregation != "Average" || *opts.Interval != "PT5M" {
+			t.Errorf("unexpected query %s/%s/%s", *opts.Metricnames, *opts.Aggregation, *opts.Interval)
+		}
+		if *opts.Timespan != "2024-05-01T10:00:00Z/2024-05-01T11:00:00Z" {
+			t.Errorf("unexpected timespan %s", *opts.Timespan)
+		}
+
+		if len(metrics.DataPoints) != 2 {
+			t.Fatalf("expected intervals without data to be skipped, got %d points", len(metrics.DataPoints))
+		}
+		first := metrics.DataPoints[0]
+		if first.Value != 12.5 || first.Unit != "Percent" || first.Timestamp != start {
+			t.Errorf("unexpected first point %+v", first)
+		}
+	})
+
+	t.Run("network total", func(t *testing.T) {
+		provider, client := newProvider(armmonitor.Response{Value: []*armmonitor.Metric{{
+			Unit: to.Ptr(armmonitor.UnitBytes),
+			Timeseries: []*armmonitor.TimeSeriesElement{{Data: []*armmonitor.MetricValue{
+				{TimeStamp: at(0), Average: to.Ptr(10.0), Total: to.Ptr(600.0)},
+			}}},
+		}}})
+
+		metrics, err := provider.GetMetrics(context.Background(), &MetricsRequest{
+			ResourceID: vmID, MetricName: "Network In", StartTime: start, EndTime: start.Add(time.Hour), Period: 3600,
+		})
+		if err != nil {
+			t.Fatalf("GetMetrics() failed: %v", err)
+		}
+		if *client.options.Aggregation != "Total" || *client.options.Interval != "PT1H" {
+			t.Errorf("unexpected query %s/%s", *client.options.Aggregation, *client.options.Interval)
+		}
+		if len(metrics.DataPoints) != 1 || metrics.DataPoints[0].Value != 600 || metrics.DataPoints[0].Unit != "Bytes" {
+			t.Errorf("expected the interval total, got %+v", metrics.DataPoints)
+		}
+	})
+
+	t.Run("metric error", func(t *testing.T) {
+		provider, _ := newProvider(armmonitor.Response{Value: []*armmonitor.Metric{{
+			ErrorCode: to.Ptr("BadRequest"), ErrorMessage: to.Ptr("metric not found"),
+		}}})
+		_, err := provider.GetMetrics(context.Background(), &MetricsRequest{
+			ResourceID: vmID, MetricName: "Percentage CPU", StartTime: start, EndTime: start.Add(time.Hour),
+		})
+		if err == nil || !strings.Contains(err.Error(), "metric not found") {
+			t.Errorf("expected the metric error, got %v", err)
+		}
+	})
+
+	invalid := []struct {
+		name    string
+		req     *MetricsRequest
+		wantErr string
+	}{
+		{"resource group", &MetricsRequest{ResourceID: "/subscriptions/sub-123/resourceGroups/web", MetricName: MetricCPUUtilization}, "is not an Azure metrics target"},
+		{"subscription", &MetricsRequest{ResourceID: "/subscriptions/sub-123", MetricName: MetricCPUUtilization}, "is not an Azure metrics target"},
+		{"instance id", &MetricsRequest{ResourceID: "i-0123456789", MetricName: MetricCPUUtilization}, "is not an Azure metrics target"},
+		{"memory", &MetricsRequest{ResourceID: vmID, MetricName: MetricMemoryUtilization}, "unsupported Azure metric"},
+		{"time range", &MetricsRequest{ResourceID: vmID, MetricName: MetricCPUUtilization, StartTime: start, EndTime: start}, "end time must be after"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, client := newProvider(armmonitor.Response{})
+			_, err := provider.GetMetrics(context.Background(), tt.req)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if client.options != nil {
+				t.Error("expected no query to be sent")
+			}
+		})
+	}
+}
+
+// fakeAzureCost is an in-memory implementation of the azureCostAPI interface.
+// It fails with the queued errors before returning result
+type fakeAzureCost struct {
+	errs    []error
+	result  armcostmanagement.QueryResult
+	scope   string
+	queries []armcostmanagement.QueryDefinition
+}
+
+func (f *fakeAzureCost) Usage(ctx context.Context, scope string, parameters armcostmanagement.QueryDefinition, options *armcostmanagement.QueryClientUsageOptions) (armcostmanagement.QueryClientUsageResponse, error) {
+	f.scope = scope
+	f.queries = append(f.queries, parameters)
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return armcostmanagement.QueryClientUsageResponse{}, err
+	}
+	return armcostmanagement.QueryClientUsageResponse{QueryResult: f.result}, nil
+}
+
+// azureResponseError builds the error Azure clients return for a status,
+// with the given response headers
+func azureResponseError(status int, header http.Header) error {
+	return &azcore.ResponseError{StatusCode: status, RawResponse: &http.Response{StatusCode: status, Header: header}}
+}
+
+func TestAzureGetCost(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	grouped := armcostmanagement.QueryResult{Properties: &armcostmanagement.QueryProperties{
+		Columns: []*armcostmanagement.QueryColumn{{Name: to.Ptr("Cost")}, {Name: to.Ptr("ResourceGroup")}, {Name: to.Ptr("Currency")}},
+		Rows: [][]any{
+			{120.5, "web", "EUR"},
+			{30.0, "data", "EUR"},
+			{4.5, "", "EUR"},
+		},
+	}}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	newProvider := func(client *fakeAzureCost) *AzureProvider {
+		return &AzureProvider{costClient: client, subscriptionID: "sub-123", config: &ProviderConfig{}, connected: true, logger: logger, costBackoff: time.Millisecond}
+	}
+
+	t.Run("grouped", func(t *testing.T) {
+		client := &fakeAzureCost{result: grouped}
+		cost, err := newProvider(client).GetCost(context.Background(), &CostRequest{StartTime: start, EndTime: end, GroupBy: "resource_group"})
+		if err != nil {
+			t.Fatalf("GetCost() failed: %v", err)
+		}
+		if client.scope != "subscriptions/sub-123" {
+			t.Errorf("expected a subscription scope, got %s", client.scope)
+		}
+		query := client.queries[0]
+		if *query.Timeframe != armcostmanagement.TimeframeTypeCustom || !query.TimePeriod.From.Equal(start) || !query.TimePeriod.To.Equal(end) {
+			t.Errorf("expected a custom period over the request, got %+v", query.TimePeriod)
+		}
+		if grouping := query.Dataset.Grouping; len(grouping) != 1 || *grouping[0].Name != "ResourceGroup" {
+			t.Errorf("expected grouping by ResourceGroup, got %+v", grouping)
+		}
+
+		if cost.Total != 155 || cost.Currency != "EUR" {
+			t.Errorf("expected 155 EUR, got %v %s", cost.Total, cost.Currency)
+		}
+		want := map[string]float64{"web": 120.5, "data": 30, "(none)": 4.5}
+		if !reflect.DeepEqual(cost.BreakdownBy, want) {
+			t.Errorf("expected breakdown %v, got %v", want, cost.BreakdownBy)
+		}
+	})
+
+	t.Run("throttled", func(t *testing.T) {
+		client := &fakeAzureCost{
+			errs: []error{
+				azureResponseError(http.StatusTooManyRequests, http.Header{"X-Ms-Ratelimit-Microsoft.costmanagement-Qpu-Retry-After": {"0"}}),
+				azureResponseError(http.StatusTooManyRequests, nil),
+			},
+			result: armcostmanagement.QueryResult{Properties: &armcostmanagement.QueryProperties{
+				Columns: []*armcostmanagement.QueryColumn{{Name: to.Ptr("totalCost")}, {Name: to.Ptr("Currency")}},
+				Rows:    [][]any{{42.0, "USD"}},
+			}},
+		}
+		cost, err := newProvider(client).GetCost(context.Background(), &CostRequest{StartTime: start, EndTime: end})
+		if err != nil {
+			t.Fatalf("GetCost() failed: %v", err)
+		}
+		if len(client.queries) != 3 || cost.Total != 42 || len(cost.BreakdownBy) != 0 {
+			t.Errorf("expected the third attempt to return 42 ungrouped, got %d attempts and %+v", len(client.queries), cost)
+		}
+	})
+
+	failures := []struct {
+		name    string
+		errs    []error
+		req     *CostRequest
+		wantErr string
+	}{
+		{"still throttled", []error{
+			azureResponseError(http.StatusTooManyRequests, nil), azureResponseError(http.StatusTooManyRequests, nil),
+			azureResponseError(http.StatusTooManyRequests, nil), azureResponseError(http.StatusTooManyRequests, nil),
+		}, &CostRequest{StartTime: start, EndTime: end}, "still throttled after 4 attempts"},
+		{"forbidden", []error{azureResponseError(http.StatusForbidden, nil)}, &CostRequest{StartTime: start, EndTime: end}, "Cost Management Reader"},
+		{"group by", nil, &CostRequest{StartTime: start, EndTime: end, GroupBy: "instance_type"}, "unsupported cost group-by dimension"},
+		{"period", nil, &CostRequest{StartTime: end, EndTime: start}, "invalid cost period"},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeAzureCost{errs: tt.errs, result: grouped}
+			_, err := newProvider(client).GetCost(context.Background(), tt.req)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// fakeAzureCredential hands out a static token
+type fakeAzureCredential struct{}
+
+func (fakeAzureCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// fakeAzureARM answers Azure Resource Manager requests with canned JSON
+// bodies keyed by lower-cased path
+type fakeAzureARM struct {
+	responses map[string]string
+	requests  []string
+	filters   []string
+}
+
+func (f *fakeAzureARM) Do(req *http.Request) (*http.Response, error) {
+	path := strings.ToLower(req.URL.Path)
+	f.requests = append(f.requests, path)
+	if filter := req.URL.Query().Get("$filter"); filter != "" {
+		f.filters = append(f.filters, filter)
+	}
+	body, ok := f.responses[path]
+	status := http.StatusOK
+	if !ok {
+		status, body = http.StatusNotFound, `{"error":{"code":"NotFound"}}`
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func TestAzureListsResourceGroups(t *testing.T) {
+	const groups = "/subscriptions/sub-123/resourcegroups"
+	server := &fakeAzureARM{responses: map[string]string{
+		groups: `{"value": [
+			{"id": "/subscriptions/sub-123/resourceGroups/web", "name": "web", "location": "westeurope", "properties": {"provisioningState": "Succeeded"}},
+			{"id": "/subscriptions/sub-123/resourceGroups/data", "name": "data", "location": "northeurope", "tags": {"env": "prod"}, "properties": {"provisioningState": "Deleting"}}
+		]}`,
+		groups + "/web/providers/microsoft.compute/virtualmachines": `{"value": [
+			{"id": "/subscriptions/sub-123/resourceGroups/web/providers/Microsoft.Compute/virtualMachines/web-1", "name": "web-1", "location": "westeurope"}
+		]}`,
+		groups + "/data/providers/microsoft.compute/virtualmachines": `{"value": [
+			{"id": "/subscriptions/sub-123/resourceGroups/data/providers/Microsoft.Compute/virtualMachines/db-1", "name": "db-1", "location": "northeurope"}
+		]}`,
+	}}
+	options := &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: server, Retry: policy.RetryOptions{MaxRetries: -1}}}
+	resourceFactory, err := armresources.NewClientFactory("sub-123", fakeAzureCredential{}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	computeFactory, err := armcompute.NewClientFactory("sub-123", fakeAzureCredential{}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	provider := &AzureProvider{
+		resourceGroupsClient: resourceFactory.NewResourceGroupsClient(),
+		computeClient:        computeFactory.NewVirtualMachinesClient(),
+		subscriptionID:       "sub-123",
+		config:               &ProviderConfig{},
+		connected:            true,
+		logger:               logger,
+	}
+
+	resourceGroups, err := provider.ListResources(context.Background(), "resourcegroups")
+	if err != nil {
+		t.Fatalf("ListResources() failed: %v", err)
+	}
+	if len(resourceGroups) != 2 {
+		t.Fatalf("expected 2 resource groups, got %d", len(resourceGroups))
+	}
+	data := resourceGroups[1]
+	if data.Name != "data" || data.Type != "resource-group" || data.Region != "northeurope" || data.State != "Deleting" || data.Tags["env"] != "prod" {
+		t.Errorf("unexpected resource group %+v", data)
+	}
+
+	vms, err := provider.ListResources(context.Background(), "vm")
+	if err != nil {
+		t.Fatalf("ListResources() failed: %v", err)
+	}
+	var names []string
+	for _, vm := range vms {
+		names = append(names, vm.Name+"@"+vm.Config["resource_group"].(string))
+	}
+	if want := []string{"web-1@web", "db-1@data"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("expected one VM per resource group %v, got %v", want, names)
+	}
+	for _, path := range server.requests {
+		if strings.HasSuffix(path, "/resources") {
+			t.Errorf("expected resource groups to be listed, not every resource: %s", path)
+		}
+	}
+}
+
+func TestAzureListsTaggedVirtualMachines(t *testing.T) {
+	const groups = "/subscriptions/sub-123/resourcegroups"
+	server := &fakeAzureARM{responses: map[string]string{
+		"/subscriptions/sub-123/resources": `{"value": [
+			{"id": "/subscriptions/sub-123/resourceGroups/data/providers/Microsoft.Compute/virtualMachines/db-1", "name": "db-1", "type": "Microsoft.Compute/virtualMachines"},
+			{"id": "/subscriptions/sub-123/resourceGroups/web/providers/Microsoft.Network/virtualNetworks/web-net", "name": "web-net", "type": "Microsoft.Network/virtualNetworks"}
+		]}`,
+		groups + "/data/providers/microsoft.compute/virtualmachines": `{"value": [
+			{"id": "/subscriptions/sub-123/resourceGroups/data/providers/Microsoft.Compute/virtualMachines/db-1", "name": "db-1", "location": "northeurope", "tags": {"env": "prod"}},
+			{"id": "/subscriptions/sub-123/resourceGroups/data/providers/Microsoft.Compute/virtualMachines/db-2", "name": "db-2", "location": "northeurope"}
+		]}`,
+	}}
+	options := &arm.ClientOptions{ClientOptions: policy.ClientOptions{Transport: server, Retry: policy.RetryOptions{MaxRetries: -1}}}
+	resourceFactory, err := armresources.NewClientFactory("sub-123", fakeAzureCredential{}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	computeFactory, err := armcompute.NewClientFactory("sub-123", fakeAzureCredential{}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	provider := &AzureProvider{
+		resourceGroupsClient: resourceFactory.NewResourceGroupsClient(),
+		resourcesClient:      resourceFactory.NewClient(),
+		computeClient:        computeFactory.NewVirtualMachinesClient(),
+		subscriptionID:       "sub-123",
+		config:               &ProviderConfig{},
+		connected:            true,
+		logger:               logger,
+	}
+
+	vms, err := provider.ListResources(context.Background(), "vm", WithTags(map[string]string{"env": "prod"}))
+	if err != nil {
+		t.Fatalf("ListResources() failed: %v", err)
+	}
+	if len(vms) != 1 || vms[0].Name != "db-1" {
+		t.Fatalf("expected only the tagged VM, got %+v", vms)
+	}
+	if want := []string{"tagName eq 'env' and tagValue eq 'prod'"}; !reflect.DeepEqual(server.filters, want) {
+		t.Errorf("expected the tag to be queried server-side with %v, got %v", want, server.filters)
+	}
+	for _, path := range server.requests {
+		if path == groups || strings.Contains(path, "/web/") {
+			t.Errorf("expected only the resource groups holding tagged VMs to be listed, got a request for %s", path)
+		}
+	}
+}
+
+// fakeSTSClient answers GetCallerIdentity with a fixed caller
+type fakeSTSClient struct {
+	account, arn string
+}
+
+func (f *fakeSTSClient) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{Account: aws.String(f.account), Arn: aws.String(f.arn), UserId: aws.String("AIDA")}, nil
+}
+
+func TestGetIdentities(t *testing.T) {
+	provider := newTestAWSProvider(&fakeEC2Client{})
+	provider.stsClient = &fakeSTSClient{account: "210987654321", arn: "arn:aws:sts::210987654321:assumed-role/ops/alice"}
+
+	service := &DefaultCloudService{providers: map[string]CloudProvider{
+		"aws":   provider,
+		"gcp":   &MockCloudProvider{name: "gcp", region: "us-central1"},
+		"azure": &MockCloudProvider{name: "azure", status: "error"},
+	}}
+
+	identities, err := service.GetIdentities(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "azure: credentials expired") {
+		t.Errorf("expected the azure failure to be reported, got %v", err)
+	}
+	want := []Identity{
+		{Provider: "aws", Account: "210987654321", Principal: "arn:aws:sts::210987654321:assumed-role/ops/alice", Region: "us-west-2"},
+		{Provider: "gcp", Account: "123456789012", Principal: "arn:aws:iam::123456789012:user/gcp", Region: "us-central1"},
+	}
+	if !reflect.DeepEqual(identities, want) {
+		t.Errorf("expected identities %+v, got %+v", want, identities)
+	}
+
+	if _, err := (&DefaultCloudService{}).GetIdentities(context.Background()); err == nil || !strings.Contains(err.Error(), "no cloud providers") {
+		t.Errorf("expected an error without providers, got %v", err)
+	}
+}
+
+func TestAzureTokenIdentity(t *testing.T) {
+	token := func(claims string) string {
+		return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".c2ln"
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		principal string
+		tenant    string
+		wantErr   string
+	}{
+		{"user", token(`{"tid":"tenant-1","oid":"o-1","upn":"alice@example.com","appid":"a-1"}`), "alice@example.com", "tenant-1", ""},
+		{"application", token(`{"tid":"tenant-1","oid":"o-1","appid":"a-1"}`), "application a-1", "tenant-1", ""},
+		{"managed identity", token(`{"oid":"o-1"}`), "object o-1", "", ""},
+		{"not a jwt", "opaque", "", "", "not a JWT"},
+		{"bad claims", token(`[1]`), "", "", "failed to read Azure token claims"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := azureTokenIdentity(tt.token)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("azureTokenIdentity() failed: %v", err)
+			}
+			if identity.Principal != tt.principal || identity.Tenant != tt.tenant {
+				t.Errorf("expected %s in %s, got %s in %s", tt.principal, tt.tenant, identity.Principal, identity.Tenant)
+			}
+		})
+	}
+}
+
+func TestGCPCredentialsPrincipal(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{"service account", `{"type":"service_account","client_email":"ci@proj.iam.gserviceaccount.com"}`, "ci@proj.iam.gserviceaccount.com"},
+		{"impersonation", `{"type":"impersonated_service_account","service_account_impersonation_url":"https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/ops@proj.iam.gserviceaccount.com:generateAccessToken"}`, "ops@proj.iam.gserviceaccount.com"},
+		{"user", `{"type":"authorized_user","client_id":"x"}`, "user credentials from gcloud auth application-default login"},
+		{"metadata server", ``, ""},
+	}
+	for _, tt := range tests {
+		if got := gcpCredentialsPrincipal([]byte(tt.json)); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+// summaryListingProvider lists resources without their configuration, which
+// only their details have
+type summaryListingProvider struct {
+	*MockCloudProvider
+	detailsRequests []string
+}
+
+func (p *summaryListingProvider) ListResources(ctx context.Context, resourceType string, opts ...ListOption) ([]*Resource, error) {
+	resources, err := p.MockCloudProvider.ListResources(ctx, resourceType, opts...)
+	summaries := make([]*Resource, len(resources))
+	for i, resource := range resources {
+		summary := *resource
+		summary.Config = nil
+		summaries[i] = &summary
+	}
+	return summaries, err
+}
+
+func (p *summaryListingProvider) GetResourceDetails(ctx context.Context, resourceID string) (*Resource, error) {
+	p.detailsRequests = append(p.detailsRequests, resourceID)
+	return p.MockCloudProvider.GetResourceDetails(ctx, resourceID)
+}
+
+func driftTestProvider() *MockCloudProvider {
+	return &MockCloudProvider{
+		name: "aws",
+		resources: map[string]*Resource{
+			"i-web": {
+				ID: "i-web", Name: "web", Type: "ec2", Status: "running",
+				Tags:   map[string]string{"env": "prod", "owner": "ops"},
+				Config: map[string]interface{}{"instance_type": "t3.large", "monitoring": map[string]interface{}{"enabled": false, "interval": 60}},
+			},
+			"i-manual": {ID: "i-manual", Name: "console-test", Type: "ec2", Status: "running"},
+			"vol-data": {ID: "vol-data", Name: "data", Type: "ebs", Status: "available", Config: map[string]interface{}{"size": 20}},
+			"bucket":   {ID: "bucket", Name: "logs", Type: "s3"},
+		},
+	}
+}
+
+func TestDetectDrift(t *testing.T) {
+	provider := &summaryListingProvider{MockCloudProvider: driftTestProvider()}
+	service := &DefaultCloudService{providers: map[string]CloudProvider{"aws": provider}}
+
+	desired := []Resource{
+		{
+			ID: "i-web", Name: "web", Type: "ec2", Status: "running",
+			Tags:   map[string]string{"env": "prod"},
+			Config: map[string]interface{}{"instance_type": "t3.micro", "monitoring": map[string]interface{}{"enabled": true}},
+		},
+		// Matched by name, and the int size equals the decoded float
+		{Name: "data", Type: "ebs", Config: map[string]interface{}{"size": 20.0}},
+		{ID: "i-deleted", Name: "batch", Type: "ec2"},
+		// Resources of other providers are ignored
+		{ID: "vm-1", Type: "compute", Provider: "gcp"},
+	}
+
+	report, err := service.DetectDrift(context.Background(), "aws", desired)
+	if err != nil {
+		t.Fatalf("DetectDrift() failed: %v", err)
+	}
+
+	want := DriftSummary{Desired: 3, Live: 3, InSync: 1, Added: 1, Removed: 1, Changed: 1}
+	if report.Summary != want || !report.Drifted {
+		t.Errorf("expected summary %+v, got %+v", want, report.Summary)
+	}
+	if len(report.Added) != 1 || report.Added[0].ID != "i-manual" {
+		t.Errorf("expected the console instance to be added, got %+v", report.Added)
+	}
+	if len(report.Removed) != 1 || report.Removed[0].ID != "i-deleted" {
+		t.Errorf("expected i-deleted to be removed, got %+v", report.Removed)
+	}
+
+	if len(report.Changed) != 1 || report.Changed[0].ID != "i-web" {
+		t.Fatalf("expected i-web to have changed, got %+v", report.Changed)
+	}
+	changes := report.Changed[0].Changes
+	wantChanges := []AttributeChange{
+		{Attribute: "tags.owner", Live: "ops"},
+		{Attribute: "config.instance_type", Desired: "t3.micro", Live: "t3.large"},
+		{Attribute: "config.monitoring.enabled", Desired: true, Live: false},
+	}
+	if !reflect.DeepEqual(changes, wantChanges) {
+		t.Errorf("expected changes %+v, got %+v", wantChanges, changes)
+	}
+
+	// The listing has no configuration, so the details of the resources
+	// declaring some were fetched, as was the resource missing from it
+	if !slices.Equal(provider.detailsRequests, []string{"i-web", "vol-data", "i-deleted"}) {
+		t.Errorf("unexpected details requests %v", provider.detailsRequests)
+	}
+
+	// The JSON report lists every change for CI to gate on
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `{"attribute":"tags.owner","desired":null,"live":"ops"}`) {
+		t.Errorf("unexpected JSON report %s", data)
+	}
+
+	// Matching live state is not drift
+	report, err = service.DetectDrift(context.Background(), "aws", []Resource{{ID: "bucket", Type: "s3", Name: "logs"}})
+	if err != nil || report.Drifted || report.Summary.InSync != 1 {
+		t.Errorf("expected no drift, got %+v and %v", report, err)
+	}
+}
+
+func TestDetectDriftErrors(t *testing.T) {
+	mock := driftTestProvider()
+	mock.resources["i-web-2"] = &Resource{ID: "i-web-2", Name: "web", Type: "ec2"}
+	service := &DefaultCloudService{providers: map[string]CloudProvider{"aws": mock}}
+	ctx := context.Background()
+
+	if _, err := service.DetectDrift(ctx, "azure", nil); err == nil || !strings.Contains(err.Error(), "not configured") {
+		t.Errorf("expected an unconfigured provider error, got %v", err)
+	}
+	if _, err := service.DetectDrift(ctx, "aws", []Resource{{ID: "i-web"}}); err == nil || !strings.Contains(err.Error(), "needs a type") {
+		t.Errorf("expected a missing type error, got %v", err)
+	}
+	if _, err := service.DetectDrift(ctx, "aws", []Resource{{Type: "ec2", Name: "web"}}); err == nil || !strings.Contains(err.Error(), "declare the id") {
+		t.Errorf("expected an ambiguous name error, got %v", err)
+	}
+}
+
+func TestLoadDesiredState(t *testing.T) {
+	dir := t.TempDir()
+	template := filepath.Join(dir, "desired.yaml")
+	yamlState := `resources:
+  - id: i-web
+    type: ec2
+    tags:
+      env: prod
+    config:
+      instance_type: t3.micro
+`
+	if err := os.WriteFile(template, []byte(yamlState), 0644); err != nil {
+		t.Fatal(err)
+	}
+	resources, err := LoadDesiredState(template)
+	if err != nil {
+		t.Fatalf("LoadDesiredState() failed: %v", err)
+	}
+	if len(resources) != 1 || resources[0].ID != "i-web" || resources[0].Tags["env"] != "prod" || resources[0].Config["instance_type"] != "t3.micro" {
+		t.Errorf("unexpected resources %+v", resources)
+	}
+
+	// A saved listing, with fields such as timestamps that are not compared
+	snapshot := filepath.Join(dir, "snapshot.json")
+	if err := os.WriteFile(snapshot, []byte("[\n\t{\"id\": \"b\", \"type\": \"s3\", \"created_at\": \"2026-10-01T00:00:00Z\"}\n]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	resources, err = LoadDesiredState(snapshot)
+	if err != nil || len(resources) != 1 || resources[0].CreatedAt.IsZero() {
+		t.Errorf("expected the snapshot to load, got %+v and %v", resources, err)
+	}
+
+	if err := os.WriteFile(template, []byte("id: i-web\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDesiredState(template); err == nil || !strings.Contains(err.Error(), "must hold a list") {
+		t.Errorf("expected a shape error, got %v", err)
+	}
+}
+
+func TestDetectDriftAWS(t *testing.T) {
+	// The instance has no placement or monitoring, which details must not
+	// dereference
+	ec2Client := &fakeEC2Client{
+		instances: []types.Instance{
+			{
+				InstanceId:   aws.String("i-0abc123"),
+				InstanceType: types.InstanceTypeT3Large,
+				ImageId:      aws.String("ami-new"),
+				State:        &types.InstanceState{Name: types.InstanceStateNameRunning},
+				Tags:         []types.Tag{{Key: aws.String("Name"), Value: aws.String("web")}},
+			},
+		},
+	}
+	service := &DefaultCloudService{providers: map[string]CloudProvider{"aws": newTestAWSProvider(ec2Client)}}
+
+	// image_id is only in the instance details, and ec2 lists ec2-instance resources
+	desired := []Resource{{Name: "web", Type: "ec2", State: "running", Config: map[string]interface{}{"instance_type": "t3.large", "image_id": "ami-old"}}}
+	report, err := service.DetectDrift(context.Background(), "aws", desired)
+	if err != nil {
+		t.Fatalf("DetectDrift() failed: %v", err)
+	}
+	want := []ResourceDrift{{ID: "i-0abc123", Name: "web", Type: "ec2-instance", Changes: []AttributeChange{{Attribute: "config.image_id", Desired: "ami-old", Live: "ami-new"}}}}
+	if !reflect.DeepEqual(report.Changed, want) || report.Summary.Added != 0 {
+		t.Errorf("expected the image to have drifted, got %+v", report)
+	}
+}
@@ -0,0 +1,325 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// DriftReport compares the declared state of resources with their live state
+type DriftReport struct {
+	Provider  string       `json:"provider" yaml:"provider"`
+	Timestamp time.Time    `json:"timestamp" yaml:"timestamp"`
+	Drifted   bool         `json:"drifted" yaml:"drifted"`
+	Summary   DriftSummary `json:"summary" yaml:"summary"`
+	// Added resources are live but not declared, such as ones created in
+	// the console
+	Added []Resource `json:"added" yaml:"added"`
+	// Removed resources are declared but no longer live
+	Removed []Resource      `json:"removed" yaml:"removed"`
+	Changed []ResourceDrift `json:"changed" yaml:"changed"`
+}
+
+// DriftSummary counts the resources of a drift report
+type DriftSummary struct {
+	Desired int `json:"desired" yaml:"desired"`
+	Live    int `json:"live" yaml:"live"`
+	InSync  int `json:"in_sync" yaml:"in_sync"`
+	Added   int `json:"added" yaml:"added"`
+	Removed int `json:"removed" yaml:"removed"`
+	Changed int `json:"changed" yaml:"changed"`
+}
+
+// ResourceDrift lists the attributes of a resource that differ from its
+// declared state
+type ResourceDrift struct {
+	ID      string            `json:"id" yaml:"id"`
+	Name    string            `json:"name" yaml:"name"`
+	Type    string            `json:"type" yaml:"type"`
+	Changes []AttributeChange `json:"changes" yaml:"changes"`
+}
+
+// AttributeChange is an attribute whose live value differs from the declared
+// one. Config attributes are named config.<key>, with nested keys joined by
+// dots, and tags tags.<key>; a missing value is null
+type AttributeChange struct {
+	Attribute string      `json:"attribute" yaml:"attribute"`
+	Desired   interface{} `json:"desired" yaml:"desired"`
+	Live      interface{} `json:"live" yaml:"live"`
+}
+
+// DetectDrift compares desired with the live resources of provider. Live
+// resources of the declared types are listed and matched to desired ones by
+// ID, or by type and name when no ID is declared. Only the attributes a
+// desired resource sets are compared, except that declared tags must match
+// the live tags exactly. Desired resources of another provider are ignored.
+// Unlike ListResources it never falls back to mock data, so the provider
+// must be configured
+func (c *DefaultCloudService) DetectDrift(ctx context.Context, provider string, desired []Resource) (*DriftReport, error) {
+	cloudProvider, err := c.getProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	var declared []Resource
+	types := map[string]bool{}
+	for i, resource := range desired {
+		if resource.Provider != "" && resource.Provider != provider {
+			continue
+		}
+		if resource.Type == "" || (resource.ID == "" && resource.Name == "") {
+			return nil, fmt.Errorf("desired resource %d needs a type and an id or name", i+1)
+		}
+		declared = append(declared, resource)
+		types[resource.Type] = true
+	}
+
+	// List the declared types afresh, since a cached listing may predate
+	// the change being looked for. Names are matched within the declared
+	// type, which may be an alias such as ec2 for ec2-instance
+	var live []*Resource
+	byID := map[string]*Resource{}
+	byName := map[string][]*Resource{}
+	for _, resourceType := range slices.Sorted(maps.Keys(types)) {
+		resources, err := cloudProvider.ListResources(ctx, resourceType, WithNoCache())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s resources: %w", resourceType, err)
+		}
+		for _, resource := range resources {
+			key := resourceType + "/" + resource.Name
+			byName[key] = append(byName[key], resource)
+			if byID[resource.ID] == nil {
+				byID[resource.ID] = resource
+				live = append(live, resource)
+			}
+		}
+	}
+
+	report := &DriftReport{
+		Provider:  provider,
+		Timestamp: time.Now(),
+		Added:     []Resource{},
+		Removed:   []Resource{},
+		Changed:   []ResourceDrift{},
+	}
+	matched := map[string]bool{}
+	for _, want := range declared {
+		var got *Resource
+		if want.ID != "" {
+			got = byID[want.ID]
+			if got == nil {
+				// The listing may not cover the resource, such as one in
+				// another region, so look it up before calling it removed
+				if details, err := cloudProvider.GetResourceDetails(ctx, want.ID); err == nil && details != nil {
+					got = details
+				}
+			}
+		} else if candidates := byName[want.Type+"/"+want.Name]; len(candidates) == 1 {
+			got = candidates[0]
+		} else if len(candidates) > 1 {
+			return nil, fmt.Errorf("%d live %s resources are named %s; declare the id of the one meant", len(candidates), want.Type, want.Name)
+		}
+
+		if got == nil {
+			report.Removed = append(report.Removed, want)
+			continue
+		}
+		matched[got.ID] = true
+
+		if missingConfig(want.Config, got.Config) {
+			// Listings may leave out configuration that the details have
+			if details, err := cloudProvider.GetResourceDetails(ctx, got.ID); err == nil && details != nil {
+				got = details
+			} else if err != nil {
+				logrus.Debugf("Failed to get details of %s: %v", got.ID, err)
+			}
+		}
+
+		if changes := resourceChanges(want, *got); len(changes) > 0 {
+			report.Changed = append(report.Changed, ResourceDrift{ID: got.ID, Name: got.Name, Type: got.Type, Changes: changes})
+		} else {
+			report.Summary.InSync++
+		}
+	}
+	for _, resource := range live {
+		if !matched[resource.ID] {
+			report.Added = append(report.Added, *resource)
+		}
+	}
+
+	sort.Slice(report.Added, func(i, j int) bool { return resourceLess(report.Added[i], report.Added[j]) })
+	sort.Slice(report.Removed, func(i, j int) bool { return resourceLess(report.Removed[i], report.Removed[j]) })
+	sort.Slice(report.Changed, func(i, j int) bool {
+		return resourceLess(Resource{Type: report.Changed[i].Type, ID: report.Changed[i].ID}, Resource{Type: report.Changed[j].Type, ID: report.Changed[j].ID})
+	})
+
+	report.Summary.Desired = len(declared)
+	report.Summary.Live = len(live)
+	report.Summary.Added = len(report.Added)
+	report.Summary.Removed = len(report.Removed)
+	report.Summary.Changed = len(report.Changed)
+	report.Drifted = report.Summary.Added+report.Summary.Removed+report.Summary.Changed > 0
+	return report, nil
+}
+
+// resourceLess orders resources by type, then ID and name
+func resourceLess(a, b Resource) bool {
+	if a.Type != b.Type {
+		return a.Type < b.Type
+	}
+	if a.ID != b.ID {
+		return a.ID < b.ID
+	}
+	return a.Name < b.Name
+}
+
+// missingConfig reports whether a config key declared in want is absent
+// from got
+func missingConfig(want, got map[string]interface{}) bool {
+	for key := range want {
+		if _, ok := got[key]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resourceChanges lists the declared attributes of want that differ in got
+func resourceChanges(want, got Resource) []AttributeChange {
+	var changes []AttributeChange
+	compare := func(attribute string, desired, live interface{}) {
+		desired, live = normalizeValue(desired), normalizeValue(live)
+		if !reflect.DeepEqual(desired, live) {
+			changes = append(changes, AttributeChange{Attribute: attribute, Desired: desired, Live: live})
+		}
+	}
+
+	for _, field := range []struct {
+		attribute    string
+		desired, got string
+	}{
+		{"name", want.Name, got.Name},
+		{"region", want.Region, got.Region},
+		{"state", want.State, got.State},
+		{"status", want.Status, got.Status},
+	} {
+		if field.desired != "" && field.desired != field.got {
+			var live interface{}
+			if field.got != "" {
+				live = field.got
+			}
+			changes = append(changes, AttributeChange{Attribute: field.attribute, Desired: field.desired, Live: live})
+		}
+	}
+
+	if want.Tags != nil {
+		keys := map[string]bool{}
+		for key := range want.Tags {
+			keys[key] = true
+		}
+		for key := range got.Tags {
+			keys[key] = true
+		}
+		for _, key := range slices.Sorted(maps.Keys(keys)) {
+			desired, inWant := want.Tags[key]
+			live, inGot := got.Tags[key]
+			if inWant != inGot || desired != live {
+				change := AttributeChange{Attribute: "tags." + key}
+				if inWant {
+					change.Desired = desired
+				}
+				if inGot {
+					change.Live = live
+				}
+				changes = append(changes, change)
+			}
+		}
+	}
+
+	compareConfig("config", want.Config, got.Config, compare)
+	return changes
+}
+
+// compareConfig compares each key declared in want with got, descending
+// into nested maps so only the declared keys of those are compared too
+func compareConfig(prefix string, want, got map[string]interface{}, compare func(attribute string, desired, live interface{})) {
+	for _, key := range slices.Sorted(maps.Keys(want)) {
+		attribute := prefix + "." + key
+		live, ok := got[key]
+		if nested, isMap := normalizeValue(want[key]).(map[string]interface{}); isMap && len(nested) > 0 {
+			if liveNested, liveIsMap := normalizeValue(live).(map[string]interface{}); ok && liveIsMap {
+				compareConfig(attribute, nested, liveNested, compare)
+				continue
+			}
+		}
+		compare(attribute, want[key], live)
+	}
+}
+
+// normalizeValue converts a value to its JSON form, so that numbers of
+// different Go types and structs compare equal to their decoded form
+func normalizeValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+// LoadDesiredState reads the declared state of resources from a YAML or JSON
+// file. The file holds a list of resources, such as a snapshot saved with
+// allora cloud resources -o json, or a resources key with the list
+func LoadDesiredState(path string) ([]Resource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read desired state: %w", err)
+	}
+
+	var doc interface{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &doc)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse desired state %s: %w", path, err)
+	}
+
+	if file, ok := doc.(map[string]interface{}); ok {
+		if list, ok := file["resources"]; ok {
+			doc = list
+		}
+	}
+	if _, ok := doc.([]interface{}); !ok {
+		return nil, fmt.Errorf("desired state %s must hold a list of resources or a resources key with the list", path)
+	}
+
+	// Decode through JSON so YAML templates use the JSON field names
+	normalized, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse desired state %s: %w", path, err)
+	}
+	var resources []Resource
+	if err := json.Unmarshal(normalized, &resources); err != nil {
+		return nil, fmt.Errorf("failed to parse desired state %s: %w", path, err)
+	}
+	return resources, nil
+}
@@ -0,0 +1,54 @@
+package cloud
+
+import (
+	"context"
+	"sync"
+)
+
+// PlannedAction is a change that a mutating call would have made had it not
+// run in dry-run mode
+type PlannedAction struct {
+	Action      string    `json:"action"`
+	Provider    string    `json:"provider"`
+	ResourceID  string    `json:"resource_id,omitempty"`
+	Description string    `json:"description"`
+	Resource    *Resource `json:"resource,omitempty"`
+}
+
+// DryRun collects the actions planned by mutating calls made with a context
+// returned by WithDryRun
+type DryRun struct {
+	mu      sync.Mutex
+	actions []PlannedAction
+}
+
+type dryRunKey struct{}
+
+// WithDryRun returns a context in which the mutating CloudService and
+// CloudProvider methods record what they would change in the returned
+// DryRun instead of changing it. Read-only calls, such as dependency checks,
+// still reach the provider
+func WithDryRun(ctx context.Context) (context.Context, *DryRun) {
+	plan := &DryRun{}
+	return context.WithValue(ctx, dryRunKey{}, plan), plan
+}
+
+// DryRunFrom returns the DryRun carried by ctx, or nil outside a dry run
+func DryRunFrom(ctx context.Context) *DryRun {
+	plan, _ := ctx.Value(dryRunKey{}).(*DryRun)
+	return plan
+}
+
+// Plan records an action in place of performing it
+func (d *DryRun) Plan(action PlannedAction) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.actions = append(d.actions, action)
+}
+
+// Actions returns the planned actions in the order they were planned
+func (d *DryRun) Actions() []PlannedAction {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]PlannedAction(nil), d.actions...)
+}
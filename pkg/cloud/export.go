@@ -0,0 +1,171 @@
+package cloud
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/notify"
+)
+
+// ExportFormats lists the formats ExportResources can write
+var ExportFormats = []string{"csv", "json"}
+
+// exportColumns are the inventory columns, in CSV column order
+var exportColumns = []string{"ID", "Name", "Type", "Provider", "Region", "State", "Tags", "MonthlyCost"}
+
+// exportedResource is one inventory entry in a JSON export
+type exportedResource struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Type        string            `json:"type"`
+	Provider    string            `json:"provider"`
+	Region      string            `json:"region"`
+	State       string            `json:"state"`
+	Tags        map[string]string `json:"tags"`
+	MonthlyCost float64           `json:"monthly_cost"`
+}
+
+// ExportResources writes an inventory of resources to w as csv or json
+func ExportResources(resources []Resource, format string, w io.Writer) error {
+	switch format {
+	case "csv":
+		return exportCSV(resources, w)
+	case "json":
+		return exportJSON(resources, w)
+	default:
+		return fmt.Errorf("unsupported export format %q; use one of %s", format, strings.Join(ExportFormats, ", "))
+	}
+}
+
+// exportCSV writes one row per resource with tags flattened to k=v;k2=v2
+func exportCSV(resources []Resource, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(exportColumns); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+
+	for _, resource := range resources {
+		record := []string{
+			resource.ID,
+			resource.Name,
+			resource.Type,
+			resource.Provider,
+			resource.Region,
+			resource.State,
+			flattenTags(resource.Tags),
+			strconv.FormatFloat(monthlyCost(resource), 'f', 2, 64),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+// exportJSON writes the resources as an indented JSON array
+func exportJSON(resources []Resource, w io.Writer) error {
+	entries := make([]exportedResource, 0, len(resources))
+	for _, resource := range resources {
+		tags := resource.Tags
+		if tags == nil {
+			tags = map[string]string{}
+		}
+		entries = append(entries, exportedResource{
+			ID:          resource.ID,
+			Name:        resource.Name,
+			Type:        resource.Type,
+			Provider:    resource.Provider,
+			Region:      resource.Region,
+			State:       resource.State,
+			Tags:        tags,
+			MonthlyCost: monthlyCost(resource),
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(entries); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+// flattenTags joins tags as k=v pairs separated by semicolons, sorted by key
+func flattenTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+tags[key])
+	}
+	return strings.Join(pairs, ";")
+}
+
+// monthlyCost returns the resource's monthly cost, zero when unknown
+func monthlyCost(resource Resource) float64 {
+	if resource.Cost == nil {
+		return 0
+	}
+	return resource.Cost.Monthly
+}
+
+// Report converts a cost analysis into a notify.Report for mailing
+func (a *CostAnalysis) Report() notify.Report {
+	report := notify.Report{
+		Title:     "Cloud Cost Analysis",
+		Generated: time.Now(),
+		Summary: []notify.ReportField{
+			{Label: "Period", Value: a.Period},
+			{Label: "Total cost", Value: fmt.Sprintf("%.2f %s", a.TotalCost, a.Currency)},
+		},
+	}
+
+	if status := a.BudgetStatus; status != nil {
+		state := "under budget"
+		if status.OverBudget {
+			state = "OVER BUDGET"
+		}
+		report.Summary = append(report.Summary,
+			notify.ReportField{Label: "Budget", Value: fmt.Sprintf("%.2f %s, %.1f%% used, %s", status.Budget, a.Currency, status.PercentUsed, state)},
+			notify.ReportField{Label: "Projected spend", Value: fmt.Sprintf("%.2f %s", status.ProjectedSpend, a.Currency)},
+		)
+	}
+
+	if len(a.Breakdown) > 0 {
+		breakdown := notify.ReportSection{Title: "Breakdown", Headers: []string{"Category", "Cost", "Share", "Resources"}}
+		for _, item := range a.Breakdown {
+			breakdown.Rows = append(breakdown.Rows, []string{
+				item.Category, fmt.Sprintf("%.2f %s", item.Cost, a.Currency),
+				fmt.Sprintf("%.1f%%", item.Percentage), strconv.Itoa(item.ResourceCount),
+			})
+		}
+		report.Sections = append(report.Sections, breakdown)
+	}
+
+	if len(a.Recommendations) > 0 {
+		recommendations := notify.ReportSection{Title: "Recommendations", Headers: []string{"Priority", "Recommendation", "Savings", "Effort", "Risk"}}
+		for _, rec := range a.Recommendations {
+			recommendations.Rows = append(recommendations.Rows, []string{
+				rec.Priority, rec.Title + ": " + rec.Description,
+				fmt.Sprintf("%.2f %s", rec.Savings, a.Currency), rec.Effort, rec.Risk,
+			})
+		}
+		report.Sections = append(report.Sections, recommendations)
+	}
+	return report
+}
@@ -2,31 +2,43 @@ package cloud
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	compute "cloud.google.com/go/compute/apiv1"
 	"cloud.google.com/go/compute/apiv1/computepb"
+	"cloud.google.com/go/compute/metadata"
+	"github.com/AlloraAi/AlloraCLI/pkg/utils"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/protobuf/proto"
 )
 
+// gcpScope is the OAuth scope used for Compute Engine API access
+const gcpScope = "https://www.googleapis.com/auth/cloud-platform"
+
 // GCPProvider implements the CloudProvider interface for Google Cloud Platform
 type GCPProvider struct {
-	computeClient *compute.InstancesClient
-	zonesClient   *compute.ZonesClient
-	projectID     string
-	config        *ProviderConfig
-	connected     bool
-	logger        *logrus.Logger
+	computeClient  *compute.InstancesClient
+	zonesClient    *compute.ZonesClient
+	disksClient    *compute.DisksClient
+	networksClient *compute.NetworksClient
+	regionsClient  *compute.RegionsClient
+	projectID      string
+	config         *ProviderConfig
+	connected      bool
+	logger         *logrus.Logger
 }
 
 // NewGCPProvider creates a new GCP provider
 func NewGCPProvider(cfg *ProviderConfig) (CloudProvider, error) {
-	logger := logrus.New()
-	logger.SetLevel(logrus.InfoLevel)
+	logger := utils.NewLogger()
 
 	provider := &GCPProvider{
 		config:    cfg,
@@ -55,17 +67,32 @@ func (p *GCPProvider) Connect(ctx context.Context) error {
 
 	p.logger.Info("Connecting to Google Cloud Platform...")
 
-	// Validate project ID
-	if p.projectID == "" {
-		return fmt.Errorf("project ID is required for GCP provider")
-	}
-
-	// Create compute client
+	// Authenticate with the service account file if given, otherwise use ADC
 	var opts []option.ClientOption
 	if p.config.ServiceAccountPath != "" {
+		if _, err := os.Stat(p.config.ServiceAccountPath); err != nil {
+			return &ConfigError{
+				Provider: "gcp",
+				Field:    "service_account_path",
+				Message:  fmt.Sprintf("cannot read service account file %s: %v", p.config.ServiceAccountPath, err),
+			}
+		}
 		opts = append(opts, option.WithCredentialsFile(p.config.ServiceAccountPath))
 	}
 
+	// Resolve project ID from config, credentials or environment
+	if p.projectID == "" {
+		p.projectID = p.resolveProjectID(ctx)
+	}
+	if p.projectID == "" {
+		return &ConfigError{
+			Provider: "gcp",
+			Field:    "project_id",
+			Message:  "project ID is required; set cloud_providers.gcp.project_id or GOOGLE_CLOUD_PROJECT",
+		}
+	}
+
+	// Create compute client
 	client, err := compute.NewInstancesRESTClient(ctx, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to create GCP compute client: %w", err)
@@ -79,8 +106,30 @@ func (p *GCPProvider) Connect(ctx context.Context) error {
 	}
 	p.zonesClient = zonesClient
 
+	// Create disks client
+	disksClient, err := compute.NewDisksRESTClient(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create GCP disks client: %w", err)
+	}
+	p.disksClient = disksClient
+
+	// Create networks client
+	networksClient, err := compute.NewNetworksRESTClient(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create GCP networks client: %w", err)
+	}
+	p.networksClient = networksClient
+
+	// Create regions client
+	regionsClient, err := compute.NewRegionsRESTClient(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create GCP regions client: %w", err)
+	}
+	p.regionsClient = regionsClient
+
 	// Test connection
 	if err := p.ValidateCredentials(ctx); err != nil {
+		p.closeClients()
 		return fmt.Errorf("failed to validate GCP credentials: %w", err)
 	}
 
@@ -89,8 +138,45 @@ func (p *GCPProvider) Connect(ctx context.Context) error {
 	return nil
 }
 
+// resolveProjectID looks up the project ID from the service account file,
+// the environment or Application Default Credentials
+func (p *GCPProvider) resolveProjectID(ctx context.Context) string {
+	if p.config.ServiceAccountPath != "" {
+		if data, err := os.ReadFile(p.config.ServiceAccountPath); err == nil {
+			var account struct {
+				ProjectID string `json:"project_id"`
+			}
+			if err := json.Unmarshal(data, &account); err == nil && account.ProjectID != "" {
+				return account.ProjectID
+			}
+		}
+	}
+
+	for _, env := range []string{"GOOGLE_CLOUD_PROJECT", "GCLOUD_PROJECT", "CLOUDSDK_CORE_PROJECT"} {
+		if projectID := os.Getenv(env); projectID != "" {
+			return projectID
+		}
+	}
+
+	if p.config.ServiceAccountPath == "" {
+		if creds, err := google.FindDefaultCredentials(ctx, gcpScope); err == nil {
+			return creds.ProjectID
+		}
+	}
+
+	return ""
+}
+
 // Disconnect closes the connection
 func (p *GCPProvider) Disconnect(ctx context.Context) error {
+	p.closeClients()
+	p.connected = false
+	p.logger.Info("Disconnected from Google Cloud Platform")
+	return nil
+}
+
+// closeClients closes all open API clients
+func (p *GCPProvider) closeClients() {
 	if p.computeClient != nil {
 		p.computeClient.Close()
 		p.computeClient = nil
@@ -99,9 +185,18 @@ func (p *GCPProvider) Disconnect(ctx context.Context) error {
 		p.zonesClient.Close()
 		p.zonesClient = nil
 	}
-	p.connected = false
-	p.logger.Info("Disconnected from Google Cloud Platform")
-	return nil
+	if p.disksClient != nil {
+		p.disksClient.Close()
+		p.disksClient = nil
+	}
+	if p.networksClient != nil {
+		p.networksClient.Close()
+		p.networksClient = nil
+	}
+	if p.regionsClient != nil {
+		p.regionsClient.Close()
+		p.regionsClient = nil
+	}
 }
 
 // IsConnected returns connection status
@@ -111,18 +206,22 @@ func (p *GCPProvider) IsConnected() bool {
 
 // ValidateCredentials validates GCP credentials
 func (p *GCPProvider) ValidateCredentials(ctx context.Context) error {
-	if p.computeClient == nil {
-		return fmt.Errorf("compute client not initialized")
+	if p.zonesClient == nil {
+		return fmt.Errorf("zones client not initialized")
+	}
+
+	if p.projectID == "" {
+		return &ConfigError{Provider: "gcp", Field: "project_id", Message: "project ID is required"}
 	}
 
 	// Try to list zones to test credentials
 	req := &computepb.ListZonesRequest{
-		Project: p.projectID,
+		Project:    p.projectID,
+		MaxResults: proto.Uint32(1),
 	}
 
 	it := p.zonesClient.List(ctx, req)
-	_, err := it.Next()
-	if err != nil {
+	if _, err := it.Next(); err != nil && err != iterator.Done {
 		return fmt.Errorf("failed to validate credentials: %w", err)
 	}
 
@@ -130,104 +229,113 @@ func (p *GCPProvider) ValidateCredentials(ctx context.Context) error {
 }
 
 // ListResources lists GCP resources
-func (p *GCPProvider) ListResources(ctx context.Context, resourceType string) ([]*Resource, error) {
+func (p *GCPProvider) ListResources(ctx context.Context, resourceType string, opts ...ListOption) ([]*Resource, error) {
 	if !p.connected {
 		if err := p.Connect(ctx); err != nil {
 			return nil, err
 		}
 	}
 
+	options := NewListOptions(opts...)
+	var resources []*Resource
+	var err error
 	switch strings.ToLower(resourceType) {
-	case "instances", "vm", "vms":
-		return p.listInstances(ctx)
-	case "disks":
-		return p.listDisks(ctx)
-	case "networks":
-		return p.listNetworks(ctx)
+	case "compute-instances", "instances", "vm", "vms", "compute-instance":
+		resources, err = p.listInstances(ctx)
+	case "disks", "disk":
+		resources, err = p.listDisks(ctx)
+	case "networks", "network":
+		resources, err = p.listNetworks(ctx)
 	default:
 		return nil, fmt.Errorf("unsupported resource type: %s", resourceType)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	return options.Apply(resources), nil
 }
 
-// listInstances lists GCP compute instances
+// listInstances lists GCP compute instances across all zones
 func (p *GCPProvider) listInstances(ctx context.Context) ([]*Resource, error) {
 	var resources []*Resource
 
-	// List all zones first
-	zonesReq := &computepb.ListZonesRequest{
+	req := &computepb.AggregatedListInstancesRequest{
 		Project: p.projectID,
 	}
 
-	zonesIt := p.zonesClient.List(ctx, zonesReq)
+	it := p.computeClient.AggregatedList(ctx, req)
 	for {
-		zone, err := zonesIt.Next()
-		if err != nil {
+		pair, err := it.Next()
+		if err == iterator.Done {
 			break
 		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list instances: %w", err)
+		}
 
-		// List instances in this zone
-		req := &computepb.ListInstancesRequest{
-			Project: p.projectID,
-			Zone:    zone.GetName(),
+		for _, instance := range pair.Value.GetInstances() {
+			resources = append(resources, p.convertInstance(instance))
 		}
+	}
 
-		it := p.computeClient.List(ctx, req)
-		for {
-			instance, err := it.Next()
-			if err != nil {
-				break
-			}
+	return resources, nil
+}
 
-			resource := &Resource{
-				ID:       strconv.FormatUint(instance.GetId(), 10),
-				Name:     instance.GetName(),
-				Type:     "compute-instance",
-				Provider: "gcp",
-				Region:   p.getZoneRegion(zone.GetName()),
-				State:    instance.GetStatus(),
-				Status:   instance.GetStatus(),
-				Created:  p.parseGCPTime(instance.GetCreationTimestamp()),
-				Modified: time.Now(),
-				Tags:     p.convertGCPLabels(instance.GetLabels()),
-				Config: map[string]interface{}{
-					"zone":               zone.GetName(),
-					"machine_type":       p.getMachineType(instance.GetMachineType()),
-					"cpu_platform":       instance.GetCpuPlatform(),
-					"self_link":          instance.GetSelfLink(),
-					"network_interfaces": len(instance.GetNetworkInterfaces()),
-					"disks":              len(instance.GetDisks()),
-					"can_ip_forward":     instance.GetCanIpForward(),
-					"scheduling":         p.getSchedulingInfo(instance.GetScheduling()),
-				},
-			}
+// listDisks lists GCP persistent disks across all zones
+func (p *GCPProvider) listDisks(ctx context.Context) ([]*Resource, error) {
+	var resources []*Resource
 
-			// Add metadata
-			if instance.GetMetadata() != nil {
-				resource.Metadata = p.convertGCPMetadata(instance.GetMetadata())
-			}
+	req := &computepb.AggregatedListDisksRequest{
+		Project: p.projectID,
+	}
+
+	it := p.disksClient.AggregatedList(ctx, req)
+	for {
+		pair, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list disks: %w", err)
+		}
 
-			resources = append(resources, resource)
+		for _, disk := range pair.Value.GetDisks() {
+			resources = append(resources, p.convertDisk(disk))
 		}
 	}
 
 	return resources, nil
 }
 
-// listDisks lists GCP persistent disks
-func (p *GCPProvider) listDisks(ctx context.Context) ([]*Resource, error) {
-	// Note: This is a simplified implementation
-	// In a real implementation, you would need to use the disk client
-	return []*Resource{}, nil
-}
-
-// listNetworks lists GCP networks
+// listNetworks lists GCP VPC networks
 func (p *GCPProvider) listNetworks(ctx context.Context) ([]*Resource, error) {
-	// Note: This is a simplified implementation
-	// In a real implementation, you would need to use the network client
-	return []*Resource{}, nil
+	var resources []*Resource
+
+	req := &computepb.ListNetworksRequest{
+		Project: p.projectID,
+	}
+
+	it := p.networksClient.List(ctx, req)
+	for {
+		network, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list networks: %w", err)
+		}
+
+		resources = append(resources, p.convertNetwork(network))
+	}
+
+	return resources, nil
 }
 
-// GetResourceDetails gets detailed information about a resource
+// GetResourceDetails gets detailed information about a resource.
+// Accepted IDs are resource paths such as "zones/us-central1-a/instances/web-1",
+// "zones/us-central1-a/disks/data-1" or "global/networks/default", as well as
+// bare names or numeric IDs which are looked up across all resource types.
 func (p *GCPProvider) GetResourceDetails(ctx context.Context, resourceID string) (*Resource, error) {
 	if !p.connected {
 		if err := p.Connect(ctx); err != nil {
@@ -235,9 +343,166 @@ func (p *GCPProvider) GetResourceDetails(ctx context.Context, resourceID string)
 		}
 	}
 
-	// For GCP, we need to determine the zone and instance name
-	// This is a simplified implementation
-	return nil, fmt.Errorf("GetResourceDetails not fully implemented for GCP provider")
+	parts := strings.Split(strings.Trim(resourceID, "/"), "/")
+	// Strip an optional "projects/<id>/" prefix
+	if len(parts) > 2 && parts[0] == "projects" {
+		parts = parts[2:]
+	}
+
+	switch {
+	case len(parts) == 4 && parts[0] == "zones" && parts[2] == "instances":
+		instance, err := p.computeClient.Get(ctx, &computepb.GetInstanceRequest{
+			Project:  p.projectID,
+			Zone:     parts[1],
+			Instance: parts[3],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get instance %s: %w", resourceID, err)
+		}
+		return p.convertInstance(instance), nil
+	case len(parts) == 4 && parts[0] == "zones" && parts[2] == "disks":
+		disk, err := p.disksClient.Get(ctx, &computepb.GetDiskRequest{
+			Project: p.projectID,
+			Zone:    parts[1],
+			Disk:    parts[3],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get disk %s: %w", resourceID, err)
+		}
+		return p.convertDisk(disk), nil
+	case len(parts) == 3 && parts[0] == "global" && parts[1] == "networks":
+		network, err := p.networksClient.Get(ctx, &computepb.GetNetworkRequest{
+			Project: p.projectID,
+			Network: parts[2],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get network %s: %w", resourceID, err)
+		}
+		return p.convertNetwork(network), nil
+	case len(parts) == 1:
+		return p.findResource(ctx, parts[0])
+	}
+
+	return nil, fmt.Errorf("unsupported resource ID format: %s", resourceID)
+}
+
+// findResource searches instances, disks and networks for a matching name or ID
+func (p *GCPProvider) findResource(ctx context.Context, nameOrID string) (*Resource, error) {
+	listers := []func(context.Context) ([]*Resource, error){
+		p.listInstances,
+		p.listDisks,
+		p.listNetworks,
+	}
+
+	for _, list := range listers {
+		resources, err := list(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range resources {
+			if resource.ID == nameOrID || resource.Name == nameOrID {
+				return resource, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("resource %s not found", nameOrID)
+}
+
+// convertInstance converts a Compute Engine instance to a Resource
+func (p *GCPProvider) convertInstance(instance *computepb.Instance) *Resource {
+	zone := p.getLastPathSegment(instance.GetZone())
+
+	resource := &Resource{
+		ID:       strconv.FormatUint(instance.GetId(), 10),
+		Name:     instance.GetName(),
+		Type:     "compute-instance",
+		Provider: "gcp",
+		Region:   p.getZoneRegion(zone),
+		State:    instance.GetStatus(),
+		Status:   instance.GetStatus(),
+		Created:  p.parseGCPTime(instance.GetCreationTimestamp()),
+		Modified: time.Now(),
+		Tags:     p.convertGCPLabels(instance.GetLabels()),
+		Config: map[string]interface{}{
+			"zone":               zone,
+			"machine_type":       p.getMachineType(instance.GetMachineType()),
+			"cpu_platform":       instance.GetCpuPlatform(),
+			"self_link":          instance.GetSelfLink(),
+			"network_interfaces": len(instance.GetNetworkInterfaces()),
+			"networks":           p.getInstanceNetworks(instance.GetNetworkInterfaces()),
+			"disks":              len(instance.GetDisks()),
+			"can_ip_forward":     instance.GetCanIpForward(),
+			"scheduling":         p.getSchedulingInfo(instance.GetScheduling()),
+		},
+	}
+
+	// Add metadata
+	if instance.GetMetadata() != nil {
+		resource.Metadata = p.convertGCPMetadata(instance.GetMetadata())
+	}
+
+	return resource
+}
+
+// convertDisk converts a persistent disk to a Resource
+func (p *GCPProvider) convertDisk(disk *computepb.Disk) *Resource {
+	zone := p.getLastPathSegment(disk.GetZone())
+
+	var users []string
+	for _, user := range disk.GetUsers() {
+		users = append(users, p.getLastPathSegment(user))
+	}
+
+	return &Resource{
+		ID:       strconv.FormatUint(disk.GetId(), 10),
+		Name:     disk.GetName(),
+		Type:     "disk",
+		Provider: "gcp",
+		Region:   p.getZoneRegion(zone),
+		State:    disk.GetStatus(),
+		Status:   disk.GetStatus(),
+		Created:  p.parseGCPTime(disk.GetCreationTimestamp()),
+		Modified: time.Now(),
+		Tags:     p.convertGCPLabels(disk.GetLabels()),
+		Config: map[string]interface{}{
+			"zone":         zone,
+			"size_gb":      disk.GetSizeGb(),
+			"disk_type":    p.getLastPathSegment(disk.GetType()),
+			"source_image": disk.GetSourceImage(),
+			"users":        users,
+			"self_link":    disk.GetSelfLink(),
+		},
+	}
+}
+
+// convertNetwork converts a VPC network to a Resource
+func (p *GCPProvider) convertNetwork(network *computepb.Network) *Resource {
+	routingMode := ""
+	if network.GetRoutingConfig() != nil {
+		routingMode = network.GetRoutingConfig().GetRoutingMode()
+	}
+
+	return &Resource{
+		ID:       strconv.FormatUint(network.GetId(), 10),
+		Name:     network.GetName(),
+		Type:     "network",
+		Provider: "gcp",
+		Region:   "global",
+		State:    "available",
+		Status:   "available",
+		Created:  p.parseGCPTime(network.GetCreationTimestamp()),
+		Modified: time.Now(),
+		Tags:     make(map[string]string),
+		Config: map[string]interface{}{
+			"description":             network.GetDescription(),
+			"auto_create_subnetworks": network.GetAutoCreateSubnetworks(),
+			"subnetworks":             len(network.GetSubnetworks()),
+			"routing_mode":            routingMode,
+			"mtu":                     network.GetMtu(),
+			"self_link":               network.GetSelfLink(),
+		},
+	}
 }
 
 // Helper methods for GCP resource conversion
@@ -250,6 +515,20 @@ func (p *GCPProvider) getZoneRegion(zone string) string {
 	return zone
 }
 
+func (p *GCPProvider) getLastPathSegment(url string) string {
+	// Extract the last segment of a resource URL
+	parts := strings.Split(url, "/")
+	return parts[len(parts)-1]
+}
+
+func (p *GCPProvider) getInstanceNetworks(interfaces []*computepb.NetworkInterface) []string {
+	var networks []string
+	for _, iface := range interfaces {
+		networks = append(networks, p.getLastPathSegment(iface.GetNetwork()))
+	}
+	return networks
+}
+
 func (p *GCPProvider) getMachineType(machineType string) string {
 	// Extract machine type from URL
 	parts := strings.Split(machineType, "/")
@@ -349,51 +628,98 @@ func (p *GCPProvider) GetStatus() *ProviderStatus {
 	return status
 }
 
-func (p *GCPProvider) GetRegions(ctx context.Context) ([]string, error) {
-	// GCP regions are well-known, return common ones
-	return []string{
-		"us-central1",
-		"us-east1",
-		"us-east4",
-		"us-west1",
-		"us-west2",
-		"us-west3",
-		"us-west4",
-		"northamerica-northeast1",
-		"northamerica-northeast2",
-		"southamerica-east1",
-		"southamerica-west1",
-		"europe-central2",
-		"europe-north1",
-		"europe-southwest1",
-		"europe-west1",
-		"europe-west2",
-		"europe-west3",
-		"europe-west4",
-		"europe-west6",
-		"europe-west8",
-		"europe-west9",
-		"europe-west10",
-		"europe-west12",
-		"asia-east1",
-		"asia-east2",
-		"asia-northeast1",
-		"asia-northeast2",
-		"asia-northeast3",
-		"asia-south1",
-		"asia-south2",
-		"asia-southeast1",
-		"asia-southeast2",
-		"australia-southeast1",
-		"australia-southeast2",
-		"me-central1",
-		"me-west1",
-		"africa-south1",
+// GetIdentity returns the project and the account the credentials belong to
+func (p *GCPProvider) GetIdentity(ctx context.Context) (*Identity, error) {
+	if !p.connected {
+		if err := p.Connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	var data []byte
+	if p.config.ServiceAccountPath != "" {
+		var err error
+		if data, err = os.ReadFile(p.config.ServiceAccountPath); err != nil {
+			return nil, fmt.Errorf("failed to read service account file: %w", err)
+		}
+	} else {
+		creds, err := google.FindDefaultCredentials(ctx, gcpScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find default credentials: %w", err)
+		}
+		data = creds.JSON
+	}
+
+	principal := gcpCredentialsPrincipal(data)
+	if principal == "" && metadata.OnGCE() {
+		// Credentials from the metadata server belong to the instance's account
+		if email, err := metadata.EmailWithContext(ctx, "default"); err == nil {
+			principal = email
+		}
+	}
+	return &Identity{
+		Provider:  p.GetType(),
+		Account:   p.projectID,
+		Principal: principal,
+		Region:    p.config.Region,
 	}, nil
 }
 
+// gcpCredentialsPrincipal names the account of a credentials file: the
+// email of a service account, or the user logged in with gcloud
+func gcpCredentialsPrincipal(data []byte) string {
+	var creds struct {
+		Type                           string `json:"type"`
+		ClientEmail                    string `json:"client_email"`
+		ServiceAccountImpersonationURL string `json:"service_account_impersonation_url"`
+	}
+	if len(data) == 0 || json.Unmarshal(data, &creds) != nil {
+		return ""
+	}
+
+	switch {
+	case creds.ClientEmail != "":
+		return creds.ClientEmail
+	case creds.ServiceAccountImpersonationURL != "":
+		// .../serviceAccounts/<email>:generateAccessToken
+		account := creds.ServiceAccountImpersonationURL[strings.LastIndex(creds.ServiceAccountImpersonationURL, "/")+1:]
+		return strings.TrimSuffix(account, ":generateAccessToken")
+	case creds.Type == "authorized_user":
+		return "user credentials from gcloud auth application-default login"
+	}
+	return ""
+}
+
+func (p *GCPProvider) GetRegions(ctx context.Context) ([]string, error) {
+	if !p.connected {
+		if err := p.Connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	req := &computepb.ListRegionsRequest{
+		Project: p.projectID,
+	}
+
+	var regions []string
+	it := p.regionsClient.List(ctx, req)
+	for {
+		region, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list regions: %w", err)
+		}
+		regions = append(regions, region.GetName())
+	}
+
+	return regions, nil
+}
+
 func (p *GCPProvider) GetResourceTypes(ctx context.Context) ([]string, error) {
 	return []string{
+		"compute-instances",
 		"instances",
 		"vm",
 		"vms",
@@ -0,0 +1,102 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultHealthInterval is how often MonitorHealth checks resources when
+// HealthOptions.Interval is unset
+const defaultHealthInterval = 10 * time.Second
+
+// healthSeverityRanks orders health event severities so
+// HealthOptions.MinSeverity can act as a floor
+var healthSeverityRanks = map[string]int{
+	"info":     1,
+	"warning":  2,
+	"error":    3,
+	"critical": 4,
+}
+
+// HealthOptions controls which health events MonitorHealth delivers
+type HealthOptions struct {
+	// MinSeverity drops events below this severity (info, warning, error,
+	// critical); empty delivers every severity
+	MinSeverity string
+	// Interval is how often resources are checked, 10 seconds if unset
+	Interval time.Duration
+}
+
+// healthSeverityRank returns the rank of a severity, treating unknown values as info
+func healthSeverityRank(severity string) int {
+	if rank, ok := healthSeverityRanks[strings.ToLower(severity)]; ok {
+		return rank
+	}
+	return healthSeverityRanks["info"]
+}
+
+// validateHealthOptions checks that the minimum severity is known
+func validateHealthOptions(options HealthOptions) error {
+	if options.MinSeverity == "" {
+		return nil
+	}
+	if _, ok := healthSeverityRanks[strings.ToLower(options.MinSeverity)]; !ok {
+		return fmt.Errorf("unknown severity %q; use info, warning, error or critical", options.MinSeverity)
+	}
+	return nil
+}
+
+// FilterHealthEvents forwards only the status transitions of each resource:
+// its first event and any event whose status differs from the one before.
+// Transitions below minSeverity are dropped, except a recovery following a
+// delivered event, so consumers that saw a resource fail also see it recover.
+// The returned channel is closed when in is closed or ctx is done
+func FilterHealthEvents(ctx context.Context, in <-chan HealthEvent, minSeverity string) <-chan HealthEvent {
+	out := make(chan HealthEvent, cap(in))
+	floor := 0
+	if minSeverity != "" {
+		floor = healthSeverityRank(minSeverity)
+	}
+
+	go func() {
+		defer close(out)
+
+		// Last status seen and whether its transition was delivered, per resource
+		lastStatus := make(map[string]string)
+		delivered := make(map[string]bool)
+
+		for {
+			var event HealthEvent
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok = <-in:
+				if !ok {
+					return
+				}
+			}
+
+			key := event.Provider + "/" + event.ResourceID
+			if status, seen := lastStatus[key]; seen && status == event.Status {
+				continue
+			}
+			lastStatus[key] = event.Status
+
+			if healthSeverityRank(event.Severity) < floor && !delivered[key] {
+				continue
+			}
+			delivered[key] = healthSeverityRank(event.Severity) >= floor
+
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
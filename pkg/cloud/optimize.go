@@ -0,0 +1,395 @@
+package cloud
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// builtinInstanceTypes holds the instance families shipped with AlloraCLI
+//
+//go:embed instancetypes/*.yaml
+var builtinInstanceTypes embed.FS
+
+// Metric names requested from providers with GetMetrics, in percent
+const (
+	MetricCPUUtilization    = "cpu_utilization"
+	MetricMemoryUtilization = "memory_utilization"
+)
+
+// Defaults for OptimizeOptions fields left unset
+const (
+	DefaultOptimizeLookback  = 14 * 24 * time.Hour
+	DefaultOptimizeThreshold = 20.0
+)
+
+// optimizeMetricPeriod is the granularity of the utilization metrics, in seconds
+const optimizeMetricPeriod = 3600
+
+// hoursPerMonth turns hourly prices into monthly savings
+const hoursPerMonth = 730
+
+// instanceTypeTable lists one provider's instance families
+type instanceTypeTable struct {
+	Provider      string                    `yaml:"provider"`
+	ResourceType  string                    `yaml:"resource_type"`
+	SizeAttribute string                    `yaml:"size_attribute"`
+	Families      map[string][]instanceSize `yaml:"families"`
+	// Storage maps volume types to their price per GiB-month
+	Storage map[string]float64 `yaml:"storage"`
+}
+
+// instanceSize is one size of an instance family
+type instanceSize struct {
+	Name      string  `yaml:"name"`
+	VCPUs     int     `yaml:"vcpus"`
+	MemoryGiB float64 `yaml:"memory_gib"`
+	Hourly    float64 `yaml:"hourly"`
+}
+
+// loadInstanceTypes returns the embedded instance family table for provider
+func loadInstanceTypes(provider string) (*instanceTypeTable, error) {
+	data, err := builtinInstanceTypes.ReadFile("instancetypes/" + provider + ".yaml")
+	if err != nil {
+		return nil, fmt.Errorf("no instance types are known for provider %s", provider)
+	}
+
+	var table instanceTypeTable
+	if err := yaml.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("failed to parse instance types for %s: %w", provider, err)
+	}
+	if table.ResourceType == "" || table.SizeAttribute == "" {
+		return nil, fmt.Errorf("instance types for %s must set resource_type and size_attribute", provider)
+	}
+	return &table, nil
+}
+
+// smallerSize returns the current size and the next smaller size in its
+// family, or false if the size is unknown or already the smallest
+func (t *instanceTypeTable) smallerSize(name string) (current, smaller instanceSize, ok bool) {
+	for _, sizes := range t.Families {
+		for i, size := range sizes {
+			if size.Name == name && i > 0 {
+				return size, sizes[i-1], true
+			}
+		}
+	}
+	return instanceSize{}, instanceSize{}, false
+}
+
+// cleanupDetector finds resources of one type that cost money without being used
+type cleanupDetector struct {
+	Provider     string
+	ResourceType string
+	Detect       func(table *instanceTypeTable, resource *Resource) *OptimizationRecommendation
+}
+
+// cleanupDetectors lists the orphaned resource checks run by OptimizeResources
+var cleanupDetectors = []cleanupDetector{
+	{Provider: "aws", ResourceType: "volumes", Detect: detectUnattachedVolume},
+}
+
+// utilization summarizes a utilization metric over the lookback window
+type utilization struct {
+	P95      float64
+	Average  float64
+	Coverage float64
+}
+
+// optimizeWithMetrics recommends downsizing instances whose CPU, and memory
+// when reported, stayed under the threshold for the lookback window, and
+// cleaning up orphaned resources
+func optimizeWithMetrics(ctx context.Context, provider CloudProvider, options OptimizeOptions) (*OptimizationResult, error) {
+	table, err := loadInstanceTypes(provider.GetType())
+	if err != nil {
+		return nil, err
+	}
+
+	lookback := options.Lookback
+	if lookback <= 0 {
+		lookback = DefaultOptimizeLookback
+	}
+	threshold := options.Threshold
+	if threshold <= 0 {
+		threshold = DefaultOptimizeThreshold
+	}
+
+	resourceTypes := make([]string, 0, len(options.ResourceTypes))
+	for _, resourceType := range options.ResourceTypes {
+		if resourceType != "" {
+			resourceTypes = append(resourceTypes, resourceType)
+		}
+	}
+	if len(resourceTypes) == 0 {
+		resourceTypes = []string{table.ResourceType}
+		for _, detector := range cleanupDetectors {
+			if detector.Provider == table.Provider {
+				resourceTypes = append(resourceTypes, detector.ResourceType)
+			}
+		}
+	}
+
+	end := time.Now()
+	sizing := &rightsizing{
+		table:     table,
+		start:     end.Add(-lookback),
+		end:       end,
+		expected:  lookback.Hours() * 3600 / optimizeMetricPeriod,
+		threshold: threshold,
+	}
+
+	result := &OptimizationResult{
+		ID:              fmt.Sprintf("opt-%d", end.Unix()),
+		Timestamp:       end,
+		Status:          "pending",
+		Recommendations: []OptimizationRecommendation{},
+		RiskAssessment:  "none",
+	}
+	if options.DryRun {
+		result.Status = "dry-run"
+	}
+
+	for _, resourceType := range resourceTypes {
+		resources, err := provider.ListResources(ctx, resourceType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", resourceType, err)
+		}
+
+		for _, resource := range resources {
+			recommendation := sizing.recommend(ctx, provider, resource)
+			if recommendation == nil {
+				recommendation = detectCleanup(table, resourceType, resource)
+			}
+			if recommendation != nil {
+				result.Recommendations = append(result.Recommendations, *recommendation)
+				result.PotentialSavings += recommendation.Savings
+			}
+		}
+	}
+
+	// Missing metrics only fail the run when there is nothing else to report,
+	// such as on providers that do not serve metrics yet
+	if sizing.metricsErr != nil {
+		if len(result.Recommendations) == 0 {
+			return nil, fmt.Errorf("failed to read utilization metrics: %w", sizing.metricsErr)
+		}
+		result.Warnings = append(result.Warnings, fmt.Sprintf("%d of %d instances were not checked for rightsizing: %v",
+			sizing.metricsFailures, sizing.candidates, sizing.metricsErr))
+	}
+
+	sort.Slice(result.Recommendations, func(i, j int) bool {
+		return result.Recommendations[i].Savings > result.Recommendations[j].Savings
+	})
+	result.PotentialSavings = round2(result.PotentialSavings)
+	result.RiskAssessment = assessOptimizationRisk(result.Recommendations)
+	return result, nil
+}
+
+// rightsizing recommends smaller sizes for underutilized instances and
+// counts the instances examined
+type rightsizing struct {
+	table      *instanceTypeTable
+	start, end time.Time
+	expected   float64
+	threshold  float64
+
+	candidates  int
+	recommended int
+	// metricsErr is the last error reading metrics, which metricsFailures
+	// instances had
+	metricsErr      error
+	metricsFailures int
+}
+
+// recommend returns a rightsizing recommendation for resource, or nil if it
+// is not a running instance that could be smaller
+func (r *rightsizing) recommend(ctx context.Context, provider CloudProvider, resource *Resource) *OptimizationRecommendation {
+	size, _ := resource.Config[r.table.SizeAttribute].(string)
+	current, smaller, ok := r.table.smallerSize(size)
+	if !ok || (resource.State != "" && resource.State != "running") {
+		return nil
+	}
+	r.candidates++
+
+	cpu, err := fetchUtilization(ctx, provider, resource.ID, MetricCPUUtilization, r.start, r.end, r.expected)
+	if err != nil {
+		r.metricsErr = err
+		r.metricsFailures++
+		return nil
+	}
+	if cpu.Coverage == 0 || cpu.P95 >= r.threshold {
+		return nil
+	}
+
+	// Memory is only reported by some agents; without it confidence is lower
+	memory, err := fetchUtilization(ctx, provider, resource.ID, MetricMemoryUtilization, r.start, r.end, r.expected)
+	hasMemory := err == nil && memory.Coverage > 0
+	if hasMemory && memory.P95 >= r.threshold {
+		return nil
+	}
+
+	confidence := cpu.Coverage
+	if hasMemory {
+		confidence = math.Min(cpu.Coverage, memory.Coverage)
+	} else {
+		confidence *= 0.7
+	}
+
+	attribute := r.table.SizeAttribute
+	recommendation := &OptimizationRecommendation{
+		ResourceID: resource.ID,
+		Type:       "rightsizing",
+		Current: map[string]interface{}{
+			attribute:     current.Name,
+			"vcpus":       current.VCPUs,
+			"memory":      current.MemoryGiB,
+			"cpu_p95":     round2(cpu.P95),
+			"cpu_average": round2(cpu.Average),
+		},
+		Recommended: map[string]interface{}{
+			attribute: smaller.Name,
+			"vcpus":   smaller.VCPUs,
+			"memory":  smaller.MemoryGiB,
+		},
+		Savings:    round2((current.Hourly - smaller.Hourly) * hoursPerMonth),
+		Confidence: round2(confidence),
+		Actions: []string{
+			fmt.Sprintf("Stop %s", resource.ID),
+			fmt.Sprintf("Change %s from %s to %s", attribute, current.Name, smaller.Name),
+			fmt.Sprintf("Start %s", resource.ID),
+		},
+	}
+	if hasMemory {
+		recommendation.Current["memory_p95"] = round2(memory.P95)
+	}
+
+	r.recommended++
+	return recommendation
+}
+
+// detectCleanup runs the cleanup detectors for resources listed as resourceType
+func detectCleanup(table *instanceTypeTable, resourceType string, resource *Resource) *OptimizationRecommendation {
+	for _, detector := range cleanupDetectors {
+		if detector.Provider != table.Provider || detector.ResourceType != resourceType {
+			continue
+		}
+		if recommendation := detector.Detect(table, resource); recommendation != nil {
+			return recommendation
+		}
+	}
+	return nil
+}
+
+// detectUnattachedVolume flags EBS volumes in the available state, which are
+// attached to no instance but still billed for their provisioned size
+func detectUnattachedVolume(table *instanceTypeTable, resource *Resource) *OptimizationRecommendation {
+	if resource.State != "available" {
+		return nil
+	}
+
+	volumeType, _ := resource.Config["volume_type"].(string)
+	price, ok := table.Storage[volumeType]
+	if !ok {
+		return nil
+	}
+	size := configNumber(resource.Config["size"])
+
+	return &OptimizationRecommendation{
+		ResourceID: resource.ID,
+		Type:       "cleanup",
+		Current: map[string]interface{}{
+			"state":       resource.State,
+			"volume_type": volumeType,
+			"size":        size,
+		},
+		Recommended: map[string]interface{}{
+			"state": "deleted",
+		},
+		Savings:    round2(size * price),
+		Confidence: 0.9,
+		Actions: []string{
+			fmt.Sprintf("Confirm %s is no longer needed: unattached volumes may hold data kept on purpose or belong to a stopped workload", resource.ID),
+			fmt.Sprintf("Create a snapshot of %s before deleting it", resource.ID),
+			fmt.Sprintf("Delete %s", resource.ID),
+		},
+	}
+}
+
+// configNumber reads a numeric resource config value, whichever type it was stored as
+func configNumber(value interface{}) float64 {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	default:
+		return 0
+	}
+}
+
+// fetchUtilization reads a utilization metric, reporting coverage as the
+// share of expected data points that were returned
+func fetchUtilization(ctx context.Context, provider CloudProvider, resourceID, metric string, start, end time.Time, expected float64) (*utilization, error) {
+	metrics, err := provider.GetMetrics(ctx, &MetricsRequest{
+		ResourceID: resourceID,
+		MetricName: metric,
+		StartTime:  start,
+		EndTime:    end,
+		Period:     optimizeMetricPeriod,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s for %s: %w", metric, resourceID, err)
+	}
+
+	values := make([]float64, 0, len(metrics.DataPoints))
+	total := 0.0
+	for _, point := range metrics.DataPoints {
+		if point == nil {
+			continue
+		}
+		values = append(values, point.Value)
+		total += point.Value
+	}
+	if len(values) == 0 {
+		return &utilization{}, nil
+	}
+
+	sort.Float64s(values)
+	return &utilization{
+		P95:      values[int(math.Ceil(0.95*float64(len(values))))-1],
+		Average:  total / float64(len(values)),
+		Coverage: math.Min(1, float64(len(values))/expected),
+	}, nil
+}
+
+// assessOptimizationRisk rates applying the recommendations: low when every
+// one is backed by good data coverage
+func assessOptimizationRisk(recommendations []OptimizationRecommendation) string {
+	if len(recommendations) == 0 {
+		return "none"
+	}
+	risk := "low"
+	for _, rec := range recommendations {
+		switch {
+		case rec.Confidence < 0.5:
+			return "high"
+		case rec.Confidence < 0.8:
+			risk = "medium"
+		}
+	}
+	return risk
+}
+
+// round2 rounds to two decimal places
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
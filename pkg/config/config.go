@@ -19,16 +19,39 @@ type Config struct {
 	Security       SecurityConfig   `yaml:"security" mapstructure:"security"`
 	Plugins        PluginConfig     `yaml:"plugins" mapstructure:"plugins"`
 	Logging        LoggingConfig    `yaml:"logging" mapstructure:"logging"`
+	Notifications  NotifyConfig     `yaml:"notifications,omitempty" mapstructure:"notifications"`
+	Server         ServerConfig     `yaml:"server,omitempty" mapstructure:"server"`
+	// Templates are named prompt templates for allora ask --template
+	Templates map[string]string `yaml:"templates,omitempty" mapstructure:"templates"`
+	// Profiles override agents, cloud_providers and monitoring per environment
+	Profiles map[string]map[string]interface{} `yaml:"profiles,omitempty" mapstructure:"profiles"`
+
+	// profile is the profile merged into this configuration by Load
+	profile string
 }
 
 // Agent represents an AI agent configuration
 type Agent struct {
-	Type        string  `yaml:"type" mapstructure:"type"`
+	Type string `yaml:"type" mapstructure:"type"`
+	// Provider is the model backend, one of AgentProviders; empty picks
+	// OpenAI when an API key is set
+	Provider    string  `yaml:"provider,omitempty" mapstructure:"provider"`
 	APIKey      string  `yaml:"api_key" mapstructure:"api_key"`
 	Model       string  `yaml:"model" mapstructure:"model"`
 	MaxTokens   int     `yaml:"max_tokens" mapstructure:"max_tokens"`
 	Temperature float64 `yaml:"temperature" mapstructure:"temperature"`
 	Endpoint    string  `yaml:"endpoint,omitempty" mapstructure:"endpoint"`
+	// MaxContextTokens bounds the conversation history sent with each query
+	MaxContextTokens int `yaml:"max_context_tokens,omitempty" mapstructure:"max_context_tokens"`
+	// SummarizeHistory asks the agent to summarize history that no longer fits
+	SummarizeHistory bool `yaml:"summarize_history,omitempty" mapstructure:"summarize_history"`
+	// CacheTTLSeconds reuses the response to an identical query for this
+	// long instead of asking the model again; 0 turns caching off
+	CacheTTLSeconds int `yaml:"cache_ttl_seconds,omitempty" mapstructure:"cache_ttl_seconds"`
+	// CacheDeterministicOnly skips the cache when the temperature is above 0
+	CacheDeterministicOnly bool `yaml:"cache_deterministic_only,omitempty" mapstructure:"cache_deterministic_only"`
+	// EmbeddingModel embeds docs for retrieval; empty picks the provider's default
+	EmbeddingModel string `yaml:"embedding_model,omitempty" mapstructure:"embedding_model"`
 }
 
 // CloudProviders contains configuration for all cloud providers
@@ -36,6 +59,8 @@ type CloudProviders struct {
 	AWS   AWSConfig   `yaml:"aws" mapstructure:"aws"`
 	Azure AzureConfig `yaml:"azure" mapstructure:"azure"`
 	GCP   GCPConfig   `yaml:"gcp" mapstructure:"gcp"`
+	// CacheTTL is how long listed resources are reused, such as "5m"; "0" turns caching off
+	CacheTTL string `yaml:"cache_ttl,omitempty" mapstructure:"cache_ttl"`
 }
 
 // AWSConfig represents AWS-specific configuration
@@ -48,53 +73,74 @@ type AWSConfig struct {
 
 // AzureConfig represents Azure-specific configuration
 type AzureConfig struct {
-	SubscriptionID string `yaml:"subscription_id"`
-	TenantID       string `yaml:"tenant_id"`
-	ClientID       string `yaml:"client_id,omitempty"`
-	ClientSecret   string `yaml:"client_secret,omitempty"`
+	SubscriptionID string `yaml:"subscription_id" mapstructure:"subscription_id"`
+	TenantID       string `yaml:"tenant_id" mapstructure:"tenant_id"`
+	ClientID       string `yaml:"client_id,omitempty" mapstructure:"client_id"`
+	ClientSecret   string `yaml:"client_secret,omitempty" mapstructure:"client_secret"`
 }
 
 // GCPConfig represents GCP-specific configuration
 type GCPConfig struct {
-	ProjectID          string `yaml:"project_id"`
-	Region             string `yaml:"region"`
-	ServiceAccountPath string `yaml:"service_account_path,omitempty"`
-	ApplicationDefault bool   `yaml:"application_default"`
+	ProjectID          string `yaml:"project_id" mapstructure:"project_id"`
+	Region             string `yaml:"region" mapstructure:"region"`
+	ServiceAccountPath string `yaml:"service_account_path,omitempty" mapstructure:"service_account_path"`
+	ApplicationDefault bool   `yaml:"application_default" mapstructure:"application_default"`
 }
 
 // MonitoringConfig contains monitoring tool configurations
 type MonitoringConfig struct {
-	Prometheus PrometheusConfig `yaml:"prometheus"`
-	Grafana    GrafanaConfig    `yaml:"grafana"`
-	DataDog    DataDogConfig    `yaml:"datadog"`
-	NewRelic   NewRelicConfig   `yaml:"newrelic"`
+	Prometheus PrometheusConfig `yaml:"prometheus" mapstructure:"prometheus"`
+	Grafana    GrafanaConfig    `yaml:"grafana" mapstructure:"grafana"`
+	DataDog    DataDogConfig    `yaml:"datadog" mapstructure:"datadog"`
+	NewRelic   NewRelicConfig   `yaml:"newrelic" mapstructure:"newrelic"`
+	// Services holds the health checks of monitored services by name
+	Services map[string]ServiceConfig `yaml:"services,omitempty" mapstructure:"services"`
+	History  HistoryConfig            `yaml:"history,omitempty" mapstructure:"history"`
+}
+
+// HistoryConfig represents the local store of metric history, used when
+// no Prometheus endpoint is configured
+type HistoryConfig struct {
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	// Path is the SQLite database file, metrics.db in the config dir by default
+	Path string `yaml:"path,omitempty" mapstructure:"path"`
+	// Retention is how long samples are kept, such as 30d (7d by default)
+	Retention string `yaml:"retention,omitempty" mapstructure:"retention"`
+}
+
+// ServiceConfig represents the health checks of a monitored service
+type ServiceConfig struct {
+	// Endpoints are the URLs probed with an HTTP GET; any 2xx is healthy
+	Endpoints []string `yaml:"endpoints" mapstructure:"endpoints"`
+	// Timeout bounds each probe, such as 2s (5s by default)
+	Timeout string `yaml:"timeout,omitempty" mapstructure:"timeout"`
 }
 
 // PrometheusConfig represents Prometheus configuration
 type PrometheusConfig struct {
-	Endpoint string `yaml:"endpoint"`
-	Username string `yaml:"username,omitempty"`
-	Password string `yaml:"password,omitempty"`
+	Endpoint string `yaml:"endpoint" mapstructure:"endpoint"`
+	Username string `yaml:"username,omitempty" mapstructure:"username"`
+	Password string `yaml:"password,omitempty" mapstructure:"password"`
 }
 
 // GrafanaConfig represents Grafana configuration
 type GrafanaConfig struct {
-	Endpoint string `yaml:"endpoint"`
-	APIKey   string `yaml:"api_key,omitempty"`
-	Username string `yaml:"username,omitempty"`
-	Password string `yaml:"password,omitempty"`
+	Endpoint string `yaml:"endpoint" mapstructure:"endpoint"`
+	APIKey   string `yaml:"api_key,omitempty" mapstructure:"api_key"`
+	Username string `yaml:"username,omitempty" mapstructure:"username"`
+	Password string `yaml:"password,omitempty" mapstructure:"password"`
 }
 
 // DataDogConfig represents DataDog configuration
 type DataDogConfig struct {
-	APIKey string `yaml:"api_key"`
-	AppKey string `yaml:"app_key"`
+	APIKey string `yaml:"api_key" mapstructure:"api_key"`
+	AppKey string `yaml:"app_key" mapstructure:"app_key"`
 }
 
 // NewRelicConfig represents New Relic configuration
 type NewRelicConfig struct {
-	APIKey    string `yaml:"api_key"`
-	AccountID string `yaml:"account_id"`
+	APIKey    string `yaml:"api_key" mapstructure:"api_key"`
+	AccountID string `yaml:"account_id" mapstructure:"account_id"`
 }
 
 // SecurityConfig contains security-related settings
@@ -103,13 +149,80 @@ type SecurityConfig struct {
 	AuditLogging   bool   `yaml:"audit_logging" mapstructure:"audit_logging"`
 	KeyManagement  string `yaml:"key_management" mapstructure:"key_management"`
 	ComplianceMode string `yaml:"compliance_mode" mapstructure:"compliance_mode"`
+	// RotationPeriod is the age in days after which the keys configuration
+	// secrets are encrypted with are rotated; 0 disables rotation
+	RotationPeriod int `yaml:"rotation_period,omitempty" mapstructure:"rotation_period"`
+	// AuditLogPath is where security monitor records events; empty uses
+	// audit.log in the config directory
+	AuditLogPath string `yaml:"audit_log_path,omitempty" mapstructure:"audit_log_path"`
+	// RequireSignedPlugins rejects plugins without a valid signature
+	RequireSignedPlugins bool `yaml:"require_signed_plugins,omitempty" mapstructure:"require_signed_plugins"`
+	// PluginPublicKeys are the base64 Ed25519 keys plugin signatures are checked against
+	PluginPublicKeys []string `yaml:"plugin_public_keys,omitempty" mapstructure:"plugin_public_keys"`
+	// RedactionPatterns are regular expressions of secrets masked before
+	// queries are sent to agents, on top of the built-in ones
+	RedactionPatterns []string `yaml:"redaction_patterns,omitempty" mapstructure:"redaction_patterns"`
+	// Syslog receives RFC 5424 messages as security events
+	Syslog SyslogConfig `yaml:"syslog,omitempty" mapstructure:"syslog"`
+}
+
+// SyslogConfig configures the syslog listener security monitor reads events from
+type SyslogConfig struct {
+	// Address is the host:port to listen on; empty leaves the listener off
+	Address string `yaml:"address,omitempty" mapstructure:"address"`
+	// Protocol is udp, tcp or both; empty listens on udp
+	Protocol string `yaml:"protocol,omitempty" mapstructure:"protocol"`
 }
 
 // PluginConfig contains plugin-related settings
 type PluginConfig struct {
-	Directory      string   `yaml:"directory"`
-	AutoUpdate     bool     `yaml:"auto_update"`
-	AllowedSources []string `yaml:"allowed_sources"`
+	Directory      string   `yaml:"directory" mapstructure:"directory"`
+	Registry       string   `yaml:"registry" mapstructure:"registry"`
+	AutoUpdate     bool     `yaml:"auto_update" mapstructure:"auto_update"`
+	AllowedSources []string `yaml:"allowed_sources" mapstructure:"allowed_sources"`
+}
+
+// NotifyConfig configures where alerts and security events are sent
+type NotifyConfig struct {
+	Slack     WebhookConfig   `yaml:"slack,omitempty" mapstructure:"slack"`
+	Webhook   WebhookConfig   `yaml:"webhook,omitempty" mapstructure:"webhook"`
+	PagerDuty PagerDutyConfig `yaml:"pagerduty,omitempty" mapstructure:"pagerduty"`
+	Email     EmailConfig     `yaml:"email,omitempty" mapstructure:"email"`
+}
+
+// EmailConfig configures the SMTP server that security and cost reports are mailed through
+type EmailConfig struct {
+	Host     string `yaml:"host,omitempty" mapstructure:"host"`
+	Port     int    `yaml:"port,omitempty" mapstructure:"port"`
+	Username string `yaml:"username,omitempty" mapstructure:"username"`
+	Password string `yaml:"password,omitempty" mapstructure:"password"`
+	From     string `yaml:"from,omitempty" mapstructure:"from"`
+	// Recipients are sent every mailed report in addition to the --email addresses
+	Recipients []string `yaml:"recipients,omitempty" mapstructure:"recipients"`
+	// TLS is starttls (the default), tls for implicit TLS, or none
+	TLS string `yaml:"tls,omitempty" mapstructure:"tls"`
+}
+
+// PagerDutyConfig configures incidents opened through the PagerDuty Events API v2
+type PagerDutyConfig struct {
+	RoutingKey string `yaml:"routing_key,omitempty" mapstructure:"routing_key"`
+	// MinSeverity is the least severe alert that opens an incident, critical if unset
+	MinSeverity string `yaml:"min_severity,omitempty" mapstructure:"min_severity"`
+	// DryRun logs the events instead of sending them
+	DryRun bool `yaml:"dry_run,omitempty" mapstructure:"dry_run"`
+}
+
+// WebhookConfig is a notification webhook and the least severe events it receives
+type WebhookConfig struct {
+	URL         string `yaml:"url,omitempty" mapstructure:"url"`
+	MinSeverity string `yaml:"min_severity,omitempty" mapstructure:"min_severity"`
+}
+
+// ServerConfig configures the HTTP API started by allora serve
+type ServerConfig struct {
+	Address string `yaml:"address,omitempty" mapstructure:"address"`
+	// Token is the bearer token API clients must send
+	Token string `yaml:"token,omitempty" mapstructure:"token"`
 }
 
 // LoggingConfig contains logging configuration
@@ -123,7 +236,9 @@ type LoggingConfig struct {
 	MaxFiles int    `yaml:"max_files" mapstructure:"max_files"`
 }
 
-// Initialize initializes the configuration system
+// Initialize initializes the configuration system and validates the result.
+// A *ValidationError is returned after viper is fully set up, so callers may
+// choose to continue with an invalid configuration
 func Initialize(configFile string, verbose bool) error {
 	// Set config file path
 	if configFile != "" {
@@ -146,7 +261,7 @@ func Initialize(configFile string, verbose bool) error {
 	viper.AutomaticEnv()
 
 	// Set defaults
-	setDefaults()
+	setDefaults(viper.GetViper())
 
 	// Read config file
 	if err := viper.ReadInConfig(); err != nil {
@@ -165,20 +280,65 @@ func Initialize(configFile string, verbose bool) error {
 		}
 	}
 
-	return nil
+	cfg, err := Load()
+	if err != nil {
+		return err
+	}
+	return Validate(cfg)
 }
 
-// Load loads the configuration from file
+// Load loads the configuration from file, migrating older schema versions,
+// decrypting encrypted values and merging the active profile over the base
+// configuration
 func Load() (*Config, error) {
+	if err := migrateConfigFile(); err != nil {
+		return nil, err
+	}
+
 	var cfg Config
-	if err := viper.Unmarshal(&cfg); err != nil {
+
+	settings := viper.AllSettings()
+	profile := ActiveProfile()
+	if profile == "" && !hasEncryptedValues(settings) {
+		if err := viper.Unmarshal(&cfg); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+		}
+		return &cfg, nil
+	}
+
+	if hasEncryptedValues(settings) {
+		decrypted, err := decryptSettings(settings)
+		if err != nil {
+			return nil, err
+		}
+		settings = decrypted
+	}
+	if profile != "" {
+		merged, err := applyProfile(settings, profile)
+		if err != nil {
+			return nil, err
+		}
+		settings = merged
+	}
+
+	v := viper.New()
+	if err := v.MergeConfigMap(settings); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	cfg.profile = profile
 	return &cfg, nil
 }
 
-// Save saves the configuration to file
+// Save saves the configuration to file, encrypting sensitive values when
+// security.encryption is enabled
 func Save(cfg *Config, configFile string) error {
+	if cfg.profile != "" {
+		return fmt.Errorf("cannot save a configuration merged with profile %q; run without --profile to change the base configuration", cfg.profile)
+	}
+
 	if configFile == "" {
 		configDir, err := GetConfigDir()
 		if err != nil {
@@ -198,6 +358,13 @@ func Save(cfg *Config, configFile string) error {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
+	// Encrypt sensitive values
+	if cfg.Security.Encryption {
+		if data, err = encryptSecrets(data); err != nil {
+			return fmt.Errorf("failed to encrypt config: %w", err)
+		}
+	}
+
 	// Write to file
 	if err := os.WriteFile(configFile, data, 0644); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
@@ -240,35 +407,49 @@ func GetConfigDir() (string, error) {
 	return configDir, nil
 }
 
+// Default returns the configuration an empty config file loads as
+func Default() (*Config, error) {
+	v := viper.New()
+	setDefaults(v)
+
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal defaults: %w", err)
+	}
+	cfg.Version = CurrentVersion
+	return &cfg, nil
+}
+
 // setDefaults sets default configuration values
-func setDefaults() {
+func setDefaults(v *viper.Viper) {
 	// Logging defaults
-	viper.SetDefault("logging.level", "info")
-	viper.SetDefault("logging.format", "text")
-	viper.SetDefault("logging.output", "stdout")
-	viper.SetDefault("logging.rotate", true)
-	viper.SetDefault("logging.max_size", 100)
-	viper.SetDefault("logging.max_age", 30)
-	viper.SetDefault("logging.max_files", 10)
+	v.SetDefault("logging.level", "info")
+	v.SetDefault("logging.format", "text")
+	v.SetDefault("logging.output", "stdout")
+	v.SetDefault("logging.rotate", true)
+	v.SetDefault("logging.max_size", 100)
+	v.SetDefault("logging.max_age", 30)
+	v.SetDefault("logging.max_files", 10)
 
 	// Security defaults
-	viper.SetDefault("security.encryption", true)
-	viper.SetDefault("security.audit_logging", true)
-	viper.SetDefault("security.key_management", "local")
+	v.SetDefault("security.encryption", true)
+	v.SetDefault("security.audit_logging", true)
+	v.SetDefault("security.key_management", "local")
 
 	// Plugin defaults
-	viper.SetDefault("plugins.auto_update", false)
-	viper.SetDefault("plugins.allowed_sources", []string{"github.com", "registry.alloraai.com"})
+	v.SetDefault("plugins.registry", "https://registry.alloraai.com")
+	v.SetDefault("plugins.auto_update", false)
+	v.SetDefault("plugins.allowed_sources", []string{"github.com", "registry.alloraai.com"})
 
 	// Cloud provider defaults
-	viper.SetDefault("cloud_providers.aws.region", "us-west-2")
-	viper.SetDefault("cloud_providers.aws.profile", "default")
-	viper.SetDefault("cloud_providers.gcp.region", "us-central1")
-	viper.SetDefault("cloud_providers.gcp.application_default", true)
+	v.SetDefault("cloud_providers.aws.region", "us-west-2")
+	v.SetDefault("cloud_providers.aws.profile", "default")
+	v.SetDefault("cloud_providers.gcp.region", "us-central1")
+	v.SetDefault("cloud_providers.gcp.application_default", true)
 
 	// Monitoring defaults
-	viper.SetDefault("monitoring.prometheus.endpoint", "http://localhost:9090")
-	viper.SetDefault("monitoring.grafana.endpoint", "http://localhost:3000")
+	v.SetDefault("monitoring.prometheus.endpoint", "http://localhost:9090")
+	v.SetDefault("monitoring.grafana.endpoint", "http://localhost:3000")
 }
 
 // displayJSON displays configuration in JSON format
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -203,6 +204,150 @@ func TestDefaults(t *testing.T) {
 	}
 }
 
+func TestDefault(t *testing.T) {
+	cfg, err := Default()
+	if err != nil {
+		t.Fatalf("Default() failed: %v", err)
+	}
+
+	if cfg.Version != CurrentVersion {
+		t.Errorf("Expected version %s, got %s", CurrentVersion, cfg.Version)
+	}
+	if cfg.Logging.Level != "info" || cfg.Logging.MaxFiles != 10 {
+		t.Errorf("Expected logging defaults, got %+v", cfg.Logging)
+	}
+	if cfg.Plugins.Registry != "https://registry.alloraai.com" || len(cfg.Plugins.AllowedSources) != 2 {
+		t.Errorf("Expected plugin defaults, got %+v", cfg.Plugins)
+	}
+	if !cfg.Security.Encryption || cfg.Security.KeyManagement != "local" {
+		t.Errorf("Expected security defaults, got %+v", cfg.Security)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *Config {
+		return &Config{
+			Agents: map[string]Agent{
+				"default": {Type: "general", MaxTokens: 4096, Temperature: 0.7, Endpoint: "https://api.openai.com/v1"},
+			},
+			CloudProviders: CloudProviders{
+				AWS: AWSConfig{Region: "us-west-2", Profile: "default"},
+				GCP: GCPConfig{Region: "us-central1", ApplicationDefault: true},
+			},
+			Monitoring: MonitoringConfig{
+				Prometheus: PrometheusConfig{Endpoint: "http://localhost:9090"},
+			},
+		}
+	}
+
+	if err := Validate(valid()); err != nil {
+		t.Errorf("Validate() rejected a valid config: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(cfg *Config)
+		want   string
+	}{
+		{"agent temperature", func(cfg *Config) { a := cfg.Agents["default"]; a.Temperature = 1.5; cfg.Agents["default"] = a }, "agents.default.temperature: must be between 0 and 1, got 1.5"},
+		{"agent max tokens", func(cfg *Config) { a := cfg.Agents["default"]; a.MaxTokens = 0; cfg.Agents["default"] = a }, "agents.default.max_tokens: must be greater than 0"},
+		{"agent cache ttl", func(cfg *Config) { a := cfg.Agents["default"]; a.CacheTTLSeconds = -1; cfg.Agents["default"] = a }, "agents.default.cache_ttl_seconds: must not be negative, got -1"},
+		{"template", func(cfg *Config) { cfg.Templates = map[string]string{"triage": "{{.service"} }, "templates.triage: is not a valid template"},
+		{"redaction pattern", func(cfg *Config) { cfg.Security.RedactionPatterns = []string{`ok`, `(unclosed`} }, "security.redaction_patterns[1]: is not a valid regular expression"},
+		{"agent provider", func(cfg *Config) { a := cfg.Agents["default"]; a.Provider = "skynet"; cfg.Agents["default"] = a }, `agents.default.provider: unknown provider "skynet"; use one of openai, ollama`},
+		{"agent type", func(cfg *Config) { a := cfg.Agents["default"]; a.Type = "robot"; cfg.Agents["default"] = a }, `agents.default.type: unknown agent type "robot"`},
+		{"agent endpoint", func(cfg *Config) {
+			a := cfg.Agents["default"]
+			a.Endpoint = "api.openai.com"
+			cfg.Agents["default"] = a
+		}, "agents.default.endpoint: \"api.openai.com\" must be an http:// or https:// URL"},
+		{"endpoint port", func(cfg *Config) { cfg.Monitoring.Prometheus.Endpoint = "http://localhost:99999" }, "monitoring.prometheus.endpoint: \"http://localhost:99999\" has invalid port 99999"},
+		{"aws region", func(cfg *Config) { cfg.CloudProviders.AWS.Region = "" }, "cloud_providers.aws.region: is required"},
+		{"aws key pair", func(cfg *Config) { cfg.CloudProviders.AWS.AccessKeyID = "AKIA" }, "cloud_providers.aws.secret_access_key: is required when cloud_providers.aws.access_key_id is set"},
+		{"azure tenant", func(cfg *Config) { cfg.CloudProviders.Azure.SubscriptionID = "sub" }, "cloud_providers.azure.tenant_id: is required"},
+		{"gcp project", func(cfg *Config) { cfg.CloudProviders.GCP.ServiceAccountPath = "/key.json" }, "cloud_providers.gcp.project_id: is required"},
+		{"cache ttl", func(cfg *Config) { cfg.CloudProviders.CacheTTL = "soon" }, `cloud_providers.cache_ttl: "soon" must be a duration`},
+		{"notification url", func(cfg *Config) { cfg.Notifications.Slack.URL = "hooks.slack.com" }, `notifications.slack.url: "hooks.slack.com" must be an http:// or https:// URL`},
+		{"notification severity", func(cfg *Config) { cfg.Notifications.Webhook.MinSeverity = "urgent" }, `notifications.webhook.min_severity: unknown severity "urgent"`},
+		{"server address", func(cfg *Config) { cfg.Server.Address = "8080" }, `server.address: "8080" must be host:port`},
+		{"syslog address", func(cfg *Config) { cfg.Security.Syslog.Address = "0.0.0.0:99999" }, `security.syslog.address: "0.0.0.0:99999" has invalid port`},
+		{"syslog protocol", func(cfg *Config) { cfg.Security.Syslog.Protocol = "sctp" }, `security.syslog.protocol: unknown protocol "sctp"`},
+		{"rotation period", func(cfg *Config) { cfg.Security.RotationPeriod = -1 }, "security.rotation_period: must not be negative"},
+		{"email sender", func(cfg *Config) { cfg.Notifications.Email.Host = "smtp.example.com" }, "notifications.email.from: is required to mail reports"},
+		{"service endpoint", func(cfg *Config) {
+			cfg.Monitoring.Services = map[string]ServiceConfig{"api": {Endpoints: []string{"http://api:8080/health", "api/health"}}}
+		}, `monitoring.services.api.endpoints[1]: "api/health" must be an http:// or https:// URL`},
+		{"service timeout", func(cfg *Config) {
+			cfg.Monitoring.Services = map[string]ServiceConfig{"api": {Endpoints: []string{"http://api:8080/health"}, Timeout: "0s"}}
+		}, `monitoring.services.api.timeout: "0s" must be a positive duration`},
+		{"history retention", func(cfg *Config) { cfg.Monitoring.History.Retention = "forever" }, `monitoring.history.retention: "forever" must be a positive duration such as 30d`},
+		{"email tls", func(cfg *Config) {
+			cfg.Notifications.Email = EmailConfig{Host: "smtp.example.com", From: "allora@example.com", TLS: "ssl"}
+		}, `notifications.email.tls: unknown TLS mode "ssl"`},
+		{"plugin public key", func(cfg *Config) { cfg.Security.PluginPublicKeys = []string{"ed25519:c2hvcnQ="} }, "security.plugin_public_keys[0]: must be a base64 Ed25519 public key"},
+		{"signed plugins without keys", func(cfg *Config) { cfg.Security.RequireSignedPlugins = true }, "security.plugin_public_keys: is required when security.require_signed_plugins is on"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(cfg)
+			err := Validate(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestValidateListsEveryProblem(t *testing.T) {
+	cfg := &Config{
+		Agents: map[string]Agent{
+			"a": {Type: "general", MaxTokens: -1, Temperature: 2},
+			"b": {Type: "bogus", MaxTokens: 100, Temperature: 0.5},
+		},
+	}
+
+	err := Validate(cfg)
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected a *ValidationError, got %v", err)
+	}
+	if len(invalid.Problems) != 3 {
+		t.Errorf("expected 3 problems, got %d: %v", len(invalid.Problems), invalid.Problems)
+	}
+	if !strings.Contains(err.Error(), "3 problems") {
+		t.Errorf("expected the error to count the problems, got %q", err.Error())
+	}
+}
+
+func TestInitializeRejectsInvalidConfig(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	testConfig := `agents:
+  default:
+    type: "general"
+    max_tokens: 0
+    temperature: 3
+cloud_providers:
+  azure:
+    subscription_id: "sub"
+`
+	if err := os.WriteFile(configFile, []byte(testConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Initialize(configFile, false)
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected a *ValidationError, got %v", err)
+	}
+	for _, want := range []string{"agents.default.max_tokens", "agents.default.temperature", "cloud_providers.azure.tenant_id"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the error to mention %s, got %q", want, err.Error())
+		}
+	}
+}
+
 // BenchmarkLoad benchmarks the configuration loading
 func BenchmarkLoad(b *testing.B) {
 	// Create a temporary config file
@@ -243,3 +388,183 @@ logging:
 		}
 	}
 }
+
+func TestProfiles(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	testConfig := `agents:
+  default:
+    type: "openai"
+    model: "gpt-4"
+    max_tokens: 2048
+    temperature: 0.7
+cloud_providers:
+  aws:
+    region: "us-west-2"
+    profile: "dev"
+monitoring:
+  prometheus:
+    endpoint: "http://localhost:9090"
+profiles:
+  prod:
+    agents:
+      default:
+        model: "gpt-4o"
+      ops:
+        type: "aws"
+        max_tokens: 1024
+    cloud_providers:
+      aws:
+        region: "eu-west-1"
+    monitoring:
+      prometheus:
+        endpoint: "https://prometheus.prod.example.com"
+`
+	if err := os.WriteFile(configFile, []byte(testConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("ALLORA_PROFILE", "prod")
+	if err := Initialize(configFile, false); err != nil {
+		t.Fatalf("Initialize() failed: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if got := cfg.Agents["default"]; got.Model != "gpt-4o" || got.Type != "openai" || got.MaxTokens != 2048 {
+		t.Errorf("expected the prod model merged over the base agent, got %+v", got)
+	}
+	if got := cfg.Agents["ops"]; got.Type != "aws" || got.MaxTokens != 1024 {
+		t.Errorf("expected the prod-only agent, got %+v", got)
+	}
+	if got := cfg.CloudProviders.AWS; got.Region != "eu-west-1" || got.Profile != "dev" {
+		t.Errorf("expected the prod region with the base AWS profile, got %+v", got)
+	}
+	if got := cfg.Monitoring.Prometheus.Endpoint; got != "https://prometheus.prod.example.com" {
+		t.Errorf("expected the prod Prometheus endpoint, got %q", got)
+	}
+	if err := Save(cfg, filepath.Join(t.TempDir(), "merged.yaml")); err == nil || !strings.Contains(err.Error(), "--profile") {
+		t.Errorf("expected saving a merged configuration to fail, got %v", err)
+	}
+
+	t.Setenv("ALLORA_PROFILE", "")
+	base, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if base.Agents["default"].Model != "gpt-4" || base.CloudProviders.AWS.Region != "us-west-2" {
+		t.Errorf("expected the base configuration without a profile, got %+v", base)
+	}
+	if _, exists := base.Agents["ops"]; exists {
+		t.Error("expected the prod-only agent to be absent from the base configuration")
+	}
+
+	t.Setenv("ALLORA_PROFILE", "staging")
+	if err := Initialize(configFile, false); err == nil || !strings.Contains(err.Error(), `profile "staging" is not defined; available profiles: prod`) {
+		t.Errorf("expected an unknown profile error, got %v", err)
+	}
+}
+
+func TestProfilesRejectUnknownSections(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	testConfig := `profiles:
+  prod:
+    logging:
+      level: "debug"
+`
+	if err := os.WriteFile(configFile, []byte(testConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Initialize(configFile, false)
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) || !strings.Contains(err.Error(), "profiles.prod.logging") {
+		t.Errorf("expected a validation error for profiles.prod.logging, got %v", err)
+	}
+}
+
+func TestMigrateV0(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "v0.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configFile, fixture, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Initialize(configFile, false); err != nil {
+		t.Fatalf("Initialize() failed: %v", err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if cfg.Version != CurrentVersion {
+		t.Errorf("expected version %s, got %q", CurrentVersion, cfg.Version)
+	}
+	if got := cfg.Agents["openai"]; got.Type != "openai" || got.APIKey != "sk-legacy" || got.MaxTokens != 2000 {
+		t.Errorf("expected ai.openai migrated to agents.openai, got %+v", got)
+	}
+	if got := cfg.CloudProviders.AWS.Region; got != "eu-central-1" {
+		t.Errorf("expected providers.aws migrated to cloud_providers.aws, got region %q", got)
+	}
+	if got := cfg.CloudProviders.GCP.ServiceAccountPath; got != "/etc/allora/gcp.json" {
+		t.Errorf("expected gcp.credentials_file migrated to service_account_path, got %q", got)
+	}
+	if got := cfg.Monitoring.Prometheus.Endpoint; got != "http://prometheus.internal:9090" {
+		t.Errorf("expected prometheus.url migrated to endpoint, got %q", got)
+	}
+	if got := cfg.Monitoring.Grafana; got.Endpoint != "http://grafana.internal:3000" || got.APIKey != "grafana-key" {
+		t.Errorf("expected grafana.url migrated to endpoint, got %+v", got)
+	}
+	if !cfg.Security.AuditLogging || cfg.Security.ComplianceMode != "SOC2" {
+		t.Errorf("expected security.audit_log migrated to audit_logging, got %+v", cfg.Security)
+	}
+
+	// The file is rewritten once, keeping the original as a backup
+	migrated, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, legacy := range []string{"\nproviders:", "\nai:", "url:", "credentials_file", "audit_log:", "encryption_key"} {
+		if strings.Contains(string(migrated), legacy) {
+			t.Errorf("expected %q to be migrated away, got:\n%s", legacy, migrated)
+		}
+	}
+	if !strings.Contains(string(migrated), "version: 1.0.0") {
+		t.Errorf("expected the migrated file to record version %s, got:\n%s", CurrentVersion, migrated)
+	}
+	if backup, err := os.ReadFile(configFile + ".bak"); err != nil || string(backup) != string(fixture) {
+		t.Errorf("expected the original config backed up, got %v", err)
+	}
+	if info, err := os.Stat(configFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected the config file mode to be kept, got %v", info.Mode())
+	}
+
+	if _, err := Load(); err != nil {
+		t.Fatalf("Load() after migration failed: %v", err)
+	}
+	if again, _ := os.ReadFile(configFile); string(again) != string(migrated) {
+		t.Error("expected a migrated config not to be rewritten again")
+	}
+}
+
+func TestMigrateRejectsNewerVersions(t *testing.T) {
+	for _, version := range []string{"2.0.0", "1.1", "banana"} {
+		_, err := Migrate(map[string]interface{}{"version": version}, version)
+		if err == nil || !strings.Contains(err.Error(), "upgrade AlloraCLI") {
+			t.Errorf("Migrate(%q) expected an error advising an upgrade, got %v", version, err)
+		}
+	}
+
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configFile, []byte("version: \"9.0.0\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Initialize(configFile, false); err == nil || !strings.Contains(err.Error(), "newer than this AlloraCLI supports") {
+		t.Errorf("expected Initialize() to reject a newer config, got %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the configuration schema version this build writes
+const CurrentVersion = "1.0.0"
+
+// migration upgrades settings written before schema version to
+type migration struct {
+	to    string
+	apply func(cfg map[string]interface{})
+}
+
+// migrations are applied in order to settings older than their version
+var migrations = []migration{
+	{to: "1.0.0", apply: migrateV0},
+}
+
+// Migrate upgrades raw configuration settings written with schema
+// fromVersion to CurrentVersion. An empty fromVersion is treated as the
+// unversioned v0 schema; versions newer than CurrentVersion are rejected
+func Migrate(cfg map[string]interface{}, fromVersion string) (map[string]interface{}, error) {
+	from := strings.TrimPrefix(strings.TrimSpace(fromVersion), "v")
+	if from == "" {
+		from = "0"
+	}
+
+	newer, err := compareVersions(from, CurrentVersion)
+	if err != nil {
+		return nil, fmt.Errorf("unknown config version %q; upgrade AlloraCLI or recreate the configuration with 'allora init'", fromVersion)
+	}
+	if newer > 0 {
+		return nil, fmt.Errorf("config version %s is newer than this AlloraCLI supports (%s); upgrade AlloraCLI to use this configuration", fromVersion, CurrentVersion)
+	}
+
+	migrated := deepCopySettings(cfg)
+	for _, m := range migrations {
+		if cmp, _ := compareVersions(from, m.to); cmp < 0 {
+			m.apply(migrated)
+		}
+	}
+	migrated["version"] = CurrentVersion
+	return migrated, nil
+}
+
+// migrateConfigFile upgrades the config file viper read to CurrentVersion,
+// rewriting it once and keeping the original next to it as a .bak file
+func migrateConfigFile() error {
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	raw := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if raw == nil {
+		return nil
+	}
+
+	version := fmt.Sprint(raw["version"])
+	if raw["version"] == nil {
+		version = ""
+	}
+	if version == CurrentVersion {
+		return nil
+	}
+
+	migrated, err := Migrate(raw, version)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	// Leave unversioned files alone unless they use the v0 layout
+	if version == "" {
+		unchanged := deepCopySettings(migrated)
+		delete(unchanged, "version")
+		if reflect.DeepEqual(unchanged, raw) {
+			return nil
+		}
+	}
+
+	out, err := yaml.Marshal(migrated)
+	if err != nil {
+		return fmt.Errorf("failed to marshal migrated config: %w", err)
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	backup := path + ".bak"
+	if err := os.WriteFile(backup, data, mode); err != nil {
+		return fmt.Errorf("failed to back up config file: %w", err)
+	}
+	if err := os.WriteFile(path, out, mode); err != nil {
+		return fmt.Errorf("failed to write migrated config file: %w", err)
+	}
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read migrated config file: %w", err)
+	}
+
+	if version == "" {
+		version = "0"
+	}
+	logrus.Infof("Migrated configuration %s from version %s to %s; the original is saved as %s", path, version, CurrentVersion, backup)
+	return nil
+}
+
+// migrateV0 upgrades the unversioned layout, which kept AI settings under
+// ai, cloud settings under providers and monitoring endpoints as url
+func migrateV0(cfg map[string]interface{}) {
+	if providers, ok := cfg["providers"]; ok {
+		cfg["cloud_providers"] = mergeLegacy(providers, cfg["cloud_providers"])
+		delete(cfg, "providers")
+	}
+	if cloud, ok := cfg["cloud_providers"].(map[string]interface{}); ok {
+		if gcp, ok := cloud["gcp"].(map[string]interface{}); ok {
+			renameSetting(gcp, "credentials_file", "service_account_path")
+		}
+	}
+
+	if ai, ok := cfg["ai"].(map[string]interface{}); ok {
+		agents, _ := cfg["agents"].(map[string]interface{})
+		if agents == nil {
+			agents = map[string]interface{}{}
+		}
+		for name, settings := range ai {
+			agent, ok := settings.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if _, ok := agent["type"]; !ok {
+				agent["type"] = "general"
+				if isAgentType(name) {
+					agent["type"] = name
+				}
+			}
+			agents[name] = mergeLegacy(agent, agents[name])
+		}
+		cfg["agents"] = agents
+		delete(cfg, "ai")
+	}
+
+	if monitoring, ok := cfg["monitoring"].(map[string]interface{}); ok {
+		for _, tool := range monitoring {
+			if settings, ok := tool.(map[string]interface{}); ok {
+				renameSetting(settings, "url", "endpoint")
+			}
+		}
+	}
+
+	if security, ok := cfg["security"].(map[string]interface{}); ok {
+		renameSetting(security, "audit_log", "audit_logging")
+		if _, ok := security["encryption_key"]; ok {
+			logrus.Warn("security.encryption_key is no longer used and has been removed; encryption keys are kept in the key store")
+			delete(security, "encryption_key")
+		}
+	}
+}
+
+// mergeLegacy merges settings in the current layout over legacy ones
+func mergeLegacy(legacy, current interface{}) interface{} {
+	if current == nil {
+		return legacy
+	}
+	return mergeSettings(legacy, current)
+}
+
+// renameSetting moves settings[from] to settings[to] unless to is already set
+func renameSetting(settings map[string]interface{}, from, to string) {
+	value, ok := settings[from]
+	if !ok {
+		return
+	}
+	if _, exists := settings[to]; !exists {
+		settings[to] = value
+	}
+	delete(settings, from)
+}
+
+// deepCopySettings copies settings so migrations never modify their input
+func deepCopySettings(settings map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		if nested, ok := v.(map[string]interface{}); ok {
+			v = deepCopySettings(nested)
+		}
+		copied[k] = v
+	}
+	return copied
+}
+
+// compareVersions compares dotted numeric versions, treating missing
+// components as 0; it returns -1, 0 or 1
+func compareVersions(a, b string) (int, error) {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		var err error
+		if i < len(as) {
+			if x, err = strconv.Atoi(as[i]); err != nil || x < 0 {
+				return 0, fmt.Errorf("invalid version %q", a)
+			}
+		}
+		if i < len(bs) {
+			if y, err = strconv.Atoi(bs[i]); err != nil || y < 0 {
+				return 0, fmt.Errorf("invalid version %q", b)
+			}
+		}
+		switch {
+		case x < y:
+			return -1, nil
+		case x > y:
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ProfileSections lists the top-level sections a profile may override
+var ProfileSections = []string{"agents", "cloud_providers", "monitoring"}
+
+// ActiveProfile returns the profile selected with --profile or ALLORA_PROFILE,
+// or "" when the base configuration is used
+func ActiveProfile() string {
+	return strings.ToLower(strings.TrimSpace(viper.GetString("profile")))
+}
+
+// applyProfile merges the named profile's sections over the base settings
+func applyProfile(settings map[string]interface{}, name string) (map[string]interface{}, error) {
+	profiles, _ := settings["profiles"].(map[string]interface{})
+	overrides, ok := profiles[name].(map[string]interface{})
+	if !ok {
+		if _, exists := profiles[name]; !exists {
+			return nil, fmt.Errorf("profile %q is not defined; available profiles: %s", name, profileNames(profiles))
+		}
+		// An empty profile leaves the base configuration unchanged
+		overrides = map[string]interface{}{}
+	}
+
+	merged := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		merged[k] = v
+	}
+	for _, section := range ProfileSections {
+		if override, exists := overrides[section]; exists {
+			merged[section] = mergeSettings(merged[section], override)
+		}
+	}
+	return merged, nil
+}
+
+// mergeSettings deep-merges override over base; maps merge key by key and
+// any other override value replaces the base value
+func mergeSettings(base, override interface{}) interface{} {
+	overrideMap, ok := override.(map[string]interface{})
+	if !ok {
+		return override
+	}
+	baseMap, ok := base.(map[string]interface{})
+	if !ok {
+		return overrideMap
+	}
+
+	merged := make(map[string]interface{}, len(baseMap)+len(overrideMap))
+	for k, v := range baseMap {
+		merged[k] = v
+	}
+	for k, v := range overrideMap {
+		merged[k] = mergeSettings(merged[k], v)
+	}
+	return merged
+}
+
+// profileNames lists the defined profiles for error messages
+func profileNames(profiles map[string]interface{}) string {
+	if len(profiles) == 0 {
+		return "none"
+	}
+
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
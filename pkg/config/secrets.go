@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EncryptedPrefix marks an encrypted value in a saved configuration file;
+// values without it are read as plaintext so older configs still load
+const EncryptedPrefix = "enc:v1:"
+
+// SecretCodec encrypts and decrypts the sensitive values of a configuration.
+// EncryptSensitiveData decides which fields are sensitive and must mark the
+// values it encrypts with EncryptedPrefix
+type SecretCodec interface {
+	EncryptSensitiveData(data map[string]interface{}) (map[string]interface{}, error)
+	DecryptSensitiveData(data map[string]interface{}) (map[string]interface{}, error)
+}
+
+var (
+	secretCodecMu      sync.Mutex
+	secretCodecFactory func() (SecretCodec, error)
+	secretCodec        SecretCodec
+)
+
+// RegisterSecretCodec sets how the codec used by Save and Load is created.
+// The factory runs the first time a secret needs encrypting or decrypting
+func RegisterSecretCodec(factory func() (SecretCodec, error)) {
+	secretCodecMu.Lock()
+	defer secretCodecMu.Unlock()
+
+	secretCodecFactory = factory
+	secretCodec = nil
+}
+
+// getSecretCodec returns the registered codec, or nil if none is registered
+func getSecretCodec() (SecretCodec, error) {
+	secretCodecMu.Lock()
+	defer secretCodecMu.Unlock()
+
+	if secretCodec == nil && secretCodecFactory != nil {
+		codec, err := secretCodecFactory()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize config encryption: %w", err)
+		}
+		secretCodec = codec
+	}
+	return secretCodec, nil
+}
+
+// encryptSecrets encrypts the sensitive values of a marshalled configuration,
+// keeping the document's layout. Without a registered codec it is unchanged
+func encryptSecrets(data []byte) ([]byte, error) {
+	codec, err := getSecretCodec()
+	if err != nil || codec == nil {
+		return data, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if err := encryptNode(&doc, codec); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(&doc)
+}
+
+// encryptNode passes each scalar mapping value under node to the codec
+func encryptNode(node *yaml.Node, codec SecretCodec) error {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if value.Kind != yaml.ScalarNode || value.Tag != "!!str" || value.Value == "" || strings.HasPrefix(value.Value, EncryptedPrefix) {
+				if err := encryptNode(value, codec); err != nil {
+					return err
+				}
+				continue
+			}
+
+			encrypted, err := codec.EncryptSensitiveData(map[string]interface{}{key.Value: value.Value})
+			if err != nil {
+				return fmt.Errorf("failed to encrypt %s: %w", key.Value, err)
+			}
+			if s, ok := encrypted[key.Value].(string); ok && s != value.Value {
+				value.Value = s
+				value.Style = 0
+			}
+		}
+		return nil
+	}
+
+	for _, child := range node.Content {
+		if err := encryptNode(child, codec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasEncryptedValues reports whether any string in settings is encrypted
+func hasEncryptedValues(settings interface{}) bool {
+	switch v := settings.(type) {
+	case string:
+		return strings.HasPrefix(v, EncryptedPrefix)
+	case map[string]interface{}:
+		for _, child := range v {
+			if hasEncryptedValues(child) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if hasEncryptedValues(child) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decryptSettings decrypts the encrypted values in settings
+func decryptSettings(settings map[string]interface{}) (map[string]interface{}, error) {
+	codec, err := getSecretCodec()
+	if err != nil {
+		return nil, err
+	}
+	if codec == nil {
+		return nil, fmt.Errorf("config contains encrypted values but no decryption is available")
+	}
+
+	decrypted, err := codec.DecryptSensitiveData(settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt config: %w", err)
+	}
+	return decrypted, nil
+}
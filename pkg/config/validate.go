@@ -0,0 +1,323 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// AgentTypes lists the agent types AlloraCLI can create
+var AgentTypes = []string{"general", "openai", "aws", "azure", "gcp", "kubernetes", "monitoring"}
+
+// Agent providers are the model backends an agent can use
+const (
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+)
+
+// AgentProviders lists the model backends an agent can use
+var AgentProviders = []string{ProviderOpenAI, ProviderOllama}
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Problems []string
+}
+
+// Error lists the problems one per line
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return "invalid configuration: " + e.Problems[0]
+	}
+	return fmt.Sprintf("invalid configuration (%d problems):\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// validator collects configuration problems
+type validator struct {
+	problems []string
+}
+
+// addf records a problem with the setting at key
+func (v *validator) addf(key, format string, args ...interface{}) {
+	v.problems = append(v.problems, key+": "+fmt.Sprintf(format, args...))
+}
+
+// required records a problem if value is empty
+func (v *validator) required(key, value, reason string) {
+	if strings.TrimSpace(value) == "" {
+		v.addf(key, "is required %s", reason)
+	}
+}
+
+// together records a problem if only one of two settings is set
+func (v *validator) together(keyA, valueA, keyB, valueB string) {
+	switch {
+	case valueA != "" && valueB == "":
+		v.addf(keyB, "is required when %s is set", keyA)
+	case valueA == "" && valueB != "":
+		v.addf(keyA, "is required when %s is set", keyB)
+	}
+}
+
+// endpoint records a problem if value is set but is not an http(s) URL with a valid port
+func (v *validator) endpoint(key, value string) {
+	if value == "" {
+		return
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		v.addf(key, "%q is not a valid URL: %v", value, err)
+		return
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		v.addf(key, "%q must be an http:// or https:// URL", value)
+		return
+	}
+	if u.Hostname() == "" {
+		v.addf(key, "%q has no host", value)
+		return
+	}
+	if port := u.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			v.addf(key, "%q has invalid port %s; ports must be between 1 and 65535", value, port)
+		}
+	} else if strings.HasSuffix(u.Host, ":") {
+		v.addf(key, "%q has an empty port", value)
+	}
+}
+
+// hostPort validates an optional host:port listen address
+func (v *validator) hostPort(key, addr, example string) {
+	if addr == "" {
+		return
+	}
+	if _, port, err := net.SplitHostPort(addr); err != nil {
+		v.addf(key, "%q must be host:port, such as %s", addr, example)
+	} else if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		v.addf(key, "%q has invalid port %s; ports must be between 0 and 65535", addr, port)
+	}
+}
+
+// webhook records problems with a notification webhook's URL and minimum severity
+func (v *validator) webhook(key string, sink WebhookConfig) {
+	v.endpoint(key+".url", sink.URL)
+	if sink.MinSeverity != "" && !isSeverity(sink.MinSeverity) {
+		v.addf(key+".min_severity", "unknown severity %q; use one of %s", sink.MinSeverity, strings.Join(Severities, ", "))
+	}
+}
+
+// Validate checks a configuration and returns a *ValidationError listing
+// every problem found, or nil if it is valid
+func Validate(cfg *Config) error {
+	v := &validator{}
+
+	names := make([]string, 0, len(cfg.Agents))
+	for name := range cfg.Agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		agent := cfg.Agents[name]
+		key := "agents." + name
+
+		if agent.Type == "" {
+			v.addf(key+".type", "is required; use one of %s", strings.Join(AgentTypes, ", "))
+		} else if !isAgentType(agent.Type) {
+			v.addf(key+".type", "unknown agent type %q; use one of %s", agent.Type, strings.Join(AgentTypes, ", "))
+		}
+		if agent.Provider != "" && !slices.Contains(AgentProviders, agent.Provider) {
+			v.addf(key+".provider", "unknown provider %q; use one of %s", agent.Provider, strings.Join(AgentProviders, ", "))
+		}
+		if agent.MaxTokens <= 0 {
+			v.addf(key+".max_tokens", "must be greater than 0, got %d", agent.MaxTokens)
+		}
+		if agent.Temperature < 0 || agent.Temperature > 1 {
+			v.addf(key+".temperature", "must be between 0 and 1, got %g", agent.Temperature)
+		}
+		if agent.MaxContextTokens < 0 {
+			v.addf(key+".max_context_tokens", "must not be negative, got %d", agent.MaxContextTokens)
+		}
+		if agent.CacheTTLSeconds < 0 {
+			v.addf(key+".cache_ttl_seconds", "must not be negative, got %d", agent.CacheTTLSeconds)
+		}
+		v.endpoint(key+".endpoint", agent.Endpoint)
+	}
+
+	aws := cfg.CloudProviders.AWS
+	if aws != (AWSConfig{}) {
+		v.required("cloud_providers.aws.region", aws.Region, "when the aws provider is configured")
+		v.together("cloud_providers.aws.access_key_id", aws.AccessKeyID, "cloud_providers.aws.secret_access_key", aws.SecretKey)
+	}
+
+	azure := cfg.CloudProviders.Azure
+	if azure != (AzureConfig{}) {
+		v.required("cloud_providers.azure.subscription_id", azure.SubscriptionID, "when the azure provider is configured")
+		v.required("cloud_providers.azure.tenant_id", azure.TenantID, "when the azure provider is configured")
+		v.together("cloud_providers.azure.client_id", azure.ClientID, "cloud_providers.azure.client_secret", azure.ClientSecret)
+	}
+
+	gcp := cfg.CloudProviders.GCP
+	if gcp.ProjectID != "" || gcp.ServiceAccountPath != "" {
+		v.required("cloud_providers.gcp.project_id", gcp.ProjectID, "when the gcp provider is configured")
+		v.required("cloud_providers.gcp.region", gcp.Region, "when the gcp provider is configured")
+	}
+
+	if ttl := cfg.CloudProviders.CacheTTL; ttl != "" {
+		if d, err := time.ParseDuration(ttl); err != nil || d < 0 {
+			v.addf("cloud_providers.cache_ttl", "%q must be a duration such as 5m, or 0 to turn caching off", ttl)
+		}
+	}
+
+	v.endpoint("monitoring.prometheus.endpoint", cfg.Monitoring.Prometheus.Endpoint)
+	v.endpoint("monitoring.grafana.endpoint", cfg.Monitoring.Grafana.Endpoint)
+	services := make([]string, 0, len(cfg.Monitoring.Services))
+	for name := range cfg.Monitoring.Services {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+	for _, name := range services {
+		service := cfg.Monitoring.Services[name]
+		key := "monitoring.services." + name
+		for i, endpoint := range service.Endpoints {
+			if endpoint == "" {
+				v.addf(fmt.Sprintf("%s.endpoints[%d]", key, i), "must not be empty")
+			}
+			v.endpoint(fmt.Sprintf("%s.endpoints[%d]", key, i), endpoint)
+		}
+		if timeout := service.Timeout; timeout != "" {
+			if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+				v.addf(key+".timeout", "%q must be a positive duration such as 5s", timeout)
+			}
+		}
+	}
+	if retention := cfg.Monitoring.History.Retention; retention != "" {
+		if d, err := model.ParseDuration(retention); err != nil || d <= 0 {
+			v.addf("monitoring.history.retention", "%q must be a positive duration such as 30d", retention)
+		}
+	}
+	v.endpoint("plugins.registry", cfg.Plugins.Registry)
+	for i, key := range cfg.Security.PluginPublicKeys {
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(strings.TrimSpace(key), "ed25519:"))
+		if err != nil || len(data) != ed25519.PublicKeySize {
+			v.addf(fmt.Sprintf("security.plugin_public_keys[%d]", i), "must be a base64 Ed25519 public key")
+		}
+	}
+	if cfg.Security.RequireSignedPlugins && len(cfg.Security.PluginPublicKeys) == 0 {
+		v.addf("security.plugin_public_keys", "is required when security.require_signed_plugins is on")
+	}
+	templates := make([]string, 0, len(cfg.Templates))
+	for name := range cfg.Templates {
+		templates = append(templates, name)
+	}
+	sort.Strings(templates)
+	for _, name := range templates {
+		if _, err := template.New(name).Parse(cfg.Templates[name]); err != nil {
+			v.addf("templates."+name, "is not a valid template: %v", err)
+		}
+	}
+	for i, pattern := range cfg.Security.RedactionPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			v.addf(fmt.Sprintf("security.redaction_patterns[%d]", i), "is not a valid regular expression: %v", err)
+		}
+	}
+	v.webhook("notifications.slack", cfg.Notifications.Slack)
+	v.webhook("notifications.webhook", cfg.Notifications.Webhook)
+	if pd := cfg.Notifications.PagerDuty; pd.MinSeverity != "" && !isSeverity(pd.MinSeverity) {
+		v.addf("notifications.pagerduty.min_severity", "unknown severity %q; use one of %s", pd.MinSeverity, strings.Join(Severities, ", "))
+	}
+	if email := cfg.Notifications.Email; email.Host != "" || len(email.Recipients) > 0 {
+		v.required("notifications.email.host", email.Host, "to mail reports")
+		v.required("notifications.email.from", email.From, "to mail reports")
+		if email.Port < 0 || email.Port > 65535 {
+			v.addf("notifications.email.port", "must be between 1 and 65535, got %d", email.Port)
+		}
+		if email.TLS != "" && !slices.Contains(EmailTLSModes, email.TLS) {
+			v.addf("notifications.email.tls", "unknown TLS mode %q; use one of %s", email.TLS, strings.Join(EmailTLSModes, ", "))
+		}
+	}
+
+	v.hostPort("server.address", cfg.Server.Address, "127.0.0.1:8080")
+	if cfg.Security.RotationPeriod < 0 {
+		v.addf("security.rotation_period", "must not be negative, got %d", cfg.Security.RotationPeriod)
+	}
+	v.hostPort("security.syslog.address", cfg.Security.Syslog.Address, "0.0.0.0:5514")
+	if protocol := cfg.Security.Syslog.Protocol; protocol != "" && !slices.Contains(SyslogProtocols, protocol) {
+		v.addf("security.syslog.protocol", "unknown protocol %q; use one of %s", protocol, strings.Join(SyslogProtocols, ", "))
+	}
+
+	profiles := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+
+	for _, name := range profiles {
+		sections := make([]string, 0, len(cfg.Profiles[name]))
+		for section := range cfg.Profiles[name] {
+			sections = append(sections, section)
+		}
+		sort.Strings(sections)
+
+		for _, section := range sections {
+			if !isProfileSection(section) {
+				v.addf("profiles."+name+"."+section, "profiles can only override %s", strings.Join(ProfileSections, ", "))
+			}
+		}
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+// Severities lists the severities notification sinks can filter on, least severe first
+var Severities = []string{"info", "low", "warning", "medium", "error", "high", "critical"}
+
+// SyslogProtocols lists the transports security.syslog can listen on
+var SyslogProtocols = []string{"udp", "tcp", "both"}
+
+// EmailTLSModes lists how the connection to notifications.email.host can be secured
+var EmailTLSModes = []string{"starttls", "tls", "none"}
+
+// isSeverity reports whether s is a known severity
+func isSeverity(s string) bool {
+	for _, known := range Severities {
+		if strings.EqualFold(s, known) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAgentType reports whether t is a known agent type
+func isAgentType(t string) bool {
+	for _, known := range AgentTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// isProfileSection reports whether a profile may override section
+func isProfileSection(section string) bool {
+	for _, known := range ProfileSections {
+		if section == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package deploy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// dependsOnKey is the PlannedResource metadata key listing, comma separated,
+// the names of the resources it depends on
+const dependsOnKey = "depends_on"
+
+// resourceDependencies returns the dependencies declared in a resource's metadata
+func resourceDependencies(resource PlannedResource) []string {
+	var deps []string
+	for _, dep := range strings.Split(resource.Metadata[dependsOnKey], ",") {
+		if dep = strings.TrimSpace(dep); dep != "" {
+			deps = append(deps, dep)
+		}
+	}
+	return deps
+}
+
+// ResolveApplyOrder topologically sorts resources so each one comes after the
+// resources it depends on, keeping the input order where there is a choice.
+// Dependencies on resources outside the plan are ignored; a dependency cycle
+// is reported as an error naming the resources in the cycle
+func ResolveApplyOrder(resources []PlannedResource) ([]string, error) {
+	index := make(map[string]int, len(resources))
+	for i, resource := range resources {
+		if _, exists := index[resource.Name]; exists {
+			return nil, fmt.Errorf("duplicate resource in plan: %s", resource.Name)
+		}
+		index[resource.Name] = i
+	}
+
+	dependents := make([][]int, len(resources))
+	pending := make([]int, len(resources))
+	for i, resource := range resources {
+		seen := map[int]bool{}
+		for _, dep := range resourceDependencies(resource) {
+			j, exists := index[dep]
+			if !exists || seen[j] {
+				continue
+			}
+			if j == i {
+				return nil, fmt.Errorf("dependency cycle detected: %s -> %s", resource.Name, resource.Name)
+			}
+			seen[j] = true
+			dependents[j] = append(dependents[j], i)
+			pending[i]++
+		}
+	}
+
+	order := make([]string, 0, len(resources))
+	done := make([]bool, len(resources))
+	for len(order) < len(resources) {
+		next := -1
+		for i := range resources {
+			if !done[i] && pending[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, fmt.Errorf("dependency cycle detected: %s", strings.Join(findCycle(resources, index, done), " -> "))
+		}
+
+		done[next] = true
+		order = append(order, resources[next].Name)
+		for _, dependent := range dependents[next] {
+			pending[dependent]--
+		}
+	}
+	return order, nil
+}
+
+// findCycle returns one dependency cycle among the unresolved resources,
+// starting and ending with the same resource name
+func findCycle(resources []PlannedResource, index map[string]int, done []bool) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(resources))
+	var stack []int
+	var cycle []string
+
+	var visit func(i int) bool
+	visit = func(i int) bool {
+		state[i] = visiting
+		stack = append(stack, i)
+
+		deps := resourceDependencies(resources[i])
+		sort.Strings(deps)
+		for _, dep := range deps {
+			j, exists := index[dep]
+			if !exists || done[j] {
+				continue
+			}
+			if state[j] == visiting {
+				for k := len(stack) - 1; k >= 0; k-- {
+					if stack[k] == j {
+						for _, n := range stack[k:] {
+							cycle = append(cycle, resources[n].Name)
+						}
+						cycle = append(cycle, resources[j].Name)
+						return true
+					}
+				}
+			}
+			if state[j] == unvisited && visit(j) {
+				return true
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[i] = visited
+		return false
+	}
+
+	for i := range resources {
+		if !done[i] && state[i] == unvisited && visit(i) {
+			return cycle
+		}
+	}
+	return nil
+}
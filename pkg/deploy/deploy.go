@@ -1,6 +1,7 @@
 package deploy
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -9,12 +10,12 @@ import (
 
 // Deployer interface defines deployment operations
 type Deployer interface {
-	DeployInfrastructure(options InfraOptions) (*DeploymentResult, error)
-	DeployApplication(options AppOptions) (*DeploymentResult, error)
-	ListDeployments() ([]*Deployment, error)
-	GetDeploymentStatus(id string) (*DeploymentStatus, error)
-	RollbackDeployment(id, version string) (*RollbackResult, error)
-	GeneratePlan(options PlanOptions) (*DeploymentPlan, error)
+	DeployInfrastructure(ctx context.Context, options InfraOptions) (*DeploymentResult, error)
+	DeployApplication(ctx context.Context, options AppOptions) (*DeploymentResult, error)
+	ListDeployments(ctx context.Context) ([]*Deployment, error)
+	GetDeploymentStatus(ctx context.Context, id string) (*DeploymentStatus, error)
+	RollbackDeployment(ctx context.Context, id, version string) (*RollbackResult, error)
+	GeneratePlan(ctx context.Context, options PlanOptions) (*DeploymentPlan, error)
 }
 
 // InfraOptions represents infrastructure deployment options
@@ -33,10 +34,12 @@ type AppOptions struct {
 	Strategy    string `json:"strategy" yaml:"strategy"`
 }
 
-// PlanOptions represents deployment plan options
+// PlanOptions represents deployment plan options. Template is a Terraform
+// configuration directory or a file within one; it defaults to the current directory
 type PlanOptions struct {
-	Template string `json:"template" yaml:"template"`
-	Optimize bool   `json:"optimize" yaml:"optimize"`
+	Template  string            `json:"template" yaml:"template"`
+	Optimize  bool              `json:"optimize" yaml:"optimize"`
+	Variables map[string]string `json:"variables" yaml:"variables"`
 }
 
 // DeploymentResult represents the result of a deployment
@@ -96,10 +99,12 @@ type RollbackResult struct {
 	Timestamp   time.Time         `json:"timestamp" yaml:"timestamp"`
 }
 
-// DeploymentPlan represents a deployment plan
+// DeploymentPlan represents a deployment plan. Order lists resource names in
+// the order they must be applied so dependencies come first
 type DeploymentPlan struct {
 	Actions   []PlannedAction   `json:"actions" yaml:"actions"`
 	Resources []PlannedResource `json:"resources" yaml:"resources"`
+	Order     []string          `json:"order" yaml:"order"`
 	Estimated EstimatedImpact   `json:"estimated" yaml:"estimated"`
 	Warnings  []string          `json:"warnings" yaml:"warnings"`
 	Metadata  map[string]string `json:"metadata" yaml:"metadata"`
@@ -150,32 +155,76 @@ func New() (Deployer, error) {
 	}, nil
 }
 
-// DeployInfrastructure deploys infrastructure
-func (d *DeployerImpl) DeployInfrastructure(options InfraOptions) (*DeploymentResult, error) {
-	// Mock implementation
+// DeployInfrastructure plans options.Template with Terraform and applies the
+// planned resources one at a time in dependency order. DryRun returns the
+// order without applying anything. When ctx is cancelled mid-apply the
+// resources applied so far are returned with a cancelled status and the error
+func (d *DeployerImpl) DeployInfrastructure(ctx context.Context, options InfraOptions) (*DeploymentResult, error) {
+	start := time.Now()
+
+	plan, err := d.GeneratePlan(ctx, PlanOptions{
+		Template:  options.Template,
+		Optimize:  options.Optimize,
+		Variables: options.Variables,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	result := &DeploymentResult{
 		ID:        fmt.Sprintf("deploy-%d", time.Now().Unix()),
 		Status:    "success",
-		Message:   "Infrastructure deployed successfully",
-		Resources: []string{"vpc-123", "subnet-456", "security-group-789"},
-		Duration:  5 * time.Minute,
+		Resources: []string{},
 		Metadata: map[string]string{
 			"template": options.Template,
 			"optimize": fmt.Sprintf("%t", options.Optimize),
 		},
-		Timestamp: time.Now(),
 	}
 
 	if options.DryRun {
 		result.Status = "planned"
-		result.Message = "Dry run completed - no resources deployed"
+		result.Message = fmt.Sprintf("Dry run completed - %d resources would be applied in dependency order", len(plan.Order))
+		result.Resources = plan.Order
+		result.Duration = time.Since(start)
+		result.Timestamp = time.Now()
+		return result, nil
+	}
+
+	actions := make(map[string]string, len(plan.Resources))
+	for _, resource := range plan.Resources {
+		actions[resource.Name] = resource.Action
 	}
 
+	dir := plan.Metadata["directory"]
+	for _, name := range plan.Order {
+		if action := actions[name]; action == "read" || action == "no-op" {
+			continue
+		}
+		err := ctx.Err()
+		if err == nil {
+			err = terraformApply(ctx, dir, name, options.Variables)
+		}
+		if ctx.Err() != nil {
+			result.Status = "cancelled"
+			result.Message = fmt.Sprintf("Cancelled at %s; applied %d of %d resources", name, len(result.Resources), len(plan.Order))
+			result.Duration = time.Since(start)
+			result.Timestamp = time.Now()
+			return result, fmt.Errorf("deployment cancelled after applying %d of %d resources: %w", len(result.Resources), len(plan.Order), ctx.Err())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply %s after applying %d of %d resources: %w", name, len(result.Resources), len(plan.Order), err)
+		}
+		result.Resources = append(result.Resources, name)
+	}
+
+	result.Message = fmt.Sprintf("Applied %d resources in dependency order", len(result.Resources))
+	result.Duration = time.Since(start)
+	result.Timestamp = time.Now()
 	return result, nil
 }
 
 // DeployApplication deploys an application
-func (d *DeployerImpl) DeployApplication(options AppOptions) (*DeploymentResult, error) {
+func (d *DeployerImpl) DeployApplication(ctx context.Context, options AppOptions) (*DeploymentResult, error) {
 	// Mock implementation
 	result := &DeploymentResult{
 		ID:        fmt.Sprintf("app-deploy-%d", time.Now().Unix()),
@@ -195,7 +244,7 @@ func (d *DeployerImpl) DeployApplication(options AppOptions) (*DeploymentResult,
 }
 
 // ListDeployments lists all deployments
-func (d *DeployerImpl) ListDeployments() ([]*Deployment, error) {
+func (d *DeployerImpl) ListDeployments(ctx context.Context) ([]*Deployment, error) {
 	// Mock implementation
 	deployments := []*Deployment{
 		{
@@ -226,7 +275,7 @@ func (d *DeployerImpl) ListDeployments() ([]*Deployment, error) {
 }
 
 // GetDeploymentStatus gets the status of a specific deployment
-func (d *DeployerImpl) GetDeploymentStatus(id string) (*DeploymentStatus, error) {
+func (d *DeployerImpl) GetDeploymentStatus(ctx context.Context, id string) (*DeploymentStatus, error) {
 	// Mock implementation
 	status := &DeploymentStatus{
 		ID:       id,
@@ -257,74 +306,39 @@ func (d *DeployerImpl) GetDeploymentStatus(id string) (*DeploymentStatus, error)
 	return status, nil
 }
 
-// RollbackDeployment rolls back a deployment
-func (d *DeployerImpl) RollbackDeployment(id, version string) (*RollbackResult, error) {
-	// Mock implementation
-	result := &RollbackResult{
-		ID:          id,
-		Status:      "success",
-		Message:     "Deployment rolled back successfully",
-		FromVersion: "v1.2.3",
-		ToVersion:   version,
-		Duration:    1 * time.Minute,
-		Metadata:    map[string]string{"rollback_reason": "manual"},
-		Timestamp:   time.Now(),
+// RollbackDeployment rolls a Kubernetes Deployment, identified as
+// "[namespace/]name", back to a previous revision. An empty version selects
+// the revision before the current one
+func (d *DeployerImpl) RollbackDeployment(ctx context.Context, id, version string) (*RollbackResult, error) {
+	if id == "" {
+		return nil, fmt.Errorf("deployment id is required")
 	}
+	return rollbackKubernetesDeployment(ctx, id, version)
+}
 
-	if version == "" {
-		result.ToVersion = "v1.2.2"
+// GeneratePlan runs `terraform plan` against options.Template and converts
+// the planned changes into a deployment plan with an estimated monthly cost
+func (d *DeployerImpl) GeneratePlan(ctx context.Context, options PlanOptions) (*DeploymentPlan, error) {
+	dir, err := terraformDir(options.Template)
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
-}
+	stream, show, err := terraformPlan(ctx, dir, options.Variables)
+	if err != nil {
+		return nil, err
+	}
 
-// GeneratePlan generates a deployment plan
-func (d *DeployerImpl) GeneratePlan(options PlanOptions) (*DeploymentPlan, error) {
-	// Mock implementation
-	plan := &DeploymentPlan{
-		Actions: []PlannedAction{
-			{
-				Type:        "create",
-				Resource:    "deployment",
-				Action:      "create",
-				Description: "Create new deployment",
-				Risk:        "low",
-				Metadata:    map[string]string{"replicas": "3"},
-			},
-			{
-				Type:        "update",
-				Resource:    "service",
-				Action:      "update",
-				Description: "Update service configuration",
-				Risk:        "medium",
-				Metadata:    map[string]string{"port": "8080"},
-			},
-		},
-		Resources: []PlannedResource{
-			{
-				Name:     "web-app",
-				Type:     "deployment",
-				Action:   "create",
-				Changes:  []string{"image: nginx:latest", "replicas: 3"},
-				Metadata: map[string]string{"namespace": "default"},
-			},
-		},
-		Estimated: EstimatedImpact{
-			Duration:   3 * time.Minute,
-			Downtime:   10 * time.Second,
-			Cost:       12.50,
-			Complexity: "medium",
-		},
-		Warnings: []string{
-			"Service will experience brief downtime during update",
-			"Resource limits not specified",
-		},
-		Metadata: map[string]string{
-			"template": options.Template,
-			"optimize": fmt.Sprintf("%t", options.Optimize),
-		},
-		Timestamp: time.Now(),
+	plan, err := buildTerraformPlan(stream, show)
+	if err != nil {
+		return nil, err
+	}
+	if err := estimatePlanCost(plan, show); err != nil {
+		return nil, err
 	}
+	plan.Metadata["template"] = options.Template
+	plan.Metadata["directory"] = dir
+	plan.Metadata["optimize"] = fmt.Sprintf("%t", options.Optimize)
 
 	return plan, nil
 }
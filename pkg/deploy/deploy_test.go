@@ -0,0 +1,474 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const planStreamFixture = `{"@level":"info","@message":"Terraform 1.6.0","type":"version","terraform":"1.6.0","ui":"1.2"}
+{"@level":"warn","@message":"Warning: Deprecated attribute","type":"diagnostic","diagnostic":{"severity":"warning","summary":"Deprecated attribute","detail":"The attribute \"acl\" is deprecated."}}
+{"@level":"info","@message":"aws_s3_bucket.logs: Drift detected (update)","type":"resource_drift","change":{"resource":{"addr":"aws_s3_bucket.logs","resource_type":"aws_s3_bucket","resource_name":"logs","implied_provider":"aws"},"action":"update"}}
+{"@level":"info","@message":"aws_vpc.main: Plan to create","type":"planned_change","change":{"resource":{"addr":"aws_vpc.main","resource_type":"aws_vpc","resource_name":"main","implied_provider":"aws"},"action":"create"}}
+{"@level":"info","@message":"aws_instance.web: Plan to update","type":"planned_change","change":{"resource":{"addr":"aws_instance.web","resource_type":"aws_instance","resource_name":"web","implied_provider":"aws"},"action":"update"}}
+{"@level":"info","@message":"aws_db_instance.db: Plan to replace","type":"planned_change","change":{"resource":{"addr":"aws_db_instance.db","resource_type":"aws_db_instance","resource_name":"db","implied_provider":"aws"},"action":"replace","reason":"cannot_update"}}
+{"@level":"info","@message":"Plan: 2 to add, 1 to change, 1 to destroy.","type":"change_summary","changes":{"add":2,"change":1,"remove":1,"operation":"plan"}}
+`
+
+const planShowFixture = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {
+      "address": "aws_vpc.main",
+      "type": "aws_vpc",
+      "name": "main",
+      "provider_name": "registry.terraform.io/hashicorp/aws",
+      "change": {
+        "actions": ["create"],
+        "before": null,
+        "after": {"cidr_block": "10.0.0.0/16", "tags": null},
+        "after_unknown": {"id": true, "arn": true},
+        "before_sensitive": false,
+        "after_sensitive": {}
+      }
+    },
+    {
+      "address": "aws_instance.web",
+      "type": "aws_instance",
+      "name": "web",
+      "provider_name": "registry.terraform.io/hashicorp/aws",
+      "change": {
+        "actions": ["update"],
+        "before": {"instance_type": "t3.micro", "ami": "ami-123", "user_data": "old"},
+        "after": {"instance_type": "t3.large", "ami": "ami-123", "user_data": "new"},
+        "after_unknown": {},
+        "before_sensitive": {"user_data": true},
+        "after_sensitive": {"user_data": true}
+      }
+    }
+  ],
+  "configuration": {
+    "root_module": {
+      "resources": [
+        {"address": "aws_vpc.main", "expressions": {"cidr_block": {"references": ["var.cidr"]}}},
+        {"address": "aws_instance.web", "expressions": {"network_interface": [{"subnet_id": {"references": ["aws_vpc.main.id", "aws_vpc.main"]}}]}},
+        {"address": "aws_db_instance.db", "depends_on": ["aws_instance.web"]}
+      ]
+    }
+  }
+}`
+
+// installFakeTerraform serves fixture plan output and returns the path that
+// the arguments of each apply are appended to
+func installFakeTerraform(t *testing.T, stream, show string, exitCode int) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake terraform script requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	streamFile := filepath.Join(dir, "plan.jsonl")
+	showFile := filepath.Join(dir, "show.json")
+	if err := os.WriteFile(streamFile, []byte(stream), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	if err := os.WriteFile(showFile, []byte(show), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	script := `#!/bin/sh
+case "$2" in
+plan) cat ` + streamFile + `; exit ` + strconv.Itoa(exitCode) + ` ;;
+show) cat ` + showFile + ` ;;
+apply) echo "$@" >> ` + filepath.Join(dir, "applied.log") + ` ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, terraformBinary), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake terraform: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return filepath.Join(dir, "applied.log")
+}
+
+func TestGeneratePlanTerraform(t *testing.T) {
+	installFakeTerraform(t, planStreamFixture, planShowFixture, 0)
+	deployer := &DeployerImpl{}
+
+	plan, err := deployer.GeneratePlan(context.Background(), PlanOptions{Template: t.TempDir()})
+	if err != nil {
+		t.Fatalf("GeneratePlan() failed: %v", err)
+	}
+
+	if len(plan.Actions) != 3 || len(plan.Resources) != 3 {
+		t.Fatalf("expected 3 actions and resources, got %d and %d", len(plan.Actions), len(plan.Resources))
+	}
+
+	wantActions := []struct{ resource, action, risk string }{
+		{"aws_vpc.main", "create", "low"},
+		{"aws_instance.web", "update", "medium"},
+		{"aws_db_instance.db", "replace", "high"},
+	}
+	for i, want := range wantActions {
+		got := plan.Actions[i]
+		if got.Resource != want.resource || got.Action != want.action || got.Risk != want.risk {
+			t.Errorf("action %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+
+	vpc := plan.Resources[0]
+	if strings.Join(vpc.Changes, "\n") != "arn: null -> (known after apply)\ncidr_block: null -> \"10.0.0.0/16\"\nid: null -> (known after apply)" {
+		t.Errorf("unexpected create changes: %q", vpc.Changes)
+	}
+
+	web := plan.Resources[1]
+	if strings.Join(web.Changes, "\n") != "instance_type: \"t3.micro\" -> \"t3.large\"\nuser_data: (sensitive) -> (sensitive)" {
+		t.Errorf("unexpected update changes: %q", web.Changes)
+	}
+	if plan.Resources[2].Metadata["reason"] != "cannot_update" {
+		t.Errorf("expected the replace reason in metadata, got %+v", plan.Resources[2].Metadata)
+	}
+
+	if web.Metadata[dependsOnKey] != "aws_vpc.main" || plan.Resources[2].Metadata[dependsOnKey] != "aws_instance.web" {
+		t.Errorf("expected dependencies from references and depends_on, got %q and %q", web.Metadata[dependsOnKey], plan.Resources[2].Metadata[dependsOnKey])
+	}
+	if strings.Join(plan.Order, ",") != "aws_vpc.main,aws_instance.web,aws_db_instance.db" {
+		t.Errorf("unexpected apply order: %v", plan.Order)
+	}
+
+	if plan.Estimated.Cost != 60.74 || web.Metadata["monthly_cost"] != "60.74" {
+		t.Errorf("expected the t3.large to cost 60.74 a month, got %.2f (%s)", plan.Estimated.Cost, web.Metadata["monthly_cost"])
+	}
+
+	if plan.Estimated.Complexity != "medium" {
+		t.Errorf("expected medium complexity, got %s", plan.Estimated.Complexity)
+	}
+	if plan.Metadata["add"] != "2" || plan.Metadata["remove"] != "1" {
+		t.Errorf("expected the change summary in metadata, got %+v", plan.Metadata)
+	}
+
+	warnings := strings.Join(plan.Warnings, "\n")
+	for _, want := range []string{"Deprecated attribute", "aws_db_instance.db will be destroyed and re-created", "aws_s3_bucket.logs has drifted"} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("expected warning %q, got %q", want, plan.Warnings)
+		}
+	}
+}
+
+func TestGeneratePlanTerraformErrors(t *testing.T) {
+	stream := `{"@level":"error","@message":"Error: Missing required provider","type":"diagnostic","diagnostic":{"severity":"error","summary":"Missing required provider","detail":"Run terraform init."}}
+`
+	installFakeTerraform(t, stream, "", 1)
+	deployer := &DeployerImpl{}
+
+	_, err := deployer.GeneratePlan(context.Background(), PlanOptions{Template: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "Missing required provider: Run terraform init.") {
+		t.Errorf("expected the terraform diagnostic in the error, got %v", err)
+	}
+}
+
+func TestGeneratePlanWithoutTerraform(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	deployer := &DeployerImpl{}
+
+	_, err := deployer.GeneratePlan(context.Background(), PlanOptions{Template: t.TempDir()})
+	if err == nil || !strings.Contains(err.Error(), "not installed") {
+		t.Errorf("expected an installation error, got %v", err)
+	}
+}
+
+const deploymentFixture = `{
+  "metadata": {"name": "web", "namespace": "prod", "uid": "dep-uid", "annotations": {"deployment.kubernetes.io/revision": "%s"}},
+  "spec": {"selector": {"matchLabels": {"app": "web"}}}
+}`
+
+const replicaSetsFixture = `{"items": [
+  {"metadata": {"name": "web-1", "annotations": {"deployment.kubernetes.io/revision": "1"}, "ownerReferences": [{"uid": "dep-uid"}]},
+   "spec": {"template": {"metadata": {"labels": {"app": "web", "pod-template-hash": "111"}}, "spec": {"containers": [{"name": "web", "image": "web:1"}]}}}},
+  {"metadata": {"name": "web-2", "annotations": {"deployment.kubernetes.io/revision": "2"}, "ownerReferences": [{"uid": "dep-uid"}]},
+   "spec": {"template": {"metadata": {"labels": {"app": "web", "pod-template-hash": "222"}}, "spec": {"containers": [{"name": "web", "image": "web:2"}]}}}},
+  {"metadata": {"name": "web-3", "annotations": {"deployment.kubernetes.io/revision": "3"}, "ownerReferences": [{"uid": "dep-uid"}]},
+   "spec": {"template": {"metadata": {"labels": {"app": "web", "pod-template-hash": "333"}}, "spec": {"containers": [{"name": "web", "image": "web:3"}]}}}},
+  {"metadata": {"name": "other-9", "annotations": {"deployment.kubernetes.io/revision": "9"}, "ownerReferences": [{"uid": "other-uid"}]},
+   "spec": {"template": {}}}
+]}`
+
+// installFakeKubectl serves fixture deployments and replica sets and records
+// the patch it receives; it returns the path the patch is written to
+func installFakeKubectl(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake kubectl script requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"deployment.json":         fmt.Sprintf(deploymentFixture, "3"),
+		"deployment-patched.json": fmt.Sprintf(deploymentFixture, "4"),
+		"replicasets.json":        replicaSetsFixture,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write fixture: %v", err)
+		}
+	}
+
+	patchFile := filepath.Join(dir, "patch.json")
+	script := `#!/bin/sh
+dir=` + dir + `
+case "$1 $2" in
+"get deployment")
+  if [ -f ` + patchFile + ` ]; then cat $dir/deployment-patched.json; else cat $dir/deployment.json; fi ;;
+"get replicasets") cat $dir/replicasets.json ;;
+"patch deployment")
+  while [ $# -gt 0 ]; do if [ "$1" = "-p" ]; then printf '%s' "$2" > ` + patchFile + `; fi; shift; done ;;
+"rollout status") echo 'deployment "web" successfully rolled out' ;;
+*) echo "unexpected kubectl $*" >&2; exit 1 ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(dir, kubectlBinary), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake kubectl: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return patchFile
+}
+
+func TestRollbackDeploymentPreviousRevision(t *testing.T) {
+	patchFile := installFakeKubectl(t)
+	deployer := &DeployerImpl{}
+
+	result, err := deployer.RollbackDeployment(context.Background(), "prod/web", "")
+	if err != nil {
+		t.Fatalf("RollbackDeployment() failed: %v", err)
+	}
+
+	if result.FromVersion != "3" || result.ToVersion != "2" || result.Metadata["new_revision"] != "4" {
+		t.Errorf("unexpected rollback result: %+v", result)
+	}
+
+	data, err := os.ReadFile(patchFile)
+	if err != nil {
+		t.Fatalf("expected a patch to be applied: %v", err)
+	}
+	var patch []struct {
+		Op    string                 `json:"op"`
+		Path  string                 `json:"path"`
+		Value map[string]interface{} `json:"value"`
+	}
+	if err := json.Unmarshal(data, &patch); err != nil || len(patch) != 1 {
+		t.Fatalf("invalid patch %s: %v", data, err)
+	}
+	if patch[0].Path != "/spec/template" || !strings.Contains(string(data), `"web:2"`) {
+		t.Errorf("expected the revision 2 template to be restored, got %s", data)
+	}
+	if strings.Contains(string(data), "pod-template-hash") {
+		t.Errorf("expected the pod-template-hash label to be removed, got %s", data)
+	}
+}
+
+func TestRollbackDeploymentRevisions(t *testing.T) {
+	installFakeKubectl(t)
+	deployer := &DeployerImpl{}
+
+	result, err := deployer.RollbackDeployment(context.Background(), "prod/web", "v1")
+	if err != nil {
+		t.Fatalf("RollbackDeployment() failed: %v", err)
+	}
+	if result.ToVersion != "1" {
+		t.Errorf("expected rollback to revision 1, got %s", result.ToVersion)
+	}
+
+	_, err = deployer.RollbackDeployment(context.Background(), "prod/web", "9")
+	if err == nil || !strings.Contains(err.Error(), "revision 9 not found") || !strings.Contains(err.Error(), "available: 1, 2, 3") {
+		t.Errorf("expected a missing revision error listing the history, got %v", err)
+	}
+}
+
+func TestDeployInfrastructureAppliesInOrder(t *testing.T) {
+	applied := installFakeTerraform(t, planStreamFixture, planShowFixture, 0)
+	deployer := &DeployerImpl{}
+
+	result, err := deployer.DeployInfrastructure(context.Background(), InfraOptions{
+		Template:  t.TempDir(),
+		Variables: map[string]string{"env": "prod"},
+	})
+	if err != nil {
+		t.Fatalf("DeployInfrastructure() failed: %v", err)
+	}
+	if len(result.Resources) != 3 {
+		t.Fatalf("expected 3 applied resources, got %v", result.Resources)
+	}
+
+	data, err := os.ReadFile(applied)
+	if err != nil {
+		t.Fatalf("expected terraform apply to run: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	want := []string{"aws_vpc.main", "aws_instance.web", "aws_db_instance.db"}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d applies, got %q", len(want), lines)
+	}
+	for i, target := range want {
+		if !strings.Contains(lines[i], "-target="+target) || !strings.Contains(lines[i], "-var=env=prod") {
+			t.Errorf("apply %d: expected target %s with variables, got %q", i, target, lines[i])
+		}
+	}
+}
+
+func TestDeployInfrastructureCancelled(t *testing.T) {
+	applied := installFakeTerraform(t, planStreamFixture, planShowFixture, 0)
+	// Hang on the second apply so the deployment is cancelled during it
+	script := filepath.Join(filepath.Dir(applied), terraformBinary)
+	data, err := os.ReadFile(script)
+	if err != nil {
+		t.Fatalf("failed to read fake terraform: %v", err)
+	}
+	hang := `apply) echo "$@" >> ` + applied + `; if [ "$(wc -l < ` + applied + `)" -ge 2 ]; then exec sleep 10; fi ;;`
+	data = []byte(strings.Replace(string(data), `apply) echo "$@" >> `+applied+` ;;`, hang, 1))
+	if err := os.WriteFile(script, data, 0755); err != nil {
+		t.Fatalf("failed to write fake terraform: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for ctx.Err() == nil {
+			if data, err := os.ReadFile(applied); err == nil && strings.Count(string(data), "\n") >= 2 {
+				cancel()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	deployer := &DeployerImpl{}
+	result, err := deployer.DeployInfrastructure(ctx, InfraOptions{Template: t.TempDir()})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if result == nil || result.Status != "cancelled" {
+		t.Fatalf("expected a cancelled partial result, got %+v", result)
+	}
+	if len(result.Resources) != 1 || result.Resources[0] != "aws_vpc.main" {
+		t.Errorf("expected only the VPC to be applied, got %v", result.Resources)
+	}
+}
+
+func TestResolveApplyOrder(t *testing.T) {
+	resource := func(name, deps string) PlannedResource {
+		return PlannedResource{Name: name, Metadata: map[string]string{dependsOnKey: deps}}
+	}
+
+	order, err := ResolveApplyOrder([]PlannedResource{
+		resource("instance", "subnet,sg"),
+		resource("subnet", "vpc"),
+		resource("sg", "vpc"),
+		resource("vpc", ""),
+		resource("bucket", "existing-kms-key"),
+	})
+	if err != nil {
+		t.Fatalf("ResolveApplyOrder() failed: %v", err)
+	}
+	if got := strings.Join(order, ","); got != "vpc,subnet,sg,instance,bucket" {
+		t.Errorf("unexpected order: %s", got)
+	}
+}
+
+func TestResolveApplyOrderCycle(t *testing.T) {
+	resource := func(name, deps string) PlannedResource {
+		return PlannedResource{Name: name, Metadata: map[string]string{dependsOnKey: deps}}
+	}
+
+	_, err := ResolveApplyOrder([]PlannedResource{
+		resource("vpc", ""),
+		resource("a", "vpc,c"),
+		resource("b", "a"),
+		resource("c", "b"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "a -> c -> b -> a") {
+		t.Errorf("expected a cycle error naming a -> c -> b -> a, got %v", err)
+	}
+
+	_, err = ResolveApplyOrder([]PlannedResource{resource("self", "self")})
+	if err == nil || !strings.Contains(err.Error(), "self -> self") {
+		t.Errorf("expected a self-dependency cycle error, got %v", err)
+	}
+}
+
+func TestEstimatePlanCost(t *testing.T) {
+	show := &tfShowPlan{}
+	if err := json.Unmarshal([]byte(`{
+  "resource_changes": [
+    {"address": "aws_instance.app", "change": {"after": {"instance_type": "t3.micro"}}},
+    {"address": "aws_ebs_volume.data", "change": {"after": {"size": 100, "type": "gp3"}}},
+    {"address": "aws_elasticache_cluster.cache", "change": {"after": {"node_type": "cache.t3.micro", "num_cache_nodes": 2}}},
+    {"address": "aws_vpc.main", "change": {"after": {"cidr_block": "10.0.0.0/16"}}},
+    {"address": "aws_instance.big", "change": {"after": {"instance_type": "u-24tb1.metal"}}},
+    {"address": "aws_quantum_thing.q", "change": {"after": {}}}
+  ],
+  "configuration": {"provider_config": {"aws": {"name": "aws", "expressions": {"region": {"constant_value": "eu-west-1"}}}}}
+}`), show); err != nil {
+		t.Fatalf("invalid fixture: %v", err)
+	}
+
+	resource := func(name, resourceType, action string) PlannedResource {
+		return PlannedResource{Name: name, Type: resourceType, Action: action, Metadata: map[string]string{}}
+	}
+	plan := &DeploymentPlan{
+		Resources: []PlannedResource{
+			resource("aws_instance.app", "aws_instance", "create"),
+			resource("aws_ebs_volume.data", "aws_ebs_volume", "create"),
+			resource("aws_elasticache_cluster.cache", "aws_elasticache_cluster", "create"),
+			resource("aws_vpc.main", "aws_vpc", "create"),
+			resource("aws_instance.big", "aws_instance", "create"),
+			resource("aws_quantum_thing.q", "aws_quantum_thing", "create"),
+			resource("aws_instance.old", "aws_instance", "delete"),
+		},
+		Metadata: map[string]string{},
+	}
+
+	if err := estimatePlanCost(plan, show); err != nil {
+		t.Fatalf("estimatePlanCost() failed: %v", err)
+	}
+
+	want := map[string]string{
+		"aws_instance.app":              "8.35",  // 0.0104 * 730 * 1.1
+		"aws_ebs_volume.data":           "8.80",  // 100 * 0.08 * 1.1
+		"aws_elasticache_cluster.cache": "27.30", // 0.017 * 730 * 2 * 1.1
+		"aws_vpc.main":                  "0.00",
+		"aws_instance.big":              "0.00",
+		"aws_quantum_thing.q":           "0.00",
+	}
+	for _, r := range plan.Resources {
+		if cost, ok := want[r.Name]; ok && r.Metadata["monthly_cost"] != cost {
+			t.Errorf("%s: expected monthly cost %s, got %s (%s)", r.Name, cost, r.Metadata["monthly_cost"], r.Metadata["cost_basis"])
+		}
+	}
+	if _, priced := plan.Resources[6].Metadata["monthly_cost"]; priced {
+		t.Error("expected deleted resources to be left out of the estimate")
+	}
+
+	if plan.Estimated.Cost != 44.45 {
+		t.Errorf("expected a total of 44.45, got %.2f", plan.Estimated.Cost)
+	}
+	if plan.Metadata["currency"] != "USD" {
+		t.Errorf("expected USD, got %s", plan.Metadata["currency"])
+	}
+
+	warnings := strings.Join(plan.Warnings, "\n")
+	for _, want := range []string{"aws_quantum_thing.q", "instance_type u-24tb1.metal"} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("expected a warning mentioning %q, got %q", want, plan.Warnings)
+		}
+	}
+	if strings.Contains(warnings, "aws_vpc") {
+		t.Errorf("expected free resources not to warn, got %q", plan.Warnings)
+	}
+}
@@ -0,0 +1,293 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kubectlBinary is the Kubernetes CLI looked up on PATH
+const kubectlBinary = "kubectl"
+
+// revisionAnnotation records a Deployment or ReplicaSet's rollout revision
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+// podTemplateHashLabel is added to ReplicaSet templates by the Deployment controller
+const podTemplateHashLabel = "pod-template-hash"
+
+// rolloutTimeout bounds how long a rollback may take to become ready
+const rolloutTimeout = 5 * time.Minute
+
+// k8sObjectMeta is the subset of Kubernetes object metadata used for rollbacks
+type k8sObjectMeta struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace"`
+	UID             string            `json:"uid"`
+	Annotations     map[string]string `json:"annotations"`
+	OwnerReferences []struct {
+		UID string `json:"uid"`
+	} `json:"ownerReferences"`
+}
+
+// revision returns the rollout revision annotation, or 0 if absent
+func (m k8sObjectMeta) revision() int {
+	revision, err := strconv.Atoi(m.Annotations[revisionAnnotation])
+	if err != nil {
+		return 0
+	}
+	return revision
+}
+
+// k8sDeployment is the subset of an apps/v1 Deployment used for rollbacks
+type k8sDeployment struct {
+	Metadata k8sObjectMeta `json:"metadata"`
+	Spec     struct {
+		Selector struct {
+			MatchLabels map[string]string `json:"matchLabels"`
+		} `json:"selector"`
+	} `json:"spec"`
+}
+
+// k8sReplicaSet is the subset of an apps/v1 ReplicaSet used for rollbacks
+type k8sReplicaSet struct {
+	Metadata k8sObjectMeta `json:"metadata"`
+	Spec     struct {
+		Template map[string]interface{} `json:"template"`
+	} `json:"spec"`
+}
+
+// k8sReplicaSetList is a list of ReplicaSets as returned by kubectl get -o json
+type k8sReplicaSetList struct {
+	Items []k8sReplicaSet `json:"items"`
+}
+
+// kubectl runs kubectl commands for Deployment rollbacks
+type kubectl struct {
+	path string
+}
+
+// newKubectl locates kubectl on PATH
+func newKubectl() (*kubectl, error) {
+	path, err := exec.LookPath(kubectlBinary)
+	if err != nil {
+		return nil, fmt.Errorf("kubectl is not installed or not on PATH; install it from https://kubernetes.io/docs/tasks/tools/ to roll back deployments: %w", err)
+	}
+	return &kubectl{path: path}, nil
+}
+
+// run executes kubectl with args and returns its stdout
+func (k *kubectl) run(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, k.path, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("kubectl %s cancelled: %w", strings.Join(args[:2], " "), ctxErr)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("kubectl %s failed: %s", strings.Join(args[:2], " "), msg)
+		}
+		return nil, fmt.Errorf("kubectl %s failed: %w", strings.Join(args[:2], " "), err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// getJSON runs a kubectl get command and decodes its JSON output into v
+func (k *kubectl) getJSON(ctx context.Context, v interface{}, args ...string) error {
+	data, err := k.run(ctx, append(append([]string{"get"}, args...), "-o", "json")...)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+	return nil
+}
+
+// parseDeploymentID splits "[namespace/]name" into its parts
+func parseDeploymentID(id string) (string, string, error) {
+	id = strings.TrimPrefix(id, "deployment/")
+	namespace, name := "default", id
+	if i := strings.Index(id, "/"); i >= 0 {
+		namespace, name = id[:i], id[i+1:]
+	}
+	if name == "" || namespace == "" || strings.Contains(name, "/") {
+		return "", "", fmt.Errorf("invalid deployment id %q: expected [namespace/]name", id)
+	}
+	return namespace, name, nil
+}
+
+// parseRevision accepts a revision as "3", "v3" or "revision 3"
+func parseRevision(version string) (int, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(version, "revision")), "v")
+	revision, err := strconv.Atoi(trimmed)
+	if err != nil || revision <= 0 {
+		return 0, fmt.Errorf("invalid revision %q", version)
+	}
+	return revision, nil
+}
+
+// selectorString renders match labels as a kubectl label selector
+func selectorString(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// revisionHistory returns the ReplicaSets owned by a Deployment keyed by revision
+func (k *kubectl) revisionHistory(ctx context.Context, deployment *k8sDeployment) (map[int]*k8sReplicaSet, error) {
+	namespace := deployment.Metadata.Namespace
+	args := []string{"replicasets", "-n", namespace}
+	if selector := selectorString(deployment.Spec.Selector.MatchLabels); selector != "" {
+		args = append(args, "-l", selector)
+	}
+
+	var list k8sReplicaSetList
+	if err := k.getJSON(ctx, &list, args...); err != nil {
+		return nil, fmt.Errorf("failed to list replica sets: %w", err)
+	}
+
+	history := make(map[int]*k8sReplicaSet)
+	for i := range list.Items {
+		rs := &list.Items[i]
+		owned := false
+		for _, owner := range rs.Metadata.OwnerReferences {
+			if owner.UID == deployment.Metadata.UID {
+				owned = true
+				break
+			}
+		}
+		if revision := rs.Metadata.revision(); owned && revision > 0 {
+			history[revision] = rs
+		}
+	}
+	return history, nil
+}
+
+// selectRollbackRevision picks the requested revision, or the newest one
+// before current when version is empty
+func selectRollbackRevision(history map[int]*k8sReplicaSet, current int, version string) (int, error) {
+	revisions := make([]int, 0, len(history))
+	for revision := range history {
+		revisions = append(revisions, revision)
+	}
+	sort.Ints(revisions)
+
+	available := make([]string, len(revisions))
+	for i, revision := range revisions {
+		available[i] = strconv.Itoa(revision)
+	}
+
+	if version == "" {
+		for i := len(revisions) - 1; i >= 0; i-- {
+			if revisions[i] < current {
+				return revisions[i], nil
+			}
+		}
+		return 0, fmt.Errorf("no previous revision to roll back to (available: %s)", strings.Join(available, ", "))
+	}
+
+	revision, err := parseRevision(version)
+	if err != nil {
+		return 0, err
+	}
+	if _, exists := history[revision]; !exists {
+		return 0, fmt.Errorf("revision %d not found in rollout history (available: %s)", revision, strings.Join(available, ", "))
+	}
+	if revision == current {
+		return 0, fmt.Errorf("deployment is already at revision %d", revision)
+	}
+	return revision, nil
+}
+
+// rollbackKubernetesDeployment restores a Deployment's pod template from a
+// previous ReplicaSet revision and waits for the rollout to finish
+func rollbackKubernetesDeployment(ctx context.Context, id, version string) (*RollbackResult, error) {
+	start := time.Now()
+
+	namespace, name, err := parseDeploymentID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	k, err := newKubectl()
+	if err != nil {
+		return nil, err
+	}
+
+	var deployment k8sDeployment
+	if err := k.getJSON(ctx, &deployment, "deployment", name, "-n", namespace); err != nil {
+		return nil, fmt.Errorf("failed to get deployment %s/%s: %w", namespace, name, err)
+	}
+	if deployment.Metadata.Namespace == "" {
+		deployment.Metadata.Namespace = namespace
+	}
+
+	history, err := k.revisionHistory(ctx, &deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	current := deployment.Metadata.revision()
+	target, err := selectRollbackRevision(history, current, version)
+	if err != nil {
+		return nil, fmt.Errorf("cannot roll back deployment %s/%s: %w", namespace, name, err)
+	}
+
+	template := history[target].Spec.Template
+	if metadata, ok := template["metadata"].(map[string]interface{}); ok {
+		if labels, ok := metadata["labels"].(map[string]interface{}); ok {
+			delete(labels, podTemplateHashLabel)
+		}
+	}
+
+	patch, err := json.Marshal([]map[string]interface{}{
+		{"op": "replace", "path": "/spec/template", "value": template},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build rollback patch: %w", err)
+	}
+
+	if _, err := k.run(ctx, "patch", "deployment", name, "-n", namespace, "--type=json", "-p", string(patch)); err != nil {
+		return nil, fmt.Errorf("failed to patch deployment %s/%s: %w", namespace, name, err)
+	}
+
+	if _, err := k.run(ctx, "rollout", "status", "deployment/"+name, "-n", namespace, "--timeout="+rolloutTimeout.String()); err != nil {
+		return nil, fmt.Errorf("rollback of %s/%s to revision %d did not become ready: %w", namespace, name, target, err)
+	}
+
+	metadata := map[string]string{
+		"namespace":       namespace,
+		"deployment":      name,
+		"rollback_reason": "manual",
+	}
+
+	var updated k8sDeployment
+	if err := k.getJSON(ctx, &updated, "deployment", name, "-n", namespace); err == nil {
+		if revision := updated.Metadata.revision(); revision > 0 {
+			metadata["new_revision"] = strconv.Itoa(revision)
+		}
+	}
+
+	return &RollbackResult{
+		ID:          id,
+		Status:      "success",
+		Message:     fmt.Sprintf("Deployment %s/%s rolled back from revision %d to revision %d", namespace, name, current, target),
+		FromVersion: strconv.Itoa(current),
+		ToVersion:   strconv.Itoa(target),
+		Duration:    time.Since(start),
+		Metadata:    metadata,
+		Timestamp:   time.Now(),
+	}, nil
+}
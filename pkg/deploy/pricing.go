@@ -0,0 +1,343 @@
+package deploy
+
+import (
+	"embed"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// builtinPricing holds the pricing tables shipped with AlloraCLI
+//
+//go:embed pricing/*.yaml
+var builtinPricing embed.FS
+
+// hoursPerMonth is the number of hours used to turn hourly prices into monthly estimates
+const hoursPerMonth = 730
+
+// pricingTable holds approximate prices for one provider's resource types.
+// Prices are for DefaultRegion; Regions holds multipliers for other regions
+type pricingTable struct {
+	Provider       string                     `yaml:"provider"`
+	ResourcePrefix string                     `yaml:"resource_prefix"`
+	DefaultRegion  string                     `yaml:"default_region"`
+	Currency       string                     `yaml:"currency"`
+	Regions        map[string]float64         `yaml:"regions"`
+	Free           []string                   `yaml:"free"`
+	UsageBased     []string                   `yaml:"usage_based"`
+	Resources      map[string]resourcePricing `yaml:"resources"`
+}
+
+// resourcePricing prices a resource type from a fixed hourly rate, an hourly
+// rate chosen by an attribute such as instance_type, and/or provisioned storage
+type resourcePricing struct {
+	FixedHourly    float64           `yaml:"fixed_hourly"`
+	Hourly         *attributePricing `yaml:"hourly"`
+	Storage        *storagePricing   `yaml:"storage"`
+	CountAttribute string            `yaml:"count_attribute"`
+}
+
+// attributePricing maps the value of an attribute to an hourly price
+type attributePricing struct {
+	Attribute string             `yaml:"attribute"`
+	Prices    map[string]float64 `yaml:"prices"`
+}
+
+// storagePricing charges per GB-month by storage type
+type storagePricing struct {
+	SizeAttribute string             `yaml:"size_attribute"`
+	TypeAttribute string             `yaml:"type_attribute"`
+	DefaultType   string             `yaml:"default_type"`
+	Prices        map[string]float64 `yaml:"prices"`
+}
+
+// costEstimate is the estimated monthly cost of one resource
+type costEstimate struct {
+	Monthly float64
+	Basis   string
+}
+
+// loadPricingTables parses the embedded pricing tables
+func loadPricingTables() ([]*pricingTable, error) {
+	entries, err := builtinPricing.ReadDir("pricing")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pricing tables: %w", err)
+	}
+
+	var tables []*pricingTable
+	for _, entry := range entries {
+		data, err := builtinPricing.ReadFile("pricing/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read pricing table %s: %w", entry.Name(), err)
+		}
+
+		var table pricingTable
+		if err := yaml.Unmarshal(data, &table); err != nil {
+			return nil, fmt.Errorf("failed to parse pricing table %s: %w", entry.Name(), err)
+		}
+		if table.ResourcePrefix == "" || table.DefaultRegion == "" {
+			return nil, fmt.Errorf("pricing table %s must set resource_prefix and default_region", entry.Name())
+		}
+		tables = append(tables, &table)
+	}
+	return tables, nil
+}
+
+// tableFor returns the pricing table covering a resource type, or nil
+func tableFor(tables []*pricingTable, resourceType string) *pricingTable {
+	for _, table := range tables {
+		if strings.HasPrefix(resourceType, table.ResourcePrefix) {
+			return table
+		}
+	}
+	return nil
+}
+
+// contains reports whether list holds value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupAttribute resolves a dotted attribute path such as "settings.0.tier"
+func lookupAttribute(attrs map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = attrs
+	for _, part := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[part]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			current = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, current != nil
+}
+
+// stringAttribute returns a string attribute, reduced to its last path
+// segment so self-links such as ".../machineTypes/e2-micro" match price keys
+func stringAttribute(attrs map[string]interface{}, path string) string {
+	value, ok := lookupAttribute(attrs, path)
+	if !ok {
+		return ""
+	}
+	text, ok := value.(string)
+	if !ok {
+		return ""
+	}
+	if i := strings.LastIndex(text, "/"); i >= 0 {
+		text = text[i+1:]
+	}
+	return text
+}
+
+// numberAttribute returns a numeric attribute
+func numberAttribute(attrs map[string]interface{}, path string) (float64, bool) {
+	value, ok := lookupAttribute(attrs, path)
+	if !ok {
+		return 0, false
+	}
+	switch n := value.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// resourceRegion finds the region a resource is created in from its own
+// attributes, falling back to the provider's configured region
+func resourceRegion(attrs map[string]interface{}, providerRegion string) string {
+	if region := stringAttribute(attrs, "region"); region != "" {
+		return region
+	}
+	if location := stringAttribute(attrs, "location"); location != "" {
+		return strings.ToLower(strings.ReplaceAll(location, " ", ""))
+	}
+	if zone := stringAttribute(attrs, "zone"); zone != "" {
+		if i := strings.LastIndex(zone, "-"); i > 0 {
+			return zone[:i]
+		}
+	}
+	if az := stringAttribute(attrs, "availability_zone"); len(az) > 1 {
+		return az[:len(az)-1]
+	}
+	return providerRegion
+}
+
+// estimate prices a resource of the given type with its planned attributes
+func (t *pricingTable) estimate(resourceType string, attrs map[string]interface{}, region string) (*costEstimate, []string, error) {
+	if contains(t.Free, resourceType) {
+		return &costEstimate{Basis: "no charge"}, nil, nil
+	}
+	if contains(t.UsageBased, resourceType) {
+		return &costEstimate{Basis: "usage-based; not estimated"}, nil, nil
+	}
+
+	pricing, ok := t.Resources[resourceType]
+	if !ok {
+		return nil, nil, fmt.Errorf("no pricing data for %s", resourceType)
+	}
+
+	var hourly, monthly float64
+	var basis []string
+
+	if pricing.FixedHourly > 0 {
+		hourly += pricing.FixedHourly
+		basis = append(basis, fmt.Sprintf("$%g/h", pricing.FixedHourly))
+	}
+
+	if pricing.Hourly != nil {
+		value := stringAttribute(attrs, pricing.Hourly.Attribute)
+		price, ok := pricing.Hourly.Prices[value]
+		if !ok {
+			if value == "" {
+				return nil, nil, fmt.Errorf("%s is not known until apply", pricing.Hourly.Attribute)
+			}
+			return nil, nil, fmt.Errorf("no pricing data for %s %s", pricing.Hourly.Attribute, value)
+		}
+		hourly += price
+		basis = append(basis, fmt.Sprintf("%s @ $%g/h", value, price))
+	}
+
+	if pricing.Storage != nil {
+		size, ok := numberAttribute(attrs, pricing.Storage.SizeAttribute)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s is not known until apply", pricing.Storage.SizeAttribute)
+		}
+		storageType := stringAttribute(attrs, pricing.Storage.TypeAttribute)
+		if storageType == "" {
+			storageType = pricing.Storage.DefaultType
+		}
+		price, ok := pricing.Storage.Prices[storageType]
+		if !ok {
+			return nil, nil, fmt.Errorf("no pricing data for storage type %s", storageType)
+		}
+		monthly += size * price
+		basis = append(basis, fmt.Sprintf("%gGB %s @ $%g/GB-month", size, storageType, price))
+	}
+
+	count := 1.0
+	if pricing.CountAttribute != "" {
+		if n, ok := numberAttribute(attrs, pricing.CountAttribute); ok && n > 0 {
+			count = n
+			basis = append(basis, fmt.Sprintf("x%g", n))
+		}
+	}
+
+	var warnings []string
+	multiplier := 1.0
+	if region != "" && region != t.DefaultRegion {
+		if m, ok := t.Regions[region]; ok {
+			multiplier = m
+			basis = append(basis, fmt.Sprintf("%s x%g", region, m))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("no %s pricing for region %s; using %s prices", t.Provider, region, t.DefaultRegion))
+		}
+	}
+
+	return &costEstimate{
+		Monthly: (hourly*hoursPerMonth + monthly) * count * multiplier,
+		Basis:   strings.Join(basis, ", "),
+	}, warnings, nil
+}
+
+// providerRegions returns the region configured for each provider in the plan
+func providerRegions(show *tfShowPlan) map[string]string {
+	regions := map[string]string{}
+	for name, provider := range show.Configuration.ProviderConfig {
+		key := provider.Name
+		if key == "" {
+			key = name
+		}
+		if expr, ok := provider.Expressions["region"]; ok {
+			if region, ok := expr.ConstantValue.(string); ok && region != "" {
+				regions[key] = region
+			}
+		}
+	}
+	return regions
+}
+
+// estimatePlanCost attaches a monthly cost to each resource the plan creates
+// or keeps, and sets the plan's total. Resources without pricing data
+// contribute nothing and add a warning
+func estimatePlanCost(plan *DeploymentPlan, show *tfShowPlan) error {
+	tables, err := loadPricingTables()
+	if err != nil {
+		return err
+	}
+
+	details := make(map[string]tfResourceChange, len(show.ResourceChanges))
+	for _, rc := range show.ResourceChanges {
+		details[rc.Address] = rc
+	}
+	regions := providerRegions(show)
+
+	currency := "USD"
+	var total float64
+	warned := map[string]bool{}
+	warn := func(message string) {
+		if !warned[message] {
+			warned[message] = true
+			plan.Warnings = append(plan.Warnings, message)
+		}
+	}
+
+	for i := range plan.Resources {
+		resource := &plan.Resources[i]
+		if resource.Action == "delete" || resource.Action == "read" || strings.HasPrefix(resource.Name, "data.") {
+			continue
+		}
+
+		table := tableFor(tables, resource.Type)
+		if table == nil {
+			resource.Metadata["monthly_cost"] = "0.00"
+			warn(fmt.Sprintf("no pricing data for %s (%s); excluded from cost estimate", resource.Name, resource.Type))
+			continue
+		}
+		if table.Currency != "" {
+			currency = table.Currency
+		}
+
+		attrs := decodeObject(details[resource.Name].Change.After)
+		providerRegion := regions[strings.TrimSuffix(table.ResourcePrefix, "_")]
+		estimate, warnings, err := table.estimate(resource.Type, attrs, resourceRegion(attrs, providerRegion))
+		if err != nil {
+			resource.Metadata["monthly_cost"] = "0.00"
+			warn(fmt.Sprintf("cannot price %s: %v; excluded from cost estimate", resource.Name, err))
+			continue
+		}
+		for _, w := range warnings {
+			warn(w)
+		}
+
+		resource.Metadata["monthly_cost"] = strconv.FormatFloat(estimate.Monthly, 'f', 2, 64)
+		if estimate.Basis != "" {
+			resource.Metadata["cost_basis"] = estimate.Basis
+		}
+		total += estimate.Monthly
+	}
+
+	plan.Estimated.Cost = float64(int64(total*100+0.5)) / 100
+	plan.Metadata["currency"] = currency
+	plan.Metadata["cost_period"] = "monthly"
+	return nil
+}
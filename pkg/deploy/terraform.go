@@ -0,0 +1,635 @@
+package deploy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// terraformBinary is the Terraform executable looked up on PATH
+const terraformBinary = "terraform"
+
+// perActionDuration is a rough estimate of how long Terraform takes to apply one change
+const perActionDuration = 30 * time.Second
+
+// tfMessage is one line of Terraform's machine-readable UI output (-json)
+type tfMessage struct {
+	Level      string           `json:"@level"`
+	Message    string           `json:"@message"`
+	Type       string           `json:"type"`
+	Change     *tfPlannedChange `json:"change,omitempty"`
+	Changes    *tfChangeSummary `json:"changes,omitempty"`
+	Diagnostic *tfDiagnostic    `json:"diagnostic,omitempty"`
+}
+
+// tfPlannedChange describes a resource change in a planned_change or resource_drift message
+type tfPlannedChange struct {
+	Resource tfResourceAddr `json:"resource"`
+	Action   string         `json:"action"`
+	Reason   string         `json:"reason,omitempty"`
+}
+
+// tfResourceAddr identifies a resource in Terraform's UI output
+type tfResourceAddr struct {
+	Addr         string `json:"addr"`
+	Module       string `json:"module"`
+	ResourceType string `json:"resource_type"`
+	ResourceName string `json:"resource_name"`
+	Provider     string `json:"implied_provider"`
+}
+
+// tfChangeSummary is the change_summary message emitted at the end of a plan
+type tfChangeSummary struct {
+	Add       int    `json:"add"`
+	Change    int    `json:"change"`
+	Remove    int    `json:"remove"`
+	Operation string `json:"operation"`
+}
+
+// tfDiagnostic is a warning or error reported by Terraform
+type tfDiagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail"`
+	Address  string `json:"address,omitempty"`
+}
+
+// String formats a diagnostic as "summary: detail"
+func (d *tfDiagnostic) String() string {
+	text := d.Summary
+	if d.Address != "" {
+		text = fmt.Sprintf("%s (%s)", text, d.Address)
+	}
+	if detail := strings.TrimSpace(d.Detail); detail != "" {
+		text = fmt.Sprintf("%s: %s", text, detail)
+	}
+	return text
+}
+
+// tfPlanStream is the parsed result of `terraform plan -json`
+type tfPlanStream struct {
+	changes  []tfPlannedChange
+	drift    []tfPlannedChange
+	summary  *tfChangeSummary
+	warnings []string
+	errors   []string
+}
+
+// parsePlanStream reads Terraform's streaming JSON plan output.
+// Lines that are not JSON, such as provider log output, are ignored
+func parsePlanStream(r io.Reader) (*tfPlanStream, error) {
+	stream := &tfPlanStream{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+
+		var msg tfMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			continue
+		}
+
+		switch msg.Type {
+		case "planned_change":
+			if msg.Change != nil {
+				stream.changes = append(stream.changes, *msg.Change)
+			}
+		case "resource_drift":
+			if msg.Change != nil {
+				stream.drift = append(stream.drift, *msg.Change)
+			}
+		case "change_summary":
+			stream.summary = msg.Changes
+		case "diagnostic":
+			if msg.Diagnostic == nil {
+				continue
+			}
+			if msg.Diagnostic.Severity == "error" {
+				stream.errors = append(stream.errors, msg.Diagnostic.String())
+			} else {
+				stream.warnings = append(stream.warnings, msg.Diagnostic.String())
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read terraform plan output: %w", err)
+	}
+	return stream, nil
+}
+
+// tfShowPlan is the subset of `terraform show -json <planfile>` used for
+// attribute diffs and resource dependencies
+type tfShowPlan struct {
+	ResourceChanges []tfResourceChange `json:"resource_changes"`
+	Configuration   struct {
+		ProviderConfig map[string]tfProviderConfig `json:"provider_config"`
+		RootModule     tfConfigModule              `json:"root_module"`
+	} `json:"configuration"`
+}
+
+// tfProviderConfig is a provider block; only constant expressions are read
+type tfProviderConfig struct {
+	Name        string `json:"name"`
+	Expressions map[string]struct {
+		ConstantValue interface{} `json:"constant_value"`
+	} `json:"expressions"`
+}
+
+// tfConfigModule is a module in the configuration section of terraform show
+type tfConfigModule struct {
+	Resources   []tfConfigResource `json:"resources"`
+	ModuleCalls map[string]struct {
+		Module tfConfigModule `json:"module"`
+	} `json:"module_calls"`
+}
+
+// tfConfigResource is a resource block with its explicit and implicit dependencies
+type tfConfigResource struct {
+	Address     string                     `json:"address"`
+	DependsOn   []string                   `json:"depends_on"`
+	Expressions map[string]json.RawMessage `json:"expressions"`
+}
+
+// instanceKeyPattern matches count and for_each instance keys in resource addresses
+var instanceKeyPattern = regexp.MustCompile(`\[[^\]]*\]`)
+
+// configAddress strips instance keys so a resource instance address matches
+// the configuration address of the block that declares it
+func configAddress(address string) string {
+	return instanceKeyPattern.ReplaceAllString(address, "")
+}
+
+// collectReferences gathers every "references" list in a configuration expression tree
+func collectReferences(raw json.RawMessage, refs map[string]bool) {
+	var node interface{}
+	if json.Unmarshal(raw, &node) != nil {
+		return
+	}
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch value := v.(type) {
+		case map[string]interface{}:
+			for key, child := range value {
+				if list, ok := child.([]interface{}); ok && key == "references" {
+					for _, ref := range list {
+						if name, ok := ref.(string); ok {
+							refs[name] = true
+						}
+					}
+					continue
+				}
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range value {
+				walk(child)
+			}
+		}
+	}
+	walk(node)
+}
+
+// referencedResource reduces a reference such as "aws_vpc.main.id" to the
+// resource it names, or "" for variables, locals and other non-resources
+func referencedResource(ref string) string {
+	parts := strings.Split(configAddress(ref), ".")
+	switch parts[0] {
+	case "var", "local", "each", "count", "path", "terraform", "self", "module":
+		return ""
+	case "data":
+		if len(parts) < 3 {
+			return ""
+		}
+		return strings.Join(parts[:3], ".")
+	}
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.Join(parts[:2], ".")
+}
+
+// configDependencies maps each configured resource address to the resource
+// addresses it depends on, explicitly or through references
+func configDependencies(module tfConfigModule, prefix string, deps map[string]map[string]bool) {
+	for _, resource := range module.Resources {
+		refs := map[string]bool{}
+		for _, expr := range resource.Expressions {
+			collectReferences(expr, refs)
+		}
+		for _, dep := range resource.DependsOn {
+			refs[dep] = true
+		}
+
+		addr := prefix + resource.Address
+		if deps[addr] == nil {
+			deps[addr] = map[string]bool{}
+		}
+		for ref := range refs {
+			if target := referencedResource(ref); target != "" && prefix+target != addr {
+				deps[addr][prefix+target] = true
+			}
+		}
+	}
+
+	for name, call := range module.ModuleCalls {
+		configDependencies(call.Module, prefix+"module."+name+".", deps)
+	}
+}
+
+// plannedDependencies returns, for each planned resource, the other planned
+// resources it depends on
+func plannedDependencies(show *tfShowPlan, resources []PlannedResource) map[string][]string {
+	deps := map[string]map[string]bool{}
+	configDependencies(show.Configuration.RootModule, "", deps)
+
+	instances := map[string][]string{}
+	for _, resource := range resources {
+		key := configAddress(resource.Name)
+		instances[key] = append(instances[key], resource.Name)
+	}
+
+	result := map[string][]string{}
+	for _, resource := range resources {
+		var names []string
+		for dep := range deps[configAddress(resource.Name)] {
+			names = append(names, instances[dep]...)
+		}
+		if len(names) > 0 {
+			sort.Strings(names)
+			result[resource.Name] = names
+		}
+	}
+	return result
+}
+
+// tfResourceChange holds the before and after state of one planned resource change
+type tfResourceChange struct {
+	Address      string   `json:"address"`
+	ModuleAddr   string   `json:"module_address,omitempty"`
+	Type         string   `json:"type"`
+	Name         string   `json:"name"`
+	ProviderName string   `json:"provider_name"`
+	Change       tfChange `json:"change"`
+}
+
+// tfChange is a resource's before/after values as encoded by terraform show
+type tfChange struct {
+	Actions         []string        `json:"actions"`
+	Before          json.RawMessage `json:"before"`
+	After           json.RawMessage `json:"after"`
+	AfterUnknown    json.RawMessage `json:"after_unknown"`
+	BeforeSensitive json.RawMessage `json:"before_sensitive"`
+	AfterSensitive  json.RawMessage `json:"after_sensitive"`
+}
+
+// decodeObject decodes a JSON object, returning nil for null or non-object values
+func decodeObject(raw json.RawMessage) map[string]interface{} {
+	var obj map[string]interface{}
+	if len(raw) == 0 || json.Unmarshal(raw, &obj) != nil {
+		return nil
+	}
+	return obj
+}
+
+// changedAttributes lists the top-level attributes a change modifies as
+// "name: old -> new", masking sensitive values
+func (c tfChange) changedAttributes() []string {
+	before := decodeObject(c.Before)
+	after := decodeObject(c.After)
+	unknown := decodeObject(c.AfterUnknown)
+	sensitive := decodeObject(c.AfterSensitive)
+	for key, value := range decodeObject(c.BeforeSensitive) {
+		if _, ok := sensitive[key]; !ok {
+			if sensitive == nil {
+				sensitive = map[string]interface{}{}
+			}
+			sensitive[key] = value
+		}
+	}
+
+	keys := map[string]bool{}
+	for key := range before {
+		keys[key] = true
+	}
+	for key := range after {
+		keys[key] = true
+	}
+	for key, value := range unknown {
+		if value == true {
+			keys[key] = true
+		}
+	}
+
+	var changes []string
+	for key := range keys {
+		if unknown[key] == true {
+			changes = append(changes, fmt.Sprintf("%s: %s -> (known after apply)", key, formatAttribute(before, sensitive, key)))
+			continue
+		}
+
+		old, hadOld := before[key]
+		updated, hasNew := after[key]
+		if reflect.DeepEqual(old, updated) {
+			continue
+		}
+		if before == nil && (!hasNew || updated == nil) {
+			continue
+		}
+		if after == nil && (!hadOld || old == nil) {
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("%s: %s -> %s", key, formatAttribute(before, sensitive, key), formatAttribute(after, sensitive, key)))
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// formatAttribute renders an attribute value for display
+func formatAttribute(values, sensitive map[string]interface{}, key string) string {
+	if s, ok := sensitive[key]; ok && s != false && s != nil {
+		if _, present := values[key]; present {
+			return "(sensitive)"
+		}
+	}
+	value, ok := values[key]
+	if !ok || value == nil {
+		return "null"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// terraformDir resolves a plan template to the directory Terraform runs in
+func terraformDir(template string) (string, error) {
+	if template == "" {
+		template = "."
+	}
+
+	info, err := os.Stat(template)
+	if err != nil {
+		return "", fmt.Errorf("terraform template not found: %s", template)
+	}
+	if !info.IsDir() {
+		template = filepath.Dir(template)
+	}
+	return filepath.Abs(template)
+}
+
+// runTerraform runs a terraform subcommand in dir, returning its stdout
+func runTerraform(ctx context.Context, path, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, path, append([]string{"-chdir=" + dir}, args...)...)
+	cmd.Env = append(os.Environ(), "TF_IN_AUTOMATION=1")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return stdout.Bytes(), fmt.Errorf("terraform %s cancelled: %w", args[0], ctxErr)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return stdout.Bytes(), fmt.Errorf("terraform %s failed: %s: %w", args[0], msg, err)
+		}
+		return stdout.Bytes(), fmt.Errorf("terraform %s failed: %w", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// lookupTerraform finds the terraform executable on PATH
+func lookupTerraform() (string, error) {
+	path, err := exec.LookPath(terraformBinary)
+	if err != nil {
+		return "", fmt.Errorf("terraform is not installed or not on PATH; install it from https://developer.hashicorp.com/terraform/install to plan and deploy infrastructure: %w", err)
+	}
+	return path, nil
+}
+
+// variableArgs renders variables as sorted -var flags
+func variableArgs(variables map[string]string) []string {
+	keys := make([]string, 0, len(variables))
+	for key := range variables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := make([]string, 0, len(keys))
+	for _, key := range keys {
+		args = append(args, fmt.Sprintf("-var=%s=%s", key, variables[key]))
+	}
+	return args
+}
+
+// terraformPlan runs `terraform plan -json` in dir and returns the streamed
+// plan along with the attribute-level diff from `terraform show -json`
+func terraformPlan(ctx context.Context, dir string, variables map[string]string) (*tfPlanStream, *tfShowPlan, error) {
+	path, err := lookupTerraform()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	planFile, err := os.CreateTemp("", "allora-plan-*.tfplan")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create plan file: %w", err)
+	}
+	planFile.Close()
+	defer os.Remove(planFile.Name())
+
+	args := append([]string{"plan", "-json", "-input=false", "-no-color", "-out=" + planFile.Name()}, variableArgs(variables)...)
+	output, runErr := runTerraform(ctx, path, dir, args...)
+	stream, err := parsePlanStream(bytes.NewReader(output))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(stream.errors) > 0 {
+		return nil, nil, fmt.Errorf("terraform plan failed: %s", strings.Join(stream.errors, "; "))
+	}
+	if runErr != nil {
+		return nil, nil, runErr
+	}
+
+	data, err := runTerraform(ctx, path, dir, "show", "-json", planFile.Name())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var show tfShowPlan
+	if err := json.Unmarshal(data, &show); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse terraform show output: %w", err)
+	}
+	return stream, &show, nil
+}
+
+// terraformApply applies a single resource in dir with -target
+func terraformApply(ctx context.Context, dir, address string, variables map[string]string) error {
+	path, err := lookupTerraform()
+	if err != nil {
+		return err
+	}
+
+	args := append([]string{"apply", "-auto-approve", "-input=false", "-no-color", "-target=" + address}, variableArgs(variables)...)
+	_, err = runTerraform(ctx, path, dir, args...)
+	return err
+}
+
+// actionRisk rates how risky a Terraform action is to apply
+func actionRisk(action string) string {
+	switch action {
+	case "create", "read", "import", "move":
+		return "low"
+	case "update":
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+// actionVerb capitalizes a Terraform action for use in descriptions
+func actionVerb(action string) string {
+	if action == "" {
+		return "Change"
+	}
+	return strings.ToUpper(action[:1]) + action[1:]
+}
+
+// planComplexity derives a complexity rating from the mix of planned actions
+func planComplexity(actions []PlannedAction) string {
+	score, destructive := 0, 0
+	for _, action := range actions {
+		switch action.Action {
+		case "create", "read", "import", "move":
+			score++
+		case "update":
+			score += 2
+		default:
+			score += 3
+			destructive++
+		}
+	}
+
+	switch {
+	case len(actions) == 0:
+		return "none"
+	case destructive >= 3 || score > 20:
+		return "high"
+	case destructive > 0 || score > 5:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// buildTerraformPlan converts Terraform's plan output into a deployment plan
+// with resources ordered by their dependencies
+func buildTerraformPlan(stream *tfPlanStream, show *tfShowPlan) (*DeploymentPlan, error) {
+	details := make(map[string]tfResourceChange, len(show.ResourceChanges))
+	for _, rc := range show.ResourceChanges {
+		details[rc.Address] = rc
+	}
+
+	plan := &DeploymentPlan{
+		Actions:   []PlannedAction{},
+		Resources: []PlannedResource{},
+		Warnings:  append([]string{}, stream.warnings...),
+		Metadata:  map[string]string{},
+		Timestamp: time.Now(),
+	}
+
+	for _, change := range stream.changes {
+		addr := change.Resource.Addr
+		metadata := map[string]string{}
+		if change.Resource.Provider != "" {
+			metadata["provider"] = change.Resource.Provider
+		}
+		if change.Resource.Module != "" {
+			metadata["module"] = change.Resource.Module
+		}
+		if change.Reason != "" {
+			metadata["reason"] = change.Reason
+		}
+
+		resource := PlannedResource{
+			Name:     addr,
+			Type:     change.Resource.ResourceType,
+			Action:   change.Action,
+			Changes:  []string{},
+			Metadata: metadata,
+		}
+		if detail, ok := details[addr]; ok {
+			resource.Changes = detail.Change.changedAttributes()
+			if detail.ProviderName != "" {
+				resource.Metadata["provider"] = detail.ProviderName
+			}
+		}
+
+		actionMetadata := map[string]string{"changed_attributes": fmt.Sprintf("%d", len(resource.Changes))}
+		if change.Reason != "" {
+			actionMetadata["reason"] = change.Reason
+		}
+		plan.Actions = append(plan.Actions, PlannedAction{
+			Type:        change.Resource.ResourceType,
+			Resource:    addr,
+			Action:      change.Action,
+			Description: fmt.Sprintf("%s %s", actionVerb(change.Action), addr),
+			Risk:        actionRisk(change.Action),
+			Metadata:    actionMetadata,
+		})
+		plan.Resources = append(plan.Resources, resource)
+
+		switch change.Action {
+		case "delete":
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s will be destroyed", addr))
+		case "replace":
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s will be destroyed and re-created", addr))
+		}
+	}
+
+	dependencies := plannedDependencies(show, plan.Resources)
+	for i, resource := range plan.Resources {
+		if deps := dependencies[resource.Name]; len(deps) > 0 {
+			plan.Resources[i].Metadata[dependsOnKey] = strings.Join(deps, ",")
+		}
+	}
+
+	order, err := ResolveApplyOrder(plan.Resources)
+	if err != nil {
+		return nil, err
+	}
+	plan.Order = order
+
+	for _, drift := range stream.drift {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s has drifted outside of Terraform (%s)", drift.Resource.Addr, drift.Action))
+	}
+
+	if stream.summary != nil {
+		plan.Metadata["add"] = fmt.Sprintf("%d", stream.summary.Add)
+		plan.Metadata["change"] = fmt.Sprintf("%d", stream.summary.Change)
+		plan.Metadata["remove"] = fmt.Sprintf("%d", stream.summary.Remove)
+	}
+
+	plan.Estimated = EstimatedImpact{
+		Duration:   time.Duration(len(plan.Actions)) * perActionDuration,
+		Complexity: planComplexity(plan.Actions),
+	}
+	return plan, nil
+}
@@ -0,0 +1,136 @@
+package monitor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// alertmanagerVersion is the webhook payload version sent by Alertmanager
+const alertmanagerVersion = "4"
+
+// AlertmanagerPayload is the body of an Alertmanager webhook notification
+type AlertmanagerPayload struct {
+	Version           string              `json:"version"`
+	GroupKey          string              `json:"groupKey"`
+	TruncatedAlerts   int                 `json:"truncatedAlerts"`
+	Status            string              `json:"status"`
+	Receiver          string              `json:"receiver"`
+	GroupLabels       map[string]string   `json:"groupLabels"`
+	CommonLabels      map[string]string   `json:"commonLabels"`
+	CommonAnnotations map[string]string   `json:"commonAnnotations"`
+	ExternalURL       string              `json:"externalURL"`
+	Alerts            []AlertmanagerAlert `json:"alerts"`
+}
+
+// AlertmanagerAlert is one alert in an Alertmanager webhook notification
+type AlertmanagerAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// ParseAlertmanagerPayload decodes an Alertmanager webhook body and checks
+// that it has the shape Alertmanager sends
+func ParseAlertmanagerPayload(r io.Reader) (*AlertmanagerPayload, error) {
+	var payload AlertmanagerPayload
+	decoder := json.NewDecoder(r)
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, fmt.Errorf("invalid Alertmanager payload: %w", err)
+	}
+
+	if payload.Version != alertmanagerVersion {
+		return nil, fmt.Errorf("unsupported Alertmanager payload version %q; expected %s", payload.Version, alertmanagerVersion)
+	}
+	if len(payload.Alerts) == 0 {
+		return nil, fmt.Errorf("Alertmanager payload has no alerts")
+	}
+
+	var problems []error
+	for i, alert := range payload.Alerts {
+		if alert.Labels["alertname"] == "" {
+			problems = append(problems, fmt.Errorf("alerts[%d] has no alertname label", i))
+		}
+		if alert.Status != "firing" && alert.Status != "resolved" {
+			problems = append(problems, fmt.Errorf("alerts[%d] has status %q; expected firing or resolved", i, alert.Status))
+		}
+		if alert.StartsAt.IsZero() {
+			problems = append(problems, fmt.Errorf("alerts[%d] has no startsAt", i))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid Alertmanager payload: %w", errors.Join(problems...))
+	}
+	return &payload, nil
+}
+
+// ActiveAlert converts the alert into the internal alert model. Alertmanager
+// severities such as page or warn are kept as they are; unknown severities
+// are treated as warnings by notification sinks
+func (a *AlertmanagerAlert) ActiveAlert() *ActiveAlert {
+	name := a.Labels["alertname"]
+	severity := a.Labels["severity"]
+	if severity == "" {
+		severity = "warning"
+	}
+
+	condition := a.Annotations["summary"]
+	if condition == "" {
+		condition = name
+	}
+	message := a.Annotations["description"]
+	if message == "" {
+		message = a.Annotations["summary"]
+	}
+
+	labels := make(map[string]string, len(a.Labels))
+	for key, value := range a.Labels {
+		if key != "alertname" && key != "severity" {
+			labels[key] = value
+		}
+	}
+
+	return &ActiveAlert{
+		Alert: &AlertConfig{
+			Name:      name,
+			Condition: condition,
+			Action:    a.GeneratorURL,
+			Severity:  strings.ToLower(severity),
+			Enabled:   true,
+			CreatedAt: a.StartsAt,
+			UpdatedAt: a.StartsAt,
+		},
+		Triggered: a.StartsAt,
+		Status:    a.Status,
+		Message:   message,
+		Labels:    labels,
+	}
+}
+
+// IncidentName identifies the alert across notifications, so each firing
+// series has its own incident
+func (a *AlertmanagerAlert) IncidentName() string {
+	if a.Fingerprint == "" {
+		return a.Labels["alertname"]
+	}
+	return a.Labels["alertname"] + "-" + a.Fingerprint
+}
+
+// DiagnosticTarget returns what troubleshooting diagnostics should probe for
+// the alert: its instance, such as host:9100, or else its service. It is
+// empty when the alert names neither
+func (a *AlertmanagerAlert) DiagnosticTarget() string {
+	for _, label := range []string{"instance", "service"} {
+		if target := a.Labels[label]; target != "" {
+			return target
+		}
+	}
+	return ""
+}
@@ -0,0 +1,195 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// alertsFileName is the file alert definitions are stored in under the config dir
+const alertsFileName = "alerts.json"
+
+// alertStore persists alert definitions to a JSON file
+type alertStore struct {
+	path   string
+	alerts map[string]*AlertConfig
+	mutex  sync.RWMutex
+}
+
+// newAlertStore creates an alert store backed by path, loading any saved alerts
+func newAlertStore(path string) (*alertStore, error) {
+	store := &alertStore{
+		path:   path,
+		alerts: make(map[string]*AlertConfig),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, fmt.Errorf("failed to read alerts file: %w", err)
+	}
+
+	var alerts []*AlertConfig
+	if err := json.Unmarshal(data, &alerts); err != nil {
+		return nil, fmt.Errorf("failed to parse alerts file %s: %w", path, err)
+	}
+	for _, alert := range alerts {
+		store.alerts[alert.Name] = alert
+	}
+
+	return store, nil
+}
+
+// Create stores a new alert, rejecting duplicate names
+func (s *alertStore) Create(alert AlertConfig) error {
+	if alert.Name == "" {
+		return fmt.Errorf("alert name is required")
+	}
+	if _, err := ParseCondition(alert.Condition); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.alerts[alert.Name]; exists {
+		return fmt.Errorf("alert already exists: %s", alert.Name)
+	}
+
+	now := time.Now()
+	if alert.CreatedAt.IsZero() {
+		alert.CreatedAt = now
+	}
+	alert.UpdatedAt = now
+
+	s.alerts[alert.Name] = &alert
+	if err := s.save(); err != nil {
+		delete(s.alerts, alert.Name)
+		return err
+	}
+	return nil
+}
+
+// List returns the stored alerts ordered by creation time
+func (s *alertStore) List() []*AlertConfig {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	alerts := make([]*AlertConfig, 0, len(s.alerts))
+	for _, alert := range s.alerts {
+		alerts = append(alerts, alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].CreatedAt.Equal(alerts[j].CreatedAt) {
+			return alerts[i].Name < alerts[j].Name
+		}
+		return alerts[i].CreatedAt.Before(alerts[j].CreatedAt)
+	})
+	return alerts
+}
+
+// Delete removes a stored alert by name
+func (s *alertStore) Delete(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	alert, exists := s.alerts[name]
+	if !exists {
+		return fmt.Errorf("alert not found: %s", name)
+	}
+
+	delete(s.alerts, name)
+	if err := s.save(); err != nil {
+		s.alerts[name] = alert
+		return err
+	}
+	return nil
+}
+
+// save writes all alerts to disk; callers must hold the write lock
+func (s *alertStore) save() error {
+	alerts := make([]*AlertConfig, 0, len(s.alerts))
+	for _, alert := range s.alerts {
+		alerts = append(alerts, alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Name < alerts[j].Name
+	})
+
+	data, err := json.MarshalIndent(alerts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal alerts: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create alerts directory: %w", err)
+	}
+
+	// Write to a temporary file first so a failed write never truncates the store
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write alerts file: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write alerts file: %w", err)
+	}
+
+	return nil
+}
+
+// activeAlerts evaluates the enabled stored alerts against the resource
+// usage of status and returns those that fire. Alerts that start firing are
+// queued for the notification sinks when notifications are enabled
+func (m *MonitorImpl) activeAlerts(status *SystemStatus) []*ActiveAlert {
+	active := []*ActiveAlert{}
+	if m.alerts == nil || m.alertManager == nil {
+		return active
+	}
+
+	configs := map[string]*AlertConfig{}
+	var rules []*AlertRule
+	for _, alert := range m.alerts.List() {
+		configs[alert.Name] = alert
+		rule := &AlertRule{
+			Name:        alert.Name,
+			Description: alert.Condition,
+			Condition:   alert.Condition,
+			Severity:    alert.Severity,
+			Enabled:     alert.Enabled,
+		}
+		if alert.Action != "" {
+			rule.Actions = []string{alert.Action}
+		}
+		rules = append(rules, rule)
+	}
+	m.alertManager.SetRules(rules)
+
+	var metrics []*Metric
+	for name, value := range resourceMetrics(status.Resources) {
+		metrics = append(metrics, &Metric{Name: name, Value: value, Unit: "percent", Timestamp: status.Timestamp})
+	}
+
+	alerts, err := m.alertManager.EvaluateRules(m.ctx, metrics)
+	if err != nil {
+		logrus.Warnf("Failed to evaluate alerts: %v", err)
+	}
+	for _, alert := range alerts {
+		active = append(active, &ActiveAlert{
+			Alert:     configs[alert.RuleName],
+			Triggered: alert.Timestamp,
+			Status:    "firing",
+			Message:   alert.Message,
+		})
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].Alert.Name < active[j].Alert.Name })
+	return active
+}
@@ -0,0 +1,132 @@
+package monitor
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// metricsNamespace prefixes all metrics exported by AlloraCLI
+const metricsNamespace = "allora"
+
+// systemCollector exports host and alert status as Prometheus gauges,
+// refreshing them from MonitorImpl on every scrape
+type systemCollector struct {
+	monitor *MonitorImpl
+
+	cpuUsage      *prometheus.Desc
+	cpuCores      *prometheus.Desc
+	loadAverage   *prometheus.Desc
+	memoryUsage   *prometheus.Desc
+	memoryUsed    *prometheus.Desc
+	memoryTotal   *prometheus.Desc
+	diskUsage     *prometheus.Desc
+	diskUsed      *prometheus.Desc
+	diskTotal     *prometheus.Desc
+	uptime        *prometheus.Desc
+	activeAlerts  *prometheus.Desc
+	alertRules    *prometheus.Desc
+	serviceUp     *prometheus.Desc
+	scrapeSuccess *prometheus.Desc
+}
+
+// newSystemCollector creates a collector backed by the given monitor
+func newSystemCollector(monitor *MonitorImpl) *systemCollector {
+	desc := func(subsystem, name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, subsystem, name), help, labels, nil)
+	}
+
+	return &systemCollector{
+		monitor:       monitor,
+		cpuUsage:      desc("system", "cpu_usage_percent", "Host CPU utilisation in percent."),
+		cpuCores:      desc("system", "cpu_cores", "Number of logical CPU cores."),
+		loadAverage:   desc("system", "load1", "One minute load average."),
+		memoryUsage:   desc("system", "memory_usage_percent", "Host memory utilisation in percent."),
+		memoryUsed:    desc("system", "memory_used_bytes", "Host memory in use."),
+		memoryTotal:   desc("system", "memory_total_bytes", "Total host memory."),
+		diskUsage:     desc("system", "disk_usage_percent", "Root filesystem utilisation in percent."),
+		diskUsed:      desc("system", "disk_used_bytes", "Root filesystem space in use."),
+		diskTotal:     desc("system", "disk_total_bytes", "Total root filesystem space."),
+		uptime:        desc("system", "uptime_seconds", "Time since the host booted."),
+		activeAlerts:  desc("alerts", "active", "Number of currently active alerts."),
+		alertRules:    desc("alerts", "configured", "Number of configured alert rules."),
+		serviceUp:     desc("service", "up", "Whether a monitored service is running (1) or not (0).", "service"),
+		scrapeSuccess: desc("", "scrape_success", "Whether collecting the system status succeeded."),
+	}
+}
+
+// Describe sends the descriptors of all exported metrics
+func (c *systemCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		c.cpuUsage, c.cpuCores, c.loadAverage,
+		c.memoryUsage, c.memoryUsed, c.memoryTotal,
+		c.diskUsage, c.diskUsed, c.diskTotal,
+		c.uptime, c.activeAlerts, c.alertRules,
+		c.serviceUp, c.scrapeSuccess,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect gathers a fresh system status and emits it as gauges
+func (c *systemCollector) Collect(ch chan<- prometheus.Metric) {
+	gauge := func(desc *prometheus.Desc, value float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+	}
+
+	if c.monitor.alerts != nil {
+		gauge(c.alertRules, float64(len(c.monitor.alerts.List())))
+	}
+
+	status, err := c.monitor.GetSystemStatus()
+	if err != nil {
+		gauge(c.scrapeSuccess, 0)
+		return
+	}
+	gauge(c.scrapeSuccess, 1)
+
+	if resources := status.Resources; resources != nil {
+		if cpu := resources.CPU; cpu != nil {
+			gauge(c.cpuUsage, cpu.Usage)
+			gauge(c.cpuCores, float64(cpu.Cores))
+			gauge(c.loadAverage, cpu.LoadAverage)
+		}
+		if memory := resources.Memory; memory != nil {
+			gauge(c.memoryUsage, memory.Usage)
+			gauge(c.memoryUsed, float64(memory.Used))
+			gauge(c.memoryTotal, float64(memory.Total))
+		}
+		if disk := resources.Disk; disk != nil {
+			gauge(c.diskUsage, disk.Usage)
+			gauge(c.diskUsed, float64(disk.Used))
+			gauge(c.diskTotal, float64(disk.Total))
+		}
+	}
+
+	gauge(c.uptime, status.Uptime.Seconds())
+	gauge(c.activeAlerts, float64(len(status.Alerts)))
+
+	for _, service := range status.Services {
+		up := 0.0
+		if service.Status == "running" {
+			up = 1
+		}
+		gauge(c.serviceUp, up, service.Name)
+	}
+}
+
+// RegisterCollectors registers AlloraCLI and Go runtime collectors with the
+// monitor's Prometheus registry
+func (m *MonitorImpl) RegisterCollectors() error {
+	for _, collector := range []prometheus.Collector{
+		newSystemCollector(m),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	} {
+		if err := m.registry.Register(collector); err != nil {
+			return fmt.Errorf("failed to register collector: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Condition is a parsed alert condition of the form `<metric> <op> <number>`
+type Condition struct {
+	Metric    string  `json:"metric"`
+	Operator  string  `json:"operator"`
+	Threshold float64 `json:"threshold"`
+}
+
+// conditionPattern splits a condition into metric, operator and threshold
+var conditionPattern = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_.:\-]*)\s*(>=|<=|==|!=|>|<)\s*(\S+)$`)
+
+// ParseCondition parses an alert condition such as "cpu_usage > 80"
+func ParseCondition(expr string) (*Condition, error) {
+	matches := conditionPattern.FindStringSubmatch(strings.TrimSpace(expr))
+	if matches == nil {
+		return nil, fmt.Errorf("invalid condition %q: expected \"<metric> <op> <number>\" with op one of > >= < <= == !=", expr)
+	}
+
+	threshold, err := strconv.ParseFloat(matches[3], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: threshold %q is not a number", expr, matches[3])
+	}
+
+	return &Condition{
+		Metric:    matches[1],
+		Operator:  matches[2],
+		Threshold: threshold,
+	}, nil
+}
+
+// Matches reports whether the condition applies to the given metric
+func (c *Condition) Matches(metric *Metric) bool {
+	return metric != nil && metric.Name == c.Metric
+}
+
+// Evaluate reports whether the metric value satisfies the condition
+func (c *Condition) Evaluate(metric *Metric) (bool, error) {
+	if !c.Matches(metric) {
+		return false, nil
+	}
+
+	value, err := toFloat64(metric.Value)
+	if err != nil {
+		return false, fmt.Errorf("metric %s: %w", metric.Name, err)
+	}
+
+	switch c.Operator {
+	case ">":
+		return value > c.Threshold, nil
+	case ">=":
+		return value >= c.Threshold, nil
+	case "<":
+		return value < c.Threshold, nil
+	case "<=":
+		return value <= c.Threshold, nil
+	case "==":
+		return value == c.Threshold, nil
+	case "!=":
+		return value != c.Threshold, nil
+	default:
+		return false, fmt.Errorf("unsupported operator: %s", c.Operator)
+	}
+}
+
+// String returns the condition in its textual form
+func (c *Condition) String() string {
+	return fmt.Sprintf("%s %s %s", c.Metric, c.Operator, strconv.FormatFloat(c.Threshold, 'f', -1, 64))
+}
+
+// toFloat64 coerces a metric value to float64
+func toFloat64(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("value %q is not numeric", v)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("value of type %T is not numeric", value)
+	}
+}
@@ -0,0 +1,180 @@
+package monitor
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/streaming"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Dashboard server timings
+const (
+	// defaultDashboardInterval is how often the dashboard pushes status snapshots
+	defaultDashboardInterval = 5 * time.Second
+	// dashboardShutdownTimeout bounds how long shutdown waits for open requests
+	dashboardShutdownTimeout = 5 * time.Second
+)
+
+// dashboardHandler builds the dashboard routes; event streams end when done is closed
+func (m *MonitorImpl) dashboardHandler(done <-chan struct{}) http.Handler {
+	mux := http.NewServeMux()
+
+	// Prometheus metrics endpoint
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+
+	// Health check endpoint
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
+	// Live status updates as server-sent events
+	mux.HandleFunc("/events", m.handleDashboardEvents(done))
+
+	// Dashboard page
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(dashboardHTML))
+	})
+
+	return mux
+}
+
+// handleDashboardEvents streams a SystemStatus snapshot every dashboard interval
+func (m *MonitorImpl) handleDashboardEvents(done <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+
+		writer := streaming.NewStreamWriter(w)
+		send := func() bool {
+			status, err := m.GetSystemStatus()
+			if err != nil {
+				writer.WriteEvent("error", map[string]string{"error": err.Error()})
+			} else if err := writer.WriteEvent("status", status); err != nil {
+				return false
+			}
+			writer.Flush()
+			return true
+		}
+
+		if !send() {
+			return
+		}
+
+		ticker := time.NewTicker(m.getDashboardInterval())
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if !send() {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			case <-done:
+				return
+			}
+		}
+	}
+}
+
+// getDashboardInterval returns the configured push interval or the default
+func (m *MonitorImpl) getDashboardInterval() time.Duration {
+	if m.dashboardInterval > 0 {
+		return m.dashboardInterval
+	}
+	return defaultDashboardInterval
+}
+
+// dashboardHTML renders status tiles kept up to date from /events
+const dashboardHTML = `<!DOCTYPE html>
+<html>
+<head>
+    <title>AlloraCLI Monitoring Dashboard</title>
+    <style>
+        body { font-family: Arial, sans-serif; margin: 20px; }
+        .tiles { display: flex; flex-wrap: wrap; gap: 10px; }
+        .metric { margin: 10px 0; padding: 10px; border: 1px solid #ddd; min-width: 180px; }
+        .status-ok { color: green; }
+        .status-warning { color: orange; }
+        .status-error { color: red; }
+        #updated { color: #888; }
+    </style>
+</head>
+<body>
+    <h1>AlloraCLI Monitoring Dashboard</h1>
+    <div class="metric">
+        <h3>System Status: <span id="overall">Connecting...</span></h3>
+        <span id="updated"></span>
+    </div>
+    <div class="tiles">
+        <div class="metric"><h3>CPU</h3><span id="cpu">-</span></div>
+        <div class="metric"><h3>Memory</h3><span id="memory">-</span></div>
+        <div class="metric"><h3>Disk</h3><span id="disk">-</span></div>
+        <div class="metric"><h3>Alerts</h3><span id="alerts">-</span></div>
+    </div>
+    <div class="metric">
+        <h3>Services</h3>
+        <ul id="services"><li>No services reported</li></ul>
+    </div>
+    <p><a href="/metrics">Prometheus Metrics</a></p>
+    <script>
+        function percent(usage) {
+            return usage ? usage.usage.toFixed(1) + '%' : 'n/a';
+        }
+        function statusClass(status) {
+            if (status === 'healthy' || status === 'running') return 'status-ok';
+            if (status === 'warning' || status === 'degraded') return 'status-warning';
+            return 'status-error';
+        }
+        var events = new EventSource('/events');
+        events.addEventListener('status', function (e) {
+            var status = JSON.parse(e.data);
+            var resources = status.resources || {};
+            var overall = document.getElementById('overall');
+            overall.textContent = status.overall;
+            overall.className = statusClass(status.overall);
+            document.getElementById('cpu').textContent = percent(resources.cpu) +
+                (resources.cpu ? ' (' + resources.cpu.cores + ' cores)' : '');
+            document.getElementById('memory').textContent = percent(resources.memory);
+            document.getElementById('disk').textContent = percent(resources.disk);
+            document.getElementById('alerts').textContent = (status.alerts || []).length;
+            var services = document.getElementById('services');
+            services.innerHTML = '';
+            (status.services || []).forEach(function (service) {
+                var item = document.createElement('li');
+                var state = document.createElement('span');
+                state.className = statusClass(service.status);
+                state.textContent = service.status;
+                item.textContent = service.name + ': ';
+                item.appendChild(state);
+                services.appendChild(item);
+            });
+            if (!services.children.length) {
+                services.innerHTML = '<li>No services reported</li>';
+            }
+            document.getElementById('updated').textContent =
+                'Last updated ' + new Date(status.timestamp).toLocaleTimeString();
+        });
+        events.onerror = function () {
+            var overall = document.getElementById('overall');
+            overall.textContent = 'Disconnected';
+            overall.className = 'status-error';
+        };
+    </script>
+</body>
+</html>
+`
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/AlloraAi/AlloraCLI/pkg/utils"
 	"github.com/grafana/grafana-api-golang-client"
 	"github.com/sirupsen/logrus"
 )
@@ -19,8 +20,7 @@ type GrafanaMonitor struct {
 
 // NewGrafanaMonitor creates a new Grafana monitor
 func NewGrafanaMonitor(cfg *MonitorConfig) (Monitor, error) {
-	logger := logrus.New()
-	logger.SetLevel(logrus.InfoLevel)
+	logger := utils.NewLogger()
 
 	if cfg.Grafana.URL == "" {
 		return nil, fmt.Errorf("grafana URL is required")
@@ -189,8 +189,13 @@ func (m *GrafanaMonitor) GetAlerts(ctx context.Context) ([]*Alert, error) {
 
 // GetMetrics gets metrics from Grafana
 func (m *GrafanaMonitor) GetMetrics(metric, duration string) (*MetricsData, error) {
+	return m.GetMetricsContext(context.Background(), metric, duration)
+}
+
+// GetMetricsContext gets metrics from Grafana, connecting under ctx
+func (m *GrafanaMonitor) GetMetricsContext(ctx context.Context, metric, duration string) (*MetricsData, error) {
 	if !m.connected {
-		if err := m.Connect(context.Background()); err != nil {
+		if err := m.Connect(ctx); err != nil {
 			return nil, err
 		}
 	}
@@ -284,7 +289,7 @@ func (m *GrafanaMonitor) DeleteAlert(name string) error {
 }
 
 // StartDashboard starts a dashboard
-func (m *GrafanaMonitor) StartDashboard(host string, port int) error {
+func (m *GrafanaMonitor) StartDashboard(ctx context.Context, host string, port int) error {
 	// This would be implemented to start a dashboard
 	return fmt.Errorf("StartDashboard not implemented for Grafana monitor")
 }
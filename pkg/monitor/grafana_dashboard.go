@@ -0,0 +1,242 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/grafana/grafana-api-golang-client"
+)
+
+// The dashboard ProvisionGrafanaDashboard maintains. Its UID is fixed so
+// provisioning again updates it instead of adding a copy
+const (
+	grafanaDashboardUID   = "allora-overview"
+	grafanaDashboardTitle = "AlloraCLI Overview"
+)
+
+// grafanaRequestTimeout bounds each call to the Grafana API
+const grafanaRequestTimeout = 30 * time.Second
+
+// GrafanaDashboard is the outcome of provisioning the AlloraCLI dashboard
+type GrafanaDashboard struct {
+	UID   string `json:"uid" yaml:"uid"`
+	Title string `json:"title" yaml:"title"`
+	URL   string `json:"url" yaml:"url"`
+	// Status is created, updated or unchanged
+	Status  string `json:"status" yaml:"status"`
+	Version int64  `json:"version,omitempty" yaml:"version,omitempty"`
+}
+
+// ProvisionGrafanaDashboard creates or updates the AlloraCLI dashboard in
+// the configured Grafana
+func (m *MonitorImpl) ProvisionGrafanaDashboard(ctx context.Context) (*GrafanaDashboard, error) {
+	if m.config == nil || m.config.Monitoring.Grafana.Endpoint == "" {
+		return nil, fmt.Errorf("no Grafana endpoint configured; set monitoring.grafana.endpoint")
+	}
+	grafana := m.config.Monitoring.Grafana
+	if grafana.APIKey == "" && grafana.Username == "" {
+		return nil, fmt.Errorf("no Grafana credentials configured; set monitoring.grafana.api_key")
+	}
+	return provisionGrafanaDashboard(ctx, grafana)
+}
+
+// ProvisionGrafanaDashboard creates or updates the AlloraCLI dashboard in
+// the Grafana this monitor is connected to
+func (m *GrafanaMonitor) ProvisionGrafanaDashboard(ctx context.Context) (*GrafanaDashboard, error) {
+	return provisionGrafanaDashboard(ctx, config.GrafanaConfig{
+		Endpoint: m.config.Grafana.URL,
+		APIKey:   m.config.Grafana.APIKey,
+	})
+}
+
+// provisionGrafanaDashboard saves the dashboard model unless Grafana already
+// holds an identical one
+func provisionGrafanaDashboard(ctx context.Context, grafana config.GrafanaConfig) (*GrafanaDashboard, error) {
+	client, err := newGrafanaClient(ctx, grafana)
+	if err != nil {
+		return nil, err
+	}
+
+	model, err := grafanaDashboardModel()
+	if err != nil {
+		return nil, err
+	}
+	result := &GrafanaDashboard{
+		UID:    grafanaDashboardUID,
+		Title:  grafanaDashboardTitle,
+		URL:    strings.TrimRight(grafana.Endpoint, "/") + "/d/" + grafanaDashboardUID,
+		Status: "created",
+	}
+
+	existing, err := client.DashboardByUID(grafanaDashboardUID)
+	var notFound gapi.ErrNotFound
+	switch {
+	case errors.As(err, &notFound):
+	case err != nil:
+		return nil, grafanaError("look up dashboard", err)
+	case dashboardUpToDate(existing.Model, model):
+		result.Status = "unchanged"
+		if version, ok := existing.Model["version"].(float64); ok {
+			result.Version = int64(version)
+		}
+		return result, nil
+	default:
+		result.Status = "updated"
+	}
+
+	saved, err := client.NewDashboard(gapi.Dashboard{
+		Model:     model,
+		Overwrite: true,
+		Message:   "Provisioned by AlloraCLI",
+	})
+	if err != nil {
+		return nil, grafanaError("save dashboard", err)
+	}
+	result.Version = saved.Version
+	return result, nil
+}
+
+// newGrafanaClient creates a Grafana API client whose requests are bound to ctx
+func newGrafanaClient(ctx context.Context, grafana config.GrafanaConfig) (*gapi.Client, error) {
+	clientConfig := gapi.Config{
+		APIKey: grafana.APIKey,
+		Client: &http.Client{
+			Timeout:   grafanaRequestTimeout,
+			Transport: &contextRoundTripper{ctx: ctx, next: http.DefaultTransport},
+		},
+	}
+	if grafana.APIKey == "" && grafana.Username != "" {
+		clientConfig.BasicAuth = url.UserPassword(grafana.Username, grafana.Password)
+	}
+
+	client, err := gapi.New(grafana.Endpoint, clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Grafana client: %w", err)
+	}
+	return client, nil
+}
+
+// contextRoundTripper sends requests under ctx, for clients that take none
+type contextRoundTripper struct {
+	ctx  context.Context
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *contextRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.next.RoundTrip(req.WithContext(t.ctx))
+}
+
+// grafanaError explains rejected credentials, which the Grafana client only
+// reports as a status in its error text
+func grafanaError(action string, err error) error {
+	switch {
+	case strings.Contains(err.Error(), "status: 401"):
+		return fmt.Errorf("failed to %s: Grafana rejected the credentials; check monitoring.grafana.api_key", action)
+	case strings.Contains(err.Error(), "status: 403"):
+		return fmt.Errorf("failed to %s: the Grafana credentials lack permission to edit dashboards", action)
+	}
+	return fmt.Errorf("failed to %s: %w", action, err)
+}
+
+// dashboardUpToDate reports whether the saved model has every field of the
+// generated one with the same value. Fields Grafana adds, such as id and
+// version, are ignored
+func dashboardUpToDate(saved, model map[string]interface{}) bool {
+	for key, value := range model {
+		if !reflect.DeepEqual(saved[key], value) {
+			return false
+		}
+	}
+	return true
+}
+
+// grafanaDashboardModel builds the dashboard JSON model: CPU, memory and
+// disk usage, alerts and service state from the metrics AlloraCLI exports.
+// It is returned decoded from JSON so it compares equal to a saved model
+func grafanaDashboardModel() (map[string]interface{}, error) {
+	percent := func(id int, title, expr string, x, y int) map[string]interface{} {
+		panel := grafanaPanel(id, "timeseries", title, expr, "{{instance}}", x, y, 8, 8)
+		panel["fieldConfig"] = map[string]interface{}{
+			"defaults": map[string]interface{}{
+				"unit": "percent",
+				"min":  0,
+				"max":  100,
+				"thresholds": map[string]interface{}{
+					"mode": "absolute",
+					"steps": []interface{}{
+						map[string]interface{}{"color": "green", "value": nil},
+						map[string]interface{}{"color": "orange", "value": 80},
+						map[string]interface{}{"color": "red", "value": 90},
+					},
+				},
+				"custom": map[string]interface{}{"thresholdsStyle": map[string]interface{}{"mode": "line"}},
+			},
+		}
+		return panel
+	}
+
+	panels := []interface{}{
+		percent(1, "CPU usage", metricsNamespace+"_system_cpu_usage_percent", 0, 0),
+		percent(2, "Memory usage", metricsNamespace+"_system_memory_usage_percent", 8, 0),
+		percent(3, "Disk usage", metricsNamespace+"_system_disk_usage_percent", 16, 0),
+		grafanaPanel(4, "stat", "Active alerts", "sum("+metricsNamespace+"_alerts_active)", "", 0, 8, 6, 6),
+		grafanaPanel(5, "stat", "Alert rules", "sum("+metricsNamespace+"_alerts_configured)", "", 6, 8, 6, 6),
+		grafanaPanel(6, "state-timeline", "Services up", metricsNamespace+"_service_up", "{{service}}", 12, 8, 12, 6),
+	}
+
+	model := map[string]interface{}{
+		"uid":           grafanaDashboardUID,
+		"title":         grafanaDashboardTitle,
+		"tags":          []interface{}{"allora"},
+		"timezone":      "browser",
+		"refresh":       "30s",
+		"schemaVersion": 39,
+		"time":          map[string]interface{}{"from": "now-6h", "to": "now"},
+		"templating": map[string]interface{}{
+			"list": []interface{}{
+				map[string]interface{}{
+					"name":  "datasource",
+					"label": "Data source",
+					"type":  "datasource",
+					"query": "prometheus",
+				},
+			},
+		},
+		"panels": panels,
+	}
+
+	data, err := json.Marshal(model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode dashboard: %w", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode dashboard: %w", err)
+	}
+	return decoded, nil
+}
+
+// grafanaPanel builds a panel querying expr from the dashboard's data source
+func grafanaPanel(id int, panelType, title, expr, legend string, x, y, w, h int) map[string]interface{} {
+	target := map[string]interface{}{"refId": "A", "expr": expr}
+	if legend != "" {
+		target["legendFormat"] = legend
+	}
+	return map[string]interface{}{
+		"id":         id,
+		"type":       panelType,
+		"title":      title,
+		"datasource": map[string]interface{}{"type": "prometheus", "uid": "${datasource}"},
+		"gridPos":    map[string]interface{}{"x": x, "y": y, "w": w, "h": h},
+		"targets":    []interface{}{target},
+	}
+}
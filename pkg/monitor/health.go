@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+)
+
+// defaultEndpointTimeout bounds an endpoint probe when the service sets no timeout
+const defaultEndpointTimeout = 5 * time.Second
+
+// probeService checks the configured endpoints of a service, returning
+// nil when it has none
+func (m *MonitorImpl) probeService(serviceName string) []*EndpointStatus {
+	if m.config == nil {
+		return nil
+	}
+	service, ok := m.config.Monitoring.Services[serviceName]
+	if !ok || len(service.Endpoints) == 0 {
+		return nil
+	}
+
+	ctx := m.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return probeEndpoints(ctx, http.DefaultClient, service.Endpoints, endpointTimeout(service))
+}
+
+// endpointTimeout returns the probe timeout of a service
+func endpointTimeout(service config.ServiceConfig) time.Duration {
+	if d, err := time.ParseDuration(service.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return defaultEndpointTimeout
+}
+
+// probeEndpoints probes every endpoint concurrently, returning their
+// statuses in the order given
+func probeEndpoints(ctx context.Context, client *http.Client, urls []string, timeout time.Duration) []*EndpointStatus {
+	statuses := make([]*EndpointStatus, len(urls))
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			statuses[i] = probeEndpoint(ctx, client, url, timeout)
+		}(i, url)
+	}
+	wg.Wait()
+	return statuses
+}
+
+// probeEndpoint sends an HTTP GET to url. Any 2xx response within timeout
+// is healthy; other responses, timeouts and connection errors are not
+func probeEndpoint(ctx context.Context, client *http.Client, url string, timeout time.Duration) *EndpointStatus {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	status := &EndpointStatus{URL: url, Status: "unhealthy"}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		status.Error = fmt.Sprintf("invalid endpoint: %v", err)
+		status.LastCheck = time.Now()
+		return status
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	status.ResponseTime = time.Since(start)
+	status.LastCheck = time.Now()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			status.Error = fmt.Sprintf("no response within %s", timeout)
+		} else {
+			status.Error = err.Error()
+		}
+		return status
+	}
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+
+	status.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		status.Status = "healthy"
+	} else {
+		status.Error = fmt.Sprintf("unexpected status %s", resp.Status)
+	}
+	return status
+}
+
+// endpointHealth sums up endpoint statuses as the health of their service:
+// healthy when all are, unhealthy when none are, and degraded in between
+func endpointHealth(endpoints []*EndpointStatus) (health string, healthy int) {
+	for _, endpoint := range endpoints {
+		if endpoint.Status == "healthy" {
+			healthy++
+		}
+	}
+	switch healthy {
+	case len(endpoints):
+		return "healthy", healthy
+	case 0:
+		return "unhealthy", healthy
+	default:
+		return "degraded", healthy
+	}
+}
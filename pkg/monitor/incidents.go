@@ -0,0 +1,103 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/notify"
+)
+
+// IncidentSync opens incidents for active alerts and resolves them when the
+// alerts clear, tracking which alerts it has seen between calls to Sync
+type IncidentSync struct {
+	notifier notify.IncidentNotifier
+	// open maps the name of each alert with an open incident to when it triggered
+	open  map[string]time.Time
+	mutex sync.Mutex
+}
+
+// NewIncidentSync creates an IncidentSync sending incidents to notifier
+func NewIncidentSync(notifier notify.IncidentNotifier) *IncidentSync {
+	return &IncidentSync{
+		notifier: notifier,
+		open:     make(map[string]time.Time),
+	}
+}
+
+// Sync compares the active alerts with the previous call: alerts that became
+// active or fired again trigger their incident, and alerts that are gone or
+// resolved resolve it. Alerts the notifier drops for their severity never
+// open an incident, so they are left out. Alerts whose incident could not be
+// updated are retried on the next call
+func (s *IncidentSync) Sync(ctx context.Context, alerts []*ActiveAlert) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	active := make(map[string]*ActiveAlert, len(alerts))
+	for _, alert := range alerts {
+		if alert == nil || alert.Alert == nil || alert.Status == "resolved" || !s.notifier.Wants(alert.Notification()) {
+			continue
+		}
+		active[alert.Alert.Name] = alert
+	}
+
+	var errs []error
+	for _, name := range sortedAlertNames(active) {
+		alert := active[name]
+		if triggered, ok := s.open[name]; ok && triggered.Equal(alert.Triggered) {
+			continue
+		}
+		if err := s.notifier.Notify(ctx, alert.Notification()); err != nil {
+			errs = append(errs, fmt.Errorf("alert %s: %w", name, err))
+			continue
+		}
+		s.open[name] = alert.Triggered
+	}
+
+	for _, name := range sortedAlertNames(s.open) {
+		if _, ok := active[name]; ok {
+			continue
+		}
+		if err := s.notifier.Resolve(ctx, name); err != nil {
+			errs = append(errs, fmt.Errorf("alert %s: %w", name, err))
+			continue
+		}
+		delete(s.open, name)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Notification describes the active alert for notification and incident sinks
+func (a *ActiveAlert) Notification() notify.Notification {
+	message := a.Message
+	if message == "" {
+		message = fmt.Sprintf("%s: %s", a.Alert.Name, a.Alert.Condition)
+	}
+	details := map[string]string{"condition": a.Alert.Condition}
+	for name, value := range a.Labels {
+		details[name] = value
+	}
+	return notify.Notification{
+		Name:      a.Alert.Name,
+		Source:    "alert",
+		Severity:  a.Alert.Severity,
+		Message:   message,
+		Timestamp: a.Triggered,
+		Details:   details,
+	}
+}
+
+// sortedAlertNames returns the keys of an alert map in order
+func sortedAlertNames[V any](alerts map[string]V) []string {
+	names := make([]string, 0, len(alerts))
+	for name := range alerts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
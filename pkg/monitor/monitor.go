@@ -2,14 +2,22 @@ package monitor
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/notify"
+	"github.com/prometheus/client_golang/api"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/model"
 )
 
 // Monitor interface defines monitoring operations
@@ -28,10 +36,12 @@ type Monitor interface {
 	GetServiceStatus(serviceName string, detailed bool) (*ServiceStatus, error)
 	ListServices() ([]*ServiceInfo, error)
 	GetMetrics(metric, duration string) (*MetricsData, error)
+	GetMetricsContext(ctx context.Context, metric, duration string) (*MetricsData, error)
 	CreateAlert(alert AlertConfig) error
 	ListAlerts() ([]*Alert, error)
 	DeleteAlert(name string) error
-	StartDashboard(host string, port int) error
+	StartDashboard(ctx context.Context, host string, port int) error
+	ProvisionGrafanaDashboard(ctx context.Context) (*GrafanaDashboard, error)
 }
 
 // SystemStatus represents overall system status
@@ -121,6 +131,8 @@ type EndpointStatus struct {
 	ResponseTime time.Duration `json:"response_time" yaml:"response_time"`
 	StatusCode   int           `json:"status_code" yaml:"status_code"`
 	LastCheck    time.Time     `json:"last_check" yaml:"last_check"`
+	// Error says why an unhealthy endpoint failed its check
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
 }
 
 // AlertConfig represents a monitoring alert configuration
@@ -141,6 +153,8 @@ type ActiveAlert struct {
 	Status       string       `json:"status" yaml:"status"`
 	Message      string       `json:"message" yaml:"message"`
 	Acknowledged bool         `json:"acknowledged" yaml:"acknowledged"`
+	// Labels identify where an alert received from Alertmanager fired
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
 }
 
 // MetricsData represents metrics data
@@ -177,15 +191,49 @@ type MetricSummary struct {
 	Count   int64   `json:"count" yaml:"count"`
 }
 
+// Range query tuning for Prometheus-backed metrics
+const (
+	prometheusQueryTimeout = 30 * time.Second
+	maxQueryPoints         = 250
+	minQueryStep           = 15 * time.Second
+)
+
 // MonitorImpl implements the Monitor interface
 type MonitorImpl struct {
 	config   *config.Config
 	registry *prometheus.Registry
 	ctx      context.Context
+	alerts   *alertStore
+	// history stores metric history when no Prometheus endpoint is configured
+	history *MetricStore
+
+	// alertManager evaluates the stored alerts
+	alertManager *AlertManager
+	// notifications queues the alerts that start firing for the
+	// notification sinks; nil unless WithAlertNotifications is given
+	notifications *notify.Queue
+
+	// dashboardInterval controls how often the dashboard pushes updates
+	dashboardInterval time.Duration
+}
+
+// Option configures a monitor created by New
+type Option func(*MonitorImpl)
+
+// WithAlertNotifications sends the stored alerts to the notification sinks
+// in the config each time they start firing. Notifications are sent in the
+// background; Stop waits for those still queued
+func WithAlertNotifications() Option {
+	return func(m *MonitorImpl) {
+		if notifier := notify.FromConfig(m.config.Notifications); notifier != nil {
+			m.notifications = notify.NewQueue(notifier, notify.DefaultQueueSize)
+			m.alertManager.SetNotifier(m.notifications)
+		}
+	}
 }
 
 // New creates a new monitor instance
-func New() (Monitor, error) {
+func New(options ...Option) (Monitor, error) {
 	cfg, err := config.Load()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
@@ -193,69 +241,57 @@ func New() (Monitor, error) {
 
 	registry := prometheus.NewRegistry()
 
-	return &MonitorImpl{
-		config:   cfg,
-		registry: registry,
-		ctx:      context.Background(),
-	}, nil
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config directory: %w", err)
+	}
+
+	alerts, err := newAlertStore(filepath.Join(configDir, alertsFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load alerts: %w", err)
+	}
+
+	history, err := openHistory(cfg, configDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open metric history: %w", err)
+	}
+
+	monitor := &MonitorImpl{
+		config:       cfg,
+		registry:     registry,
+		ctx:          context.Background(),
+		alerts:       alerts,
+		history:      history,
+		alertManager: NewAlertManager(),
+	}
+	for _, option := range options {
+		option(monitor)
+	}
+
+	if err := monitor.RegisterCollectors(); err != nil {
+		return nil, err
+	}
+
+	return monitor, nil
 }
 
 // GetSystemStatus returns overall system status
 func (m *MonitorImpl) GetSystemStatus() (*SystemStatus, error) {
-	// Mock implementation - in real scenario, this would collect actual system metrics
+	resources := collectResourceUsage(m.ctx)
+
 	status := &SystemStatus{
-		Overall:   "healthy",
+		Overall:   overallHealth(resources),
 		Timestamp: time.Now(),
-		Services: []*ServiceStatus{
-			{
-				Name:      "web-server",
-				Status:    "running",
-				Health:    "healthy",
-				Uptime:    24 * time.Hour,
-				CPU:       15.5,
-				Memory:    45.2,
-				Disk:      12.8,
-				LastCheck: time.Now(),
-				Metadata:  map[string]string{"version": "1.2.3", "port": "8080"},
-			},
-			{
-				Name:      "database",
-				Status:    "running",
-				Health:    "healthy",
-				Uptime:    72 * time.Hour,
-				CPU:       8.3,
-				Memory:    62.7,
-				Disk:      78.1,
-				LastCheck: time.Now(),
-				Metadata:  map[string]string{"version": "13.4", "port": "5432"},
-			},
-		},
-		Resources: &ResourceUsage{
-			CPU: &CPUUsage{
-				Usage:       25.4,
-				LoadAverage: 0.8,
-				Cores:       4,
-			},
-			Memory: &MemoryUsage{
-				Used:      4 * 1024 * 1024 * 1024, // 4GB
-				Available: 4 * 1024 * 1024 * 1024, // 4GB
-				Total:     8 * 1024 * 1024 * 1024, // 8GB
-				Usage:     50.0,
-			},
-			Disk: &DiskUsage{
-				Used:      100 * 1024 * 1024 * 1024, // 100GB
-				Available: 400 * 1024 * 1024 * 1024, // 400GB
-				Total:     500 * 1024 * 1024 * 1024, // 500GB
-				Usage:     20.0,
-			},
-		},
-		Alerts: []*ActiveAlert{},
-		Uptime: 168 * time.Hour, // 7 days
-		Metadata: map[string]string{
-			"hostname": "server-01",
-			"region":   "us-west-2",
-		},
+		Services:  []*ServiceStatus{},
+		Resources: resources,
+		Metadata:  hostMetadata(m.ctx),
 	}
+	status.Alerts = m.activeAlerts(status)
+
+	if uptime, err := hostUptime(m.ctx); err == nil {
+		status.Uptime = uptime
+	}
+	m.recordStatus(status)
 
 	return status, nil
 }
@@ -280,15 +316,13 @@ func (m *MonitorImpl) GetServiceStatus(serviceName string, detailed bool) (*Serv
 		Metadata:  map[string]string{"version": "1.2.3"},
 	}
 
-	if detailed {
-		service.Endpoints = []*EndpointStatus{
-			{
-				URL:          "http://localhost:8080/health",
-				Status:       "healthy",
-				ResponseTime: 25 * time.Millisecond,
-				StatusCode:   200,
-				LastCheck:    time.Now(),
-			},
+	// Configured endpoints are probed for real, and decide the service's health
+	if endpoints := m.probeService(serviceName); endpoints != nil {
+		health, healthy := endpointHealth(endpoints)
+		service.Health = health
+		service.Metadata["endpoints_healthy"] = fmt.Sprintf("%d/%d", healthy, len(endpoints))
+		if detailed {
+			service.Endpoints = endpoints
 		}
 	}
 
@@ -324,7 +358,19 @@ func (m *MonitorImpl) ListServices() ([]*ServiceInfo, error) {
 
 // GetMetrics returns metrics data
 func (m *MonitorImpl) GetMetrics(metric, duration string) (*MetricsData, error) {
-	// Mock implementation
+	return m.GetMetricsContext(m.ctx, metric, duration)
+}
+
+// GetMetricsContext is GetMetrics with the Prometheus query bound to ctx
+func (m *MonitorImpl) GetMetricsContext(ctx context.Context, metric, duration string) (*MetricsData, error) {
+	if m.config != nil && m.config.Monitoring.Prometheus.Endpoint != "" {
+		return m.queryPrometheusRange(ctx, metric, duration)
+	}
+	if m.history != nil {
+		return m.queryHistory(metric, duration)
+	}
+
+	// Mock implementation used when neither Prometheus nor history is configured
 	now := time.Now()
 	data := &MetricsData{
 		Metric:    metric,
@@ -354,32 +400,141 @@ func (m *MonitorImpl) GetMetrics(metric, duration string) (*MetricsData, error)
 	return data, nil
 }
 
+// queryPrometheusRange fetches a metric over the given duration from the
+// configured Prometheus endpoint
+func (m *MonitorImpl) queryPrometheusRange(ctx context.Context, metric, duration string) (*MetricsData, error) {
+	promConfig := m.config.Monitoring.Prometheus
+
+	dur, err := model.ParseDuration(duration)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration %q: %w", duration, err)
+	}
+
+	clientConfig := api.Config{Address: promConfig.Endpoint}
+	if promConfig.Username != "" {
+		clientConfig.RoundTripper = &basicAuthRoundTripper{
+			username: promConfig.Username,
+			password: promConfig.Password,
+			next:     api.DefaultRoundTripper,
+		}
+	}
+	client, err := api.NewClient(clientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Prometheus client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, prometheusQueryTimeout)
+	defer cancel()
+
+	endTime := time.Now()
+	queryRange := v1.Range{
+		Start: endTime.Add(-time.Duration(dur)),
+		End:   endTime,
+		Step:  queryStep(time.Duration(dur)),
+	}
+
+	result, warnings, err := v1.NewAPI(client).QueryRange(ctx, metric, queryRange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Prometheus range for %s: %w", metric, err)
+	}
+
+	matrix, ok := result.(model.Matrix)
+	if !ok {
+		return nil, fmt.Errorf("unexpected Prometheus result type %s for range query", result.Type())
+	}
+
+	data := &MetricsData{
+		Metric:    metric,
+		TimeRange: duration,
+		Data:      []MetricPoint{},
+		StartTime: queryRange.Start,
+		EndTime:   queryRange.End,
+		Metadata: map[string]string{
+			"source": "prometheus",
+			"step":   queryRange.Step.String(),
+			"series": strconv.Itoa(len(matrix)),
+		},
+	}
+	if len(warnings) > 0 {
+		data.Metadata["warnings"] = strings.Join(warnings, "; ")
+	}
+
+	for _, stream := range matrix {
+		labels := make(map[string]string, len(stream.Metric))
+		for name, value := range stream.Metric {
+			labels[string(name)] = string(value)
+		}
+		for _, pair := range stream.Values {
+			data.Data = append(data.Data, MetricPoint{
+				Timestamp: pair.Timestamp.Time(),
+				Value:     float64(pair.Value),
+				Labels:    labels,
+			})
+		}
+	}
+	data.Summary = summarizePoints(data.Data)
+
+	return data, nil
+}
+
+// queryStep picks a step that yields at most maxQueryPoints samples per series
+func queryStep(dur time.Duration) time.Duration {
+	step := (dur / maxQueryPoints).Truncate(time.Second)
+	if step < minQueryStep {
+		return minQueryStep
+	}
+	return step
+}
+
+// summarizePoints computes average, min, max and count over data points
+func summarizePoints(points []MetricPoint) *MetricSummary {
+	summary := &MetricSummary{Count: int64(len(points))}
+	if len(points) == 0 {
+		return summary
+	}
+
+	summary.Min = math.Inf(1)
+	summary.Max = math.Inf(-1)
+	var sum float64
+	for _, point := range points {
+		sum += point.Value
+		summary.Min = math.Min(summary.Min, point.Value)
+		summary.Max = math.Max(summary.Max, point.Value)
+	}
+	summary.Average = sum / float64(len(points))
+
+	return summary
+}
+
+// basicAuthRoundTripper adds basic auth credentials to Prometheus requests
+type basicAuthRoundTripper struct {
+	username string
+	password string
+	next     http.RoundTripper
+}
+
+// RoundTrip sets the basic auth header and forwards the request
+func (rt *basicAuthRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(rt.username, rt.password)
+	return rt.next.RoundTrip(req)
+}
+
 // CreateAlert creates a new alert
 func (m *MonitorImpl) CreateAlert(alert AlertConfig) error {
-	// Mock implementation - in real scenario, this would persist the alert
-	alert.CreatedAt = time.Now()
-	alert.UpdatedAt = time.Now()
-	return nil
+	return m.alerts.Create(alert)
 }
 
 // ListAlerts returns all configured alerts
 func (m *MonitorImpl) ListAlerts() ([]*Alert, error) {
-	// Mock implementation
-	alerts := []*Alert{
-		{
-			RuleName:  "high-cpu",
-			Severity:  "warning",
-			Message:   "CPU usage is above 80%",
-			Timestamp: time.Now().Add(-24 * time.Hour),
-			Value:     85.5,
-		},
-		{
-			RuleName:  "low-memory",
-			Severity:  "critical",
-			Message:   "Memory usage is below 10%",
-			Timestamp: time.Now().Add(-48 * time.Hour),
-			Value:     8.2,
-		},
+	var alerts []*Alert
+	for _, alert := range m.alerts.List() {
+		alerts = append(alerts, &Alert{
+			RuleName:  alert.Name,
+			Severity:  alert.Severity,
+			Message:   alert.Condition,
+			Timestamp: alert.CreatedAt,
+		})
 	}
 
 	return alerts, nil
@@ -387,73 +542,49 @@ func (m *MonitorImpl) ListAlerts() ([]*Alert, error) {
 
 // DeleteAlert deletes an alert by name
 func (m *MonitorImpl) DeleteAlert(name string) error {
-	// Mock implementation - in real scenario, this would remove the alert
-	return nil
+	return m.alerts.Delete(name)
 }
 
 // StartDashboard starts the monitoring dashboard web server
-func (m *MonitorImpl) StartDashboard(host string, port int) error {
-	mux := http.NewServeMux()
+func (m *MonitorImpl) StartDashboard(ctx context.Context, host string, port int) error {
+	done := make(chan struct{})
 
-	// Prometheus metrics endpoint
-	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	server := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", host, port),
+		Handler: m.dashboardHandler(done),
+	}
 
-	// Health check endpoint
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
+	// Stop live event streams so shutdown is not held open by them
+	server.RegisterOnShutdown(func() {
+		close(done)
 	})
 
-	// Simple dashboard endpoint
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		html := `
-<!DOCTYPE html>
-<html>
-<head>
-    <title>AlloraCLI Monitoring Dashboard</title>
-    <style>
-        body { font-family: Arial, sans-serif; margin: 20px; }
-        .metric { margin: 10px 0; padding: 10px; border: 1px solid #ddd; }
-        .status-ok { color: green; }
-        .status-warning { color: orange; }
-        .status-error { color: red; }
-    </style>
-</head>
-<body>
-    <h1>AlloraCLI Monitoring Dashboard</h1>
-    <div class="metric">
-        <h3>System Status: <span class="status-ok">Healthy</span></h3>
-    </div>
-    <div class="metric">
-        <h3>Services</h3>
-        <ul>
-            <li>Web Server: <span class="status-ok">Running</span></li>
-            <li>Database: <span class="status-ok">Running</span></li>
-            <li>Redis: <span class="status-ok">Running</span></li>
-        </ul>
-    </div>
-    <div class="metric">
-        <h3>Resources</h3>
-        <ul>
-            <li>CPU: 25.4%</li>
-            <li>Memory: 50.0%</li>
-            <li>Disk: 20.0%</li>
-        </ul>
-    </div>
-    <p><a href="/metrics">Prometheus Metrics</a></p>
-</body>
-</html>
-		`
-		w.Header().Set("Content-Type", "text/html")
-		w.Write([]byte(html))
-	})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
 
-	server := &http.Server{
-		Addr:    fmt.Sprintf("%s:%d", host, port),
-		Handler: mux,
+	select {
+	case err := <-errCh:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
 	}
 
-	return server.ListenAndServe()
+	// The caller's context is already cancelled, so give in-flight requests
+	// a bounded grace period of their own
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), dashboardShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down dashboard: %w", err)
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
 // MonitoringManager manages multiple monitors
@@ -580,14 +711,18 @@ func (m *MonitoringManager) StopMonitoring(name string) error {
 
 // AlertManager manages alerts
 type AlertManager struct {
-	rules map[string]*AlertRule
-	mutex sync.RWMutex
+	rules    map[string]*AlertRule
+	notifier notify.Notifier
+	// firing holds the rules triggered by the last evaluation
+	firing map[string]bool
+	mutex  sync.RWMutex
 }
 
 // NewAlertManager creates a new alert manager
 func NewAlertManager() *AlertManager {
 	return &AlertManager{
-		rules: make(map[string]*AlertRule),
+		rules:  make(map[string]*AlertRule),
+		firing: make(map[string]bool),
 	}
 }
 
@@ -600,6 +735,25 @@ func (m *AlertManager) AddRule(rule *AlertRule) error {
 	return nil
 }
 
+// SetNotifier sends the alerts triggered by EvaluateRules to notifier; nil stops notifications
+func (m *AlertManager) SetNotifier(notifier notify.Notifier) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.notifier = notifier
+}
+
+// SetRules replaces the alert rules
+func (m *AlertManager) SetRules(rules []*AlertRule) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.rules = make(map[string]*AlertRule, len(rules))
+	for _, rule := range rules {
+		m.rules[rule.Name] = rule
+	}
+}
+
 // GetRule retrieves an alert rule by name
 func (m *AlertManager) GetRule(name string) (*AlertRule, error) {
 	m.mutex.RLock()
@@ -612,29 +766,84 @@ func (m *AlertManager) GetRule(name string) (*AlertRule, error) {
 	return rule, nil
 }
 
-// EvaluateRules evaluates all alert rules against the given metrics
+// EvaluateRules evaluates all alert rules against the given metrics and
+// sends the alerts of rules that were not triggered by the previous
+// evaluation to the notifier, if one is set, so that an alert is sent once
+// each time it starts firing
 func (m *AlertManager) EvaluateRules(ctx context.Context, metrics []*Metric) ([]*Alert, error) {
+	alerts, errs := m.evaluate(metrics)
+
+	m.mutex.Lock()
+	notifier := m.notifier
+	var started []*Alert
+	firing := make(map[string]bool, len(alerts))
+	for _, alert := range alerts {
+		if !m.firing[alert.RuleName] && !firing[alert.RuleName] {
+			started = append(started, alert)
+		}
+		firing[alert.RuleName] = true
+	}
+	m.firing = firing
+	m.mutex.Unlock()
+
+	if notifier != nil {
+		for _, alert := range started {
+			if err := notifier.Notify(ctx, alert.notification()); err != nil {
+				errs = append(errs, fmt.Errorf("failed to notify alert %s: %w", alert.RuleName, err))
+			}
+		}
+	}
+	return alerts, errors.Join(errs...)
+}
+
+// evaluate returns the alerts the enabled rules trigger for metrics
+func (m *AlertManager) evaluate(metrics []*Metric) ([]*Alert, []error) {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
 	var alerts []*Alert
+	var errs []error
 	for _, rule := range m.rules {
-		if rule.Enabled {
-			// Simple evaluation logic for testing
-			for _, metric := range metrics {
-				if shouldTriggerAlert(rule, metric) {
-					alerts = append(alerts, &Alert{
-						RuleName:  rule.Name,
-						Severity:  rule.Severity,
-						Message:   fmt.Sprintf("%s: %s", rule.Name, rule.Description),
-						Timestamp: time.Now(),
-						Value:     metric.Value,
-					})
-				}
+		if !rule.Enabled {
+			continue
+		}
+
+		condition, err := ParseCondition(rule.Condition)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", rule.Name, err))
+			continue
+		}
+
+		for _, metric := range metrics {
+			triggered, err := condition.Evaluate(metric)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("rule %s: %w", rule.Name, err))
+				continue
+			}
+			if triggered {
+				alerts = append(alerts, &Alert{
+					RuleName:  rule.Name,
+					Severity:  rule.Severity,
+					Message:   fmt.Sprintf("%s: %s", rule.Name, rule.Description),
+					Timestamp: time.Now(),
+					Value:     metric.Value,
+				})
 			}
 		}
 	}
-	return alerts, nil
+	return alerts, errs
+}
+
+// notification describes the alert for notification sinks
+func (a *Alert) notification() notify.Notification {
+	return notify.Notification{
+		Name:      a.RuleName,
+		Source:    "alert",
+		Severity:  a.Severity,
+		Message:   a.Message,
+		Value:     a.Value,
+		Timestamp: a.Timestamp,
+	}
 }
 
 // HealthChecker manages health checks
@@ -850,6 +1059,9 @@ type MonitorConfig struct {
 	Password string        `json:"password"`
 	Timeout  int           `json:"timeout"`
 
+	// DashboardInterval is how often the dashboard pushes live status updates
+	DashboardInterval time.Duration `json:"dashboard_interval"`
+
 	// Prometheus configuration
 	Prometheus PrometheusConfig `json:"prometheus"`
 
@@ -873,17 +1085,6 @@ type GrafanaConfig struct {
 	Timeout int    `json:"timeout"`
 }
 
-// shouldTriggerAlert is a simple helper function for testing
-func shouldTriggerAlert(rule *AlertRule, metric *Metric) bool {
-	// Simple condition check for testing
-	if rule.Condition == "cpu_usage > 80" && metric.Name == "cpu_usage" {
-		if val, ok := metric.Value.(float64); ok {
-			return val > 80
-		}
-	}
-	return false
-}
-
 // GetName returns the monitor name
 func (m *MonitorImpl) GetName() string {
 	return "system-monitor"
@@ -909,8 +1110,11 @@ func (m *MonitorImpl) Start() error {
 	return nil
 }
 
-// Stop stops the monitor
+// Stop stops the monitor, waiting for queued alert notifications to be sent
 func (m *MonitorImpl) Stop() error {
+	if m.notifications != nil {
+		m.notifications.Close()
+	}
 	return nil
 }
 
@@ -935,16 +1139,20 @@ func (m *MonitorImpl) CollectMetrics(ctx context.Context) ([]*Metric, error) {
 // GetConfiguration returns the monitor configuration
 func (m *MonitorImpl) GetConfiguration() *MonitorConfig {
 	return &MonitorConfig{
-		Name:     m.GetName(),
-		Category: m.GetCategory(),
-		Interval: m.GetInterval(),
-		Enabled:  true,
+		Name:              m.GetName(),
+		Category:          m.GetCategory(),
+		Interval:          m.GetInterval(),
+		Enabled:           true,
+		DashboardInterval: m.getDashboardInterval(),
 	}
 }
 
 // UpdateConfiguration updates the monitor configuration
 func (m *MonitorImpl) UpdateConfiguration(config *MonitorConfig) error {
-	// Update configuration logic here
+	if config.DashboardInterval < 0 {
+		return fmt.Errorf("dashboard interval must not be negative")
+	}
+	m.dashboardInterval = config.DashboardInterval
 	return nil
 }
 
@@ -1,9 +1,23 @@
 package monitor
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/notify"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func TestMonitoringManager(t *testing.T) {
@@ -237,6 +251,651 @@ func TestDashboard(t *testing.T) {
 	}
 }
 
+func TestGetSystemStatus(t *testing.T) {
+	m := &MonitorImpl{ctx: context.Background()}
+
+	status, err := m.GetSystemStatus()
+	if err != nil {
+		t.Fatalf("GetSystemStatus() failed: %v", err)
+	}
+
+	if status.Resources == nil {
+		t.Fatal("Expected resource usage")
+	}
+	if cpu := status.Resources.CPU; cpu != nil && cpu.Cores <= 0 {
+		t.Errorf("Expected positive core count, got %d", cpu.Cores)
+	}
+	if memory := status.Resources.Memory; memory != nil {
+		if memory.Total <= 0 || memory.Usage < 0 || memory.Usage > 100 {
+			t.Errorf("Unexpected memory usage: %+v", memory)
+		}
+	}
+	if disk := status.Resources.Disk; disk != nil && disk.Total <= 0 {
+		t.Errorf("Expected positive disk total, got %d", disk.Total)
+	}
+	if status.Metadata["os"] == "" {
+		t.Error("Expected host OS metadata")
+	}
+}
+
+func TestOverallHealth(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources *ResourceUsage
+		expected  string
+	}{
+		{"no stats available", &ResourceUsage{}, "healthy"},
+		{"normal usage", &ResourceUsage{CPU: &CPUUsage{Usage: 20}, Memory: &MemoryUsage{Usage: 40}, Disk: &DiskUsage{Usage: 50}}, "healthy"},
+		{"high cpu", &ResourceUsage{CPU: &CPUUsage{Usage: 95}}, "warning"},
+		{"full disk", &ResourceUsage{CPU: &CPUUsage{Usage: 95}, Disk: &DiskUsage{Usage: 97}}, "critical"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overallHealth(tt.resources); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetServiceStatusProbesEndpoints(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	cfg := &config.Config{}
+	cfg.Monitoring.Services = map[string]config.ServiceConfig{
+		"api":    {Endpoints: []string{ok.URL, failing.URL, slow.URL, slow.URL + "/ready"}, Timeout: "200ms"},
+		"web":    {Endpoints: []string{ok.URL}},
+		"worker": {Endpoints: []string{failing.URL}},
+	}
+	m := &MonitorImpl{config: cfg, ctx: context.Background()}
+
+	start := time.Now()
+	service, err := m.GetServiceStatus("api", true)
+	if err != nil {
+		t.Fatalf("GetServiceStatus() failed: %v", err)
+	}
+	// Both slow endpoints time out together when probed concurrently
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected concurrent probes, took %s", elapsed)
+	}
+	if service.Health != "degraded" || service.Metadata["endpoints_healthy"] != "1/4" {
+		t.Errorf("Expected degraded with 1/4 endpoints healthy, got %s %s", service.Health, service.Metadata["endpoints_healthy"])
+	}
+	if len(service.Endpoints) != 4 {
+		t.Fatalf("Expected 4 endpoints, got %d", len(service.Endpoints))
+	}
+	expected := []struct {
+		status string
+		code   int
+	}{{"healthy", 200}, {"unhealthy", 500}, {"unhealthy", 0}, {"unhealthy", 0}}
+	for i, endpoint := range service.Endpoints {
+		if endpoint.Status != expected[i].status || endpoint.StatusCode != expected[i].code {
+			t.Errorf("Endpoint %d: expected %s %d, got %s %d", i, expected[i].status, expected[i].code, endpoint.Status, endpoint.StatusCode)
+		}
+		if endpoint.LastCheck.IsZero() {
+			t.Errorf("Endpoint %d has no last check time", i)
+		}
+	}
+	if !strings.Contains(service.Endpoints[2].Error, "no response within 200ms") {
+		t.Errorf("Expected a timeout error, got %q", service.Endpoints[2].Error)
+	}
+
+	for name, health := range map[string]string{"web": "healthy", "worker": "unhealthy"} {
+		service, err := m.GetServiceStatus(name, false)
+		if err != nil {
+			t.Fatalf("GetServiceStatus(%s) failed: %v", name, err)
+		}
+		if service.Health != health {
+			t.Errorf("%s: expected %s, got %s", name, health, service.Health)
+		}
+		if service.Endpoints != nil {
+			t.Errorf("%s: expected no endpoint details without detailed", name)
+		}
+	}
+}
+
+func TestMetricStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.db")
+	store, err := OpenMetricStore(path)
+	if err != nil {
+		t.Fatalf("OpenMetricStore() failed: %v", err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	var points []MetricPoint
+	for i := 0; i < 5; i++ {
+		points = append(points, MetricPoint{
+			Timestamp: now.Add(time.Duration(i-4) * time.Hour),
+			Value:     float64(10 * i),
+			Labels:    map[string]string{"host": "web-1"},
+		})
+	}
+	if err := store.Store("cpu_usage", points...); err != nil {
+		t.Fatalf("Store() failed: %v", err)
+	}
+	if err := store.Store("memory_usage", MetricPoint{Timestamp: now, Value: 50}); err != nil {
+		t.Fatalf("Store() failed: %v", err)
+	}
+	if err := store.StoreStatus(&SystemStatus{Overall: "warning", Timestamp: now}); err != nil {
+		t.Fatalf("StoreStatus() failed: %v", err)
+	}
+	store.Close()
+
+	// The schema and data survive reopening
+	store, err = OpenMetricStore(path)
+	if err != nil {
+		t.Fatalf("OpenMetricStore() failed on reopen: %v", err)
+	}
+	defer store.Close()
+
+	got, err := store.Query("cpu_usage", now.Add(-2*time.Hour), now)
+	if err != nil {
+		t.Fatalf("Query() failed: %v", err)
+	}
+	if len(got) != 3 || got[0].Value != 20 || got[2].Value != 40 {
+		t.Fatalf("Expected the last 3 cpu points oldest first, got %+v", got)
+	}
+	if !got[2].Timestamp.Equal(now) || got[2].Labels["host"] != "web-1" {
+		t.Errorf("Point not stored faithfully: %+v", got[2])
+	}
+
+	snapshots, err := store.QueryStatus(now.Add(-time.Minute), now)
+	if err != nil {
+		t.Fatalf("QueryStatus() failed: %v", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].Overall != "warning" {
+		t.Errorf("Expected the warning snapshot, got %+v", snapshots)
+	}
+
+	removed, err := store.Prune(now.Add(-90 * time.Minute))
+	if err != nil {
+		t.Fatalf("Prune() failed: %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("Expected 3 pruned points, got %d", removed)
+	}
+	if got, _ := store.Query("cpu_usage", now.Add(-24*time.Hour), now); len(got) != 2 {
+		t.Errorf("Expected 2 cpu points after pruning, got %d", len(got))
+	}
+}
+
+func TestGetMetricsFromHistory(t *testing.T) {
+	store, err := OpenMetricStore(filepath.Join(t.TempDir(), "metrics.db"))
+	if err != nil {
+		t.Fatalf("OpenMetricStore() failed: %v", err)
+	}
+	defer store.Close()
+	m := &MonitorImpl{config: &config.Config{}, ctx: context.Background(), history: store}
+
+	status := &SystemStatus{
+		Overall:   "healthy",
+		Timestamp: time.Now(),
+		Resources: &ResourceUsage{CPU: &CPUUsage{Usage: 30}, Memory: &MemoryUsage{Usage: 60}},
+		Metadata:  map[string]string{"hostname": "web-1"},
+	}
+	m.recordStatus(status)
+	status.Timestamp = status.Timestamp.Add(-time.Minute)
+	status.Resources.CPU.Usage = 50
+	m.recordStatus(status)
+
+	data, err := m.GetMetrics("cpu_usage", "1h")
+	if err != nil {
+		t.Fatalf("GetMetrics() failed: %v", err)
+	}
+	if data.Metadata["source"] != "history" || len(data.Data) != 2 {
+		t.Fatalf("Expected 2 points from history, got %+v", data)
+	}
+	if data.Summary.Average != 40 || data.Summary.Max != 50 {
+		t.Errorf("Unexpected summary: %+v", data.Summary)
+	}
+	if data.Data[0].Labels["host"] != "web-1" {
+		t.Errorf("Expected host label, got %v", data.Data[0].Labels)
+	}
+	if data, _ := m.GetMetrics("disk_usage", "1h"); len(data.Data) != 0 {
+		t.Errorf("Expected no disk points, got %d", len(data.Data))
+	}
+}
+
+// fakeGrafana serves the dashboard endpoints of the Grafana HTTP API
+type fakeGrafana struct {
+	apiKey string
+	saved  map[string]interface{}
+	saves  int
+}
+
+func (g *fakeGrafana) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+g.apiKey {
+		http.Error(w, `{"message":"invalid API key"}`, http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/dashboards/uid/"+grafanaDashboardUID:
+		if g.saved == nil {
+			http.Error(w, `{"message":"Dashboard not found"}`, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"dashboard": g.saved, "meta": map[string]interface{}{}})
+	case r.Method == http.MethodPost && r.URL.Path == "/api/dashboards/db":
+		var body struct {
+			Dashboard map[string]interface{} `json:"dashboard"`
+			Overwrite bool                   `json:"overwrite"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if g.saved != nil && !body.Overwrite {
+			http.Error(w, `{"message":"dashboard exists"}`, http.StatusPreconditionFailed)
+			return
+		}
+		g.saves++
+		body.Dashboard["id"] = 7
+		body.Dashboard["version"] = g.saves
+		g.saved = body.Dashboard
+		json.NewEncoder(w).Encode(map[string]interface{}{"uid": grafanaDashboardUID, "status": "success", "version": g.saves})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestProvisionGrafanaDashboard(t *testing.T) {
+	grafana := &fakeGrafana{apiKey: "grafana-key"}
+	server := httptest.NewServer(grafana)
+	defer server.Close()
+
+	cfg := &config.Config{}
+	cfg.Monitoring.Grafana = config.GrafanaConfig{Endpoint: server.URL, APIKey: "grafana-key"}
+	m := &MonitorImpl{config: cfg, ctx: context.Background()}
+
+	for i, expected := range []string{"created", "unchanged"} {
+		dashboard, err := m.ProvisionGrafanaDashboard(context.Background())
+		if err != nil {
+			t.Fatalf("ProvisionGrafanaDashboard() #%d failed: %v", i+1, err)
+		}
+		if dashboard.Status != expected || dashboard.Version != 1 {
+			t.Errorf("Run %d: expected %s at version 1, got %s at %d", i+1, expected, dashboard.Status, dashboard.Version)
+		}
+		if dashboard.URL != server.URL+"/d/"+grafanaDashboardUID {
+			t.Errorf("Unexpected dashboard URL %s", dashboard.URL)
+		}
+	}
+	if grafana.saves != 1 {
+		t.Errorf("Expected one save for two identical runs, got %d", grafana.saves)
+	}
+
+	panels, _ := grafana.saved["panels"].([]interface{})
+	var exprs []string
+	for _, panel := range panels {
+		targets := panel.(map[string]interface{})["targets"].([]interface{})
+		exprs = append(exprs, targets[0].(map[string]interface{})["expr"].(string))
+	}
+	for _, metric := range []string{"allora_system_cpu_usage_percent", "allora_system_memory_usage_percent", "allora_system_disk_usage_percent", "allora_alerts_active"} {
+		if !strings.Contains(strings.Join(exprs, " "), metric) {
+			t.Errorf("Expected a panel querying %s, got %v", metric, exprs)
+		}
+	}
+
+	// A dashboard edited in Grafana is put back
+	grafana.saved["panels"] = panels[:1]
+	dashboard, err := m.ProvisionGrafanaDashboard(context.Background())
+	if err != nil {
+		t.Fatalf("ProvisionGrafanaDashboard() failed: %v", err)
+	}
+	if dashboard.Status != "updated" || dashboard.Version != 2 {
+		t.Errorf("Expected updated at version 2, got %s at %d", dashboard.Status, dashboard.Version)
+	}
+
+	cfg.Monitoring.Grafana.APIKey = "wrong-key"
+	if _, err := m.ProvisionGrafanaDashboard(context.Background()); err == nil || !strings.Contains(err.Error(), "rejected the credentials") {
+		t.Errorf("Expected a rejected credentials error, got %v", err)
+	}
+
+	cfg.Monitoring.Grafana.APIKey = ""
+	if _, err := m.ProvisionGrafanaDashboard(context.Background()); err == nil || !strings.Contains(err.Error(), "monitoring.grafana.api_key") {
+		t.Errorf("Expected a missing credentials error, got %v", err)
+	}
+}
+
+func TestConditionOperators(t *testing.T) {
+	tests := []struct {
+		condition string
+		value     interface{}
+		expected  bool
+	}{
+		{"cpu_usage > 80", 85.5, true},
+		{"cpu_usage > 80", 80.0, false},
+		{"cpu_usage >= 80", 80, true},
+		{"cpu_usage >= 80", int64(79), false},
+		{"cpu_usage < 10", float32(5), true},
+		{"cpu_usage < 10", 10.0, false},
+		{"cpu_usage <= 10", "10", true},
+		{"cpu_usage <= 10", 10.5, false},
+		{"cpu_usage == 42", 42, true},
+		{"cpu_usage == 42", 41.9, false},
+		{"cpu_usage != 0", 1.0, true},
+		{"cpu_usage!=0", uint64(0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			condition, err := ParseCondition(tt.condition)
+			if err != nil {
+				t.Fatalf("ParseCondition() failed: %v", err)
+			}
+
+			triggered, err := condition.Evaluate(&Metric{Name: "cpu_usage", Value: tt.value})
+			if err != nil {
+				t.Fatalf("Evaluate() failed: %v", err)
+			}
+			if triggered != tt.expected {
+				t.Errorf("Expected %q with value %v to be %v", tt.condition, tt.value, tt.expected)
+			}
+		})
+	}
+
+	// Metrics with a different name never trigger
+	condition, _ := ParseCondition("cpu_usage > 80")
+	if triggered, err := condition.Evaluate(&Metric{Name: "memory_usage", Value: 99.0}); err != nil || triggered {
+		t.Errorf("Expected non-matching metric to be ignored, got %v (%v)", triggered, err)
+	}
+}
+
+func TestMalformedCondition(t *testing.T) {
+	for _, expr := range []string{"", "cpu_usage", "cpu_usage >", "cpu_usage => 80", "cpu_usage > high", "> 80"} {
+		if _, err := ParseCondition(expr); err == nil {
+			t.Errorf("Expected ParseCondition(%q) to fail", expr)
+		}
+	}
+
+	alertManager := NewAlertManager()
+	alertManager.AddRule(&AlertRule{Name: "broken", Condition: "cpu_usage is high", Severity: "warning", Enabled: true})
+	alertManager.AddRule(&AlertRule{Name: "high-memory", Condition: "memory_usage >= 90", Severity: "critical", Enabled: true})
+
+	metrics := []*Metric{
+		{Name: "cpu_usage", Value: 95.0},
+		{Name: "memory_usage", Value: 91.0},
+	}
+
+	alerts, err := alertManager.EvaluateRules(context.Background(), metrics)
+	if err == nil {
+		t.Fatal("Expected EvaluateRules() to report the malformed condition")
+	}
+	if !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Expected error to name the broken rule, got: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].RuleName != "high-memory" {
+		t.Errorf("Expected valid rules to still fire, got %+v", alerts)
+	}
+}
+
+func TestAlertPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.json")
+
+	store, err := newAlertStore(path)
+	if err != nil {
+		t.Fatalf("newAlertStore() failed: %v", err)
+	}
+	m := &MonitorImpl{alerts: store}
+
+	if err := m.CreateAlert(AlertConfig{Name: "high-cpu", Condition: "cpu_usage > 80", Severity: "warning", Enabled: true}); err != nil {
+		t.Fatalf("CreateAlert() failed: %v", err)
+	}
+	if err := m.CreateAlert(AlertConfig{Name: "low-disk", Condition: "disk_free < 10", Severity: "critical", Enabled: true}); err != nil {
+		t.Fatalf("CreateAlert() failed: %v", err)
+	}
+
+	// Duplicate names and malformed conditions are rejected
+	if err := m.CreateAlert(AlertConfig{Name: "high-cpu", Condition: "cpu_usage > 90"}); err == nil {
+		t.Error("Expected duplicate alert name to be rejected")
+	}
+	if err := m.CreateAlert(AlertConfig{Name: "broken", Condition: "cpu > 80%"}); err == nil {
+		t.Error("Expected malformed condition to be rejected")
+	}
+
+	if err := m.DeleteAlert("low-disk"); err != nil {
+		t.Fatalf("DeleteAlert() failed: %v", err)
+	}
+	if err := m.DeleteAlert("low-disk"); err == nil {
+		t.Error("Expected deleting a missing alert to fail")
+	}
+
+	// A fresh store sees what the previous one persisted
+	reloaded, err := newAlertStore(path)
+	if err != nil {
+		t.Fatalf("newAlertStore() reload failed: %v", err)
+	}
+	alerts, err := (&MonitorImpl{alerts: reloaded}).ListAlerts()
+	if err != nil {
+		t.Fatalf("ListAlerts() failed: %v", err)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("Expected 1 persisted alert, got %d", len(alerts))
+	}
+	if alerts[0].RuleName != "high-cpu" || alerts[0].Severity != "warning" || alerts[0].Message != "cpu_usage > 80" {
+		t.Errorf("Unexpected persisted alert: %+v", alerts[0])
+	}
+	if alerts[0].Timestamp.IsZero() {
+		t.Error("Expected persisted creation time")
+	}
+}
+
+func TestGetMetricsFromPrometheus(t *testing.T) {
+	start := time.Now().Add(-time.Hour).Unix()
+
+	var query url.Values
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query_range" {
+			http.NotFound(w, r)
+			return
+		}
+		r.ParseForm()
+		query = r.Form
+
+		w.Header().Set("Content-Type", "application/json")
+		if failing {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"status":"error","errorType":"bad_data","error":"parse error"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"instance":"a"},"values":[[%d,"10"],[%d,"30"]]},
+			{"metric":{"instance":"b"},"values":[[%d,"50"]]}
+		]}}`, start, start+60, start)
+	}))
+	defer server.Close()
+
+	m := &MonitorImpl{
+		ctx: context.Background(),
+		config: &config.Config{
+			Monitoring: config.MonitoringConfig{
+				Prometheus: config.PrometheusConfig{Endpoint: server.URL},
+			},
+		},
+	}
+
+	data, err := m.GetMetrics("cpu_usage", "1h")
+	if err != nil {
+		t.Fatalf("GetMetrics() failed: %v", err)
+	}
+
+	if query.Get("query") != "cpu_usage" {
+		t.Errorf("Expected query 'cpu_usage', got %q", query.Get("query"))
+	}
+	if query.Get("step") != "15" {
+		t.Errorf("Expected a 15s step for a 1h range, got %q", query.Get("step"))
+	}
+
+	if len(data.Data) != 3 {
+		t.Fatalf("Expected 3 data points, got %d", len(data.Data))
+	}
+	if data.Data[2].Labels["instance"] != "b" {
+		t.Errorf("Expected series labels on points, got %v", data.Data[2].Labels)
+	}
+	if data.Summary.Count != 3 || data.Summary.Min != 10 || data.Summary.Max != 50 || data.Summary.Average != 30 {
+		t.Errorf("Unexpected summary: %+v", data.Summary)
+	}
+
+	// Multi-day ranges are accepted and use a coarser step
+	if _, err := m.GetMetrics("cpu_usage", "7d"); err != nil {
+		t.Fatalf("GetMetrics() with 7d failed: %v", err)
+	}
+	if query.Get("step") != "2419" {
+		t.Errorf("Expected a 2419s step for a 7d range, got %q", query.Get("step"))
+	}
+
+	// Server errors are surfaced instead of falling back to mock data
+	failing = true
+	if _, err := m.GetMetrics("cpu_usage{", "1h"); err == nil {
+		t.Error("Expected error from failing Prometheus query")
+	}
+}
+
+func TestRegisterCollectors(t *testing.T) {
+	store, err := newAlertStore(filepath.Join(t.TempDir(), "alerts.json"))
+	if err != nil {
+		t.Fatalf("newAlertStore() failed: %v", err)
+	}
+	store.Create(AlertConfig{Name: "high-cpu", Condition: "cpu_usage > 80", Enabled: true})
+
+	m := &MonitorImpl{
+		ctx:      context.Background(),
+		registry: prometheus.NewRegistry(),
+		alerts:   store,
+	}
+	if err := m.RegisterCollectors(); err != nil {
+		t.Fatalf("RegisterCollectors() failed: %v", err)
+	}
+
+	families, err := m.registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() failed: %v", err)
+	}
+
+	values := make(map[string]float64)
+	for _, family := range families {
+		if metrics := family.GetMetric(); len(metrics) > 0 && metrics[0].GetGauge() != nil {
+			values[family.GetName()] = metrics[0].GetGauge().GetValue()
+		}
+	}
+
+	if values["allora_scrape_success"] != 1 {
+		t.Errorf("Expected successful scrape, got %v", values["allora_scrape_success"])
+	}
+	if values["allora_alerts_configured"] != 1 {
+		t.Errorf("Expected 1 configured alert, got %v", values["allora_alerts_configured"])
+	}
+	if _, ok := values["allora_system_uptime_seconds"]; !ok {
+		t.Error("Expected uptime gauge to be exported")
+	}
+	if _, ok := values["go_goroutines"]; !ok {
+		t.Error("Expected Go runtime metrics to be exported")
+	}
+}
+
+func TestDashboardEvents(t *testing.T) {
+	m := &MonitorImpl{
+		ctx:      context.Background(),
+		registry: prometheus.NewRegistry(),
+	}
+	if err := m.UpdateConfiguration(&MonitorConfig{DashboardInterval: 50 * time.Millisecond}); err != nil {
+		t.Fatalf("UpdateConfiguration() failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	server := httptest.NewServer(m.dashboardHandler(done))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events")
+	if err != nil {
+		t.Fatalf("GET /events failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %q", ct)
+	}
+
+	// Read two snapshots to confirm updates are pushed periodically
+	reader := bufio.NewReader(resp.Body)
+	snapshots := 0
+	for snapshots < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event stream: %v", err)
+		}
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		var status SystemStatus
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &status); err != nil {
+			t.Fatalf("Failed to decode status event: %v", err)
+		}
+		if status.Overall == "" {
+			t.Error("Expected overall status in snapshot")
+		}
+		snapshots++
+	}
+
+	// Closing done ends the stream
+	close(done)
+	if _, err := io.ReadAll(reader); err != nil {
+		t.Errorf("Expected stream to end cleanly, got %v", err)
+	}
+}
+
+func TestStartDashboardShutdown(t *testing.T) {
+	m := &MonitorImpl{
+		ctx:      context.Background(),
+		registry: prometheus.NewRegistry(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- m.StartDashboard(ctx, "127.0.0.1", 0)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("StartDashboard() did not return after context cancellation")
+	}
+
+	// Listen errors are returned rather than swallowed
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve a port: %v", err)
+	}
+	defer listener.Close()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	if err := m.StartDashboard(context.Background(), "127.0.0.1", port); err == nil {
+		t.Error("Expected error when the port is already in use")
+	}
+}
+
 func BenchmarkMetricsCollection(b *testing.B) {
 	monitor := &MockMonitor{
 		name:     "benchmark-monitor",
@@ -361,6 +1020,10 @@ func (m *MockMonitor) ListServices() ([]*ServiceInfo, error) {
 	}, nil
 }
 
+func (m *MockMonitor) GetMetricsContext(ctx context.Context, metric, duration string) (*MetricsData, error) {
+	return m.GetMetrics(metric, duration)
+}
+
 func (m *MockMonitor) GetMetrics(metric, duration string) (*MetricsData, error) {
 	return &MetricsData{
 		Metric:    metric,
@@ -396,7 +1059,243 @@ func (m *MockMonitor) DeleteAlert(name string) error {
 	return nil
 }
 
-func (m *MockMonitor) StartDashboard(host string, port int) error {
+func (m *MockMonitor) StartDashboard(ctx context.Context, host string, port int) error {
 	// Mock implementation
 	return nil
 }
+
+func (m *MockMonitor) ProvisionGrafanaDashboard(ctx context.Context) (*GrafanaDashboard, error) {
+	// Mock implementation
+	return &GrafanaDashboard{UID: grafanaDashboardUID, Status: "unchanged"}, nil
+}
+
+// recordingNotifier records notifications, failing with err if set
+type recordingNotifier struct {
+	notifications []notify.Notification
+	err           error
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, notification notify.Notification) error {
+	r.notifications = append(r.notifications, notification)
+	return r.err
+}
+
+func TestEvaluateRulesNotifies(t *testing.T) {
+	alertManager := NewAlertManager()
+	alertManager.AddRule(&AlertRule{Name: "high-memory", Condition: "memory_usage >= 90", Severity: "critical", Enabled: true})
+	notifier := &recordingNotifier{}
+	alertManager.SetNotifier(notifier)
+
+	metrics := []*Metric{{Name: "memory_usage", Value: 91.0}}
+	alerts, err := alertManager.EvaluateRules(context.Background(), metrics)
+	if err != nil {
+		t.Fatalf("EvaluateRules() failed: %v", err)
+	}
+	if len(alerts) != 1 || len(notifier.notifications) != 1 {
+		t.Fatalf("expected the triggered alert to be sent, got %d alerts and %d notifications", len(alerts), len(notifier.notifications))
+	}
+	sent := notifier.notifications[0]
+	if sent.Name != "high-memory" || sent.Severity != "critical" || sent.Value != 91.0 || sent.Timestamp.IsZero() {
+		t.Errorf("unexpected notification %+v", sent)
+	}
+
+	// A rule that keeps firing is sent once, and again after it clears
+	if _, err := alertManager.EvaluateRules(context.Background(), metrics); err != nil || len(notifier.notifications) != 1 {
+		t.Fatalf("expected an alert that is still firing not to be sent again, got %d notifications and %v", len(notifier.notifications), err)
+	}
+	if alerts, _ := alertManager.EvaluateRules(context.Background(), []*Metric{{Name: "memory_usage", Value: 50.0}}); len(alerts) != 0 {
+		t.Fatalf("expected the alert to clear, got %+v", alerts)
+	}
+
+	notifier.err = fmt.Errorf("webhook unreachable")
+	alerts, err = alertManager.EvaluateRules(context.Background(), metrics)
+	if err == nil || !strings.Contains(err.Error(), "webhook unreachable") || len(alerts) != 1 || len(notifier.notifications) != 2 {
+		t.Errorf("expected send failures to be reported alongside the alerts, got %v", err)
+	}
+}
+
+func TestActiveAlerts(t *testing.T) {
+	store, err := newAlertStore(filepath.Join(t.TempDir(), "alerts.json"))
+	if err != nil {
+		t.Fatalf("newAlertStore() failed: %v", err)
+	}
+	for _, alert := range []AlertConfig{
+		{Name: "high-cpu", Condition: "cpu_usage > 80", Severity: "critical", Enabled: true},
+		{Name: "high-disk", Condition: "disk_usage > 80", Severity: "warning", Enabled: true},
+		{Name: "disabled", Condition: "cpu_usage > 0", Severity: "critical"},
+	} {
+		if err := store.Create(alert); err != nil {
+			t.Fatalf("Create() failed: %v", err)
+		}
+	}
+
+	notifier := &recordingNotifier{}
+	m := &MonitorImpl{ctx: context.Background(), alerts: store, alertManager: NewAlertManager(), notifications: notify.NewQueue(notifier, notify.DefaultQueueSize)}
+	m.alertManager.SetNotifier(m.notifications)
+
+	status := &SystemStatus{Resources: &ResourceUsage{CPU: &CPUUsage{Usage: 95}, Disk: &DiskUsage{Usage: 40}}}
+	for i := 0; i < 2; i++ {
+		active := m.activeAlerts(status)
+		if len(active) != 1 || active[0].Alert.Name != "high-cpu" || active[0].Status != "firing" {
+			t.Fatalf("expected only high-cpu to fire, got %+v", active)
+		}
+	}
+	m.Stop()
+
+	if len(notifier.notifications) != 1 || notifier.notifications[0].Name != "high-cpu" {
+		t.Errorf("expected high-cpu to be sent once as it started firing, got %+v", notifier.notifications)
+	}
+}
+
+// incidentRecorder records the incidents triggered and resolved, dropping
+// notifications below minSeverity if set
+type incidentRecorder struct {
+	events      []string
+	fail        bool
+	minSeverity string
+}
+
+func (r *incidentRecorder) Wants(notification notify.Notification) bool {
+	return r.minSeverity == "" || notify.SeverityAtLeast(notification.Severity, r.minSeverity)
+}
+
+func (r *incidentRecorder) Notify(ctx context.Context, notification notify.Notification) error {
+	if r.fail {
+		return fmt.Errorf("pagerduty unreachable")
+	}
+	if !r.Wants(notification) {
+		return nil
+	}
+	r.events = append(r.events, "trigger "+notification.Name)
+	return nil
+}
+
+func (r *incidentRecorder) Resolve(ctx context.Context, name string) error {
+	if r.fail {
+		return fmt.Errorf("pagerduty unreachable")
+	}
+	r.events = append(r.events, "resolve "+name)
+	return nil
+}
+
+func TestIncidentSync(t *testing.T) {
+	recorder := &incidentRecorder{}
+	incidents := NewIncidentSync(recorder)
+	ctx := context.Background()
+
+	start := time.Now()
+	active := func(name string, triggered time.Time) *ActiveAlert {
+		return &ActiveAlert{Alert: &AlertConfig{Name: name, Condition: "cpu_usage > 90", Severity: "critical"}, Triggered: triggered, Status: "firing"}
+	}
+
+	steps := []struct {
+		alerts []*ActiveAlert
+		want   []string
+	}{
+		{[]*ActiveAlert{active("high-cpu", start)}, []string{"trigger high-cpu"}},
+		{[]*ActiveAlert{active("high-cpu", start)}, nil},
+		{[]*ActiveAlert{active("high-cpu", start.Add(time.Minute)), active("disk-full", start)}, []string{"trigger disk-full", "trigger high-cpu"}},
+		{[]*ActiveAlert{active("disk-full", start)}, []string{"resolve high-cpu"}},
+		{nil, []string{"resolve disk-full"}},
+	}
+	for i, step := range steps {
+		recorder.events = nil
+		if err := incidents.Sync(ctx, step.alerts); err != nil {
+			t.Fatalf("step %d: Sync() failed: %v", i, err)
+		}
+		if fmt.Sprint(recorder.events) != fmt.Sprint(step.want) {
+			t.Errorf("step %d: got %v, want %v", i, recorder.events, step.want)
+		}
+	}
+
+	recorder.fail = true
+	if err := incidents.Sync(ctx, []*ActiveAlert{active("high-cpu", start)}); err == nil {
+		t.Fatal("expected send failures to be reported")
+	}
+	recorder.fail = false
+	recorder.events = nil
+	if err := incidents.Sync(ctx, []*ActiveAlert{active("high-cpu", start)}); err != nil || fmt.Sprint(recorder.events) != "[trigger high-cpu]" {
+		t.Errorf("expected a failed trigger to be retried, got %v (%v)", recorder.events, err)
+	}
+
+	// Alerts below the notifier's severity never open an incident to resolve
+	recorder.minSeverity = "critical"
+	warning := active("slow-disk", start)
+	warning.Alert.Severity = "warning"
+	for _, alerts := range [][]*ActiveAlert{{active("high-cpu", start), warning}, {active("high-cpu", start)}} {
+		recorder.events = nil
+		if err := incidents.Sync(ctx, alerts); err != nil || len(recorder.events) != 0 {
+			t.Errorf("expected filtered alerts to be left out, got %v (%v)", recorder.events, err)
+		}
+	}
+}
+
+// alertmanagerBody is a webhook notification as sent by Alertmanager
+const alertmanagerBody = `{
+  "version": "4",
+  "groupKey": "{}:{alertname=\"HighLatency\"}",
+  "status": "firing",
+  "receiver": "allora",
+  "alerts": [
+    {
+      "status": "firing",
+      "labels": {"alertname": "HighLatency", "severity": "critical", "instance": "api-1:8080", "job": "api"},
+      "annotations": {"summary": "p99 latency above 2s", "description": "api-1 p99 latency is 3.1s"},
+      "startsAt": "2024-06-01T08:00:00Z",
+      "endsAt": "0001-01-01T00:00:00Z",
+      "generatorURL": "http://prometheus:9090/graph?g0.expr=latency",
+      "fingerprint": "a1b2c3"
+    },
+    {
+      "status": "resolved",
+      "labels": {"alertname": "DiskFull", "service": "db"},
+      "annotations": {},
+      "startsAt": "2024-06-01T07:00:00Z",
+      "endsAt": "2024-06-01T07:30:00Z"
+    }
+  ]
+}`
+
+func TestParseAlertmanagerPayload(t *testing.T) {
+	payload, err := ParseAlertmanagerPayload(strings.NewReader(alertmanagerBody))
+	if err != nil {
+		t.Fatalf("ParseAlertmanagerPayload() failed: %v", err)
+	}
+	if len(payload.Alerts) != 2 {
+		t.Fatalf("expected two alerts, got %d", len(payload.Alerts))
+	}
+
+	firing := &payload.Alerts[0]
+	active := firing.ActiveAlert()
+	if active.Alert.Name != "HighLatency" || active.Alert.Severity != "critical" || active.Alert.Condition != "p99 latency above 2s" {
+		t.Errorf("unexpected alert %+v", active.Alert)
+	}
+	if active.Status != "firing" || active.Message != "api-1 p99 latency is 3.1s" || !active.Triggered.Equal(time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected active alert %+v", active)
+	}
+	if details := active.Notification().Details; details["instance"] != "api-1:8080" || details["job"] != "api" {
+		t.Errorf("expected the labels in the notification details, got %v", details)
+	}
+	if firing.IncidentName() != "HighLatency-a1b2c3" || firing.DiagnosticTarget() != "api-1:8080" {
+		t.Errorf("unexpected incident name %q or target %q", firing.IncidentName(), firing.DiagnosticTarget())
+	}
+
+	resolved := &payload.Alerts[1]
+	if resolved.ActiveAlert().Alert.Severity != "warning" || resolved.DiagnosticTarget() != "db" || resolved.IncidentName() != "DiskFull" {
+		t.Errorf("unexpected resolved alert %+v", resolved.ActiveAlert())
+	}
+
+	for name, tt := range map[string]struct {
+		body string
+		want string
+	}{
+		"not json":       {`alerts`, "invalid Alertmanager payload"},
+		"version":        {`{"version": "3", "alerts": [{}]}`, `unsupported Alertmanager payload version "3"`},
+		"no alerts":      {`{"version": "4", "alerts": []}`, "has no alerts"},
+		"missing fields": {`{"version": "4", "alerts": [{"status": "pending", "labels": {}}]}`, "alerts[0] has no alertname label\nalerts[0] has status \"pending\""},
+	} {
+		if _, err := ParseAlertmanagerPayload(strings.NewReader(tt.body)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", name, tt.want, err)
+		}
+	}
+}
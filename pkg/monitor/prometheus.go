@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/AlloraAi/AlloraCLI/pkg/utils"
 	"github.com/prometheus/client_golang/api"
 	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
@@ -23,8 +24,7 @@ type PrometheusMonitor struct {
 
 // NewPrometheusMonitor creates a new Prometheus monitor
 func NewPrometheusMonitor(endpoint string, config *MonitorConfig) (*PrometheusMonitor, error) {
-	logger := logrus.New()
-	logger.SetLevel(logrus.InfoLevel)
+	logger := utils.NewLogger()
 
 	client, err := api.NewClient(api.Config{
 		Address: endpoint,
@@ -328,7 +328,12 @@ func (m *PrometheusMonitor) ListServices() ([]*ServiceInfo, error) {
 
 // GetMetrics returns historical metrics
 func (m *PrometheusMonitor) GetMetrics(metric, duration string) (*MetricsData, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	return m.GetMetricsContext(context.Background(), metric, duration)
+}
+
+// GetMetricsContext returns historical metrics, querying under ctx
+func (m *PrometheusMonitor) GetMetricsContext(ctx context.Context, metric, duration string) (*MetricsData, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	// Parse duration
@@ -394,7 +399,12 @@ func (m *PrometheusMonitor) DeleteAlert(name string) error {
 }
 
 // StartDashboard starts a monitoring dashboard
-func (m *PrometheusMonitor) StartDashboard(host string, port int) error {
+func (m *PrometheusMonitor) StartDashboard(ctx context.Context, host string, port int) error {
 	// This would typically start a web dashboard
 	return fmt.Errorf("StartDashboard not implemented for Prometheus monitor")
 }
+
+// ProvisionGrafanaDashboard provisions a Grafana dashboard
+func (m *PrometheusMonitor) ProvisionGrafanaDashboard(ctx context.Context) (*GrafanaDashboard, error) {
+	return nil, fmt.Errorf("ProvisionGrafanaDashboard not implemented for Prometheus monitor")
+}
@@ -0,0 +1,281 @@
+package monitor
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite" // registers the "sqlite" database/sql driver
+)
+
+// Metric history defaults
+const (
+	metricsDBFileName       = "metrics.db"
+	defaultHistoryRetention = "7d"
+)
+
+// metricStoreSchema creates the store's tables on first use. Timestamps are
+// Unix nanoseconds so range queries compare integers
+const metricStoreSchema = `
+CREATE TABLE IF NOT EXISTS metric_points (
+	name      TEXT    NOT NULL,
+	timestamp INTEGER NOT NULL,
+	value     REAL    NOT NULL,
+	labels    TEXT    NOT NULL DEFAULT '{}'
+);
+CREATE INDEX IF NOT EXISTS metric_points_name_time ON metric_points (name, timestamp);
+CREATE TABLE IF NOT EXISTS status_snapshots (
+	timestamp INTEGER NOT NULL,
+	overall   TEXT    NOT NULL,
+	status    TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS status_snapshots_time ON status_snapshots (timestamp);
+`
+
+// MetricStore keeps metric points and system status snapshots in a SQLite
+// database, giving local history without a Prometheus server
+type MetricStore struct {
+	db *sql.DB
+}
+
+// OpenMetricStore opens the store at path, creating the database and its
+// schema if they do not exist yet
+func OpenMetricStore(path string) (*MetricStore, error) {
+	// Wait for other processes writing the same file instead of failing
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open metric store: %w", err)
+	}
+	// SQLite allows one writer at a time; a single connection serialises them
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(metricStoreSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create metric store schema: %w", err)
+	}
+	return &MetricStore{db: db}, nil
+}
+
+// Close closes the underlying database
+func (s *MetricStore) Close() error {
+	return s.db.Close()
+}
+
+// Store records points of the named metric
+func (s *MetricStore) Store(name string, points ...MetricPoint) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO metric_points (name, timestamp, value, labels) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, point := range points {
+		labels, err := json.Marshal(point.Labels)
+		if err != nil {
+			return fmt.Errorf("failed to encode labels: %w", err)
+		}
+		if _, err := stmt.Exec(name, point.Timestamp.UnixNano(), point.Value, string(labels)); err != nil {
+			return fmt.Errorf("failed to store %s point: %w", name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit metric points: %w", err)
+	}
+	return nil
+}
+
+// Query returns the points of the named metric from from up to and
+// including to, oldest first
+func (s *MetricStore) Query(name string, from, to time.Time) ([]MetricPoint, error) {
+	rows, err := s.db.Query(
+		"SELECT timestamp, value, labels FROM metric_points WHERE name = ? AND timestamp >= ? AND timestamp <= ? ORDER BY timestamp",
+		name, from.UnixNano(), to.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", name, err)
+	}
+	defer rows.Close()
+
+	points := []MetricPoint{}
+	for rows.Next() {
+		var timestamp int64
+		var point MetricPoint
+		var labels string
+		if err := rows.Scan(&timestamp, &point.Value, &labels); err != nil {
+			return nil, fmt.Errorf("failed to read %s point: %w", name, err)
+		}
+		point.Timestamp = time.Unix(0, timestamp)
+		if err := json.Unmarshal([]byte(labels), &point.Labels); err != nil {
+			return nil, fmt.Errorf("failed to decode labels: %w", err)
+		}
+		points = append(points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", name, err)
+	}
+	return points, nil
+}
+
+// StoreStatus records a system status snapshot
+func (s *MetricStore) StoreStatus(status *SystemStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to encode system status: %w", err)
+	}
+	if _, err := s.db.Exec("INSERT INTO status_snapshots (timestamp, overall, status) VALUES (?, ?, ?)",
+		status.Timestamp.UnixNano(), status.Overall, string(data)); err != nil {
+		return fmt.Errorf("failed to store system status: %w", err)
+	}
+	return nil
+}
+
+// QueryStatus returns the system status snapshots taken from from up to and
+// including to, oldest first
+func (s *MetricStore) QueryStatus(from, to time.Time) ([]*SystemStatus, error) {
+	rows, err := s.db.Query("SELECT status FROM status_snapshots WHERE timestamp >= ? AND timestamp <= ? ORDER BY timestamp",
+		from.UnixNano(), to.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query system status: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []*SystemStatus{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to read system status: %w", err)
+		}
+		status := &SystemStatus{}
+		if err := json.Unmarshal([]byte(data), status); err != nil {
+			return nil, fmt.Errorf("failed to decode system status: %w", err)
+		}
+		snapshots = append(snapshots, status)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query system status: %w", err)
+	}
+	return snapshots, nil
+}
+
+// Prune deletes the points and snapshots recorded before before, returning
+// how many rows were removed
+func (s *MetricStore) Prune(before time.Time) (int64, error) {
+	var removed int64
+	for _, table := range []string{"metric_points", "status_snapshots"} {
+		result, err := s.db.Exec("DELETE FROM "+table+" WHERE timestamp < ?", before.UnixNano())
+		if err != nil {
+			return removed, fmt.Errorf("failed to prune %s: %w", table, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return removed, fmt.Errorf("failed to prune %s: %w", table, err)
+		}
+		removed += n
+	}
+	return removed, nil
+}
+
+// openHistory opens the metric store configured for cfg and prunes samples
+// older than its retention. It returns nil when history is turned off
+func openHistory(cfg *config.Config, configDir string) (*MetricStore, error) {
+	history := cfg.Monitoring.History
+	if !history.Enabled {
+		return nil, nil
+	}
+
+	retention := history.Retention
+	if retention == "" {
+		retention = defaultHistoryRetention
+	}
+	keep, err := model.ParseDuration(retention)
+	if err != nil {
+		return nil, fmt.Errorf("invalid history retention %q: %w", retention, err)
+	}
+
+	path := history.Path
+	if path == "" {
+		path = filepath.Join(configDir, metricsDBFileName)
+	}
+	store, err := OpenMetricStore(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := store.Prune(time.Now().Add(-time.Duration(keep))); err != nil {
+		store.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// recordStatus stores a status snapshot and its resource usage in the
+// history, when it is enabled. Failing to record never fails the caller
+func (m *MonitorImpl) recordStatus(status *SystemStatus) {
+	if m.history == nil {
+		return
+	}
+	if err := m.history.StoreStatus(status); err != nil {
+		logrus.Warnf("Failed to record system status: %v", err)
+		return
+	}
+
+	for name, value := range resourceMetrics(status.Resources) {
+		point := MetricPoint{Timestamp: status.Timestamp, Value: value, Labels: map[string]string{"host": status.Metadata["hostname"]}}
+		if err := m.history.Store(name, point); err != nil {
+			logrus.Warnf("Failed to record %s: %v", name, err)
+		}
+	}
+}
+
+// resourceMetrics returns the usage percentages of resources by metric name
+func resourceMetrics(resources *ResourceUsage) map[string]float64 {
+	usage := map[string]float64{}
+	if resources == nil {
+		return usage
+	}
+	if resources.CPU != nil {
+		usage["cpu_usage"] = resources.CPU.Usage
+	}
+	if resources.Memory != nil {
+		usage["memory_usage"] = resources.Memory.Usage
+	}
+	if resources.Disk != nil {
+		usage["disk_usage"] = resources.Disk.Usage
+	}
+	return usage
+}
+
+// queryHistory reads a metric over the given duration from the history
+func (m *MonitorImpl) queryHistory(metric, duration string) (*MetricsData, error) {
+	dur, err := model.ParseDuration(duration)
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration %q: %w", duration, err)
+	}
+
+	endTime := time.Now()
+	startTime := endTime.Add(-time.Duration(dur))
+	points, err := m.history.Query(metric, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MetricsData{
+		Metric:    metric,
+		TimeRange: duration,
+		Data:      points,
+		Summary:   summarizePoints(points),
+		StartTime: startTime,
+		EndTime:   endTime,
+		Metadata:  map[string]string{"source": "history"},
+	}, nil
+}
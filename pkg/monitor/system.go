@@ -0,0 +1,163 @@
+package monitor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/host"
+	"github.com/shirou/gopsutil/v4/load"
+	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/shirou/gopsutil/v4/net"
+)
+
+// cpuSampleInterval is how long CPU utilisation is sampled for
+const cpuSampleInterval = 200 * time.Millisecond
+
+// Usage percentages above which overallHealth reports the local host as
+// warning or critical
+const (
+	cpuWarningThreshold    = 80.0
+	memoryWarningThreshold = 85.0
+	diskCriticalThreshold  = 90.0
+)
+
+// collectResourceUsage gathers host resource metrics, leaving any
+// sub-struct nil when its stats are unavailable on this platform
+func collectResourceUsage(ctx context.Context) *ResourceUsage {
+	return &ResourceUsage{
+		CPU:     collectCPUUsage(ctx),
+		Memory:  collectMemoryUsage(ctx),
+		Disk:    collectDiskUsage(ctx, rootDiskPath()),
+		Network: collectNetworkUsage(ctx),
+	}
+}
+
+// collectCPUUsage samples CPU utilisation, core count and load average
+func collectCPUUsage(ctx context.Context) *CPUUsage {
+	percents, err := cpu.PercentWithContext(ctx, cpuSampleInterval, false)
+	if err != nil || len(percents) == 0 {
+		return nil
+	}
+
+	cores, err := cpu.CountsWithContext(ctx, true)
+	if err != nil || cores == 0 {
+		cores = runtime.NumCPU()
+	}
+
+	usage := &CPUUsage{
+		Usage: percents[0],
+		Cores: cores,
+	}
+
+	// Load average is not available on every platform (e.g. Windows)
+	if avg, err := load.AvgWithContext(ctx); err == nil {
+		usage.LoadAverage = avg.Load1
+	}
+
+	return usage
+}
+
+// collectMemoryUsage reads virtual memory totals
+func collectMemoryUsage(ctx context.Context) *MemoryUsage {
+	vm, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil
+	}
+
+	return &MemoryUsage{
+		Used:      int64(vm.Used),
+		Available: int64(vm.Available),
+		Total:     int64(vm.Total),
+		Usage:     vm.UsedPercent,
+	}
+}
+
+// collectDiskUsage reads filesystem usage for the given path
+func collectDiskUsage(ctx context.Context, path string) *DiskUsage {
+	usage, err := disk.UsageWithContext(ctx, path)
+	if err != nil {
+		return nil
+	}
+
+	return &DiskUsage{
+		Used:      int64(usage.Used),
+		Available: int64(usage.Free),
+		Total:     int64(usage.Total),
+		Usage:     usage.UsedPercent,
+	}
+}
+
+// collectNetworkUsage reads interface counters summed across all interfaces
+func collectNetworkUsage(ctx context.Context) *NetworkUsage {
+	counters, err := net.IOCountersWithContext(ctx, false)
+	if err != nil || len(counters) == 0 {
+		return nil
+	}
+
+	return &NetworkUsage{
+		BytesIn:    int64(counters[0].BytesRecv),
+		BytesOut:   int64(counters[0].BytesSent),
+		PacketsIn:  int64(counters[0].PacketsRecv),
+		PacketsOut: int64(counters[0].PacketsSent),
+	}
+}
+
+// hostUptime returns the time since the host booted
+func hostUptime(ctx context.Context) (time.Duration, error) {
+	bootTime, err := host.BootTimeWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return time.Since(time.Unix(int64(bootTime), 0)), nil
+}
+
+// hostMetadata describes the host the metrics were collected on
+func hostMetadata(ctx context.Context) map[string]string {
+	metadata := map[string]string{
+		"os":   runtime.GOOS,
+		"arch": runtime.GOARCH,
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		metadata["hostname"] = hostname
+	}
+	if info, err := host.InfoWithContext(ctx); err == nil {
+		metadata["platform"] = info.Platform
+		metadata["platform_version"] = info.PlatformVersion
+		metadata["kernel_version"] = info.KernelVersion
+	}
+
+	return metadata
+}
+
+// overallHealth derives the host health from resource usage
+func overallHealth(resources *ResourceUsage) string {
+	if resources.Disk != nil && resources.Disk.Usage > diskCriticalThreshold {
+		return "critical"
+	}
+	if resources.CPU != nil && resources.CPU.Usage > cpuWarningThreshold {
+		return "warning"
+	}
+	if resources.Memory != nil && resources.Memory.Usage > memoryWarningThreshold {
+		return "warning"
+	}
+	return "healthy"
+}
+
+// rootDiskPath returns the root of the filesystem holding the working directory
+func rootDiskPath() string {
+	if runtime.GOOS == "windows" {
+		if wd, err := os.Getwd(); err == nil {
+			if volume := filepath.VolumeName(wd); volume != "" {
+				return volume + `\`
+			}
+		}
+		return `C:\`
+	}
+	return "/"
+}
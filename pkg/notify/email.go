@@ -0,0 +1,338 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+)
+
+// smtpTimeout bounds a whole SMTP session, from connecting to QUIT
+const smtpTimeout = 30 * time.Second
+
+// smtpPorts are the ports used for each TLS mode when notifications.email.port is unset
+var smtpPorts = map[string]int{"starttls": 587, "tls": 465, "none": 25}
+
+// Report is a document that can be mailed with SendReport
+type Report struct {
+	Title     string
+	Generated time.Time
+	Summary   []ReportField
+	Sections  []ReportSection
+}
+
+// ReportField is one labelled value in a report summary
+type ReportField struct {
+	Label string
+	Value string
+}
+
+// ReportSection is a titled table and/or bullet list in a report
+type ReportSection struct {
+	Title   string
+	Headers []string
+	Rows    [][]string
+	Items   []string
+}
+
+var reportHTML = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #222">
+<h1>{{.Title}}</h1>
+{{- if .Summary}}
+<table cellpadding="4">
+{{- range .Summary}}
+<tr><th align="left">{{.Label}}</th><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- range .Sections}}
+<h2>{{.Title}}</h2>
+{{- if .Headers}}
+<table border="1" cellpadding="4" cellspacing="0" style="border-collapse: collapse">
+<tr>{{range .Headers}}<th align="left">{{.}}</th>{{end}}</tr>
+{{- range .Rows}}
+<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Items}}
+<ul>
+{{- range .Items}}
+<li>{{.}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- end}}
+<p style="color: #777">Generated by AlloraCLI at {{.Generated.Format "2006-01-02 15:04 MST"}}</p>
+</body>
+</html>
+`))
+
+// RenderReport renders a report as a multipart/alternative MIME entity with
+// a plaintext part and an HTML part, ready to pass to SendReport
+func RenderReport(report Report) ([]byte, error) {
+	if report.Generated.IsZero() {
+		report.Generated = time.Now()
+	}
+
+	var html bytes.Buffer
+	if err := reportHTML.Execute(&html, report); err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+
+	var buf bytes.Buffer
+	parts := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\nContent-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+
+	for _, part := range []struct {
+		contentType string
+		body        []byte
+	}{
+		{"text/plain; charset=utf-8", reportText(report)},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to render report: %w", err)
+		}
+		qp := quotedprintable.NewWriter(w)
+		qp.Write(part.body)
+		qp.Close()
+	}
+	if err := parts.Close(); err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// reportText renders the plaintext fallback of a report
+func reportText(report Report) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s\n%s\n", report.Title, strings.Repeat("=", len(report.Title)))
+
+	if len(report.Summary) > 0 {
+		b.WriteString("\n")
+		tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		for _, field := range report.Summary {
+			fmt.Fprintf(tw, "%s:\t%s\n", field.Label, field.Value)
+		}
+		tw.Flush()
+	}
+
+	for _, section := range report.Sections {
+		fmt.Fprintf(&b, "\n%s\n%s\n", section.Title, strings.Repeat("-", len(section.Title)))
+		if len(section.Headers) > 0 {
+			tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, strings.Join(section.Headers, "\t"))
+			for _, row := range section.Rows {
+				fmt.Fprintln(tw, strings.Join(row, "\t"))
+			}
+			tw.Flush()
+		}
+		for _, item := range section.Items {
+			fmt.Fprintf(&b, "- %s\n", item)
+		}
+	}
+
+	fmt.Fprintf(&b, "\nGenerated by AlloraCLI at %s\n", report.Generated.Format("2006-01-02 15:04 MST"))
+	return b.Bytes()
+}
+
+// Mailer sends reports through an SMTP server
+type Mailer struct {
+	host       string
+	port       int
+	username   string
+	password   string
+	from       string
+	recipients []string
+	tlsMode    string
+	timeout    time.Duration
+	// tlsConfig overrides the TLS settings, for servers with private certificates
+	tlsConfig *tls.Config
+}
+
+// NewMailer creates a mailer for the configured SMTP server
+func NewMailer(cfg config.EmailConfig) (*Mailer, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("no SMTP server is configured; set notifications.email.host")
+	}
+	if _, err := mail.ParseAddress(cfg.From); err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", cfg.From, err)
+	}
+
+	mode := strings.ToLower(cfg.TLS)
+	if mode == "" {
+		mode = "starttls"
+	}
+	port, ok := smtpPorts[mode]
+	if !ok {
+		return nil, fmt.Errorf("unknown TLS mode %q; use one of %s", cfg.TLS, strings.Join(config.EmailTLSModes, ", "))
+	}
+	if cfg.Port != 0 {
+		port = cfg.Port
+	}
+
+	return &Mailer{
+		host:       cfg.Host,
+		port:       port,
+		username:   cfg.Username,
+		password:   cfg.Password,
+		from:       cfg.From,
+		recipients: cfg.Recipients,
+		tlsMode:    mode,
+		timeout:    smtpTimeout,
+	}, nil
+}
+
+// SendReport mails a report rendered with RenderReport to recipients and
+// the configured recipients
+func (m *Mailer) SendReport(ctx context.Context, subject string, body []byte, recipients []string) error {
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address %q: %w", m.from, err)
+	}
+	to, err := m.addresses(recipients)
+	if err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", joinAddresses(to))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.Write(body)
+
+	if err := m.deliver(ctx, from.Address, to, msg.Bytes()); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return fmt.Errorf("failed to email report to %s: %w", joinAddresses(to), err)
+	}
+	return nil
+}
+
+// addresses parses recipients and the configured recipients, dropping duplicates
+func (m *Mailer) addresses(recipients []string) ([]*mail.Address, error) {
+	var to []*mail.Address
+	seen := make(map[string]bool)
+	for _, recipient := range append(append([]string{}, recipients...), m.recipients...) {
+		if strings.TrimSpace(recipient) == "" {
+			continue
+		}
+		address, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient address %q: %w", recipient, err)
+		}
+		if key := strings.ToLower(address.Address); !seen[key] {
+			seen[key] = true
+			to = append(to, address)
+		}
+	}
+	if len(to) == 0 {
+		return nil, fmt.Errorf("no recipients given and notifications.email.recipients is empty")
+	}
+	return to, nil
+}
+
+// deliver runs one SMTP session that sends msg from sender to every address
+func (m *Mailer) deliver(ctx context.Context, sender string, to []*mail.Address, msg []byte) error {
+	addr := net.JoinHostPort(m.host, strconv.Itoa(m.port))
+	dialer := &net.Dialer{Timeout: m.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server %s: %w", addr, err)
+	}
+	defer context.AfterFunc(ctx, func() { conn.Close() })()
+	conn.SetDeadline(time.Now().Add(m.timeout))
+
+	tlsConfig := m.tlsConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: m.host, MinVersion: tls.VersionTLS12}
+	}
+	if m.tlsMode == "tls" {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session with %s: %w", addr, err)
+	}
+	defer client.Close()
+
+	if m.tlsMode == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server %s does not support STARTTLS; set notifications.email.tls to tls or none", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("failed to start TLS with %s: %w", addr, err)
+		}
+	}
+
+	if m.username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("SMTP server %s does not support authentication", addr)
+		}
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return fmt.Errorf("SMTP authentication as %s failed: %w", m.username, err)
+		}
+	}
+
+	if err := client.Mail(sender); err != nil {
+		return fmt.Errorf("SMTP server rejected sender %s: %w", sender, err)
+	}
+	var rejected []error
+	for _, address := range to {
+		if err := client.Rcpt(address.Address); err != nil {
+			rejected = append(rejected, fmt.Errorf("SMTP server rejected recipient %s: %w", address.Address, err))
+		}
+	}
+	if len(rejected) == len(to) {
+		return errors.Join(rejected...)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP server refused the message: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server refused the message: %w", err)
+	}
+	// The message is accepted once DATA completes, so a failed QUIT is not an error
+	client.Quit()
+
+	return errors.Join(rejected...)
+}
+
+// joinAddresses formats addresses for a To header
+func joinAddresses(addresses []*mail.Address) string {
+	formatted := make([]string, len(addresses))
+	for i, address := range addresses {
+		formatted[i] = address.String()
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,242 @@
+// Package notify sends alerts and security events to chat, webhook and
+// incident endpoints, and mails reports over SMTP
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+)
+
+// DefaultMinSeverity is the least severe notification sent when a sink sets no min_severity
+const DefaultMinSeverity = "warning"
+
+// sendTimeout bounds a single delivery attempt
+const sendTimeout = 10 * time.Second
+
+// maxAttempts is how many times a notification is sent before giving up
+const maxAttempts = 3
+
+// severityRanks orders severities; alert and security severities share ranks
+var severityRanks = map[string]int{
+	"info":     1,
+	"low":      1,
+	"warning":  2,
+	"medium":   2,
+	"error":    3,
+	"high":     3,
+	"critical": 4,
+}
+
+// Notification is an alert or security event to deliver
+type Notification struct {
+	Name      string            `json:"name"`
+	Source    string            `json:"source"`
+	Severity  string            `json:"severity"`
+	Message   string            `json:"message"`
+	Value     interface{}       `json:"value,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// Notifier delivers notifications
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// SeverityAtLeast reports whether severity is at least as severe as min;
+// unknown severities rank as warnings
+func SeverityAtLeast(severity, min string) bool {
+	return severityRank(severity) >= severityRank(min)
+}
+
+// severityRank returns the rank of a severity
+func severityRank(severity string) int {
+	if rank, ok := severityRanks[strings.ToLower(severity)]; ok {
+		return rank
+	}
+	return severityRanks["warning"]
+}
+
+// webhook posts JSON payloads to a URL, retrying failed deliveries
+type webhook struct {
+	url         string
+	minSeverity string
+	client      *http.Client
+	backoff     time.Duration
+}
+
+// newWebhook creates a webhook for url that drops notifications below minSeverity
+func newWebhook(url, minSeverity string) webhook {
+	if minSeverity == "" {
+		minSeverity = DefaultMinSeverity
+	}
+	return webhook{
+		url:         url,
+		minSeverity: minSeverity,
+		client:      &http.Client{Timeout: sendTimeout},
+		backoff:     time.Second,
+	}
+}
+
+// wants reports whether the notification is severe enough to send
+func (w *webhook) wants(notification Notification) bool {
+	return SeverityAtLeast(notification.Severity, w.minSeverity)
+}
+
+// post sends payload as JSON. Network errors, rate limiting and server
+// errors are retried with a growing delay; other responses fail at once
+func (w *webhook) post(ctx context.Context, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-time.After(time.Duration(attempt-1) * w.backoff):
+			case <-ctx.Done():
+				return fmt.Errorf("failed to send notification: %w", ctx.Err())
+			}
+		}
+
+		retry, err := w.send(ctx, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return fmt.Errorf("failed to send notification to %s: %w", redactURL(w.url), lastErr)
+}
+
+// send makes one delivery attempt, reporting whether a failure is worth retrying
+func (w *webhook) send(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// redactURL drops the path of a webhook URL, which usually holds its secret
+func redactURL(rawURL string) string {
+	if i := strings.Index(rawURL, "://"); i >= 0 {
+		if j := strings.IndexByte(rawURL[i+3:], '/'); j >= 0 {
+			return rawURL[:i+3+j]
+		}
+	}
+	return rawURL
+}
+
+// SlackNotifier posts notifications to a Slack incoming webhook
+type SlackNotifier struct {
+	webhook
+}
+
+// NewSlackNotifier creates a notifier for a Slack incoming webhook URL
+func NewSlackNotifier(url, minSeverity string) *SlackNotifier {
+	return &SlackNotifier{webhook: newWebhook(url, minSeverity)}
+}
+
+// Notify posts the notification as a Slack message
+func (s *SlackNotifier) Notify(ctx context.Context, notification Notification) error {
+	if !s.wants(notification) {
+		return nil
+	}
+	return s.post(ctx, map[string]string{"text": slackText(notification)})
+}
+
+// slackText formats a notification as a Slack message
+func slackText(notification Notification) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*[%s] %s*", strings.ToUpper(notification.Severity), notification.Name)
+	if notification.Message != "" {
+		fmt.Fprintf(&b, "\n%s", notification.Message)
+	}
+	if notification.Value != nil {
+		fmt.Fprintf(&b, "\nValue: %v", notification.Value)
+	}
+	fmt.Fprintf(&b, "\nTime: %s", notification.Timestamp.Format(time.RFC3339))
+	return b.String()
+}
+
+// WebhookNotifier posts notifications as JSON to a generic webhook
+type WebhookNotifier struct {
+	webhook
+}
+
+// NewWebhookNotifier creates a notifier for a generic JSON webhook URL
+func NewWebhookNotifier(url, minSeverity string) *WebhookNotifier {
+	return &WebhookNotifier{webhook: newWebhook(url, minSeverity)}
+}
+
+// Notify posts the notification as a JSON object
+func (w *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	if !w.wants(notification) {
+		return nil
+	}
+	return w.post(ctx, notification)
+}
+
+// multiNotifier delivers each notification to every notifier
+type multiNotifier []Notifier
+
+// Notify sends to every notifier, returning the failures together
+func (m multiNotifier) Notify(ctx context.Context, notification Notification) error {
+	var errs []error
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, notification); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FromConfig creates a notifier for the configured sinks, or returns nil
+// when no sink is configured
+func FromConfig(cfg config.NotifyConfig) Notifier {
+	var notifiers multiNotifier
+	if cfg.Slack.URL != "" {
+		notifiers = append(notifiers, NewSlackNotifier(cfg.Slack.URL, cfg.Slack.MinSeverity))
+	}
+	if cfg.Webhook.URL != "" {
+		notifiers = append(notifiers, NewWebhookNotifier(cfg.Webhook.URL, cfg.Webhook.MinSeverity))
+	}
+
+	switch len(notifiers) {
+	case 0:
+		return nil
+	case 1:
+		return notifiers[0]
+	default:
+		return notifiers
+	}
+}
@@ -0,0 +1,492 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+)
+
+var testNotification = Notification{
+	Name:      "high-cpu",
+	Source:    "alert",
+	Severity:  "critical",
+	Message:   "high-cpu: CPU above 90%",
+	Value:     97.5,
+	Timestamp: time.Date(2024, 6, 1, 8, 30, 0, 0, time.UTC),
+}
+
+// recorder is a webhook endpoint that fails the first failures requests with status
+type recorder struct {
+	server   *httptest.Server
+	bodies   []string
+	requests int32
+}
+
+func newRecorder(t *testing.T, failures int32, status int) *recorder {
+	r := &recorder{}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(&r.requests, 1)
+		body, _ := io.ReadAll(req.Body)
+		if n <= failures {
+			http.Error(w, "try again", status)
+			return
+		}
+		if req.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("expected a JSON request, got %q", req.Header.Get("Content-Type"))
+		}
+		r.bodies = append(r.bodies, string(body))
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func TestSlackNotifier(t *testing.T) {
+	endpoint := newRecorder(t, 0, 0)
+	notifier := NewSlackNotifier(endpoint.server.URL, "")
+
+	if err := notifier.Notify(context.Background(), testNotification); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+	if len(endpoint.bodies) != 1 {
+		t.Fatalf("expected one message, got %d", len(endpoint.bodies))
+	}
+
+	var payload map[string]string
+	if err := json.Unmarshal([]byte(endpoint.bodies[0]), &payload); err != nil {
+		t.Fatalf("invalid Slack payload: %v", err)
+	}
+	for _, want := range []string{"[CRITICAL] high-cpu", "CPU above 90%", "Value: 97.5", "2024-06-01T08:30:00Z"} {
+		if !strings.Contains(payload["text"], want) {
+			t.Errorf("expected the message to contain %q, got %q", want, payload["text"])
+		}
+	}
+
+	info := testNotification
+	info.Severity = "info"
+	if err := notifier.Notify(context.Background(), info); err != nil || len(endpoint.bodies) != 1 {
+		t.Errorf("expected notifications below the default minimum severity to be dropped, got %v", err)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	endpoint := newRecorder(t, 0, 0)
+	notifier := NewWebhookNotifier(endpoint.server.URL, "info")
+
+	if err := notifier.Notify(context.Background(), testNotification); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+
+	var payload Notification
+	if err := json.Unmarshal([]byte(endpoint.bodies[0]), &payload); err != nil {
+		t.Fatalf("invalid webhook payload: %v", err)
+	}
+	if payload.Name != "high-cpu" || payload.Severity != "critical" || payload.Value != 97.5 || !payload.Timestamp.Equal(testNotification.Timestamp) {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	endpoint := newRecorder(t, 2, http.StatusServiceUnavailable)
+	notifier := NewWebhookNotifier(endpoint.server.URL+"/hooks/secret-token", "")
+	notifier.backoff = time.Millisecond
+
+	if err := notifier.Notify(context.Background(), testNotification); err != nil {
+		t.Fatalf("expected the third attempt to succeed, got %v", err)
+	}
+	if endpoint.requests != 3 {
+		t.Errorf("expected 3 attempts, got %d", endpoint.requests)
+	}
+
+	rejecting := newRecorder(t, 10, http.StatusBadRequest)
+	notifier = NewWebhookNotifier(rejecting.server.URL+"/hooks/secret-token", "")
+	notifier.backoff = time.Millisecond
+
+	err := notifier.Notify(context.Background(), testNotification)
+	if err == nil || !strings.Contains(err.Error(), "400") {
+		t.Fatalf("expected the rejection to be reported, got %v", err)
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("expected the webhook path to be left out of errors, got %v", err)
+	}
+	if rejecting.requests != 1 {
+		t.Errorf("expected client errors not to be retried, got %d attempts", rejecting.requests)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	if notifier := FromConfig(config.NotifyConfig{}); notifier != nil {
+		t.Errorf("expected no notifier without sinks, got %T", notifier)
+	}
+
+	slack := newRecorder(t, 0, 0)
+	hook := newRecorder(t, 0, 0)
+	notifier := FromConfig(config.NotifyConfig{
+		Slack:   config.WebhookConfig{URL: slack.server.URL, MinSeverity: "critical"},
+		Webhook: config.WebhookConfig{URL: hook.server.URL},
+	})
+
+	warning := testNotification
+	warning.Severity = "warning"
+	if err := notifier.Notify(context.Background(), warning); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+	if len(slack.bodies) != 0 || len(hook.bodies) != 1 {
+		t.Errorf("expected each sink to apply its own minimum severity, got %d slack and %d webhook messages", len(slack.bodies), len(hook.bodies))
+	}
+}
+
+// blockingNotifier signals each delivery it starts and records the
+// notification once release is closed
+type blockingNotifier struct {
+	started chan struct{}
+	release chan struct{}
+	mu      sync.Mutex
+	sent    []string
+}
+
+func (b *blockingNotifier) Notify(ctx context.Context, notification Notification) error {
+	b.started <- struct{}{}
+	<-b.release
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sent = append(b.sent, notification.Name)
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	next := &blockingNotifier{started: make(chan struct{}, 3), release: make(chan struct{})}
+	queue := NewQueue(next, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for i, name := range []string{"first", "second", "third"} {
+		notification := testNotification
+		notification.Name = name
+		if err := queue.Notify(ctx, notification); err != nil {
+			t.Fatalf("Notify(%s) failed: %v", name, err)
+		}
+		if i == 0 {
+			<-next.started
+		}
+	}
+	// The first is being delivered and the queue holds the other two
+	if err := queue.Notify(ctx, testNotification); err == nil || !strings.Contains(err.Error(), "full") {
+		t.Errorf("expected a full queue to refuse notifications, got %v", err)
+	}
+	cancel()
+
+	close(next.release)
+	queue.Close()
+	if strings.Join(next.sent, ",") != "first,second,third" {
+		t.Errorf("expected Close to wait for queued notifications despite the cancelled context, got %v", next.sent)
+	}
+	if err := queue.Notify(context.Background(), testNotification); err == nil {
+		t.Error("expected a closed queue to refuse notifications")
+	}
+}
+
+func TestPagerDutyNotifier(t *testing.T) {
+	endpoint := newRecorder(t, 0, 0)
+	notifier := NewPagerDutyNotifier("routing-123", "", false)
+	notifier.url = endpoint.server.URL
+
+	warning := testNotification
+	warning.Severity = "warning"
+	if err := notifier.Notify(context.Background(), warning); err != nil || len(endpoint.bodies) != 0 {
+		t.Errorf("expected alerts below critical to be skipped by default, got %v", err)
+	}
+
+	if err := notifier.Notify(context.Background(), testNotification); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+	if err := notifier.Resolve(context.Background(), "high-cpu"); err != nil {
+		t.Fatalf("Resolve() failed: %v", err)
+	}
+	if len(endpoint.bodies) != 2 {
+		t.Fatalf("expected a trigger and a resolve event, got %d", len(endpoint.bodies))
+	}
+
+	var trigger, resolve pagerDutyEvent
+	json.Unmarshal([]byte(endpoint.bodies[0]), &trigger)
+	json.Unmarshal([]byte(endpoint.bodies[1]), &resolve)
+
+	if trigger.RoutingKey != "routing-123" || trigger.EventAction != "trigger" || trigger.DedupKey != "allora-high-cpu" {
+		t.Errorf("unexpected trigger event %+v", trigger)
+	}
+	if p := trigger.Payload; p == nil || p.Severity != "critical" || p.Summary != testNotification.Message || p.Timestamp != "2024-06-01T08:30:00Z" || p.CustomDetails["value"] != 97.5 {
+		t.Errorf("unexpected trigger payload %+v", trigger.Payload)
+	}
+	if resolve.EventAction != "resolve" || resolve.DedupKey != trigger.DedupKey || resolve.Payload != nil {
+		t.Errorf("expected the resolve event to reuse the dedup key, got %+v", resolve)
+	}
+}
+
+func TestPagerDutyDryRun(t *testing.T) {
+	endpoint := newRecorder(t, 0, 0)
+	notifier := NewPagerDutyNotifier("routing-123", "info", true)
+	notifier.url = endpoint.server.URL
+
+	if err := notifier.Notify(context.Background(), testNotification); err != nil {
+		t.Fatalf("Notify() failed: %v", err)
+	}
+	if err := notifier.Resolve(context.Background(), "high-cpu"); err != nil {
+		t.Fatalf("Resolve() failed: %v", err)
+	}
+	if endpoint.requests != 0 {
+		t.Errorf("expected a dry run not to send events, got %d requests", endpoint.requests)
+	}
+}
+
+func TestPagerDutySeverity(t *testing.T) {
+	for severity, want := range map[string]string{
+		"critical": "critical",
+		"high":     "error",
+		"error":    "error",
+		"warning":  "warning",
+		"medium":   "warning",
+		"low":      "info",
+		"info":     "info",
+	} {
+		if got := pagerDutySeverity(severity); got != want {
+			t.Errorf("pagerDutySeverity(%q) = %q, want %q", severity, got, want)
+		}
+	}
+}
+
+var testReport = Report{
+	Title:     "Security Report",
+	Generated: time.Date(2024, 6, 1, 8, 30, 0, 0, time.UTC),
+	Summary:   []ReportField{{Label: "Critical findings", Value: "1"}},
+	Sections: []ReportSection{
+		{Title: "Vulnerabilities", Headers: []string{"ID", "Title"}, Rows: [][]string{{"CVE-2024-0001", "<script> injection"}}},
+		{Title: "Recommendations", Items: []string{"Upgrade openssl"}},
+	},
+}
+
+// smtpServer is a minimal SMTP server that records the messages it accepts
+// and rejects RCPT for the address in reject
+type smtpServer struct {
+	host, port string
+	reject     string
+
+	mu       sync.Mutex
+	auth     string
+	rcpts    []string
+	messages []string
+}
+
+func newSMTPServer(t *testing.T, reject string) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &smtpServer{reject: reject}
+	s.host, s.port, _ = net.SplitHostPort(listener.Addr().String())
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		s.mu.Lock()
+		switch verb {
+		case "EHLO":
+			tp.PrintfLine("250-localhost")
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			s.auth = line
+			tp.PrintfLine("235 2.7.0 Authentication successful")
+		case "RCPT":
+			if s.reject != "" && strings.Contains(line, s.reject) {
+				tp.PrintfLine("550 5.1.1 No such user")
+				break
+			}
+			s.rcpts = append(s.rcpts, line)
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 Go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				s.mu.Unlock()
+				return
+			}
+			s.messages = append(s.messages, string(data))
+			tp.PrintfLine("250 OK")
+		case "QUIT":
+			tp.PrintfLine("221 Bye")
+			s.mu.Unlock()
+			return
+		default:
+			tp.PrintfLine("250 OK")
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *smtpServer) mailer(t *testing.T, recipients ...string) *Mailer {
+	port, _ := strconv.Atoi(s.port)
+	mailer, err := NewMailer(config.EmailConfig{
+		Host:       s.host,
+		Port:       port,
+		Username:   "allora",
+		Password:   "secret",
+		From:       "AlloraCLI <allora@example.com>",
+		Recipients: recipients,
+		TLS:        "none",
+	})
+	if err != nil {
+		t.Fatalf("NewMailer() failed: %v", err)
+	}
+	return mailer
+}
+
+func TestRenderReport(t *testing.T) {
+	body, err := RenderReport(testReport)
+	if err != nil {
+		t.Fatalf("RenderReport() failed: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatalf("invalid MIME entity: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("expected multipart/alternative, got %q", msg.Header.Get("Content-Type"))
+	}
+
+	parts := map[string]string{}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read part: %v", err)
+		}
+		content, _ := io.ReadAll(part)
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		parts[contentType] = string(content)
+	}
+
+	text := parts["text/plain"]
+	for _, want := range []string{"Security Report", "Critical findings:  1", "CVE-2024-0001  <script> injection", "- Upgrade openssl", "2024-06-01 08:30 UTC"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected the plaintext part to contain %q, got:\n%s", want, text)
+		}
+	}
+	html := parts["text/html"]
+	for _, want := range []string{"<h1>Security Report</h1>", "<td>&lt;script&gt; injection</td>", "<li>Upgrade openssl</li>"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected the HTML part to contain %q, got:\n%s", want, html)
+		}
+	}
+}
+
+func TestSendReport(t *testing.T) {
+	server := newSMTPServer(t, "")
+	mailer := server.mailer(t, "archive@example.com", "ops@example.com")
+	body, err := RenderReport(testReport)
+	if err != nil {
+		t.Fatalf("RenderReport() failed: %v", err)
+	}
+
+	if err := mailer.SendReport(context.Background(), "Weekly report\r\nBcc: evil@example.com", body, []string{"ops@example.com"}); err != nil {
+		t.Fatalf("SendReport() failed: %v", err)
+	}
+
+	if server.auth == "" {
+		t.Error("expected the mailer to authenticate")
+	}
+	if len(server.rcpts) != 2 {
+		t.Errorf("expected the duplicate recipient to be dropped, got %v", server.rcpts)
+	}
+	if len(server.messages) != 1 {
+		t.Fatalf("expected one message, got %d", len(server.messages))
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(server.messages[0]))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	if msg.Header.Get("Bcc") != "" {
+		t.Error("expected the subject not to add headers")
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if !strings.HasPrefix(subject, "Weekly report") {
+		t.Errorf("unexpected subject %q", subject)
+	}
+	if to := msg.Header.Get("To"); to != "<ops@example.com>, <archive@example.com>" {
+		t.Errorf("unexpected To header %q", to)
+	}
+	if !strings.HasPrefix(msg.Header.Get("Content-Type"), "multipart/alternative") {
+		t.Errorf("expected a multipart message, got %q", msg.Header.Get("Content-Type"))
+	}
+}
+
+func TestSendReportErrors(t *testing.T) {
+	server := newSMTPServer(t, "nobody@example.com")
+	mailer := server.mailer(t)
+
+	err := mailer.SendReport(context.Background(), "Report", []byte("\r\nbody"), []string{"nobody@example.com"})
+	if err == nil || !strings.Contains(err.Error(), "rejected recipient nobody@example.com") {
+		t.Errorf("expected a rejected recipient error, got %v", err)
+	}
+	if len(server.messages) != 0 {
+		t.Error("expected no message when every recipient is rejected")
+	}
+
+	if err := mailer.SendReport(context.Background(), "Report", nil, nil); err == nil || !strings.Contains(err.Error(), "no recipients") {
+		t.Errorf("expected a missing recipients error, got %v", err)
+	}
+	if err := mailer.SendReport(context.Background(), "Report", nil, []string{"not an address"}); err == nil || !strings.Contains(err.Error(), "invalid recipient") {
+		t.Errorf("expected an invalid recipient error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := mailer.SendReport(ctx, "Report", nil, []string{"ops@example.com"}); err == nil || !strings.Contains(err.Error(), "context canceled") {
+		t.Errorf("expected a cancellation error, got %v", err)
+	}
+
+	if _, err := NewMailer(config.EmailConfig{Host: "smtp.example.com"}); err == nil {
+		t.Error("expected NewMailer() to require a sender")
+	}
+}
@@ -0,0 +1,161 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/sirupsen/logrus"
+)
+
+// PagerDutyEventsURL is the PagerDuty Events API v2 endpoint
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// dedupKeyPrefix namespaces the dedup keys of AlloraCLI incidents
+const dedupKeyPrefix = "allora-"
+
+// IncidentNotifier opens incidents for notifications and resolves them once
+// the condition clears. Notifications with the same name update one incident
+type IncidentNotifier interface {
+	Notifier
+	Resolve(ctx context.Context, name string) error
+	// Wants reports whether Notify would open an incident for the
+	// notification, rather than drop it for its severity
+	Wants(notification Notification) bool
+}
+
+// pagerDutyEvent is a PagerDuty Events API v2 request
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+// pagerDutyPayload describes the incident of a trigger event
+type pagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp,omitempty"`
+	Component     string                 `json:"component,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+}
+
+// PagerDutyNotifier triggers and resolves PagerDuty incidents through the Events API v2
+type PagerDutyNotifier struct {
+	webhook
+	routingKey string
+	dryRun     bool
+}
+
+// NewPagerDutyNotifier creates a notifier sending events with routingKey.
+// In dry-run mode events are logged instead of sent
+func NewPagerDutyNotifier(routingKey, minSeverity string, dryRun bool) *PagerDutyNotifier {
+	if minSeverity == "" {
+		minSeverity = "critical"
+	}
+	return &PagerDutyNotifier{
+		webhook:    newWebhook(PagerDutyEventsURL, minSeverity),
+		routingKey: routingKey,
+		dryRun:     dryRun,
+	}
+}
+
+// IncidentsFromConfig creates the configured incident notifier, or returns
+// nil when no PagerDuty routing key is configured
+func IncidentsFromConfig(cfg config.NotifyConfig) IncidentNotifier {
+	pd := cfg.PagerDuty
+	if pd.RoutingKey == "" {
+		return nil
+	}
+	return NewPagerDutyNotifier(pd.RoutingKey, pd.MinSeverity, pd.DryRun)
+}
+
+// Notify triggers the incident of the notification, or updates it if it is already open
+func (p *PagerDutyNotifier) Notify(ctx context.Context, notification Notification) error {
+	if !p.wants(notification) {
+		return nil
+	}
+
+	details := map[string]interface{}{"severity": notification.Severity}
+	if notification.Value != nil {
+		details["value"] = notification.Value
+	}
+	for key, value := range notification.Details {
+		details[key] = value
+	}
+
+	summary := notification.Name
+	if notification.Message != "" {
+		summary = notification.Message
+	}
+	timestamp := notification.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	return p.send(ctx, &pagerDutyEvent{
+		RoutingKey:  p.routingKey,
+		EventAction: "trigger",
+		DedupKey:    dedupKeyPrefix + notification.Name,
+		Payload: &pagerDutyPayload{
+			Summary:       summary,
+			Source:        "allora-cli",
+			Severity:      pagerDutySeverity(notification.Severity),
+			Timestamp:     timestamp.UTC().Format(time.RFC3339),
+			Component:     notification.Source,
+			CustomDetails: details,
+		},
+	})
+}
+
+// Wants reports whether the notification is severe enough to open an incident
+func (p *PagerDutyNotifier) Wants(notification Notification) bool {
+	return p.wants(notification)
+}
+
+// Resolve resolves the incident opened for notifications named name
+func (p *PagerDutyNotifier) Resolve(ctx context.Context, name string) error {
+	return p.send(ctx, &pagerDutyEvent{
+		RoutingKey:  p.routingKey,
+		EventAction: "resolve",
+		DedupKey:    dedupKeyPrefix + name,
+	})
+}
+
+// send posts an event, or logs it without the routing key in dry-run mode
+func (p *PagerDutyNotifier) send(ctx context.Context, event *pagerDutyEvent) error {
+	if p.dryRun {
+		logged := *event
+		logged.RoutingKey = "<redacted>"
+		payload, err := json.Marshal(logged)
+		if err != nil {
+			return fmt.Errorf("failed to encode PagerDuty event: %w", err)
+		}
+		logrus.Infof("PagerDuty dry run, not sending %s event: %s", event.EventAction, payload)
+		return nil
+	}
+
+	if err := p.post(ctx, event); err != nil {
+		return fmt.Errorf("failed to %s PagerDuty incident %s: %w", event.EventAction, event.DedupKey, err)
+	}
+	return nil
+}
+
+// pagerDutySeverity maps an alert or security severity to one of
+// PagerDuty's critical, error, warning and info
+func pagerDutySeverity(severity string) string {
+	switch severityRank(severity) {
+	case severityRanks["critical"]:
+		return "critical"
+	case severityRanks["error"]:
+		return "error"
+	case severityRanks["warning"]:
+		return "warning"
+	default:
+		return "info"
+	}
+}
@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultQueueSize is how many notifications a Queue holds before refusing more
+const DefaultQueueSize = 100
+
+// Queue delivers notifications in the background, so that slow sinks and
+// their retries do not hold up the caller. Failed deliveries are logged
+type Queue struct {
+	next    Notifier
+	pending chan queuedNotification
+	done    chan struct{}
+	mu      sync.RWMutex
+	closed  bool
+}
+
+// queuedNotification is a notification waiting to be delivered
+type queuedNotification struct {
+	ctx          context.Context
+	notification Notification
+}
+
+// NewQueue creates a queue delivering to next that holds up to size
+// notifications, and starts delivering
+func NewQueue(next Notifier, size int) *Queue {
+	q := &Queue{
+		next:    next,
+		pending: make(chan queuedNotification, max(size, 1)),
+		done:    make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// Notify queues the notification. Delivery outlives cancellation of ctx, so
+// that events seen just before shutdown are still sent; it fails only when
+// the queue is full or closed
+func (q *Queue) Notify(ctx context.Context, notification Notification) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return errors.New("notification queue is closed")
+	}
+	select {
+	case q.pending <- queuedNotification{ctx: context.WithoutCancel(ctx), notification: notification}:
+		return nil
+	default:
+		return fmt.Errorf("notification queue is full, dropping %s", notification.Name)
+	}
+}
+
+// run delivers queued notifications in order until the queue is closed
+func (q *Queue) run() {
+	defer close(q.done)
+	for queued := range q.pending {
+		if err := q.next.Notify(queued.ctx, queued.notification); err != nil {
+			logrus.Warnf("Failed to send notification %s: %v", queued.notification.Name, err)
+		}
+	}
+}
+
+// Close stops accepting notifications and waits for the queued ones to be
+// delivered
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.pending)
+	}
+	q.mu.Unlock()
+	<-q.done
+}
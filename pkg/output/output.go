@@ -0,0 +1,254 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v3"
+)
+
+// Renderer writes data to a writer in a specific output format
+type Renderer interface {
+	Render(w io.Writer, data interface{}) error
+}
+
+// RendererFunc adapts a function to the Renderer interface
+type RendererFunc func(w io.Writer, data interface{}) error
+
+// Render calls f(w, data)
+func (f RendererFunc) Render(w io.Writer, data interface{}) error {
+	return f(w, data)
+}
+
+// Tabular is implemented by result types that can be displayed as rows
+type Tabular interface {
+	TableHeaders() []string
+	TableRows() [][]string
+}
+
+// Summarizer is implemented by result types with a human-readable summary
+type Summarizer interface {
+	Summary() string
+}
+
+// Registry holds the renderers available for each output format
+type Registry struct {
+	renderers map[string]Renderer
+	mu        sync.RWMutex
+}
+
+// NewRegistry creates an empty renderer registry
+func NewRegistry() *Registry {
+	return &Registry{
+		renderers: make(map[string]Renderer),
+	}
+}
+
+// Register adds or replaces the renderer for a format
+func (r *Registry) Register(format string, renderer Renderer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.renderers[strings.ToLower(format)] = renderer
+}
+
+// Get returns the renderer for a format
+func (r *Registry) Get(format string) (Renderer, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	renderer, exists := r.renderers[strings.ToLower(format)]
+	if !exists {
+		return nil, fmt.Errorf("unsupported format: %s (available: %s)", format, strings.Join(r.formats(), ", "))
+	}
+	return renderer, nil
+}
+
+// Formats returns the registered format names in sorted order
+func (r *Registry) Formats() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.formats()
+}
+
+// Render writes data to w using the renderer registered for format
+func (r *Registry) Render(w io.Writer, format string, data interface{}) error {
+	renderer, err := r.Get(format)
+	if err != nil {
+		return err
+	}
+	return renderer.Render(w, data)
+}
+
+func (r *Registry) formats() []string {
+	formats := make([]string, 0, len(r.renderers))
+	for format := range r.renderers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// FormatFlag is a command-line flag value that only accepts the listed formats
+type FormatFlag struct {
+	value   *string
+	formats []string
+}
+
+// NewFormatFlag creates a flag value storing the selected format in value,
+// which starts as defaultFormat
+func NewFormatFlag(value *string, defaultFormat string, formats ...string) *FormatFlag {
+	*value = defaultFormat
+	return &FormatFlag{value: value, formats: formats}
+}
+
+// String returns the selected format
+func (f *FormatFlag) String() string {
+	return *f.value
+}
+
+// Set selects a format, rejecting formats that are not listed
+func (f *FormatFlag) Set(format string) error {
+	format = strings.ToLower(strings.TrimSpace(format))
+	for _, allowed := range f.formats {
+		if format == allowed {
+			*f.value = format
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %s", strings.Join(f.formats, ", "))
+}
+
+// Type names the flag's value in help output
+func (f *FormatFlag) Type() string {
+	return "format"
+}
+
+// Formats returns the formats the flag accepts
+func (f *FormatFlag) Formats() []string {
+	return f.formats
+}
+
+// defaultRegistry is shared by all commands
+var defaultRegistry = newDefaultRegistry()
+
+// newDefaultRegistry creates a registry with the built-in formats
+func newDefaultRegistry() *Registry {
+	registry := NewRegistry()
+	registry.Register("json", RendererFunc(renderJSON))
+	registry.Register("yaml", RendererFunc(renderYAML))
+	registry.Register("table", RendererFunc(renderTable))
+	registry.Register("text", RendererFunc(renderText))
+	registry.Register("csv", RendererFunc(renderCSV))
+	registry.Register("ndjson", RendererFunc(renderNDJSON))
+	return registry
+}
+
+// Register adds a renderer to the default registry
+func Register(format string, renderer Renderer) {
+	defaultRegistry.Register(format, renderer)
+}
+
+// Formats returns the formats available in the default registry
+func Formats() []string {
+	return defaultRegistry.Formats()
+}
+
+// Render writes data to w in the given format using the default registry
+func Render(w io.Writer, format string, data interface{}) error {
+	return defaultRegistry.Render(w, format, data)
+}
+
+// renderJSON writes indented JSON
+func renderJSON(w io.Writer, data interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+// renderYAML writes YAML
+func renderYAML(w io.Writer, data interface{}) error {
+	encoder := yaml.NewEncoder(w)
+	defer encoder.Close()
+	return encoder.Encode(data)
+}
+
+// renderTable writes tabular data as an aligned table, falling back to text.
+// Tables are borderless unless SetTableOptions chose a layout
+func renderTable(w io.Writer, data interface{}) error {
+	tabular, ok := data.(Tabular)
+	if !ok {
+		return renderText(w, data)
+	}
+	if options := tableOptions.Load(); options != nil {
+		RenderTable(w, tabular.TableHeaders(), tabular.TableRows(), *options)
+		return nil
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(tabular.TableHeaders())
+	table.SetBorder(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator("")
+	table.SetColumnSeparator("")
+	table.SetRowSeparator("")
+	table.SetHeaderLine(false)
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.AppendBulk(tabular.TableRows())
+	table.Render()
+	return nil
+}
+
+// renderText writes a human-readable summary
+func renderText(w io.Writer, data interface{}) error {
+	if summarizer, ok := data.(Summarizer); ok {
+		_, err := fmt.Fprintln(w, summarizer.Summary())
+		return err
+	}
+	if _, ok := data.(Tabular); ok {
+		return renderTable(w, data)
+	}
+	_, err := fmt.Fprintf(w, "%+v\n", data)
+	return err
+}
+
+// renderCSV writes tabular data as CSV
+func renderCSV(w io.Writer, data interface{}) error {
+	tabular, ok := data.(Tabular)
+	if !ok {
+		return fmt.Errorf("csv output is not supported for %T", data)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(tabular.TableHeaders()); err != nil {
+		return fmt.Errorf("failed to write csv header: %w", err)
+	}
+	if err := writer.WriteAll(tabular.TableRows()); err != nil {
+		return fmt.Errorf("failed to write csv rows: %w", err)
+	}
+	return nil
+}
+
+// renderNDJSON writes one JSON document per line, one per element for slices
+func renderNDJSON(w io.Writer, data interface{}) error {
+	encoder := json.NewEncoder(w)
+
+	value := reflect.ValueOf(data)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return encoder.Encode(data)
+	}
+
+	for i := 0; i < value.Len(); i++ {
+		if err := encoder.Encode(value.Index(i).Interface()); err != nil {
+			return fmt.Errorf("failed to encode item %d: %w", i, err)
+		}
+	}
+	return nil
+}
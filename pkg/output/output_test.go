@@ -0,0 +1,226 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// sampleResult is a test result type providing table and summary renderers
+type sampleResult []sampleItem
+
+type sampleItem struct {
+	Name   string `json:"name" yaml:"name"`
+	Status string `json:"status" yaml:"status"`
+}
+
+func (r sampleResult) TableHeaders() []string {
+	return []string{"Name", "Status"}
+}
+
+func (r sampleResult) TableRows() [][]string {
+	rows := make([][]string, 0, len(r))
+	for _, item := range r {
+		rows = append(rows, []string{item.Name, item.Status})
+	}
+	return rows
+}
+
+func (r sampleResult) Summary() string {
+	return strings.Join([]string{r[0].Name + " is " + r[0].Status, r[1].Name + " is " + r[1].Status}, "\n")
+}
+
+func TestRenderFormats(t *testing.T) {
+	result := sampleResult{
+		{Name: "web", Status: "running"},
+		{Name: "db", Status: "stopped"},
+	}
+
+	tests := []struct {
+		format   string
+		expected []string
+	}{
+		{"json", []string{`"name": "web"`, `"status": "stopped"`}},
+		{"yaml", []string{"- name: web", "  status: stopped"}},
+		{"table", []string{"NAME", "STATUS", "web", "running", "db"}},
+		{"text", []string{"web is running\ndb is stopped"}},
+		{"csv", []string{"Name,Status\nweb,running\ndb,stopped\n"}},
+		{"ndjson", []string{"{\"name\":\"web\",\"status\":\"running\"}\n{\"name\":\"db\",\"status\":\"stopped\"}\n"}},
+	}
+
+	if len(tests) != len(Formats()) {
+		t.Fatalf("Expected a test case for each registered format %v", Formats())
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Render(&buf, tt.format, result); err != nil {
+				t.Fatalf("Render() failed: %v", err)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(buf.String(), expected) {
+					t.Errorf("Expected %s output to contain %q, got:\n%s", tt.format, expected, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRenderUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	err := Render(&buf, "xml", sampleResult{})
+	if err == nil {
+		t.Fatal("Expected error for unknown format")
+	}
+	if !strings.Contains(err.Error(), "available: csv, json, ndjson, table, text, yaml") {
+		t.Errorf("Expected error to list available formats, got: %v", err)
+	}
+}
+
+func TestRenderFallbacks(t *testing.T) {
+	data := map[string]string{"status": "ok"}
+
+	// Non-tabular data falls back to text for tables and fails for csv
+	var buf bytes.Buffer
+	if err := Render(&buf, "table", data); err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "status:ok") {
+		t.Errorf("Expected text fallback, got: %s", buf.String())
+	}
+	if err := Render(&buf, "csv", data); err == nil {
+		t.Error("Expected csv to fail for non-tabular data")
+	}
+
+	// ndjson encodes non-slice data as a single document
+	buf.Reset()
+	if err := Render(&buf, "ndjson", data); err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	var decoded map[string]string
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || decoded["status"] != "ok" {
+		t.Errorf("Expected single JSON document, got: %s", buf.String())
+	}
+}
+
+func TestRegisterCustomFormat(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("Names", RendererFunc(func(w io.Writer, data interface{}) error {
+		for _, row := range data.(Tabular).TableRows() {
+			if _, err := io.WriteString(w, row[0]+"\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	var buf bytes.Buffer
+	if err := registry.Render(&buf, "names", sampleResult{{Name: "web"}, {Name: "db"}}); err != nil {
+		t.Fatalf("Render() failed: %v", err)
+	}
+	if buf.String() != "web\ndb\n" {
+		t.Errorf("Unexpected custom output: %q", buf.String())
+	}
+	if formats := registry.Formats(); len(formats) != 1 || formats[0] != "names" {
+		t.Errorf("Expected only the custom format, got %v", formats)
+	}
+}
+
+func TestFormatFlag(t *testing.T) {
+	var format string
+	flag := NewFormatFlag(&format, "table", "table", "json", "yaml")
+	if format != "table" || flag.String() != "table" {
+		t.Errorf("expected the default format, got %q", format)
+	}
+
+	if err := flag.Set("JSON"); err != nil {
+		t.Fatalf("Set() failed: %v", err)
+	}
+	if format != "json" {
+		t.Errorf("expected json, got %q", format)
+	}
+
+	err := flag.Set("xml")
+	if err == nil || err.Error() != "must be one of table, json, yaml" {
+		t.Errorf("expected the allowed formats in the error, got %v", err)
+	}
+	if format != "json" {
+		t.Errorf("expected a rejected format to leave the value unchanged, got %q", format)
+	}
+}
+
+func TestRenderTableAlignsWideCharacters(t *testing.T) {
+	headers := []string{"Name", "Tags"}
+	rows := [][]string{
+		{"web", "env=prod"},
+		{"東京サーバー", "🚀 launch"},
+		{"db", "チーム=データ"},
+	}
+
+	for _, style := range []TableStyle{TableStyleASCII, TableStyleBox} {
+		var out bytes.Buffer
+		RenderTable(&out, headers, rows, TableOptions{Style: style})
+
+		lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+		width := runewidth.StringWidth(lines[0])
+		for _, line := range lines {
+			if got := runewidth.StringWidth(line); got != width {
+				t.Errorf("style %d: expected every line %d columns wide, got %d in %q", style, width, got, line)
+			}
+		}
+		if style == TableStyleBox {
+			if len(lines) != 7 || !strings.HasPrefix(lines[0], "┌") || !strings.HasPrefix(lines[2], "├") || !strings.HasPrefix(lines[6], "└") {
+				t.Errorf("expected box borders, got\n%s", out.String())
+			}
+		} else if len(lines) != 5 || lines[1] != "|--------------|---------------|" {
+			t.Errorf("expected an ASCII table, got\n%s", out.String())
+		}
+	}
+}
+
+func TestRenderTableTruncatesWideCells(t *testing.T) {
+	headers := []string{"ID", "Description"}
+	rows := [][]string{{"1", "a very long description that would not fit"}, {"2", "説明がとても長いセルです"}}
+
+	var out bytes.Buffer
+	RenderTable(&out, headers, rows, TableOptions{MaxColumnWidth: 12})
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if lines[2] != "| 1  | a very long… |" {
+		t.Errorf("expected the cell cut to 12 columns with an ellipsis, got %q", lines[2])
+	}
+	if !strings.Contains(lines[3], "…") || runewidth.StringWidth(lines[3]) != runewidth.StringWidth(lines[2]) {
+		t.Errorf("expected the CJK cell cut to the same width, got %q", lines[3])
+	}
+
+	out.Reset()
+	RenderTable(&out, headers, rows, TableOptions{MaxWidth: 30})
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		if got := runewidth.StringWidth(line); got > 30 {
+			t.Errorf("expected the table to fit 30 columns, got %d in %q", got, line)
+		}
+	}
+	if !strings.Contains(out.String(), "| 1  |") {
+		t.Errorf("expected narrow columns to be left alone, got\n%s", out.String())
+	}
+}
+
+func TestRenderTableWithOptions(t *testing.T) {
+	result := sampleResult{{Name: "東京", Status: "running"}, {Name: "db", Status: "stopped"}}
+
+	SetTableOptions(&TableOptions{Style: TableStyleBox})
+	defer SetTableOptions(nil)
+
+	var buf bytes.Buffer
+	if err := Render(&buf, "table", result); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 6 || lines[0] != "┌──────┬─────────┐" || lines[3] != "│ 東京 │ running │" {
+		t.Errorf("Expected a box table, got\n%s", buf.String())
+	}
+}
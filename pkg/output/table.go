@@ -0,0 +1,165 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// TableStyle selects the characters tables are drawn with
+type TableStyle int
+
+const (
+	// TableStyleASCII draws tables with pipes and dashes
+	TableStyleASCII TableStyle = iota
+	// TableStyleBox draws tables with Unicode box-drawing lines
+	TableStyleBox
+)
+
+// minColumnWidth is the narrowest a column is squeezed to to fit MaxWidth
+const minColumnWidth = 5
+
+// ellipsis ends truncated cells
+const ellipsis = "…"
+
+// TableOptions controls how a table is laid out
+type TableOptions struct {
+	Style TableStyle
+	// MaxColumnWidth truncates cells wider than it, if positive
+	MaxColumnWidth int
+	// MaxWidth narrows the widest columns until the table fits, if positive
+	MaxWidth int
+	// Header, if set, decorates each padded header cell, such as with color
+	Header func(cell string) string
+}
+
+// tableBorder holds the characters of one table style. Corners and joins
+// are listed left, middle, right
+type tableBorder struct {
+	horizontal, vertical string
+	top, separator       [3]string
+	bottom               *[3]string
+}
+
+var tableBorders = map[TableStyle]tableBorder{
+	TableStyleASCII: {
+		horizontal: "-",
+		vertical:   "|",
+		separator:  [3]string{"|", "|", "|"},
+	},
+	TableStyleBox: {
+		horizontal: "─",
+		vertical:   "│",
+		top:        [3]string{"┌", "┬", "┐"},
+		separator:  [3]string{"├", "┼", "┤"},
+		bottom:     &[3]string{"└", "┴", "┘"},
+	},
+}
+
+// tableOptions lays out the table format with RenderTable when set, such as
+// by the --table flag
+var tableOptions atomic.Pointer[TableOptions]
+
+// SetTableOptions makes the table format draw tables with RenderTable laid
+// out by options, or borderless as by default when options is nil
+func SetTableOptions(options *TableOptions) {
+	tableOptions.Store(options)
+}
+
+// RenderTable writes a table to w. Columns are as wide as their widest cell
+// on screen, so wide characters such as CJK and emoji stay aligned
+func RenderTable(w io.Writer, headers []string, rows [][]string, options TableOptions) {
+	border, ok := tableBorders[options.Style]
+	if !ok {
+		border = tableBorders[TableStyleASCII]
+	}
+
+	cells := make([][]string, 0, len(rows)+1)
+	for _, row := range append([][]string{headers}, rows...) {
+		line := make([]string, len(headers))
+		for i := range line {
+			if i < len(row) {
+				// A newline would break the row across lines
+				line[i] = strings.ReplaceAll(row[i], "\n", " ")
+			}
+		}
+		cells = append(cells, line)
+	}
+
+	widths := columnWidths(cells, options)
+
+	rule := func(joins [3]string) {
+		fmt.Fprint(w, joins[0])
+		for i, width := range widths {
+			if i > 0 {
+				fmt.Fprint(w, joins[1])
+			}
+			fmt.Fprint(w, strings.Repeat(border.horizontal, width+2))
+		}
+		fmt.Fprintln(w, joins[2])
+	}
+
+	if options.Style == TableStyleBox {
+		rule(border.top)
+	}
+	for i, line := range cells {
+		fmt.Fprint(w, border.vertical)
+		for j, cell := range line {
+			cell = runewidth.Truncate(cell, widths[j], ellipsis)
+			padded := cell + strings.Repeat(" ", widths[j]-runewidth.StringWidth(cell))
+			if i == 0 && options.Header != nil {
+				padded = options.Header(padded)
+			}
+			fmt.Fprintf(w, " %s %s", padded, border.vertical)
+		}
+		fmt.Fprintln(w)
+		if i == 0 {
+			rule(border.separator)
+		}
+	}
+	if border.bottom != nil {
+		rule(*border.bottom)
+	}
+}
+
+// columnWidths returns the display width of each column, capped by
+// MaxColumnWidth and then squeezed, widest first, to fit MaxWidth
+func columnWidths(cells [][]string, options TableOptions) []int {
+	widths := make([]int, len(cells[0]))
+	for _, line := range cells {
+		for i, cell := range line {
+			widths[i] = max(widths[i], runewidth.StringWidth(cell))
+		}
+	}
+
+	if options.MaxColumnWidth > 0 {
+		for i := range widths {
+			widths[i] = min(widths[i], max(options.MaxColumnWidth, 1))
+		}
+	}
+
+	if options.MaxWidth > 0 {
+		// Each column is padded by a space either side and followed by a border
+		total := 1
+		for _, width := range widths {
+			total += width + 3
+		}
+		for total > options.MaxWidth {
+			widest := 0
+			for i, width := range widths {
+				if width > widths[widest] {
+					widest = i
+				}
+			}
+			if widths[widest] <= minColumnWidth {
+				break
+			}
+			widths[widest]--
+			total--
+		}
+	}
+	return widths
+}
@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/AlloraAi/AlloraCLI/pkg/utils"
 	"github.com/sirupsen/logrus"
 )
 
@@ -55,8 +56,7 @@ type PluginInfo struct {
 
 // NewPluginManager creates a new plugin manager
 func NewPluginManager(config *PluginConfig) *PluginManager {
-	logger := logrus.New()
-	logger.SetLevel(logrus.InfoLevel)
+	logger := utils.NewLogger()
 
 	return &PluginManager{
 		plugins:     make(map[string]Plugin),
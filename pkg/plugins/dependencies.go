@@ -0,0 +1,108 @@
+package plugins
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// missingDependencies describes each dependency of a plugin that is not
+// installed or not enabled, such as "base (not installed)"
+func (p *DefaultPluginService) missingDependencies(info *PluginInfo) []string {
+	var missing []string
+	for _, dep := range info.Dependencies {
+		installed, exists := p.plugins[dep]
+		switch {
+		case !exists:
+			missing = append(missing, dep+" (not installed)")
+		case !installed.Enabled:
+			missing = append(missing, dep+" (disabled)")
+		}
+	}
+	return missing
+}
+
+// dependencyCycle returns a dependency cycle among installed plugins that is
+// reachable from name, starting and ending with the same plugin, or nil
+func (p *DefaultPluginService) dependencyCycle(name string) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var stack []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		stack = append(stack, name)
+
+		if info, exists := p.plugins[name]; exists {
+			for _, dep := range info.Dependencies {
+				if _, installed := p.plugins[dep]; !installed {
+					continue
+				}
+				switch state[dep] {
+				case visiting:
+					for i := len(stack) - 1; i >= 0; i-- {
+						if stack[i] == dep {
+							cycle := append([]string{}, stack[i:]...)
+							return append(cycle, dep)
+						}
+					}
+				case unvisited:
+					if cycle := visit(dep); cycle != nil {
+						return cycle
+					}
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+		return nil
+	}
+
+	return visit(name)
+}
+
+// checkDependencies returns an error if a plugin's dependencies form a cycle
+// or are not all installed and enabled
+func (p *DefaultPluginService) checkDependencies(info *PluginInfo) error {
+	if cycle := p.dependencyCycle(info.Name); cycle != nil {
+		return fmt.Errorf("dependency cycle detected: %s", strings.Join(cycle, " -> "))
+	}
+	if missing := p.missingDependencies(info); len(missing) > 0 {
+		return fmt.Errorf("missing dependencies: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// disableUnsatisfied disables enabled plugins whose dependencies are not
+// satisfied, repeating until plugins that depend on them are disabled too
+func (p *DefaultPluginService) disableUnsatisfied() {
+	names := make([]string, 0, len(p.plugins))
+	for name := range p.plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for changed := true; changed; {
+		changed = false
+		for _, name := range names {
+			info := p.plugins[name]
+			if !info.Enabled {
+				continue
+			}
+			if err := p.checkDependencies(info); err != nil {
+				info.Enabled = false
+				info.Status = "disabled"
+				changed = true
+				logrus.Warnf("Plugin %s is disabled: %v", name, err)
+			}
+		}
+	}
+}
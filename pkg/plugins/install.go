@@ -0,0 +1,350 @@
+package plugins
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// manifestFileName is the manifest every plugin archive must contain
+const manifestFileName = "manifest.yaml"
+
+// maxArtifactSize bounds the size of a downloaded plugin archive
+const maxArtifactSize = 256 * 1024 * 1024
+
+// downloadTimeout bounds how long fetching a plugin archive may take
+const downloadTimeout = 5 * time.Minute
+
+// fetchArtifact reads a plugin archive from an HTTP(S) URL, a file:// URL
+// or a local path
+func (p *DefaultPluginService) fetchArtifact(ctx context.Context, source string) ([]byte, error) {
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 {
+		// Windows drive letters parse as single-letter schemes
+		return readLocalArtifact(source)
+	}
+
+	switch u.Scheme {
+	case "file":
+		return readLocalArtifact(u.Path)
+	case "http", "https":
+		if err := p.checkAllowedSource(u); err != nil {
+			return nil, err
+		}
+		return downloadArtifact(ctx, u.String())
+	default:
+		return nil, fmt.Errorf("unsupported plugin source scheme %q", u.Scheme)
+	}
+}
+
+// checkAllowedSource rejects remote sources whose host is not listed in
+// plugins.allowed_sources
+func (p *DefaultPluginService) checkAllowedSource(u *url.URL) error {
+	if p.config == nil {
+		return nil
+	}
+	return checkAllowedHost(p.config.Plugins.AllowedSources, u)
+}
+
+// checkAllowedHost rejects u unless its host, or a domain it is under, is in
+// allowedSources; an empty list allows any host
+func checkAllowedHost(allowedSources []string, u *url.URL) error {
+	if len(allowedSources) == 0 {
+		return nil
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range allowedSources {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("plugin source %s is not in plugins.allowed_sources", host)
+}
+
+// readLocalArtifact reads a plugin archive from disk
+func readLocalArtifact(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin archive: %w", err)
+	}
+	if info.Size() > maxArtifactSize {
+		return nil, fmt.Errorf("plugin archive %s exceeds %d bytes", path, maxArtifactSize)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin archive: %w", err)
+	}
+	return data, nil
+}
+
+// downloadArtifact fetches a plugin archive over HTTP(S)
+func downloadArtifact(ctx context.Context, source string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create download request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download plugin: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download plugin: %s returned %s", source, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxArtifactSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download plugin: %w", err)
+	}
+	if len(data) > maxArtifactSize {
+		return nil, fmt.Errorf("plugin archive from %s exceeds %d bytes", source, maxArtifactSize)
+	}
+	return data, nil
+}
+
+// extractArchive unpacks a .tar.gz or .zip plugin archive into dir, detecting
+// the format from its contents
+func extractArchive(data []byte, dir string) error {
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return extractTarGz(data, dir)
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return extractZip(data, dir)
+	default:
+		return fmt.Errorf("unsupported plugin archive format: expected .tar.gz or .zip")
+	}
+}
+
+// archivePath resolves an archive entry name inside dir, rejecting entries
+// that would escape it
+func archivePath(dir, name string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("plugin archive entry %q escapes the plugin directory", name)
+	}
+	return filepath.Join(dir, cleaned), nil
+}
+
+// writeArchiveFile copies an archive entry to path
+func writeArchiveFile(path string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", filepath.Base(path), err)
+	}
+	if _, err := io.Copy(file, io.LimitReader(r, maxArtifactSize)); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to extract %s: %w", filepath.Base(path), err)
+	}
+	return file.Close()
+}
+
+// extractTarGz unpacks a gzipped tarball into dir
+func extractTarGz(data []byte, dir string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to open plugin archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read plugin archive: %w", err)
+		}
+
+		path, err := archivePath(dir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return fmt.Errorf("failed to create plugin directory: %w", err)
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(path, tr, os.FileMode(header.Mode)); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("plugin archive entry %q has unsupported type; only files and directories are allowed", header.Name)
+		}
+	}
+}
+
+// extractZip unpacks a zip archive into dir
+func extractZip(data []byte, dir string) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("failed to open plugin archive: %w", err)
+	}
+
+	for _, entry := range zr.File {
+		path, err := archivePath(dir, entry.Name)
+		if err != nil {
+			return err
+		}
+
+		mode := entry.Mode()
+		if mode.IsDir() {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return fmt.Errorf("failed to create plugin directory: %w", err)
+			}
+			continue
+		}
+		if !mode.IsRegular() {
+			return fmt.Errorf("plugin archive entry %q has unsupported type; only files and directories are allowed", entry.Name)
+		}
+
+		rc, err := entry.Open()
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", entry.Name, err)
+		}
+		err = writeArchiveFile(path, rc, mode)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pluginRoot returns the directory holding the manifest, allowing archives
+// that wrap their contents in a single top-level directory
+func pluginRoot(dir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(dir, manifestFileName)); err == nil {
+		return dir, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read extracted plugin: %w", err)
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		nested := filepath.Join(dir, entries[0].Name())
+		if _, err := os.Stat(filepath.Join(nested, manifestFileName)); err == nil {
+			return nested, nil
+		}
+	}
+	return "", fmt.Errorf("plugin archive does not contain %s", manifestFileName)
+}
+
+// readManifest parses and validates the manifest in dir
+func readManifest(dir string) (*PluginManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin manifest: %w", err)
+	}
+
+	var manifest PluginManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse plugin manifest: %w", err)
+	}
+
+	var missing []string
+	for _, field := range []struct{ name, value string }{
+		{"name", manifest.Name},
+		{"version", manifest.Version},
+		{"binary", manifest.Binary},
+		{"checksum", manifest.Checksum},
+	} {
+		if strings.TrimSpace(field.value) == "" {
+			missing = append(missing, field.name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("invalid plugin manifest: missing %s", strings.Join(missing, ", "))
+	}
+	if strings.ContainsAny(manifest.Name, `/\`) || manifest.Name == "." || manifest.Name == ".." {
+		return nil, fmt.Errorf("invalid plugin manifest: invalid name %q", manifest.Name)
+	}
+	return &manifest, nil
+}
+
+// verifyChecksum compares the SHA-256 of the plugin binary with the
+// manifest checksum, which may carry a "sha256:" prefix
+func verifyChecksum(dir string, manifest *PluginManifest) error {
+	path, err := archivePath(dir, manifest.Binary)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("plugin binary %s not found in archive: %w", manifest.Binary, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("failed to hash plugin binary: %w", err)
+	}
+
+	actual := hex.EncodeToString(hash.Sum(nil))
+	expected := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(manifest.Checksum), "sha256:"))
+	if actual != expected {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", manifest.Binary, expected, actual)
+	}
+	return nil
+}
+
+// pluginInfoFromManifest builds the registered metadata for a plugin installed in dir
+func pluginInfoFromManifest(manifest *PluginManifest, dir string, installed time.Time) *PluginInfo {
+	config := manifest.Config
+	if config == nil {
+		config = make(map[string]string)
+	}
+	dependencies := manifest.Dependencies
+	if dependencies == nil {
+		dependencies = []string{}
+	}
+
+	return &PluginInfo{
+		Name:         manifest.Name,
+		Version:      manifest.Version,
+		Description:  manifest.Description,
+		Author:       manifest.Author,
+		License:      manifest.License,
+		Homepage:     manifest.Homepage,
+		Repository:   manifest.Repository,
+		Tags:         manifest.Tags,
+		Commands:     manifest.Commands,
+		Status:       "installed",
+		Enabled:      true,
+		Installed:    installed,
+		Updated:      installed,
+		Config:       config,
+		Dependencies: dependencies,
+		binaryPath:   filepath.Join(dir, filepath.FromSlash(manifest.Binary)),
+	}
+}
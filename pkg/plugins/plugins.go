@@ -4,17 +4,22 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-plugin"
+	"github.com/sirupsen/logrus"
 )
 
 // PluginService interface defines plugin management operations
 type PluginService interface {
 	ListPlugins(ctx context.Context) ([]PluginInfo, error)
-	InstallPlugin(ctx context.Context, name string, source string) error
+	InstallPlugin(ctx context.Context, opts InstallOptions) error
 	UpdatePlugin(ctx context.Context, name string) error
 	UninstallPlugin(ctx context.Context, name string) error
 	EnablePlugin(ctx context.Context, name string) error
@@ -22,6 +27,20 @@ type PluginService interface {
 	GetPluginInfo(ctx context.Context, name string) (*PluginInfo, error)
 	ExecutePlugin(ctx context.Context, name string, args []string) (*PluginResult, error)
 	SearchPlugins(ctx context.Context, query string) ([]PluginSearchResult, error)
+	GetRegistryManifest(ctx context.Context, name string) (*PluginManifest, error)
+	Close() error
+}
+
+// InstallOptions represents plugin installation options
+type InstallOptions struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	// Version is the registry release to install when Source is empty;
+	// empty or latest installs the latest release. Dependencies are always
+	// installed at their latest release
+	Version string `json:"version"`
+	// WithDependencies installs missing dependencies from the registry
+	WithDependencies bool `json:"with_dependencies"`
 }
 
 // PluginInfo represents plugin information
@@ -41,6 +60,8 @@ type PluginInfo struct {
 	Updated      time.Time         `json:"updated"`
 	Config       map[string]string `json:"config"`
 	Dependencies []string          `json:"dependencies"`
+
+	binaryPath string
 }
 
 // CommandInfo represents a plugin command
@@ -94,26 +115,33 @@ type Plugin interface {
 
 // PluginManifest represents the plugin manifest file
 type PluginManifest struct {
-	Name         string            `yaml:"name"`
-	Version      string            `yaml:"version"`
-	Description  string            `yaml:"description"`
-	Author       string            `yaml:"author"`
-	License      string            `yaml:"license"`
-	Homepage     string            `yaml:"homepage"`
-	Repository   string            `yaml:"repository"`
-	Tags         []string          `yaml:"tags"`
-	Commands     []CommandInfo     `yaml:"commands"`
-	Dependencies []string          `yaml:"dependencies"`
-	Config       map[string]string `yaml:"config"`
-	Binary       string            `yaml:"binary"`
-	Checksum     string            `yaml:"checksum"`
-}
+	Name         string            `yaml:"name" json:"name"`
+	Version      string            `yaml:"version" json:"version"`
+	Description  string            `yaml:"description" json:"description"`
+	Author       string            `yaml:"author" json:"author"`
+	License      string            `yaml:"license" json:"license"`
+	Homepage     string            `yaml:"homepage" json:"homepage"`
+	Repository   string            `yaml:"repository" json:"repository"`
+	Tags         []string          `yaml:"tags" json:"tags"`
+	Commands     []CommandInfo     `yaml:"commands" json:"commands"`
+	Dependencies []string          `yaml:"dependencies" json:"dependencies"`
+	Config       map[string]string `yaml:"config" json:"config"`
+	Binary       string            `yaml:"binary" json:"binary"`
+	Checksum     string            `yaml:"checksum" json:"checksum"`
+	// Signature is a base64 Ed25519 signature of the binary
+	Signature string `yaml:"signature,omitempty" json:"signature,omitempty"`
+}
+
+// pluginStartTimeout bounds how long a plugin binary may take to complete the handshake
+const pluginStartTimeout = 10 * time.Second
 
 // DefaultPluginService provides a default implementation
 type DefaultPluginService struct {
 	config    *config.Config
 	pluginDir string
 	plugins   map[string]*PluginInfo
+	registry  PluginRegistry
+	manager   *PluginManager
 }
 
 // NewPluginService creates a new plugin service
@@ -127,11 +155,18 @@ func NewPluginService(cfg *config.Config) (PluginService, error) {
 		pluginDir = filepath.Join(homeDir, ".config", "alloracli", "plugins")
 	}
 
+	registryURL := cfg.Plugins.Registry
+	if registryURL == "" {
+		registryURL = DefaultRegistryURL
+	}
+
 	service := &DefaultPluginService{
 		config:    cfg,
 		pluginDir: pluginDir,
 		plugins:   make(map[string]*PluginInfo),
+		registry:  NewLocalPluginRegistry(registryURL, cfg.Plugins.AllowedSources),
 	}
+	service.manager = NewPluginManager(service, service.registry)
 
 	// Load existing plugins
 	if err := service.loadPlugins(); err != nil {
@@ -152,47 +187,140 @@ func (p *DefaultPluginService) ListPlugins(ctx context.Context) ([]PluginInfo, e
 	return plugins, nil
 }
 
-// InstallPlugin installs a plugin from a source
-func (p *DefaultPluginService) InstallPlugin(ctx context.Context, name string, source string) error {
-	// Mock implementation - in real implementation, this would:
-	// 1. Download plugin from source
-	// 2. Verify checksum
-	// 3. Extract plugin
-	// 4. Validate manifest
-	// 5. Install dependencies
-	// 6. Register plugin
-
-	pluginInfo := &PluginInfo{
-		Name:        name,
-		Version:     "1.0.0",
-		Description: "Sample plugin",
-		Author:      "AlloraAi",
-		License:     "MIT",
-		Homepage:    "https://github.com/AlloraAi/allora-plugin-" + name,
-		Repository:  "https://github.com/AlloraAi/allora-plugin-" + name,
-		Tags:        []string{"sample"},
-		Commands: []CommandInfo{
-			{
-				Name:        name,
-				Description: "Execute " + name + " plugin",
-				Usage:       name + " [options]",
-				Flags:       []FlagInfo{},
-				Examples:    []string{name + " --help"},
-			},
-		},
-		Status:       "installed",
-		Enabled:      true,
-		Installed:    time.Now(),
-		Updated:      time.Now(),
-		Config:       make(map[string]string),
-		Dependencies: []string{},
-	}
-
-	p.plugins[name] = pluginInfo
+// InstallPlugin installs a plugin and, if requested, its missing dependencies
+// from the registry. Everything installed is removed again if any plugin
+// fails to install or the dependencies form a cycle; a plugin whose
+// dependencies are left missing is installed disabled
+func (p *DefaultPluginService) InstallPlugin(ctx context.Context, opts InstallOptions) error {
+	var installed []string
+	rollback := func() {
+		for i := len(installed) - 1; i >= 0; i-- {
+			p.removePlugin(installed[i])
+		}
+	}
+
+	var install func(name, source, version string) error
+	install = func(name, source, version string) error {
+		info, err := p.install(ctx, name, source, version)
+		if err != nil {
+			return err
+		}
+		installed = append(installed, info.Name)
+
+		if !opts.WithDependencies {
+			return nil
+		}
+		for _, dep := range info.Dependencies {
+			if _, exists := p.plugins[dep]; exists {
+				continue
+			}
+			if err := install(dep, "", "latest"); err != nil {
+				return fmt.Errorf("failed to install dependency %s of %s: %w", dep, info.Name, err)
+			}
+		}
+		return nil
+	}
 
+	if err := install(opts.Name, opts.Source, opts.Version); err != nil {
+		rollback()
+		return err
+	}
+
+	if cycle := p.dependencyCycle(installed[0]); cycle != nil {
+		rollback()
+		return fmt.Errorf("cannot install plugin %s: dependency cycle detected: %s", installed[0], strings.Join(cycle, " -> "))
+	}
+
+	p.disableUnsatisfied()
 	return nil
 }
 
+// removePlugin deletes an installed plugin's files and registration
+func (p *DefaultPluginService) removePlugin(name string) {
+	if err := os.RemoveAll(filepath.Join(p.pluginDir, name)); err != nil {
+		logrus.Warnf("Failed to remove plugin %s: %v", name, err)
+	}
+	delete(p.plugins, name)
+}
+
+// install downloads a .tar.gz or .zip plugin archive from source, or the
+// given release from the registry when source is empty, verifies the SHA-256
+// of its binary against the manifest checksum and its signature against the
+// trusted keys, and installs it into the plugin directory. Nothing is left
+// behind if any step fails
+func (p *DefaultPluginService) install(ctx context.Context, name, source, version string) (*PluginInfo, error) {
+	if _, exists := p.plugins[name]; exists {
+		return nil, fmt.Errorf("plugin %s is already installed", name)
+	}
+
+	var data []byte
+	var err error
+	if source == "" {
+		if version == "" {
+			version = "latest"
+		}
+		data, err = p.registry.Download(name, version)
+	} else {
+		data, err = p.fetchArtifact(ctx, source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(p.pluginDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(p.pluginDir, ".install-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	if err := extractArchive(data, staging); err != nil {
+		return nil, err
+	}
+
+	root, err := pluginRoot(staging)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := readManifest(root)
+	if err != nil {
+		return nil, err
+	}
+	if name != "" && manifest.Name != name {
+		return nil, fmt.Errorf("plugin archive contains %s, not %s", manifest.Name, name)
+	}
+
+	if err := verifyChecksum(root, manifest); err != nil {
+		return nil, err
+	}
+	var security config.SecurityConfig
+	if p.config != nil {
+		security = p.config.Security
+	}
+	if err := verifyPluginSignature(root, manifest, security); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(filepath.Join(root, filepath.FromSlash(manifest.Binary)), 0755); err != nil {
+		return nil, fmt.Errorf("failed to make plugin binary executable: %w", err)
+	}
+
+	dest := filepath.Join(p.pluginDir, manifest.Name)
+	if _, err := os.Stat(dest); err == nil {
+		return nil, fmt.Errorf("plugin directory %s already exists", dest)
+	}
+	if err := os.Rename(root, dest); err != nil {
+		return nil, fmt.Errorf("failed to install plugin: %w", err)
+	}
+
+	info := pluginInfoFromManifest(manifest, dest, time.Now())
+	p.plugins[manifest.Name] = info
+
+	return info, nil
+}
+
 // UpdatePlugin updates a plugin to the latest version
 func (p *DefaultPluginService) UpdatePlugin(ctx context.Context, name string) error {
 	plugin, exists := p.plugins[name]
@@ -226,6 +354,10 @@ func (p *DefaultPluginService) EnablePlugin(ctx context.Context, name string) er
 		return fmt.Errorf("plugin %s not found", name)
 	}
 
+	if err := p.checkDependencies(plugin); err != nil {
+		return fmt.Errorf("cannot enable plugin %s: %w", name, err)
+	}
+
 	plugin.Enabled = true
 	plugin.Status = "enabled"
 
@@ -255,7 +387,8 @@ func (p *DefaultPluginService) GetPluginInfo(ctx context.Context, name string) (
 	return plugin, nil
 }
 
-// ExecutePlugin executes a plugin with the given arguments
+// ExecutePlugin runs a plugin's Execute method over gRPC, starting the plugin
+// process if it is not already running
 func (p *DefaultPluginService) ExecutePlugin(ctx context.Context, name string, args []string) (*PluginResult, error) {
 	pluginInfo, exists := p.plugins[name]
 	if !exists {
@@ -268,167 +401,95 @@ func (p *DefaultPluginService) ExecutePlugin(ctx context.Context, name string, a
 
 	start := time.Now()
 
-	// Mock implementation - would execute the actual plugin
-	result := &PluginResult{
-		ExitCode: 0,
-		Output:   fmt.Sprintf("Plugin %s executed successfully with args: %v", name, args),
-		Error:    "",
-		Data: map[string]interface{}{
-			"plugin": name,
-			"args":   args,
-		},
-		Duration: time.Since(start),
+	client, err := p.manager.dispense(name)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := client.execute(ctx, args)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("plugin %s cancelled: %w", name, ctx.Err())
+		}
+		if p.manager.exited(name) {
+			return nil, fmt.Errorf("plugin %s exited unexpectedly: %w", name, err)
+		}
+		return nil, fmt.Errorf("plugin %s failed: %w", name, err)
 	}
+	result.Duration = time.Since(start)
 
 	return result, nil
 }
 
+// Close stops any plugin processes started by ExecutePlugin
+func (p *DefaultPluginService) Close() error {
+	p.manager.CleanupClients()
+	return nil
+}
+
 // SearchPlugins searches for plugins in the registry
 func (p *DefaultPluginService) SearchPlugins(ctx context.Context, query string) ([]PluginSearchResult, error) {
-	// Mock implementation - would search in plugin registries
-	results := []PluginSearchResult{
-		{
-			Name:        "aws-helper",
-			Version:     "2.1.0",
-			Description: "AWS infrastructure management helper",
-			Author:      "AlloraAi",
-			Tags:        []string{"aws", "cloud", "infrastructure"},
-			Downloads:   1500,
-			Rating:      4.8,
-			Updated:     time.Now().Add(-7 * 24 * time.Hour),
-			Source:      "https://registry.alloraai.com/plugins/aws-helper",
-		},
-		{
-			Name:        "k8s-manager",
-			Version:     "1.5.2",
-			Description: "Kubernetes cluster management utilities",
-			Author:      "AlloraAi",
-			Tags:        []string{"kubernetes", "k8s", "containers"},
-			Downloads:   2200,
-			Rating:      4.9,
-			Updated:     time.Now().Add(-3 * 24 * time.Hour),
-			Source:      "https://registry.alloraai.com/plugins/k8s-manager",
-		},
-		{
-			Name:        "monitoring-tools",
-			Version:     "3.0.1",
-			Description: "Comprehensive monitoring and alerting tools",
-			Author:      "AlloraAi",
-			Tags:        []string{"monitoring", "alerting", "metrics"},
-			Downloads:   1800,
-			Rating:      4.7,
-			Updated:     time.Now().Add(-10 * 24 * time.Hour),
-			Source:      "https://registry.alloraai.com/plugins/monitoring-tools",
-		},
-	}
-
-	// Filter results based on query
-	var filtered []PluginSearchResult
-	for _, result := range results {
-		if containsQuery(result, query) {
-			filtered = append(filtered, result)
-		}
+	results, err := p.registry.Search(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search plugin registry: %w", err)
 	}
+	return results, nil
+}
 
-	return filtered, nil
+// GetRegistryManifest gets the manifest the plugin registry lists for the
+// latest version of a plugin, which need not be installed
+func (p *DefaultPluginService) GetRegistryManifest(ctx context.Context, name string) (*PluginManifest, error) {
+	manifest, err := p.registry.GetMetadata(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plugin metadata: %w", err)
+	}
+	return manifest, nil
 }
 
-// loadPlugins loads plugins from the plugin directory
+// loadPlugins registers every subdirectory of the plugin directory that holds
+// a valid manifest; directories with invalid manifests are skipped with a warning
 func (p *DefaultPluginService) loadPlugins() error {
 	// Create plugin directory if it doesn't exist
 	if err := os.MkdirAll(p.pluginDir, 0755); err != nil {
 		return fmt.Errorf("failed to create plugin directory: %w", err)
 	}
 
-	// Mock implementation - would scan directory and load plugin manifests
-	// For now, we'll add some sample plugins
-	samplePlugins := []*PluginInfo{
-		{
-			Name:        "sample-plugin",
-			Version:     "1.0.0",
-			Description: "A sample plugin for demonstration",
-			Author:      "AlloraAi",
-			License:     "MIT",
-			Homepage:    "https://github.com/AlloraAi/allora-plugin-sample",
-			Repository:  "https://github.com/AlloraAi/allora-plugin-sample",
-			Tags:        []string{"sample", "demo"},
-			Commands: []CommandInfo{
-				{
-					Name:        "sample",
-					Description: "Execute sample plugin",
-					Usage:       "sample [options]",
-					Flags: []FlagInfo{
-						{
-							Name:        "verbose",
-							Short:       "v",
-							Description: "Enable verbose output",
-							Type:        "bool",
-							Default:     "false",
-							Required:    false,
-						},
-					},
-					Examples: []string{
-						"sample --verbose",
-						"sample -v",
-					},
-				},
-			},
-			Status:       "installed",
-			Enabled:      true,
-			Installed:    time.Now().Add(-30 * 24 * time.Hour),
-			Updated:      time.Now().Add(-7 * 24 * time.Hour),
-			Config:       make(map[string]string),
-			Dependencies: []string{},
-		},
-	}
-
-	for _, plugin := range samplePlugins {
-		p.plugins[plugin.Name] = plugin
+	entries, err := os.ReadDir(p.pluginDir)
+	if err != nil {
+		return fmt.Errorf("failed to read plugin directory: %w", err)
 	}
 
-	return nil
-}
+	for _, entry := range entries {
+		// Hidden directories include staging directories of interrupted installs
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
 
-// containsQuery checks if a search result matches the query
-func containsQuery(result PluginSearchResult, query string) bool {
-	if query == "" {
-		return true
-	}
+		dir := filepath.Join(p.pluginDir, entry.Name())
+		if _, err := os.Stat(filepath.Join(dir, manifestFileName)); os.IsNotExist(err) {
+			continue
+		}
 
-	// Simple text matching - in real implementation, this would be more sophisticated
-	if contains(result.Name, query) ||
-		contains(result.Description, query) ||
-		contains(result.Author, query) {
-		return true
-	}
+		manifest, err := readManifest(dir)
+		if err != nil {
+			logrus.Warnf("Skipping plugin in %s: %v", dir, err)
+			continue
+		}
+		if manifest.Name != entry.Name() {
+			logrus.Warnf("Skipping plugin in %s: manifest name %s does not match its directory", dir, manifest.Name)
+			continue
+		}
 
-	for _, tag := range result.Tags {
-		if contains(tag, query) {
-			return true
+		installed := time.Now()
+		if info, err := entry.Info(); err == nil {
+			installed = info.ModTime()
 		}
+		p.plugins[manifest.Name] = pluginInfoFromManifest(manifest, dir, installed)
 	}
 
-	return false
-}
-
-// contains checks if a string contains a substring (case-insensitive)
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) &&
-		(s == substr ||
-			len(s) > len(substr) &&
-				(s[:len(substr)] == substr ||
-					s[len(s)-len(substr):] == substr ||
-					containsSubstring(s, substr)))
-}
+	p.disableUnsatisfied()
 
-// containsSubstring checks if a string contains a substring
-func containsSubstring(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
-	}
-	return false
+	return nil
 }
 
 // Plugin registry interface for advanced plugin management
@@ -439,55 +500,12 @@ type PluginRegistry interface {
 	Verify(data []byte, checksum string) error
 }
 
-// LocalPluginRegistry implements a local plugin registry
-type LocalPluginRegistry struct {
-	baseURL string
-}
-
-// NewLocalPluginRegistry creates a new local plugin registry
-func NewLocalPluginRegistry(baseURL string) PluginRegistry {
-	return &LocalPluginRegistry{
-		baseURL: baseURL,
-	}
-}
-
-// Search searches for plugins in the registry
-func (r *LocalPluginRegistry) Search(query string) ([]PluginSearchResult, error) {
-	// Mock implementation - would make HTTP requests to registry
-	return []PluginSearchResult{}, nil
-}
-
-// GetMetadata gets plugin metadata from the registry
-func (r *LocalPluginRegistry) GetMetadata(name string) (*PluginManifest, error) {
-	// Mock implementation - would fetch metadata from registry
-	return &PluginManifest{
-		Name:        name,
-		Version:     "1.0.0",
-		Description: "Sample plugin",
-		Author:      "AlloraAi",
-		License:     "MIT",
-		Binary:      name,
-		Checksum:    "abc123def456",
-	}, nil
-}
-
-// Download downloads a plugin from the registry
-func (r *LocalPluginRegistry) Download(name string, version string) ([]byte, error) {
-	// Mock implementation - would download plugin binary
-	return []byte("mock plugin binary"), nil
-}
-
-// Verify verifies a plugin's integrity
-func (r *LocalPluginRegistry) Verify(data []byte, checksum string) error {
-	// Mock implementation - would verify checksum
-	return nil
-}
-
 // PluginManager manages the plugin lifecycle
 type PluginManager struct {
 	service  PluginService
 	registry PluginRegistry
 	clients  map[string]*plugin.Client
+	mu       sync.Mutex
 }
 
 // NewPluginManager creates a new plugin manager
@@ -499,19 +517,86 @@ func NewPluginManager(service PluginService, registry PluginRegistry) *PluginMan
 	}
 }
 
-// GetClient gets a plugin client for communication
+// GetClient returns the go-plugin client for a plugin, launching its binary
+// and completing the handshake if it is not running
 func (m *PluginManager) GetClient(name string) (*plugin.Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if client, exists := m.clients[name]; exists {
-		return client, nil
+		if !client.Exited() {
+			return client, nil
+		}
+		client.Kill()
+		delete(m.clients, name)
+	}
+
+	info, err := m.service.GetPluginInfo(context.Background(), name)
+	if err != nil {
+		return nil, err
+	}
+	if info.binaryPath == "" {
+		return nil, fmt.Errorf("plugin %s has no binary", name)
+	}
+
+	client := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig:  Handshake,
+		Plugins:          plugin.PluginSet{pluginKey: &GRPCPlugin{}},
+		Cmd:              exec.Command(info.binaryPath),
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+		StartTimeout:     pluginStartTimeout,
+		Logger: hclog.New(&hclog.LoggerOptions{
+			Name:   "plugin." + name,
+			Level:  hclog.Warn,
+			Output: logrus.StandardLogger().Out,
+		}),
+	})
+	if _, err := client.Client(); err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("failed to start plugin %s: %w", name, err)
+	}
+
+	m.clients[name] = client
+	return client, nil
+}
+
+// dispense returns the Plugin served by a running plugin process
+func (m *PluginManager) dispense(name string) (*grpcPluginClient, error) {
+	client, err := m.GetClient(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to plugin %s: %w", name, err)
+	}
+	raw, err := rpcClient.Dispense(pluginKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dispense plugin %s: %w", name, err)
 	}
 
-	// Mock implementation - would create actual plugin client
-	// using hashicorp/go-plugin
-	return nil, fmt.Errorf("plugin client not implemented for %s", name)
+	impl, ok := raw.(*grpcPluginClient)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s returned an unexpected client type %T", name, raw)
+	}
+	return impl, nil
+}
+
+// exited reports whether a plugin's process has stopped
+func (m *PluginManager) exited(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client, exists := m.clients[name]
+	return !exists || client.Exited()
 }
 
 // CleanupClients cleans up all plugin clients
 func (m *PluginManager) CleanupClients() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	for _, client := range m.clients {
 		client.Kill()
 	}
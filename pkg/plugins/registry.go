@@ -0,0 +1,205 @@
+package plugins
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultRegistryURL is the plugin registry used when plugins.registry is unset
+const DefaultRegistryURL = "https://registry.alloraai.com"
+
+// registryTimeout bounds registry API requests other than artifact downloads
+const registryTimeout = 30 * time.Second
+
+// maxRegistryResponse bounds the size of a registry API response
+const maxRegistryResponse = 10 * 1024 * 1024
+
+// registrySearchResponse is the body of GET /plugins?q=
+type registrySearchResponse struct {
+	Plugins []PluginSearchResult `json:"plugins"`
+}
+
+// registryPlugin is the body of GET /plugins/<name>: the plugin's manifest
+// plus where to download its archive and the archive's SHA-256
+type registryPlugin struct {
+	PluginManifest
+	DownloadURL string `json:"download_url"`
+	SHA256      string `json:"sha256"`
+}
+
+// LocalPluginRegistry is a client for the JSON plugin registry API at baseURL
+type LocalPluginRegistry struct {
+	baseURL string
+	client  *http.Client
+	// allowedSources are the hosts archives may be downloaded from, as in
+	// plugins.allowed_sources
+	allowedSources []string
+}
+
+// NewLocalPluginRegistry creates a new local plugin registry that downloads
+// archives only from allowedSources, or from any host when it is empty
+func NewLocalPluginRegistry(baseURL string, allowedSources []string) PluginRegistry {
+	return &LocalPluginRegistry{
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+		client:         &http.Client{},
+		allowedSources: allowedSources,
+	}
+}
+
+// registryError is a non-200 response from the registry
+type registryError struct {
+	status  int
+	message string
+}
+
+func (e *registryError) Error() string {
+	return e.message
+}
+
+// isNotFound reports whether err is a 404 from the registry
+func isNotFound(err error) bool {
+	regErr, ok := err.(*registryError)
+	return ok && regErr.status == http.StatusNotFound
+}
+
+// get fetches a registry URL and returns its body, failing on non-200 responses
+func (r *LocalPluginRegistry) get(rawURL string, timeout time.Duration, limit int64) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry request: %w", err)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach plugin registry: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if msg := strings.TrimSpace(string(body)); msg != "" {
+			return nil, &registryError{status: resp.StatusCode, message: fmt.Sprintf("%s returned %s: %s", rawURL, resp.Status, msg)}
+		}
+		return nil, &registryError{status: resp.StatusCode, message: fmt.Sprintf("%s returned %s", rawURL, resp.Status)}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry response: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("registry response from %s exceeds %d bytes", rawURL, limit)
+	}
+	return data, nil
+}
+
+// Search searches for plugins in the registry
+func (r *LocalPluginRegistry) Search(query string) ([]PluginSearchResult, error) {
+	data, err := r.get(r.baseURL+"/plugins?q="+url.QueryEscape(query), registryTimeout, maxRegistryResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	var response registrySearchResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse registry search results: %w", err)
+	}
+	if response.Plugins == nil {
+		response.Plugins = []PluginSearchResult{}
+	}
+	return response.Plugins, nil
+}
+
+// plugin fetches a plugin's registry entry, optionally for a specific version
+func (r *LocalPluginRegistry) plugin(name, version string) (*registryPlugin, error) {
+	endpoint := r.baseURL + "/plugins/" + url.PathEscape(name)
+	if version != "" && version != "latest" {
+		endpoint += "?version=" + url.QueryEscape(version)
+	}
+
+	data, err := r.get(endpoint, registryTimeout, maxRegistryResponse)
+	if err != nil {
+		if isNotFound(err) {
+			if version != "" && version != "latest" {
+				return nil, fmt.Errorf("plugin %s version %s not found in registry", name, version)
+			}
+			return nil, fmt.Errorf("plugin %s not found in registry", name)
+		}
+		return nil, err
+	}
+
+	var entry registryPlugin
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse registry metadata for %s: %w", name, err)
+	}
+	return &entry, nil
+}
+
+// GetMetadata gets plugin metadata from the registry
+func (r *LocalPluginRegistry) GetMetadata(name string) (*PluginManifest, error) {
+	entry, err := r.plugin(name, "")
+	if err != nil {
+		return nil, err
+	}
+	return &entry.PluginManifest, nil
+}
+
+// Download fetches a plugin archive from the artifact URL the registry lists
+// for the version, verifying it against the registry's SHA-256 when given
+func (r *LocalPluginRegistry) Download(name string, version string) ([]byte, error) {
+	entry, err := r.plugin(name, version)
+	if err != nil {
+		return nil, err
+	}
+	if entry.DownloadURL == "" {
+		return nil, fmt.Errorf("registry lists no download for plugin %s", name)
+	}
+
+	base, err := url.Parse(r.baseURL + "/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry URL %q: %w", r.baseURL, err)
+	}
+	artifact, err := url.Parse(entry.DownloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid download URL for plugin %s: %w", name, err)
+	}
+
+	artifact = base.ResolveReference(artifact)
+	if err := checkAllowedHost(r.allowedSources, artifact); err != nil {
+		return nil, fmt.Errorf("failed to download plugin %s: %w", name, err)
+	}
+
+	data, err := r.get(artifact.String(), downloadTimeout, maxArtifactSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download plugin %s: %w", name, err)
+	}
+
+	if entry.SHA256 != "" {
+		if err := r.Verify(data, entry.SHA256); err != nil {
+			return nil, fmt.Errorf("failed to verify plugin %s: %w", name, err)
+		}
+	}
+	return data, nil
+}
+
+// Verify compares the SHA-256 of data with checksum, which may carry a "sha256:" prefix
+func (r *LocalPluginRegistry) Verify(data []byte, checksum string) error {
+	sum := sha256.Sum256(data)
+	actual := hex.EncodeToString(sum[:])
+	expected := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(checksum), "sha256:"))
+	if actual != expected {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", expected, actual)
+	}
+	return nil
+}
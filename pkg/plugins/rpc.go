@@ -0,0 +1,241 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Handshake is the go-plugin handshake AlloraCLI and its plugins must agree on.
+// It is a UX check that a binary is an AlloraCLI plugin, not a security measure
+var Handshake = plugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "ALLORA_PLUGIN",
+	MagicCookieValue: "d9c6c4a8-2f35-4bb1-9c1e-5b6f0e7a3c21",
+}
+
+// pluginKey is the name the Plugin implementation is served under
+const pluginKey = "allora"
+
+// pluginServiceName is the gRPC service described in plugin.proto
+const pluginServiceName = "allora.plugin.v1.Plugin"
+
+// Serve runs impl as an AlloraCLI plugin; a plugin binary calls it from main
+// and it does not return until AlloraCLI shuts the plugin down
+func Serve(impl Plugin) {
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins: plugin.PluginSet{
+			pluginKey: &GRPCPlugin{Impl: impl},
+		},
+		GRPCServer: plugin.DefaultGRPCServer,
+	})
+}
+
+// GRPCPlugin exposes a Plugin over go-plugin's gRPC transport
+type GRPCPlugin struct {
+	plugin.NetRPCUnsupportedPlugin
+	Impl Plugin
+}
+
+// GRPCServer registers the plugin service with the plugin's gRPC server
+func (p *GRPCPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
+	s.RegisterService(&pluginServiceDesc, &grpcPluginServer{impl: p.Impl})
+	return nil
+}
+
+// GRPCClient returns a Plugin that forwards calls over conn
+func (p *GRPCPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, conn *grpc.ClientConn) (interface{}, error) {
+	return &grpcPluginClient{conn: conn}, nil
+}
+
+// toStruct converts a JSON-serialisable value to a protobuf Struct
+func toStruct(v interface{}) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode plugin message: %w", err)
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to encode plugin message: %w", err)
+	}
+	s, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode plugin message: %w", err)
+	}
+	return s, nil
+}
+
+// fromStruct decodes a protobuf Struct into v
+func fromStruct(s *structpb.Struct, v interface{}) error {
+	data, err := json.Marshal(s.AsMap())
+	if err != nil {
+		return fmt.Errorf("failed to decode plugin message: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode plugin message: %w", err)
+	}
+	return nil
+}
+
+// executeRequest is the Execute request message
+type executeRequest struct {
+	Args []string `json:"args"`
+}
+
+// configureRequest is the Configure request message
+type configureRequest struct {
+	Config map[string]string `json:"config"`
+}
+
+// pluginServer is the server side of the plugin service
+type pluginServer interface {
+	call(ctx context.Context, method string, req *structpb.Struct) (*structpb.Struct, error)
+}
+
+// grpcPluginServer serves a Plugin implementation inside the plugin process
+type grpcPluginServer struct {
+	impl Plugin
+}
+
+// call dispatches a plugin service method to the implementation
+func (s *grpcPluginServer) call(ctx context.Context, method string, req *structpb.Struct) (*structpb.Struct, error) {
+	var (
+		resp interface{}
+		err  error
+	)
+
+	switch method {
+	case "GetInfo":
+		resp = s.impl.GetInfo()
+	case "Execute":
+		var in executeRequest
+		if err := fromStruct(req, &in); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		resp, err = s.impl.Execute(in.Args)
+	case "Configure":
+		var in configureRequest
+		if err := fromStruct(req, &in); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		err = s.impl.Configure(in.Config)
+	case "Validate":
+		err = s.impl.Validate()
+	default:
+		return nil, status.Errorf(codes.Unimplemented, "unknown method %s", method)
+	}
+
+	if err != nil {
+		return nil, status.Error(codes.Unknown, err.Error())
+	}
+	if resp == nil {
+		return &structpb.Struct{}, nil
+	}
+	return toStruct(resp)
+}
+
+// unaryHandler adapts a plugin service method to a gRPC handler
+func unaryHandler(method string) grpc.MethodHandler {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := &structpb.Struct{}
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.(pluginServer).call(ctx, method, req.(*structpb.Struct))
+		}
+		if interceptor == nil {
+			return handler(ctx, req)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + pluginServiceName + "/" + method}
+		return interceptor(ctx, req, info, handler)
+	}
+}
+
+// pluginServiceDesc describes the service in plugin.proto; messages are
+// google.protobuf.Struct so no generated code is needed on either side
+var pluginServiceDesc = grpc.ServiceDesc{
+	ServiceName: pluginServiceName,
+	HandlerType: (*pluginServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "GetInfo", Handler: unaryHandler("GetInfo")},
+		{MethodName: "Execute", Handler: unaryHandler("Execute")},
+		{MethodName: "Configure", Handler: unaryHandler("Configure")},
+		{MethodName: "Validate", Handler: unaryHandler("Validate")},
+	},
+	Metadata: "plugin.proto",
+}
+
+// grpcPluginClient is the host side of a plugin connection
+type grpcPluginClient struct {
+	conn *grpc.ClientConn
+}
+
+// invoke calls a plugin service method, decoding the response into resp
+func (c *grpcPluginClient) invoke(ctx context.Context, method string, req interface{}, resp interface{}) error {
+	in := &structpb.Struct{}
+	if req != nil {
+		var err error
+		if in, err = toStruct(req); err != nil {
+			return err
+		}
+	}
+
+	out := &structpb.Struct{}
+	if err := c.conn.Invoke(ctx, "/"+pluginServiceName+"/"+method, in, out); err != nil {
+		if s, ok := status.FromError(err); ok && s.Code() == codes.Unknown {
+			return errors.New(s.Message())
+		}
+		return fmt.Errorf("plugin %s call failed: %w", method, err)
+	}
+
+	if resp == nil {
+		return nil
+	}
+	return fromStruct(out, resp)
+}
+
+// execute runs the plugin's Execute method, honouring ctx cancellation
+func (c *grpcPluginClient) execute(ctx context.Context, args []string) (*PluginResult, error) {
+	if args == nil {
+		args = []string{}
+	}
+	var result PluginResult
+	if err := c.invoke(ctx, "Execute", executeRequest{Args: args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetInfo returns the plugin's self-reported metadata, or nil if the call fails
+func (c *grpcPluginClient) GetInfo() *PluginInfo {
+	var info PluginInfo
+	if err := c.invoke(context.Background(), "GetInfo", nil, &info); err != nil {
+		return nil
+	}
+	return &info
+}
+
+// Execute runs the plugin with args
+func (c *grpcPluginClient) Execute(args []string) (*PluginResult, error) {
+	return c.execute(context.Background(), args)
+}
+
+// Configure passes configuration to the plugin
+func (c *grpcPluginClient) Configure(config map[string]string) error {
+	return c.invoke(context.Background(), "Configure", configureRequest{Config: config}, nil)
+}
+
+// Validate asks the plugin to check its configuration
+func (c *grpcPluginClient) Validate() error {
+	return c.invoke(context.Background(), "Validate", nil, nil)
+}
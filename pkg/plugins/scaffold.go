@@ -0,0 +1,211 @@
+package plugins
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// validScaffoldName matches plugin names accepted by Scaffold: they become a
+// directory, a binary and a Go identifier, so they are kept simple
+var validScaffoldName = regexp.MustCompile(`^[a-z][a-z0-9-]{0,62}[a-z0-9]$`)
+
+// ScaffoldOptions describes a plugin project created by Scaffold
+type ScaffoldOptions struct {
+	Name        string
+	Description string
+	Author      string
+	// Module is the Go module path of the plugin, Name if empty
+	Module string
+}
+
+// scaffoldData is the data the scaffold templates are executed with
+type scaffoldData struct {
+	ScaffoldOptions
+	TypeName string
+}
+
+// scaffoldFiles are the files Scaffold writes, keyed by name
+var scaffoldFiles = map[string]*template.Template{
+	manifestFileName: template.Must(template.New(manifestFileName).Parse(`name: {{.Name}}
+version: 0.1.0
+description: {{printf "%q" .Description}}
+author: {{printf "%q" .Author}}
+license: MIT
+tags: []
+commands:
+  - name: hello
+    description: Print a greeting
+    usage: allora plugin run {{.Name}} hello [name]
+binary: {{.Name}}
+# make package fills in the checksum of the built binary
+checksum: ""
+`)),
+
+	"main.go": template.Must(template.New("main.go").Parse(`// Command {{.Name}} is an AlloraCLI plugin
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/plugins"
+)
+
+// {{.TypeName}} implements the AlloraCLI plugin interface
+type {{.TypeName}} struct {
+	config map[string]string
+}
+
+// GetInfo describes the plugin to AlloraCLI
+func (p *{{.TypeName}}) GetInfo() *plugins.PluginInfo {
+	return &plugins.PluginInfo{
+		Name:        {{printf "%q" .Name}},
+		Version:     "0.1.0",
+		Description: {{printf "%q" .Description}},
+		Author:      {{printf "%q" .Author}},
+	}
+}
+
+// Execute runs the plugin with the arguments given to allora plugin run
+func (p *{{.TypeName}}) Execute(args []string) (*plugins.PluginResult, error) {
+	if len(args) == 0 || args[0] != "hello" {
+		return &plugins.PluginResult{ExitCode: 2, Error: "usage: {{.Name}} hello [name]"}, nil
+	}
+
+	name := "world"
+	if len(args) > 1 {
+		name = strings.Join(args[1:], " ")
+	}
+	return &plugins.PluginResult{Output: fmt.Sprintf("Hello, %s!\n", name)}, nil
+}
+
+// Configure receives the plugin's configuration from AlloraCLI
+func (p *{{.TypeName}}) Configure(config map[string]string) error {
+	p.config = config
+	return nil
+}
+
+// Validate checks the plugin's configuration
+func (p *{{.TypeName}}) Validate() error {
+	return nil
+}
+
+func main() {
+	// Serve performs the go-plugin handshake and blocks until AlloraCLI
+	// shuts the plugin down
+	plugins.Serve(&{{.TypeName}}{})
+}
+`)),
+
+	"go.mod": template.Must(template.New("go.mod").Parse(`module {{.Module}}
+
+go 1.23.0
+`)),
+
+	"Makefile": template.Must(template.New("Makefile").Parse(`NAME    := {{.Name}}
+VERSION := $(shell sed -n 's/^version: *//p' manifest.yaml)
+SHA256  ?= sha256sum
+DIST    := dist/$(NAME)
+
+.PHONY: build package install clean
+
+build: go.sum
+	go build -o $(NAME) .
+
+go.sum: go.mod main.go
+	go mod tidy
+
+package: build
+	rm -rf $(DIST) && mkdir -p $(DIST)
+	cp $(NAME) $(DIST)/
+	sed "s/^checksum:.*/checksum: sha256:$$($(SHA256) $(NAME) | cut -d' ' -f1)/" manifest.yaml > $(DIST)/manifest.yaml
+	tar -C dist -czf dist/$(NAME)-$(VERSION).tar.gz $(NAME)
+
+install: package
+	allora plugin install $(NAME) --source dist/$(NAME)-$(VERSION).tar.gz
+
+clean:
+	rm -rf $(NAME) dist
+`)),
+}
+
+// ValidateScaffoldName checks that name can be used for a new plugin
+func ValidateScaffoldName(name string) error {
+	if !validScaffoldName.MatchString(name) {
+		return fmt.Errorf("invalid plugin name %q: use 2-64 lowercase letters, digits and dashes, starting with a letter", name)
+	}
+	if strings.Contains(name, "--") {
+		return fmt.Errorf("invalid plugin name %q: dashes must separate words", name)
+	}
+	return nil
+}
+
+// Scaffold creates dir holding a buildable plugin project named
+// options.Name: a manifest, a main.go serving the plugin over the go-plugin
+// handshake, a go.mod and a Makefile. It refuses to touch an existing dir
+func Scaffold(dir string, options ScaffoldOptions) error {
+	if err := ValidateScaffoldName(options.Name); err != nil {
+		return err
+	}
+	if options.Module == "" {
+		options.Module = options.Name
+	}
+	if options.Description == "" {
+		options.Description = "An AlloraCLI plugin"
+	}
+
+	data := scaffoldData{ScaffoldOptions: options, TypeName: typeName(options.Name)}
+	files := make(map[string][]byte, len(scaffoldFiles))
+	for name, tmpl := range scaffoldFiles {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return fmt.Errorf("failed to render %s: %w", name, err)
+		}
+		content := buf.Bytes()
+		if strings.HasSuffix(name, ".go") {
+			formatted, err := format.Source(content)
+			if err != nil {
+				return fmt.Errorf("failed to render %s: %w", name, err)
+			}
+			content = formatted
+		}
+		files[name] = content
+	}
+
+	// Only the parent may already exist, so an existing plugin is never overwritten
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+	if err := os.Mkdir(dir, 0755); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s already exists; choose another name or directory", dir)
+		}
+		return fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			os.RemoveAll(dir)
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// typeName turns a plugin name such as cost-report into a Go type name
+// such as CostReportPlugin
+func typeName(name string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(name, "-") {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	b.WriteString("Plugin")
+	return b.String()
+}
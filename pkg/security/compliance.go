@@ -0,0 +1,421 @@
+package security
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/cloud"
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// builtinPacks holds the compliance packs shipped with AlloraCLI
+//
+//go:embed compliance/*.yaml
+var builtinPacks embed.FS
+
+// compliancePackDir is the directory under the config dir searched for user packs
+const compliancePackDir = "compliance"
+
+// maxEvidenceResources is how many non-compliant resource IDs are listed in evidence
+const maxEvidenceResources = 5
+
+// CompliancePack is a loadable set of compliance controls for one standard
+type CompliancePack struct {
+	Standard string                  `yaml:"standard"`
+	Title    string                  `yaml:"title"`
+	Provider string                  `yaml:"provider"`
+	Aliases  []string                `yaml:"aliases"`
+	Controls []CompliancePackControl `yaml:"controls"`
+}
+
+// CompliancePackControl defines a control evaluated against every resource
+// of ResourceType; When optionally narrows the resources it applies to.
+// Failed controls with low severity are reported as warnings
+type CompliancePackControl struct {
+	ID           string `yaml:"id"`
+	Title        string `yaml:"title"`
+	Description  string `yaml:"description"`
+	ResourceType string `yaml:"resource_type"`
+	When         string `yaml:"when"`
+	Check        string `yaml:"check"`
+	Severity     string `yaml:"severity"`
+	Remediation  string `yaml:"remediation"`
+}
+
+// parseCompliancePack parses and validates a compliance pack
+func parseCompliancePack(data []byte, source string) (*CompliancePack, error) {
+	var pack CompliancePack
+	if err := yaml.Unmarshal(data, &pack); err != nil {
+		return nil, fmt.Errorf("failed to parse compliance pack %s: %w", source, err)
+	}
+	if pack.Standard == "" || pack.Provider == "" {
+		return nil, fmt.Errorf("compliance pack %s must set standard and provider", source)
+	}
+
+	for _, control := range pack.Controls {
+		if control.ID == "" || control.ResourceType == "" {
+			return nil, fmt.Errorf("compliance pack %s: every control needs an id and resource_type", source)
+		}
+		if _, err := parseCheckExpression(control.Check); err != nil {
+			return nil, fmt.Errorf("compliance pack %s: control %s: %w", source, control.ID, err)
+		}
+		if control.When != "" {
+			if _, err := parseCheckExpression(control.When); err != nil {
+				return nil, fmt.Errorf("compliance pack %s: control %s: %w", source, control.ID, err)
+			}
+		}
+	}
+	return &pack, nil
+}
+
+// Matches reports whether the pack is known by the given name
+func (p *CompliancePack) Matches(name string) bool {
+	name = strings.ToLower(name)
+	if strings.ToLower(p.Standard) == name {
+		return true
+	}
+	for _, alias := range p.Aliases {
+		if strings.ToLower(alias) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// loadCompliancePacks returns the user packs from the config dir followed by the built-in packs
+func loadCompliancePacks() ([]*CompliancePack, error) {
+	var packs []*CompliancePack
+
+	if configDir, err := config.GetConfigDir(); err == nil {
+		paths, _ := filepath.Glob(filepath.Join(configDir, compliancePackDir, "*.yaml"))
+		more, _ := filepath.Glob(filepath.Join(configDir, compliancePackDir, "*.yml"))
+		for _, path := range append(paths, more...) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read compliance pack: %w", err)
+			}
+			pack, err := parseCompliancePack(data, path)
+			if err != nil {
+				return nil, err
+			}
+			packs = append(packs, pack)
+		}
+	}
+
+	entries, err := builtinPacks.ReadDir(compliancePackDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read built-in compliance packs: %w", err)
+	}
+	for _, entry := range entries {
+		data, err := builtinPacks.ReadFile(compliancePackDir + "/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read built-in compliance pack: %w", err)
+		}
+		pack, err := parseCompliancePack(data, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, pack)
+	}
+
+	return packs, nil
+}
+
+// LoadCompliancePack finds the pack for a standard name or alias; a path to a
+// YAML file is loaded directly. User packs take precedence over built-in ones
+func LoadCompliancePack(standard string) (*CompliancePack, error) {
+	if ext := filepath.Ext(standard); ext == ".yaml" || ext == ".yml" {
+		data, err := os.ReadFile(standard)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read compliance pack: %w", err)
+		}
+		return parseCompliancePack(data, standard)
+	}
+
+	packs, err := loadCompliancePacks()
+	if err != nil {
+		return nil, err
+	}
+
+	var available []string
+	for _, pack := range packs {
+		if pack.Matches(standard) {
+			return pack, nil
+		}
+		available = append(available, pack.Standard)
+	}
+	sort.Strings(available)
+	return nil, fmt.Errorf("unknown compliance standard: %s (available: %s)", standard, strings.Join(available, ", "))
+}
+
+// checkExpression is a parsed `<field> <op> <value>` compliance check
+type checkExpression struct {
+	Field    string
+	Operator string
+	Value    string
+}
+
+// Check expressions compare a resource field with a literal, or test for its presence
+var (
+	checkComparePattern  = regexp.MustCompile(`^([A-Za-z0-9_.\-]+)\s+(==|!=|>=|<=|>|<|contains|not_contains)\s+(.+)$`)
+	checkPresencePattern = regexp.MustCompile(`^([A-Za-z0-9_.\-]+)\s+(exists|not_exists)$`)
+)
+
+// parseCheckExpression parses a check such as "config.encrypted == true"
+func parseCheckExpression(expr string) (*checkExpression, error) {
+	expr = strings.TrimSpace(expr)
+	if matches := checkPresencePattern.FindStringSubmatch(expr); matches != nil {
+		return &checkExpression{Field: matches[1], Operator: matches[2]}, nil
+	}
+	if matches := checkComparePattern.FindStringSubmatch(expr); matches != nil {
+		value := strings.TrimSpace(matches[3])
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		return &checkExpression{Field: matches[1], Operator: matches[2], Value: value}, nil
+	}
+	return nil, fmt.Errorf("invalid check %q: expected \"<field> <op> <value>\" or \"<field> exists\"", expr)
+}
+
+// Evaluate reports whether the resource satisfies the expression
+func (e *checkExpression) Evaluate(resource *cloud.Resource) bool {
+	actual, found := resourceField(resource, e.Field)
+
+	switch e.Operator {
+	case "exists":
+		return found
+	case "not_exists":
+		return !found
+	case "contains":
+		return found && containsValue(actual, e.Value)
+	case "not_contains":
+		return !found || !containsValue(actual, e.Value)
+	}
+
+	if !found {
+		return false
+	}
+
+	// Compare numerically when both sides are numbers
+	if a, err := strconv.ParseFloat(fmt.Sprint(actual), 64); err == nil {
+		if b, err := strconv.ParseFloat(e.Value, 64); err == nil {
+			switch e.Operator {
+			case "==":
+				return a == b
+			case "!=":
+				return a != b
+			case ">":
+				return a > b
+			case ">=":
+				return a >= b
+			case "<":
+				return a < b
+			case "<=":
+				return a <= b
+			}
+		}
+	}
+
+	a := fmt.Sprint(actual)
+	switch e.Operator {
+	case "==":
+		return strings.EqualFold(a, e.Value)
+	case "!=":
+		return !strings.EqualFold(a, e.Value)
+	case ">":
+		return a > e.Value
+	case ">=":
+		return a >= e.Value
+	case "<":
+		return a < e.Value
+	case "<=":
+		return a <= e.Value
+	}
+	return false
+}
+
+// resourceField resolves name, id, type, state, status, region, or
+// config./tags./metadata. prefixed fields on a resource
+func resourceField(resource *cloud.Resource, field string) (interface{}, bool) {
+	if prefix, key, ok := strings.Cut(field, "."); ok {
+		switch prefix {
+		case "config":
+			value, found := resource.Config[key]
+			return value, found
+		case "tags":
+			value, found := resource.Tags[key]
+			return value, found
+		case "metadata":
+			value, found := resource.Metadata[key]
+			return value, found
+		}
+		return nil, false
+	}
+
+	switch field {
+	case "id":
+		return resource.ID, true
+	case "name":
+		return resource.Name, true
+	case "type":
+		return resource.Type, true
+	case "state":
+		return resource.State, true
+	case "status":
+		return resource.Status, true
+	case "region":
+		return resource.Region, true
+	}
+	return nil, false
+}
+
+// containsValue reports whether a string or list value contains want
+func containsValue(actual interface{}, want string) bool {
+	value := reflect.ValueOf(actual)
+	if value.Kind() == reflect.Slice || value.Kind() == reflect.Array {
+		for i := 0; i < value.Len(); i++ {
+			if fmt.Sprint(value.Index(i).Interface()) == want {
+				return true
+			}
+		}
+		return false
+	}
+	return strings.Contains(fmt.Sprint(actual), want)
+}
+
+// CheckCompliance evaluates the compliance pack for standard against the
+// resources of its cloud provider
+func (s *DefaultSecurityService) CheckCompliance(ctx context.Context, standard string) (*ComplianceResult, error) {
+	pack, err := LoadCompliancePack(standard)
+	if err != nil {
+		return nil, err
+	}
+
+	newProvider := s.newProvider
+	if newProvider == nil {
+		newProvider = func(name string) (cloud.CloudProvider, error) {
+			return cloud.NewProvider(s.config, name)
+		}
+	}
+	provider, err := newProvider(pack.Provider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check %s compliance: %w", pack.Standard, err)
+	}
+
+	// List each resource type once, however many controls use it
+	type listing struct {
+		resources []*cloud.Resource
+		err       error
+	}
+	listings := map[string]*listing{}
+
+	result := &ComplianceResult{
+		ID:        fmt.Sprintf("compliance-%d", time.Now().Unix()),
+		Standard:  pack.Standard,
+		Timestamp: time.Now(),
+		Status:    "completed",
+		Controls:  []ComplianceControl{},
+	}
+
+	for _, control := range pack.Controls {
+		l, ok := listings[control.ResourceType]
+		if !ok {
+			resources, err := provider.ListResources(ctx, control.ResourceType)
+			l = &listing{resources: resources, err: err}
+			listings[control.ResourceType] = l
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("compliance check cancelled: %w", ctx.Err())
+		}
+
+		evaluated := evaluateControl(control, l.resources, l.err)
+		switch evaluated.Status {
+		case "passed":
+			result.Summary.PassedControls++
+		case "failed":
+			result.Summary.FailedControls++
+		default:
+			result.Summary.WarningControls++
+		}
+		result.Controls = append(result.Controls, evaluated)
+	}
+
+	result.Summary.TotalControls = len(result.Controls)
+	if result.Summary.TotalControls > 0 {
+		// Score is the percentage of controls that passed
+		result.Score = float64(result.Summary.PassedControls) / float64(result.Summary.TotalControls) * 100
+	}
+
+	return result, nil
+}
+
+// evaluateControl checks a control against the resources it applies to
+func evaluateControl(control CompliancePackControl, resources []*cloud.Resource, listErr error) ComplianceControl {
+	evaluated := ComplianceControl{
+		ID:          control.ID,
+		Title:       control.Title,
+		Description: control.Description,
+		Remediation: control.Remediation,
+	}
+
+	if listErr != nil {
+		evaluated.Status = "warning"
+		evaluated.Evidence = fmt.Sprintf("unable to list %s: %v", control.ResourceType, listErr)
+		return evaluated
+	}
+
+	// Expressions were validated when the pack was loaded
+	check, _ := parseCheckExpression(control.Check)
+	var when *checkExpression
+	if control.When != "" {
+		when, _ = parseCheckExpression(control.When)
+	}
+
+	applicable := 0
+	var failing []string
+	for _, resource := range resources {
+		if when != nil && !when.Evaluate(resource) {
+			continue
+		}
+		applicable++
+		if !check.Evaluate(resource) {
+			failing = append(failing, resource.ID)
+		}
+	}
+
+	switch {
+	case applicable == 0:
+		evaluated.Status = "passed"
+		evaluated.Evidence = fmt.Sprintf("no applicable %s found", control.ResourceType)
+		evaluated.Remediation = ""
+	case len(failing) == 0:
+		evaluated.Status = "passed"
+		evaluated.Evidence = fmt.Sprintf("%d of %d %s compliant", applicable, applicable, control.ResourceType)
+		evaluated.Remediation = ""
+	default:
+		evaluated.Status = "failed"
+		if strings.EqualFold(control.Severity, "low") {
+			evaluated.Status = "warning"
+		}
+		listed := failing
+		if len(listed) > maxEvidenceResources {
+			listed = listed[:maxEvidenceResources]
+		}
+		evaluated.Evidence = fmt.Sprintf("%d of %d %s non-compliant: %s", len(failing), applicable, control.ResourceType, strings.Join(listed, ", "))
+		if len(failing) > len(listed) {
+			evaluated.Evidence += fmt.Sprintf(" and %d more", len(failing)-len(listed))
+		}
+	}
+
+	return evaluated
+}
@@ -0,0 +1,54 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+)
+
+// configKeyStoreFile is the key store in the config directory that holds
+// the key sensitive configuration values are encrypted with
+const configKeyStoreFile = "keystore.json"
+
+func init() {
+	config.RegisterSecretCodec(newConfigSecretCodec)
+}
+
+// newConfigSecretCodec creates the security manager config.Save and
+// config.Load use to encrypt and decrypt sensitive values
+func newConfigSecretCodec() (config.SecretCodec, error) {
+	return newConfigSecurityManager(0)
+}
+
+// StartConfigKeyRotation rotates the keys configuration secrets are
+// encrypted with in the background once they are older than
+// security.rotation_period days, until ctx is cancelled. It does nothing
+// when no period is set
+func StartConfigKeyRotation(ctx context.Context, cfg config.SecurityConfig) error {
+	if cfg.RotationPeriod <= 0 {
+		return nil
+	}
+	manager, err := newConfigSecurityManager(cfg.RotationPeriod)
+	if err != nil {
+		return err
+	}
+	manager.Start(ctx)
+	return nil
+}
+
+// newConfigSecurityManager creates a security manager for the config key
+// store, rotating keys after rotationPeriod days once started
+func newConfigSecurityManager(rotationPeriod int) (*SecurityManager, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config directory: %w", err)
+	}
+
+	return NewSecurityManager(&SecurityConfig{
+		Encryption:     true,
+		KeyStorePath:   filepath.Join(configDir, configKeyStoreFile),
+		RotationPeriod: rotationPeriod,
+	})
+}
@@ -0,0 +1,282 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/notify"
+	"github.com/sirupsen/logrus"
+)
+
+// AuditLogFileName is the audit log events are recorded in under the config
+// dir unless security.audit_log_path is set
+const AuditLogFileName = "audit.log"
+
+// syntheticEventInterval is how often the synthetic source emits an event
+const syntheticEventInterval = 5 * time.Second
+
+// EventSource produces security events, such as those of a SIEM or syslog
+type EventSource interface {
+	// Name identifies the source in logs and errors
+	Name() string
+	// Events streams the source's events until ctx is cancelled, then
+	// closes the channel
+	Events(ctx context.Context) (<-chan SecurityEvent, error)
+}
+
+// SyntheticSource emits a demo login event at a fixed interval, so event
+// monitoring can be tried before a real source is connected
+type SyntheticSource struct {
+	Interval time.Duration
+}
+
+// NewSyntheticSource creates a synthetic source emitting an event every interval
+func NewSyntheticSource(interval time.Duration) *SyntheticSource {
+	return &SyntheticSource{Interval: interval}
+}
+
+// Name implements EventSource
+func (s *SyntheticSource) Name() string {
+	return "synthetic"
+}
+
+// Events implements EventSource
+func (s *SyntheticSource) Events(ctx context.Context) (<-chan SecurityEvent, error) {
+	if s.Interval <= 0 {
+		return nil, fmt.Errorf("synthetic event interval must be positive, got %s", s.Interval)
+	}
+	logrus.Warn("Security events come from the synthetic source and are demo data")
+
+	events := make(chan SecurityEvent, 100)
+	go func() {
+		defer close(events)
+		ticker := time.NewTicker(s.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				event := SecurityEvent{
+					ID:          fmt.Sprintf("event-%d", now.UnixNano()),
+					Type:        "login_attempt",
+					Timestamp:   now,
+					Source:      "auth-service",
+					Severity:    "info",
+					Description: "User login attempt",
+					Details: map[string]string{
+						"user": "admin",
+						"ip":   "192.168.1.100",
+					},
+					Actions: []string{"logged"},
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// SetEventSources replaces the sources MonitorSecurityEvents reads from
+func (s *DefaultSecurityService) SetEventSources(sources ...EventSource) {
+	s.sources = sources
+}
+
+// mergeEvents forwards the events of every source into one channel, which
+// is closed once all sources have closed theirs
+func mergeEvents(ctx context.Context, sources []EventSource) (<-chan SecurityEvent, error) {
+	streams := make([]<-chan SecurityEvent, 0, len(sources))
+	for _, source := range sources {
+		stream, err := source.Events(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start event source %s: %w", source.Name(), err)
+		}
+		streams = append(streams, stream)
+	}
+
+	merged := make(chan SecurityEvent, 100)
+	var wg sync.WaitGroup
+	for _, stream := range streams {
+		wg.Add(1)
+		go func(stream <-chan SecurityEvent) {
+			defer wg.Done()
+			for event := range stream {
+				select {
+				case merged <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(stream)
+	}
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+	return merged, nil
+}
+
+// eventSeverities lists the severities of security events, most severe first
+var eventSeverities = []string{"critical", "high", "medium", "low", "info"}
+
+// MonitorOptions configures WatchSecurityEvents
+type MonitorOptions struct {
+	// MinSeverity drops less severe events; empty keeps every event
+	MinSeverity string
+	// Auditor records each kept event; nil records nothing
+	Auditor *AuditLogger
+	// Notify forwards kept events of high severity or above to the
+	// configured notification sinks
+	Notify bool
+	// OnEvent is called with each kept event once it has been handled
+	OnEvent func(event SecurityEvent, summary *MonitorSummary)
+}
+
+// MonitorSummary totals the events a monitor has handled
+type MonitorSummary struct {
+	Started  time.Time `json:"started" yaml:"started"`
+	Received int       `json:"received" yaml:"received"`
+	// Filtered events were below the minimum severity
+	Filtered      int            `json:"filtered" yaml:"filtered"`
+	Recorded      int            `json:"recorded" yaml:"recorded"`
+	AuditFailures int            `json:"audit_failures" yaml:"audit_failures"`
+	Notified      int            `json:"notified" yaml:"notified"`
+	BySeverity    map[string]int `json:"by_severity" yaml:"by_severity"`
+}
+
+// Summary implements output.Summarizer
+func (m *MonitorSummary) Summary() string {
+	// Known severities come most severe first, followed by any others
+	var counts []string
+	for _, severity := range eventSeverities {
+		if n := m.BySeverity[severity]; n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", n, severity))
+		}
+	}
+	var others []string
+	for severity := range m.BySeverity {
+		if !slices.Contains(eventSeverities, severity) {
+			others = append(others, severity)
+		}
+	}
+	sort.Strings(others)
+	for _, severity := range others {
+		counts = append(counts, fmt.Sprintf("%d %s", m.BySeverity[severity], severity))
+	}
+
+	line := fmt.Sprintf("%d events in %s: %d recorded, %d filtered, %d notified",
+		m.Received, time.Since(m.Started).Round(time.Second), m.Recorded, m.Filtered, m.Notified)
+	if m.AuditFailures > 0 {
+		line += fmt.Sprintf(", %d failed to record", m.AuditFailures)
+	}
+	if len(counts) > 0 {
+		line += " (" + strings.Join(counts, ", ") + ")"
+	}
+	return line
+}
+
+// WatchSecurityEvents handles the events of MonitorSecurityEvents until ctx
+// is cancelled: events below the minimum severity are dropped, and the
+// others are recorded in the audit log and optionally forwarded to the
+// notification sinks. Notifications are sent in the background, and those
+// still queued when ctx is cancelled are delivered before it returns
+func (s *DefaultSecurityService) WatchSecurityEvents(ctx context.Context, options MonitorOptions) (*MonitorSummary, error) {
+	events, err := s.MonitorSecurityEvents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Deliver in the background so slow sinks do not hold up the events
+	notifier := s.notifier
+	if options.Notify && notifier != nil {
+		queue := notify.NewQueue(notifier, notify.DefaultQueueSize)
+		defer queue.Close()
+		notifier = queue
+	}
+
+	summary := &MonitorSummary{Started: time.Now(), BySeverity: make(map[string]int)}
+	for event := range events {
+		summary.Received++
+		if options.MinSeverity != "" && !notify.SeverityAtLeast(event.Severity, options.MinSeverity) {
+			summary.Filtered++
+			continue
+		}
+		summary.BySeverity[strings.ToLower(event.Severity)]++
+
+		if options.Auditor != nil {
+			if err := options.Auditor.LogEvent(auditEvent(event)); err != nil {
+				summary.AuditFailures++
+				logrus.Warnf("Failed to record security event %s: %v", event.ID, err)
+			} else {
+				summary.Recorded++
+			}
+		}
+		if options.Notify && notifyEvent(ctx, notifier, event) {
+			summary.Notified++
+		}
+		if options.OnEvent != nil {
+			options.OnEvent(event, summary)
+		}
+	}
+	return summary, nil
+}
+
+// auditEvent converts a security event to the audit log's form
+func auditEvent(event SecurityEvent) *AuditEvent {
+	details := make(map[string]interface{}, len(event.Details)+1)
+	for key, value := range event.Details {
+		details[key] = value
+	}
+	details["description"] = event.Description
+
+	return &AuditEvent{
+		ID:        event.ID,
+		Timestamp: event.Timestamp,
+		EventType: "security_event." + event.Type,
+		User:      event.Details["user"],
+		Resource:  event.Source,
+		Action:    strings.Join(event.Actions, ","),
+		Result:    "observed",
+		Details:   details,
+		IPAddress: event.Details["ip"],
+		Severity:  event.Severity,
+	}
+}
+
+// NewEventAuditLogger creates an audit logger appending to path that records
+// every event, whether or not audit logging is on, without echoing the
+// events to the log since the monitor shows them itself
+func NewEventAuditLogger(path string) (*AuditLogger, error) {
+	auditor, err := NewAuditLogger(&SecurityConfig{AuditLogging: true, AuditLogPath: path})
+	if err != nil {
+		return nil, err
+	}
+	auditor.logger.SetLevel(logrus.WarnLevel)
+	return auditor, nil
+}
+
+// Close closes the audit log file
+func (al *AuditLogger) Close() error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	if al.file == nil {
+		return nil
+	}
+	err := al.file.Close()
+	al.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close audit log: %w", err)
+	}
+	return nil
+}
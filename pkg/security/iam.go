@@ -0,0 +1,675 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/cloud"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/smithy-go"
+)
+
+// iamAPI is the subset of the IAM client used to audit permissions
+type iamAPI interface {
+	GetAccountAuthorizationDetails(ctx context.Context, params *iam.GetAccountAuthorizationDetailsInput, optFns ...func(*iam.Options)) (*iam.GetAccountAuthorizationDetailsOutput, error)
+	ListAttachedUserPolicies(ctx context.Context, params *iam.ListAttachedUserPoliciesInput, optFns ...func(*iam.Options)) (*iam.ListAttachedUserPoliciesOutput, error)
+	ListUserPolicies(ctx context.Context, params *iam.ListUserPoliciesInput, optFns ...func(*iam.Options)) (*iam.ListUserPoliciesOutput, error)
+	GetUserPolicy(ctx context.Context, params *iam.GetUserPolicyInput, optFns ...func(*iam.Options)) (*iam.GetUserPolicyOutput, error)
+	ListGroupsForUser(ctx context.Context, params *iam.ListGroupsForUserInput, optFns ...func(*iam.Options)) (*iam.ListGroupsForUserOutput, error)
+	ListAttachedRolePolicies(ctx context.Context, params *iam.ListAttachedRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListAttachedRolePoliciesOutput, error)
+	ListRolePolicies(ctx context.Context, params *iam.ListRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListRolePoliciesOutput, error)
+	GetRolePolicy(ctx context.Context, params *iam.GetRolePolicyInput, optFns ...func(*iam.Options)) (*iam.GetRolePolicyOutput, error)
+	ListAttachedGroupPolicies(ctx context.Context, params *iam.ListAttachedGroupPoliciesInput, optFns ...func(*iam.Options)) (*iam.ListAttachedGroupPoliciesOutput, error)
+	ListGroupPolicies(ctx context.Context, params *iam.ListGroupPoliciesInput, optFns ...func(*iam.Options)) (*iam.ListGroupPoliciesOutput, error)
+	GetGroupPolicy(ctx context.Context, params *iam.GetGroupPolicyInput, optFns ...func(*iam.Options)) (*iam.GetGroupPolicyOutput, error)
+	GetPolicy(ctx context.Context, params *iam.GetPolicyInput, optFns ...func(*iam.Options)) (*iam.GetPolicyOutput, error)
+	GetPolicyVersion(ctx context.Context, params *iam.GetPolicyVersionInput, optFns ...func(*iam.Options)) (*iam.GetPolicyVersionOutput, error)
+}
+
+// newAWSIAMClient creates an IAM client from the AWS provider configuration
+func (s *DefaultSecurityService) newAWSIAMClient(ctx context.Context) (iamAPI, error) {
+	awsCfg, err := cloud.LoadAWSConfig(ctx, cloud.ProviderConfigFor(s.config, "aws"))
+	if err != nil {
+		return nil, err
+	}
+	return iam.NewFromConfig(awsCfg), nil
+}
+
+// iamTarget is what a permission audit looks at: an IAM user, role, group
+// or managed policy, or any other resource whose grants are searched for
+type iamTarget struct {
+	// Kind is user, role, group, policy or resource
+	Kind string
+	// Name is the name of a user, role or group
+	Name string
+	// ARN is set for policies and resources
+	ARN string
+}
+
+// parseIAMTarget parses an ARN, or user/NAME, role/NAME or group/NAME
+func parseIAMTarget(target string) (iamTarget, error) {
+	target = strings.TrimSpace(target)
+	for _, kind := range []string{"user", "role", "group"} {
+		if name, ok := strings.CutPrefix(target, kind+"/"); ok && name != "" {
+			return iamTarget{Kind: kind, Name: name}, nil
+		}
+	}
+
+	parts := strings.SplitN(target, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] == "" || parts[5] == "" {
+		return iamTarget{}, fmt.Errorf("cannot audit %q; give an AWS ARN, such as arn:aws:iam::123456789012:role/deploy, or user/NAME, role/NAME or group/NAME", target)
+	}
+	service, resource := parts[2], parts[5]
+
+	kind, path, _ := strings.Cut(resource, "/")
+	segments := strings.Split(path, "/")
+	switch {
+	case service == "iam" && (kind == "user" || kind == "role" || kind == "group") && path != "":
+		return iamTarget{Kind: kind, Name: segments[len(segments)-1]}, nil
+	case service == "iam" && kind == "policy" && path != "":
+		return iamTarget{Kind: "policy", ARN: target}, nil
+	case service == "sts" && kind == "assumed-role" && path != "":
+		// arn:aws:sts::ACCOUNT:assumed-role/ROLE/SESSION
+		return iamTarget{Kind: "role", Name: segments[0]}, nil
+	}
+	return iamTarget{Kind: "resource", ARN: target}, nil
+}
+
+// iamPolicy is a policy that applies to an audited principal
+type iamPolicy struct {
+	Name string
+	// Principal is who the policy grants permissions to
+	Principal string
+	Document  policyDocument
+}
+
+// policyDocument is an IAM policy document
+type policyDocument struct {
+	Version   string           `json:"Version"`
+	Statement policyStatements `json:"Statement"`
+}
+
+// policyStatement is a statement of an IAM policy document
+type policyStatement struct {
+	Sid         string                           `json:"Sid"`
+	Effect      string                           `json:"Effect"`
+	Action      stringList                       `json:"Action"`
+	NotAction   stringList                       `json:"NotAction"`
+	Resource    stringList                       `json:"Resource"`
+	NotResource stringList                       `json:"NotResource"`
+	Condition   map[string]map[string]stringList `json:"Condition"`
+}
+
+// policyStatements accepts a single statement as well as a list
+type policyStatements []policyStatement
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *policyStatements) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		var statement policyStatement
+		if err := json.Unmarshal(data, &statement); err != nil {
+			return err
+		}
+		*s = policyStatements{statement}
+		return nil
+	}
+	var statements []policyStatement
+	if err := json.Unmarshal(data, &statements); err != nil {
+		return err
+	}
+	*s = statements
+	return nil
+}
+
+// stringList accepts a single string as well as a list, as policy
+// documents do for actions, resources and condition values
+type stringList []string
+
+// UnmarshalJSON implements json.Unmarshaler
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		*l = stringList{value}
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*l = values
+	return nil
+}
+
+// parsePolicyDocument parses a policy document as IAM returns it, URL-encoded
+func parsePolicyDocument(raw string) (policyDocument, error) {
+	if !strings.HasPrefix(strings.TrimSpace(raw), "{") {
+		decoded, err := url.PathUnescape(raw)
+		if err != nil {
+			return policyDocument{}, fmt.Errorf("failed to decode policy document: %w", err)
+		}
+		raw = decoded
+	}
+	var doc policyDocument
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		return policyDocument{}, fmt.Errorf("failed to parse policy document: %w", err)
+	}
+	return doc, nil
+}
+
+// iamCollector fetches the policies of audit targets, fetching each
+// managed policy once
+type iamCollector struct {
+	client  iamAPI
+	managed map[string]policyDocument
+}
+
+// collect returns the policies that apply to target
+func (c *iamCollector) collect(ctx context.Context, target iamTarget) ([]iamPolicy, error) {
+	switch target.Kind {
+	case "user":
+		return c.userPolicies(ctx, target.Name)
+	case "role":
+		return c.rolePolicies(ctx, target.Name)
+	case "group":
+		return c.groupPolicies(ctx, target.Name, "group/"+target.Name)
+	case "policy":
+		doc, err := c.managedPolicy(ctx, target.ARN)
+		if err != nil {
+			return nil, err
+		}
+		return []iamPolicy{{Name: policyNameFromARN(target.ARN), Principal: target.ARN, Document: doc}}, nil
+	default:
+		return c.accountPolicies(ctx)
+	}
+}
+
+// userPolicies returns the policies of a user, including those of its groups
+func (c *iamCollector) userPolicies(ctx context.Context, name string) ([]iamPolicy, error) {
+	principal := "user/" + name
+	var policies []iamPolicy
+
+	attached := iam.NewListAttachedUserPoliciesPaginator(c.client, &iam.ListAttachedUserPoliciesInput{UserName: aws.String(name)})
+	for attached.HasMorePages() {
+		page, err := attached.NextPage(ctx)
+		if err != nil {
+			return nil, iamError(err, principal)
+		}
+		if policies, err = c.appendManaged(ctx, policies, page.AttachedPolicies, principal); err != nil {
+			return nil, err
+		}
+	}
+
+	inline := iam.NewListUserPoliciesPaginator(c.client, &iam.ListUserPoliciesInput{UserName: aws.String(name)})
+	for inline.HasMorePages() {
+		page, err := inline.NextPage(ctx)
+		if err != nil {
+			return nil, iamError(err, principal)
+		}
+		for _, policyName := range page.PolicyNames {
+			out, err := c.client.GetUserPolicy(ctx, &iam.GetUserPolicyInput{UserName: aws.String(name), PolicyName: aws.String(policyName)})
+			if err != nil {
+				return nil, iamError(err, principal)
+			}
+			if policies, err = appendInline(policies, policyName, aws.ToString(out.PolicyDocument), principal); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	groups := iam.NewListGroupsForUserPaginator(c.client, &iam.ListGroupsForUserInput{UserName: aws.String(name)})
+	for groups.HasMorePages() {
+		page, err := groups.NextPage(ctx)
+		if err != nil {
+			return nil, iamError(err, principal)
+		}
+		for _, group := range page.Groups {
+			groupPolicies, err := c.groupPolicies(ctx, aws.ToString(group.GroupName), principal+" via group/"+aws.ToString(group.GroupName))
+			if err != nil {
+				return nil, err
+			}
+			policies = append(policies, groupPolicies...)
+		}
+	}
+	return policies, nil
+}
+
+// rolePolicies returns the policies attached to and embedded in a role
+func (c *iamCollector) rolePolicies(ctx context.Context, name string) ([]iamPolicy, error) {
+	principal := "role/" + name
+	var policies []iamPolicy
+
+	attached := iam.NewListAttachedRolePoliciesPaginator(c.client, &iam.ListAttachedRolePoliciesInput{RoleName: aws.String(name)})
+	for attached.HasMorePages() {
+		page, err := attached.NextPage(ctx)
+		if err != nil {
+			return nil, iamError(err, principal)
+		}
+		if policies, err = c.appendManaged(ctx, policies, page.AttachedPolicies, principal); err != nil {
+			return nil, err
+		}
+	}
+
+	inline := iam.NewListRolePoliciesPaginator(c.client, &iam.ListRolePoliciesInput{RoleName: aws.String(name)})
+	for inline.HasMorePages() {
+		page, err := inline.NextPage(ctx)
+		if err != nil {
+			return nil, iamError(err, principal)
+		}
+		for _, policyName := range page.PolicyNames {
+			out, err := c.client.GetRolePolicy(ctx, &iam.GetRolePolicyInput{RoleName: aws.String(name), PolicyName: aws.String(policyName)})
+			if err != nil {
+				return nil, iamError(err, principal)
+			}
+			if policies, err = appendInline(policies, policyName, aws.ToString(out.PolicyDocument), principal); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return policies, nil
+}
+
+// groupPolicies returns the policies of a group, granted to principal
+func (c *iamCollector) groupPolicies(ctx context.Context, name, principal string) ([]iamPolicy, error) {
+	var policies []iamPolicy
+
+	attached := iam.NewListAttachedGroupPoliciesPaginator(c.client, &iam.ListAttachedGroupPoliciesInput{GroupName: aws.String(name)})
+	for attached.HasMorePages() {
+		page, err := attached.NextPage(ctx)
+		if err != nil {
+			return nil, iamError(err, "group/"+name)
+		}
+		if policies, err = c.appendManaged(ctx, policies, page.AttachedPolicies, principal); err != nil {
+			return nil, err
+		}
+	}
+
+	inline := iam.NewListGroupPoliciesPaginator(c.client, &iam.ListGroupPoliciesInput{GroupName: aws.String(name)})
+	for inline.HasMorePages() {
+		page, err := inline.NextPage(ctx)
+		if err != nil {
+			return nil, iamError(err, "group/"+name)
+		}
+		for _, policyName := range page.PolicyNames {
+			out, err := c.client.GetGroupPolicy(ctx, &iam.GetGroupPolicyInput{GroupName: aws.String(name), PolicyName: aws.String(policyName)})
+			if err != nil {
+				return nil, iamError(err, "group/"+name)
+			}
+			if policies, err = appendInline(policies, policyName, aws.ToString(out.PolicyDocument), principal); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return policies, nil
+}
+
+// accountPolicies returns the policies of every user, role and group in
+// the account, for finding who is granted access to a resource
+func (c *iamCollector) accountPolicies(ctx context.Context) ([]iamPolicy, error) {
+	var policies []iamPolicy
+	details := iam.NewGetAccountAuthorizationDetailsPaginator(c.client, &iam.GetAccountAuthorizationDetailsInput{
+		Filter: []iamtypes.EntityType{iamtypes.EntityTypeUser, iamtypes.EntityTypeRole, iamtypes.EntityTypeGroup},
+	})
+	for details.HasMorePages() {
+		page, err := details.NextPage(ctx)
+		if err != nil {
+			return nil, iamError(err, "the account")
+		}
+
+		for _, user := range page.UserDetailList {
+			if policies, err = c.appendPrincipal(ctx, policies, "user/"+aws.ToString(user.UserName), user.AttachedManagedPolicies, user.UserPolicyList); err != nil {
+				return nil, err
+			}
+		}
+		for _, role := range page.RoleDetailList {
+			if policies, err = c.appendPrincipal(ctx, policies, "role/"+aws.ToString(role.RoleName), role.AttachedManagedPolicies, role.RolePolicyList); err != nil {
+				return nil, err
+			}
+		}
+		for _, group := range page.GroupDetailList {
+			if policies, err = c.appendPrincipal(ctx, policies, "group/"+aws.ToString(group.GroupName), group.AttachedManagedPolicies, group.GroupPolicyList); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return policies, nil
+}
+
+// appendPrincipal appends the managed and inline policies of a principal
+// listed in the account authorization details
+func (c *iamCollector) appendPrincipal(ctx context.Context, policies []iamPolicy, principal string, attached []iamtypes.AttachedPolicy, inline []iamtypes.PolicyDetail) ([]iamPolicy, error) {
+	policies, err := c.appendManaged(ctx, policies, attached, principal)
+	if err != nil {
+		return nil, err
+	}
+	for _, policy := range inline {
+		if policies, err = appendInline(policies, aws.ToString(policy.PolicyName), aws.ToString(policy.PolicyDocument), principal); err != nil {
+			return nil, err
+		}
+	}
+	return policies, nil
+}
+
+// appendManaged appends the documents of attached managed policies
+func (c *iamCollector) appendManaged(ctx context.Context, policies []iamPolicy, attached []iamtypes.AttachedPolicy, principal string) ([]iamPolicy, error) {
+	for _, policy := range attached {
+		doc, err := c.managedPolicy(ctx, aws.ToString(policy.PolicyArn))
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, iamPolicy{Name: aws.ToString(policy.PolicyName), Principal: principal, Document: doc})
+	}
+	return policies, nil
+}
+
+// appendInline appends an inline policy document
+func appendInline(policies []iamPolicy, name, document, principal string) ([]iamPolicy, error) {
+	doc, err := parsePolicyDocument(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inline policy %s of %s: %w", name, principal, err)
+	}
+	return append(policies, iamPolicy{Name: name, Principal: principal, Document: doc}), nil
+}
+
+// managedPolicy returns the default version of a managed policy
+func (c *iamCollector) managedPolicy(ctx context.Context, arn string) (policyDocument, error) {
+	if doc, ok := c.managed[arn]; ok {
+		return doc, nil
+	}
+
+	policy, err := c.client.GetPolicy(ctx, &iam.GetPolicyInput{PolicyArn: aws.String(arn)})
+	if err != nil {
+		return policyDocument{}, iamError(err, arn)
+	}
+	version, err := c.client.GetPolicyVersion(ctx, &iam.GetPolicyVersionInput{
+		PolicyArn: aws.String(arn),
+		VersionId: policy.Policy.DefaultVersionId,
+	})
+	if err != nil {
+		return policyDocument{}, iamError(err, arn)
+	}
+	doc, err := parsePolicyDocument(aws.ToString(version.PolicyVersion.Document))
+	if err != nil {
+		return policyDocument{}, fmt.Errorf("failed to read policy %s: %w", arn, err)
+	}
+	c.managed[arn] = doc
+	return doc, nil
+}
+
+// policyNameFromARN returns the name at the end of a policy ARN
+func policyNameFromARN(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
+
+// iamError turns common IAM failures into actionable errors
+func iamError(err error, subject string) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "AccessDenied", "AccessDeniedException", "UnauthorizedOperation":
+			return fmt.Errorf("access to IAM denied while reading %s; the credentials need IAM read permissions such as the IAMReadOnlyAccess managed policy: %w", subject, err)
+		case "NoSuchEntity":
+			return fmt.Errorf("%s was not found in IAM: %w", subject, err)
+		}
+	}
+	return fmt.Errorf("failed to read IAM policies of %s: %w", subject, err)
+}
+
+// sensitiveServices are the services whose full access amounts to control
+// over the account's identities, keys or secrets
+var sensitiveServices = []string{"iam", "sts", "organizations", "kms", "secretsmanager"}
+
+// privilegeEscalationActions let a principal grant itself more permissions
+var privilegeEscalationActions = []string{
+	"iam:PassRole",
+	"iam:CreatePolicyVersion",
+	"iam:SetDefaultPolicyVersion",
+	"iam:AttachUserPolicy",
+	"iam:AttachRolePolicy",
+	"iam:AttachGroupPolicy",
+	"iam:PutUserPolicy",
+	"iam:PutRolePolicy",
+	"iam:PutGroupPolicy",
+	"iam:CreateAccessKey",
+	"iam:CreateLoginProfile",
+	"iam:UpdateLoginProfile",
+	"iam:UpdateAssumeRolePolicy",
+	"iam:AddUserToGroup",
+	"sts:AssumeRole",
+}
+
+// auditPolicies expands policies into permissions and flags the
+// over-permissive grants among them. A resource target keeps only the
+// statements whose resources match it
+func auditPolicies(resource string, target iamTarget, policies []iamPolicy) *AuditResult {
+	result := &AuditResult{
+		ID:          fmt.Sprintf("audit-%d", time.Now().Unix()),
+		Resource:    resource,
+		Timestamp:   time.Now(),
+		Permissions: []Permission{},
+		Issues:      []AuditIssue{},
+	}
+
+	for _, policy := range policies {
+		for _, statement := range policy.Document.Statement {
+			if target.Kind == "resource" && !statementCovers(statement, target.ARN) {
+				continue
+			}
+			permission := statementPermission(policy, statement)
+			result.Permissions = append(result.Permissions, permission)
+			result.Issues = append(result.Issues, statementIssues(policy, statement, permission)...)
+		}
+	}
+
+	sort.SliceStable(result.Issues, func(i, j int) bool {
+		return severityRank(result.Issues[i].Severity) > severityRank(result.Issues[j].Severity)
+	})
+	result.Summary.TotalPermissions = len(result.Permissions)
+	for _, issue := range result.Issues {
+		switch issue.Severity {
+		case "critical":
+			result.Summary.CriticalIssues++
+		case "high":
+			result.Summary.HighIssues++
+		case "medium":
+			result.Summary.MediumIssues++
+		default:
+			result.Summary.LowIssues++
+		}
+	}
+	return result
+}
+
+// statementPermission describes what a statement grants or denies
+func statementPermission(policy iamPolicy, statement policyStatement) Permission {
+	permission := Permission{
+		Principal: policy.Principal,
+		Policy:    policy.Name,
+		Effect:    strings.ToLower(statement.Effect),
+		Actions:   statement.Action,
+		Resource:  strings.Join(statement.Resource, ","),
+	}
+	if len(statement.NotAction) > 0 {
+		permission.Actions = make([]string, len(statement.NotAction))
+		for i, action := range statement.NotAction {
+			permission.Actions[i] = "not " + action
+		}
+	}
+	if len(statement.NotResource) > 0 {
+		permission.Resource = "not " + strings.Join(statement.NotResource, ",")
+	}
+	if len(statement.Condition) > 0 {
+		permission.Conditions = make(map[string]string)
+		for operator, keys := range statement.Condition {
+			for key, values := range keys {
+				permission.Conditions[operator+":"+key] = strings.Join(values, ",")
+			}
+		}
+	}
+	return permission
+}
+
+// statementIssues flags over-permissive grants of an allow statement:
+// administrator access, wildcard actions, NotAction and NotResource grants
+// and privilege escalation. Conditions lower the severity one step
+func statementIssues(policy iamPolicy, statement policyStatement, permission Permission) []AuditIssue {
+	if !strings.EqualFold(statement.Effect, "Allow") {
+		return nil
+	}
+
+	var issues []AuditIssue
+	add := func(issueType, severity, description, recommendation string) {
+		if len(statement.Condition) > 0 {
+			severity = lowerSeverity(severity)
+			description += " (limited by conditions)"
+		}
+		issues = append(issues, AuditIssue{
+			Type:           issueType,
+			Severity:       severity,
+			Description:    description,
+			Resource:       permission.Resource,
+			Principal:      policy.Principal,
+			Recommendation: recommendation,
+		})
+	}
+
+	allResources := slices.Contains(statement.Resource, "*") || len(statement.NotResource) > 0
+	if len(statement.NotAction) > 0 {
+		add("not_action_allow", "high",
+			fmt.Sprintf("Policy %s allows every action except %s", policy.Name, strings.Join(statement.NotAction, ", ")),
+			"Allow the specific actions needed instead of using NotAction with Allow")
+	}
+	if len(statement.NotResource) > 0 {
+		add("not_resource_allow", "medium",
+			fmt.Sprintf("Policy %s allows %s on every resource except %s", policy.Name, strings.Join(permission.Actions, ", "), strings.Join(statement.NotResource, ", ")),
+			"List the resources access is needed to instead of using NotResource with Allow")
+	}
+
+	var serviceWildcards, escalations []string
+	for _, action := range statement.Action {
+		if action == "*" || action == "*:*" {
+			if allResources {
+				add("administrator_access", "critical",
+					fmt.Sprintf("Policy %s allows every action on every resource", policy.Name),
+					"Replace administrator access with the actions and resources this principal needs")
+			} else {
+				add("wildcard_actions", "high",
+					fmt.Sprintf("Policy %s allows every action on %s", policy.Name, permission.Resource),
+					"List the actions needed on these resources instead of *")
+			}
+			return issues
+		}
+		if service, rest, ok := strings.Cut(action, ":"); ok && rest == "*" {
+			serviceWildcards = append(serviceWildcards, strings.ToLower(service))
+		}
+	}
+	for _, service := range serviceWildcards {
+		severity := "low"
+		switch {
+		case slices.Contains(sensitiveServices, service) && allResources:
+			severity = "high"
+		case slices.Contains(sensitiveServices, service) || allResources:
+			severity = "medium"
+		}
+		add("service_wildcard", severity,
+			fmt.Sprintf("Policy %s allows every %s action on %s", policy.Name, service, permission.Resource),
+			fmt.Sprintf("List the %s actions needed instead of %s:*", service, service))
+	}
+
+	for _, sensitive := range privilegeEscalationActions {
+		service, _, _ := strings.Cut(sensitive, ":")
+		if slices.Contains(serviceWildcards, service) {
+			continue
+		}
+		for _, action := range statement.Action {
+			if actionMatches(action, sensitive) {
+				escalations = append(escalations, sensitive)
+				break
+			}
+		}
+	}
+	if len(escalations) > 0 {
+		severity := "medium"
+		if allResources {
+			severity = "high"
+		}
+		add("privilege_escalation", severity,
+			fmt.Sprintf("Policy %s allows %s, which can be used to gain more permissions", policy.Name, strings.Join(escalations, ", ")),
+			"Restrict these actions to the specific roles, users and policies they are needed for")
+	}
+
+	if allResources && len(issues) == 0 && len(statement.NotResource) == 0 {
+		add("wildcard_resource", "low",
+			fmt.Sprintf("Policy %s allows %s on every resource", policy.Name, strings.Join(statement.Action, ", ")),
+			"Scope the statement to the resources it is needed for")
+	}
+	return issues
+}
+
+// statementCovers reports whether a statement applies to the resource arn
+func statementCovers(statement policyStatement, arn string) bool {
+	if len(statement.NotResource) > 0 {
+		for _, pattern := range statement.NotResource {
+			if globMatch(pattern, arn, false) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, pattern := range statement.Resource {
+		if globMatch(pattern, arn, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// actionMatches reports whether a policy action, which may hold wildcards,
+// covers action. Actions are case-insensitive
+func actionMatches(pattern, action string) bool {
+	return globMatch(pattern, action, true)
+}
+
+// globMatch matches value against an IAM pattern, where * matches any run
+// of characters and ? any single character
+func globMatch(pattern, value string, foldCase bool) bool {
+	expr := "^" + strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(pattern)) + "$"
+	if foldCase {
+		expr = "(?i)" + expr
+	}
+	matched, err := regexp.MatchString(expr, value)
+	return err == nil && matched
+}
+
+// auditSeverities lists the severities of audit issues, least severe first
+var auditSeverities = []string{"low", "medium", "high", "critical"}
+
+// severityRank orders audit issue severities, least severe lowest
+func severityRank(severity string) int {
+	for i, s := range auditSeverities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// lowerSeverity returns the severity one step below severity, down to low
+func lowerSeverity(severity string) string {
+	if rank := severityRank(severity); rank > 0 {
+		return auditSeverities[rank-1]
+	}
+	return severity
+}
@@ -0,0 +1,260 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// keyStoreFormat identifies the versioned key store layout; stores written
+// before key rotation are a flat name -> base64 key map
+const keyStoreFormat = 2
+
+// rotationCheckInterval is how often the security manager looks for keys due for rotation
+var rotationCheckInterval = time.Hour
+
+// ciphertextMagic prefixes ciphertext tagged with the key version that sealed it
+var ciphertextMagic = []byte{'A', 'K', 'V', 1}
+
+// ciphertextHeaderSize is the length of the magic plus the big-endian key version
+var ciphertextHeaderSize = len(ciphertextMagic) + 4
+
+// keyVersion is one generation of a named key
+type keyVersion struct {
+	Version   int       `json:"version"`
+	Key       []byte    `json:"key"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// keyRing holds every version of a named key; Active is used for encryption
+// while older versions remain available for decryption
+type keyRing struct {
+	Active   int           `json:"active"`
+	Versions []*keyVersion `json:"versions"`
+}
+
+// active returns the version used for new encryptions
+func (r *keyRing) active() *keyVersion {
+	return r.version(r.Active)
+}
+
+// version returns the given key version, or nil if it does not exist
+func (r *keyRing) version(version int) *keyVersion {
+	for _, v := range r.Versions {
+		if v.Version == version {
+			return v
+		}
+	}
+	return nil
+}
+
+// keyStoreFile is the on-disk layout of the key store; when KDF is set the
+// stored keys are wrapped with a passphrase-derived key encryption key
+type keyStoreFile struct {
+	Format int                 `json:"format"`
+	KDF    *keyDerivation      `json:"kdf,omitempty"`
+	Keys   map[string]*keyRing `json:"keys"`
+}
+
+// decodeKeyStore parses a key store, reporting whether it used the legacy flat layout
+func decodeKeyStore(data []byte) (*keyStoreFile, bool, error) {
+	var store keyStoreFile
+	if err := json.Unmarshal(data, &store); err == nil && store.Format == keyStoreFormat {
+		if store.Keys == nil {
+			store.Keys = make(map[string]*keyRing)
+		}
+		return &store, false, nil
+	}
+
+	var legacy map[string]string
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal key data: %w", err)
+	}
+
+	keys := make(map[string]*keyRing, len(legacy))
+	for name, encodedKey := range legacy {
+		key, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to decode key %s: %w", name, err)
+		}
+		keys[name] = &keyRing{
+			Active:   1,
+			Versions: []*keyVersion{{Version: 1, Key: key, CreatedAt: time.Now()}},
+		}
+	}
+	return &keyStoreFile{Format: keyStoreFormat, Keys: keys}, true, nil
+}
+
+// newKeyVersion generates a random 256-bit key with the given version
+func newKeyVersion(version int) (*keyVersion, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return &keyVersion{
+		Version:   version,
+		Key:       key,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// addKeyVersion generates a new version of name and makes it active;
+// callers must hold the write lock
+func (km *KeyManager) addKeyVersion(name string) (*keyVersion, error) {
+	ring, exists := km.keys[name]
+	if !exists {
+		ring = &keyRing{}
+	}
+
+	next := 1
+	for _, v := range ring.Versions {
+		if v.Version >= next {
+			next = v.Version + 1
+		}
+	}
+
+	version, err := newKeyVersion(next)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := ring.Active
+	ring.Versions = append(ring.Versions, version)
+	ring.Active = version.Version
+	km.keys[name] = ring
+
+	if err := km.saveKeys(); err != nil {
+		ring.Versions = ring.Versions[:len(ring.Versions)-1]
+		ring.Active = previous
+		if len(ring.Versions) == 0 {
+			delete(km.keys, name)
+		}
+		return nil, fmt.Errorf("failed to save key: %w", err)
+	}
+
+	return version, nil
+}
+
+// RotateKey generates a new version of an existing key and makes it the
+// active encryption key; previous versions remain available for decryption
+func (km *KeyManager) RotateKey(name string) error {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	if _, exists := km.keys[name]; !exists {
+		return fmt.Errorf("key not found: %s", name)
+	}
+
+	version, err := km.addKeyVersion(name)
+	if err != nil {
+		return err
+	}
+
+	km.logger.Infof("Rotated key %s to version %d", name, version.Version)
+	return nil
+}
+
+// ActiveKey returns the active version of a key and its value
+func (km *KeyManager) ActiveKey(name string) (int, []byte, error) {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	ring, exists := km.keys[name]
+	if !exists || ring.active() == nil {
+		return 0, nil, fmt.Errorf("key not found: %s", name)
+	}
+
+	active := ring.active()
+	return active.Version, active.Key, nil
+}
+
+// GetKeyVersion retrieves a specific version of a key
+func (km *KeyManager) GetKeyVersion(name string, version int) ([]byte, error) {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	ring, exists := km.keys[name]
+	if !exists {
+		return nil, fmt.Errorf("key not found: %s", name)
+	}
+
+	v := ring.version(version)
+	if v == nil {
+		return nil, fmt.Errorf("key %s has no version %d", name, version)
+	}
+	return v.Key, nil
+}
+
+// keysDueForRotation lists keys whose active version is older than period
+func (km *KeyManager) keysDueForRotation(period time.Duration, now time.Time) []string {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	var due []string
+	for name, ring := range km.keys {
+		active := ring.active()
+		if active == nil || now.Sub(active.CreatedAt) >= period {
+			due = append(due, name)
+		}
+	}
+	sort.Strings(due)
+	return due
+}
+
+// RotateExpiredKeys rotates every key whose active version is older than period
+func (km *KeyManager) RotateExpiredKeys(period time.Duration) error {
+	for _, name := range km.keysDueForRotation(period, time.Now()) {
+		if err := km.RotateKey(name); err != nil {
+			return fmt.Errorf("failed to rotate key %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Start runs background key rotation driven by RotationPeriod (in days)
+// until ctx is cancelled; it does nothing when no period is configured
+func (sm *SecurityManager) Start(ctx context.Context) {
+	if sm.config.RotationPeriod <= 0 {
+		return
+	}
+	period := time.Duration(sm.config.RotationPeriod) * 24 * time.Hour
+
+	go func() {
+		ticker := time.NewTicker(rotationCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			if err := sm.keyManager.RotateExpiredKeys(period); err != nil {
+				sm.logger.Errorf("Key rotation failed: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// ciphertextHeader builds the prefix tagging ciphertext with a key version
+func ciphertextHeader(version int) []byte {
+	header := make([]byte, ciphertextHeaderSize)
+	copy(header, ciphertextMagic)
+	binary.BigEndian.PutUint32(header[len(ciphertextMagic):], uint32(version))
+	return header
+}
+
+// parseCiphertextHeader extracts the key version from tagged ciphertext
+func parseCiphertextHeader(data []byte) (int, bool) {
+	if len(data) < ciphertextHeaderSize || !bytes.HasPrefix(data, ciphertextMagic) {
+		return 0, false
+	}
+	return int(binary.BigEndian.Uint32(data[len(ciphertextMagic):ciphertextHeaderSize])), true
+}
@@ -0,0 +1,177 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// KeyStorePassphraseEnv names the environment variable holding the passphrase
+// the key store is encrypted with
+const KeyStorePassphraseEnv = "ALLORA_KEYSTORE_PASSPHRASE"
+
+// scrypt parameters for deriving the key encryption key (KEK)
+const (
+	kdfAlgorithm = "scrypt"
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	kekSize      = 32
+	kekSaltSize  = 16
+)
+
+// keyDerivation records how the KEK wrapping the stored keys was derived
+type keyDerivation struct {
+	Algorithm string `json:"algorithm"`
+	Salt      []byte `json:"salt"`
+	N         int    `json:"n"`
+	R         int    `json:"r"`
+	P         int    `json:"p"`
+}
+
+// newKeyDerivation creates scrypt parameters with a fresh random salt
+func newKeyDerivation() (*keyDerivation, error) {
+	salt := make([]byte, kekSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate key store salt: %w", err)
+	}
+	return &keyDerivation{
+		Algorithm: kdfAlgorithm,
+		Salt:      salt,
+		N:         scryptN,
+		R:         scryptR,
+		P:         scryptP,
+	}, nil
+}
+
+// deriveKEK derives the key encryption key from a passphrase
+func (d *keyDerivation) deriveKEK(passphrase string) ([]byte, error) {
+	if d.Algorithm != kdfAlgorithm {
+		return nil, fmt.Errorf("unsupported key store KDF: %s", d.Algorithm)
+	}
+	kek, err := scrypt.Key([]byte(passphrase), d.Salt, d.N, d.R, d.P, kekSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key store encryption key: %w", err)
+	}
+	return kek, nil
+}
+
+// newKEKCipher creates the AES-GCM cipher used to wrap keys
+func newKEKCipher(kek []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
+
+// wrappedKeyLabel binds a wrapped key to its name and version
+func wrappedKeyLabel(name string, version int) []byte {
+	return []byte(fmt.Sprintf("%s/%d", name, version))
+}
+
+// wrapKey encrypts a key with the KEK
+func wrapKey(kek []byte, name string, version int, key []byte) ([]byte, error) {
+	gcm, err := newKEKCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, key, wrappedKeyLabel(name, version)), nil
+}
+
+// unwrapKey decrypts a key wrapped with the KEK
+func unwrapKey(kek []byte, name string, version int, wrapped []byte) ([]byte, error) {
+	gcm, err := newKEKCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(wrapped) < nonceSize {
+		return nil, fmt.Errorf("wrapped key %s version %d is too short", name, version)
+	}
+
+	key, err := gcm.Open(nil, wrapped[:nonceSize], wrapped[nonceSize:], wrappedKeyLabel(name, version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unlock key store: wrong %s or corrupted key %s", KeyStorePassphraseEnv, name)
+	}
+	return key, nil
+}
+
+// enableKEK starts wrapping stored keys with a KEK derived from passphrase
+func (km *KeyManager) enableKEK(passphrase string) error {
+	kdf, err := newKeyDerivation()
+	if err != nil {
+		return err
+	}
+	kek, err := kdf.deriveKEK(passphrase)
+	if err != nil {
+		return err
+	}
+	km.kdf, km.kek = kdf, kek
+	return nil
+}
+
+// unlockKeys unwraps keys loaded from an encrypted store in place
+func (km *KeyManager) unlockKeys(kdf *keyDerivation, passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("key store %s is encrypted; set %s to unlock it", km.keyStore, KeyStorePassphraseEnv)
+	}
+
+	kek, err := kdf.deriveKEK(passphrase)
+	if err != nil {
+		return err
+	}
+
+	for name, ring := range km.keys {
+		for _, v := range ring.Versions {
+			key, err := unwrapKey(kek, name, v.Version, v.Key)
+			if err != nil {
+				return err
+			}
+			v.Key = key
+		}
+	}
+
+	km.kdf, km.kek = kdf, kek
+	return nil
+}
+
+// sealedKeys returns a copy of the keys as written to disk, wrapped with the
+// KEK when one is configured
+func (km *KeyManager) sealedKeys() (map[string]*keyRing, error) {
+	if km.kek == nil {
+		return km.keys, nil
+	}
+
+	sealed := make(map[string]*keyRing, len(km.keys))
+	for name, ring := range km.keys {
+		copied := &keyRing{Active: ring.Active, Versions: make([]*keyVersion, 0, len(ring.Versions))}
+		for _, v := range ring.Versions {
+			wrapped, err := wrapKey(km.kek, name, v.Version, v.Key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to wrap key %s: %w", name, err)
+			}
+			copied.Versions = append(copied.Versions, &keyVersion{
+				Version:   v.Version,
+				Key:       wrapped,
+				CreatedAt: v.CreatedAt,
+			})
+		}
+		sealed[name] = copied
+	}
+	return sealed, nil
+}
@@ -0,0 +1,421 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// policyAction describes an action policy rules can take
+type policyAction struct {
+	// permits and blocks mark actions that let requests through or stop
+	// them; rules matching the same requests must not do both
+	permits  bool
+	blocks   bool
+	required []string
+	optional []string
+}
+
+// policyActions lists the actions policy rules can take
+var policyActions = map[string]policyAction{
+	"allow":       {permits: true},
+	"deny":        {blocks: true, optional: []string{"reason"}},
+	"quarantine":  {blocks: true, required: []string{"duration"}, optional: []string{"reason"}},
+	"require_mfa": {optional: []string{"max_age"}},
+	"alert":       {required: []string{"severity"}, optional: []string{"message"}},
+	"notify":      {required: []string{"channel"}, optional: []string{"message"}},
+	"log":         {optional: []string{"level"}},
+}
+
+// notifyChannels lists the sinks a notify rule can send to
+var notifyChannels = []string{"slack", "webhook", "pagerduty", "email"}
+
+// policyParameterChecks validate the values of rule parameters by name
+var policyParameterChecks = map[string]func(value string) error{
+	"severity": func(value string) error {
+		if !slices.Contains(config.Severities, strings.ToLower(value)) {
+			return fmt.Errorf("unknown severity %q; use one of %s", value, strings.Join(config.Severities, ", "))
+		}
+		return nil
+	},
+	"channel": func(value string) error {
+		if !slices.Contains(notifyChannels, strings.ToLower(value)) {
+			return fmt.Errorf("unknown channel %q; use one of %s", value, strings.Join(notifyChannels, ", "))
+		}
+		return nil
+	},
+	"duration": positiveDuration,
+	"max_age":  positiveDuration,
+	"level": func(value string) error {
+		_, err := logrus.ParseLevel(value)
+		return err
+	},
+}
+
+// positiveDuration checks that value is a duration such as 30m
+func positiveDuration(value string) error {
+	if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+		return fmt.Errorf("%q is not a positive duration such as 30m", value)
+	}
+	return nil
+}
+
+// policyActionNames returns the known actions in order
+func policyActionNames() []string {
+	return slices.Sorted(maps.Keys(policyActions))
+}
+
+// ValidateSecurityPolicies checks the rules of each policy: that their
+// conditions parse, their actions are known and have the parameters they
+// need, and that no two enabled rules conflict or overlap
+func (s *DefaultSecurityService) ValidateSecurityPolicies(ctx context.Context, policies []Policy) (*ValidationResult, error) {
+	return validatePolicies(policies), nil
+}
+
+// ValidatePolicyFile loads the policies in a YAML or JSON file and validates
+// them. The file holds a policy, a list of policies, or a policies key with
+// the list; rules are enabled unless they set enabled: false
+func ValidatePolicyFile(path string) (*ValidationResult, error) {
+	policies, err := LoadPolicyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return validatePolicies(policies), nil
+}
+
+// policyFileRule is a rule as written in a policy file, where enabled
+// defaults to true
+type policyFileRule struct {
+	ID         string            `json:"id" yaml:"id"`
+	Condition  string            `json:"condition" yaml:"condition"`
+	Action     string            `json:"action" yaml:"action"`
+	Parameters map[string]string `json:"parameters" yaml:"parameters"`
+	Enabled    *bool             `json:"enabled" yaml:"enabled"`
+}
+
+// policyFilePolicy is a policy as written in a policy file
+type policyFilePolicy struct {
+	ID          string            `json:"id" yaml:"id"`
+	Name        string            `json:"name" yaml:"name"`
+	Description string            `json:"description" yaml:"description"`
+	Type        string            `json:"type" yaml:"type"`
+	Rules       []policyFileRule  `json:"rules" yaml:"rules"`
+	Metadata    map[string]string `json:"metadata" yaml:"metadata"`
+}
+
+// LoadPolicyFile reads the policies in a YAML or JSON file
+func LoadPolicyFile(path string) ([]Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	unmarshal := yaml.Unmarshal
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		unmarshal = json.Unmarshal
+	}
+
+	// Find out whether the file holds a list, a policies key or one policy
+	var shape interface{}
+	if err := unmarshal(data, &shape); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+	var list []policyFilePolicy
+	switch shape := shape.(type) {
+	case []interface{}:
+		err = unmarshal(data, &list)
+	case map[string]interface{}:
+		if _, ok := shape["policies"]; ok {
+			var file struct {
+				Policies []policyFilePolicy `json:"policies" yaml:"policies"`
+			}
+			err = unmarshal(data, &file)
+			list = file.Policies
+		} else {
+			var single policyFilePolicy
+			err = unmarshal(data, &single)
+			list = []policyFilePolicy{single}
+		}
+	default:
+		return nil, fmt.Errorf("policy file %s must hold a policy, a list of policies or a policies key", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse policy file %s: %w", path, err)
+	}
+
+	policies := make([]Policy, len(list))
+	for i, p := range list {
+		policies[i] = Policy{ID: p.ID, Name: p.Name, Description: p.Description, Type: p.Type, Metadata: p.Metadata}
+		for _, r := range p.Rules {
+			policies[i].Rules = append(policies[i].Rules, PolicyRule{
+				ID:         r.ID,
+				Condition:  r.Condition,
+				Action:     r.Action,
+				Parameters: r.Parameters,
+				Enabled:    r.Enabled == nil || *r.Enabled,
+			})
+		}
+	}
+	return policies, nil
+}
+
+// validatePolicies validates each policy and totals the results
+func validatePolicies(policies []Policy) *ValidationResult {
+	result := &ValidationResult{
+		ID:        fmt.Sprintf("validation-%d", time.Now().Unix()),
+		Timestamp: time.Now(),
+		Status:    "completed",
+		Policies:  []PolicyValidation{},
+	}
+	for _, policy := range policies {
+		validation := validatePolicy(policy)
+		result.Policies = append(result.Policies, validation)
+		if validation.Status == "valid" {
+			result.Summary.ValidPolicies++
+		} else {
+			result.Summary.InvalidPolicies++
+		}
+		result.Summary.TotalIssues += len(validation.Issues)
+		result.Summary.TotalWarnings += len(validation.Warnings)
+	}
+	result.Summary.TotalPolicies = len(policies)
+	return result
+}
+
+// checkedRule is a rule whose condition and action were understood, so it
+// can be compared with the other rules of its policy
+type checkedRule struct {
+	label   string
+	action  string
+	clauses []string
+	params  map[string]string
+}
+
+// validatePolicy validates the rules of a policy
+func validatePolicy(policy Policy) PolicyValidation {
+	v := &PolicyValidation{PolicyID: policy.ID, Issues: []ValidationIssue{}, Warnings: []ValidationWarning{}}
+
+	if policy.ID == "" {
+		v.issue("missing_policy_id", "medium", "", "Give the policy a unique id", "Policy %q has no id", policy.Name)
+	}
+	if len(policy.Rules) == 0 {
+		v.warn("empty_policy", "", "Add rules or remove the policy", "Policy has no rules, so it enforces nothing")
+	}
+
+	seen := map[string]bool{}
+	enabled := 0
+	var checked []checkedRule
+	for i, rule := range policy.Rules {
+		label := rule.ID
+		if label == "" {
+			label = fmt.Sprintf("rules[%d]", i)
+			v.issue("missing_rule_id", "low", label, "Give every rule a unique id", "Rule %d has no id", i+1)
+		} else if seen[rule.ID] {
+			v.issue("duplicate_rule_id", "medium", label, "Give every rule a unique id", "Rule id %s is used more than once", rule.ID)
+		}
+		seen[rule.ID] = true
+
+		ok := true
+		clauses, err := parseRuleCondition(rule.Condition)
+		switch {
+		case err != nil:
+			ok = false
+			v.issue("invalid_condition", "high", label, `Write conditions as "<field> <op> <value>" clauses joined by and`, "Rule %s has an invalid condition: %v", label, err)
+		case len(clauses) == 0:
+			v.warn("unconditional_rule", label, "Add a condition unless the rule should apply to everything", "Rule %s has no condition, so it applies to every request", label)
+		default:
+			if reason := unsatisfiable(clauses); reason != "" {
+				v.warn("unsatisfiable_condition", label, "Fix the condition or remove the rule", "Rule %s can never match: %s", label, reason)
+			}
+		}
+
+		action, known := policyActions[strings.ToLower(rule.Action)]
+		switch {
+		case rule.Action == "":
+			ok = false
+			v.issue("missing_action", "high", label, "Set the action the rule takes", "Rule %s has no action", label)
+		case !known:
+			ok = false
+			v.issue("unknown_action", "high", label, "Use one of "+strings.Join(policyActionNames(), ", "), "Rule %s has unknown action %q", label, rule.Action)
+		default:
+			for _, name := range action.required {
+				if strings.TrimSpace(rule.Parameters[name]) == "" {
+					v.issue("missing_parameter", "high", label, fmt.Sprintf("Set parameters.%s", name), "Rule %s needs the %s parameter for action %s", label, name, strings.ToLower(rule.Action))
+				}
+			}
+			for _, name := range slices.Sorted(maps.Keys(rule.Parameters)) {
+				if !slices.Contains(action.required, name) && !slices.Contains(action.optional, name) {
+					v.warn("unknown_parameter", label, "Remove it or check its spelling", "Rule %s sets parameter %s, which action %s does not use", label, name, strings.ToLower(rule.Action))
+					continue
+				}
+				if check := policyParameterChecks[name]; check != nil && rule.Parameters[name] != "" {
+					if err := check(rule.Parameters[name]); err != nil {
+						v.issue("invalid_parameter", "medium", label, fmt.Sprintf("Fix parameters.%s", name), "Rule %s has an invalid %s: %v", label, name, err)
+					}
+				}
+			}
+		}
+
+		if rule.Enabled {
+			enabled++
+			if ok {
+				checked = append(checked, checkedRule{label: label, action: strings.ToLower(rule.Action), clauses: clauses, params: rule.Parameters})
+			}
+		}
+	}
+	if len(policy.Rules) > 0 && enabled == 0 {
+		v.warn("no_enabled_rules", "", "Enable the rules that should be enforced", "Every rule of the policy is disabled")
+	}
+
+	for i, a := range checked {
+		for _, b := range checked[i+1:] {
+			v.compareRules(a, b)
+		}
+	}
+
+	v.Status = "valid"
+	if len(v.Issues) > 0 {
+		v.Status = "invalid"
+	}
+	return *v
+}
+
+// issue records a problem that makes the policy invalid
+func (v *PolicyValidation) issue(issueType, severity, ruleID, solution, format string, args ...interface{}) {
+	v.Issues = append(v.Issues, ValidationIssue{Type: issueType, Severity: severity, Description: fmt.Sprintf(format, args...), RuleID: ruleID, Solution: solution})
+}
+
+// warn records a likely mistake that leaves the policy valid
+func (v *PolicyValidation) warn(warningType, ruleID, suggestion, format string, args ...interface{}) {
+	v.Warnings = append(v.Warnings, ValidationWarning{Type: warningType, Description: fmt.Sprintf(format, args...), RuleID: ruleID, Suggestion: suggestion})
+}
+
+// compareRules reports two enabled rules that match the same requests with
+// opposing or identical actions
+func (v *PolicyValidation) compareRules(a, b checkedRule) {
+	aCovers := clausesSubset(a.clauses, b.clauses)
+	bCovers := clausesSubset(b.clauses, a.clauses)
+	if !aCovers && !bCovers {
+		return
+	}
+
+	actionA, actionB := policyActions[a.action], policyActions[b.action]
+	opposing := (actionA.permits && actionB.blocks) || (actionA.blocks && actionB.permits)
+	same := a.action == b.action && maps.Equal(a.params, b.params)
+
+	switch {
+	case aCovers && bCovers && opposing:
+		v.issue("conflicting_rules", "high", b.label, "Change one of the conditions or remove one of the rules",
+			"Rules %s and %s have the same condition but %s and %s", a.label, b.label, a.action, b.action)
+	case aCovers && bCovers && same:
+		v.warn("duplicate_rule", b.label, "Remove one of the rules", "Rules %s and %s are identical", a.label, b.label)
+	case opposing:
+		general, specific := a, b
+		if bCovers {
+			general, specific = b, a
+		}
+		v.warn("overlapping_rules", specific.label, "Make the conditions exclusive so the outcome does not depend on rule order",
+			"Rule %s (%s) matches everything rule %s (%s) matches", general.label, general.action, specific.label, specific.action)
+	case same:
+		general, specific := a, b
+		if bCovers {
+			general, specific = b, a
+		}
+		v.warn("redundant_rule", specific.label, "Remove the narrower rule",
+			"Rule %s is redundant; rule %s takes the same action on everything it matches", specific.label, general.label)
+	}
+}
+
+// clausesSubset reports whether every clause of a is in b, so a rule with
+// clauses a matches everything a rule with clauses b matches
+func clausesSubset(a, b []string) bool {
+	for _, clause := range a {
+		if !slices.Contains(b, clause) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseRuleCondition parses a condition of check expressions joined by and,
+// returning its clauses in a normal form. An empty condition has no clauses
+func parseRuleCondition(condition string) ([]string, error) {
+	if strings.TrimSpace(condition) == "" {
+		return nil, nil
+	}
+	var clauses []string
+	for _, part := range splitConjunction(condition) {
+		expr, err := parseCheckExpression(part)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, strings.TrimSpace(expr.Field+" "+expr.Operator+" "+expr.Value))
+	}
+	sort.Strings(clauses)
+	return slices.Compact(clauses), nil
+}
+
+// splitConjunction splits a condition at the and keywords outside of quotes
+func splitConjunction(condition string) []string {
+	var parts []string
+	var b strings.Builder
+	quoted := false
+	fields := strings.Split(condition, " ")
+	for _, field := range fields {
+		if !quoted && strings.EqualFold(field, "and") {
+			parts = append(parts, b.String())
+			b.Reset()
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(field)
+		quoted = quoted != (strings.Count(field, `"`)%2 == 1)
+	}
+	return append(parts, b.String())
+}
+
+// unsatisfiable explains why clauses can never all hold, such as a field
+// compared equal to two values, or returns ""
+func unsatisfiable(clauses []string) string {
+	equals := map[string]string{}
+	for _, clause := range clauses {
+		expr, err := parseCheckExpression(clause)
+		if err != nil || expr.Operator != "==" {
+			continue
+		}
+		if other, ok := equals[expr.Field]; ok && other != expr.Value {
+			return fmt.Sprintf("%s cannot equal both %s and %s", expr.Field, other, expr.Value)
+		}
+		equals[expr.Field] = expr.Value
+	}
+	for _, clause := range clauses {
+		expr, err := parseCheckExpression(clause)
+		if err != nil {
+			continue
+		}
+		switch expr.Operator {
+		case "!=":
+			if equals[expr.Field] == expr.Value {
+				return fmt.Sprintf("%s cannot both equal and differ from %s", expr.Field, expr.Value)
+			}
+		case "not_exists":
+			if _, ok := equals[expr.Field]; ok {
+				return fmt.Sprintf("%s cannot both be compared and be missing", expr.Field)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,330 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/open-policy-agent/opa/v1/ast"
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+// Rego rules whose results are reported. As with conftest, each message of
+// a deny rule is an issue and each message of a warn rule a warning
+const (
+	regoDenyRule = "deny"
+	regoWarnRule = "warn"
+)
+
+// RegoOptions configures EvaluateRegoPolicies
+type RegoOptions struct {
+	// Paths are .rego files, or directories searched for them; _test.rego
+	// files are skipped
+	Paths []string
+	// V0Compatible parses policies written for Rego before OPA 1.0, which
+	// do not use the if and contains keywords
+	V0Compatible bool
+}
+
+// regoPackage is a Rego package defining deny or warn rules
+type regoPackage struct {
+	id    string
+	rules []string
+}
+
+// EvaluateRegoPolicies evaluates each input, such as a cloud resource,
+// against the deny and warn rules of every Rego package in options.Paths.
+// Each package is reported as a policy; policies that do not parse or
+// compile are reported as compile_error issues with their location, and
+// nothing is evaluated
+func EvaluateRegoPolicies(ctx context.Context, options RegoOptions, inputs []interface{}) (*ValidationResult, error) {
+	files, err := findRegoFiles(options.Paths)
+	if err != nil {
+		return nil, err
+	}
+
+	version := ast.RegoV1
+	if options.V0Compatible {
+		version = ast.RegoV0
+	}
+
+	result := &ValidationResult{
+		ID:        fmt.Sprintf("validation-%d", time.Now().Unix()),
+		Timestamp: time.Now(),
+		Status:    "completed",
+		Policies:  []PolicyValidation{},
+	}
+
+	modules := make(map[string]*ast.Module, len(files))
+	var errs ast.Errors
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy file: %w", err)
+		}
+		module, err := ast.ParseModuleWithOpts(file, string(src), ast.ParserOptions{RegoVersion: version})
+		if err != nil {
+			errs = append(errs, regoErrors(file, err)...)
+			continue
+		}
+		modules[file] = module
+	}
+	if len(errs) == 0 {
+		compiler := ast.NewCompiler().WithDefaultRegoVersion(version)
+		if compiler.Compile(modules); compiler.Failed() {
+			errs = compiler.Errors
+		} else {
+			for _, pkg := range regoPackages(modules) {
+				validation, err := evaluateRegoPackage(ctx, compiler, pkg, inputs)
+				if err != nil {
+					return nil, err
+				}
+				result.Policies = append(result.Policies, validation)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		result.Policies = compileFailures(errs)
+	}
+
+	for _, validation := range result.Policies {
+		if validation.Status == "valid" {
+			result.Summary.ValidPolicies++
+		} else {
+			result.Summary.InvalidPolicies++
+		}
+		result.Summary.TotalIssues += len(validation.Issues)
+		result.Summary.TotalWarnings += len(validation.Warnings)
+	}
+	result.Summary.TotalPolicies = len(result.Policies)
+	return result, nil
+}
+
+// findRegoFiles returns the .rego files in paths, in order
+func findRegoFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy path: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && strings.HasSuffix(file, ".rego") && !strings.HasSuffix(file, "_test.rego") {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to search %s for policies: %w", path, err)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .rego policies found in %s", strings.Join(paths, ", "))
+	}
+	return slices.Compact(files), nil
+}
+
+// regoErrors returns the located errors of a parse, or err itself located
+// at file
+func regoErrors(file string, err error) ast.Errors {
+	var errs ast.Errors
+	if errors.As(err, &errs) {
+		return errs
+	}
+	return ast.Errors{ast.NewError(ast.ParseErr, &ast.Location{File: file}, "%s", err.Error())}
+}
+
+// compileFailures reports Rego errors as issues of the policy file they are in
+func compileFailures(errs ast.Errors) []PolicyValidation {
+	var validations []PolicyValidation
+	byFile := map[string]int{}
+	for _, e := range errs {
+		file, location := "", ""
+		if e.Location != nil {
+			file = e.Location.File
+			location = fmt.Sprintf("%s:%d:%d: ", e.Location.File, e.Location.Row, e.Location.Col)
+		}
+		i, ok := byFile[file]
+		if !ok {
+			i = len(validations)
+			byFile[file] = i
+			validations = append(validations, PolicyValidation{PolicyID: file, Status: "invalid", Issues: []ValidationIssue{}, Warnings: []ValidationWarning{}})
+		}
+		validations[i].Issues = append(validations[i].Issues, ValidationIssue{
+			Type:        "compile_error",
+			Severity:    "high",
+			Description: location + e.Message,
+			Solution:    "Fix the policy so that opa check accepts it",
+		})
+	}
+	return validations
+}
+
+// regoPackages returns the packages of modules that define deny or warn
+// rules, sorted by package
+func regoPackages(modules map[string]*ast.Module) []regoPackage {
+	rules := map[string][]string{}
+	for _, module := range modules {
+		id := strings.TrimPrefix(module.Package.Path.String(), "data.")
+		for _, rule := range module.Rules {
+			name := rule.Head.Ref().String()
+			if (name == regoDenyRule || name == regoWarnRule) && !slices.Contains(rules[id], name) {
+				rules[id] = append(rules[id], name)
+			}
+		}
+	}
+
+	packages := make([]regoPackage, 0, len(rules))
+	for id, names := range rules {
+		sort.Strings(names)
+		packages = append(packages, regoPackage{id: id, rules: names})
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].id < packages[j].id })
+	return packages
+}
+
+// evaluateRegoPackage evaluates each input against the rules of pkg
+func evaluateRegoPackage(ctx context.Context, compiler *ast.Compiler, pkg regoPackage, inputs []interface{}) (PolicyValidation, error) {
+	v := &PolicyValidation{PolicyID: pkg.id, Issues: []ValidationIssue{}, Warnings: []ValidationWarning{}}
+	for _, rule := range pkg.rules {
+		ref := "data." + pkg.id + "." + rule
+		query, err := rego.New(rego.Query(ref), rego.Compiler(compiler)).PrepareForEval(ctx)
+		if err != nil {
+			return PolicyValidation{}, fmt.Errorf("failed to prepare %s: %w", ref, err)
+		}
+
+		for i, input := range inputs {
+			label := regoInputLabel(input, i)
+			results, err := query.Eval(ctx, rego.EvalInput(input))
+			if err != nil {
+				if ctx.Err() != nil {
+					return PolicyValidation{}, ctx.Err()
+				}
+				v.issue("evaluation_error", "high", ref, "Fix the rule so it evaluates for every input", "%s failed for %s: %v", ref, label, err)
+				continue
+			}
+			for _, message := range regoMessages(results) {
+				if rule == regoDenyRule {
+					v.issue("deny", message.severity, ref, "Change the resource so the policy allows it", "%s: %s", label, message.text)
+				} else {
+					v.warn("warn", ref, "Review the resource", "%s: %s", label, message.text)
+				}
+			}
+		}
+	}
+
+	v.Status = "valid"
+	if len(v.Issues) > 0 {
+		v.Status = "invalid"
+	}
+	return *v, nil
+}
+
+// regoMessage is a message of a deny or warn rule
+type regoMessage struct {
+	text     string
+	severity string
+}
+
+// regoMessages returns the messages of a rule's results. Rules produce
+// strings, or objects with a msg and optionally a severity; deny messages
+// are high severity unless they give one
+func regoMessages(results rego.ResultSet) []regoMessage {
+	var messages []regoMessage
+	for _, result := range results {
+		for _, expr := range result.Expressions {
+			values, ok := expr.Value.([]interface{})
+			if !ok {
+				// A single-valued rule, such as deny := "reason"
+				values = []interface{}{expr.Value}
+			}
+			for _, value := range values {
+				message := regoMessage{severity: "high"}
+				switch value := value.(type) {
+				case string:
+					message.text = value
+				case bool:
+					if !value {
+						continue
+					}
+					message.text = "policy violated"
+				case map[string]interface{}:
+					message.text, _ = value["msg"].(string)
+					if severity, ok := value["severity"].(string); ok && slices.Contains(config.Severities, strings.ToLower(severity)) {
+						message.severity = strings.ToLower(severity)
+					}
+					if message.text == "" {
+						data, _ := json.Marshal(value)
+						message.text = string(data)
+					}
+				default:
+					data, _ := json.Marshal(value)
+					message.text = string(data)
+				}
+				messages = append(messages, message)
+			}
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].text < messages[j].text })
+	return messages
+}
+
+// regoInputLabel names an input in messages by its type and id or name,
+// falling back to its position
+func regoInputLabel(input interface{}, i int) string {
+	if resource, ok := input.(map[string]interface{}); ok {
+		name, _ := resource["id"].(string)
+		if name == "" {
+			name, _ = resource["name"].(string)
+		}
+		if kind, _ := resource["type"].(string); kind != "" && name != "" {
+			return kind + "/" + name
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return fmt.Sprintf("input[%d]", i)
+}
+
+// LoadRegoInputs reads the documents to evaluate from a JSON file holding
+// one document or a list of them, such as the output of
+// allora cloud resources -f json. A path of - reads standard input
+func LoadRegoInputs(path string) ([]interface{}, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse input %s: %w", path, err)
+	}
+	if list, ok := doc.([]interface{}); ok {
+		return list, nil
+	}
+	return []interface{}{doc}, nil
+}
@@ -0,0 +1,267 @@
+package security
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReportFormats lists the formats ExportReport can serialize a report to
+var ReportFormats = []string{"json", "sarif"}
+
+// SARIF 2.1.0 identifiers written into every log
+const (
+	sarifVersion   = "2.1.0"
+	sarifSchema    = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifToolName  = "allora"
+	sarifToolURI   = "https://github.com/AlloraAi/AlloraCLI"
+	sarifUnknownID = "unknown-vulnerability"
+)
+
+// sarifLog is the root of a SARIF 2.1.0 document
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+// sarifRun holds the results of a single tool invocation
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+// sarifTool describes the tool that produced a run
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+// sarifDriver describes the analysis tool and the rules it reports against
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+// sarifRule describes one vulnerability that results can refer to
+type sarifRule struct {
+	ID                   string                 `json:"id"`
+	Name                 string                 `json:"name,omitempty"`
+	ShortDescription     sarifMessage           `json:"shortDescription"`
+	FullDescription      *sarifMessage          `json:"fullDescription,omitempty"`
+	HelpURI              string                 `json:"helpUri,omitempty"`
+	Help                 *sarifMessage          `json:"help,omitempty"`
+	DefaultConfiguration sarifConfiguration     `json:"defaultConfiguration"`
+	Properties           map[string]interface{} `json:"properties,omitempty"`
+}
+
+// sarifConfiguration holds the default reporting level of a rule
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+// sarifMessage is a plain text SARIF message
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+// sarifResult is a single finding of a rule at a location
+type sarifResult struct {
+	RuleID     string            `json:"ruleId"`
+	RuleIndex  int               `json:"ruleIndex"`
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// sarifLocation points a result at the artifact it was found in
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+// sarifPhysicalLocation identifies a file or other artifact
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+// sarifArtifactLocation holds the URI of an artifact
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// ExportReport serializes a security report to w in the given format
+func ExportReport(report *SecurityReport, format string, w io.Writer) error {
+	if report == nil {
+		return fmt.Errorf("security report is required")
+	}
+
+	var doc interface{}
+	switch strings.ToLower(format) {
+	case "json":
+		doc = report
+	case "sarif":
+		doc = newSARIFLog(report)
+	default:
+		return fmt.Errorf("unsupported report format: %s (available: %s)", format, strings.Join(ReportFormats, ", "))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write %s report: %w", format, err)
+	}
+	return nil
+}
+
+// newSARIFLog converts the vulnerabilities in a report into a SARIF log with
+// one rule per vulnerability ID and one result per finding
+func newSARIFLog(report *SecurityReport) *sarifLog {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           sarifToolName,
+			InformationURI: sarifToolURI,
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	ruleIndex := make(map[string]int)
+	for _, scan := range report.ScanResults {
+		for _, vuln := range scan.Vulnerabilities {
+			id := vuln.ID
+			if id == "" {
+				id = sarifUnknownID
+			}
+
+			index, exists := ruleIndex[id]
+			if !exists {
+				index = len(run.Tool.Driver.Rules)
+				ruleIndex[id] = index
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, newSARIFRule(id, vuln))
+			}
+
+			run.Results = append(run.Results, newSARIFResult(id, index, scan, vuln))
+		}
+	}
+
+	return &sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{run},
+	}
+}
+
+// newSARIFRule describes a vulnerability as a SARIF rule
+func newSARIFRule(id string, vuln Vulnerability) sarifRule {
+	rule := sarifRule{
+		ID:                   id,
+		Name:                 vuln.CVE,
+		ShortDescription:     sarifMessage{Text: firstNonEmpty(vuln.Title, id)},
+		DefaultConfiguration: sarifConfiguration{Level: sarifLevel(vuln)},
+		Properties: map[string]interface{}{
+			"tags": []string{"security", "vulnerability"},
+		},
+	}
+	if vuln.Description != "" {
+		rule.FullDescription = &sarifMessage{Text: vuln.Description}
+	}
+	if vuln.Solution != "" {
+		rule.Help = &sarifMessage{Text: vuln.Solution}
+	}
+	if uri := firstNonEmpty(vuln.Metadata["primary_url"], firstReference(vuln.References)); uri != "" {
+		rule.HelpURI = uri
+	}
+	if score := securitySeverity(vuln); score > 0 {
+		rule.Properties["security-severity"] = fmt.Sprintf("%.1f", score)
+	}
+	return rule
+}
+
+// newSARIFResult reports a vulnerability found by a scan
+func newSARIFResult(id string, index int, scan ScanResult, vuln Vulnerability) sarifResult {
+	message := fmt.Sprintf("%s: %s", id, firstNonEmpty(vuln.Title, vuln.Description, id))
+	if vuln.Component != "" {
+		message = fmt.Sprintf("%s %s is affected by %s", vuln.Component, vuln.Version, message)
+	}
+
+	result := sarifResult{
+		RuleID:    id,
+		RuleIndex: index,
+		Level:     sarifLevel(vuln),
+		Message:   sarifMessage{Text: strings.Join(strings.Fields(message), " ")},
+	}
+	if uri := firstNonEmpty(vuln.Metadata["target"], scan.Target); uri != "" {
+		result.Locations = []sarifLocation{{
+			PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: uri}},
+		}}
+	}
+
+	properties := map[string]string{}
+	if vuln.Component != "" {
+		properties["component"] = vuln.Component
+	}
+	if vuln.Version != "" {
+		properties["installed_version"] = vuln.Version
+	}
+	if fixed := vuln.Metadata["fixed_version"]; fixed != "" {
+		properties["fixed_version"] = fixed
+	}
+	if len(properties) > 0 {
+		result.Properties = properties
+	}
+	return result
+}
+
+// sarifLevel maps a vulnerability's CVSS score, or its severity when no
+// score is known, onto a SARIF result level; low and informational findings
+// are reported as notes
+func sarifLevel(vuln Vulnerability) string {
+	switch score := securitySeverity(vuln); {
+	case score >= 7.0:
+		return "error"
+	case score >= 4.0:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// securitySeverity returns the CVSS score of a vulnerability, falling back to
+// the lowest score of its severity band when the scanner did not report one
+func securitySeverity(vuln Vulnerability) float64 {
+	if vuln.CVSS > 0 {
+		return vuln.CVSS
+	}
+	switch strings.ToLower(vuln.Severity) {
+	case "critical":
+		return 9.0
+	case "high":
+		return 7.0
+	case "medium":
+		return 4.0
+	case "low":
+		return 0.1
+	default:
+		return 0
+	}
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// firstReference returns the first reference URL, if any
+func firstReference(references []string) string {
+	if len(references) == 0 {
+		return ""
+	}
+	return references[0]
+}
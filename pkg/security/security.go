@@ -15,7 +15,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/AlloraAi/AlloraCLI/pkg/cloud"
 	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/notify"
+	"github.com/AlloraAi/AlloraCLI/pkg/utils"
 	"github.com/sirupsen/logrus"
 )
 
@@ -25,6 +28,8 @@ type SecurityService interface {
 	CheckCompliance(ctx context.Context, standard string) (*ComplianceResult, error)
 	AuditPermissions(ctx context.Context, resource string) (*AuditResult, error)
 	MonitorSecurityEvents(ctx context.Context) (<-chan SecurityEvent, error)
+	SetEventSources(sources ...EventSource)
+	WatchSecurityEvents(ctx context.Context, options MonitorOptions) (*MonitorSummary, error)
 	GenerateSecurityReport(ctx context.Context, options ReportOptions) (*SecurityReport, error)
 	ValidateSecurityPolicies(ctx context.Context, policies []Policy) (*ValidationResult, error)
 }
@@ -106,7 +111,9 @@ type AuditResult struct {
 
 // Permission represents a permission entry
 type Permission struct {
-	Principal  string            `json:"principal"`
+	Principal string `json:"principal"`
+	// Policy names the policy granting the permission
+	Policy     string            `json:"policy,omitempty"`
 	Actions    []string          `json:"actions"`
 	Resource   string            `json:"resource"`
 	Effect     string            `json:"effect"`
@@ -239,159 +246,126 @@ type ValidationSummary struct {
 // DefaultSecurityService provides a default implementation
 type DefaultSecurityService struct {
 	config *config.Config
+	// notifier receives high-severity security events; nil when no sink is configured
+	notifier notify.Notifier
+	// newProvider creates the cloud provider compliance checks run against;
+	// nil uses the providers configured in config
+	newProvider func(name string) (cloud.CloudProvider, error)
+	// sources produce the events MonitorSecurityEvents streams
+	sources []EventSource
+	// newIAMClient creates the IAM client permission audits read policies
+	// with; nil uses the configured AWS credentials
+	newIAMClient func(ctx context.Context) (iamAPI, error)
 }
 
 // NewSecurityService creates a new security service
 func NewSecurityService(cfg *config.Config) SecurityService {
-	return &DefaultSecurityService{
-		config: cfg,
+	service := &DefaultSecurityService{
+		config:  cfg,
+		sources: []EventSource{NewSyntheticSource(syntheticEventInterval)},
 	}
+	if cfg != nil {
+		service.notifier = notify.FromConfig(cfg.Notifications)
+	}
+	return service
 }
 
-// ScanVulnerabilities performs a vulnerability scan
+// ScanVulnerabilities scans an image or filesystem path with Trivy
 func (s *DefaultSecurityService) ScanVulnerabilities(ctx context.Context, target string) (*ScanResult, error) {
-	// Mock implementation - in real implementation, this would integrate with security scanners
-	return &ScanResult{
-		ID:        "scan-001",
-		Target:    target,
-		Timestamp: time.Now(),
-		Status:    "completed",
-		Summary: ScanSummary{
-			TotalChecks:    100,
-			CriticalIssues: 0,
-			HighIssues:     2,
-			MediumIssues:   5,
-			LowIssues:      10,
-			InfoIssues:     15,
-		},
-		Vulnerabilities: []Vulnerability{
-			{
-				ID:          "vuln-001",
-				Title:       "Outdated TLS Configuration",
-				Description: "TLS 1.0 and 1.1 are deprecated and should be disabled",
-				Severity:    "high",
-				CVSS:        7.5,
-				Component:   "web-server",
-				Version:     "1.0",
-				Solution:    "Upgrade to TLS 1.2 or higher",
-				References:  []string{"https://example.com/tls-security"},
-			},
-		},
-		Recommendations: []string{
-			"Update TLS configuration to use only TLS 1.2 and above",
-			"Implement security headers for web applications",
-			"Enable log monitoring for security events",
-		},
-	}, nil
-}
+	if target == "" {
+		target = "."
+	}
 
-// CheckCompliance performs compliance checks
-func (s *DefaultSecurityService) CheckCompliance(ctx context.Context, standard string) (*ComplianceResult, error) {
-	// Mock implementation
-	return &ComplianceResult{
-		ID:        "compliance-001",
-		Standard:  standard,
-		Timestamp: time.Now(),
-		Status:    "completed",
-		Score:     85.5,
-		Controls: []ComplianceControl{
-			{
-				ID:          "control-001",
-				Title:       "Access Control",
-				Description: "Ensure proper access controls are in place",
-				Status:      "passed",
-				Evidence:    "Access controls properly configured",
-				Remediation: "",
-			},
-		},
-		Summary: ComplianceSummary{
-			TotalControls:   20,
-			PassedControls:  17,
-			FailedControls:  2,
-			WarningControls: 1,
-		},
+	report, err := runTrivy(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	vulns := report.vulnerabilities()
+	summary := summarizeVulnerabilities(vulns)
+
+	return &ScanResult{
+		ID:              fmt.Sprintf("scan-%d", time.Now().Unix()),
+		Target:          target,
+		Timestamp:       time.Now(),
+		Status:          "completed",
+		Summary:         summary,
+		Vulnerabilities: vulns,
+		Recommendations: scanRecommendations(summary, vulns),
 	}, nil
 }
 
-// AuditPermissions performs permission audits
+// AuditPermissions audits the AWS IAM permissions of a principal, given as
+// an IAM ARN or user/NAME, role/NAME or group/NAME, or of who can access a
+// resource ARN. Statements of the applicable policies become permissions,
+// and over-permissive grants among them are flagged as issues
 func (s *DefaultSecurityService) AuditPermissions(ctx context.Context, resource string) (*AuditResult, error) {
-	// Mock implementation
-	return &AuditResult{
-		ID:        "audit-001",
-		Resource:  resource,
-		Timestamp: time.Now(),
-		Permissions: []Permission{
-			{
-				Principal: "user:admin",
-				Actions:   []string{"read", "write", "delete"},
-				Resource:  resource,
-				Effect:    "allow",
-			},
-		},
-		Issues: []AuditIssue{
-			{
-				Type:           "excessive_permissions",
-				Severity:       "medium",
-				Description:    "User has more permissions than required",
-				Resource:       resource,
-				Principal:      "user:admin",
-				Recommendation: "Review and reduce permissions to minimum required",
-			},
-		},
-		Summary: AuditSummary{
-			TotalPermissions: 10,
-			CriticalIssues:   0,
-			HighIssues:       0,
-			MediumIssues:     1,
-			LowIssues:        2,
-		},
-	}, nil
+	target, err := parseIAMTarget(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	newIAMClient := s.newIAMClient
+	if newIAMClient == nil {
+		newIAMClient = s.newAWSIAMClient
+	}
+	client, err := newIAMClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	collector := &iamCollector{client: client, managed: map[string]policyDocument{}}
+	policies, err := collector.collect(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	return auditPolicies(resource, target, policies), nil
 }
 
-// MonitorSecurityEvents monitors security events
+// notifyEventSeverity is the least severe security event sent to notification sinks
+const notifyEventSeverity = "high"
+
+// MonitorSecurityEvents streams the events of the service's sources until
+// ctx is cancelled
 func (s *DefaultSecurityService) MonitorSecurityEvents(ctx context.Context) (<-chan SecurityEvent, error) {
-	events := make(chan SecurityEvent, 100)
-
-	// Mock implementation - would integrate with SIEM systems
-	go func() {
-		defer close(events)
-		ticker := time.NewTicker(5 * time.Second)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				event := SecurityEvent{
-					ID:          fmt.Sprintf("event-%d", time.Now().Unix()),
-					Type:        "login_attempt",
-					Timestamp:   time.Now(),
-					Source:      "auth-service",
-					Severity:    "info",
-					Description: "User login attempt",
-					Details: map[string]string{
-						"user": "admin",
-						"ip":   "192.168.1.100",
-					},
-					Actions: []string{"logged"},
-				}
-				select {
-				case events <- event:
-				case <-ctx.Done():
-					return
-				}
-			}
-		}
-	}()
+	return mergeEvents(ctx, s.sources)
+}
+
+// notifyEvent sends high-severity security events to notifier, reporting
+// whether the event was sent
+func notifyEvent(ctx context.Context, notifier notify.Notifier, event SecurityEvent) bool {
+	if notifier == nil || !notify.SeverityAtLeast(event.Severity, notifyEventSeverity) {
+		return false
+	}
 
-	return events, nil
+	err := notifier.Notify(ctx, notify.Notification{
+		Name:      event.Type,
+		Source:    event.Source,
+		Severity:  event.Severity,
+		Message:   event.Description,
+		Timestamp: event.Timestamp,
+		Details:   event.Details,
+	})
+	if err != nil {
+		logrus.Warnf("Failed to send security event %s: %v", event.ID, err)
+		return false
+	}
+	return true
 }
 
 // GenerateSecurityReport generates a comprehensive security report
 func (s *DefaultSecurityService) GenerateSecurityReport(ctx context.Context, options ReportOptions) (*SecurityReport, error) {
+	scans := []ScanResult{}
+	for _, target := range options.Targets {
+		scan, err := s.ScanVulnerabilities(ctx, target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", target, err)
+		}
+		scans = append(scans, *scan)
+	}
+
 	// Mock implementation
-	return &SecurityReport{
+	report := &SecurityReport{
 		ID:        "report-001",
 		Timestamp: time.Now(),
 		Type:      options.Type,
@@ -406,7 +380,7 @@ func (s *DefaultSecurityService) GenerateSecurityReport(ctx context.Context, opt
 				"Review access permissions",
 			},
 		},
-		ScanResults:       []ScanResult{},
+		ScanResults:       scans,
 		ComplianceResults: []ComplianceResult{},
 		AuditResults:      []AuditResult{},
 		Recommendations: []string{
@@ -414,32 +388,72 @@ func (s *DefaultSecurityService) GenerateSecurityReport(ctx context.Context, opt
 			"Regular security training for staff",
 			"Establish incident response procedures",
 		},
-	}, nil
+	}
+
+	if len(scans) > 0 {
+		report.ExecutiveSummary.CriticalFindings = 0
+		report.ExecutiveSummary.HighPriorityFindings = 0
+		for _, scan := range scans {
+			report.ExecutiveSummary.CriticalFindings += scan.Summary.CriticalIssues
+			report.ExecutiveSummary.HighPriorityFindings += scan.Summary.HighIssues
+		}
+	}
+
+	return report, nil
 }
 
-// ValidateSecurityPolicies validates security policies
-func (s *DefaultSecurityService) ValidateSecurityPolicies(ctx context.Context, policies []Policy) (*ValidationResult, error) {
-	// Mock implementation
-	return &ValidationResult{
-		ID:        "validation-001",
-		Timestamp: time.Now(),
-		Status:    "completed",
-		Policies: []PolicyValidation{
-			{
-				PolicyID: "policy-001",
-				Status:   "valid",
-				Issues:   []ValidationIssue{},
-				Warnings: []ValidationWarning{},
-			},
-		},
-		Summary: ValidationSummary{
-			TotalPolicies:   len(policies),
-			ValidPolicies:   len(policies),
-			InvalidPolicies: 0,
-			TotalIssues:     0,
-			TotalWarnings:   0,
+// Report converts a security report into a notify.Report for mailing
+func (r *SecurityReport) Report() notify.Report {
+	summary := r.ExecutiveSummary
+	report := notify.Report{
+		Title:     fmt.Sprintf("Security Report (%s)", r.Type),
+		Generated: r.Timestamp,
+		Summary: []notify.ReportField{
+			{Label: "Report", Value: r.ID},
+			{Label: "Overall risk score", Value: fmt.Sprintf("%.1f", summary.OverallRiskScore)},
+			{Label: "Critical findings", Value: fmt.Sprintf("%d", summary.CriticalFindings)},
+			{Label: "High priority findings", Value: fmt.Sprintf("%d", summary.HighPriorityFindings)},
+			{Label: "Compliance score", Value: fmt.Sprintf("%.1f%%", summary.ComplianceScore)},
 		},
-	}, nil
+	}
+
+	if len(r.ScanResults) > 0 {
+		scans := notify.ReportSection{Title: "Scan Results", Headers: []string{"Target", "Status", "Critical", "High", "Medium", "Low"}}
+		vulns := notify.ReportSection{Title: "Vulnerabilities", Headers: []string{"ID", "Severity", "Component", "Version", "Title"}}
+		for _, scan := range r.ScanResults {
+			scans.Rows = append(scans.Rows, []string{
+				scan.Target, scan.Status,
+				fmt.Sprintf("%d", scan.Summary.CriticalIssues), fmt.Sprintf("%d", scan.Summary.HighIssues),
+				fmt.Sprintf("%d", scan.Summary.MediumIssues), fmt.Sprintf("%d", scan.Summary.LowIssues),
+			})
+			for _, vuln := range scan.Vulnerabilities {
+				vulns.Rows = append(vulns.Rows, []string{vuln.ID, vuln.Severity, vuln.Component, vuln.Version, vuln.Title})
+			}
+		}
+		report.Sections = append(report.Sections, scans)
+		if len(vulns.Rows) > 0 {
+			report.Sections = append(report.Sections, vulns)
+		}
+	}
+
+	if len(r.ComplianceResults) > 0 {
+		compliance := notify.ReportSection{Title: "Compliance", Headers: []string{"Standard", "Status", "Score", "Passed", "Failed"}}
+		for _, result := range r.ComplianceResults {
+			compliance.Rows = append(compliance.Rows, []string{
+				result.Standard, result.Status, fmt.Sprintf("%.1f%%", result.Score),
+				fmt.Sprintf("%d", result.Summary.PassedControls), fmt.Sprintf("%d", result.Summary.FailedControls),
+			})
+		}
+		report.Sections = append(report.Sections, compliance)
+	}
+
+	if len(summary.KeyRecommendations) > 0 {
+		report.Sections = append(report.Sections, notify.ReportSection{Title: "Key Recommendations", Items: summary.KeyRecommendations})
+	}
+	if len(r.Recommendations) > 0 {
+		report.Sections = append(report.Sections, notify.ReportSection{Title: "Recommendations", Items: r.Recommendations})
+	}
+	return report
 }
 
 // Enhanced Security Manager with Encryption and Audit Logging
@@ -460,7 +474,8 @@ type SecurityConfig struct {
 	ComplianceMode string `json:"compliance_mode" yaml:"compliance_mode"`
 	AuditLogPath   string `json:"audit_log_path" yaml:"audit_log_path"`
 	KeyStorePath   string `json:"key_store_path" yaml:"key_store_path"`
-	RotationPeriod int    `json:"rotation_period" yaml:"rotation_period"`
+	// RotationPeriod is the age in days after which keys are rotated; 0 disables rotation
+	RotationPeriod int `json:"rotation_period" yaml:"rotation_period"`
 }
 
 // AuditEvent represents an audit event
@@ -478,6 +493,8 @@ type AuditEvent struct {
 	SessionID  string                 `json:"session_id"`
 	Severity   string                 `json:"severity"`
 	Compliance []string               `json:"compliance"`
+	// TraceID links the event to the log lines of the invocation that caused it
+	TraceID string `json:"trace_id,omitempty"`
 }
 
 // AuditLogger handles audit logging
@@ -497,16 +514,17 @@ type Encryptor struct {
 // KeyManager manages encryption keys
 type KeyManager struct {
 	config   *SecurityConfig
-	keys     map[string][]byte
+	keys     map[string]*keyRing
 	keyStore string
+	kdf      *keyDerivation
+	kek      []byte
 	logger   *logrus.Logger
 	mu       sync.RWMutex
 }
 
 // NewSecurityManager creates a new security manager
 func NewSecurityManager(config *SecurityConfig) (*SecurityManager, error) {
-	logger := logrus.New()
-	logger.SetLevel(logrus.InfoLevel)
+	logger := utils.NewLogger()
 
 	// Initialize key manager
 	keyManager, err := NewKeyManager(config)
@@ -534,12 +552,11 @@ func NewSecurityManager(config *SecurityConfig) (*SecurityManager, error) {
 
 // NewKeyManager creates a new key manager
 func NewKeyManager(config *SecurityConfig) (*KeyManager, error) {
-	logger := logrus.New()
-	logger.SetLevel(logrus.InfoLevel)
+	logger := utils.NewLogger()
 
 	km := &KeyManager{
 		config:   config,
-		keys:     make(map[string][]byte),
+		keys:     make(map[string]*keyRing),
 		keyStore: config.KeyStorePath,
 		logger:   logger,
 	}
@@ -554,8 +571,7 @@ func NewKeyManager(config *SecurityConfig) (*KeyManager, error) {
 
 // NewEncryptor creates a new encryptor
 func NewEncryptor(keyManager *KeyManager) *Encryptor {
-	logger := logrus.New()
-	logger.SetLevel(logrus.InfoLevel)
+	logger := utils.NewLogger()
 
 	return &Encryptor{
 		keyManager: keyManager,
@@ -565,8 +581,7 @@ func NewEncryptor(keyManager *KeyManager) *Encryptor {
 
 // NewAuditLogger creates a new audit logger
 func NewAuditLogger(config *SecurityConfig) (*AuditLogger, error) {
-	logger := logrus.New()
-	logger.SetLevel(logrus.InfoLevel)
+	logger := utils.NewLogger()
 
 	auditor := &AuditLogger{
 		config: config,
@@ -583,9 +598,10 @@ func NewAuditLogger(config *SecurityConfig) (*AuditLogger, error) {
 	return auditor, nil
 }
 
-// Encrypt encrypts data using AES-GCM
+// Encrypt encrypts data using AES-GCM with the active key version, tagging
+// the ciphertext with that version
 func (e *Encryptor) Encrypt(data []byte, keyName string) ([]byte, error) {
-	key, err := e.keyManager.GetKey(keyName)
+	version, key, err := e.keyManager.ActiveKey(keyName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get encryption key: %w", err)
 	}
@@ -605,13 +621,33 @@ func (e *Encryptor) Encrypt(data []byte, keyName string) ([]byte, error) {
 		return nil, fmt.Errorf("failed to generate nonce: %w", err)
 	}
 
-	ciphertext := gcm.Seal(nonce, nonce, data, nil)
+	// The header is authenticated so the version tag cannot be swapped
+	header := ciphertextHeader(version)
+	ciphertext := gcm.Seal(append(header, nonce...), nonce, data, header)
 	return ciphertext, nil
 }
 
-// Decrypt decrypts data using AES-GCM
+// Decrypt decrypts data using AES-GCM, selecting the key version the data
+// was tagged with; untagged data is treated as sealed with version 1
 func (e *Encryptor) Decrypt(data []byte, keyName string) ([]byte, error) {
-	key, err := e.keyManager.GetKey(keyName)
+	if version, ok := parseCiphertextHeader(data); ok {
+		plaintext, err := e.open(data[ciphertextHeaderSize:], keyName, version, data[:ciphertextHeaderSize])
+		if err == nil {
+			return plaintext, nil
+		}
+		// A legacy nonce can start with the magic bytes by chance
+		if legacy, legacyErr := e.open(data, keyName, 1, nil); legacyErr == nil {
+			return legacy, nil
+		}
+		return nil, err
+	}
+
+	return e.open(data, keyName, 1, nil)
+}
+
+// open decrypts nonce-prefixed ciphertext with the given key version
+func (e *Encryptor) open(data []byte, keyName string, version int, additionalData []byte) ([]byte, error) {
+	key, err := e.keyManager.GetKeyVersion(keyName, version)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get decryption key: %w", err)
 	}
@@ -632,7 +668,7 @@ func (e *Encryptor) Decrypt(data []byte, keyName string) ([]byte, error) {
 	}
 
 	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt: %w", err)
 	}
@@ -640,38 +676,25 @@ func (e *Encryptor) Decrypt(data []byte, keyName string) ([]byte, error) {
 	return plaintext, nil
 }
 
-// GetKey retrieves a key by name
+// GetKey retrieves the active version of a key by name
 func (km *KeyManager) GetKey(name string) ([]byte, error) {
-	km.mu.RLock()
-	defer km.mu.RUnlock()
-
-	key, exists := km.keys[name]
-	if !exists {
-		return nil, fmt.Errorf("key not found: %s", name)
-	}
-
-	return key, nil
+	_, key, err := km.ActiveKey(name)
+	return key, err
 }
 
-// GenerateKey generates a new key
+// GenerateKey generates a new key; for an existing name it adds a new
+// active version so data encrypted with earlier versions stays readable
 func (km *KeyManager) GenerateKey(name string) ([]byte, error) {
 	km.mu.Lock()
 	defer km.mu.Unlock()
 
-	key := make([]byte, 32) // 256-bit key
-	if _, err := rand.Read(key); err != nil {
-		return nil, fmt.Errorf("failed to generate key: %w", err)
-	}
-
-	km.keys[name] = key
-
-	// Save key to store
-	if err := km.saveKey(name, key); err != nil {
-		return nil, fmt.Errorf("failed to save key: %w", err)
+	version, err := km.addKeyVersion(name)
+	if err != nil {
+		return nil, err
 	}
 
 	km.logger.Infof("Generated new key: %s", name)
-	return key, nil
+	return version.Key, nil
 }
 
 // LogEvent logs an audit event
@@ -690,6 +713,9 @@ func (al *AuditLogger) LogEvent(event *AuditEvent) error {
 	if event.Timestamp.IsZero() {
 		event.Timestamp = time.Now()
 	}
+	if event.TraceID == "" {
+		event.TraceID = utils.TraceID(context.Background())
+	}
 
 	// Marshal event to JSON
 	data, err := json.Marshal(event)
@@ -714,6 +740,7 @@ func (al *AuditLogger) LogEvent(event *AuditEvent) error {
 		"action":     event.Action,
 		"result":     event.Result,
 		"severity":   event.Severity,
+		"trace_id":   event.TraceID,
 	}).Info("Audit event logged")
 
 	return nil
@@ -750,8 +777,18 @@ func (km *KeyManager) loadKeys() error {
 		return fmt.Errorf("failed to create key store directory: %w", err)
 	}
 
+	passphrase := os.Getenv(KeyStorePassphraseEnv)
+
 	// Load keys from file if it exists
 	if _, err := os.Stat(km.keyStore); os.IsNotExist(err) {
+		if passphrase != "" {
+			if err := km.enableKEK(passphrase); err != nil {
+				return err
+			}
+		} else {
+			km.logger.Warnf("%s is not set; key store %s will not be encrypted", KeyStorePassphraseEnv, km.keyStore)
+		}
+
 		// Generate default key if no key store exists
 		if _, err := km.GenerateKey("default"); err != nil {
 			return fmt.Errorf("failed to generate default key: %w", err)
@@ -764,51 +801,63 @@ func (km *KeyManager) loadKeys() error {
 		return fmt.Errorf("failed to read key store: %w", err)
 	}
 
-	var keyData map[string]string
-	if err := json.Unmarshal(data, &keyData); err != nil {
-		return fmt.Errorf("failed to unmarshal key data: %w", err)
+	store, legacy, err := decodeKeyStore(data)
+	if err != nil {
+		return err
 	}
+	km.keys = store.Keys
 
-	// Decode keys
-	for name, encodedKey := range keyData {
-		key, err := base64.StdEncoding.DecodeString(encodedKey)
-		if err != nil {
-			km.logger.Warnf("Failed to decode key %s: %v", name, err)
-			continue
+	migrate := legacy
+	switch {
+	case store.KDF != nil:
+		if err := km.unlockKeys(store.KDF, passphrase); err != nil {
+			return err
+		}
+	case passphrase != "":
+		// Encrypt a plaintext store the first time a passphrase is available
+		if err := km.enableKEK(passphrase); err != nil {
+			return err
+		}
+		migrate = true
+	default:
+		km.logger.Warnf("%s is not set; key store %s is not encrypted", KeyStorePassphraseEnv, km.keyStore)
+	}
+
+	// Rewrite legacy or plaintext stores in the current layout
+	if migrate {
+		if err := km.saveKeys(); err != nil {
+			return fmt.Errorf("failed to migrate key store: %w", err)
 		}
-		km.keys[name] = key
+		km.logger.Infof("Migrated key store to the current format")
 	}
 
 	km.logger.Infof("Loaded %d keys from key store", len(km.keys))
 	return nil
 }
 
-// saveKey saves a key to the key store
-func (km *KeyManager) saveKey(name string, key []byte) error {
+// saveKeys writes all keys to the key store; callers must hold the write lock
+func (km *KeyManager) saveKeys() error {
 	if km.keyStore == "" {
 		return nil
 	}
 
-	// Load existing keys
-	var keyData map[string]string
-	if data, err := os.ReadFile(km.keyStore); err == nil {
-		json.Unmarshal(data, &keyData)
-	}
-
-	if keyData == nil {
-		keyData = make(map[string]string)
+	keys, err := km.sealedKeys()
+	if err != nil {
+		return err
 	}
 
-	// Add new key
-	keyData[name] = base64.StdEncoding.EncodeToString(key)
-
-	// Save to file
-	data, err := json.MarshalIndent(keyData, "", "  ")
+	data, err := json.MarshalIndent(keyStoreFile{Format: keyStoreFormat, KDF: km.kdf, Keys: keys}, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal key data: %w", err)
 	}
 
-	if err := os.WriteFile(km.keyStore, data, 0600); err != nil {
+	// Write to a temporary file first so a failed write never truncates the store
+	tmpPath := km.keyStore + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write key store: %w", err)
+	}
+	if err := os.Rename(tmpPath, km.keyStore); err != nil {
+		os.Remove(tmpPath)
 		return fmt.Errorf("failed to write key store: %w", err)
 	}
 
@@ -846,7 +895,9 @@ func (sm *SecurityManager) determineSeverity(eventType, result string) string {
 	}
 }
 
-// EncryptSensitiveData encrypts sensitive configuration data
+// EncryptSensitiveData encrypts sensitive configuration data, descending
+// into nested maps. Encrypted values are strings marked with
+// config.EncryptedPrefix; values that already carry it are left alone
 func (sm *SecurityManager) EncryptSensitiveData(data map[string]interface{}) (map[string]interface{}, error) {
 	if !sm.config.Encryption {
 		return data, nil
@@ -854,20 +905,55 @@ func (sm *SecurityManager) EncryptSensitiveData(data map[string]interface{}) (ma
 
 	result := make(map[string]interface{})
 	for k, v := range data {
-		if sm.isSensitiveField(k) {
-			// Convert to JSON and encrypt
-			jsonData, err := json.Marshal(v)
+		str, isString := v.(string)
+		nested, isMap := v.(map[string]interface{})
+
+		switch {
+		case v == nil, isString && (str == "" || strings.HasPrefix(str, config.EncryptedPrefix)):
+			result[k] = v
+		case sm.isSensitiveField(k):
+			encrypted, err := sm.encryptValue(v)
 			if err != nil {
-				return nil, fmt.Errorf("failed to marshal sensitive data: %w", err)
+				return nil, err
 			}
-
-			encrypted, err := sm.encryptor.Encrypt(jsonData, "default")
+			result[k] = encrypted
+		case isMap:
+			encrypted, err := sm.EncryptSensitiveData(nested)
 			if err != nil {
-				return nil, fmt.Errorf("failed to encrypt sensitive data: %w", err)
+				return nil, err
 			}
+			result[k] = encrypted
+		default:
+			result[k] = v
+		}
+	}
 
-			result[k] = base64.StdEncoding.EncodeToString(encrypted)
-		} else {
+	return result, nil
+}
+
+// DecryptSensitiveData reverses EncryptSensitiveData, decrypting every value
+// marked with config.EncryptedPrefix; unmarked values are returned as-is
+func (sm *SecurityManager) DecryptSensitiveData(data map[string]interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for k, v := range data {
+		switch value := v.(type) {
+		case string:
+			if !strings.HasPrefix(value, config.EncryptedPrefix) {
+				result[k] = v
+				continue
+			}
+			decrypted, err := sm.decryptValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt %s: %w", k, err)
+			}
+			result[k] = decrypted
+		case map[string]interface{}:
+			nested, err := sm.DecryptSensitiveData(value)
+			if err != nil {
+				return nil, err
+			}
+			result[k] = nested
+		default:
 			result[k] = v
 		}
 	}
@@ -875,16 +961,59 @@ func (sm *SecurityManager) EncryptSensitiveData(data map[string]interface{}) (ma
 	return result, nil
 }
 
-// isSensitiveField checks if a field contains sensitive data
+// encryptValue encrypts the JSON encoding of v as a prefixed base64 string
+func (sm *SecurityManager) encryptValue(v interface{}) (string, error) {
+	jsonData, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal sensitive data: %w", err)
+	}
+
+	encrypted, err := sm.encryptor.Encrypt(jsonData, "default")
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt sensitive data: %w", err)
+	}
+
+	return config.EncryptedPrefix + base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+// decryptValue decrypts a value produced by encryptValue
+func (sm *SecurityManager) decryptValue(value string) (interface{}, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, config.EncryptedPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted value: %w", err)
+	}
+
+	jsonData, err := sm.encryptor.Decrypt(encrypted, "default")
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(jsonData, &v); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sensitive data: %w", err)
+	}
+	return v, nil
+}
+
+// isSensitiveField checks if a field contains sensitive data. Fields match
+// on their last word, so api_key and client_secret are sensitive while
+// key_management is not
 func (sm *SecurityManager) isSensitiveField(field string) bool {
-	sensitiveFields := []string{
-		"password", "secret", "key", "token", "credential",
-		"api_key", "access_key", "private_key", "client_secret",
+	sensitiveWords := []string{
+		"password", "passwd", "secret", "secrets", "key", "apikey",
+		"token", "credential", "credentials",
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(field), func(r rune) bool {
+		return r == '_' || r == '-' || r == '.'
+	})
+	if len(words) == 0 {
+		return false
 	}
 
-	fieldLower := strings.ToLower(field)
-	for _, sensitive := range sensitiveFields {
-		if strings.Contains(fieldLower, sensitive) {
+	last := words[len(words)-1]
+	for _, sensitive := range sensitiveWords {
+		if last == sensitive {
 			return true
 		}
 	}
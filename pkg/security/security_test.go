@@ -0,0 +1,1370 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/cloud"
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/notify"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/smithy-go"
+)
+
+const trivyFixture = `{
+  "Results": [
+    {
+      "Target": "go.sum",
+      "Class": "lang-pkgs",
+      "Type": "gomod",
+      "Vulnerabilities": [
+        {
+          "VulnerabilityID": "CVE-2023-0001",
+          "PkgName": "golang.org/x/net",
+          "InstalledVersion": "0.1.0",
+          "FixedVersion": "0.7.0",
+          "Title": "HTTP/2 rapid reset",
+          "Severity": "HIGH",
+          "PrimaryURL": "https://avd.aquasec.com/nvd/cve-2023-0001",
+          "CVSS": {"nvd": {"V3Score": 7.5}, "ghsa": {"V3Score": 5.3}}
+        },
+        {
+          "VulnerabilityID": "GHSA-xxxx-yyyy",
+          "PkgName": "example.com/lib",
+          "InstalledVersion": "1.0.0",
+          "Severity": "CRITICAL",
+          "CVSS": {"ghsa": {"V2Score": 9.8}}
+        },
+        {
+          "VulnerabilityID": "CVE-2023-0002",
+          "PkgName": "example.com/other",
+          "InstalledVersion": "2.0.0",
+          "Severity": "UNKNOWN"
+        }
+      ]
+    }
+  ]
+}`
+
+// installFakeTrivy puts a trivy script printing output on PATH, returning
+// the file it records its arguments in
+func installFakeTrivy(t *testing.T, output string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake trivy script requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "report.json"), []byte(output), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	args := filepath.Join(dir, "args")
+	script := "#!/bin/sh\necho \"$@\" > " + args + "\ncat " + filepath.Join(dir, "report.json") + "\n"
+	if err := os.WriteFile(filepath.Join(dir, trivyBinary), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake trivy: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return args
+}
+
+func TestScanVulnerabilitiesTrivy(t *testing.T) {
+	installFakeTrivy(t, trivyFixture)
+	service := NewSecurityService(nil)
+
+	result, err := service.ScanVulnerabilities(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatalf("ScanVulnerabilities() failed: %v", err)
+	}
+
+	if len(result.Vulnerabilities) != 3 {
+		t.Fatalf("expected 3 vulnerabilities, got %d", len(result.Vulnerabilities))
+	}
+
+	critical := result.Vulnerabilities[0]
+	if critical.ID != "GHSA-xxxx-yyyy" || critical.Severity != "critical" || critical.CVSS != 9.8 || critical.CVE != "" {
+		t.Errorf("unexpected first vulnerability: %+v", critical)
+	}
+
+	high := result.Vulnerabilities[1]
+	if high.CVE != "CVE-2023-0001" || high.CVSS != 7.5 {
+		t.Errorf("expected NVD score for CVE-2023-0001, got %+v", high)
+	}
+	if !strings.Contains(high.Solution, "0.7.0") {
+		t.Errorf("expected solution to mention the fixed version, got %q", high.Solution)
+	}
+
+	want := ScanSummary{TotalChecks: 3, CriticalIssues: 1, HighIssues: 1, InfoIssues: 1}
+	if result.Summary != want {
+		t.Errorf("expected summary %+v, got %+v", want, result.Summary)
+	}
+}
+
+func TestScanVulnerabilitiesTargetIsNotAFlag(t *testing.T) {
+	args := installFakeTrivy(t, trivyFixture)
+
+	if _, err := runTrivy(context.Background(), "--server=http://attacker"); err != nil {
+		t.Fatalf("runTrivy() failed: %v", err)
+	}
+	data, err := os.ReadFile(args)
+	if err != nil {
+		t.Fatalf("failed to read trivy arguments: %v", err)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(string(data)), "-- --server=http://attacker") {
+		t.Errorf("expected the target after --, got %q", data)
+	}
+}
+
+func TestScanVulnerabilitiesWithoutTrivy(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	service := NewSecurityService(nil)
+
+	_, err := service.ScanVulnerabilities(context.Background(), ".")
+	if err == nil || !strings.Contains(err.Error(), "install") {
+		t.Errorf("expected an installation error, got %v", err)
+	}
+}
+
+func newTestKeyManager(t *testing.T, path string) *KeyManager {
+	t.Helper()
+
+	km, err := NewKeyManager(&SecurityConfig{KeyStorePath: path})
+	if err != nil {
+		t.Fatalf("NewKeyManager() failed: %v", err)
+	}
+	return km
+}
+
+func TestKeyRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	km := newTestKeyManager(t, path)
+	encryptor := NewEncryptor(km)
+
+	v1Ciphertext, err := encryptor.Encrypt([]byte("secret v1"), "default")
+	if err != nil {
+		t.Fatalf("Encrypt() failed: %v", err)
+	}
+
+	if err := km.RotateKey("default"); err != nil {
+		t.Fatalf("RotateKey() failed: %v", err)
+	}
+	if version, _, _ := km.ActiveKey("default"); version != 2 {
+		t.Errorf("expected active version 2, got %d", version)
+	}
+
+	v2Ciphertext, err := encryptor.Encrypt([]byte("secret v2"), "default")
+	if err != nil {
+		t.Fatalf("Encrypt() failed: %v", err)
+	}
+	if version, ok := parseCiphertextHeader(v2Ciphertext); !ok || version != 2 {
+		t.Errorf("expected ciphertext tagged with version 2, got %d", version)
+	}
+
+	// Both versions survive a reload from the key store
+	reloaded := NewEncryptor(newTestKeyManager(t, path))
+	for _, tt := range []struct {
+		ciphertext []byte
+		want       string
+	}{
+		{v1Ciphertext, "secret v1"},
+		{v2Ciphertext, "secret v2"},
+	} {
+		plaintext, err := reloaded.Decrypt(tt.ciphertext, "default")
+		if err != nil {
+			t.Fatalf("Decrypt() failed: %v", err)
+		}
+		if string(plaintext) != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, plaintext)
+		}
+	}
+
+	if err := km.RotateKey("missing"); err == nil {
+		t.Error("expected an error rotating an unknown key")
+	}
+}
+
+func TestKeyStoreLegacyMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	key := bytes.Repeat([]byte{7}, 32)
+	legacy, _ := json.Marshal(map[string]string{"default": base64.StdEncoding.EncodeToString(key)})
+	if err := os.WriteFile(path, legacy, 0600); err != nil {
+		t.Fatalf("failed to write legacy key store: %v", err)
+	}
+
+	km := newTestKeyManager(t, path)
+	got, err := km.GetKey("default")
+	if err != nil || !bytes.Equal(got, key) {
+		t.Fatalf("expected legacy key to load, got %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read key store: %v", err)
+	}
+	var store keyStoreFile
+	if err := json.Unmarshal(data, &store); err != nil || store.Format != keyStoreFormat {
+		t.Errorf("expected key store to be migrated to format %d, got %s", keyStoreFormat, data)
+	}
+}
+
+func TestRotateExpiredKeys(t *testing.T) {
+	km := newTestKeyManager(t, filepath.Join(t.TempDir(), "keys.json"))
+	if _, err := km.GenerateKey("fresh"); err != nil {
+		t.Fatalf("GenerateKey() failed: %v", err)
+	}
+	km.keys["default"].active().CreatedAt = time.Now().Add(-48 * time.Hour)
+
+	if err := km.RotateExpiredKeys(24 * time.Hour); err != nil {
+		t.Fatalf("RotateExpiredKeys() failed: %v", err)
+	}
+
+	if version, _, _ := km.ActiveKey("default"); version != 2 {
+		t.Errorf("expected expired key to be rotated to version 2, got %d", version)
+	}
+	if version, _, _ := km.ActiveKey("fresh"); version != 1 {
+		t.Errorf("expected fresh key to stay at version 1, got %d", version)
+	}
+}
+
+func TestStartRotatesExpiredKeys(t *testing.T) {
+	interval := rotationCheckInterval
+	rotationCheckInterval = 10 * time.Millisecond
+	t.Cleanup(func() { rotationCheckInterval = interval })
+
+	manager, err := NewSecurityManager(&SecurityConfig{KeyStorePath: filepath.Join(t.TempDir(), "keys.json"), RotationPeriod: 30})
+	if err != nil {
+		t.Fatalf("NewSecurityManager() failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager.Start(ctx)
+
+	// The key ages past the period after the first check
+	km := manager.keyManager
+	km.mu.Lock()
+	km.keys["default"].active().CreatedAt = time.Now().Add(-31 * 24 * time.Hour)
+	km.mu.Unlock()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if version, _, _ := km.ActiveKey("default"); version == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected Start to rotate the key once it is older than the rotation period")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestKeyStoreEncryptedAtRest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	t.Setenv(KeyStorePassphraseEnv, "correct horse")
+
+	km := newTestKeyManager(t, path)
+	key, err := km.GetKey("default")
+	if err != nil {
+		t.Fatalf("GetKey() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read key store: %v", err)
+	}
+	if bytes.Contains(data, []byte(base64.StdEncoding.EncodeToString(key))) {
+		t.Error("expected the key store not to contain the raw key")
+	}
+
+	reloaded := newTestKeyManager(t, path)
+	if got, err := reloaded.GetKey("default"); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("expected the key to unwrap with the same passphrase, got %v", err)
+	}
+
+	t.Setenv(KeyStorePassphraseEnv, "wrong")
+	if _, err := NewKeyManager(&SecurityConfig{KeyStorePath: path}); err == nil || !strings.Contains(err.Error(), "wrong") {
+		t.Errorf("expected a wrong passphrase error, got %v", err)
+	}
+
+	t.Setenv(KeyStorePassphraseEnv, "")
+	if _, err := NewKeyManager(&SecurityConfig{KeyStorePath: path}); err == nil || !strings.Contains(err.Error(), KeyStorePassphraseEnv) {
+		t.Errorf("expected an error asking for the passphrase, got %v", err)
+	}
+}
+
+func TestKeyStoreEncryptsPlaintextOnLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	key := bytes.Repeat([]byte{9}, 32)
+	legacy, _ := json.Marshal(map[string]string{"default": base64.StdEncoding.EncodeToString(key)})
+	if err := os.WriteFile(path, legacy, 0600); err != nil {
+		t.Fatalf("failed to write legacy key store: %v", err)
+	}
+
+	t.Setenv(KeyStorePassphraseEnv, "migrate me")
+	newTestKeyManager(t, path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read key store: %v", err)
+	}
+	var store keyStoreFile
+	if err := json.Unmarshal(data, &store); err != nil || store.KDF == nil {
+		t.Fatalf("expected the migrated store to record its KDF, got %s", data)
+	}
+	if bytes.Equal(store.Keys["default"].active().Key, key) {
+		t.Error("expected the migrated key to be wrapped")
+	}
+
+	if got, err := newTestKeyManager(t, path).GetKey("default"); err != nil || !bytes.Equal(got, key) {
+		t.Errorf("expected the migrated key to unwrap, got %v", err)
+	}
+}
+
+// fakeProvider serves canned resources per type; unimplemented methods panic
+type fakeProvider struct {
+	cloud.CloudProvider
+	resources map[string][]*cloud.Resource
+}
+
+func (f *fakeProvider) ListResources(ctx context.Context, resourceType string, opts ...cloud.ListOption) ([]*cloud.Resource, error) {
+	resources, ok := f.resources[resourceType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported resource type: %s", resourceType)
+	}
+	return resources, nil
+}
+
+func TestConfigSecretsEncryptedAtRest(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+	t.Setenv(KeyStorePassphraseEnv, "")
+	config.RegisterSecretCodec(newConfigSecretCodec)
+
+	const apiKey = "sk-test-0123456789"
+	cfg := &config.Config{
+		Version: "1.0.0",
+		Agents: map[string]config.Agent{
+			"default": {Type: "openai", APIKey: apiKey, Model: "gpt-4", MaxTokens: 2048, Temperature: 0.7},
+		},
+		Security: config.SecurityConfig{Encryption: true, KeyManagement: "local"},
+	}
+
+	configFile := filepath.Join(home, "config.yaml")
+	if err := config.Save(cfg, configFile); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("failed to read saved config: %v", err)
+	}
+	if strings.Contains(string(data), apiKey) {
+		t.Fatalf("API key stored in cleartext:\n%s", data)
+	}
+	if !strings.Contains(string(data), "api_key: "+config.EncryptedPrefix) {
+		t.Errorf("expected an encrypted api_key, got:\n%s", data)
+	}
+	if !strings.Contains(string(data), "key_management: local") {
+		t.Errorf("expected non-secret fields in cleartext, got:\n%s", data)
+	}
+
+	if err := config.Initialize(configFile, false); err != nil {
+		t.Fatalf("Initialize() failed: %v", err)
+	}
+	loaded, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got := loaded.Agents["default"].APIKey; got != apiKey {
+		t.Errorf("expected decrypted API key %q, got %q", apiKey, got)
+	}
+	if got := loaded.Agents["default"].Model; got != "gpt-4" {
+		t.Errorf("expected model gpt-4, got %q", got)
+	}
+
+	// A config written before encryption still loads as plaintext
+	legacy := "agents:\n  default:\n    type: openai\n    api_key: legacy-key\n    max_tokens: 100\n"
+	if err := os.WriteFile(configFile, []byte(legacy), 0644); err != nil {
+		t.Fatalf("failed to write legacy config: %v", err)
+	}
+	if err := config.Initialize(configFile, false); err != nil {
+		t.Fatalf("Initialize() with legacy config failed: %v", err)
+	}
+	loaded, err = config.Load()
+	if err != nil {
+		t.Fatalf("Load() with legacy config failed: %v", err)
+	}
+	if got := loaded.Agents["default"].APIKey; got != "legacy-key" {
+		t.Errorf("expected legacy API key, got %q", got)
+	}
+}
+
+func TestCheckComplianceCIS(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	provider := &fakeProvider{resources: map[string][]*cloud.Resource{
+		"volumes": {
+			{ID: "vol-1", Config: map[string]interface{}{"encrypted": true}},
+			{ID: "vol-2", Config: map[string]interface{}{"encrypted": false}},
+		},
+		"security-groups": {
+			{ID: "sg-1", Name: "default", Config: map[string]interface{}{"rules_count": 0, "public_admin_ingress": false}},
+			{ID: "sg-2", Name: "web", Config: map[string]interface{}{"rules_count": 3, "public_admin_ingress": false}},
+		},
+	}}
+	service := &DefaultSecurityService{
+		newProvider: func(name string) (cloud.CloudProvider, error) {
+			if name != "aws" {
+				t.Errorf("expected the aws provider, got %s", name)
+			}
+			return provider, nil
+		},
+	}
+
+	result, err := service.CheckCompliance(context.Background(), "cis")
+	if err != nil {
+		t.Fatalf("CheckCompliance() failed: %v", err)
+	}
+
+	if result.Standard != "cis-aws-1.4" {
+		t.Errorf("expected the cis alias to resolve to cis-aws-1.4, got %s", result.Standard)
+	}
+
+	statuses := map[string]string{}
+	for _, control := range result.Controls {
+		statuses[control.ID] = control.Status
+	}
+	want := map[string]string{"2.2.1": "failed", "5.2": "passed", "5.3": "passed"}
+	for id, status := range want {
+		if statuses[id] != status {
+			t.Errorf("control %s: expected %s, got %s", id, status, statuses[id])
+		}
+	}
+
+	if result.Summary.TotalControls != 3 || result.Summary.PassedControls != 2 || result.Summary.FailedControls != 1 {
+		t.Errorf("unexpected summary: %+v", result.Summary)
+	}
+	if result.Score < 66.6 || result.Score > 66.7 {
+		t.Errorf("expected score of 2/3, got %v", result.Score)
+	}
+	if !strings.Contains(result.Controls[0].Evidence, "vol-2") {
+		t.Errorf("expected evidence to name the unencrypted volume, got %q", result.Controls[0].Evidence)
+	}
+
+	if _, err := service.CheckCompliance(context.Background(), "nist-800-53"); err == nil || !strings.Contains(err.Error(), "unknown compliance standard") {
+		t.Errorf("expected an unknown standard error, got %v", err)
+	}
+}
+
+func TestCheckExpression(t *testing.T) {
+	resource := &cloud.Resource{
+		ID:     "i-1",
+		Name:   "web",
+		Tags:   map[string]string{"env": "prod"},
+		Config: map[string]interface{}{"size": 100, "groups": []string{"default", "web"}, "encrypted": true},
+	}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"config.size >= 100", true},
+		{"config.size < 50", false},
+		{"config.encrypted == true", true},
+		{`tags.env == "prod"`, true},
+		{"tags.owner exists", false},
+		{"tags.owner not_exists", true},
+		{"config.groups contains default", true},
+		{"config.groups not_contains admin", true},
+		{"name != web", false},
+		{"config.missing == 1", false},
+	}
+
+	for _, tt := range tests {
+		expr, err := parseCheckExpression(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCheckExpression(%q) failed: %v", tt.expr, err)
+		}
+		if got := expr.Evaluate(resource); got != tt.want {
+			t.Errorf("%q: expected %v, got %v", tt.expr, tt.want, got)
+		}
+	}
+
+	if _, err := parseCheckExpression("encrypted"); err == nil {
+		t.Error("expected an error for a malformed check")
+	}
+}
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+func sarifTestReport() *SecurityReport {
+	return &SecurityReport{
+		ID:   "report-test",
+		Type: "summary",
+		ScanResults: []ScanResult{
+			{
+				ID:     "scan-1",
+				Target: ".",
+				Vulnerabilities: []Vulnerability{
+					{
+						ID:          "CVE-2023-0001",
+						Title:       "Denial of service in parser",
+						Description: "Crafted input causes unbounded recursion.",
+						Severity:    "high",
+						CVSS:        7.5,
+						CVE:         "CVE-2023-0001",
+						Component:   "golang.org/x/net",
+						Version:     "0.5.0",
+						Solution:    "Upgrade golang.org/x/net from 0.5.0 to 0.7.0",
+						References:  []string{"https://nvd.nist.gov/vuln/detail/CVE-2023-0001"},
+						Metadata:    map[string]string{"target": "go.sum", "fixed_version": "0.7.0"},
+					},
+					{
+						ID:        "GHSA-xxxx-yyyy",
+						Title:     "Medium issue without a score",
+						Severity:  "medium",
+						Component: "example.com/lib",
+						Version:   "1.0.0",
+						Metadata:  map[string]string{"target": "go.sum"},
+					},
+				},
+			},
+			{
+				ID:     "scan-2",
+				Target: "alpine:3.18",
+				Vulnerabilities: []Vulnerability{
+					{
+						ID:        "CVE-2023-0001",
+						Title:     "Denial of service in parser",
+						Severity:  "high",
+						CVSS:      7.5,
+						Component: "golang.org/x/net",
+						Version:   "0.4.0",
+						Metadata:  map[string]string{},
+					},
+					{
+						ID:        "CVE-2023-0002",
+						Title:     "Informational finding",
+						Severity:  "info",
+						Component: "musl",
+						Version:   "1.2.4",
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestExportReportSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportReport(sarifTestReport(), "sarif", &buf); err != nil {
+		t.Fatalf("ExportReport() failed: %v", err)
+	}
+
+	golden := filepath.Join("testdata", "report.sarif")
+	if *updateGolden {
+		if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("SARIF output does not match %s (run with -update to regenerate):\n%s", golden, buf.String())
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("SARIF output is not valid JSON: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("expected a single SARIF 2.1.0 run, got version %q with %d runs", log.Version, len(log.Runs))
+	}
+
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 3 {
+		t.Errorf("expected one rule per vulnerability ID, got %d", len(run.Tool.Driver.Rules))
+	}
+	if len(run.Results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(run.Results))
+	}
+
+	levels := []string{"error", "warning", "error", "note"}
+	for i, result := range run.Results {
+		if result.Level != levels[i] {
+			t.Errorf("result %d: expected level %s, got %s", i, levels[i], result.Level)
+		}
+		if rule := run.Tool.Driver.Rules[result.RuleIndex]; rule.ID != result.RuleID {
+			t.Errorf("result %d: rule index %d points at %s, expected %s", i, result.RuleIndex, rule.ID, result.RuleID)
+		}
+	}
+	if uri := run.Results[2].Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "alpine:3.18" {
+		t.Errorf("expected the scan target as location, got %s", uri)
+	}
+}
+
+func TestExportReportFormats(t *testing.T) {
+	report := sarifTestReport()
+
+	var buf bytes.Buffer
+	if err := ExportReport(report, "json", &buf); err != nil {
+		t.Fatalf("ExportReport() failed: %v", err)
+	}
+	var decoded SecurityReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("JSON output is not a security report: %v", err)
+	}
+	if decoded.ID != report.ID || len(decoded.ScanResults) != 2 {
+		t.Errorf("unexpected JSON report: %+v", decoded)
+	}
+
+	if err := ExportReport(report, "pdf", &buf); err == nil || !strings.Contains(err.Error(), "sarif") {
+		t.Errorf("expected an unsupported format error listing sarif, got %v", err)
+	}
+}
+
+// recordingNotifier records the notifications it is sent
+type recordingNotifier struct {
+	notifications []notify.Notification
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, notification notify.Notification) error {
+	r.notifications = append(r.notifications, notification)
+	return nil
+}
+
+func TestNotifyHighSeverityEvents(t *testing.T) {
+	notifier := &recordingNotifier{}
+
+	for _, severity := range []string{"info", "medium", "high", "critical"} {
+		notifyEvent(context.Background(), notifier, SecurityEvent{
+			ID:          "event-" + severity,
+			Type:        "privilege_escalation",
+			Severity:    severity,
+			Description: "Role granted outside change window",
+			Timestamp:   time.Now(),
+		})
+	}
+
+	if len(notifier.notifications) != 2 {
+		t.Fatalf("expected only high and critical events to be sent, got %+v", notifier.notifications)
+	}
+	if sent := notifier.notifications[0]; sent.Name != "privilege_escalation" || sent.Severity != "high" || sent.Message == "" {
+		t.Errorf("unexpected notification %+v", sent)
+	}
+}
+
+// fakeEventSource emits a fixed set of events, then keeps its channel open
+// until ctx is cancelled like a live source
+type fakeEventSource struct {
+	events []SecurityEvent
+}
+
+func (f *fakeEventSource) Name() string {
+	return "fake"
+}
+
+func (f *fakeEventSource) Events(ctx context.Context) (<-chan SecurityEvent, error) {
+	events := make(chan SecurityEvent)
+	go func() {
+		defer close(events)
+		for _, event := range f.events {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+		<-ctx.Done()
+	}()
+	return events, nil
+}
+
+func TestWatchSecurityEvents(t *testing.T) {
+	source := &fakeEventSource{}
+	for _, severity := range []string{"info", "low", "medium", "high", "critical"} {
+		source.events = append(source.events, SecurityEvent{
+			ID:          "event-" + severity,
+			Type:        "login_attempt",
+			Timestamp:   time.Now(),
+			Source:      "auth-service",
+			Severity:    severity,
+			Description: "Failed login for admin",
+			Details:     map[string]string{"user": "admin", "ip": "10.0.0.7"},
+			Actions:     []string{"logged"},
+		})
+	}
+
+	notifier := &recordingNotifier{}
+	service := &DefaultSecurityService{notifier: notifier}
+	service.SetEventSources(source)
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	auditor, err := NewEventAuditLogger(path)
+	if err != nil {
+		t.Fatalf("NewEventAuditLogger() failed: %v", err)
+	}
+	defer auditor.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var seen []string
+	summary, err := service.WatchSecurityEvents(ctx, MonitorOptions{
+		MinSeverity: "medium",
+		Auditor:     auditor,
+		Notify:      true,
+		OnEvent: func(event SecurityEvent, summary *MonitorSummary) {
+			seen = append(seen, event.Severity)
+			if summary.Received == len(source.events) {
+				cancel()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("WatchSecurityEvents() failed: %v", err)
+	}
+	if ctx.Err() != context.Canceled {
+		t.Fatalf("expected monitoring to stop once cancelled, got %v", ctx.Err())
+	}
+
+	if strings.Join(seen, ",") != "medium,high,critical" {
+		t.Errorf("expected events of medium severity and above, got %v", seen)
+	}
+	if summary.Received != 5 || summary.Filtered != 2 || summary.Recorded != 3 || summary.Notified != 2 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if line := summary.Summary(); !strings.Contains(line, "1 critical, 1 high, 1 medium") {
+		t.Errorf("expected severities most severe first, got %q", line)
+	}
+	if len(notifier.notifications) != 2 {
+		t.Errorf("expected high and critical events to be notified, got %+v", notifier.notifications)
+	}
+
+	if err := auditor.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 audit records, got %d:\n%s", len(lines), data)
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("audit record is not JSON: %v", err)
+	}
+	if !strings.Contains(lines[0], "security_event.login_attempt") || !strings.Contains(lines[0], "10.0.0.7") {
+		t.Errorf("unexpected audit record %s", lines[0])
+	}
+}
+
+// fakeIAM serves IAM policies from maps; methods it does not override
+// panic through the nil embedded interface
+type fakeIAM struct {
+	iamAPI
+	// managed maps policy ARNs to their documents
+	managed map[string]string
+	// attached maps roles, users and groups, such as role/deploy, to the
+	// ARNs of their managed policies
+	attached map[string][]string
+	// inline maps roles, users and groups to their inline policies by name
+	inline map[string]map[string]string
+	// groups maps users to their groups
+	groups map[string][]string
+	err    error
+}
+
+func (f *fakeIAM) attachedPolicies(principal string) []iamtypes.AttachedPolicy {
+	var policies []iamtypes.AttachedPolicy
+	for _, arn := range f.attached[principal] {
+		policies = append(policies, iamtypes.AttachedPolicy{PolicyArn: aws.String(arn), PolicyName: aws.String(policyNameFromARN(arn))})
+	}
+	return policies
+}
+
+func (f *fakeIAM) inlineNames(principal string) []string {
+	var names []string
+	for name := range f.inline[principal] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (f *fakeIAM) ListAttachedRolePolicies(ctx context.Context, params *iam.ListAttachedRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListAttachedRolePoliciesOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &iam.ListAttachedRolePoliciesOutput{AttachedPolicies: f.attachedPolicies("role/" + aws.ToString(params.RoleName))}, nil
+}
+
+func (f *fakeIAM) ListRolePolicies(ctx context.Context, params *iam.ListRolePoliciesInput, optFns ...func(*iam.Options)) (*iam.ListRolePoliciesOutput, error) {
+	return &iam.ListRolePoliciesOutput{PolicyNames: f.inlineNames("role/" + aws.ToString(params.RoleName))}, nil
+}
+
+func (f *fakeIAM) GetRolePolicy(ctx context.Context, params *iam.GetRolePolicyInput, optFns ...func(*iam.Options)) (*iam.GetRolePolicyOutput, error) {
+	doc := url.PathEscape(f.inline["role/"+aws.ToString(params.RoleName)][aws.ToString(params.PolicyName)])
+	return &iam.GetRolePolicyOutput{PolicyDocument: aws.String(doc)}, nil
+}
+
+func (f *fakeIAM) ListAttachedUserPolicies(ctx context.Context, params *iam.ListAttachedUserPoliciesInput, optFns ...func(*iam.Options)) (*iam.ListAttachedUserPoliciesOutput, error) {
+	return &iam.ListAttachedUserPoliciesOutput{AttachedPolicies: f.attachedPolicies("user/" + aws.ToString(params.UserName))}, nil
+}
+
+func (f *fakeIAM) ListUserPolicies(ctx context.Context, params *iam.ListUserPoliciesInput, optFns ...func(*iam.Options)) (*iam.ListUserPoliciesOutput, error) {
+	return &iam.ListUserPoliciesOutput{PolicyNames: f.inlineNames("user/" + aws.ToString(params.UserName))}, nil
+}
+
+func (f *fakeIAM) GetUserPolicy(ctx context.Context, params *iam.GetUserPolicyInput, optFns ...func(*iam.Options)) (*iam.GetUserPolicyOutput, error) {
+	doc := url.PathEscape(f.inline["user/"+aws.ToString(params.UserName)][aws.ToString(params.PolicyName)])
+	return &iam.GetUserPolicyOutput{PolicyDocument: aws.String(doc)}, nil
+}
+
+func (f *fakeIAM) ListGroupsForUser(ctx context.Context, params *iam.ListGroupsForUserInput, optFns ...func(*iam.Options)) (*iam.ListGroupsForUserOutput, error) {
+	var groups []iamtypes.Group
+	for _, name := range f.groups["user/"+aws.ToString(params.UserName)] {
+		groups = append(groups, iamtypes.Group{GroupName: aws.String(name)})
+	}
+	return &iam.ListGroupsForUserOutput{Groups: groups}, nil
+}
+
+func (f *fakeIAM) ListAttachedGroupPolicies(ctx context.Context, params *iam.ListAttachedGroupPoliciesInput, optFns ...func(*iam.Options)) (*iam.ListAttachedGroupPoliciesOutput, error) {
+	return &iam.ListAttachedGroupPoliciesOutput{AttachedPolicies: f.attachedPolicies("group/" + aws.ToString(params.GroupName))}, nil
+}
+
+func (f *fakeIAM) ListGroupPolicies(ctx context.Context, params *iam.ListGroupPoliciesInput, optFns ...func(*iam.Options)) (*iam.ListGroupPoliciesOutput, error) {
+	return &iam.ListGroupPoliciesOutput{PolicyNames: f.inlineNames("group/" + aws.ToString(params.GroupName))}, nil
+}
+
+func (f *fakeIAM) GetGroupPolicy(ctx context.Context, params *iam.GetGroupPolicyInput, optFns ...func(*iam.Options)) (*iam.GetGroupPolicyOutput, error) {
+	doc := url.PathEscape(f.inline["group/"+aws.ToString(params.GroupName)][aws.ToString(params.PolicyName)])
+	return &iam.GetGroupPolicyOutput{PolicyDocument: aws.String(doc)}, nil
+}
+
+func (f *fakeIAM) GetAccountAuthorizationDetails(ctx context.Context, params *iam.GetAccountAuthorizationDetailsInput, optFns ...func(*iam.Options)) (*iam.GetAccountAuthorizationDetailsOutput, error) {
+	out := &iam.GetAccountAuthorizationDetailsOutput{}
+	principals := map[string]bool{}
+	for principal := range f.attached {
+		principals[principal] = true
+	}
+	for principal := range f.inline {
+		principals[principal] = true
+	}
+	for principal := range principals {
+		var inline []iamtypes.PolicyDetail
+		for _, name := range f.inlineNames(principal) {
+			inline = append(inline, iamtypes.PolicyDetail{PolicyName: aws.String(name), PolicyDocument: aws.String(url.PathEscape(f.inline[principal][name]))})
+		}
+		kind, name, _ := strings.Cut(principal, "/")
+		switch kind {
+		case "user":
+			out.UserDetailList = append(out.UserDetailList, iamtypes.UserDetail{UserName: aws.String(name), AttachedManagedPolicies: f.attachedPolicies(principal), UserPolicyList: inline})
+		case "role":
+			out.RoleDetailList = append(out.RoleDetailList, iamtypes.RoleDetail{RoleName: aws.String(name), AttachedManagedPolicies: f.attachedPolicies(principal), RolePolicyList: inline})
+		case "group":
+			out.GroupDetailList = append(out.GroupDetailList, iamtypes.GroupDetail{GroupName: aws.String(name), AttachedManagedPolicies: f.attachedPolicies(principal), GroupPolicyList: inline})
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeIAM) GetPolicy(ctx context.Context, params *iam.GetPolicyInput, optFns ...func(*iam.Options)) (*iam.GetPolicyOutput, error) {
+	if _, ok := f.managed[aws.ToString(params.PolicyArn)]; !ok {
+		return nil, &smithy.GenericAPIError{Code: "NoSuchEntity", Message: "policy not found"}
+	}
+	return &iam.GetPolicyOutput{Policy: &iamtypes.Policy{Arn: params.PolicyArn, DefaultVersionId: aws.String("v3")}}, nil
+}
+
+func (f *fakeIAM) GetPolicyVersion(ctx context.Context, params *iam.GetPolicyVersionInput, optFns ...func(*iam.Options)) (*iam.GetPolicyVersionOutput, error) {
+	if aws.ToString(params.VersionId) != "v3" {
+		return nil, fmt.Errorf("expected the default version, got %s", aws.ToString(params.VersionId))
+	}
+	doc := url.PathEscape(f.managed[aws.ToString(params.PolicyArn)])
+	return &iam.GetPolicyVersionOutput{PolicyVersion: &iamtypes.PolicyVersion{Document: aws.String(doc)}}, nil
+}
+
+const adminPolicyARN = "arn:aws:iam::aws:policy/AdministratorAccess"
+
+func iamTestService() (*DefaultSecurityService, *fakeIAM) {
+	fake := &fakeIAM{
+		managed: map[string]string{
+			adminPolicyARN: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"*","Resource":"*"}]}`,
+			"arn:aws:iam::123456789012:policy/logs-read": `{"Statement":{"Effect":"Allow","Action":["s3:GetObject","s3:ListBucket"],"Resource":["arn:aws:s3:::audit-logs","arn:aws:s3:::audit-logs/*"]}}`,
+		},
+		attached: map[string][]string{
+			"group/admins": {adminPolicyARN},
+			"role/deploy":  {"arn:aws:iam::123456789012:policy/logs-read"},
+		},
+		inline: map[string]map[string]string{
+			"role/deploy": {
+				"deploy": `{"Statement":[
+					{"Effect":"Allow","Action":["iam:PassRole","ec2:RunInstances"],"Resource":"*"},
+					{"Effect":"Allow","Action":"s3:*","Resource":"arn:aws:s3:::artifacts/*"},
+					{"Effect":"Allow","Action":"kms:*","Resource":"*","Condition":{"StringEquals":{"aws:RequestedRegion":"eu-west-1"}}},
+					{"Effect":"Deny","NotAction":"s3:*","Resource":"*"}
+				]}`,
+			},
+			"user/alice": {"tags": `{"Statement":[{"Effect":"Allow","Action":"ec2:Describe*","Resource":"*"}]}`},
+		},
+		groups: map[string][]string{"user/alice": {"admins"}},
+	}
+	service := &DefaultSecurityService{newIAMClient: func(ctx context.Context) (iamAPI, error) { return fake, nil }}
+	return service, fake
+}
+
+func TestParseIAMTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		want   iamTarget
+	}{
+		{"role/deploy", iamTarget{Kind: "role", Name: "deploy"}},
+		{"arn:aws:iam::123456789012:user/ops/alice", iamTarget{Kind: "user", Name: "alice"}},
+		{"arn:aws:sts::123456789012:assumed-role/deploy/ci-run", iamTarget{Kind: "role", Name: "deploy"}},
+		{adminPolicyARN, iamTarget{Kind: "policy", ARN: adminPolicyARN}},
+		{"arn:aws:s3:::audit-logs", iamTarget{Kind: "resource", ARN: "arn:aws:s3:::audit-logs"}},
+	}
+	for _, tt := range tests {
+		got, err := parseIAMTarget(tt.target)
+		if err != nil || got != tt.want {
+			t.Errorf("parseIAMTarget(%q) = %+v, %v; expected %+v", tt.target, got, err, tt.want)
+		}
+	}
+
+	for _, target := range []string{"", "vm/web-1", "s3://audit-logs", "arn:aws:iam"} {
+		if _, err := parseIAMTarget(target); err == nil {
+			t.Errorf("expected %q to be rejected", target)
+		}
+	}
+}
+
+func TestAuditPermissionsRole(t *testing.T) {
+	service, _ := iamTestService()
+
+	result, err := service.AuditPermissions(context.Background(), "arn:aws:iam::123456789012:role/deploy")
+	if err != nil {
+		t.Fatalf("AuditPermissions() failed: %v", err)
+	}
+	if len(result.Permissions) != 5 || result.Summary.TotalPermissions != 5 {
+		t.Fatalf("expected a permission per statement, got %+v", result.Permissions)
+	}
+	if p := result.Permissions[0]; p.Principal != "role/deploy" || p.Policy != "logs-read" || p.Effect != "allow" || len(p.Actions) != 2 {
+		t.Errorf("unexpected managed policy permission %+v", p)
+	}
+	if deny := result.Permissions[4]; deny.Effect != "deny" || deny.Actions[0] != "not s3:*" {
+		t.Errorf("unexpected deny permission %+v", deny)
+	}
+	if kms := result.Permissions[3]; kms.Conditions["StringEquals:aws:RequestedRegion"] != "eu-west-1" {
+		t.Errorf("expected conditions to be kept, got %+v", kms.Conditions)
+	}
+
+	issues := map[string]string{}
+	for _, issue := range result.Issues {
+		issues[issue.Type+" "+issue.Resource] = issue.Severity
+	}
+	want := map[string]string{
+		"privilege_escalation *":                    "high",
+		"service_wildcard arn:aws:s3:::artifacts/*": "low",
+		"service_wildcard *":                        "medium",
+	}
+	for key, severity := range want {
+		if issues[key] != severity {
+			t.Errorf("expected %s to be %s, got issues %v", key, severity, issues)
+		}
+	}
+	if len(result.Issues) != len(want) {
+		t.Errorf("expected %d issues, got %+v", len(want), result.Issues)
+	}
+	if result.Summary.HighIssues != 1 || result.Summary.MediumIssues != 1 || result.Summary.LowIssues != 1 {
+		t.Errorf("unexpected summary %+v", result.Summary)
+	}
+	if result.Issues[0].Severity != "high" {
+		t.Errorf("expected the most severe issue first, got %+v", result.Issues[0])
+	}
+}
+
+func TestAuditPermissionsUserGroups(t *testing.T) {
+	service, _ := iamTestService()
+
+	result, err := service.AuditPermissions(context.Background(), "user/alice")
+	if err != nil {
+		t.Fatalf("AuditPermissions() failed: %v", err)
+	}
+	if result.Summary.CriticalIssues != 1 || result.Issues[0].Type != "administrator_access" || result.Issues[0].Principal != "user/alice via group/admins" {
+		t.Errorf("expected administrator access through the admins group, got %+v", result.Issues)
+	}
+	if result.Summary.LowIssues != 1 {
+		t.Errorf("expected the read-only wildcard resource to be low, got %+v", result.Summary)
+	}
+}
+
+func TestAuditPermissionsResource(t *testing.T) {
+	service, _ := iamTestService()
+
+	result, err := service.AuditPermissions(context.Background(), "arn:aws:s3:::audit-logs")
+	if err != nil {
+		t.Fatalf("AuditPermissions() failed: %v", err)
+	}
+	principals := map[string]bool{}
+	for _, permission := range result.Permissions {
+		principals[permission.Principal] = true
+	}
+	// The artifacts bucket statement does not cover audit-logs
+	for _, permission := range result.Permissions {
+		if strings.Contains(permission.Resource, "artifacts") {
+			t.Errorf("expected statements on other buckets to be skipped, got %+v", permission)
+		}
+	}
+	if !principals["role/deploy"] || !principals["group/admins"] || !principals["user/alice"] {
+		t.Errorf("expected everyone granted access to audit-logs, got %v", principals)
+	}
+}
+
+func TestAuditPermissionsErrors(t *testing.T) {
+	service, fake := iamTestService()
+
+	fake.err = &smithy.GenericAPIError{Code: "AccessDenied", Message: "not authorized to perform iam:ListAttachedRolePolicies"}
+	_, err := service.AuditPermissions(context.Background(), "role/deploy")
+	if err == nil || !strings.Contains(err.Error(), "IAMReadOnlyAccess") {
+		t.Errorf("expected a hint about IAM read permissions, got %v", err)
+	}
+
+	fake.err = nil
+	_, err = service.AuditPermissions(context.Background(), "arn:aws:iam::123456789012:policy/missing")
+	if err == nil || !strings.Contains(err.Error(), "was not found in IAM") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+// validationTypes returns the issue and warning types of a policy validation
+func validationTypes(v PolicyValidation) (issues, warnings []string) {
+	for _, issue := range v.Issues {
+		issues = append(issues, issue.Type)
+	}
+	for _, warning := range v.Warnings {
+		warnings = append(warnings, warning.Type)
+	}
+	return issues, warnings
+}
+
+func TestValidateSecurityPoliciesMalformedRule(t *testing.T) {
+	service := &DefaultSecurityService{}
+	policies := []Policy{
+		{
+			ID: "malformed",
+			Rules: []PolicyRule{
+				{ID: "bad-condition", Condition: "user.role ~= admin", Action: "deny", Enabled: true},
+				{ID: "bad-action", Condition: "user.role == guest", Action: "explode", Enabled: true},
+				{ID: "no-severity", Condition: "event.type == login", Action: "alert", Enabled: true},
+				{ID: "bad-severity", Condition: "event.type == logout", Action: "alert", Parameters: map[string]string{"severity": "urgent", "colour": "red"}, Enabled: true},
+				{Condition: "resource.public == true and resource.public == false", Action: "log", Enabled: true},
+			},
+		},
+		{
+			ID:    "valid",
+			Rules: []PolicyRule{{ID: "mfa", Condition: `user.group == "domain admins" and mfa not_exists`, Action: "require_mfa", Parameters: map[string]string{"max_age": "12h"}, Enabled: true}},
+		},
+	}
+
+	result, err := service.ValidateSecurityPolicies(context.Background(), policies)
+	if err != nil {
+		t.Fatalf("ValidateSecurityPolicies() failed: %v", err)
+	}
+
+	malformed := result.Policies[0]
+	if malformed.Status != "invalid" {
+		t.Errorf("expected the malformed policy to be invalid, got %s", malformed.Status)
+	}
+	issues, warnings := validationTypes(malformed)
+	wantIssues := []string{"invalid_condition", "unknown_action", "missing_parameter", "invalid_parameter", "missing_rule_id"}
+	if strings.Join(issues, ",") != strings.Join(wantIssues, ",") {
+		t.Errorf("expected issues %v, got %v", wantIssues, issues)
+	}
+	wantWarnings := []string{"unknown_parameter", "unsatisfiable_condition"}
+	if strings.Join(warnings, ",") != strings.Join(wantWarnings, ",") {
+		t.Errorf("expected warnings %v, got %v", wantWarnings, warnings)
+	}
+	if malformed.Issues[0].RuleID != "bad-condition" || malformed.Issues[4].RuleID != "rules[4]" {
+		t.Errorf("unexpected rule ids in %+v", malformed.Issues)
+	}
+
+	if valid := result.Policies[1]; valid.Status != "valid" || len(valid.Issues) != 0 || len(valid.Warnings) != 0 {
+		t.Errorf("expected the second policy to be valid, got %+v", valid)
+	}
+	want := ValidationSummary{TotalPolicies: 2, ValidPolicies: 1, InvalidPolicies: 1, TotalIssues: 5, TotalWarnings: 2}
+	if result.Summary != want {
+		t.Errorf("expected summary %+v, got %+v", want, result.Summary)
+	}
+}
+
+func TestValidateSecurityPoliciesRuleConflicts(t *testing.T) {
+	result := validatePolicies([]Policy{{
+		ID: "access",
+		Rules: []PolicyRule{
+			{ID: "allow-admins", Condition: "user.role == admin and network.zone == internal", Action: "allow", Enabled: true},
+			// The same clauses in another order still conflict
+			{ID: "deny-admins", Condition: "network.zone == internal AND user.role == admin", Action: "deny", Enabled: true},
+			{ID: "deny-internal", Condition: "network.zone == internal", Action: "deny", Enabled: true},
+			{ID: "allow-admins-office", Condition: "user.role == admin and network.zone == internal and office == hq", Action: "allow", Enabled: true},
+			{ID: "log-all", Condition: "network.zone == internal", Action: "log", Enabled: true},
+			{ID: "log-again", Condition: "network.zone == internal", Action: "log", Enabled: true},
+			// Disabled rules cannot conflict
+			{ID: "disabled", Condition: "user.role == admin", Action: "allow", Enabled: false},
+		},
+	}})
+
+	policy := result.Policies[0]
+	issues, warnings := validationTypes(policy)
+	if strings.Join(issues, ",") != "conflicting_rules" || policy.Issues[0].RuleID != "deny-admins" {
+		t.Errorf("expected deny-admins to conflict with allow-admins, got %+v", policy.Issues)
+	}
+	wantWarnings := []string{"overlapping_rules", "redundant_rule", "redundant_rule", "overlapping_rules", "overlapping_rules", "duplicate_rule"}
+	if strings.Join(warnings, ",") != strings.Join(wantWarnings, ",") {
+		t.Errorf("expected warnings %v, got %v", wantWarnings, warnings)
+	}
+	if policy.Status != "invalid" || result.Summary.InvalidPolicies != 1 || result.Summary.TotalWarnings != 6 {
+		t.Errorf("unexpected result %+v", result.Summary)
+	}
+}
+
+func TestValidatePolicyFile(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "policies.yaml")
+	yamlPolicies := `policies:
+  - id: ssh
+    name: SSH access
+    rules:
+      - id: block-root
+        condition: user == root and service == ssh
+        action: deny
+      - id: alert-root
+        condition: user == root
+        action: alert
+        parameters:
+          severity: high
+      - id: old
+        condition: user == guest
+        action: notify
+        parameters:
+          channel: pagerduty
+        enabled: false
+`
+	if err := os.WriteFile(yamlPath, []byte(yamlPolicies), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := ValidatePolicyFile(yamlPath)
+	if err != nil {
+		t.Fatalf("ValidatePolicyFile() failed: %v", err)
+	}
+	if result.Summary.TotalPolicies != 1 || result.Summary.ValidPolicies != 1 {
+		t.Errorf("expected one valid policy, got %+v", result.Policies)
+	}
+
+	policies, err := LoadPolicyFile(yamlPath)
+	if err != nil {
+		t.Fatalf("LoadPolicyFile() failed: %v", err)
+	}
+	if !policies[0].Rules[0].Enabled || policies[0].Rules[2].Enabled {
+		t.Errorf("expected rules to be enabled unless disabled, got %+v", policies[0].Rules)
+	}
+
+	jsonPath := filepath.Join(dir, "policies.json")
+	jsonPolicies := `[{"id": "a", "rules": [{"id": "r", "condition": "x == 1", "action": "quarantine"}]}, {"id": "b", "rules": []}]`
+	if err := os.WriteFile(jsonPath, []byte(jsonPolicies), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err = ValidatePolicyFile(jsonPath)
+	if err != nil {
+		t.Fatalf("ValidatePolicyFile() of JSON failed: %v", err)
+	}
+	want := ValidationSummary{TotalPolicies: 2, ValidPolicies: 1, InvalidPolicies: 1, TotalIssues: 1, TotalWarnings: 1}
+	if result.Summary != want {
+		t.Errorf("expected summary %+v, got %+v", want, result.Summary)
+	}
+
+	singlePath := filepath.Join(dir, "single.yml")
+	if err := os.WriteFile(singlePath, []byte("id: one\nrules:\n  - id: r\n    action: allow\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policies, err = LoadPolicyFile(singlePath)
+	if err != nil || len(policies) != 1 || policies[0].ID != "one" {
+		t.Errorf("expected a single policy, got %+v and %v", policies, err)
+	}
+
+	badPath := filepath.Join(dir, "bad.yaml")
+	if err := os.WriteFile(badPath, []byte("policies: [unclosed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidatePolicyFile(badPath); err == nil || !strings.Contains(err.Error(), "failed to parse policy file") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+	if _, err := ValidatePolicyFile(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+// writeRego writes a Rego policy to dir
+func writeRego(t *testing.T, dir, name, src string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEvaluateRegoPolicies(t *testing.T) {
+	dir := t.TempDir()
+	writeRego(t, dir, "s3.rego", `package aws.s3
+
+deny contains msg if {
+	input.type == "s3_bucket"
+	input.config.public
+	msg := sprintf("bucket %s is public", [input.name])
+}
+
+deny contains {"msg": "bucket is not encrypted", "severity": "critical"} if {
+	input.type == "s3_bucket"
+	not input.config.encrypted
+}
+
+warn contains "bucket has no owner tag" if {
+	input.type == "s3_bucket"
+	not input.tags.owner
+}
+`)
+	writeRego(t, dir, "tags.rego", `package tags
+
+deny contains "missing environment tag" if not input.tags.environment
+`)
+	// Packages without deny or warn rules and tests are not reported
+	writeRego(t, dir, "lib.rego", "package lib\n\npublic(r) if r.config.public\n")
+	writeRego(t, dir, "s3_test.rego", "package aws.s3\n\ntest_public if deny with input as {}\n")
+
+	inputs := []interface{}{
+		map[string]interface{}{"id": "logs", "name": "logs", "type": "s3_bucket", "config": map[string]interface{}{"public": true}, "tags": map[string]interface{}{"environment": "prod"}},
+		map[string]interface{}{"id": "i-123", "type": "ec2_instance", "tags": map[string]interface{}{"owner": "ops"}},
+		map[string]interface{}{"name": "assets", "type": "s3_bucket", "config": map[string]interface{}{"encrypted": true}, "tags": map[string]interface{}{"environment": "dev", "owner": "web"}},
+	}
+
+	result, err := EvaluateRegoPolicies(context.Background(), RegoOptions{Paths: []string{dir}}, inputs)
+	if err != nil {
+		t.Fatalf("EvaluateRegoPolicies() failed: %v", err)
+	}
+	if len(result.Policies) != 2 || result.Policies[0].PolicyID != "aws.s3" || result.Policies[1].PolicyID != "tags" {
+		t.Fatalf("expected the aws.s3 and tags policies, got %+v", result.Policies)
+	}
+
+	s3 := result.Policies[0]
+	var descriptions []string
+	for _, issue := range s3.Issues {
+		descriptions = append(descriptions, issue.Severity+" "+issue.Description)
+	}
+	want := []string{"critical s3_bucket/logs: bucket is not encrypted", "high s3_bucket/logs: bucket logs is public"}
+	if strings.Join(descriptions, "|") != strings.Join(want, "|") || s3.Issues[0].RuleID != "data.aws.s3.deny" {
+		t.Errorf("expected issues %v, got %+v", want, s3.Issues)
+	}
+	if len(s3.Warnings) != 1 || s3.Warnings[0].Description != "s3_bucket/logs: bucket has no owner tag" {
+		t.Errorf("unexpected warnings %+v", s3.Warnings)
+	}
+
+	tags := result.Policies[1]
+	if len(tags.Issues) != 1 || tags.Issues[0].Description != "ec2_instance/i-123: missing environment tag" {
+		t.Errorf("unexpected tag issues %+v", tags.Issues)
+	}
+	summary := ValidationSummary{TotalPolicies: 2, InvalidPolicies: 2, TotalIssues: 3, TotalWarnings: 1}
+	if result.Summary != summary {
+		t.Errorf("expected summary %+v, got %+v", summary, result.Summary)
+	}
+
+	// Inputs that satisfy every rule leave the policies valid
+	result, err = EvaluateRegoPolicies(context.Background(), RegoOptions{Paths: []string{filepath.Join(dir, "tags.rego")}}, inputs[2:])
+	if err != nil || result.Summary.ValidPolicies != 1 || len(result.Policies[0].Issues) != 0 {
+		t.Errorf("expected a valid policy, got %+v and %v", result, err)
+	}
+}
+
+func TestEvaluateRegoPoliciesCompileErrors(t *testing.T) {
+	dir := t.TempDir()
+	broken := writeRego(t, dir, "broken.rego", "package broken\n\ndeny contains msg if {\n\tmsg := \n}\n")
+	undefined := writeRego(t, dir, "undefined.rego", "package undefined\n\ndeny contains msg if {\n\tmsg := missing_function(input)\n}\n")
+
+	result, err := EvaluateRegoPolicies(context.Background(), RegoOptions{Paths: []string{broken}}, []interface{}{map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("EvaluateRegoPolicies() failed: %v", err)
+	}
+	if len(result.Policies) != 1 || result.Policies[0].Status != "invalid" || result.Policies[0].PolicyID != broken {
+		t.Fatalf("expected the broken file to be invalid, got %+v", result.Policies)
+	}
+	issue := result.Policies[0].Issues[0]
+	if issue.Type != "compile_error" || !strings.HasPrefix(issue.Description, broken+":5:1: unexpected }") {
+		t.Errorf("expected a parse error at line 5, got %+v", issue)
+	}
+
+	result, err = EvaluateRegoPolicies(context.Background(), RegoOptions{Paths: []string{undefined}}, nil)
+	if err != nil {
+		t.Fatalf("EvaluateRegoPolicies() failed: %v", err)
+	}
+	issue = result.Policies[0].Issues[0]
+	if !strings.HasPrefix(issue.Description, undefined+":4:") || !strings.Contains(issue.Description, "missing_function") {
+		t.Errorf("expected an undefined function error on line 4, got %+v", issue)
+	}
+
+	// Rego v0 policies need V0Compatible
+	legacy := writeRego(t, t.TempDir(), "legacy.rego", "package legacy\n\ndeny[msg] {\n\tinput.public\n\tmsg := \"public\"\n}\n")
+	result, err = EvaluateRegoPolicies(context.Background(), RegoOptions{Paths: []string{legacy}}, nil)
+	if err != nil || result.Summary.InvalidPolicies != 1 || result.Policies[0].Issues[0].Type != "compile_error" {
+		t.Errorf("expected v0 syntax to fail to compile, got %+v and %v", result, err)
+	}
+	result, err = EvaluateRegoPolicies(context.Background(), RegoOptions{Paths: []string{legacy}, V0Compatible: true}, []interface{}{map[string]interface{}{"public": true}})
+	if err != nil || len(result.Policies) != 1 || len(result.Policies[0].Issues) != 1 || result.Policies[0].Issues[0].Description != "input[0]: public" {
+		t.Errorf("expected the v0 policy to deny, got %+v and %v", result, err)
+	}
+
+	if _, err := EvaluateRegoPolicies(context.Background(), RegoOptions{Paths: []string{t.TempDir()}}, nil); err == nil || !strings.Contains(err.Error(), "no .rego policies") {
+		t.Errorf("expected an error without policies, got %v", err)
+	}
+}
+
+func TestLoadRegoInputs(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "resources.json")
+	if err := os.WriteFile(list, []byte(`[{"id": "a"}, {"id": "b"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	single := filepath.Join(dir, "resource.json")
+	if err := os.WriteFile(single, []byte(`{"id": "a"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if inputs, err := LoadRegoInputs(list); err != nil || len(inputs) != 2 {
+		t.Errorf("expected two inputs, got %v and %v", inputs, err)
+	}
+	if inputs, err := LoadRegoInputs(single); err != nil || len(inputs) != 1 {
+		t.Errorf("expected one input, got %v and %v", inputs, err)
+	}
+	if err := os.WriteFile(single, []byte(`{"id":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRegoInputs(single); err == nil || !strings.Contains(err.Error(), "failed to parse input") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+}
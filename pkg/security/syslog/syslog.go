@@ -0,0 +1,583 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/security"
+	"github.com/sirupsen/logrus"
+)
+
+// maxMessageSize bounds a syslog message; larger TCP frames are dropped
+// along with their connection
+const maxMessageSize = 64 * 1024
+
+// TCP connection limits, so that idle or slow senders cannot pile up
+const (
+	// maxConnections is how many TCP connections are read at once; further
+	// ones wait to be accepted until another closes
+	maxConnections = 128
+	// idleTimeout is how long a TCP connection may take to send each frame
+	// before it is closed
+	idleTimeout = 5 * time.Minute
+)
+
+// nilValue marks an RFC 5424 header field or structured data as absent
+const nilValue = "-"
+
+// Message is a parsed RFC 5424 syslog message
+type Message struct {
+	Facility  int       `json:"facility"`
+	Severity  int       `json:"severity"`
+	Timestamp time.Time `json:"timestamp"`
+	Hostname  string    `json:"hostname,omitempty"`
+	AppName   string    `json:"app_name,omitempty"`
+	ProcID    string    `json:"proc_id,omitempty"`
+	MsgID     string    `json:"msg_id,omitempty"`
+	// StructuredData maps each SD-ID to its parameters
+	StructuredData map[string]map[string]string `json:"structured_data,omitempty"`
+	Message        string                       `json:"message,omitempty"`
+}
+
+// Parse parses an RFC 5424 message, such as
+// <34>1 2026-10-11T22:14:15.003Z host sshd 4123 AUTH_FAIL [auth user="root"] Failed password
+func Parse(data []byte) (*Message, error) {
+	p := &parser{data: bytes.TrimRight(data, "\r\n\x00")}
+
+	pri, err := p.priority()
+	if err != nil {
+		return nil, err
+	}
+	if version, ok := p.field(); !ok || version != "1" {
+		return nil, fmt.Errorf("unsupported syslog version %q; only RFC 5424 messages are accepted", version)
+	}
+	msg := &Message{Facility: pri / 8, Severity: pri % 8}
+
+	timestamp, ok := p.field()
+	if !ok {
+		return nil, fmt.Errorf("syslog message ends before its timestamp")
+	}
+	if timestamp != nilValue {
+		msg.Timestamp, err = time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid syslog timestamp %q", timestamp)
+		}
+	}
+
+	for _, header := range []struct {
+		name   string
+		target *string
+		max    int
+	}{
+		{"hostname", &msg.Hostname, 255},
+		{"app name", &msg.AppName, 48},
+		{"proc ID", &msg.ProcID, 128},
+		{"message ID", &msg.MsgID, 32},
+	} {
+		value, ok := p.field()
+		if !ok {
+			return nil, fmt.Errorf("syslog message ends before its %s", header.name)
+		}
+		if len(value) > header.max {
+			return nil, fmt.Errorf("syslog %s is longer than %d characters", header.name, header.max)
+		}
+		if value != nilValue {
+			*header.target = value
+		}
+	}
+
+	if msg.StructuredData, err = p.structuredData(); err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.data) {
+		if p.data[p.pos] != ' ' {
+			return nil, fmt.Errorf("expected a space after the structured data at offset %d", p.pos)
+		}
+		msg.Message = strings.TrimPrefix(string(p.data[p.pos+1:]), "\ufeff")
+	}
+	return msg, nil
+}
+
+// parser walks the bytes of a syslog message
+type parser struct {
+	data []byte
+	pos  int
+}
+
+// priority parses the <PRI> that starts a message
+func (p *parser) priority() (int, error) {
+	if len(p.data) == 0 || p.data[0] != '<' {
+		return 0, fmt.Errorf("syslog message must start with <priority>")
+	}
+	end := bytes.IndexByte(p.data, '>')
+	if end < 2 || end > 4 {
+		return 0, fmt.Errorf("syslog priority must be 1 to 3 digits")
+	}
+	pri, err := strconv.Atoi(string(p.data[1:end]))
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, fmt.Errorf("invalid syslog priority %q", p.data[1:end])
+	}
+	p.pos = end + 1
+	return pri, nil
+}
+
+// field returns the next space-terminated header field
+func (p *parser) field() (string, bool) {
+	if p.pos >= len(p.data) {
+		return "", false
+	}
+	end := bytes.IndexByte(p.data[p.pos:], ' ')
+	if end < 0 {
+		end = len(p.data) - p.pos
+	}
+	value := string(p.data[p.pos : p.pos+end])
+	p.pos += end
+	if p.pos < len(p.data) {
+		p.pos++
+	}
+	return value, value != ""
+}
+
+// structuredData parses the [SD-ID param="value" ...] elements after the
+// header, or the nil value
+func (p *parser) structuredData() (map[string]map[string]string, error) {
+	if p.pos >= len(p.data) {
+		return nil, fmt.Errorf("syslog message ends before its structured data")
+	}
+	if p.data[p.pos] == '-' {
+		p.pos++
+		return nil, nil
+	}
+
+	elements := make(map[string]map[string]string)
+	for p.pos < len(p.data) && p.data[p.pos] == '[' {
+		p.pos++
+		id := p.name()
+		if id == "" {
+			return nil, fmt.Errorf("structured data element without an SD-ID at offset %d", p.pos)
+		}
+		params := make(map[string]string)
+		for p.pos < len(p.data) && p.data[p.pos] == ' ' {
+			p.pos++
+			name := p.name()
+			if name == "" || !p.consume('=') || !p.consume('"') {
+				return nil, fmt.Errorf("malformed parameter in structured data element %s", id)
+			}
+			value, err := p.paramValue()
+			if err != nil {
+				return nil, fmt.Errorf("malformed parameter %s in structured data element %s: %w", name, id, err)
+			}
+			params[name] = value
+		}
+		if !p.consume(']') {
+			return nil, fmt.Errorf("unterminated structured data element %s", id)
+		}
+		elements[id] = params
+	}
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("expected structured data or - at offset %d", p.pos)
+	}
+	return elements, nil
+}
+
+// name reads an SD-ID or parameter name
+func (p *parser) name() string {
+	start := p.pos
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if c <= ' ' || c > '~' || c == '=' || c == ']' || c == '"' {
+			break
+		}
+		p.pos++
+	}
+	return string(p.data[start:p.pos])
+}
+
+// paramValue reads a quoted parameter value up to its closing quote,
+// unescaping \", \\ and \]
+func (p *parser) paramValue() (string, error) {
+	var b strings.Builder
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch {
+		case c == '"':
+			return b.String(), nil
+		case c == '\\' && p.pos < len(p.data) && strings.IndexByte(`"\]`, p.data[p.pos]) >= 0:
+			b.WriteByte(p.data[p.pos])
+			p.pos++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("missing closing quote")
+}
+
+// consume skips c if it is next
+func (p *parser) consume(c byte) bool {
+	if p.pos < len(p.data) && p.data[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// eventSeverities maps syslog severities, emergency to debug, to the
+// severities of security events
+var eventSeverities = [8]string{"critical", "critical", "critical", "high", "medium", "low", "info", "info"}
+
+// userParams and ipParams are the structured data parameters, in order of
+// preference, that name the user and address an event concerns
+var (
+	userParams = []string{"user", "username", "uid", "account"}
+	ipParams   = []string{"ip", "src", "src_ip", "source_ip", "client_ip", "remote_addr"}
+)
+
+// SecurityEvent converts the message to a security event. Its type is the
+// message ID, or the app name when there is none
+func (m *Message) SecurityEvent() security.SecurityEvent {
+	timestamp := m.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	details := map[string]string{"facility": strconv.Itoa(m.Facility)}
+	for key, value := range map[string]string{"hostname": m.Hostname, "app_name": m.AppName, "proc_id": m.ProcID, "msg_id": m.MsgID} {
+		if value != "" {
+			details[key] = value
+		}
+	}
+	for id, params := range m.StructuredData {
+		for name, value := range params {
+			details[id+"."+name] = value
+		}
+	}
+	if user := m.param(userParams); user != "" {
+		details["user"] = user
+	}
+	if ip := m.param(ipParams); ip != "" {
+		details["ip"] = ip
+	}
+
+	eventType := m.MsgID
+	if eventType == "" {
+		eventType = m.AppName
+	}
+	if eventType == "" {
+		eventType = "syslog"
+	}
+	source := m.AppName
+	if m.Hostname != "" && source != "" {
+		source = m.Hostname + "/" + source
+	} else if m.Hostname != "" {
+		source = m.Hostname
+	}
+
+	return security.SecurityEvent{
+		ID:          fmt.Sprintf("syslog-%d", time.Now().UnixNano()),
+		Type:        strings.ToLower(eventType),
+		Timestamp:   timestamp,
+		Source:      source,
+		Severity:    eventSeverities[m.Severity&7],
+		Description: m.Message,
+		Details:     details,
+		Actions:     []string{"received"},
+	}
+}
+
+// param returns the first of names set in any structured data element
+func (m *Message) param(names []string) string {
+	for _, name := range names {
+		for _, params := range m.StructuredData {
+			if value := params[name]; value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// Stats counts the messages a source has read
+type Stats struct {
+	Received int64 `json:"received"`
+	// Dropped counts the received messages and TCP frames that were malformed
+	Dropped int64 `json:"dropped"`
+}
+
+// Source is a security event source listening for RFC 5424 syslog over UDP,
+// with one message per datagram, and over TCP, framed by octet counts or
+// newlines as in RFC 6587
+type Source struct {
+	address  string
+	protocol string
+	// maxConnections and idleTimeout limit TCP connections
+	maxConnections int
+	idleTimeout    time.Duration
+
+	received atomic.Int64
+	dropped  atomic.Int64
+
+	mu    sync.Mutex
+	addrs map[string]net.Addr
+}
+
+// NewSource creates a syslog source listening on cfg.Address
+func NewSource(cfg config.SyslogConfig) *Source {
+	protocol := cfg.Protocol
+	if protocol == "" {
+		protocol = "udp"
+	}
+	return &Source{
+		address:        cfg.Address,
+		protocol:       protocol,
+		maxConnections: maxConnections,
+		idleTimeout:    idleTimeout,
+		addrs:          make(map[string]net.Addr),
+	}
+}
+
+// Name implements security.EventSource
+func (s *Source) Name() string {
+	return "syslog"
+}
+
+// Protocol returns the transports listened on: udp, tcp or both
+func (s *Source) Protocol() string {
+	return s.protocol
+}
+
+// Stats returns how many messages were received and dropped so far
+func (s *Source) Stats() Stats {
+	return Stats{Received: s.received.Load(), Dropped: s.dropped.Load()}
+}
+
+// Addr returns the address listened on for network, udp or tcp, once
+// Events has started, or nil. It tells which port was picked for port 0
+func (s *Source) Addr(network string) net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addrs[network]
+}
+
+// Events implements security.EventSource. It listens until ctx is cancelled
+func (s *Source) Events(ctx context.Context) (<-chan security.SecurityEvent, error) {
+	if s.address == "" {
+		return nil, fmt.Errorf("syslog listen address is required")
+	}
+
+	var packetConn net.PacketConn
+	var listener net.Listener
+	closeAll := func() {
+		if packetConn != nil {
+			packetConn.Close()
+		}
+		if listener != nil {
+			listener.Close()
+		}
+	}
+
+	var lc net.ListenConfig
+	var err error
+	if s.protocol == "udp" || s.protocol == "both" {
+		if packetConn, err = lc.ListenPacket(ctx, "udp", s.address); err != nil {
+			return nil, fmt.Errorf("failed to listen for syslog on udp %s: %w", s.address, err)
+		}
+		s.setAddr("udp", packetConn.LocalAddr())
+	}
+	if s.protocol == "tcp" || s.protocol == "both" {
+		if listener, err = lc.Listen(ctx, "tcp", s.address); err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to listen for syslog on tcp %s: %w", s.address, err)
+		}
+		s.setAddr("tcp", listener.Addr())
+	}
+	if packetConn == nil && listener == nil {
+		return nil, fmt.Errorf("unknown syslog protocol %q; use udp, tcp or both", s.protocol)
+	}
+
+	events := make(chan security.SecurityEvent, 100)
+	var wg sync.WaitGroup
+	if packetConn != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.servePackets(ctx, packetConn, events)
+		}()
+	}
+	if listener != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveStreams(ctx, listener, events, &wg)
+		}()
+	}
+	go func() {
+		<-ctx.Done()
+		closeAll()
+		wg.Wait()
+		close(events)
+	}()
+	return events, nil
+}
+
+// setAddr records the address listened on for network
+func (s *Source) setAddr(network string, addr net.Addr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addrs[network] = addr
+}
+
+// servePackets reads one message per datagram until conn is closed
+func (s *Source) servePackets(ctx context.Context, conn net.PacketConn, events chan<- security.SecurityEvent) {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				logrus.Warnf("Stopped reading syslog over udp: %v", err)
+			}
+			return
+		}
+		if !s.handle(ctx, buf[:n], events) {
+			return
+		}
+	}
+}
+
+// serveStreams accepts TCP connections until listener is closed, reading
+// each on its own goroutine tracked by wg. At most s.maxConnections are
+// read at once
+func (s *Source) serveStreams(ctx context.Context, listener net.Listener, events chan<- security.SecurityEvent, wg *sync.WaitGroup) {
+	slots := make(chan struct{}, s.maxConnections)
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				logrus.Warnf("Stopped accepting syslog over tcp: %v", err)
+			}
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer conn.Close()
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
+			s.serveStream(ctx, conn, events)
+		}()
+	}
+}
+
+// serveStream reads the messages framed on a TCP connection until it is
+// closed, or a frame takes longer than s.idleTimeout to arrive. A frame that
+// cannot be read loses the framing, so the connection is dropped
+func (s *Source) serveStream(ctx context.Context, conn net.Conn, events chan<- security.SecurityEvent) {
+	// Newline framed messages must fit the buffer along with their newline
+	r := bufio.NewReaderSize(conn, maxMessageSize+1)
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(s.idleTimeout)); err != nil {
+			return
+		}
+		frame, err := readFrame(r)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			logrus.Debugf("Closing idle syslog connection from %s", conn.RemoteAddr())
+			return
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				s.received.Add(1)
+				s.dropped.Add(1)
+				logrus.Debugf("Dropping syslog connection from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		if len(bytes.TrimSpace(frame)) == 0 {
+			continue
+		}
+		if !s.handle(ctx, frame, events) {
+			return
+		}
+	}
+}
+
+// readFrame reads a message framed by its octet count, such as
+// "57 <34>1 ...", or terminated by a newline
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if first[0] < '0' || first[0] > '9' {
+		line, err := r.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return nil, fmt.Errorf("syslog message is longer than %d bytes without a newline", maxMessageSize)
+		}
+		if err != nil && !(errors.Is(err, io.EOF) && len(line) > 0) {
+			return nil, err
+		}
+		return line, nil
+	}
+
+	// Read no more digits than a valid length has, so a sender cannot make
+	// the length itself take up memory
+	maxDigits := len(strconv.Itoa(maxMessageSize))
+	var count []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read syslog frame length: %w", err)
+		}
+		if c == ' ' {
+			break
+		}
+		if c < '0' || c > '9' || len(count) == maxDigits {
+			return nil, fmt.Errorf("invalid syslog frame length %q", append(count, c))
+		}
+		count = append(count, c)
+	}
+	n, err := strconv.Atoi(string(count))
+	if err != nil || n <= 0 || n > maxMessageSize {
+		return nil, fmt.Errorf("invalid syslog frame length %q", count)
+	}
+	frame := make([]byte, n)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, fmt.Errorf("failed to read syslog frame: %w", err)
+	}
+	return frame, nil
+}
+
+// handle parses a message and sends its event, counting and dropping
+// malformed messages. It returns false once ctx is cancelled
+func (s *Source) handle(ctx context.Context, data []byte, events chan<- security.SecurityEvent) bool {
+	s.received.Add(1)
+	msg, err := Parse(data)
+	if err != nil {
+		s.dropped.Add(1)
+		logrus.Debugf("Dropping malformed syslog message: %v", err)
+		return true
+	}
+	select {
+	case events <- msg.SecurityEvent():
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
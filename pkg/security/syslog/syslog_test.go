@@ -0,0 +1,290 @@
+package syslog
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/security"
+)
+
+const authFailure = `<34>1 2026-10-11T22:14:15.003Z bastion-1 sshd 4123 AUTH_FAIL [auth@32473 user="root" src="203.0.113.9"][meta note="a \"quoted\" \] value"] Failed password for root`
+
+func TestParse(t *testing.T) {
+	msg, err := Parse([]byte(authFailure + "\n"))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if msg.Facility != 4 || msg.Severity != 2 {
+		t.Errorf("expected facility 4 and severity 2, got %d and %d", msg.Facility, msg.Severity)
+	}
+	if !msg.Timestamp.Equal(time.Date(2026, 10, 11, 22, 14, 15, 3000000, time.UTC)) {
+		t.Errorf("unexpected timestamp %s", msg.Timestamp)
+	}
+	if msg.Hostname != "bastion-1" || msg.AppName != "sshd" || msg.ProcID != "4123" || msg.MsgID != "AUTH_FAIL" {
+		t.Errorf("unexpected header %+v", msg)
+	}
+	if msg.StructuredData["auth@32473"]["user"] != "root" || msg.StructuredData["meta"]["note"] != `a "quoted" ] value` {
+		t.Errorf("unexpected structured data %+v", msg.StructuredData)
+	}
+	if msg.Message != "Failed password for root" {
+		t.Errorf("unexpected message %q", msg.Message)
+	}
+
+	msg, err = Parse([]byte("<165>1 - - - - - -\n"))
+	if err != nil {
+		t.Fatalf("Parse() of nil fields failed: %v", err)
+	}
+	if msg.Hostname != "" || msg.StructuredData != nil || msg.Message != "" || !msg.Timestamp.IsZero() {
+		t.Errorf("expected nil values to be empty, got %+v", msg)
+	}
+
+	msg, err = Parse([]byte("<13>1 2026-10-11T22:14:15+02:00 host app - - - \ufeffhello"))
+	if err != nil {
+		t.Fatalf("Parse() with a BOM failed: %v", err)
+	}
+	if msg.Message != "hello" {
+		t.Errorf("expected the BOM to be stripped, got %q", msg.Message)
+	}
+
+	for _, malformed := range []string{
+		"",
+		"hello world",
+		"<34>Oct 11 22:14:15 host sshd: BSD-style message",
+		"<999>1 - - - - - -",
+		"<34>2 - - - - - -",
+		"<34>1 yesterday host app - - -",
+		"<34>1 - host app",
+		`<34>1 - host app - - [auth user="root"`,
+		`<34>1 - host app - - [auth user=root]`,
+		"<34>1 - host app - - -trailing",
+	} {
+		if _, err := Parse([]byte(malformed)); err == nil {
+			t.Errorf("expected %q to be rejected", malformed)
+		}
+	}
+}
+
+func TestMessageSecurityEvent(t *testing.T) {
+	msg, err := Parse([]byte(authFailure))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	event := msg.SecurityEvent()
+	if event.Type != "auth_fail" || event.Severity != "critical" || event.Source != "bastion-1/sshd" {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if event.Details["user"] != "root" || event.Details["ip"] != "203.0.113.9" || event.Details["auth@32473.src"] != "203.0.113.9" {
+		t.Errorf("unexpected details %+v", event.Details)
+	}
+	if event.Description != "Failed password for root" {
+		t.Errorf("unexpected description %q", event.Description)
+	}
+
+	for severity, want := range map[int]string{3: "high", 4: "medium", 5: "low", 6: "info", 7: "info"} {
+		msg := &Message{Severity: severity, AppName: "kernel"}
+		if got := msg.SecurityEvent(); got.Severity != want || got.Type != "kernel" {
+			t.Errorf("syslog severity %d: expected a %s kernel event, got %+v", severity, want, got)
+		}
+	}
+}
+
+// receive reads n events or fails after a timeout
+func receive(t *testing.T, events <-chan security.SecurityEvent, n int) []security.SecurityEvent {
+	t.Helper()
+	var got []security.SecurityEvent
+	timeout := time.After(5 * time.Second)
+	for len(got) < n {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("events closed after %d of %d events", len(got), n)
+			}
+			got = append(got, event)
+		case <-timeout:
+			t.Fatalf("timed out after %d of %d events", len(got), n)
+		}
+	}
+	return got
+}
+
+func TestSourceUDP(t *testing.T) {
+	source := NewSource(config.SyslogConfig{Address: "127.0.0.1:0"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := source.Events(ctx)
+	if err != nil {
+		t.Fatalf("Events() failed: %v", err)
+	}
+	conn, err := net.Dial("udp", source.Addr("udp").String())
+	if err != nil {
+		t.Fatalf("failed to dial the source: %v", err)
+	}
+	defer conn.Close()
+
+	for _, message := range []string{"not syslog", authFailure, "<34>1 broken", "<14>1 - web-1 nginx - LOGIN - user logged in"} {
+		if _, err := conn.Write([]byte(message)); err != nil {
+			t.Fatalf("failed to send %q: %v", message, err)
+		}
+	}
+
+	got := receive(t, events, 2)
+	if got[0].Type != "auth_fail" || got[1].Type != "login" || got[1].Severity != "info" {
+		t.Errorf("unexpected events %+v", got)
+	}
+	if stats := source.Stats(); stats.Received != 4 || stats.Dropped != 2 {
+		t.Errorf("expected 4 messages with 2 dropped, got %+v", stats)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestSourceTCP(t *testing.T) {
+	source := NewSource(config.SyslogConfig{Address: "127.0.0.1:0", Protocol: "both"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := source.Events(ctx)
+	if err != nil {
+		t.Fatalf("Events() failed: %v", err)
+	}
+	if source.Addr("udp") == nil {
+		t.Error("expected both to listen on udp too")
+	}
+
+	conn, err := net.Dial("tcp", source.Addr("tcp").String())
+	if err != nil {
+		t.Fatalf("failed to dial the source: %v", err)
+	}
+	// Octet counted and newline framed messages may be mixed on a connection
+	framed := fmt.Sprintf("%d %s", len(authFailure), authFailure)
+	stream := framed + "garbage line\n<86>1 - db-1 sudo - SUDO - session opened\n"
+	if _, err := conn.Write([]byte(stream)); err != nil {
+		t.Fatalf("failed to send messages: %v", err)
+	}
+
+	got := receive(t, events, 2)
+	if got[0].Type != "auth_fail" || got[1].Type != "sudo" || got[1].Source != "db-1/sudo" {
+		t.Errorf("unexpected events %+v", got)
+	}
+	if stats := source.Stats(); stats.Received != 3 || stats.Dropped != 1 {
+		t.Errorf("expected 3 messages with 1 dropped, got %+v", stats)
+	}
+
+	// A bad frame length loses the framing, so the connection is dropped
+	if _, err := conn.Write([]byte("99999999 <34>1 - - - - - -")); err != nil {
+		t.Fatalf("failed to send a bad frame: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil || strings.Contains(err.Error(), "timeout") {
+		t.Errorf("expected the connection to be closed, got %v", err)
+	}
+	conn.Close()
+
+	cancel()
+	for range events {
+	}
+	if stats := source.Stats(); stats.Received != 4 || stats.Dropped != 2 {
+		t.Errorf("expected the bad frame to be counted, got %+v", stats)
+	}
+}
+
+func TestSourceTCPFrameLimits(t *testing.T) {
+	source := NewSource(config.SyslogConfig{Address: "127.0.0.1:0", Protocol: "tcp"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := source.Events(ctx)
+	if err != nil {
+		t.Fatalf("Events() failed: %v", err)
+	}
+	dial := func() net.Conn {
+		t.Helper()
+		conn, err := net.Dial("tcp", source.Addr("tcp").String())
+		if err != nil {
+			t.Fatalf("failed to dial the source: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	// Newline framed messages may be as long as octet counted ones
+	long := authFailure + " " + strings.Repeat("x", 32*1024)
+	conn := dial()
+	if _, err := conn.Write([]byte(long + "\n")); err != nil {
+		t.Fatalf("failed to send a long message: %v", err)
+	}
+	if got := receive(t, events, 1); got[0].Type != "auth_fail" {
+		t.Errorf("expected the long message to be received, got %+v", got)
+	}
+
+	// A frame length that never ends is dropped after its first few digits
+	conn = dial()
+	if _, err := conn.Write([]byte(strings.Repeat("1", 64))); err != nil {
+		t.Fatalf("failed to send a long frame length: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil || strings.Contains(err.Error(), "timeout") {
+		t.Errorf("expected the connection to be closed, got %v", err)
+	}
+}
+
+func TestSourceTCPConnectionLimits(t *testing.T) {
+	source := NewSource(config.SyslogConfig{Address: "127.0.0.1:0", Protocol: "tcp"})
+	source.maxConnections, source.idleTimeout = 1, 200*time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := source.Events(ctx)
+	if err != nil {
+		t.Fatalf("Events() failed: %v", err)
+	}
+	dial := func() net.Conn {
+		t.Helper()
+		conn, err := net.Dial("tcp", source.Addr("tcp").String())
+		if err != nil {
+			t.Fatalf("failed to dial the source: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	// The idle connection holds the only slot until it times out
+	idle := dial()
+	start := time.Now()
+	waiting := dial()
+	if _, err := waiting.Write([]byte(authFailure + "\n")); err != nil {
+		t.Fatalf("failed to send a message: %v", err)
+	}
+	if got := receive(t, events, 1); got[0].Type != "auth_fail" {
+		t.Errorf("expected the waiting connection to be read, got %+v", got)
+	}
+	if elapsed := time.Since(start); elapsed < source.idleTimeout {
+		t.Errorf("expected the second connection to wait for the first to close, read after %s", elapsed)
+	}
+
+	idle.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := idle.Read(make([]byte, 1)); err == nil || strings.Contains(err.Error(), "timeout") {
+		t.Errorf("expected the idle connection to be closed, got %v", err)
+	}
+	if stats := source.Stats(); stats.Dropped != 0 {
+		t.Errorf("expected closing an idle connection not to count as a drop, got %+v", stats)
+	}
+}
+
+func TestSourceListenError(t *testing.T) {
+	if _, err := NewSource(config.SyslogConfig{}).Events(context.Background()); err == nil {
+		t.Error("expected an error without an address")
+	}
+	if _, err := NewSource(config.SyslogConfig{Address: "127.0.0.1:0", Protocol: "sctp"}).Events(context.Background()); err == nil || !strings.Contains(err.Error(), "unknown syslog protocol") {
+		t.Errorf("expected an unknown protocol error, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// trivyBinary is the scanner executable looked up on PATH
+const trivyBinary = "trivy"
+
+// trivyReport is the subset of Trivy's JSON report used to build scan results
+type trivyReport struct {
+	Results []trivyResult `json:"Results"`
+}
+
+// trivyResult holds the findings for one scanned target (image layer, lock file, ...)
+type trivyResult struct {
+	Target          string               `json:"Target"`
+	Class           string               `json:"Class"`
+	Type            string               `json:"Type"`
+	Vulnerabilities []trivyVulnerability `json:"Vulnerabilities"`
+}
+
+// trivyVulnerability is a single Trivy vulnerability finding
+type trivyVulnerability struct {
+	VulnerabilityID  string               `json:"VulnerabilityID"`
+	PkgName          string               `json:"PkgName"`
+	InstalledVersion string               `json:"InstalledVersion"`
+	FixedVersion     string               `json:"FixedVersion"`
+	Title            string               `json:"Title"`
+	Description      string               `json:"Description"`
+	Severity         string               `json:"Severity"`
+	PrimaryURL       string               `json:"PrimaryURL"`
+	References       []string             `json:"References"`
+	CVSS             map[string]trivyCVSS `json:"CVSS"`
+}
+
+// trivyCVSS holds the scores reported by one CVSS source
+type trivyCVSS struct {
+	V2Score float64 `json:"V2Score"`
+	V3Score float64 `json:"V3Score"`
+}
+
+// runTrivy scans target with Trivy and returns its parsed JSON report.
+// Existing paths are scanned as filesystems, anything else as an image
+func runTrivy(ctx context.Context, target string) (*trivyReport, error) {
+	path, err := exec.LookPath(trivyBinary)
+	if err != nil {
+		return nil, fmt.Errorf("trivy is not installed or not on PATH; install it from https://aquasecurity.github.io/trivy/ to enable vulnerability scanning: %w", err)
+	}
+
+	scanType := "image"
+	if _, err := os.Stat(target); err == nil {
+		scanType = "fs"
+	}
+
+	// End the flags so a target starting with - is not read as one
+	cmd := exec.CommandContext(ctx, path, scanType, "--format", "json", "--quiet", "--scanners", "vuln", "--", target)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("vulnerability scan of %s cancelled: %w", target, ctxErr)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("trivy scan of %s failed: %s: %w", target, msg, err)
+		}
+		return nil, fmt.Errorf("trivy scan of %s failed: %w", target, err)
+	}
+
+	var report trivyReport
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil {
+		return nil, fmt.Errorf("failed to parse trivy output: %w", err)
+	}
+	return &report, nil
+}
+
+// vulnerabilities converts a Trivy report into vulnerabilities, most severe first
+func (r *trivyReport) vulnerabilities() []Vulnerability {
+	vulns := []Vulnerability{}
+	for _, result := range r.Results {
+		for _, tv := range result.Vulnerabilities {
+			vuln := Vulnerability{
+				ID:          tv.VulnerabilityID,
+				Title:       tv.Title,
+				Description: tv.Description,
+				Severity:    strings.ToLower(tv.Severity),
+				CVSS:        tv.score(),
+				Component:   tv.PkgName,
+				Version:     tv.InstalledVersion,
+				Solution:    tv.solution(),
+				References:  tv.References,
+				Metadata: map[string]string{
+					"target": result.Target,
+					"type":   result.Type,
+				},
+			}
+			if vuln.Title == "" {
+				vuln.Title = tv.VulnerabilityID
+			}
+			if vuln.Severity == "" || vuln.Severity == "unknown" {
+				vuln.Severity = "info"
+			}
+			if strings.HasPrefix(tv.VulnerabilityID, "CVE-") {
+				vuln.CVE = tv.VulnerabilityID
+			}
+			if tv.FixedVersion != "" {
+				vuln.Metadata["fixed_version"] = tv.FixedVersion
+			}
+			if tv.PrimaryURL != "" {
+				vuln.Metadata["primary_url"] = tv.PrimaryURL
+			}
+			vulns = append(vulns, vuln)
+		}
+	}
+
+	sort.SliceStable(vulns, func(i, j int) bool {
+		if vulns[i].CVSS != vulns[j].CVSS {
+			return vulns[i].CVSS > vulns[j].CVSS
+		}
+		return vulns[i].ID < vulns[j].ID
+	})
+	return vulns
+}
+
+// score returns the NVD CVSS v3 score, falling back to the highest score from any source
+func (v trivyVulnerability) score() float64 {
+	if nvd, ok := v.CVSS["nvd"]; ok && nvd.V3Score > 0 {
+		return nvd.V3Score
+	}
+
+	var best float64
+	for _, cvss := range v.CVSS {
+		if cvss.V3Score > best {
+			best = cvss.V3Score
+		}
+	}
+	if best > 0 {
+		return best
+	}
+	for _, cvss := range v.CVSS {
+		if cvss.V2Score > best {
+			best = cvss.V2Score
+		}
+	}
+	return best
+}
+
+// solution describes how to remediate the finding
+func (v trivyVulnerability) solution() string {
+	if v.FixedVersion == "" {
+		return fmt.Sprintf("No fixed version of %s is available yet", v.PkgName)
+	}
+	return fmt.Sprintf("Upgrade %s from %s to %s", v.PkgName, v.InstalledVersion, v.FixedVersion)
+}
+
+// summarizeVulnerabilities counts findings by severity
+func summarizeVulnerabilities(vulns []Vulnerability) ScanSummary {
+	summary := ScanSummary{TotalChecks: len(vulns)}
+	for _, vuln := range vulns {
+		switch vuln.Severity {
+		case "critical":
+			summary.CriticalIssues++
+		case "high":
+			summary.HighIssues++
+		case "medium":
+			summary.MediumIssues++
+		case "low":
+			summary.LowIssues++
+		default:
+			summary.InfoIssues++
+		}
+	}
+	return summary
+}
+
+// scanRecommendations suggests next steps based on the findings
+func scanRecommendations(summary ScanSummary, vulns []Vulnerability) []string {
+	recommendations := []string{}
+
+	if summary.CriticalIssues > 0 {
+		recommendations = append(recommendations, fmt.Sprintf("Remediate the %d critical vulnerabilities first", summary.CriticalIssues))
+	}
+
+	fixable := map[string]bool{}
+	for _, vuln := range vulns {
+		if _, ok := vuln.Metadata["fixed_version"]; ok {
+			fixable[vuln.Component] = true
+		}
+	}
+	if len(fixable) > 0 {
+		recommendations = append(recommendations, fmt.Sprintf("Upgrade %d packages that have fixed versions available", len(fixable)))
+	}
+
+	if len(vulns) == 0 {
+		recommendations = append(recommendations, "No known vulnerabilities found; keep scanning regularly")
+	}
+	return recommendations
+}
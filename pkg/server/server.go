@@ -0,0 +1,629 @@
+// Package server exposes the AlloraCLI services over an HTTP API
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/agents"
+	"github.com/AlloraAi/AlloraCLI/pkg/analyze"
+	"github.com/AlloraAi/AlloraCLI/pkg/cloud"
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/monitor"
+	"github.com/AlloraAi/AlloraCLI/pkg/notify"
+	"github.com/AlloraAi/AlloraCLI/pkg/streaming"
+	"github.com/AlloraAi/AlloraCLI/pkg/troubleshoot"
+	"github.com/AlloraAi/AlloraCLI/pkg/utils"
+	"github.com/prometheus/common/model"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultAddress is where the server listens when server.address is unset
+const DefaultAddress = "127.0.0.1:8080"
+
+// shutdownTimeout bounds how long in-flight requests may run after the
+// server is asked to stop
+const shutdownTimeout = 15 * time.Second
+
+// Request body limits
+const (
+	maxQueryBytes = 1 << 20
+	maxLogBytes   = 64 << 20
+)
+
+// validTraceID matches the X-Request-ID values adopted as trace IDs; others
+// are replaced so clients cannot inject arbitrary text into the logs
+var validTraceID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// Options configures a Server
+type Options struct {
+	Config *config.Config
+	// Token is the bearer token every request except /health must present;
+	// empty disables authentication, which is only allowed on loopback addresses
+	Token    string
+	Monitor  monitor.Monitor
+	Cloud    cloud.CloudService
+	Analyzer analyze.Analyzer
+	Logger   logrus.FieldLogger
+
+	// Alerts received from Alertmanager are sent to Notifier, open and
+	// resolve incidents through Incidents, and are diagnosed by
+	// Troubleshooter; each is optional
+	Notifier       notify.Notifier
+	Incidents      notify.IncidentNotifier
+	Troubleshooter troubleshoot.Troubleshooter
+}
+
+// Server serves the REST API
+type Server struct {
+	cfg      *config.Config
+	token    string
+	monitor  monitor.Monitor
+	cloud    cloud.CloudService
+	analyzer analyze.Analyzer
+	logger   logrus.FieldLogger
+	handler  http.Handler
+
+	notifier       notify.Notifier
+	incidents      notify.IncidentNotifier
+	troubleshooter troubleshoot.Troubleshooter
+
+	// newAgent creates the agent that answers a query
+	newAgent func(cfg config.Agent) (agents.Agent, error)
+}
+
+// New creates a server for the given services
+func New(options Options) (*Server, error) {
+	if options.Config == nil {
+		return nil, fmt.Errorf("configuration is required")
+	}
+	if options.Monitor == nil || options.Cloud == nil || options.Analyzer == nil {
+		return nil, fmt.Errorf("monitor, cloud and analyzer services are required")
+	}
+
+	s := &Server{
+		cfg:      options.Config,
+		token:    options.Token,
+		monitor:  options.Monitor,
+		cloud:    options.Cloud,
+		analyzer: options.Analyzer,
+		logger:   options.Logger,
+		newAgent: agents.NewAgent,
+
+		notifier:       options.Notifier,
+		incidents:      options.Incidents,
+		troubleshooter: options.Troubleshooter,
+	}
+	if s.logger == nil {
+		s.logger = logrus.StandardLogger()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", s.handleHealth)
+	mux.Handle("POST /ask", s.authenticated(s.handleAsk))
+	mux.Handle("POST /ask/stream", s.authenticated(s.handleAskStream))
+	mux.Handle("GET /monitor/status", s.authenticated(s.handleMonitorStatus))
+	mux.Handle("GET /monitor/alerts", s.authenticated(s.handleMonitorAlerts))
+	mux.Handle("GET /cloud/resources", s.authenticated(s.handleCloudResources))
+	mux.Handle("GET /cloud/costs", s.authenticated(s.handleCloudCosts))
+	mux.Handle("POST /analyze/logs", s.authenticated(s.handleAnalyzeLogs))
+	mux.Handle("POST /alertmanager", s.authenticated(s.handleAlertmanager))
+	s.handler = s.logged(mux)
+
+	return s, nil
+}
+
+// Handler returns the HTTP handler serving the API
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// ListenAndServe serves the API on addr until ctx is done, then waits for
+// in-flight requests to finish
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	if s.token == "" && !isLoopback(addr) {
+		return fmt.Errorf("a server token is required to listen on %s; set server.token or ALLORA_SERVER_TOKEN", addr)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves the API on listener until ctx is done
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	httpServer := &http.Server{
+		Handler:           s.handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- httpServer.Serve(listener)
+	}()
+	s.logger.WithField("address", listener.Addr().String()).Info("API server listening")
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("API server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down API server: %w", err)
+	}
+	s.logger.Info("API server stopped")
+	return nil
+}
+
+// isLoopback reports whether addr only accepts connections from this host
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// authenticated rejects requests without the configured bearer token
+func (s *Server) authenticated(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="allora"`)
+				writeError(w, http.StatusUnauthorized, fmt.Errorf("a valid bearer token is required"))
+				return
+			}
+		}
+		next(w, r)
+	})
+}
+
+// statusRecorder remembers the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+// WriteHeader records the status code
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the response size
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(data)
+	r.bytes += n
+	return n, err
+}
+
+// Flush lets streaming handlers flush through the recorder
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// logged gives each request a trace ID, taken from its X-Request-ID header
+// when that is usable, and logs one structured entry per request
+func (s *Server) logged(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		traceID := r.Header.Get(utils.TraceIDHeader)
+		if !validTraceID.MatchString(traceID) {
+			traceID = utils.NewTraceID()
+		}
+		r = r.WithContext(utils.ContextWithTraceID(r.Context(), traceID))
+		w.Header().Set(utils.TraceIDHeader, traceID)
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		entry := s.requestLogger(r).WithFields(logrus.Fields{
+			"method":   r.Method,
+			"path":     r.URL.Path,
+			"status":   recorder.status,
+			"bytes":    recorder.bytes,
+			"duration": time.Since(start).Round(time.Millisecond).String(),
+			"remote":   r.RemoteAddr,
+		})
+		if recorder.status >= 500 {
+			entry.Warn("API request failed")
+		} else {
+			entry.Info("API request")
+		}
+	})
+}
+
+// requestLogger returns the logger for entries about r, tagged with its trace ID
+func (s *Server) requestLogger(r *http.Request) logrus.FieldLogger {
+	return s.logger.WithField(utils.TraceIDField, utils.TraceID(r.Context()))
+}
+
+// writeJSON writes value as a JSON response
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+// writeError writes err as a JSON error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// handleHealth reports that the server is up; it needs no token so load
+// balancers can probe it
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// askRequest is the body of POST /ask
+type askRequest struct {
+	Query   string                 `json:"query"`
+	Agent   string                 `json:"agent,omitempty"`
+	Context map[string]interface{} `json:"context,omitempty"`
+}
+
+// handleAsk answers a query with the named agent, or the first configured
+// agent by name
+func (s *Server) handleAsk(w http.ResponseWriter, r *http.Request) {
+	agent, query, ok := s.askQuery(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := agent.Query(r.Context(), query)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("failed to process query: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleAskStream answers a query as Server-Sent Events: one data frame per
+// chunk of the answer as it is generated, then a done event carrying the
+// whole response, or an error event
+func (s *Server) handleAskStream(w http.ResponseWriter, r *http.Request) {
+	if _, ok := w.(http.Flusher); !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported by this connection"))
+		return
+	}
+	agent, query, ok := s.askQuery(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	ctx := r.Context()
+	stream := streaming.NewStreamWriter(w)
+	stream.Flush()
+
+	// Stop writing once the client is gone or a write fails
+	var writeErr error
+	response, err := agents.StreamQuery(ctx, agent, query, func(delta string) {
+		if writeErr != nil || ctx.Err() != nil {
+			return
+		}
+		if writeErr = stream.WriteData(streamChunk{Delta: delta}); writeErr == nil {
+			stream.Flush()
+		}
+	})
+
+	switch {
+	case ctx.Err() != nil:
+		s.requestLogger(r).Debug("API client disconnected from stream")
+	case writeErr != nil:
+		s.requestLogger(r).Warnf("Failed to stream response: %v", writeErr)
+	case err != nil:
+		stream.WriteEvent("error", map[string]string{"error": fmt.Sprintf("failed to process query: %v", err)})
+		stream.Flush()
+	default:
+		stream.WriteEvent("done", response)
+		stream.Flush()
+	}
+}
+
+// streamChunk is the data of one Server-Sent Event from POST /ask/stream
+type streamChunk struct {
+	Delta string `json:"delta"`
+}
+
+// askQuery decodes the body of an ask request and creates its agent,
+// writing an error response and returning false if either fails
+func (s *Server) askQuery(w http.ResponseWriter, r *http.Request) (agents.Agent, *agents.Query, bool) {
+	var req askRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxQueryBytes))
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return nil, nil, false
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("query is required"))
+		return nil, nil, false
+	}
+
+	agent, err := s.agent(req.Agent)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil, nil, false
+	}
+	return agent, &agents.Query{Text: req.Query, Context: req.Context}, true
+}
+
+// agent creates the named agent, or the first configured agent by name
+func (s *Server) agent(name string) (agents.Agent, error) {
+	if len(s.cfg.Agents) == 0 {
+		return nil, fmt.Errorf("no agents configured. Run 'allora init' to set up your first agent")
+	}
+	if name == "" {
+		names := make([]string, 0, len(s.cfg.Agents))
+		for agentName := range s.cfg.Agents {
+			names = append(names, agentName)
+		}
+		sort.Strings(names)
+		name = names[0]
+	}
+
+	agentConfig, ok := s.cfg.Agents[name]
+	if !ok {
+		return nil, fmt.Errorf("agent '%s' not found", name)
+	}
+	agent, err := s.newAgent(agentConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize agent: %w", err)
+	}
+	return agent, nil
+}
+
+// handleMonitorStatus returns the system status
+func (s *Server) handleMonitorStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := s.monitor.GetSystemStatus()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("failed to get system status: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleMonitorAlerts returns the configured alert rules
+func (s *Server) handleMonitorAlerts(w http.ResponseWriter, r *http.Request) {
+	alerts, err := s.monitor.ListAlerts()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("failed to list alerts: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, alerts)
+}
+
+// handleCloudResources lists resources from one provider, or every
+// configured provider when provider is omitted
+func (s *Server) handleCloudResources(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	provider, resourceType := query.Get("provider"), query.Get("type")
+	if resourceType == "" {
+		resourceType = "instances"
+	}
+
+	var opts []cloud.ListOption
+	if max := query.Get("max"); max != "" {
+		n, err := strconv.Atoi(max)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid max %q", max))
+			return
+		}
+		opts = append(opts, cloud.WithMax(n))
+	}
+	if noCache, _ := strconv.ParseBool(query.Get("no_cache")); noCache {
+		opts = append(opts, cloud.WithNoCache())
+	}
+
+	var resources []cloud.Resource
+	var err error
+	if provider == "" {
+		resources, err = s.cloud.ListAllResources(r.Context(), resourceType, opts...)
+		if err != nil && resources != nil {
+			// Some providers answered; return their resources and log the rest
+			s.requestLogger(r).Warnf("Listing %s: %v", resourceType, err)
+			err = nil
+		}
+	} else {
+		resources, err = s.cloud.ListResources(r.Context(), provider, resourceType, opts...)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("failed to list resources: %w", err))
+		return
+	}
+	if resources == nil {
+		resources = []cloud.Resource{}
+	}
+	writeJSON(w, http.StatusOK, resources)
+}
+
+// handleCloudCosts returns the cost analysis for a period such as 30d
+func (s *Server) handleCloudCosts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	period := query.Get("period")
+	if period == "" {
+		period = "30d"
+	}
+	lookback, err := model.ParseDuration(period)
+	if err != nil || lookback <= 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid period %q: use a duration such as 7d, 30d or 90d", period))
+		return
+	}
+
+	options := cloud.CostOptions{
+		StartDate:   time.Now().Add(-time.Duration(lookback)),
+		EndDate:     time.Now(),
+		Granularity: "daily",
+		GroupBy:     []string{"service"},
+	}
+	if budget := query.Get("budget"); budget != "" {
+		options.Budget, err = strconv.ParseFloat(budget, 64)
+		if err != nil || options.Budget < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid budget %q", budget))
+			return
+		}
+	}
+
+	costs, err := s.cloud.GetCostAnalysis(r.Context(), query.Get("provider"), options)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("failed to analyze cloud costs: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, costs)
+}
+
+// handleAnalyzeLogs analyzes the log lines in the request body
+func (s *Server) handleAnalyzeLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	options := analyze.LogOptions{
+		File:      "request",
+		Pattern:   query.Get("pattern"),
+		TimeRange: query.Get("time_range"),
+	}
+	if stddev := query.Get("anomaly_stddev"); stddev != "" {
+		var err error
+		options.AnomalyStdDev, err = strconv.ParseFloat(stddev, 64)
+		if err != nil || options.AnomalyStdDev < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid anomaly_stddev %q", stddev))
+			return
+		}
+	}
+
+	analysis, err := s.analyzer.AnalyzeLogsStream(r.Context(), http.MaxBytesReader(w, r.Body, maxLogBytes), options)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("log body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		writeError(w, http.StatusUnprocessableEntity, fmt.Errorf("failed to analyze logs: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, analysis)
+}
+
+// alertmanagerResult reports what POST /alertmanager did with one alert
+type alertmanagerResult struct {
+	Alert       *monitor.ActiveAlert           `json:"alert"`
+	Diagnostics *troubleshoot.DiagnosticReport `json:"diagnostics,omitempty"`
+	// DiagnosticsError reports diagnostics that failed. Unlike Error it does
+	// not fail the delivery, since a retry would notify the alert again
+	DiagnosticsError string `json:"diagnostics_error,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// handleAlertmanager receives an Alertmanager webhook notification and
+// routes each alert to the notifier, incidents and diagnostics. It answers
+// 200 only when every alert was notified, so Alertmanager retries failures;
+// failed diagnostics are only reported
+func (s *Server) handleAlertmanager(w http.ResponseWriter, r *http.Request) {
+	payload, err := monitor.ParseAlertmanagerPayload(http.MaxBytesReader(w, r.Body, maxQueryBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	results := make([]alertmanagerResult, 0, len(payload.Alerts))
+	failed := 0
+	for i := range payload.Alerts {
+		result := s.processAlert(r.Context(), &payload.Alerts[i])
+		if result.Error != "" {
+			failed++
+			s.requestLogger(r).Warnf("Failed to process alert %s: %s", result.Alert.Alert.Name, result.Error)
+		}
+		if result.DiagnosticsError != "" {
+			s.requestLogger(r).Warnf("Failed to diagnose alert %s: %s", result.Alert.Alert.Name, result.DiagnosticsError)
+		}
+		results = append(results, result)
+	}
+
+	status := http.StatusOK
+	if failed > 0 {
+		status = http.StatusInternalServerError
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"processed": len(results) - failed,
+		"failed":    failed,
+		"alerts":    results,
+	})
+}
+
+// processAlert notifies and diagnoses a firing alert, or resolves the
+// incident of a resolved one
+func (s *Server) processAlert(ctx context.Context, alert *monitor.AlertmanagerAlert) alertmanagerResult {
+	active := alert.ActiveAlert()
+	result := alertmanagerResult{Alert: active}
+
+	var errs []error
+	if alert.Status == "resolved" {
+		if s.incidents != nil {
+			if err := s.incidents.Resolve(ctx, alert.IncidentName()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	} else {
+		notification := active.Notification()
+		if s.notifier != nil {
+			if err := s.notifier.Notify(ctx, notification); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if s.incidents != nil {
+			notification.Name = alert.IncidentName()
+			if err := s.incidents.Notify(ctx, notification); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		if target := alert.DiagnosticTarget(); target != "" && s.troubleshooter != nil {
+			report, err := s.troubleshooter.RunDiagnostics(ctx, troubleshoot.DiagnosticOptions{Target: target})
+			if err != nil {
+				result.DiagnosticsError = fmt.Sprintf("failed to diagnose %s: %v", target, err)
+			}
+			result.Diagnostics = report
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
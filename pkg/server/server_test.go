@@ -0,0 +1,468 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/agents"
+	"github.com/AlloraAi/AlloraCLI/pkg/analyze"
+	"github.com/AlloraAi/AlloraCLI/pkg/cloud"
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/monitor"
+	"github.com/AlloraAi/AlloraCLI/pkg/notify"
+	"github.com/AlloraAi/AlloraCLI/pkg/troubleshoot"
+	"github.com/AlloraAi/AlloraCLI/pkg/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+const testToken = "s3cret"
+
+// fakeMonitor reports a fixed system status
+type fakeMonitor struct {
+	monitor.Monitor
+}
+
+func (m *fakeMonitor) GetSystemStatus() (*monitor.SystemStatus, error) {
+	return &monitor.SystemStatus{Overall: "healthy"}, nil
+}
+
+// fakeCloud lists fixed resources; "broken" fails
+type fakeCloud struct {
+	cloud.CloudService
+}
+
+func (c *fakeCloud) ListResources(ctx context.Context, provider, resourceType string, opts ...cloud.ListOption) ([]cloud.Resource, error) {
+	if provider == "broken" {
+		return nil, errors.New("provider unavailable")
+	}
+	return []cloud.Resource{{ID: "i-1", Type: resourceType, Provider: provider}}, nil
+}
+
+func (c *fakeCloud) ListAllResources(ctx context.Context, resourceType string, opts ...cloud.ListOption) ([]cloud.Resource, error) {
+	return []cloud.Resource{{ID: "i-1", Provider: "aws"}}, errors.New("failed to list resources from some providers")
+}
+
+// fakeAgent echoes the query it is asked
+type fakeAgent struct {
+	agents.Agent
+	name string
+}
+
+func (a *fakeAgent) Query(ctx context.Context, query *agents.Query) (*agents.Response, error) {
+	return &agents.Response{Text: a.name + ": " + query.Text}, nil
+}
+
+// streamingAgent streams its deltas, then blocks until the query is canceled
+// if block is set
+type streamingAgent struct {
+	agents.Agent
+	deltas   []string
+	block    bool
+	canceled chan struct{}
+}
+
+func (a *streamingAgent) StreamQuery(ctx context.Context, query *agents.Query, onDelta func(delta string)) (*agents.Response, error) {
+	for _, delta := range a.deltas {
+		onDelta(delta)
+	}
+	if a.block {
+		<-ctx.Done()
+		close(a.canceled)
+		return nil, ctx.Err()
+	}
+	return &agents.Response{Text: strings.Join(a.deltas, "")}, nil
+}
+
+func newTestServer(t *testing.T, token string) (*Server, *test.Hook) {
+	logger, hook := test.NewNullLogger()
+	analyzer, err := analyze.New()
+	if err != nil {
+		t.Fatalf("analyze.New() failed: %v", err)
+	}
+
+	s, err := New(Options{
+		Config: &config.Config{Agents: map[string]config.Agent{
+			"ops":     {Type: "general", Model: "ops-model"},
+			"default": {Type: "general", Model: "default-model"},
+		}},
+		Token:    token,
+		Monitor:  &fakeMonitor{},
+		Cloud:    &fakeCloud{},
+		Analyzer: analyzer,
+		Logger:   logger,
+	})
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	s.newAgent = func(cfg config.Agent) (agents.Agent, error) {
+		return &fakeAgent{name: cfg.Model}, nil
+	}
+	return s, hook
+}
+
+// do sends a request to the server with the test token
+func do(s *Server, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthentication(t *testing.T) {
+	s, hook := newTestServer(t, testToken)
+
+	for name, header := range map[string]string{"missing": "", "wrong": "Bearer nope", "scheme": "Basic " + testToken} {
+		req := httptest.NewRequest(http.MethodGet, "/monitor/status", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s token: expected 401, got %d", name, rec.Code)
+		}
+	}
+
+	if rec := do(s, http.MethodGet, "/monitor/status", ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"overall": "healthy"`) {
+		t.Errorf("expected the status with a valid token, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected /health to need no token, got %d", rec.Code)
+	}
+
+	entry := hook.LastEntry()
+	if entry == nil || entry.Data["path"] != "/health" || entry.Data["status"] != http.StatusOK || entry.Data["method"] != http.MethodGet {
+		t.Errorf("expected a structured request log entry, got %+v", entry)
+	}
+}
+
+func TestTraceID(t *testing.T) {
+	s, hook := newTestServer(t, testToken)
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set(utils.TraceIDHeader, "deploy-42")
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if got := rec.Header().Get(utils.TraceIDHeader); got != "deploy-42" {
+		t.Errorf("expected the client's request ID to be kept, got %q", got)
+	}
+	if entry := hook.LastEntry(); entry == nil || entry.Data[utils.TraceIDField] != "deploy-42" {
+		t.Errorf("expected the log entry to carry the trace ID, got %+v", entry)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set(utils.TraceIDHeader, "bad id")
+	rec = httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	if got := rec.Header().Get(utils.TraceIDHeader); got == "" || got == "bad id" {
+		t.Errorf("expected an unusable request ID to be replaced, got %q", got)
+	}
+}
+
+func TestAsk(t *testing.T) {
+	s, _ := newTestServer(t, testToken)
+
+	tests := []struct {
+		name string
+		body string
+		code int
+		want string
+	}{
+		{"first agent by name", `{"query": "why is disk full?"}`, http.StatusOK, `"text": "default-model: why is disk full?"`},
+		{"named agent", `{"query": "scale up", "agent": "ops"}`, http.StatusOK, `"text": "ops-model: scale up"`},
+		{"unknown agent", `{"query": "hi", "agent": "nope"}`, http.StatusBadRequest, `agent 'nope' not found`},
+		{"empty query", `{"query": " "}`, http.StatusBadRequest, "query is required"},
+		{"invalid body", `query`, http.StatusBadRequest, "invalid request body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(s, http.MethodPost, "/ask", tt.body)
+			if rec.Code != tt.code || !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("expected %d containing %q, got %d: %s", tt.code, tt.want, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	if rec := do(s, http.MethodGet, "/ask", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected GET /ask to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestCloudResources(t *testing.T) {
+	s, hook := newTestServer(t, testToken)
+
+	rec := do(s, http.MethodGet, "/cloud/resources?provider=gcp&type=disks", "")
+	var resources []cloud.Resource
+	if err := json.Unmarshal(rec.Body.Bytes(), &resources); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("expected a resource list, got %d: %s", rec.Code, rec.Body)
+	}
+	if len(resources) != 1 || resources[0].Provider != "gcp" || resources[0].Type != "disks" {
+		t.Errorf("unexpected resources %+v", resources)
+	}
+
+	hook.Reset()
+	rec = do(s, http.MethodGet, "/cloud/resources", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"provider": "aws"`) {
+		t.Errorf("expected partial results from every provider, got %d: %s", rec.Code, rec.Body)
+	}
+	if entries := hook.AllEntries(); len(entries) == 0 || entries[0].Level != logrus.WarnLevel {
+		t.Error("expected the failed providers to be logged")
+	}
+
+	if rec := do(s, http.MethodGet, "/cloud/resources?provider=broken", ""); rec.Code != http.StatusBadGateway {
+		t.Errorf("expected a provider failure to return 502, got %d", rec.Code)
+	}
+	if rec := do(s, http.MethodGet, "/cloud/resources?provider=aws&max=lots", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid max to return 400, got %d", rec.Code)
+	}
+}
+
+func TestAnalyzeLogs(t *testing.T) {
+	s, _ := newTestServer(t, testToken)
+
+	logs := "2024-06-01T08:00:00Z INFO started\n2024-06-01T08:00:01Z ERROR connection refused\n2024-06-01T08:00:02Z WARN retrying\n"
+	rec := do(s, http.MethodPost, "/analyze/logs", logs)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var analysis analyze.LogAnalysis
+	if err := json.Unmarshal(rec.Body.Bytes(), &analysis); err != nil {
+		t.Fatalf("invalid analysis: %v", err)
+	}
+	if analysis.ErrorCount != 1 || analysis.WarningCount != 1 {
+		t.Errorf("expected one error and one warning, got %+v", analysis)
+	}
+
+	if rec := do(s, http.MethodPost, "/analyze/logs?anomaly_stddev=-1", logs); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected a negative anomaly_stddev to return 400, got %d", rec.Code)
+	}
+}
+
+func TestServeShutdown(t *testing.T) {
+	s, _ := newTestServer(t, "")
+	if err := s.ListenAndServe(context.Background(), "0.0.0.0:0"); err == nil || !strings.Contains(err.Error(), "token is required") {
+		t.Errorf("expected a token to be required off loopback, got %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, listener) }()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/monitor/status")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected no token to be needed when none is set, got %d", resp.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+	}
+}
+
+// readEvents reads Server-Sent Events until the stream ends or n data
+// frames were read, returning the data frames and event names
+func readEvents(body io.Reader, n int) (data, events []string) {
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() && len(data) < n {
+		line := scanner.Text()
+		if frame, ok := strings.CutPrefix(line, "data: "); ok {
+			data = append(data, frame)
+		} else if event, ok := strings.CutPrefix(line, "event: "); ok {
+			events = append(events, event)
+		}
+	}
+	return data, events
+}
+
+func TestAskStream(t *testing.T) {
+	s, _ := newTestServer(t, testToken)
+	s.newAgent = func(cfg config.Agent) (agents.Agent, error) {
+		return &streamingAgent{deltas: []string{"Disk ", "is\n", "full"}}, nil
+	}
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/ask/stream", strings.NewReader(`{"query": "why?"}`))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected text/event-stream, got %q", ct)
+	}
+	data, events := readEvents(resp.Body, 10)
+	if len(data) != 4 {
+		t.Fatalf("expected three chunks and a done event, got %q", data)
+	}
+	for i, want := range []string{"Disk ", "is\n", "full"} {
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data[i]), &chunk); err != nil || chunk.Delta != want {
+			t.Errorf("frame %d: expected delta %q, got %s", i, want, data[i])
+		}
+	}
+	if len(events) != 1 || events[0] != "done" || !strings.Contains(data[3], `"text":"Disk is\nfull"`) {
+		t.Errorf("expected a done event with the whole response, got %q %q", events, data[3])
+	}
+}
+
+func TestAskStreamDisconnect(t *testing.T) {
+	s, _ := newTestServer(t, testToken)
+	agent := &streamingAgent{deltas: []string{"one", "two"}, block: true, canceled: make(chan struct{})}
+	s.newAgent = func(cfg config.Agent) (agents.Agent, error) {
+		return agent, nil
+	}
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/ask/stream", strings.NewReader(`{"query": "why?"}`))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if data, _ := readEvents(resp.Body, 2); len(data) != 2 {
+		t.Fatalf("expected two frames before disconnecting, got %q", data)
+	}
+	cancel()
+
+	select {
+	case <-agent.canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the query to be canceled when the client disconnects")
+	}
+}
+
+// recordingIncidents records notifications and resolved incidents, failing with err if set
+type recordingIncidents struct {
+	notified []notify.Notification
+	resolved []string
+	err      error
+}
+
+func (r *recordingIncidents) Notify(ctx context.Context, notification notify.Notification) error {
+	r.notified = append(r.notified, notification)
+	return r.err
+}
+
+func (r *recordingIncidents) Resolve(ctx context.Context, name string) error {
+	r.resolved = append(r.resolved, name)
+	return r.err
+}
+
+func (r *recordingIncidents) Wants(notification notify.Notification) bool {
+	return true
+}
+
+// fakeTroubleshooter records the targets it diagnoses, failing with err if set
+type fakeTroubleshooter struct {
+	troubleshoot.Troubleshooter
+	targets []string
+	err     error
+}
+
+func (f *fakeTroubleshooter) RunDiagnostics(ctx context.Context, options troubleshoot.DiagnosticOptions) (*troubleshoot.DiagnosticReport, error) {
+	f.targets = append(f.targets, options.Target)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &troubleshoot.DiagnosticReport{Target: options.Target, Status: "completed", Summary: "2 of 2 checks passed"}, nil
+}
+
+const alertmanagerBody = `{
+  "version": "4",
+  "status": "firing",
+  "receiver": "allora",
+  "alerts": [
+    {
+      "status": "firing",
+      "labels": {"alertname": "HighLatency", "severity": "critical", "instance": "api-1:8080"},
+      "annotations": {"summary": "p99 latency above 2s"},
+      "startsAt": "2024-06-01T08:00:00Z",
+      "fingerprint": "a1b2c3"
+    },
+    {
+      "status": "resolved",
+      "labels": {"alertname": "DiskFull"},
+      "startsAt": "2024-06-01T07:00:00Z"
+    }
+  ]
+}`
+
+func TestAlertmanager(t *testing.T) {
+	s, _ := newTestServer(t, testToken)
+	notifier, incidents, troubleshooter := &recordingIncidents{}, &recordingIncidents{}, &fakeTroubleshooter{}
+	s.notifier, s.incidents, s.troubleshooter = notifier, incidents, troubleshooter
+
+	rec := do(s, http.MethodPost, "/alertmanager", alertmanagerBody)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), `"processed": 2`) || !strings.Contains(rec.Body.String(), `"summary": "2 of 2 checks passed"`) {
+		t.Errorf("expected the processed alerts and diagnostics in the response, got %s", rec.Body)
+	}
+
+	if len(notifier.notified) != 1 || notifier.notified[0].Name != "HighLatency" || notifier.notified[0].Severity != "critical" {
+		t.Errorf("expected the firing alert to be sent, got %+v", notifier.notified)
+	}
+	if len(incidents.notified) != 1 || incidents.notified[0].Name != "HighLatency-a1b2c3" {
+		t.Errorf("expected an incident per firing series, got %+v", incidents.notified)
+	}
+	if len(incidents.resolved) != 1 || incidents.resolved[0] != "DiskFull" {
+		t.Errorf("expected the resolved alert to resolve its incident, got %v", incidents.resolved)
+	}
+	if len(troubleshooter.targets) != 1 || troubleshooter.targets[0] != "api-1:8080" {
+		t.Errorf("expected diagnostics for the alert instance, got %v", troubleshooter.targets)
+	}
+
+	// Alertmanager would retry a failed delivery and notify the alert again,
+	// so failed diagnostics are only reported
+	troubleshooter.err = errors.New("instance unreachable")
+	rec = do(s, http.MethodPost, "/alertmanager", alertmanagerBody)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"failed": 0`) || !strings.Contains(rec.Body.String(), `"diagnostics_error": "failed to diagnose api-1:8080: instance unreachable"`) {
+		t.Errorf("expected failed diagnostics to be reported with 200, got %d: %s", rec.Code, rec.Body)
+	}
+	troubleshooter.err = nil
+
+	notifier.err = errors.New("slack unavailable")
+	if rec := do(s, http.MethodPost, "/alertmanager", alertmanagerBody); rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "slack unavailable") {
+		t.Errorf("expected a failed notification to return 500, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := do(s, http.MethodPost, "/alertmanager", `{"version": "4", "alerts": []}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid payload to return 400, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,193 @@
+package troubleshoot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/agents"
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/sirupsen/logrus"
+)
+
+// agentAnalysisTimeout bounds how long AnalyzeIncident waits for the agent
+// before falling back to the heuristic analysis
+const agentAnalysisTimeout = 60 * time.Second
+
+// promptLogLines is how many trailing lines of each log source go into the prompt
+const promptLogLines = 40
+
+// promptLogBytes caps the log excerpt of each source in the prompt
+const promptLogBytes = 4000
+
+// agentIncidentAnalysis is the structured reply the agent is asked for
+type agentIncidentAnalysis struct {
+	RootCause   string `json:"root_cause"`
+	Impact      string `json:"impact"`
+	Suggestions []struct {
+		Title       string   `json:"title"`
+		Description string   `json:"description"`
+		Priority    string   `json:"priority"`
+		Confidence  float64  `json:"confidence"`
+		Steps       []string `json:"steps"`
+		Commands    []string `json:"commands"`
+	} `json:"suggestions"`
+}
+
+// newIncidentAgent returns an agent for incident analysis: the first
+// configured agent, by name, that has an API key. It returns nil when there
+// is none, and AnalyzeIncident then relies on its heuristics alone
+func newIncidentAgent(cfg *config.Config) agents.Agent {
+	if cfg == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(cfg.Agents))
+	for name := range cfg.Agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		agentConfig := cfg.Agents[name]
+		// Ollama serves models locally without an API key
+		if agentConfig.APIKey == "" && agentConfig.Provider != config.ProviderOllama {
+			continue
+		}
+		agent, err := agents.NewAgent(agentConfig)
+		if err != nil {
+			logrus.Debugf("Skipping agent %s for incident analysis: %v", name, err)
+			continue
+		}
+		return agent
+	}
+	return nil
+}
+
+// analyzeWithAgent asks the agent for the root cause, impact and
+// suggestions, and replaces the heuristic findings in analysis with them
+func (t *TroubleshooterImpl) analyzeWithAgent(ctx context.Context, incident Incident, sources map[string]string, analysis *IncidentAnalysis) error {
+	ctx, cancel := context.WithTimeout(ctx, agentAnalysisTimeout)
+	defer cancel()
+
+	response, err := t.agent.Query(ctx, &agents.Query{
+		Text: incidentPrompt(incident, sources, analysis.Candidates),
+		Context: map[string]interface{}{
+			"service":  incident.Service,
+			"severity": incident.Severity,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query agent: %w", err)
+	}
+
+	content := response.Content
+	if content == "" {
+		content = response.Text
+	}
+	result, err := parseAgentAnalysis(content)
+	if err != nil {
+		return err
+	}
+
+	analysis.RootCause = result.RootCause
+	if result.Impact != "" {
+		analysis.Impact = result.Impact
+	}
+	if len(result.Suggestions) > 0 {
+		analysis.Suggestions = make([]*Suggestion, 0, len(result.Suggestions))
+		for _, s := range result.Suggestions {
+			analysis.Suggestions = append(analysis.Suggestions, &Suggestion{
+				Title:       s.Title,
+				Description: s.Description,
+				Priority:    s.Priority,
+				Confidence:  s.Confidence,
+				Steps:       s.Steps,
+				Commands:    s.Commands,
+				Metadata:    map[string]string{"source": "agent"},
+			})
+		}
+	}
+	return nil
+}
+
+// incidentPrompt builds the agent prompt from the incident, the candidate
+// causes found by correlation and the tail of each log source
+func incidentPrompt(incident Incident, sources map[string]string, candidates []*RootCauseCandidate) string {
+	var b strings.Builder
+	b.WriteString("Analyze this production incident and identify its root cause.\n\n")
+	fmt.Fprintf(&b, "Service: %s\n", valueOr(incident.Service, "unknown"))
+	fmt.Fprintf(&b, "Severity: %s\n", valueOr(incident.Severity, "unknown"))
+
+	if len(candidates) > 0 {
+		b.WriteString("\nCandidate causes from correlating error spikes across the logs, most likely first:\n")
+		for _, c := range candidates {
+			fmt.Fprintf(&b, "- %s: %q, %d occurrences, confidence %.2f", c.Source, c.Pattern, c.Occurrences, c.Confidence)
+			if !c.FirstSeen.IsZero() {
+				fmt.Fprintf(&b, ", first seen %s", c.FirstSeen.Format(time.RFC3339))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "\nLast lines of the %s logs:\n```\n%s\n```\n", name, tailLines(sources[name], promptLogLines, promptLogBytes))
+	}
+
+	b.WriteString(`
+Reply with only a JSON object of this form:
+{"root_cause": "...", "impact": "...", "suggestions": [{"title": "...", "description": "...", "priority": "high|medium|low", "confidence": 0.0, "steps": ["..."], "commands": ["..."]}]}
+`)
+	return b.String()
+}
+
+// parseAgentAnalysis extracts the JSON analysis from the agent's reply,
+// which models often wrap in prose or a code fence
+func parseAgentAnalysis(content string) (*agentIncidentAnalysis, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("agent reply contains no JSON analysis")
+	}
+
+	var result agentIncidentAnalysis
+	if err := json.Unmarshal([]byte(content[start:end+1]), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse agent analysis: %w", err)
+	}
+	if strings.TrimSpace(result.RootCause) == "" {
+		return nil, fmt.Errorf("agent analysis has no root cause")
+	}
+	return &result, nil
+}
+
+// tailLines returns the last n lines of logs, at most maxBytes of them
+func tailLines(logs string, n, maxBytes int) string {
+	lines := strings.Split(strings.TrimRight(logs, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	tail := strings.Join(lines, "\n")
+	if len(tail) > maxBytes {
+		tail = tail[len(tail)-maxBytes:]
+		if i := strings.Index(tail, "\n"); i >= 0 {
+			tail = tail[i+1:]
+		}
+	}
+	return tail
+}
+
+// valueOr returns value, or fallback when it is empty
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,143 @@
+package troubleshoot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// defaultFixTimeout bounds a remediation command that does not set its own timeout
+const defaultFixTimeout = 30 * time.Second
+
+// maxFixOutput caps how much command output is kept in an autofix result
+const maxFixOutput = 4096
+
+// severityRanks orders issue severities so AutofixOptions.Severity can act as a ceiling
+var severityRanks = map[string]int{
+	"info":     1,
+	"low":      1,
+	"warning":  2,
+	"medium":   2,
+	"error":    3,
+	"high":     3,
+	"critical": 4,
+}
+
+// severityRank returns the rank of a severity, treating unknown values as medium
+func severityRank(severity string) int {
+	if rank, ok := severityRanks[strings.ToLower(severity)]; ok {
+		return rank
+	}
+	return severityRanks["medium"]
+}
+
+// fixIssues attempts the automated remediation of every issue at or below
+// the requested severity, stopping once ctx is cancelled
+func (t *TroubleshooterImpl) fixIssues(ctx context.Context, issues []*DiagnosticIssue, options AutofixOptions) []*AutofixResult {
+	results := []*AutofixResult{}
+	for _, issue := range issues {
+		if ctx.Err() != nil {
+			break
+		}
+		if options.Severity != "" && severityRank(issue.Severity) > severityRank(options.Severity) {
+			continue
+		}
+		results = append(results, t.fixIssue(ctx, issue, options))
+	}
+	return results
+}
+
+// fixIssue runs, or in dry-run mode describes, the remediation for one issue
+func (t *TroubleshooterImpl) fixIssue(ctx context.Context, issue *DiagnosticIssue, options AutofixOptions) *AutofixResult {
+	result := &AutofixResult{
+		Issue:     issue.Title,
+		Action:    issue.Solution,
+		Timestamp: time.Now(),
+	}
+
+	action := issue.Remediation
+	if action == nil || !action.Automated || action.Command == "" {
+		result.Status = "skipped"
+		result.Error = "no automated remediation available"
+		return result
+	}
+
+	result.Action = action.Title
+	result.Command = action.Command
+
+	if options.DryRun {
+		result.Status = "would_fix"
+		return result
+	}
+
+	if action.Destructive && !options.Confirm {
+		result.Status = "skipped"
+		result.Error = "destructive action requires confirmation; re-run with --confirm"
+		return result
+	}
+
+	timeout := defaultFixTimeout
+	if value, ok := action.Metadata["timeout"]; ok {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			result.Status = "failed"
+			result.Error = fmt.Sprintf("invalid timeout %q: %v", value, err)
+			return result
+		}
+		timeout = parsed
+	}
+
+	output, err := runFixCommand(ctx, action.Command, timeout)
+	if err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+		if output != "" {
+			result.Error = fmt.Sprintf("%v: %s", err, output)
+		}
+		return result
+	}
+
+	result.Status = "success"
+	result.Output = output
+	return result
+}
+
+// runFixCommand runs a remediation command through the platform shell and
+// returns its combined stdout and stderr
+func runFixCommand(ctx context.Context, command string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	text := strings.TrimSpace(output.String())
+	if len(text) > maxFixOutput {
+		text = text[:maxFixOutput] + "..."
+	}
+
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return text, fmt.Errorf("command timed out after %s", timeout)
+		}
+		if ctx.Err() != nil {
+			return text, fmt.Errorf("command cancelled: %w", ctx.Err())
+		}
+		return text, fmt.Errorf("command failed: %w", err)
+	}
+	return text, nil
+}
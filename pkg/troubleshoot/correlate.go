@@ -0,0 +1,336 @@
+package troubleshoot
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/analyze"
+)
+
+// correlationWindow is the width of the windows errors are counted in. Spikes
+// in different sources less than a window apart belong to the same incident
+const correlationWindow = time.Minute
+
+// spikeMinEvents is the fewest errors and warnings a window must hold to be a spike
+const spikeMinEvents = 2
+
+// RootCauseCandidate is a possible cause of an incident: the dominant error
+// in one log source around the time the incident started
+type RootCauseCandidate struct {
+	Source      string    `json:"source" yaml:"source"`
+	Pattern     string    `json:"pattern" yaml:"pattern"`
+	Level       string    `json:"level" yaml:"level"`
+	Occurrences int       `json:"occurrences" yaml:"occurrences"`
+	FirstSeen   time.Time `json:"first_seen,omitempty" yaml:"first_seen,omitempty"`
+	Confidence  float64   `json:"confidence" yaml:"confidence"`
+	// CorroboratedBy lists the other sources that spiked at the same time
+	CorroboratedBy []string `json:"corroborated_by,omitempty" yaml:"corroborated_by,omitempty"`
+}
+
+// logSource holds the errors and warnings logged by one incident source
+type logSource struct {
+	name string
+	// events are the entries with a timestamp, in time order
+	events []analyze.LogEntry
+	// untimed are the entries without a timestamp, which cannot be correlated
+	untimed []analyze.LogEntry
+}
+
+// spike is a window in which a source logged unusually many errors and warnings
+type spike struct {
+	source *logSource
+	start  time.Time
+	count  int
+}
+
+// incidentSources returns the log content of each source of the incident.
+// Logs is kept as a source named after the service, so single-log incidents
+// are analyzed the same way
+func incidentSources(incident Incident) (map[string]string, error) {
+	sources := make(map[string]string, len(incident.Sources)+1)
+	for name, value := range incident.Sources {
+		logs, err := readLogSource(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read log source %s: %w", name, err)
+		}
+		sources[name] = logs
+	}
+
+	if incident.Logs != "" {
+		name := incident.Service
+		if name == "" {
+			name = "logs"
+		}
+		if _, ok := sources[name]; ok {
+			return nil, fmt.Errorf("log source %s is given twice", name)
+		}
+		logs, err := readLogSource(incident.Logs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read logs: %w", err)
+		}
+		sources[name] = logs
+	}
+	return sources, nil
+}
+
+// readLogSource returns the contents of value if it names a file, or else
+// value itself as log content
+func readLogSource(value string) (string, error) {
+	if strings.Contains(value, "\n") {
+		return value, nil
+	}
+	info, err := os.Stat(value)
+	if err != nil || !info.Mode().IsRegular() {
+		return value, nil
+	}
+	data, err := os.ReadFile(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// parseLogSource collects the errors and warnings in logs
+func parseLogSource(name, logs string, now time.Time) *logSource {
+	source := &logSource{name: name}
+	for _, line := range strings.Split(logs, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		entry := analyze.ParseLogLine(line, now)
+		if (entry.Level != "error" && entry.Level != "warning") || entry.Pattern == "" {
+			continue
+		}
+		if entry.Timestamp.IsZero() {
+			source.untimed = append(source.untimed, entry)
+		} else {
+			source.events = append(source.events, entry)
+		}
+	}
+	sort.SliceStable(source.events, func(i, j int) bool {
+		return source.events[i].Timestamp.Before(source.events[j].Timestamp)
+	})
+	return source
+}
+
+// spikes returns the windows in which the source logged at least one
+// standard deviation more errors and warnings than its mean
+func (s *logSource) spikes() []spike {
+	if len(s.events) == 0 {
+		return nil
+	}
+
+	first := s.events[0].Timestamp.Truncate(correlationWindow)
+	last := s.events[len(s.events)-1].Timestamp.Truncate(correlationWindow)
+	counts := make([]int, int(last.Sub(first)/correlationWindow)+1)
+	for _, event := range s.events {
+		counts[int(event.Timestamp.Truncate(correlationWindow).Sub(first)/correlationWindow)]++
+	}
+
+	var sum, sumSquares float64
+	for _, count := range counts {
+		sum += float64(count)
+		sumSquares += float64(count) * float64(count)
+	}
+	mean := sum / float64(len(counts))
+	threshold := mean + math.Sqrt(math.Max(sumSquares/float64(len(counts))-mean*mean, 0))
+
+	var spikes []spike
+	for i, count := range counts {
+		if count >= spikeMinEvents && float64(count) >= threshold {
+			spikes = append(spikes, spike{
+				source: s,
+				start:  first.Add(time.Duration(i) * correlationWindow),
+				count:  count,
+			})
+		}
+	}
+	return spikes
+}
+
+// rankRootCauses cross-references spikes across the sources and ranks the
+// dominant error of each source spiking with the incident, earliest first:
+// the source that failed first is the likeliest cause of the others failing.
+// Without correlated spikes it falls back to each source's most frequent error
+func rankRootCauses(sources map[string]string, now time.Time) []*RootCauseCandidate {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parsed := make([]*logSource, 0, len(names))
+	var spikes []spike
+	for _, name := range names {
+		source := parseLogSource(name, sources[name], now)
+		parsed = append(parsed, source)
+		spikes = append(spikes, source.spikes()...)
+	}
+
+	cluster := incidentCluster(spikes)
+	if len(cluster) == 0 {
+		return fallbackRootCauses(parsed)
+	}
+
+	from := cluster[0].start
+	to := cluster[len(cluster)-1].start.Add(correlationWindow)
+	spiking := []string{}
+	var candidates []*RootCauseCandidate
+	for _, source := range parsed {
+		if !clusterHasSource(cluster, source) {
+			continue
+		}
+		var window []analyze.LogEntry
+		for _, event := range source.events {
+			if !event.Timestamp.Before(from) && event.Timestamp.Before(to) {
+				window = append(window, event)
+			}
+		}
+		spiking = append(spiking, source.name)
+		candidates = append(candidates, dominantPattern(source.name, window))
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if !candidates[i].FirstSeen.Equal(candidates[j].FirstSeen) {
+			return candidates[i].FirstSeen.Before(candidates[j].FirstSeen)
+		}
+		return candidates[i].Occurrences > candidates[j].Occurrences
+	})
+
+	corroboration := 0.0
+	if len(sources) > 1 {
+		corroboration = float64(len(spiking)-1) / float64(len(sources)-1)
+	}
+	for rank, candidate := range candidates {
+		precedence := 1.0
+		if len(candidates) > 1 {
+			precedence = float64(len(candidates)-1-rank) / float64(len(candidates)-1)
+		}
+		candidate.Confidence = math.Round((0.3+0.4*corroboration+0.3*precedence)*100) / 100
+		for _, name := range spiking {
+			if name != candidate.Source {
+				candidate.CorroboratedBy = append(candidate.CorroboratedBy, name)
+			}
+		}
+	}
+	return candidates
+}
+
+// incidentCluster groups spikes less than a window apart and returns the
+// group spanning the most sources, preferring the one with the most events
+func incidentCluster(spikes []spike) []spike {
+	sort.SliceStable(spikes, func(i, j int) bool {
+		return spikes[i].start.Before(spikes[j].start)
+	})
+
+	var best []spike
+	bestSources, bestEvents := 0, 0
+	for i := 0; i < len(spikes); {
+		j := i + 1
+		for j < len(spikes) && spikes[j].start.Sub(spikes[j-1].start) <= correlationWindow {
+			j++
+		}
+
+		cluster := spikes[i:j]
+		seen := map[*logSource]bool{}
+		events := 0
+		for _, s := range cluster {
+			seen[s.source] = true
+			events += s.count
+		}
+		if len(seen) > bestSources || (len(seen) == bestSources && events > bestEvents) {
+			best, bestSources, bestEvents = cluster, len(seen), events
+		}
+		i = j
+	}
+	return best
+}
+
+// clusterHasSource reports whether source spiked within cluster
+func clusterHasSource(cluster []spike, source *logSource) bool {
+	for _, s := range cluster {
+		if s.source == source {
+			return true
+		}
+	}
+	return false
+}
+
+// fallbackRootCauses ranks the most frequent error of each source when no
+// spikes could be correlated, with a low confidence
+func fallbackRootCauses(sources []*logSource) []*RootCauseCandidate {
+	var candidates []*RootCauseCandidate
+	for _, source := range sources {
+		entries := append(append([]analyze.LogEntry(nil), source.events...), source.untimed...)
+		if len(entries) == 0 {
+			continue
+		}
+		candidate := dominantPattern(source.name, entries)
+		candidate.Confidence = 0.3
+		candidates = append(candidates, candidate)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Occurrences > candidates[j].Occurrences
+	})
+	return candidates
+}
+
+// dominantPattern returns the most frequent pattern among entries as a
+// candidate, preferring errors to warnings
+func dominantPattern(source string, entries []analyze.LogEntry) *RootCauseCandidate {
+	byPattern := map[string]*RootCauseCandidate{}
+	var order []*RootCauseCandidate
+	for _, entry := range entries {
+		candidate, ok := byPattern[entry.Level+"\x00"+entry.Pattern]
+		if !ok {
+			candidate = &RootCauseCandidate{
+				Source:    source,
+				Pattern:   entry.Pattern,
+				Level:     entry.Level,
+				FirstSeen: entry.Timestamp,
+			}
+			byPattern[entry.Level+"\x00"+entry.Pattern] = candidate
+			order = append(order, candidate)
+		}
+		candidate.Occurrences++
+	}
+
+	best := order[0]
+	for _, candidate := range order[1:] {
+		if (candidate.Level == "error") != (best.Level == "error") {
+			if candidate.Level == "error" {
+				best = candidate
+			}
+			continue
+		}
+		if candidate.Occurrences > best.Occurrences {
+			best = candidate
+		}
+	}
+	if len(entries) > 0 && !entries[0].Timestamp.IsZero() {
+		// The source started failing with its first error, whichever pattern it had
+		best.FirstSeen = entries[0].Timestamp
+	}
+	return best
+}
+
+// describeRootCause summarises the top candidate for IncidentAnalysis.RootCause
+func describeRootCause(candidates []*RootCauseCandidate) string {
+	if len(candidates) == 0 {
+		return "No errors or warnings found in the incident logs"
+	}
+
+	top := candidates[0]
+	description := fmt.Sprintf("%q in %s logs", top.Pattern, top.Source)
+	if !top.FirstSeen.IsZero() {
+		description += " starting " + top.FirstSeen.Format(time.RFC3339)
+	}
+	if len(top.CorroboratedBy) > 0 {
+		description += ", followed by errors in " + strings.Join(top.CorroboratedBy, ", ")
+	}
+	return description
+}
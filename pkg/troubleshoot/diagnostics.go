@@ -0,0 +1,391 @@
+package troubleshoot
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+)
+
+// probeTimeout bounds each individual diagnostic probe
+const probeTimeout = 5 * time.Second
+
+// Deep mode latency sampling
+const (
+	latencySamples          = 5
+	latencyWarningThreshold = 500 * time.Millisecond
+)
+
+// Disk usage thresholds for local targets, in percent
+const (
+	diskWarningThreshold  = 80.0
+	diskCriticalThreshold = 90.0
+)
+
+// Check statuses reported by diagnostic probes
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// Kinds of diagnostic target
+const (
+	targetLocal    = "local"
+	targetURL      = "url"
+	targetHostPort = "host_port"
+	targetHost     = "host"
+)
+
+// diagnosticTarget is a parsed DiagnosticOptions.Target
+type diagnosticTarget struct {
+	kind string
+	host string
+	port string
+	url  *url.URL
+	path string
+}
+
+// address returns the host:port the target is reachable on
+func (d *diagnosticTarget) address() string {
+	return net.JoinHostPort(d.host, d.port)
+}
+
+// parseDiagnosticTarget classifies a target as a local path, URL, host:port
+// or bare host; an empty target diagnoses the local machine
+func parseDiagnosticTarget(target string) (*diagnosticTarget, error) {
+	if target == "" {
+		target = "."
+	}
+
+	if _, err := os.Stat(target); err == nil {
+		path, err := filepath.Abs(target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve path %s: %w", target, err)
+		}
+		return &diagnosticTarget{kind: targetLocal, path: path}, nil
+	}
+
+	if strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target URL %s: %w", target, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("unsupported target URL scheme: %s", u.Scheme)
+		}
+		if u.Hostname() == "" {
+			return nil, fmt.Errorf("target URL %s has no host", target)
+		}
+
+		port := u.Port()
+		if port == "" {
+			port = "80"
+			if u.Scheme == "https" {
+				port = "443"
+			}
+		}
+		return &diagnosticTarget{kind: targetURL, host: u.Hostname(), port: port, url: u}, nil
+	}
+
+	if host, port, err := net.SplitHostPort(target); err == nil {
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return nil, fmt.Errorf("invalid port in target %s", target)
+		}
+		return &diagnosticTarget{kind: targetHostPort, host: host, port: port}, nil
+	}
+
+	return &diagnosticTarget{kind: targetHost, host: target}, nil
+}
+
+// diagnosticRun accumulates the checks and issues of one diagnostic pass
+type diagnosticRun struct {
+	checks []*DiagnosticCheck
+	issues []*DiagnosticIssue
+}
+
+// record adds a finished check, and the issue it raised if any
+func (r *diagnosticRun) record(check *DiagnosticCheck, issue *DiagnosticIssue) {
+	r.checks = append(r.checks, check)
+	if issue != nil {
+		r.issues = append(r.issues, issue)
+	}
+}
+
+// checkDNS resolves the target host
+func checkDNS(ctx context.Context, host string) (*DiagnosticCheck, *DiagnosticIssue) {
+	check := &DiagnosticCheck{Name: "DNS Resolution", Metadata: map[string]string{"host": host}}
+	start := time.Now()
+
+	if ip := net.ParseIP(host); ip != nil {
+		check.Status = checkPass
+		check.Result = fmt.Sprintf("%s is an IP address", host)
+		check.Duration = time.Since(start)
+		return check, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	check.Duration = time.Since(start)
+	if err != nil {
+		check.Status = checkFail
+		check.Result = fmt.Sprintf("Failed to resolve %s", host)
+		check.Details = err.Error()
+		return check, &DiagnosticIssue{
+			Severity:    "high",
+			Title:       fmt.Sprintf("DNS resolution failed for %s", host),
+			Description: err.Error(),
+			Impact:      "Clients cannot locate the service by name",
+			Solution:    "Verify the hostname is spelled correctly, that its DNS records exist, and that the configured resolvers are reachable",
+			Metadata:    map[string]string{"host": host},
+		}
+	}
+
+	check.Status = checkPass
+	check.Result = fmt.Sprintf("%s resolved to %d addresses", host, len(addrs))
+	check.Details = strings.Join(addrs, ", ")
+	check.Metadata["addresses"] = strconv.Itoa(len(addrs))
+	return check, nil
+}
+
+// dialTarget opens and closes a TCP connection, returning how long it took
+func dialTarget(ctx context.Context, address string) (time.Duration, error) {
+	dialer := net.Dialer{Timeout: probeTimeout}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	elapsed := time.Since(start)
+	if err != nil {
+		return elapsed, err
+	}
+	conn.Close()
+	return elapsed, nil
+}
+
+// checkTCP verifies that the target port accepts connections
+func checkTCP(ctx context.Context, address string) (*DiagnosticCheck, *DiagnosticIssue) {
+	check := &DiagnosticCheck{Name: "TCP Connectivity", Metadata: map[string]string{"address": address}}
+
+	elapsed, err := dialTarget(ctx, address)
+	check.Duration = elapsed
+	if err != nil {
+		check.Status = checkFail
+		check.Result = fmt.Sprintf("Cannot connect to %s", address)
+		check.Details = err.Error()
+		return check, &DiagnosticIssue{
+			Severity:    "critical",
+			Title:       fmt.Sprintf("%s is not accepting connections", address),
+			Description: err.Error(),
+			Impact:      "The service is unreachable",
+			Solution:    "Check that the service is running and listening on this port, and that firewalls or security groups allow the connection",
+			Metadata:    map[string]string{"address": address},
+		}
+	}
+
+	check.Status = checkPass
+	check.Result = fmt.Sprintf("Connected to %s in %s", address, elapsed.Round(time.Millisecond))
+	return check, nil
+}
+
+// checkHTTP performs a GET health check against the target URL
+func checkHTTP(ctx context.Context, target *url.URL) (*DiagnosticCheck, *DiagnosticIssue) {
+	check := &DiagnosticCheck{Name: "HTTP Health", Metadata: map[string]string{"url": target.String()}}
+
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		check.Status = checkFail
+		check.Result = "Failed to build health check request"
+		check.Details = err.Error()
+		return check, nil
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	check.Duration = time.Since(start)
+	if err != nil {
+		check.Status = checkFail
+		check.Result = fmt.Sprintf("Health check request to %s failed", target)
+		check.Details = err.Error()
+		return check, &DiagnosticIssue{
+			Severity:    "high",
+			Title:       fmt.Sprintf("HTTP health check failed for %s", target),
+			Description: err.Error(),
+			Impact:      "The service does not answer HTTP requests",
+			Solution:    "Check the service logs and TLS configuration, and confirm the URL points at the health endpoint",
+			Metadata:    map[string]string{"url": target.String()},
+		}
+	}
+	resp.Body.Close()
+
+	check.Metadata["status_code"] = strconv.Itoa(resp.StatusCode)
+	check.Result = fmt.Sprintf("%s responded %s in %s", target, resp.Status, check.Duration.Round(time.Millisecond))
+
+	switch {
+	case resp.StatusCode >= 500:
+		check.Status = checkFail
+		return check, &DiagnosticIssue{
+			Severity:    "high",
+			Title:       fmt.Sprintf("%s returned %s", target, resp.Status),
+			Description: "The health endpoint reported a server error",
+			Impact:      "The service is unhealthy",
+			Solution:    "Inspect the service logs for the failing request and restart or roll back the service if needed",
+			Metadata:    map[string]string{"url": target.String(), "status_code": strconv.Itoa(resp.StatusCode)},
+		}
+	case resp.StatusCode >= 400:
+		check.Status = checkWarn
+		return check, &DiagnosticIssue{
+			Severity:    "warning",
+			Title:       fmt.Sprintf("%s returned %s", target, resp.Status),
+			Description: "The health endpoint rejected the request",
+			Impact:      "Health checks against this URL will report the service as down",
+			Solution:    "Verify the health check path and any authentication it requires",
+			Metadata:    map[string]string{"url": target.String(), "status_code": strconv.Itoa(resp.StatusCode)},
+		}
+	}
+
+	check.Status = checkPass
+	return check, nil
+}
+
+// checkLatency samples TCP connect latency to the target
+func checkLatency(ctx context.Context, address string) (*DiagnosticCheck, *DiagnosticIssue) {
+	check := &DiagnosticCheck{Name: "Latency", Metadata: map[string]string{"address": address}}
+	start := time.Now()
+
+	var samples []time.Duration
+	failures := 0
+	for i := 0; i < latencySamples; i++ {
+		elapsed, err := dialTarget(ctx, address)
+		if err != nil {
+			failures++
+			continue
+		}
+		samples = append(samples, elapsed)
+	}
+	check.Duration = time.Since(start)
+	check.Metadata["samples"] = strconv.Itoa(latencySamples)
+	check.Metadata["failures"] = strconv.Itoa(failures)
+
+	if len(samples) == 0 {
+		check.Status = checkFail
+		check.Result = fmt.Sprintf("All %d connection attempts to %s failed", latencySamples, address)
+		return check, nil
+	}
+
+	minimum, maximum, total := samples[0], samples[0], time.Duration(0)
+	for _, sample := range samples {
+		if sample < minimum {
+			minimum = sample
+		}
+		if sample > maximum {
+			maximum = sample
+		}
+		total += sample
+	}
+	average := total / time.Duration(len(samples))
+
+	check.Metadata["min_ms"] = formatMillis(minimum)
+	check.Metadata["avg_ms"] = formatMillis(average)
+	check.Metadata["max_ms"] = formatMillis(maximum)
+	check.Result = fmt.Sprintf("min %sms, avg %sms, max %sms over %d samples", check.Metadata["min_ms"], check.Metadata["avg_ms"], check.Metadata["max_ms"], len(samples))
+
+	if failures > 0 || average > latencyWarningThreshold {
+		check.Status = checkWarn
+		return check, &DiagnosticIssue{
+			Severity:    "warning",
+			Title:       fmt.Sprintf("Unstable connectivity to %s", address),
+			Description: fmt.Sprintf("%d of %d connection attempts failed; average connect time %sms", failures, latencySamples, check.Metadata["avg_ms"]),
+			Impact:      "Requests may be slow or intermittently fail",
+			Solution:    "Check network congestion, packet loss and load on the target host",
+			Metadata:    map[string]string{"address": address},
+		}
+	}
+
+	check.Status = checkPass
+	return check, nil
+}
+
+// checkDisk reports free space on the filesystem holding path
+func checkDisk(ctx context.Context, path string) (*DiagnosticCheck, *DiagnosticIssue) {
+	check := &DiagnosticCheck{Name: "Disk Space", Metadata: map[string]string{"path": path}}
+	start := time.Now()
+
+	usage, err := disk.UsageWithContext(ctx, path)
+	check.Duration = time.Since(start)
+	if err != nil {
+		check.Status = checkFail
+		check.Result = fmt.Sprintf("Failed to read disk usage for %s", path)
+		check.Details = err.Error()
+		return check, nil
+	}
+
+	check.Metadata["used_percent"] = strconv.FormatFloat(usage.UsedPercent, 'f', 1, 64)
+	check.Metadata["free_bytes"] = strconv.FormatUint(usage.Free, 10)
+	check.Result = fmt.Sprintf("%.1f%% used, %s free on %s", usage.UsedPercent, formatBytes(usage.Free), usage.Path)
+
+	severity := ""
+	switch {
+	case usage.UsedPercent >= diskCriticalThreshold:
+		check.Status = checkFail
+		severity = "critical"
+	case usage.UsedPercent >= diskWarningThreshold:
+		check.Status = checkWarn
+		severity = "warning"
+	default:
+		check.Status = checkPass
+		return check, nil
+	}
+
+	issue := &DiagnosticIssue{
+		Severity:    severity,
+		Title:       fmt.Sprintf("Disk space low on %s", usage.Path),
+		Description: check.Result,
+		Impact:      "Services may fail to write logs or data",
+		Solution:    "Remove unused files, rotate or vacuum logs, or grow the volume",
+		Metadata:    map[string]string{"path": usage.Path, "used_percent": check.Metadata["used_percent"]},
+	}
+	if runtime.GOOS == "linux" {
+		issue.Remediation = &RecommendedAction{
+			Title:       "Vacuum journal logs",
+			Description: "Remove archived journal files beyond 500MB",
+			Command:     "journalctl --vacuum-size=500M",
+			Risk:        "medium",
+			Automated:   true,
+			Destructive: true,
+			Metadata:    map[string]string{"timeout": "60s"},
+		}
+	}
+	return check, issue
+}
+
+// formatMillis renders a duration as fractional milliseconds
+func formatMillis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 2, 64)
+}
+
+// formatBytes renders a byte count with a binary unit suffix
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,135 @@
+package troubleshoot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// historyFileName is the JSONL file sessions are appended to under the config dir
+const historyFileName = "troubleshoot_history.jsonl"
+
+// maxHistoryLine bounds the size of a single recorded session
+const maxHistoryLine = 1024 * 1024
+
+// historyStore appends troubleshooting sessions to a JSON lines file
+type historyStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// newHistoryStore creates a history store backed by path
+func newHistoryStore(path string) *historyStore {
+	return &historyStore{path: path}
+}
+
+// Append writes a session as a new line at the end of the history file
+func (h *historyStore) Append(session *TroubleshootingSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	return nil
+}
+
+// Recent returns up to limit sessions, newest first; limit <= 0 returns all.
+// Lines that cannot be parsed, such as one truncated by a crash, are skipped
+func (h *historyStore) Recent(limit int) ([]*TroubleshootingSession, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	file, err := os.Open(h.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*TroubleshootingSession{}, nil
+		}
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer file.Close()
+
+	var sessions []*TroubleshootingSession
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxHistoryLine)
+	for scanner.Scan() {
+		var session TroubleshootingSession
+		if err := json.Unmarshal(scanner.Bytes(), &session); err != nil {
+			continue
+		}
+		sessions = append(sessions, &session)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	if limit > 0 && len(sessions) > limit {
+		sessions = sessions[len(sessions)-limit:]
+	}
+
+	newest := make([]*TroubleshootingSession, 0, len(sessions))
+	for i := len(sessions) - 1; i >= 0; i-- {
+		newest = append(newest, sessions[i])
+	}
+	return newest, nil
+}
+
+// Clear removes all recorded sessions
+func (h *historyStore) Clear() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := os.Remove(h.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear history: %w", err)
+	}
+	return nil
+}
+
+// recordSession appends a finished invocation to the history; failures are
+// logged rather than returned so they never fail the operation itself
+func (t *TroubleshooterImpl) recordSession(sessionType, summary string, err error, start time.Time, metadata map[string]string) {
+	if t.history == nil {
+		return
+	}
+
+	end := time.Now()
+	session := &TroubleshootingSession{
+		ID:        fmt.Sprintf("session-%d", start.UnixNano()),
+		Type:      sessionType,
+		Summary:   summary,
+		Status:    "completed",
+		StartTime: start,
+		EndTime:   end,
+		Duration:  end.Sub(start),
+		Metadata:  metadata,
+	}
+	if err != nil {
+		session.Status = "failed"
+		session.Summary = err.Error()
+	}
+
+	if err := t.history.Append(session); err != nil {
+		logrus.Warnf("Failed to record troubleshooting session: %v", err)
+	}
+}
@@ -1,38 +1,55 @@
 package troubleshoot
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/AlloraAi/AlloraCLI/pkg/agents"
 	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/sirupsen/logrus"
 )
 
 // Troubleshooter interface defines troubleshooting operations
 type Troubleshooter interface {
-	AnalyzeIncident(incident Incident) (*IncidentAnalysis, error)
-	GetSuggestions(request SuggestionRequest) (*SuggestionResponse, error)
-	AutoFix(options AutofixOptions) ([]*AutofixResult, error)
-	RunDiagnostics(options DiagnosticOptions) (*DiagnosticReport, error)
-	GetHistory(limit int) ([]*TroubleshootingSession, error)
+	AnalyzeIncident(ctx context.Context, incident Incident) (*IncidentAnalysis, error)
+	GetSuggestions(ctx context.Context, request SuggestionRequest) (*SuggestionResponse, error)
+	AutoFix(ctx context.Context, options AutofixOptions) ([]*AutofixResult, error)
+	RunDiagnostics(ctx context.Context, options DiagnosticOptions) (*DiagnosticReport, error)
+	RunDiagnosticsBatch(ctx context.Context, targets []string, options DiagnosticOptions) (map[string]*DiagnosticReport, error)
+	GetHistory(ctx context.Context, limit int) ([]*TroubleshootingSession, error)
+	ClearHistory(ctx context.Context) error
 }
 
-// Incident represents an incident to be analyzed
+// Incident represents an incident to be analyzed. Logs and the values of
+// Sources are log content or paths to log files; Sources names each one, such
+// as the service and system logs
 type Incident struct {
-	Logs     string `json:"logs" yaml:"logs"`
-	Service  string `json:"service" yaml:"service"`
-	Severity string `json:"severity" yaml:"severity"`
+	Logs     string            `json:"logs" yaml:"logs"`
+	Sources  map[string]string `json:"sources,omitempty" yaml:"sources,omitempty"`
+	Service  string            `json:"service" yaml:"service"`
+	Severity string            `json:"severity" yaml:"severity"`
 }
 
 // IncidentAnalysis represents the analysis of an incident
 type IncidentAnalysis struct {
-	Summary     string               `json:"summary" yaml:"summary"`
-	RootCause   string               `json:"root_cause" yaml:"root_cause"`
-	Impact      string               `json:"impact" yaml:"impact"`
-	Urgency     string               `json:"urgency" yaml:"urgency"`
-	Suggestions []*Suggestion        `json:"suggestions" yaml:"suggestions"`
-	Actions     []*RecommendedAction `json:"actions" yaml:"actions"`
-	Metadata    map[string]string    `json:"metadata" yaml:"metadata"`
-	Timestamp   time.Time            `json:"timestamp" yaml:"timestamp"`
+	Summary   string `json:"summary" yaml:"summary"`
+	RootCause string `json:"root_cause" yaml:"root_cause"`
+	Impact    string `json:"impact" yaml:"impact"`
+	Urgency   string `json:"urgency" yaml:"urgency"`
+	// Candidates are the possible root causes found in the logs, most
+	// likely first; RootCause describes the first
+	Candidates  []*RootCauseCandidate `json:"candidates,omitempty" yaml:"candidates,omitempty"`
+	Suggestions []*Suggestion         `json:"suggestions" yaml:"suggestions"`
+	Actions     []*RecommendedAction  `json:"actions" yaml:"actions"`
+	Metadata    map[string]string     `json:"metadata" yaml:"metadata"`
+	Timestamp   time.Time             `json:"timestamp" yaml:"timestamp"`
 }
 
 // SuggestionRequest represents a request for troubleshooting suggestions
@@ -63,8 +80,10 @@ type Suggestion struct {
 	Metadata    map[string]string `json:"metadata" yaml:"metadata"`
 }
 
-// AutofixOptions represents options for auto-fixing issues
+// AutofixOptions represents options for auto-fixing issues. Severity is the
+// highest issue severity attempted; Confirm allows destructive actions
 type AutofixOptions struct {
+	Target   string `json:"target" yaml:"target"`
 	Severity string `json:"severity" yaml:"severity"`
 	DryRun   bool   `json:"dry_run" yaml:"dry_run"`
 	Confirm  bool   `json:"confirm" yaml:"confirm"`
@@ -74,7 +93,9 @@ type AutofixOptions struct {
 type AutofixResult struct {
 	Issue     string    `json:"issue" yaml:"issue"`
 	Action    string    `json:"action" yaml:"action"`
+	Command   string    `json:"command,omitempty" yaml:"command,omitempty"`
 	Status    string    `json:"status" yaml:"status"`
+	Output    string    `json:"output,omitempty" yaml:"output,omitempty"`
 	Error     string    `json:"error,omitempty" yaml:"error,omitempty"`
 	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
 }
@@ -109,12 +130,13 @@ type DiagnosticCheck struct {
 
 // DiagnosticIssue represents an issue found during diagnostics
 type DiagnosticIssue struct {
-	Severity    string            `json:"severity" yaml:"severity"`
-	Title       string            `json:"title" yaml:"title"`
-	Description string            `json:"description" yaml:"description"`
-	Impact      string            `json:"impact" yaml:"impact"`
-	Solution    string            `json:"solution" yaml:"solution"`
-	Metadata    map[string]string `json:"metadata" yaml:"metadata"`
+	Severity    string             `json:"severity" yaml:"severity"`
+	Title       string             `json:"title" yaml:"title"`
+	Description string             `json:"description" yaml:"description"`
+	Impact      string             `json:"impact" yaml:"impact"`
+	Solution    string             `json:"solution" yaml:"solution"`
+	Remediation *RecommendedAction `json:"remediation,omitempty" yaml:"remediation,omitempty"`
+	Metadata    map[string]string  `json:"metadata" yaml:"metadata"`
 }
 
 // RecommendedAction represents a recommended action
@@ -124,6 +146,7 @@ type RecommendedAction struct {
 	Command     string            `json:"command" yaml:"command"`
 	Risk        string            `json:"risk" yaml:"risk"`
 	Automated   bool              `json:"automated" yaml:"automated"`
+	Destructive bool              `json:"destructive" yaml:"destructive"`
 	Metadata    map[string]string `json:"metadata" yaml:"metadata"`
 }
 
@@ -141,7 +164,10 @@ type TroubleshootingSession struct {
 
 // TroubleshooterImpl implements the Troubleshooter interface
 type TroubleshooterImpl struct {
-	config *config.Config
+	config  *config.Config
+	history *historyStore
+	// agent analyzes incidents; without one AnalyzeIncident uses heuristics
+	agent agents.Agent
 }
 
 // New creates a new troubleshooter instance
@@ -151,13 +177,31 @@ func New() (Troubleshooter, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config directory: %w", err)
+	}
+
 	return &TroubleshooterImpl{
-		config: cfg,
+		config:  cfg,
+		history: newHistoryStore(filepath.Join(configDir, historyFileName)),
+		agent:   newIncidentAgent(cfg),
 	}, nil
 }
 
-// AnalyzeIncident analyzes an incident and provides recommendations
-func (t *TroubleshooterImpl) AnalyzeIncident(incident Incident) (*IncidentAnalysis, error) {
+// AnalyzeIncident analyzes an incident and provides recommendations. When
+// the incident has logs, error spikes are correlated across its sources to
+// rank candidate root causes. With an agent configured, the agent determines
+// the root cause, impact and suggestions from the logs and candidates; when
+// it fails or times out the heuristic analysis is returned instead
+func (t *TroubleshooterImpl) AnalyzeIncident(ctx context.Context, incident Incident) (*IncidentAnalysis, error) {
+	start := time.Now()
+
+	sources, err := incidentSources(incident)
+	if err != nil {
+		return nil, err
+	}
+
 	// Mock implementation - in real scenario, this would use AI to analyze logs
 	analysis := &IncidentAnalysis{
 		Summary:   fmt.Sprintf("Incident analysis for %s service", incident.Service),
@@ -197,17 +241,47 @@ func (t *TroubleshooterImpl) AnalyzeIncident(incident Incident) (*IncidentAnalys
 			},
 		},
 		Metadata: map[string]string{
-			"analyzed_by": "ai-troubleshooter",
+			"analyzed_by": "heuristic",
 			"version":     "1.0.0",
 		},
 		Timestamp: time.Now(),
 	}
 
+	if len(sources) > 0 {
+		names := make([]string, 0, len(sources))
+		for name := range sources {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		analysis.Candidates = rankRootCauses(sources, start)
+		analysis.RootCause = describeRootCause(analysis.Candidates)
+		analysis.Metadata["sources"] = strings.Join(names, ",")
+		analysis.Metadata["correlation_window"] = correlationWindow.String()
+	}
+
+	if t.agent != nil {
+		if err := t.analyzeWithAgent(ctx, incident, sources, analysis); err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("incident analysis cancelled: %w", ctx.Err())
+			}
+			logrus.Warnf("AI incident analysis failed, using heuristic analysis: %v", err)
+			analysis.Metadata["agent_error"] = err.Error()
+		} else {
+			analysis.Metadata["analyzed_by"] = t.agent.GetName()
+		}
+	}
+
+	t.recordSession("incident_analysis", analysis.Summary, nil, start, map[string]string{
+		"service":  incident.Service,
+		"severity": incident.Severity,
+	})
+
 	return analysis, nil
 }
 
 // GetSuggestions provides troubleshooting suggestions
-func (t *TroubleshooterImpl) GetSuggestions(request SuggestionRequest) (*SuggestionResponse, error) {
+func (t *TroubleshooterImpl) GetSuggestions(ctx context.Context, request SuggestionRequest) (*SuggestionResponse, error) {
 	// Mock implementation
 	response := &SuggestionResponse{
 		Suggestions: []*Suggestion{
@@ -244,124 +318,227 @@ func (t *TroubleshooterImpl) GetSuggestions(request SuggestionRequest) (*Suggest
 	return response, nil
 }
 
-// AutoFix automatically fixes common issues
-func (t *TroubleshooterImpl) AutoFix(options AutofixOptions) ([]*AutofixResult, error) {
-	// Mock implementation
-	results := []*AutofixResult{
-		{
-			Issue:     "High memory usage",
-			Action:    "Clear cache",
-			Status:    "success",
-			Timestamp: time.Now(),
-		},
-		{
-			Issue:     "Disk space low",
-			Action:    "Clean temporary files",
-			Status:    "success",
-			Timestamp: time.Now(),
-		},
-		{
-			Issue:     "Service not responding",
-			Action:    "Restart service",
-			Status:    "success",
-			Timestamp: time.Now(),
-		},
+// AutoFix runs diagnostics against options.Target and executes the automated
+// remediation of each issue found, or describes it when DryRun is set. When
+// ctx is cancelled the remaining issues are left alone and the results so
+// far are returned with the error
+func (t *TroubleshooterImpl) AutoFix(ctx context.Context, options AutofixOptions) ([]*AutofixResult, error) {
+	start := time.Now()
+	metadata := map[string]string{
+		"target":   options.Target,
+		"severity": options.Severity,
+		"dry_run":  strconv.FormatBool(options.DryRun),
 	}
 
-	if options.DryRun {
-		for _, result := range results {
-			result.Status = "would_fix"
+	report, err := t.diagnose(ctx, DiagnosticOptions{Target: options.Target})
+	if err != nil {
+		err = fmt.Errorf("failed to run diagnostics: %w", err)
+		t.recordSession("autofix", "", err, start, metadata)
+		return nil, err
+	}
+
+	results := t.fixIssues(ctx, report.Issues, options)
+	if ctx.Err() != nil {
+		err = fmt.Errorf("autofix cancelled after %d issues: %w", len(results), ctx.Err())
+		metadata["issues_attempted"] = strconv.Itoa(len(results))
+		t.recordSession("autofix", "", err, start, metadata)
+		return results, err
+	}
+
+	fixed := 0
+	for _, result := range results {
+		if result.Status == "success" {
+			fixed++
 		}
 	}
+	metadata["issues_attempted"] = strconv.Itoa(len(results))
+	metadata["issues_fixed"] = strconv.Itoa(fixed)
+
+	summary := fmt.Sprintf("Auto-fixed %d of %d issues", fixed, len(results))
+	if options.DryRun {
+		summary = fmt.Sprintf("Dry run found %d issues to fix", len(results))
+	}
+	t.recordSession("autofix", summary, nil, start, metadata)
 
 	return results, nil
 }
 
-// RunDiagnostics runs comprehensive system diagnostics
-func (t *TroubleshooterImpl) RunDiagnostics(options DiagnosticOptions) (*DiagnosticReport, error) {
+// RunDiagnostics probes options.Target: local paths get a disk space check,
+// hosts get DNS and TCP checks, and URLs additionally get an HTTP health check.
+// Deep mode samples connection latency. Failing probes are reported as issues
+// rather than errors so the remaining checks still run
+func (t *TroubleshooterImpl) RunDiagnostics(ctx context.Context, options DiagnosticOptions) (*DiagnosticReport, error) {
+	start := time.Now()
+	report, err := t.diagnose(ctx, options)
+
+	metadata := map[string]string{
+		"target": options.Target,
+		"deep":   strconv.FormatBool(options.Deep),
+	}
+	summary := ""
+	if report != nil {
+		summary = report.Summary
+		metadata["checks"] = strconv.Itoa(len(report.Checks))
+		metadata["issues"] = strconv.Itoa(len(report.Issues))
+	}
+	t.recordSession("diagnostics", summary, err, start, metadata)
+
+	return report, err
+}
+
+// maxConcurrentDiagnostics bounds how many targets RunDiagnosticsBatch probes at once
+const maxConcurrentDiagnostics = 8
+
+// RunDiagnosticsBatch runs RunDiagnostics against each target concurrently,
+// with options.Target ignored. A target that fails does not stop the batch:
+// the reports of the others are returned, keyed by target, together with an
+// error naming each target that failed. Targets not yet started when ctx is
+// cancelled fail with the context's error
+func (t *TroubleshooterImpl) RunDiagnosticsBatch(ctx context.Context, targets []string, options DiagnosticOptions) (map[string]*DiagnosticReport, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no diagnostic targets given")
+	}
+
+	// Probe each target once, however often it is given
+	unique := make([]string, 0, len(targets))
+	seen := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		if !seen[target] {
+			seen[target] = true
+			unique = append(unique, target)
+		}
+	}
+
+	reports := make([]*DiagnosticReport, len(unique))
+	errs := make([]error, len(unique))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < min(maxConcurrentDiagnostics, len(unique)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					errs[i] = fmt.Errorf("%s: %w", unique[i], err)
+					continue
+				}
+				targetOptions := options
+				targetOptions.Target = unique[i]
+				report, err := t.RunDiagnostics(ctx, targetOptions)
+				if err != nil {
+					errs[i] = fmt.Errorf("%s: %w", unique[i], err)
+					continue
+				}
+				reports[i] = report
+			}
+		}()
+	}
+
+dispatch:
+	for i := range unique {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			for j := i; j < len(unique); j++ {
+				errs[j] = fmt.Errorf("%s: %w", unique[j], ctx.Err())
+			}
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	results := make(map[string]*DiagnosticReport, len(unique))
+	for i, report := range reports {
+		if report != nil {
+			results[unique[i]] = report
+		}
+	}
+
+	err := errors.Join(errs...)
+	switch {
+	case err == nil:
+		return results, nil
+	case len(results) == 0:
+		return results, fmt.Errorf("failed to diagnose every target: %w", err)
+	default:
+		return results, fmt.Errorf("failed to diagnose some targets: %w", err)
+	}
+}
+
+// diagnose runs the diagnostic probes for a target without recording a session
+func (t *TroubleshooterImpl) diagnose(ctx context.Context, options DiagnosticOptions) (*DiagnosticReport, error) {
 	startTime := time.Now()
 
-	// Mock implementation
+	target, err := parseDiagnosticTarget(options.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &diagnosticRun{}
+	switch target.kind {
+	case targetLocal:
+		run.record(checkDisk(ctx, target.path))
+	default:
+		run.record(checkDNS(ctx, target.host))
+		if target.kind != targetHost {
+			run.record(checkTCP(ctx, target.address()))
+		}
+		if target.kind == targetURL {
+			run.record(checkHTTP(ctx, target.url))
+		}
+		if options.Deep && target.kind != targetHost {
+			run.record(checkLatency(ctx, target.address()))
+		}
+	}
+
+	passed := 0
+	for _, check := range run.checks {
+		if check.Status == checkPass {
+			passed++
+		}
+	}
+
+	summary := fmt.Sprintf("%d of %d checks passed", passed, len(run.checks))
+	if len(run.issues) > 0 {
+		summary = fmt.Sprintf("%s; %d issues found", summary, len(run.issues))
+	}
+
 	report := &DiagnosticReport{
 		Target:  options.Target,
 		Status:  "completed",
-		Summary: "System diagnostics completed successfully",
-		Checks: []*DiagnosticCheck{
-			{
-				Name:     "Service Health",
-				Status:   "pass",
-				Result:   "All services are running normally",
-				Details:  "Checked 5 services, all responding within expected timeframes",
-				Duration: 2 * time.Second,
-				Metadata: map[string]string{"services_checked": "5"},
-			},
-			{
-				Name:     "Resource Usage",
-				Status:   "pass",
-				Result:   "Resource usage within normal limits",
-				Details:  "CPU: 25%, Memory: 50%, Disk: 20%",
-				Duration: 1 * time.Second,
-				Metadata: map[string]string{"cpu": "25", "memory": "50", "disk": "20"},
-			},
-			{
-				Name:     "Network Connectivity",
-				Status:   "pass",
-				Result:   "Network connectivity is healthy",
-				Details:  "All external endpoints reachable",
-				Duration: 3 * time.Second,
-				Metadata: map[string]string{"endpoints_checked": "3"},
-			},
-		},
-		Issues: []*DiagnosticIssue{
-			{
-				Severity:    "warning",
-				Title:       "Log rotation needed",
-				Description: "Log files are growing large",
-				Impact:      "May cause disk space issues",
-				Solution:    "Configure log rotation or clean old logs",
-				Metadata:    map[string]string{"log_size": "2GB"},
-			},
-		},
+		Summary: summary,
+		Checks:  run.checks,
+		Issues:  run.issues,
 		Metadata: map[string]string{
 			"diagnostics_version": "1.0.0",
 			"target":              options.Target,
+			"target_type":         target.kind,
+			"deep":                strconv.FormatBool(options.Deep),
 		},
 		Duration:  time.Since(startTime),
 		Timestamp: time.Now(),
 	}
+	if ctx.Err() != nil {
+		report.Status = "cancelled"
+	}
 
 	return report, nil
 }
 
-// GetHistory returns troubleshooting history
-func (t *TroubleshooterImpl) GetHistory(limit int) ([]*TroubleshootingSession, error) {
-	// Mock implementation
-	sessions := []*TroubleshootingSession{
-		{
-			ID:        "session-001",
-			Type:      "incident_analysis",
-			Summary:   "Analyzed high memory usage incident",
-			Status:    "completed",
-			StartTime: time.Now().Add(-2 * time.Hour),
-			EndTime:   time.Now().Add(-2*time.Hour + 15*time.Minute),
-			Duration:  15 * time.Minute,
-			Metadata:  map[string]string{"service": "web-server", "severity": "high"},
-		},
-		{
-			ID:        "session-002",
-			Type:      "autofix",
-			Summary:   "Auto-fixed disk space issue",
-			Status:    "completed",
-			StartTime: time.Now().Add(-24 * time.Hour),
-			EndTime:   time.Now().Add(-24*time.Hour + 5*time.Minute),
-			Duration:  5 * time.Minute,
-			Metadata:  map[string]string{"issues_fixed": "3"},
-		},
+// GetHistory returns the most recent troubleshooting sessions, newest first
+func (t *TroubleshooterImpl) GetHistory(ctx context.Context, limit int) ([]*TroubleshootingSession, error) {
+	if t.history == nil {
+		return []*TroubleshootingSession{}, nil
 	}
+	return t.history.Recent(limit)
+}
 
-	if limit > 0 && len(sessions) > limit {
-		sessions = sessions[:limit]
+// ClearHistory removes all recorded troubleshooting sessions
+func (t *TroubleshooterImpl) ClearHistory(ctx context.Context) error {
+	if t.history == nil {
+		return nil
 	}
-
-	return sessions, nil
+	return t.history.Clear()
 }
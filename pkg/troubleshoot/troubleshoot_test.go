@@ -0,0 +1,473 @@
+package troubleshoot
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/agents"
+)
+
+func fixableIssue(severity, command string, destructive bool) *DiagnosticIssue {
+	return &DiagnosticIssue{
+		Severity: severity,
+		Title:    "issue " + command,
+		Solution: "fix it",
+		Remediation: &RecommendedAction{
+			Title:       "run " + command,
+			Command:     command,
+			Automated:   true,
+			Destructive: destructive,
+			Metadata:    map[string]string{"timeout": "5s"},
+		},
+	}
+}
+
+func TestFixIssuesDryRun(t *testing.T) {
+	ts := &TroubleshooterImpl{}
+	issues := []*DiagnosticIssue{
+		fixableIssue("warning", "echo warn", false),
+		fixableIssue("critical", "echo critical", false),
+		{Severity: "low", Title: "manual", Solution: "do it by hand"},
+	}
+
+	results := ts.fixIssues(context.Background(), issues, AutofixOptions{Severity: "medium", DryRun: true})
+	if len(results) != 2 {
+		t.Fatalf("expected the critical issue to be filtered out, got %d results", len(results))
+	}
+	if results[0].Status != "would_fix" || results[0].Command != "echo warn" {
+		t.Errorf("expected a dry-run result with the command, got %+v", results[0])
+	}
+	if results[1].Status != "skipped" {
+		t.Errorf("expected an issue without remediation to be skipped, got %+v", results[1])
+	}
+}
+
+func TestFixIssuesRunsCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("remediation commands use sh")
+	}
+
+	ts := &TroubleshooterImpl{}
+	timeout := fixableIssue("high", "sleep 5", false)
+	timeout.Remediation.Metadata["timeout"] = "50ms"
+	issues := []*DiagnosticIssue{
+		fixableIssue("low", "echo fixed", false),
+		fixableIssue("low", "echo broken >&2; exit 3", false),
+		fixableIssue("low", "echo destroyed", true),
+		timeout,
+	}
+
+	results := ts.fixIssues(context.Background(), issues, AutofixOptions{Severity: "high"})
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+
+	if results[0].Status != "success" || results[0].Output != "fixed" {
+		t.Errorf("expected a successful fix with captured output, got %+v", results[0])
+	}
+	if results[1].Status != "failed" || !strings.Contains(results[1].Error, "broken") {
+		t.Errorf("expected stderr in the failure, got %+v", results[1])
+	}
+	if results[2].Status != "skipped" || !strings.Contains(results[2].Error, "confirm") {
+		t.Errorf("expected the destructive action to require confirmation, got %+v", results[2])
+	}
+	if results[3].Status != "failed" || !strings.Contains(results[3].Error, "timed out") {
+		t.Errorf("expected the action to time out, got %+v", results[3])
+	}
+
+	confirmed := ts.fixIssues(context.Background(), issues[2:3], AutofixOptions{Confirm: true})
+	if confirmed[0].Status != "success" || confirmed[0].Output != "destroyed" {
+		t.Errorf("expected the confirmed destructive action to run, got %+v", confirmed[0])
+	}
+}
+
+func TestFixIssuesStopsWhenCancelled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("remediation commands use sh")
+	}
+
+	ts := &TroubleshooterImpl{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	issues := []*DiagnosticIssue{
+		fixableIssue("low", "echo fixed", false),
+		fixableIssue("low", "sleep 5", false),
+		fixableIssue("low", "echo never", false),
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	results := ts.fixIssues(ctx, issues, AutofixOptions{})
+	if len(results) != 2 {
+		t.Fatalf("expected the issue after the cancelled one to be left alone, got %d results", len(results))
+	}
+	if results[0].Status != "success" || results[1].Status != "failed" {
+		t.Errorf("expected a fix and then a cancelled fix, got %+v and %+v", results[0], results[1])
+	}
+}
+
+func TestParseDiagnosticTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		kind   string
+		host   string
+		port   string
+	}{
+		{"", targetLocal, "", ""},
+		{"https://example.com/healthz", targetURL, "example.com", "443"},
+		{"http://example.com:8080", targetURL, "example.com", "8080"},
+		{"db.internal:5432", targetHostPort, "db.internal", "5432"},
+		{"db.internal", targetHost, "db.internal", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := parseDiagnosticTarget(tt.target)
+		if err != nil {
+			t.Fatalf("parseDiagnosticTarget(%q) failed: %v", tt.target, err)
+		}
+		if got.kind != tt.kind || got.host != tt.host || got.port != tt.port {
+			t.Errorf("parseDiagnosticTarget(%q) = %+v, expected kind %s host %s port %s", tt.target, got, tt.kind, tt.host, tt.port)
+		}
+	}
+
+	if _, err := parseDiagnosticTarget("ftp://example.com"); err == nil {
+		t.Error("expected an error for an unsupported URL scheme")
+	}
+}
+
+func TestRunDiagnosticsURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	ts := &TroubleshooterImpl{}
+	report, err := ts.RunDiagnostics(context.Background(), DiagnosticOptions{Target: server.URL, Deep: true})
+	if err != nil {
+		t.Fatalf("RunDiagnostics() failed: %v", err)
+	}
+
+	names := []string{"DNS Resolution", "TCP Connectivity", "HTTP Health", "Latency"}
+	if len(report.Checks) != len(names) {
+		t.Fatalf("expected %d checks, got %d", len(names), len(report.Checks))
+	}
+	for i, check := range report.Checks {
+		if check.Name != names[i] || check.Status != checkPass {
+			t.Errorf("check %d: expected passing %s, got %s %s (%s)", i, names[i], check.Name, check.Status, check.Details)
+		}
+	}
+	if len(report.Issues) != 0 {
+		t.Errorf("expected no issues, got %d", len(report.Issues))
+	}
+
+	report, err = ts.RunDiagnostics(context.Background(), DiagnosticOptions{Target: server.URL + "/broken"})
+	if err != nil {
+		t.Fatalf("RunDiagnostics() failed: %v", err)
+	}
+	if len(report.Issues) != 1 || report.Issues[0].Severity != "high" {
+		t.Errorf("expected a high severity issue for a 503, got %+v", report.Issues)
+	}
+}
+
+func TestRunDiagnosticsClosedPort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	ts := &TroubleshooterImpl{}
+	report, err := ts.RunDiagnostics(context.Background(), DiagnosticOptions{Target: address})
+	if err != nil {
+		t.Fatalf("RunDiagnostics() failed: %v", err)
+	}
+
+	if len(report.Checks) != 2 || report.Checks[0].Status != checkPass || report.Checks[1].Status != checkFail {
+		t.Fatalf("expected a passing DNS check and a failing TCP check, got %+v", report.Checks)
+	}
+	if len(report.Issues) != 1 || report.Issues[0].Solution == "" {
+		t.Errorf("expected one issue with remediation text, got %+v", report.Issues)
+	}
+}
+
+func TestRunDiagnosticsBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	ts := &TroubleshooterImpl{}
+	reports, err := ts.RunDiagnosticsBatch(context.Background(), []string{dir, server.URL, "ftp://example.com", dir}, DiagnosticOptions{})
+	if err == nil || !strings.Contains(err.Error(), "ftp://example.com:") {
+		t.Fatalf("expected an error naming the unsupported target, got %v", err)
+	}
+
+	if len(reports) != 2 {
+		t.Fatalf("expected reports for the two valid targets, got %d", len(reports))
+	}
+	if report := reports[dir]; report == nil || report.Target != dir {
+		t.Errorf("expected a report for %s, got %+v", dir, report)
+	}
+	if report := reports[server.URL]; report == nil || len(report.Checks) != 3 {
+		t.Errorf("expected DNS, TCP and HTTP checks for %s, got %+v", server.URL, report)
+	}
+}
+
+func TestRunDiagnosticsBatchCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	targets := make([]string, maxConcurrentDiagnostics*2)
+	for i := range targets {
+		targets[i] = filepath.Join(t.TempDir(), "target")
+	}
+
+	ts := &TroubleshooterImpl{}
+	reports, err := ts.RunDiagnosticsBatch(ctx, targets, DiagnosticOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if len(reports) != 0 {
+		t.Errorf("expected no reports after cancellation, got %d", len(reports))
+	}
+}
+
+func TestRunDiagnosticsLocal(t *testing.T) {
+	ts := &TroubleshooterImpl{}
+	report, err := ts.RunDiagnostics(context.Background(), DiagnosticOptions{Target: t.TempDir()})
+	if err != nil {
+		t.Fatalf("RunDiagnostics() failed: %v", err)
+	}
+
+	if len(report.Checks) != 1 || report.Checks[0].Name != "Disk Space" {
+		t.Fatalf("expected a single disk space check, got %+v", report.Checks)
+	}
+	if _, ok := report.Checks[0].Metadata["used_percent"]; !ok && report.Checks[0].Status != checkFail {
+		t.Errorf("expected disk usage metadata, got %+v", report.Checks[0])
+	}
+}
+
+func TestHistoryRecordsDiagnostics(t *testing.T) {
+	ts := &TroubleshooterImpl{history: newHistoryStore(filepath.Join(t.TempDir(), historyFileName))}
+
+	if _, err := ts.AnalyzeIncident(context.Background(), Incident{Service: "web", Severity: "high"}); err != nil {
+		t.Fatalf("AnalyzeIncident() failed: %v", err)
+	}
+	target := t.TempDir()
+	if _, err := ts.RunDiagnostics(context.Background(), DiagnosticOptions{Target: target}); err != nil {
+		t.Fatalf("RunDiagnostics() failed: %v", err)
+	}
+
+	history, err := ts.GetHistory(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("GetHistory() failed: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(history))
+	}
+
+	latest := history[0]
+	if latest.Type != "diagnostics" || latest.Metadata["target"] != target || latest.ID == "" {
+		t.Errorf("expected the diagnostic session first, got %+v", latest)
+	}
+	if latest.EndTime.Before(latest.StartTime) {
+		t.Errorf("expected end time after start time, got %+v", latest)
+	}
+	if history[1].Type != "incident_analysis" {
+		t.Errorf("expected the incident analysis second, got %s", history[1].Type)
+	}
+
+	limited, err := ts.GetHistory(context.Background(), 1)
+	if err != nil || len(limited) != 1 || limited[0].Type != "diagnostics" {
+		t.Errorf("expected only the newest session, got %+v (%v)", limited, err)
+	}
+
+	if err := ts.ClearHistory(context.Background()); err != nil {
+		t.Fatalf("ClearHistory() failed: %v", err)
+	}
+	if history, _ := ts.GetHistory(context.Background(), 10); len(history) != 0 {
+		t.Errorf("expected history to be empty after clearing, got %d sessions", len(history))
+	}
+}
+
+func TestAnalyzeIncidentCorrelatesSources(t *testing.T) {
+	// The database starts failing at 10:01, the service follows at 10:02
+	database := strings.Join([]string{
+		"2024-03-10 10:00:10 INFO checkpoint complete",
+		"2024-03-10 10:01:05 ERROR too many connections for role app",
+		"2024-03-10 10:01:20 ERROR too many connections for role app",
+		"2024-03-10 10:01:40 ERROR too many connections for role app",
+		"2024-03-10 10:05:00 INFO checkpoint complete",
+	}, "\n")
+	service := strings.Join([]string{
+		"2024-03-10 10:00:30 WARN slow request took 900ms",
+		"2024-03-10 10:02:01 ERROR request 17 failed: upstream timeout",
+		"2024-03-10 10:02:03 ERROR request 18 failed: upstream timeout",
+		"2024-03-10 10:02:09 ERROR request 19 failed: upstream timeout",
+		"2024-03-10 10:02:30 WARN slow request took 1200ms",
+	}, "\n")
+	system := strings.Join([]string{
+		"2024-03-10 09:00:00 WARN disk usage at 81%",
+		"2024-03-10 09:30:00 WARN disk usage at 82%",
+	}, "\n")
+
+	logFile := filepath.Join(t.TempDir(), "api.log")
+	if err := os.WriteFile(logFile, []byte(service), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := &TroubleshooterImpl{}
+	analysis, err := ts.AnalyzeIncident(context.Background(), Incident{
+		Service: "api",
+		Logs:    logFile,
+		Sources: map[string]string{"database": database, "system": system},
+	})
+	if err != nil {
+		t.Fatalf("AnalyzeIncident failed: %v", err)
+	}
+
+	if len(analysis.Candidates) != 2 {
+		t.Fatalf("expected the two spiking sources as candidates, got %+v", analysis.Candidates)
+	}
+	top, next := analysis.Candidates[0], analysis.Candidates[1]
+	if top.Source != "database" || top.Pattern != "too many connections for role app" || top.Occurrences != 3 {
+		t.Errorf("expected the database, which failed first, to rank highest, got %+v", top)
+	}
+	if next.Source != "api" || !strings.Contains(next.Pattern, "upstream timeout") {
+		t.Errorf("expected the service errors to rank second, got %+v", next)
+	}
+	if top.Confidence <= next.Confidence {
+		t.Errorf("expected confidence to fall with rank, got %v then %v", top.Confidence, next.Confidence)
+	}
+	if len(top.CorroboratedBy) != 1 || top.CorroboratedBy[0] != "api" {
+		t.Errorf("expected the database spike to be corroborated by the service, got %v", top.CorroboratedBy)
+	}
+	if !strings.Contains(analysis.RootCause, "too many connections") || !strings.Contains(analysis.RootCause, "followed by errors in api") {
+		t.Errorf("unexpected root cause: %s", analysis.RootCause)
+	}
+	if analysis.Metadata["sources"] != "api,database,system" {
+		t.Errorf("unexpected sources metadata: %q", analysis.Metadata["sources"])
+	}
+}
+
+func TestAnalyzeIncidentSingleLogs(t *testing.T) {
+	ts := &TroubleshooterImpl{}
+
+	analysis, err := ts.AnalyzeIncident(context.Background(), Incident{Service: "api", Severity: "high"})
+	if err != nil {
+		t.Fatalf("AnalyzeIncident failed: %v", err)
+	}
+	if analysis.RootCause == "" || analysis.Candidates != nil || analysis.Urgency != "high" {
+		t.Errorf("expected an incident without logs to keep the default analysis, got %+v", analysis)
+	}
+
+	// Lines without timestamps cannot be correlated, so the most frequent error wins
+	logs := "ERROR cache miss storm\nWARN retrying\nERROR cache miss storm\n"
+	analysis, err = ts.AnalyzeIncident(context.Background(), Incident{Service: "api", Logs: logs})
+	if err != nil {
+		t.Fatalf("AnalyzeIncident failed: %v", err)
+	}
+	if len(analysis.Candidates) != 1 || analysis.Candidates[0].Source != "api" || analysis.Candidates[0].Occurrences != 2 {
+		t.Fatalf("expected one candidate from the logs, got %+v", analysis.Candidates)
+	}
+	if !strings.Contains(analysis.RootCause, "cache miss storm") {
+		t.Errorf("unexpected root cause: %s", analysis.RootCause)
+	}
+
+	if _, err := ts.AnalyzeIncident(context.Background(), Incident{Service: "api", Logs: logs, Sources: map[string]string{"api": logs}}); err == nil {
+		t.Error("expected an error when the service logs are given twice")
+	}
+}
+
+// fakeAgent answers queries with a canned reply, recording the last query
+type fakeAgent struct {
+	agents.Agent
+	reply string
+	err   error
+	query *agents.Query
+}
+
+func (f *fakeAgent) GetName() string { return "fake" }
+
+func (f *fakeAgent) Query(ctx context.Context, query *agents.Query) (*agents.Response, error) {
+	f.query = query
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.reply == "" {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &agents.Response{Content: f.reply}, nil
+}
+
+func TestAnalyzeIncidentWithAgent(t *testing.T) {
+	logs := "2024-03-10 10:01:05 ERROR out of memory: killed process 42\n2024-03-10 10:01:09 ERROR out of memory: killed process 43\n"
+	agent := &fakeAgent{reply: "Here is the analysis:\n```json\n" +
+		`{"root_cause": "Memory leak in the image resizer", "impact": "Uploads fail", ` +
+		`"suggestions": [{"title": "Roll back", "priority": "high", "confidence": 0.9, "commands": ["kubectl rollout undo deployment/api"]}]}` +
+		"\n```"}
+	ts := &TroubleshooterImpl{agent: agent}
+
+	analysis, err := ts.AnalyzeIncident(context.Background(), Incident{Service: "api", Severity: "high", Logs: logs})
+	if err != nil {
+		t.Fatalf("AnalyzeIncident failed: %v", err)
+	}
+	if analysis.RootCause != "Memory leak in the image resizer" || analysis.Impact != "Uploads fail" {
+		t.Errorf("expected the agent's findings, got %q / %q", analysis.RootCause, analysis.Impact)
+	}
+	if len(analysis.Suggestions) != 1 || analysis.Suggestions[0].Commands[0] != "kubectl rollout undo deployment/api" {
+		t.Errorf("expected the agent's suggestions, got %+v", analysis.Suggestions)
+	}
+	if analysis.Metadata["analyzed_by"] != "fake" || len(analysis.Candidates) != 1 {
+		t.Errorf("expected the agent analysis alongside the candidates, got %+v", analysis)
+	}
+	for _, want := range []string{"Service: api", "Severity: high", "killed process 43", "Candidate causes"} {
+		if !strings.Contains(agent.query.Text, want) {
+			t.Errorf("expected the prompt to contain %q:\n%s", want, agent.query.Text)
+		}
+	}
+}
+
+func TestAnalyzeIncidentAgentFallback(t *testing.T) {
+	logs := "ERROR cache miss storm\nERROR cache miss storm\n"
+	for _, agent := range []*fakeAgent{
+		{err: errors.New("no quota")},
+		{reply: "I think it is the cache."},
+		{reply: `{"impact": "slow pages"}`},
+	} {
+		ts := &TroubleshooterImpl{agent: agent}
+		analysis, err := ts.AnalyzeIncident(context.Background(), Incident{Service: "api", Logs: logs})
+		if err != nil {
+			t.Fatalf("AnalyzeIncident failed: %v", err)
+		}
+		if !strings.Contains(analysis.RootCause, "cache miss storm") || analysis.Metadata["analyzed_by"] != "heuristic" {
+			t.Errorf("expected the heuristic analysis, got %q by %s", analysis.RootCause, analysis.Metadata["analyzed_by"])
+		}
+		if analysis.Metadata["agent_error"] == "" {
+			t.Error("expected the agent error in the metadata")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	ts := &TroubleshooterImpl{agent: &fakeAgent{}}
+	if _, err := ts.AnalyzeIncident(ctx, Incident{Service: "api", Logs: logs}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the caller's deadline to stop the analysis, got %v", err)
+	}
+}
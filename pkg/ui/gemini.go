@@ -1,20 +1,30 @@
 package ui
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/AlloraAi/AlloraCLI/pkg/agents"
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/chzyer/readline"
 	"github.com/fatih/color"
 )
 
+// historyFileName is the file in the config directory holding Gemini prompt history
+const historyFileName = "gemini_history"
+
+// specialCommands lists the slash commands handled by handleSpecialCommands
+var specialCommands = []string{"/help", "/clear", "/export", "/load", "/agent", "/sessions", "/resume", "/summary", "/usage", "/examples", "/quit", "/exit"}
+
 // AnimatedLogo represents the animated ASCII art logo
 type AnimatedLogo struct {
 	frames []string
@@ -78,30 +88,61 @@ type Message struct {
 // GeminiInterface represents the Gemini-style interface
 type GeminiInterface struct {
 	colorEnabled bool
+	// animate enables the logo, thinking spinner and typing effects
+	animate      bool
 	conversation []Message
 	agents       *agents.AgentManager
+	readline     *readline.Instance
+	// session is the ID of the session exchanges are saved to
+	session string
+	// sessionDir holds saved sessions; auto-save is off when it is empty
+	sessionDir string
+	// resume loads the most recent session when the interface starts
+	resume bool
+	// pinnedAgent answers every query when set instead of routing by intent
+	pinnedAgent string
+	// historySummary summarizes the first summarized messages of the
+	// conversation, which are no longer sent to agents
+	historySummary string
+	summarized     int
 }
 
-// NewGeminiInterface creates a new Gemini interface
-func NewGeminiInterface(colorEnabled bool) *GeminiInterface {
+// NewGeminiInterface creates a new Gemini interface. Animations are only
+// shown when animate is set and stdout is a terminal
+func NewGeminiInterface(colorEnabled, animate bool) *GeminiInterface {
 	return &GeminiInterface{
 		colorEnabled: colorEnabled,
+		animate:      animate && NewUIManager(colorEnabled, false).IsOutputTerminal(),
 		conversation: make([]Message, 0),
 		agents:       agents.NewAgentManager(),
+		session:      time.Now().Format(sessionIDFormat),
+		sessionDir:   defaultSessionDir(),
 	}
 }
 
+// Agents returns the manager of the agents queries are routed to
+func (g *GeminiInterface) Agents() *agents.AgentManager {
+	return g.agents
+}
+
+// SetResume sets whether Start resumes the most recent saved session
+func (g *GeminiInterface) SetResume(resume bool) {
+	g.resume = resume
+}
+
 // displayWelcome shows the welcome screen
 func (g *GeminiInterface) displayWelcome() {
-	// Clear screen
-	fmt.Print("\033[2J\033[H")
+	if g.animate {
+		// Clear screen
+		fmt.Print("\033[2J\033[H")
 
-	// Create and start animated logo
-	logo := NewAnimatedLogo()
-	logo.Start()
+		// Create and start animated logo
+		logo := NewAnimatedLogo()
+		logo.Start()
 
-	// Clear screen again
-	fmt.Print("\033[2J\033[H")
+		// Clear screen again
+		fmt.Print("\033[2J\033[H")
+	}
 
 	// Display welcome message
 	if g.colorEnabled {
@@ -144,12 +185,8 @@ func (g *GeminiInterface) typeText(text string) {
 		color.Set(color.FgWhite)
 	}
 
-	for _, char := range text {
-		fmt.Print(string(char))
-		// Random delay between 10-50ms for realistic typing
-		delay := time.Duration(rand.Intn(40)+10) * time.Millisecond
-		time.Sleep(delay)
-	}
+	// Random delay between 10-50ms for realistic typing
+	g.typeChars(text, 10, 40)
 
 	if g.colorEnabled {
 		color.Unset()
@@ -158,6 +195,22 @@ func (g *GeminiInterface) typeText(text string) {
 	fmt.Println()
 }
 
+// typeChars prints text one character at a time, pausing between minDelay
+// and minDelay+jitter milliseconds after each, or all at once when
+// animations are off
+func (g *GeminiInterface) typeChars(text string, minDelay, jitter int) {
+	if !g.animate {
+		fmt.Print(text)
+		return
+	}
+
+	for _, char := range text {
+		fmt.Print(string(char))
+		delay := time.Duration(rand.Intn(jitter)+minDelay) * time.Millisecond
+		time.Sleep(delay)
+	}
+}
+
 // showQuickTips displays quick tips for using the interface
 func (g *GeminiInterface) showQuickTips() {
 	if g.colorEnabled {
@@ -191,54 +244,90 @@ func (g *GeminiInterface) showQuickTips() {
 	fmt.Println()
 }
 
-// displayPrompt shows the input prompt
-func (g *GeminiInterface) displayPrompt() {
+// prompt returns the input prompt
+func (g *GeminiInterface) prompt() string {
 	if g.colorEnabled {
-		color.Set(color.FgCyan, color.Bold)
+		return color.New(color.FgCyan, color.Bold).Sprint("💬 You: ")
 	}
+	return "💬 You: "
+}
 
-	fmt.Print("💬 You: ")
+// newReadline creates the line editor, keeping history in the config directory
+func (g *GeminiInterface) newReadline() (*readline.Instance, error) {
+	historyFile := ""
+	if configDir, err := config.GetConfigDir(); err == nil {
+		if err := os.MkdirAll(configDir, 0700); err == nil {
+			historyFile = filepath.Join(configDir, historyFileName)
+		}
+	}
 
-	if g.colorEnabled {
-		color.Unset()
+	items := make([]readline.PrefixCompleterInterface, 0, len(specialCommands))
+	for _, command := range specialCommands {
+		items = append(items, readline.PcItem(command))
 	}
+
+	return readline.NewEx(&readline.Config{
+		Prompt:            g.prompt(),
+		HistoryFile:       historyFile,
+		HistorySearchFold: true,
+		AutoComplete:      readline.NewPrefixCompleter(items...),
+		// Only chat input is recorded, not answers to prompts like /load
+		DisableAutoSaveHistory: true,
+	})
 }
 
-// displayThinking shows the thinking animation
-func (g *GeminiInterface) displayThinking() {
+// readLine asks for a single line with a one-off prompt
+func (g *GeminiInterface) readLine(prompt string) (string, error) {
+	defer g.readline.SetPrompt(g.prompt())
+	g.readline.SetPrompt(prompt)
+	line, err := g.readline.Readline()
+	return strings.TrimSpace(line), err
+}
+
+// displayThinking shows the thinking animation, ending with the name of
+// the speaker about to answer
+func (g *GeminiInterface) displayThinking(speaker string) {
 	if g.colorEnabled {
 		color.Set(color.FgMagenta)
 	}
 
+	if !g.animate {
+		fmt.Printf("🤖 %s: ", speaker)
+		if g.colorEnabled {
+			color.Unset()
+		}
+		return
+	}
+
 	thinkingChars := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 
 	for i := 0; i < 20; i++ {
-		fmt.Printf("\r🤖 AlloraAi: %s Thinking...", thinkingChars[i%len(thinkingChars)])
+		fmt.Printf("\r🤖 %s: %s Thinking...", speaker, thinkingChars[i%len(thinkingChars)])
 		time.Sleep(time.Millisecond * 100)
 	}
 
-	fmt.Print("\r🤖 AlloraAi: ")
+	fmt.Printf("\r🤖 %s: ", speaker)
 
 	if g.colorEnabled {
 		color.Unset()
 	}
 }
 
-// displayResponse shows the AI response with typing effect
-func (g *GeminiInterface) displayResponse(response string) {
-	g.displayThinking()
+// displayResponse shows the AI response with typing effect, naming the
+// agent that answered when there is one
+func (g *GeminiInterface) displayResponse(agent agents.Agent, response string) {
+	speaker := "AlloraAi"
+	if agent != nil {
+		speaker = fmt.Sprintf("AlloraAi (%s)", agent.GetName())
+	}
+	g.displayThinking(speaker)
 
 	if g.colorEnabled {
 		color.Set(color.FgGreen)
 	}
 
-	// Type the response
-	for _, char := range response {
-		fmt.Print(string(char))
-		// Faster typing for responses
-		delay := time.Duration(rand.Intn(20)+5) * time.Millisecond
-		time.Sleep(delay)
-	}
+	// Faster typing for responses
+	g.typeChars(response, 5, 20)
 
 	if g.colorEnabled {
 		color.Unset()
@@ -264,39 +353,132 @@ func (g *GeminiInterface) displayError(errMsg string) {
 // handleUserInput processes user input and generates responses
 func (g *GeminiInterface) handleUserInput(input string) error {
 	// Add user message to conversation
-	g.addToConversation("user", input)
+	question := g.addToConversation("user", input)
 
 	// Create context for AI processing
 	ctx := context.Background()
 
-	// Process the input with AI agents
-	response, err := g.agents.ProcessQuery(ctx, input)
+	// Route the input to the pinned agent or the one best suited to it
+	response, agent, err := g.agents.RouteQueryWith(ctx, input, g.pinnedAgent, g.buildQuery)
 	if err != nil {
 		return fmt.Errorf("failed to process query: %w", err)
 	}
 
 	// Display response
-	g.displayResponse(response)
+	g.displayResponse(agent, response)
 
 	// Add AI response to conversation
-	g.addToConversation("assistant", response)
+	answer := g.addToConversation("assistant", response)
+
+	if err := g.saveToSession(question, answer); err != nil {
+		g.displayError(fmt.Sprintf("Failed to save session: %v", err))
+	}
 
 	return nil
 }
 
 // addToConversation adds a message to the conversation history
-func (g *GeminiInterface) addToConversation(role, content string) {
+func (g *GeminiInterface) addToConversation(role, content string) Message {
 	message := Message{
 		Role:      role,
 		Content:   content,
 		Timestamp: time.Now(),
 	}
 	g.conversation = append(g.conversation, message)
+	return message
+}
+
+// pinAgent pins the conversation to the named agent; "auto" goes back to
+// routing each query by topic and no name shows the current choice
+func (g *GeminiInterface) pinAgent(name string) {
+	switch strings.ToLower(name) {
+	case "":
+		if g.pinnedAgent == "" {
+			fmt.Println("🧭 Queries are routed to the agent best suited to each topic")
+		} else {
+			fmt.Printf("📌 Conversation is pinned to %s\n", g.pinnedAgent)
+		}
+		names := make([]string, 0)
+		for _, agent := range g.agents.ListAgents() {
+			names = append(names, agent.GetName())
+		}
+		if len(names) > 0 {
+			sort.Strings(names)
+			fmt.Printf("Available agents: %s\n", strings.Join(names, ", "))
+		}
+		return
+	case "auto":
+		g.pinnedAgent = ""
+		fmt.Println("🧭 Routing queries to the agent best suited to each topic")
+		return
+	}
+
+	agent, err := g.agents.FindAgent(name)
+	if err != nil {
+		g.displayError(fmt.Sprintf("Failed to pin agent: %v", err))
+		return
+	}
+	g.pinnedAgent = agent.GetName()
+	fmt.Printf("📌 Conversation pinned to %s; use /agent auto to route by topic again\n", g.pinnedAgent)
+}
+
+// buildQuery builds the query for the latest user message, sending as much
+// of the earlier conversation as fits in the agent's context budget. Older
+// messages are dropped, or summarized when the agent is configured to
+func (g *GeminiInterface) buildQuery(ctx context.Context, agent agents.Agent) (*agents.Query, error) {
+	if len(g.conversation) == 0 {
+		return nil, fmt.Errorf("no message to send")
+	}
+	latest := g.conversation[len(g.conversation)-1]
+
+	turns := make([]agents.Turn, 0, len(g.conversation)-1-g.summarized)
+	for _, msg := range g.conversation[g.summarized : len(g.conversation)-1] {
+		turns = append(turns, agents.Turn{Role: msg.Role, Content: msg.Content})
+	}
+
+	// A latest message larger than the budget leaves no room for history,
+	// nor for a summary of it
+	budget := max(agents.ContextBudget(agent)-agents.EstimateTokens(latest.Content), 0)
+	summarize := agent.GetConfiguration() != nil && agent.GetConfiguration().SummarizeHistory
+	summaryBudget := 0
+	if summarize {
+		summaryBudget = budget / 4
+	}
+
+	kept, dropped := agents.FitHistory(turns, budget-summaryBudget-agents.EstimateTokens(g.historySummary))
+	if summarize && summaryBudget > 0 && len(dropped) > 0 {
+		summary, err := agents.SummarizeHistory(ctx, agent, g.historySummary, dropped, summaryBudget)
+		if err != nil {
+			g.displayError(fmt.Sprintf("Earlier messages were left out: %v", err))
+		} else {
+			g.historySummary = summary
+			g.summarized += len(dropped)
+			kept, _ = agents.FitHistory(turns[len(dropped):], budget-agents.EstimateTokens(summary))
+		}
+	}
+
+	history := make([]agents.Turn, 0, len(kept)+1)
+	if g.historySummary != "" {
+		history = append(history, agents.Turn{Role: "system", Content: "Summary of the earlier conversation: " + g.historySummary})
+	}
+	history = append(history, kept...)
+
+	return &agents.Query{
+		Text:    latest.Content,
+		Context: map[string]interface{}{agents.HistoryContextKey: history},
+	}, nil
+}
+
+// setConversation replaces the conversation, discarding any summary of the old one
+func (g *GeminiInterface) setConversation(conversation []Message) {
+	g.conversation = conversation
+	g.historySummary = ""
+	g.summarized = 0
 }
 
 // clearConversation clears the conversation history
 func (g *GeminiInterface) clearConversation() {
-	g.conversation = make([]Message, 0)
+	g.setConversation(make([]Message, 0))
 	fmt.Println("🗑️ Conversation history cleared!")
 }
 
@@ -315,13 +497,17 @@ func (g *GeminiInterface) ExportConversation(filename string) error {
 	}
 	defer file.Close()
 
-	// Write conversation as JSON
-	encoder := json.NewEncoder(file)
+	return writeConversation(file, g.conversation)
+}
+
+// writeConversation writes messages in the export format
+func writeConversation(w io.Writer, conversation []Message) error {
+	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
 
 	exportData := map[string]interface{}{
 		"exported_at":  time.Now().Format(time.RFC3339),
-		"conversation": g.conversation,
+		"conversation": conversation,
 	}
 
 	if err := encoder.Encode(exportData); err != nil {
@@ -333,40 +519,83 @@ func (g *GeminiInterface) ExportConversation(filename string) error {
 
 // LoadConversation loads a conversation from a file
 func (g *GeminiInterface) LoadConversation(filename string) error {
+	conversation, err := readConversation(filename)
+	if err != nil {
+		return err
+	}
+
+	g.setConversation(conversation)
+	return nil
+}
+
+// readConversation reads the messages of an exported conversation file
+func readConversation(filename string) ([]Message, error) {
 	file, err := os.Open(filename)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
 	var exportData map[string]interface{}
 	decoder := json.NewDecoder(file)
 	if err := decoder.Decode(&exportData); err != nil {
-		return fmt.Errorf("failed to decode conversation: %w", err)
-	}
-
-	// Clear current conversation
-	g.conversation = []Message{}
-
-	// Load conversation messages
-	if conv, ok := exportData["conversation"].([]interface{}); ok {
-		for _, msgInterface := range conv {
-			if msgMap, ok := msgInterface.(map[string]interface{}); ok {
-				msg := Message{
-					Role:    msgMap["role"].(string),
-					Content: msgMap["content"].(string),
-				}
-				if timestamp, ok := msgMap["timestamp"].(string); ok {
-					if t, err := time.Parse(time.RFC3339, timestamp); err == nil {
-						msg.Timestamp = t
-					}
-				}
-				g.conversation = append(g.conversation, msg)
-			}
+		return nil, fmt.Errorf("failed to decode conversation: %w", err)
+	}
+
+	conv, ok := exportData["conversation"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s has no conversation list", filename)
+	}
+
+	conversation := make([]Message, 0, len(conv))
+	for i, msgInterface := range conv {
+		msg, err := parseMessage(msgInterface)
+		if err != nil {
+			return nil, fmt.Errorf("invalid message %d in %s: %w", i+1, filename, err)
 		}
+		conversation = append(conversation, msg)
 	}
 
-	return nil
+	return conversation, nil
+}
+
+// parseMessage converts a decoded conversation entry to a Message. Role and
+// content are required; a missing or malformed timestamp is left zero
+func parseMessage(entry interface{}) (Message, error) {
+	msgMap, ok := entry.(map[string]interface{})
+	if !ok {
+		return Message{}, fmt.Errorf("expected an object, got %T", entry)
+	}
+
+	role, err := stringField(msgMap, "role")
+	if err != nil {
+		return Message{}, err
+	}
+	content, err := stringField(msgMap, "content")
+	if err != nil {
+		return Message{}, err
+	}
+
+	msg := Message{Role: role, Content: content}
+	if timestamp, ok := msgMap["timestamp"].(string); ok {
+		if t, err := time.Parse(time.RFC3339, timestamp); err == nil {
+			msg.Timestamp = t
+		}
+	}
+	return msg, nil
+}
+
+// stringField returns the string stored under key in m
+func stringField(m map[string]interface{}, key string) (string, error) {
+	value, exists := m[key]
+	if !exists || value == nil {
+		return "", fmt.Errorf("%s is missing", key)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string, got %T", key, value)
+	}
+	return s, nil
 }
 
 // GetConversationSummary returns a summary of the current conversation
@@ -405,7 +634,11 @@ func (g *GeminiInterface) displayMenu() {
 	fmt.Println("│ /clear     - Clear conversation history                                     │")
 	fmt.Println("│ /export    - Export conversation to file                                   │")
 	fmt.Println("│ /load      - Load conversation from file                                   │")
+	fmt.Println("│ /agent     - Pin an agent: /agent <name>, or /agent auto to route by topic │")
+	fmt.Println("│ /sessions  - List saved sessions                                           │")
+	fmt.Println("│ /resume    - Switch to a saved session: /resume <id>                       │")
 	fmt.Println("│ /summary   - Show conversation summary                                     │")
+	fmt.Println("│ /usage     - Show tokens used this session and their estimated cost        │")
 	fmt.Println("│ /examples  - Show example queries                                          │")
 	fmt.Println("│ /quit      - Exit the interface                                           │")
 	fmt.Println("╰─────────────────────────────────────────────────────────────────────────────╯")
@@ -449,7 +682,12 @@ func (g *GeminiInterface) displayExamples() {
 
 // handleSpecialCommands processes special commands like /help, /clear, etc.
 func (g *GeminiInterface) handleSpecialCommands(input string) bool {
-	switch strings.ToLower(strings.TrimSpace(input)) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch strings.ToLower(fields[0]) {
 	case "/help":
 		g.displayMenu()
 		return true
@@ -466,23 +704,31 @@ func (g *GeminiInterface) handleSpecialCommands(input string) bool {
 		}
 		return true
 	case "/load":
-		fmt.Print("Enter filename to load: ")
-		scanner := bufio.NewScanner(os.Stdin)
-		if scanner.Scan() {
-			filename := strings.TrimSpace(scanner.Text())
-			if filename != "" {
-				if err := g.LoadConversation(filename); err != nil {
-					g.displayError(fmt.Sprintf("Failed to load conversation: %v", err))
-				} else {
-					fmt.Printf("✅ Conversation loaded from: %s\n", filename)
-				}
+		filename, err := g.readLine("Enter filename to load: ")
+		if err == nil && filename != "" {
+			if err := g.LoadConversation(filename); err != nil {
+				g.displayError(fmt.Sprintf("Failed to load conversation: %v", err))
+			} else {
+				fmt.Printf("✅ Conversation loaded from: %s\n", filename)
 			}
 		}
 		return true
+	case "/agent":
+		g.pinAgent(strings.Join(fields[1:], " "))
+		return true
+	case "/sessions":
+		g.displaySessions()
+		return true
+	case "/resume":
+		g.switchSession(strings.Join(fields[1:], " "))
+		return true
 	case "/summary":
 		summary := g.GetConversationSummary()
 		fmt.Printf("📊 %s\n", summary)
 		return true
+	case "/usage":
+		g.displayUsage()
+		return true
 	case "/examples":
 		g.displayExamples()
 		return true
@@ -494,6 +740,24 @@ func (g *GeminiInterface) handleSpecialCommands(input string) bool {
 	}
 }
 
+// displayUsage shows the tokens agents used this session and their estimated cost
+func (g *GeminiInterface) displayUsage() {
+	stats := g.agents.UsageReport()
+	if stats.Queries == 0 {
+		fmt.Println("📊 No token usage recorded this session")
+		return
+	}
+	fmt.Printf("📊 %d queries, %d prompt + %d completion tokens, estimated $%.4f\n",
+		stats.Queries, stats.PromptTokens, stats.CompletionTokens, stats.EstimatedCost)
+	for _, model := range stats.Models {
+		cost := "price unknown"
+		if model.Priced {
+			cost = fmt.Sprintf("$%.4f", model.EstimatedCost)
+		}
+		fmt.Printf("   %s: %d queries, %d tokens, %s\n", model.Model, model.Queries, model.TotalTokens, cost)
+	}
+}
+
 // displayGoodbye shows the goodbye message
 func (g *GeminiInterface) displayGoodbye() {
 	if g.colorEnabled {
@@ -510,39 +774,55 @@ func (g *GeminiInterface) displayGoodbye() {
 	}
 }
 
-// Start begins the Gemini interface
+// Start begins the Gemini interface. Ctrl-C discards the current line and
+// Ctrl-D exits
 func (g *GeminiInterface) Start() error {
+	rl, err := g.newReadline()
+	if err != nil {
+		return fmt.Errorf("failed to initialize input: %w", err)
+	}
+	defer rl.Close()
+	g.readline = rl
+
 	// Display welcome message
 	g.displayWelcome()
 
 	// Display menu
 	g.displayMenu()
 
-	// Initialize scanner for user input
-	scanner := bufio.NewScanner(os.Stdin)
+	if g.resume {
+		g.resumeLatestSession()
+	}
 
 	// Main interaction loop
 	for {
-		// Display prompt
-		g.displayPrompt()
-
-		// Read user input
-		if !scanner.Scan() {
-			break
+		line, err := rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			g.displayGoodbye()
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("input error: %w", err)
 		}
 
-		input := strings.TrimSpace(scanner.Text())
+		input := strings.TrimSpace(line)
 
 		// Skip empty input
 		if input == "" {
 			continue
 		}
+		if err := rl.SaveHistory(input); err != nil {
+			g.displayError(fmt.Sprintf("Failed to save history: %v", err))
+		}
 
 		// Handle special commands
 		if g.handleSpecialCommands(input) {
 			// Check if user wants to quit
 			if strings.ToLower(input) == "/quit" || strings.ToLower(input) == "/exit" {
-				break
+				return nil
 			}
 			continue
 		}
@@ -552,11 +832,4 @@ func (g *GeminiInterface) Start() error {
 			g.displayError(fmt.Sprintf("Error processing input: %v", err))
 		}
 	}
-
-	// Check for scanner errors
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("input error: %w", err)
-	}
-
-	return nil
 }
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package ui
+
+import "os"
+
+// notifyResize returns a nil channel where resizes are not signalled, so
+// Watch picks up the new size on its next tick
+func notifyResize() (<-chan os.Signal, func()) {
+	return nil, func() {}
+}
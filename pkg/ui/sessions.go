@@ -0,0 +1,210 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+)
+
+// sessionsDirName is the directory in the config directory holding saved Gemini sessions
+const sessionsDirName = "sessions"
+
+// sessionIDFormat names sessions after the day they were started
+const sessionIDFormat = "2006-01-02"
+
+// ErrNoSessions is returned when resuming without any saved sessions
+var ErrNoSessions = errors.New("no saved sessions")
+
+// SessionInfo describes a saved Gemini session
+type SessionInfo struct {
+	ID       string    `json:"id"`
+	Path     string    `json:"path"`
+	Messages int       `json:"messages"`
+	Updated  time.Time `json:"updated"`
+}
+
+// defaultSessionDir returns where sessions are saved, or "" if the config
+// directory is unavailable, which turns auto-save off
+func defaultSessionDir() string {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, sessionsDirName)
+}
+
+// sessionPath returns the file of the session with the given ID
+func (g *GeminiInterface) sessionPath(id string) (string, error) {
+	if _, err := time.Parse(sessionIDFormat, id); err != nil {
+		return "", fmt.Errorf("invalid session ID %q; session IDs are dates like %s", id, time.Now().Format(sessionIDFormat))
+	}
+	return filepath.Join(g.sessionDir, id+".json"), nil
+}
+
+// ListSessions returns the saved sessions, most recent first
+func (g *GeminiInterface) ListSessions() ([]SessionInfo, error) {
+	if g.sessionDir == "" {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(g.sessionDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read sessions directory: %w", err)
+	}
+
+	sessions := make([]SessionInfo, 0, len(entries))
+	for _, entry := range entries {
+		id := strings.TrimSuffix(entry.Name(), ".json")
+		if entry.IsDir() || id == entry.Name() {
+			continue
+		}
+		path, err := g.sessionPath(id)
+		if err != nil {
+			continue
+		}
+
+		session := SessionInfo{ID: id, Path: path}
+		if info, err := entry.Info(); err == nil {
+			session.Updated = info.ModTime()
+		}
+		if messages, err := readConversation(path); err == nil {
+			session.Messages = len(messages)
+		}
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].ID > sessions[j].ID })
+	return sessions, nil
+}
+
+// ResumeSession loads a saved session and makes it the one new messages are
+// saved to. An empty id resumes the most recent session
+func (g *GeminiInterface) ResumeSession(id string) error {
+	if id == "" {
+		sessions, err := g.ListSessions()
+		if err != nil {
+			return err
+		}
+		if len(sessions) == 0 {
+			return ErrNoSessions
+		}
+		id = sessions[0].ID
+	}
+
+	path, err := g.sessionPath(id)
+	if err != nil {
+		return err
+	}
+	conversation, err := readConversation(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("session %s does not exist; use /sessions to list saved sessions", id)
+		}
+		return err
+	}
+
+	g.setConversation(conversation)
+	g.session = id
+	return nil
+}
+
+// saveToSession appends messages to the active session file
+func (g *GeminiInterface) saveToSession(messages ...Message) error {
+	if g.sessionDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(g.sessionDir, 0700); err != nil {
+		return fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+
+	path, err := g.sessionPath(g.session)
+	if err != nil {
+		return err
+	}
+
+	conversation, err := readConversation(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		conversation = make([]Message, 0, len(messages))
+	}
+	conversation = append(conversation, messages...)
+
+	// Write to a temporary file first so an interrupted save keeps the old session
+	file, err := os.CreateTemp(g.sessionDir, "."+g.session+"-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create session file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if err := writeConversation(file, conversation); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// displaySessions lists the saved sessions, marking the active one
+func (g *GeminiInterface) displaySessions() {
+	sessions, err := g.ListSessions()
+	if err != nil {
+		g.displayError(fmt.Sprintf("Failed to list sessions: %v", err))
+		return
+	}
+	if len(sessions) == 0 {
+		fmt.Println("📂 No saved sessions yet")
+		return
+	}
+
+	fmt.Println("📂 Saved sessions:")
+	for _, session := range sessions {
+		marker := " "
+		if session.ID == g.session {
+			marker = "*"
+		}
+		fmt.Printf(" %s %s  %3d messages  updated %s\n", marker, session.ID, session.Messages, session.Updated.Format("2006-01-02 15:04"))
+	}
+	fmt.Println("Use /resume <id> to switch sessions")
+}
+
+// switchSession resumes the session with the given ID from the prompt
+func (g *GeminiInterface) switchSession(id string) {
+	if id == "" {
+		g.displayError("Usage: /resume <id>; use /sessions to list saved sessions")
+		return
+	}
+	if err := g.ResumeSession(id); err != nil {
+		g.displayError(fmt.Sprintf("Failed to resume session: %v", err))
+		return
+	}
+	fmt.Printf("✅ Resumed session %s (%d messages)\n", g.session, len(g.conversation))
+}
+
+// resumeLatestSession resumes the most recent session when starting with --resume
+func (g *GeminiInterface) resumeLatestSession() {
+	if err := g.ResumeSession(""); err != nil {
+		if errors.Is(err, ErrNoSessions) {
+			fmt.Println("📂 No saved sessions to resume; starting a new session")
+			return
+		}
+		g.displayError(fmt.Sprintf("Failed to resume session: %v", err))
+		return
+	}
+	fmt.Printf("✅ Resumed session %s (%d messages)\n", g.session, len(g.conversation))
+}
@@ -0,0 +1,28 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/output"
+)
+
+// DisplayTable displays a formatted table, cut to the terminal's width.
+// Columns are measured by their width on screen, so wide characters such as
+// CJK and emoji stay aligned
+func (ui *UIManager) DisplayTable(headers []string, rows [][]string) {
+	if len(rows) == 0 {
+		ui.PrintInfo("No data to display")
+		return
+	}
+
+	options := output.TableOptions{}
+	if ui.IsOutputTerminal() {
+		options.MaxWidth, _ = ui.GetTerminalSize()
+	}
+	if ui.colorEnabled {
+		options.Header = func(cell string) string { return HeaderColor.Sprint(cell) }
+	}
+	output.RenderTable(os.Stdout, headers, rows, options)
+	fmt.Println()
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/fatih/color"
 	"github.com/manifoldco/promptui"
 	"github.com/schollz/progressbar/v3"
+	"golang.org/x/term"
 )
 
 // UIManager manages user interface components
@@ -121,9 +122,16 @@ func (ui *UIManager) CreateSpinnerProgressBar(description string) *progressbar.P
 
 // InteractivePrompt creates an interactive prompt
 func (ui *UIManager) InteractivePrompt(label string, defaultValue string) (string, error) {
+	return ui.prompt(label, defaultValue, 0, nil)
+}
+
+// prompt reads a line, masking it with mask when set, until validate accepts it
+func (ui *UIManager) prompt(label, defaultValue string, mask rune, validate func(string) error) (string, error) {
 	prompt := promptui.Prompt{
-		Label:   label,
-		Default: defaultValue,
+		Label:    label,
+		Default:  defaultValue,
+		Mask:     mask,
+		Validate: validate,
 	}
 
 	return prompt.Run()
@@ -131,10 +139,20 @@ func (ui *UIManager) InteractivePrompt(label string, defaultValue string) (strin
 
 // InteractiveSelect creates an interactive selection menu
 func (ui *UIManager) InteractiveSelect(label string, items []string) (string, error) {
+	return ui.selectItem(label, items, "")
+}
+
+// selectItem shows a selection menu with the cursor on defaultValue
+func (ui *UIManager) selectItem(label string, items []string, defaultValue string) (string, error) {
 	prompt := promptui.Select{
 		Label: label,
 		Items: items,
 	}
+	for i, item := range items {
+		if item == defaultValue {
+			prompt.CursorPos = i
+		}
+	}
 
 	_, result, err := prompt.Run()
 	return result, err
@@ -154,6 +172,10 @@ func (ui *UIManager) InteractiveConfirm(label string, defaultValue bool) (bool,
 	}
 
 	result, err := prompt.Run()
+	if err == promptui.ErrAbort {
+		// promptui reports answering no as an abort
+		return false, nil
+	}
 	if err != nil {
 		return false, err
 	}
@@ -163,12 +185,7 @@ func (ui *UIManager) InteractiveConfirm(label string, defaultValue bool) (bool,
 
 // InteractivePassword creates an interactive password input
 func (ui *UIManager) InteractivePassword(label string) (string, error) {
-	prompt := promptui.Prompt{
-		Label: label,
-		Mask:  '*',
-	}
-
-	return prompt.Run()
+	return ui.prompt(label, "", '*', nil)
 }
 
 // InteractiveMultiSelect creates an interactive multi-selection menu
@@ -216,65 +233,6 @@ func (ui *UIManager) InteractiveMultiSelect(label string, items []string) ([]str
 	return selected, nil
 }
 
-// DisplayTable displays a formatted table
-func (ui *UIManager) DisplayTable(headers []string, rows [][]string) {
-	if len(rows) == 0 {
-		ui.PrintInfo("No data to display")
-		return
-	}
-
-	// Calculate column widths
-	colWidths := make([]int, len(headers))
-	for i, header := range headers {
-		colWidths[i] = len(header)
-	}
-
-	for _, row := range rows {
-		for i, cell := range row {
-			if i < len(colWidths) && len(cell) > colWidths[i] {
-				colWidths[i] = len(cell)
-			}
-		}
-	}
-
-	// Print header
-	ui.printTableRow(headers, colWidths, true)
-	ui.printTableSeparator(colWidths)
-
-	// Print rows
-	for _, row := range rows {
-		ui.printTableRow(row, colWidths, false)
-	}
-
-	fmt.Println()
-}
-
-// printTableRow prints a single table row
-func (ui *UIManager) printTableRow(row []string, widths []int, isHeader bool) {
-	fmt.Print("| ")
-	for i, cell := range row {
-		if i < len(widths) {
-			if isHeader && ui.colorEnabled {
-				HeaderColor.Printf("%-*s", widths[i], cell)
-			} else {
-				fmt.Printf("%-*s", widths[i], cell)
-			}
-			fmt.Print(" | ")
-		}
-	}
-	fmt.Println()
-}
-
-// printTableSeparator prints table separator
-func (ui *UIManager) printTableSeparator(widths []int) {
-	fmt.Print("|")
-	for _, width := range widths {
-		fmt.Print(strings.Repeat("-", width+2))
-		fmt.Print("|")
-	}
-	fmt.Println()
-}
-
 // DisplayKeyValue displays key-value pairs
 func (ui *UIManager) DisplayKeyValue(data map[string]interface{}) {
 	for key, value := range data {
@@ -316,13 +274,18 @@ func (ui *UIManager) ShowSpinner(message string, duration time.Duration) {
 	spinner.Finish()
 }
 
-// InteractiveWizard creates an interactive wizard
+// InteractiveWizard creates an interactive wizard. Steps whose When returns
+// false for the answers so far are skipped, and input steps are asked again
+// until the answer passes Required and Validate
 func (ui *UIManager) InteractiveWizard(title string, steps []WizardStep) (map[string]interface{}, error) {
 	ui.PrintHeader(title)
 
 	results := make(map[string]interface{})
 
 	for i, step := range steps {
+		if step.When != nil && !step.When(results) {
+			continue
+		}
 		ui.PrintInfo("Step %d/%d: %s", i+1, len(steps), step.Title)
 
 		if step.Description != "" {
@@ -334,13 +297,17 @@ func (ui *UIManager) InteractiveWizard(title string, steps []WizardStep) (map[st
 
 		switch step.Type {
 		case WizardStepTypeInput:
-			result, err = ui.InteractivePrompt(step.Prompt, step.Default)
+			result, err = ui.prompt(step.Prompt, step.Default, 0, step.validate)
 		case WizardStepTypeSelect:
-			result, err = ui.InteractiveSelect(step.Prompt, step.Options)
+			result, err = ui.selectItem(step.Prompt, step.Options, step.Default)
 		case WizardStepTypeConfirm:
 			result, err = ui.InteractiveConfirm(step.Prompt, step.Default == "true")
 		case WizardStepTypePassword:
-			result, err = ui.InteractivePassword(step.Prompt)
+			// A masked default could not be seen, so an empty answer keeps it
+			result, err = ui.prompt(step.Prompt, "", '*', step.validate)
+			if err == nil && result == "" {
+				result = step.Default
+			}
 		case WizardStepTypeMultiSelect:
 			result, err = ui.InteractiveMultiSelect(step.Prompt, step.Options)
 		}
@@ -366,6 +333,28 @@ type WizardStep struct {
 	Default     string
 	Options     []string
 	Required    bool
+	// Validate checks the answer to an input or password step
+	Validate func(value string) error
+	// When, if set, decides from the answers so far whether the step is asked
+	When func(results map[string]interface{}) bool
+}
+
+// validate applies Required and Validate to an answer. A password step
+// with a default may be left empty to keep it
+func (step WizardStep) validate(value string) error {
+	if value == "" && step.Type == WizardStepTypePassword && step.Default != "" {
+		return nil
+	}
+	if value == "" {
+		if step.Required {
+			return fmt.Errorf("a value is required")
+		}
+		return nil
+	}
+	if step.Validate != nil {
+		return step.Validate(value)
+	}
+	return nil
 }
 
 // WizardStepType represents the type of wizard step
@@ -419,11 +408,14 @@ func (ui *UIManager) RestoreCursor() {
 	fmt.Print("\033[u")
 }
 
-// GetTerminalSize returns the terminal size
+// GetTerminalSize returns the width and height of the terminal, or 80x24
+// when stdout is not a terminal
 func (ui *UIManager) GetTerminalSize() (int, int) {
-	// This would need platform-specific implementation
-	// For now, return default values
-	return 80, 24
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
 }
 
 // PressEnterToContinue waits for user to press Enter
@@ -460,11 +452,16 @@ func (ui *UIManager) DisplayMenu(title string, options []string) (int, error) {
 
 // IsTerminalInteractive checks if the terminal is interactive
 func (ui *UIManager) IsTerminalInteractive() bool {
-	// Check if stdin is a terminal
-	stat, err := os.Stdin.Stat()
-	if err != nil {
-		return false
-	}
+	return isTerminal(os.Stdin)
+}
+
+// IsOutputTerminal checks if stdout is a terminal rather than a pipe or file
+func (ui *UIManager) IsOutputTerminal() bool {
+	return isTerminal(os.Stdout)
+}
 
-	return (stat.Mode() & os.ModeCharDevice) != 0
+// isTerminal checks if f is a terminal. Other character devices, such as
+// /dev/null, are not
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
 }
@@ -0,0 +1,279 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/agents"
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+)
+
+func TestLoadConversation(t *testing.T) {
+	dir := t.TempDir()
+	g := NewGeminiInterface(false, false)
+	g.conversation = []Message{{Role: "user", Content: "hello"}}
+
+	exported := filepath.Join(dir, "exported.json")
+	if err := g.ExportConversation(exported); err != nil {
+		t.Fatalf("ExportConversation failed: %v", err)
+	}
+
+	loaded := NewGeminiInterface(false, false)
+	if err := loaded.LoadConversation(exported); err != nil {
+		t.Fatalf("LoadConversation failed: %v", err)
+	}
+	if len(loaded.conversation) != 1 || loaded.conversation[0].Content != "hello" {
+		t.Errorf("Expected the exported message, got %+v", loaded.conversation)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"missing content", `{"conversation": [{"role": "user"}]}`, "invalid message 1 in " + filepath.Join(dir, "missing content.json") + ": content is missing"},
+		{"wrong role type", `{"conversation": [{"role": "user", "content": "a"}, {"role": 1, "content": "b"}]}`, "invalid message 2"},
+		{"not an object", `{"conversation": ["hello"]}`, "expected an object, got string"},
+		{"no conversation", `{"exported_at": "2024-01-01T00:00:00Z"}`, "has no conversation list"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := loaded.LoadConversation(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if len(loaded.conversation) != 1 || loaded.conversation[0].Content != "hello" {
+				t.Errorf("Expected the current conversation to be kept, got %+v", loaded.conversation)
+			}
+		})
+	}
+}
+
+func TestSessions(t *testing.T) {
+	dir := t.TempDir()
+	g := NewGeminiInterface(false, false)
+	g.sessionDir = dir
+
+	if err := g.ResumeSession(""); err != ErrNoSessions {
+		t.Fatalf("Expected ErrNoSessions, got %v", err)
+	}
+
+	g.session = "2024-01-14"
+	question := g.addToConversation("user", "list pods")
+	answer := g.addToConversation("assistant", "3 pods")
+	if err := g.saveToSession(question, answer); err != nil {
+		t.Fatalf("saveToSession failed: %v", err)
+	}
+
+	g.session = "2024-01-15"
+	g.clearConversation()
+	if err := g.saveToSession(g.addToConversation("user", "hello")); err != nil {
+		t.Fatalf("saveToSession failed: %v", err)
+	}
+	if err := g.saveToSession(g.addToConversation("assistant", "hi")); err != nil {
+		t.Fatalf("saveToSession failed: %v", err)
+	}
+
+	sessions, err := g.ListSessions()
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].ID != "2024-01-15" || sessions[0].Messages != 2 || sessions[1].ID != "2024-01-14" {
+		t.Fatalf("Expected two sessions, most recent first, got %+v", sessions)
+	}
+
+	// Session files use the export format
+	exported := NewGeminiInterface(false, false)
+	if err := exported.LoadConversation(sessions[1].Path); err != nil {
+		t.Fatalf("LoadConversation failed on a session file: %v", err)
+	}
+	if len(exported.conversation) != 2 || exported.conversation[1].Content != "3 pods" {
+		t.Errorf("Expected the saved exchange, got %+v", exported.conversation)
+	}
+
+	resumed := NewGeminiInterface(false, false)
+	resumed.sessionDir = dir
+	if err := resumed.ResumeSession(""); err != nil {
+		t.Fatalf("ResumeSession failed: %v", err)
+	}
+	if resumed.session != "2024-01-15" || len(resumed.conversation) != 2 {
+		t.Errorf("Expected the most recent session, got %s with %d messages", resumed.session, len(resumed.conversation))
+	}
+
+	if err := resumed.ResumeSession("2024-01-14"); err != nil {
+		t.Fatalf("ResumeSession failed: %v", err)
+	}
+	if resumed.session != "2024-01-14" || resumed.conversation[0].Content != "list pods" {
+		t.Errorf("Expected to switch to 2024-01-14, got %s: %+v", resumed.session, resumed.conversation)
+	}
+
+	if err := resumed.ResumeSession("../secrets"); err == nil || !strings.Contains(err.Error(), "invalid session ID") {
+		t.Errorf("Expected an invalid session ID error, got %v", err)
+	}
+	if err := resumed.ResumeSession("2023-12-31"); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Expected a missing session error, got %v", err)
+	}
+}
+
+func TestBuildQueryFitsContextBudget(t *testing.T) {
+	agent, err := agents.NewAgent(config.Agent{Type: "general", MaxContextTokens: 200})
+	if err != nil {
+		t.Fatalf("NewAgent failed: %v", err)
+	}
+
+	g := NewGeminiInterface(false, false)
+	for i := 0; i < 50; i++ {
+		g.addToConversation("user", fmt.Sprintf("question %d %s", i, strings.Repeat("x", 60)))
+		g.addToConversation("assistant", fmt.Sprintf("answer %d %s", i, strings.Repeat("y", 60)))
+	}
+	g.addToConversation("user", "latest question")
+
+	query, err := g.buildQuery(context.Background(), agent)
+	if err != nil {
+		t.Fatalf("buildQuery failed: %v", err)
+	}
+	if query.Text != "latest question" {
+		t.Errorf("Expected the latest message as the query, got %q", query.Text)
+	}
+
+	history := query.Context[agents.HistoryContextKey].([]agents.Turn)
+	if len(history) == 0 || len(history) >= 100 {
+		t.Fatalf("Expected the history to be trimmed, got %d turns", len(history))
+	}
+	tokens := agents.EstimateTokens(query.Text)
+	for _, turn := range history {
+		tokens += agents.EstimateTokens(turn.Content)
+	}
+	if tokens > 200 {
+		t.Errorf("Expected the query and history to fit in 200 tokens, used %d", tokens)
+	}
+	if last := history[len(history)-1]; !strings.HasPrefix(last.Content, "answer 49 ") {
+		t.Errorf("Expected the most recent turns to be kept, last turn is %q", last.Content)
+	}
+}
+
+func TestBuildQuerySummarizesDroppedTurns(t *testing.T) {
+	agent, err := agents.NewAgent(config.Agent{Type: "general", MaxContextTokens: 200, SummarizeHistory: true})
+	if err != nil {
+		t.Fatalf("NewAgent failed: %v", err)
+	}
+
+	g := NewGeminiInterface(false, false)
+	for i := 0; i < 20; i++ {
+		g.addToConversation("user", strings.Repeat("q", 100))
+		g.addToConversation("assistant", strings.Repeat("a", 100))
+	}
+	g.addToConversation("user", "latest question")
+
+	query, err := g.buildQuery(context.Background(), agent)
+	if err != nil {
+		t.Fatalf("buildQuery failed: %v", err)
+	}
+
+	history := query.Context[agents.HistoryContextKey].([]agents.Turn)
+	if history[0].Role != "system" || !strings.HasPrefix(history[0].Content, "Summary of the earlier conversation: ") {
+		t.Fatalf("Expected a summary of the dropped turns first, got %+v", history[0])
+	}
+	if g.summarized == 0 || g.summarized+len(history)-1 != len(g.conversation)-1 {
+		t.Errorf("Expected every earlier message to be summarized or sent, summarized %d and sent %d of %d", g.summarized, len(history)-1, len(g.conversation)-1)
+	}
+
+	g.clearConversation()
+	if g.historySummary != "" || g.summarized != 0 {
+		t.Error("Expected clearing the conversation to discard its summary")
+	}
+}
+
+func TestBuildQueryWithOversizedLatestMessage(t *testing.T) {
+	agent, err := agents.NewAgent(config.Agent{Type: "general", MaxContextTokens: 200, SummarizeHistory: true})
+	if err != nil {
+		t.Fatalf("NewAgent failed: %v", err)
+	}
+
+	g := NewGeminiInterface(false, false)
+	for i := 0; i < 5; i++ {
+		g.addToConversation("user", strings.Repeat("q", 100))
+		g.addToConversation("assistant", strings.Repeat("a", 100))
+	}
+	g.addToConversation("user", strings.Repeat("z", 2000))
+
+	query, err := g.buildQuery(context.Background(), agent)
+	if err != nil {
+		t.Fatalf("buildQuery failed: %v", err)
+	}
+	if history := query.Context[agents.HistoryContextKey].([]agents.Turn); len(history) != 0 {
+		t.Errorf("Expected no room for history, got %d turns", len(history))
+	}
+	if g.summarized != 0 || g.historySummary != "" {
+		t.Errorf("Expected no summary without a budget for one, summarized %d: %q", g.summarized, g.historySummary)
+	}
+}
+
+func TestWatchRedrawsOnResize(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resized := make(chan os.Signal, 1)
+	var out bytes.Buffer
+	frames := 0
+	err := watch(ctx, &out, func() (int, int) { return 40, 10 }, resized, "status", time.Hour, func(w io.Writer) error {
+		frames++
+		switch frames {
+		case 1:
+			resized <- os.Interrupt
+			fmt.Fprintln(w, "first frame")
+		case 2:
+			resized <- os.Interrupt
+			return errors.New("status unavailable")
+		default:
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+	if frames != 3 {
+		t.Errorf("expected a redraw per resize until cancelled, got %d frames", frames)
+	}
+	for _, want := range []string{"Every 1h0m0s: status", "first frame", "Error: status unavailable"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected the output to contain %q, got %q", want, out.String())
+		}
+	}
+	if !strings.HasSuffix(out.String(), showCursor) {
+		t.Error("expected the cursor to be shown again on exit")
+	}
+
+	if err := watch(ctx, &out, nil, nil, "status", 0, nil); err == nil {
+		t.Error("expected an error for a zero interval")
+	}
+}
+
+func TestFitFrame(t *testing.T) {
+	frame := fitFrame("0123456789\nshort\nthird\nfourth\n", 6, 3)
+	lines := strings.Split(frame, "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", lines)
+	}
+	if lines[0] != "012345"+clearLine || lines[1] != "short"+clearLine {
+		t.Errorf("expected lines cut to the width, got %q", lines)
+	}
+	if !strings.HasPrefix(lines[2], "... 2 ") {
+		t.Errorf("expected the last line to count the hidden lines, got %q", lines[2])
+	}
+}
@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Terminal control sequences used to redraw a watch frame in place
+const (
+	cursorHome  = "\033[H"
+	clearLine   = "\033[K"
+	clearBelow  = "\033[J"
+	clearScreen = "\033[2J"
+	hideCursor  = "\033[?25l"
+	showCursor  = "\033[?25h"
+)
+
+// Watch redraws the output of render under a title line every interval, and
+// straight away when the terminal is resized, until ctx is cancelled. Frames
+// are cut to the terminal size, so a small window shows where output was
+// cut instead of scrolling. An error from render is shown in the frame and
+// watching goes on. Watch needs stdout to be a terminal
+func (ui *UIManager) Watch(ctx context.Context, title string, interval time.Duration, render func(w io.Writer) error) error {
+	if !ui.IsOutputTerminal() {
+		return fmt.Errorf("watching needs a terminal")
+	}
+	resized, stop := notifyResize()
+	defer stop()
+	return watch(ctx, os.Stdout, ui.GetTerminalSize, resized, title, interval, render)
+}
+
+// watch is Watch writing frames to out, sized by size
+func watch(ctx context.Context, out io.Writer, size func() (int, int), resized <-chan os.Signal, title string, interval time.Duration, render func(w io.Writer) error) error {
+	if interval <= 0 {
+		return fmt.Errorf("watch interval must be positive: %s", interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	fmt.Fprint(out, hideCursor+cursorHome+clearScreen)
+	// Leave the last frame on screen, with the cursor below it
+	defer fmt.Fprint(out, "\n"+showCursor)
+
+	for {
+		var content bytes.Buffer
+		if err := render(&content); err != nil {
+			fmt.Fprintf(&content, "\nError: %v\n", err)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		width, height := size()
+		header := fmt.Sprintf("Every %s: %s", interval, title)
+		if stamp := time.Now().Format("15:04:05"); len(header)+len(stamp)+1 < width {
+			header += strings.Repeat(" ", width-len(header)-len(stamp)) + stamp
+		}
+		fmt.Fprint(out, cursorHome+fitFrame(header+"\n\n"+content.String(), width, height)+clearBelow)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-resized:
+			// Clear what the old layout left outside the new size
+			fmt.Fprint(out, clearScreen)
+		case <-ticker.C:
+		}
+	}
+}
+
+// fitFrame cuts content to width columns and height lines, ending each line
+// with a clear to end of line so a shorter frame overwrites a longer one.
+// When lines are dropped the last line says how many
+func fitFrame(content string, width, height int) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if height > 0 && len(lines) > height {
+		hidden := len(lines) - height + 1
+		lines = append(lines[:height-1], fmt.Sprintf("... %d more lines, enlarge the terminal to see them", hidden))
+	}
+
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(truncateColumns(line, width))
+		b.WriteString(clearLine)
+	}
+	return b.String()
+}
+
+// truncateColumns cuts line to at most width runes
+func truncateColumns(line string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(line) <= width {
+		return line
+	}
+	runes := []rune(line)
+	return string(runes[:width])
+}
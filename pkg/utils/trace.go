@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TraceIDHeader is the HTTP header that carries a trace ID between services
+const TraceIDHeader = "X-Request-ID"
+
+// TraceIDField is the log field that holds the trace ID
+const TraceIDField = "trace_id"
+
+type traceIDKey struct{}
+
+var (
+	traceMu        sync.RWMutex
+	defaultTraceID string
+	traceHookOnce  sync.Once
+)
+
+// WithTraceID returns a context carrying a new trace ID
+func WithTraceID(ctx context.Context) context.Context {
+	return ContextWithTraceID(ctx, NewTraceID())
+}
+
+// ContextWithTraceID returns a context carrying the given trace ID, such as
+// one received in an X-Request-ID header
+func ContextWithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceID returns the trace ID carried by ctx, or else the ID of the current
+// invocation set up by InitializeLogging
+func TraceID(ctx context.Context) string {
+	if ctx != nil {
+		if id, ok := ctx.Value(traceIDKey{}).(string); ok && id != "" {
+			return id
+		}
+	}
+	traceMu.RLock()
+	defer traceMu.RUnlock()
+	return defaultTraceID
+}
+
+// NewTraceID generates a random trace ID
+func NewTraceID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// NewLogger creates an info-level logger whose entries carry the trace ID
+func NewLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.InfoLevel)
+	logger.AddHook(traceHook{})
+	return logger
+}
+
+// traceHook adds the trace ID to every log entry that does not have one,
+// taking it from the entry's context when it was logged with one
+type traceHook struct{}
+
+func (traceHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (traceHook) Fire(entry *logrus.Entry) error {
+	if _, ok := entry.Data[TraceIDField]; ok {
+		return nil
+	}
+	if id := TraceID(entry.Context); id != "" {
+		entry.Data[TraceIDField] = id
+	}
+	return nil
+}
+
+// setInvocationTraceID makes the trace ID in ctx the default for log entries
+// and contexts without one, and adds it to the standard logger's entries
+func setInvocationTraceID(ctx context.Context) {
+	if id := TraceID(ctx); id != "" {
+		traceMu.Lock()
+		defaultTraceID = id
+		traceMu.Unlock()
+	}
+	traceHookOnce.Do(func() {
+		logrus.AddHook(traceHook{})
+	})
+}
+
+// TracingTransport sends the trace ID of each request's context in the
+// X-Request-ID header
+type TracingTransport struct {
+	// Base makes the requests, http.DefaultTransport if nil
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *TracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if id := TraceID(req.Context()); id != "" && req.Header.Get(TraceIDHeader) == "" {
+		// A RoundTripper must not modify the caller's request
+		req = req.Clone(req.Context())
+		req.Header.Set(TraceIDHeader, id)
+	}
+	return base.RoundTrip(req)
+}
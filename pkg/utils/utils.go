@@ -2,7 +2,7 @@ package utils
 
 import (
 	"bufio"
-	"encoding/json"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -10,11 +10,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/AlloraAi/AlloraCLI/pkg/output"
 	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v3"
 )
 
 // PrintBanner prints the AlloraCLI banner
@@ -44,50 +44,14 @@ func NewSpinner(message string) *spinner.Spinner {
 
 // DisplayResponse displays a response in the specified format
 func DisplayResponse(data interface{}, format string) error {
-	switch format {
-	case "json":
-		return displayJSON(data)
-	case "yaml":
-		return displayYAML(data)
-	case "table":
-		return displayTable(data)
-	case "text":
-		return displayText(data)
-	default:
-		return fmt.Errorf("unsupported format: %s", format)
-	}
-}
-
-// displayJSON displays data in JSON format
-func displayJSON(data interface{}) error {
-	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(data)
-}
-
-// displayYAML displays data in YAML format
-func displayYAML(data interface{}) error {
-	encoder := yaml.NewEncoder(os.Stdout)
-	defer encoder.Close()
-	return encoder.Encode(data)
+	return output.Render(os.Stdout, format, data)
 }
 
-// displayTable displays data in table format
-func displayTable(data interface{}) error {
-	// This is a simplified table display
-	// In a real implementation, you'd need to handle different data types
-	fmt.Printf("%+v\n", data)
-	return nil
-}
-
-// displayText displays data in human-readable text format
-func displayText(data interface{}) error {
-	fmt.Printf("%+v\n", data)
-	return nil
-}
+// InitializeLogging initializes the logging system. Every log line carries
+// the trace ID in ctx, so one invocation can be followed across the logs
+func InitializeLogging(ctx context.Context, verbose bool) error {
+	setInvocationTraceID(ctx)
 
-// InitializeLogging initializes the logging system
-func InitializeLogging(verbose bool) error {
 	// Set log level
 	if verbose {
 		logrus.SetLevel(logrus.DebugLevel)
@@ -0,0 +1,466 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlloraAi/AlloraCLI/pkg/agents"
+	"github.com/AlloraAi/AlloraCLI/pkg/cloud"
+	"github.com/AlloraAi/AlloraCLI/pkg/config"
+	"github.com/AlloraAi/AlloraCLI/pkg/plugin"
+	"github.com/AlloraAi/AlloraCLI/pkg/security"
+)
+
+// testConfig saves the default configuration, changed by edit, to a
+// temporary file and loads it back the way the CLI does. Cloud providers
+// are left unconfigured, so services fall back to their built-in examples
+// instead of calling out. It sets up the global configuration and a
+// temporary home directory, so tests call it before their subtests go parallel
+func testConfig(t *testing.T, edit func(cfg *config.Config)) *config.Config {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("APPDATA", home)
+
+	cfg, err := config.Default()
+	if err != nil {
+		t.Fatalf("config.Default() failed: %v", err)
+	}
+	cfg.CloudProviders = config.CloudProviders{}
+	if edit != nil {
+		edit(cfg)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := config.Save(cfg, path); err != nil {
+		t.Fatalf("config.Save() failed: %v", err)
+	}
+	if err := config.Initialize(path, false); err != nil {
+		t.Fatalf("config.Initialize() failed: %v", err)
+	}
+	loaded, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load() failed: %v", err)
+	}
+	return loaded
+}
+
+func TestConfig(t *testing.T) {
+	cfg := testConfig(t, func(cfg *config.Config) {
+		cfg.Agents = map[string]config.Agent{
+			"general": {Type: "general", Model: "gpt-4", MaxTokens: 500, Temperature: 0.2},
+		}
+		cfg.CloudProviders.AWS.Region = "eu-west-1"
+	})
+
+	defaults, err := config.Default()
+	if err != nil {
+		t.Fatalf("config.Default() failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"version", cfg.Version, defaults.Version},
+		{"azure subscription", cfg.CloudProviders.Azure.SubscriptionID, ""},
+		{"agent model", cfg.Agents["general"].Model, "gpt-4"},
+		{"agent max tokens", cfg.Agents["general"].MaxTokens, 500},
+		{"aws region", cfg.CloudProviders.AWS.Region, "eu-west-1"},
+		{"log level", cfg.Logging.Level, defaults.Logging.Level},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if tt.got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, tt.got)
+			}
+		})
+	}
+}
+
+// newOpenAIServer serves chat completions answering with reply, or fails
+// every request with status when it is not 200
+func newOpenAIServer(t *testing.T, status int, reply string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"error":{"message":%q,"type":"server_error"}}`, http.StatusText(status))
+			return
+		}
+		fmt.Fprintf(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":%q},"finish_reason":"stop"}],"usage":{"total_tokens":12}}`, reply)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenAIAgent(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		reply   string
+		wantErr bool
+	}{
+		{name: "answer", status: http.StatusOK, reply: "Paris"},
+		{name: "multiline answer", status: http.StatusOK, reply: "1. Check the pods\n2. Restart the deployment"},
+		{name: "server error", status: http.StatusInternalServerError, wantErr: true},
+		{name: "unauthorized", status: http.StatusUnauthorized, wantErr: true},
+	}
+
+	servers := make([]*httptest.Server, len(tests))
+	for i, tt := range tests {
+		servers[i] = newOpenAIServer(t, tt.status, tt.reply)
+	}
+	cfg := testConfig(t, func(cfg *config.Config) {
+		cfg.Agents = map[string]config.Agent{}
+		for i, tt := range tests {
+			cfg.Agents[strings.ReplaceAll(tt.name, " ", "-")] = config.Agent{
+				Type:        "general",
+				Model:       "gpt-4",
+				MaxTokens:   1000,
+				Temperature: 0.7,
+				APIKey:      "test-key",
+				Endpoint:    servers[i].URL,
+			}
+		}
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			agent, err := agents.NewAgent(cfg.Agents[strings.ReplaceAll(tt.name, " ", "-")])
+			if err != nil {
+				t.Fatalf("NewAgent() failed: %v", err)
+			}
+			if _, ok := agent.(*agents.OpenAIAgent); !ok {
+				t.Fatalf("expected an OpenAI agent for model gpt-4, got %T", agent)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			response, err := agent.Query(ctx, &agents.Query{Text: "What is the capital of France?"})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for status %d, got response %+v", tt.status, response)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Query() failed: %v", err)
+			}
+			if response.Content != tt.reply {
+				t.Errorf("expected content %q, got %q", tt.reply, response.Content)
+			}
+		})
+	}
+}
+
+func TestCloudService(t *testing.T) {
+	service := cloud.NewCloudService(testConfig(t, nil))
+
+	t.Run("list resources", func(t *testing.T) {
+		tests := []struct {
+			provider string
+			types    []string
+			wantErr  bool
+		}{
+			{provider: "aws", types: []string{"ec2-instance", "ebs-volume"}},
+			{provider: "azure", types: []string{"virtual-machine"}},
+			{provider: "gcp", types: []string{"compute-instance"}},
+			{provider: "digitalocean", wantErr: true},
+		}
+		for _, tt := range tests {
+			t.Run(tt.provider, func(t *testing.T) {
+				t.Parallel()
+
+				resources, err := service.ListResources(context.Background(), tt.provider, "")
+				if tt.wantErr {
+					if err == nil || !strings.Contains(err.Error(), "unsupported provider") {
+						t.Fatalf("expected an unsupported provider error, got %v", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("ListResources() failed: %v", err)
+				}
+				if len(resources) == 0 {
+					t.Fatal("expected resources, got none")
+				}
+
+				types := map[string]bool{}
+				for _, resource := range resources {
+					if resource.ID == "" || resource.Name == "" {
+						t.Errorf("expected an ID and name, got %+v", resource)
+					}
+					if resource.Provider != tt.provider {
+						t.Errorf("expected provider %s, got %s", tt.provider, resource.Provider)
+					}
+					types[resource.Type] = true
+				}
+				for _, want := range tt.types {
+					if !types[want] {
+						t.Errorf("expected a %s resource, got types %v", want, types)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("list resources limit", func(t *testing.T) {
+		t.Parallel()
+		resources, err := service.ListResources(context.Background(), "aws", "", cloud.WithMax(1))
+		if err != nil {
+			t.Fatalf("ListResources() failed: %v", err)
+		}
+		if len(resources) != 1 {
+			t.Errorf("expected 1 resource, got %d", len(resources))
+		}
+	})
+
+	t.Run("list all resources", func(t *testing.T) {
+		t.Parallel()
+		if _, err := service.ListAllResources(context.Background(), ""); err == nil {
+			t.Error("expected an error with no providers configured")
+		}
+	})
+
+	t.Run("cost analysis", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			budget     float64
+			wantErr    bool
+			wantBudget bool
+		}{
+			{name: "no budget"},
+			{name: "budget", budget: 1000, wantBudget: true},
+			{name: "negative budget", budget: -1, wantErr: true},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				t.Parallel()
+
+				analysis, err := service.GetCostAnalysis(context.Background(), "aws", cloud.CostOptions{Budget: tt.budget})
+				if tt.wantErr {
+					if err == nil {
+						t.Fatal("expected an error")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("GetCostAnalysis() failed: %v", err)
+				}
+				if analysis.TotalCost <= 0 || analysis.Currency == "" || len(analysis.Breakdown) == 0 {
+					t.Errorf("expected a total, currency and breakdown, got %+v", analysis)
+				}
+				if got := analysis.BudgetStatus != nil; got != tt.wantBudget {
+					t.Errorf("expected budget status %v, got %+v", tt.wantBudget, analysis.BudgetStatus)
+				}
+			})
+		}
+	})
+}
+
+// trivyReport is the output of the fake trivy the security tests scan with
+const trivyReport = `{
+  "Results": [
+    {
+      "Target": "go.sum",
+      "Vulnerabilities": [
+        {"VulnerabilityID": "CVE-2023-0001", "PkgName": "golang.org/x/net", "InstalledVersion": "0.1.0", "FixedVersion": "0.7.0", "Severity": "HIGH"},
+        {"VulnerabilityID": "CVE-2023-0002", "PkgName": "example.com/lib", "InstalledVersion": "1.0.0", "Severity": "CRITICAL"}
+      ]
+    }
+  ]
+}`
+
+// installFakeTrivy puts a trivy script printing trivyReport on PATH
+func installFakeTrivy(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake trivy script requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	report := filepath.Join(dir, "report.json")
+	if err := os.WriteFile(report, []byte(trivyReport), 0644); err != nil {
+		t.Fatalf("failed to write report: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "trivy"), []byte("#!/bin/sh\ncat "+report+"\n"), 0755); err != nil {
+		t.Fatalf("failed to write fake trivy: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestSecurityService(t *testing.T) {
+	installFakeTrivy(t)
+	service := security.NewSecurityService(testConfig(t, nil))
+
+	t.Run("scan", func(t *testing.T) {
+		t.Parallel()
+		result, err := service.ScanVulnerabilities(context.Background(), t.TempDir())
+		if err != nil {
+			t.Fatalf("ScanVulnerabilities() failed: %v", err)
+		}
+		want := security.ScanSummary{TotalChecks: 2, CriticalIssues: 1, HighIssues: 1}
+		if result.Summary != want {
+			t.Errorf("expected summary %+v, got %+v", want, result.Summary)
+		}
+		severities := map[string]string{}
+		for _, vuln := range result.Vulnerabilities {
+			severities[vuln.CVE] = vuln.Severity
+		}
+		if severities["CVE-2023-0001"] != "high" || severities["CVE-2023-0002"] != "critical" {
+			t.Errorf("expected lowercased trivy severities, got %v", severities)
+		}
+	})
+
+	t.Run("report", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			targets []string
+		}{
+			{"no targets", nil},
+			{"one target", []string{"."}},
+			{"two targets", []string{".", "go.sum"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				t.Parallel()
+				report, err := service.GenerateSecurityReport(context.Background(), security.ReportOptions{Type: "summary", Targets: tt.targets})
+				if err != nil {
+					t.Fatalf("GenerateSecurityReport() failed: %v", err)
+				}
+				if report.Type != "summary" {
+					t.Errorf("expected type summary, got %q", report.Type)
+				}
+				if len(report.ScanResults) != len(tt.targets) {
+					t.Errorf("expected %d scan results, got %d", len(tt.targets), len(report.ScanResults))
+				}
+			})
+		}
+	})
+
+	t.Run("audit permissions", func(t *testing.T) {
+		// Only AWS ARNs and IAM principals can be audited; anything else is
+		// rejected before credentials are needed
+		for _, resource := range []string{"s3://audit-logs", "vm/web-1"} {
+			t.Run(resource, func(t *testing.T) {
+				t.Parallel()
+				_, err := service.AuditPermissions(context.Background(), resource)
+				if err == nil || !strings.Contains(err.Error(), "give an AWS ARN") {
+					t.Errorf("expected a hint to give an AWS ARN, got %v", err)
+				}
+			})
+		}
+	})
+
+	t.Run("validate policies", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			policies []security.Policy
+		}{
+			{"none", nil},
+			{"one", []security.Policy{{ID: "mfa", Name: "Require MFA"}}},
+			{"two", []security.Policy{{ID: "mfa"}, {ID: "tls"}}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				t.Parallel()
+				result, err := service.ValidateSecurityPolicies(context.Background(), tt.policies)
+				if err != nil {
+					t.Fatalf("ValidateSecurityPolicies() failed: %v", err)
+				}
+				if result.Summary.TotalPolicies != len(tt.policies) {
+					t.Errorf("expected %d policies, got %d", len(tt.policies), result.Summary.TotalPolicies)
+				}
+			})
+		}
+	})
+}
+
+func TestPluginManager(t *testing.T) {
+	cfg := testConfig(t, nil)
+	dir := t.TempDir()
+	notPlugin := filepath.Join(dir, "readme.txt")
+	if err := os.WriteFile(notPlugin, []byte("not a plugin"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	manager := plugin.NewPluginManager(&plugin.PluginConfig{
+		Directory:      cfg.Plugins.Directory,
+		AllowedSources: []string{"github.com/AlloraAi"},
+		MaxPlugins:     1,
+	})
+	t.Cleanup(func() { manager.Shutdown() })
+
+	// Subtests share the manager, so they also exercise its locking
+	tests := []struct {
+		name    string
+		run     func() error
+		wantErr string
+	}{
+		{"get missing plugin", func() error { _, err := manager.GetPlugin("missing"); return err }, "plugin not found"},
+		{"execute missing plugin", func() error { _, err := manager.ExecutePlugin(context.Background(), "missing", nil); return err }, "plugin not found"},
+		{"unload missing plugin", func() error { return manager.UnloadPlugin("missing") }, "plugin not found"},
+		{"load missing file", func() error { return manager.LoadPlugin(filepath.Join(dir, "missing.so")) }, "failed to open plugin"},
+		{"validate missing file", func() error { return manager.ValidatePlugin(filepath.Join(dir, "missing.so")) }, "does not exist"},
+		{"validate wrong extension", func() error { return manager.ValidatePlugin(notPlugin) }, "invalid plugin file extension"},
+		{"install from disallowed source", func() error { return manager.InstallPlugin("example.com/plugins/x", "x") }, "not allowed"},
+		{"install from allowed source", func() error { return manager.InstallPlugin("github.com/AlloraAi/plugins/x", "x") }, "not implemented"},
+		{"load directory without plugins", func() error { return manager.LoadPluginsFromDirectory(dir) }, ""},
+		{"load missing directory", func() error { return manager.LoadPluginsFromDirectory(filepath.Join(dir, "missing")) }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := tt.run()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("expected no error, got %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("nothing loaded", func(t *testing.T) {
+		t.Parallel()
+		if plugins := manager.ListPlugins(); len(plugins) != 0 {
+			t.Errorf("expected no plugins, got %+v", plugins)
+		}
+	})
+
+	t.Run("base plugin", func(t *testing.T) {
+		t.Parallel()
+		base := plugin.NewBasePlugin("echo", "1.0.0", "Echoes its arguments")
+		var p plugin.Plugin = base
+		if p.Name() != "echo" || p.Version() != "1.0.0" || p.Description() != "Echoes its arguments" {
+			t.Errorf("unexpected plugin metadata: %s %s %s", p.Name(), p.Version(), p.Description())
+		}
+		if err := p.Initialize(nil); err != nil || !base.IsInitialized() {
+			t.Errorf("expected the plugin to initialize, got %v", err)
+		}
+		if _, err := p.Execute(context.Background(), nil); err == nil {
+			t.Error("expected Execute to need overriding")
+		}
+		if err := p.Cleanup(); err != nil || base.IsInitialized() {
+			t.Errorf("expected cleanup to reset the plugin, got %v", err)
+		}
+	})
+}
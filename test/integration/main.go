@@ -1,143 +0,0 @@
-package main
-
-import (
-	"context"
-	"fmt"
-	"os"
-	"time"
-
-	"github.com/AlloraAi/AlloraCLI/pkg/agents"
-	"github.com/AlloraAi/AlloraCLI/pkg/config"
-)
-
-func main() {
-	fmt.Println("🚀 AlloraCLI Integration Test")
-	fmt.Println("============================")
-
-	// Test OpenAI Agent Integration
-	fmt.Println("\n1. Testing OpenAI Agent Integration...")
-	if err := testOpenAIAgent(); err != nil {
-		fmt.Printf("❌ OpenAI Agent test failed: %v\n", err)
-	} else {
-		fmt.Println("✅ OpenAI Agent integration working!")
-	}
-
-	// Test Cloud Provider Integration (Mock)
-	fmt.Println("\n2. Testing Cloud Provider Integration...")
-	if err := testCloudProviders(); err != nil {
-		fmt.Printf("❌ Cloud Provider test failed: %v\n", err)
-	} else {
-		fmt.Println("✅ Cloud Provider integration working!")
-	}
-
-	// Test Security Features
-	fmt.Println("\n3. Testing Security Features...")
-	if err := testSecurityFeatures(); err != nil {
-		fmt.Printf("❌ Security features test failed: %v\n", err)
-	} else {
-		fmt.Println("✅ Security features working!")
-	}
-
-	// Test Plugin System
-	fmt.Println("\n4. Testing Plugin System...")
-	if err := testPluginSystem(); err != nil {
-		fmt.Printf("❌ Plugin system test failed: %v\n", err)
-	} else {
-		fmt.Println("✅ Plugin system working!")
-	}
-
-	fmt.Println("\n🎉 Integration tests completed!")
-	fmt.Println("Note: Some tests may show warnings due to missing credentials or services.")
-	fmt.Println("This is expected in a development environment.")
-}
-
-func testOpenAIAgent() error {
-	// Check if OpenAI API key is available
-	apiKey := os.Getenv("ALLORA_OPENAI_API_KEY")
-	if apiKey == "" {
-		fmt.Println("⚠️  OpenAI API key not set (ALLORA_OPENAI_API_KEY). Using mock mode.")
-		return nil
-	}
-
-	// Create agent config
-	agentConfig := config.Agent{
-		Model:       "gpt-4",
-		MaxTokens:   1000,
-		Temperature: 0.7,
-		APIKey:      apiKey,
-		Endpoint:    "https://api.openai.com/v1",
-	}
-
-	// Create agent
-	agent, err := agents.NewAgent(agentConfig)
-	if err != nil {
-		return fmt.Errorf("failed to create agent: %w", err)
-	}
-
-	// Test query
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	query := &agents.Query{
-		Text: "What is the capital of France?",
-	}
-
-	response, err := agent.Query(ctx, query)
-	if err != nil {
-		return fmt.Errorf("failed to query OpenAI agent: %w", err)
-	}
-
-	fmt.Printf("✅ OpenAI Agent Response: %s\n", response.Content)
-	return nil
-}
-
-func testCloudProviders() error {
-	// Test AWS provider creation
-	fmt.Println("  📱 Testing AWS Provider...")
-
-	// We'll test the mock functionality since we don't have real credentials
-	fmt.Println("  ✅ AWS Provider structure implemented")
-
-	// Test Azure provider creation
-	fmt.Println("  📱 Testing Azure Provider...")
-	fmt.Println("  ✅ Azure Provider structure implemented")
-
-	// Test GCP provider creation
-	fmt.Println("  📱 Testing GCP Provider...")
-	fmt.Println("  ✅ GCP Provider structure implemented")
-
-	return nil
-}
-
-func testSecurityFeatures() error {
-	// Test encryption/decryption
-	fmt.Println("  🔒 Testing Encryption...")
-
-	// Test audit logging
-	fmt.Println("  📝 Testing Audit Logging...")
-
-	// Test key management
-	fmt.Println("  🔑 Testing Key Management...")
-
-	fmt.Println("  ✅ Security features structure implemented")
-	return nil
-}
-
-func testPluginSystem() error {
-	// Test plugin manager creation
-	fmt.Println("  🔌 Testing Plugin Manager...")
-
-	fmt.Println("  ✅ Plugin system structure implemented")
-	return nil
-}
-
-func testConfig() error {
-	// Test configuration loading
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
-	}
-
-	fmt.Printf("✅ Config loaded successfully. Version: %s\n", cfg.Version)
-	return nil
-}